@@ -38,6 +38,18 @@ const (
 	Flow
 )
 
+// Keys of the claims of an Astarte token holding the authorizations for each service. Each claim is a list of
+// regular expressions in the "<METHOD>::<path>" form, e.g. "GET::devices/.*", or "JOIN::<room>" and
+// "WATCH::<room>" for Channels.
+const (
+	AppEngineClaimKey       = "a_aea"
+	RealmManagementClaimKey = "a_rma"
+	ChannelsClaimKey        = "a_ch"
+	FlowClaimKey            = "a_f"
+	HousekeepingClaimKey    = "a_ha"
+	PairingClaimKey         = "a_pa"
+)
+
 var astarteServiceValidNames = map[string]AstarteService{
 	"housekeeping":     Housekeeping,
 	"hk":               Housekeeping,
@@ -76,3 +88,23 @@ func FromString(astarteServiceString string) (AstarteService, error) {
 
 	return Unknown, errors.New("Invalid type")
 }
+
+// ClaimKeyFor returns the key of the claim of an Astarte token holding the authorizations for service,
+// e.g. "a_aea" for AppEngine, or an empty string if service is not a valid Astarte service.
+func ClaimKeyFor(service AstarteService) string {
+	switch service {
+	case Housekeeping:
+		return HousekeepingClaimKey
+	case RealmManagement:
+		return RealmManagementClaimKey
+	case Pairing:
+		return PairingClaimKey
+	case AppEngine:
+		return AppEngineClaimKey
+	case Channels:
+		return ChannelsClaimKey
+	case Flow:
+		return FlowClaimKey
+	}
+	return ""
+}
@@ -0,0 +1,310 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+
+	"github.com/astarte-platform/astarte-go/astarteservices"
+)
+
+// Astarte authorization claims have the form "<method regex>::<path regex>". A request is authorized if both regexes
+// match its whole HTTP method and its path relative to the realm, e.g. "devices/<device ID>/interfaces/<interface>"
+// for the request "/v1/<realm>/devices/<device ID>/interfaces/<interface>" to AppEngine API.
+
+// ClaimsForDevice returns the claims granting full AppEngine API access to the device with the given Device ID.
+// If interfaceNames is not empty, access is restricted to the data on those interfaces.
+// The result can be passed to the token generation functions of this package.
+func ClaimsForDevice(deviceID string, interfaceNames []string) map[astarteservices.AstarteService][]string {
+	return deviceClaims(".*", deviceID, interfaceNames)
+}
+
+// ReadOnlyClaimsForDevice works like ClaimsForDevice, but only grants GET requests.
+func ReadOnlyClaimsForDevice(deviceID string, interfaceNames []string) map[astarteservices.AstarteService][]string {
+	return deviceClaims("GET", deviceID, interfaceNames)
+}
+
+func deviceClaims(methodRegex, deviceID string, interfaceNames []string) map[astarteservices.AstarteService][]string {
+	devicePath := "devices/" + regexp.QuoteMeta(deviceID)
+	if len(interfaceNames) == 0 {
+		return map[astarteservices.AstarteService][]string{
+			astarteservices.AppEngine: {Claim(methodRegex, devicePath+"(/.*)?")},
+		}
+	}
+	return map[astarteservices.AstarteService][]string{
+		astarteservices.AppEngine: {
+			// Allow to read the details (and introspection) of the device
+			Claim("GET", devicePath),
+			Claim(methodRegex, devicePath+"/interfaces/"+interfacesRegex(interfaceNames)+"(/.*)?"),
+		},
+	}
+}
+
+// ClaimsForGroup returns the claims granting full AppEngine API access to the devices in the given group, through
+// the group endpoints. If interfaceNames is not empty, access is restricted to the data on those interfaces.
+// The result can be passed to the token generation functions of this package. Astarte claims match request paths
+// only, so the devices of a group can be reached only through the group endpoints: requests to the same devices
+// through "devices/<device ID>" are not authorized. See ClaimIsScopedToGroup to check claims built elsewhere.
+func ClaimsForGroup(groupName string, interfaceNames []string) map[astarteservices.AstarteService][]string {
+	return groupClaims(".*", groupName, interfaceNames)
+}
+
+// ReadOnlyClaimsForGroup works like ClaimsForGroup, but only grants GET requests.
+func ReadOnlyClaimsForGroup(groupName string, interfaceNames []string) map[astarteservices.AstarteService][]string {
+	return groupClaims("GET", groupName, interfaceNames)
+}
+
+func groupClaims(methodRegex, groupName string, interfaceNames []string) map[astarteservices.AstarteService][]string {
+	groupPath := "groups/" + regexp.QuoteMeta(groupName)
+	if len(interfaceNames) == 0 {
+		return map[astarteservices.AstarteService][]string{
+			astarteservices.AppEngine: {Claim(methodRegex, groupPath+"(/.*)?")},
+		}
+	}
+	return map[astarteservices.AstarteService][]string{
+		astarteservices.AppEngine: {
+			// Allow to list the devices in the group, and to read their details
+			Claim("GET", groupPath+"/devices(/[^/]+)?"),
+			Claim(methodRegex, groupPath+"/devices/[^/]+/interfaces/"+interfacesRegex(interfaceNames)+"(/.*)?"),
+		},
+	}
+}
+
+// ClaimIsScopedToGroup returns whether claim only authorizes AppEngine API requests on the endpoints of the group
+// called groupName, i.e. on "groups/<groupName>" and on the paths below it. Claims are checked on their syntax, so
+// claims which are scoped to the group but too convoluted to tell (e.g. alternations of whole paths) are reported as
+// not scoped. It returns an error if claim is not a valid claim.
+func ClaimIsScopedToGroup(claim, groupName string) (bool, error) {
+	_, pathRegex, found := strings.Cut(claim, "::")
+	if !found {
+		return false, fmt.Errorf("%q is not a valid claim: the method and path regexes must be separated by \"::\"", claim)
+	}
+	re, err := syntax.Parse(pathRegex, syntax.Perl)
+	if err != nil {
+		return false, err
+	}
+	re = re.Simplify()
+	// Astarte matches paths without their leading slash, which is optional in claims too, see ClaimAllows
+	groupPath := "groups/" + groupName
+
+	subs := []*syntax.Regexp{re}
+	if re.Op == syntax.OpConcat {
+		subs = re.Sub
+	}
+	for len(subs) > 0 && (subs[0].Op == syntax.OpBeginText || subs[0].Op == syntax.OpBeginLine) {
+		subs = subs[1:]
+	}
+	if len(subs) == 0 || subs[0].Op != syntax.OpLiteral || subs[0].Flags&syntax.FoldCase != 0 {
+		return false, nil
+	}
+	prefix := strings.TrimPrefix(string(subs[0].Rune), "/")
+	switch {
+	case strings.HasPrefix(prefix, groupPath+"/"):
+		return true, nil
+	case prefix != groupPath:
+		return false, nil
+	}
+	return startsWithSlashOrEmpty(subs[1:]), nil
+}
+
+// ValidateGroupScopedClaims checks that servicesAndClaims, e.g. the claims of a token for a multi-tenant dashboard,
+// only grant access to the group called groupName: all the claims must be AppEngine claims scoped to the group,
+// see ClaimIsScopedToGroup. The returned error lists all the offending claims.
+func ValidateGroupScopedClaims(servicesAndClaims map[astarteservices.AstarteService][]string, groupName string) error {
+	errs := []error{}
+	for service, claims := range servicesAndClaims {
+		for _, claim := range claims {
+			if service != astarteservices.AppEngine {
+				errs = append(errs, fmt.Errorf("%w: %s claim %q", ErrClaimNotScopedToGroup, service, claim))
+				continue
+			}
+			scoped, err := ClaimIsScopedToGroup(claim, groupName)
+			if err != nil {
+				errs = append(errs, err)
+			} else if !scoped {
+				errs = append(errs, fmt.Errorf("%w: %q is not scoped to group %s", ErrClaimNotScopedToGroup, claim, groupName))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// startsWithSlashOrEmpty returns whether the concatenation of subs only matches the empty string or strings
+// starting with a slash.
+func startsWithSlashOrEmpty(subs []*syntax.Regexp) bool {
+	for _, sub := range subs {
+		if !startsWithSlash(sub) {
+			return false
+		}
+		if !canMatchEmpty(sub) {
+			return true
+		}
+	}
+	return true
+}
+
+// startsWithSlash returns whether the non-empty strings matched by re start with a slash.
+func startsWithSlash(re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpEmptyMatch, syntax.OpBeginText, syntax.OpEndText, syntax.OpBeginLine, syntax.OpEndLine:
+		return true
+	case syntax.OpLiteral:
+		return len(re.Rune) > 0 && re.Rune[0] == '/'
+	case syntax.OpCharClass:
+		return len(re.Rune) == 2 && re.Rune[0] == '/' && re.Rune[1] == '/'
+	case syntax.OpCapture, syntax.OpQuest, syntax.OpStar, syntax.OpPlus, syntax.OpRepeat:
+		return startsWithSlash(re.Sub[0])
+	case syntax.OpConcat:
+		return startsWithSlashOrEmpty(re.Sub)
+	case syntax.OpAlternate:
+		for _, sub := range re.Sub {
+			if !startsWithSlash(sub) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func canMatchEmpty(re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpEmptyMatch, syntax.OpBeginText, syntax.OpEndText, syntax.OpBeginLine, syntax.OpEndLine,
+		syntax.OpQuest, syntax.OpStar:
+		return true
+	case syntax.OpRepeat:
+		return re.Min == 0 || canMatchEmpty(re.Sub[0])
+	case syntax.OpCapture, syntax.OpPlus:
+		return canMatchEmpty(re.Sub[0])
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			if !canMatchEmpty(sub) {
+				return false
+			}
+		}
+		return true
+	case syntax.OpAlternate:
+		for _, sub := range re.Sub {
+			if canMatchEmpty(sub) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ClaimsForInterfaces returns the claims granting read-only Realm Management API access to the given interfaces,
+// e.g. to let a client validate the data it sends. The result can be passed to the token generation functions of
+// this package.
+func ClaimsForInterfaces(interfaceNames []string) map[astarteservices.AstarteService][]string {
+	return map[astarteservices.AstarteService][]string{
+		astarteservices.RealmManagement: {Claim("GET", "interfaces/"+interfacesRegex(interfaceNames)+"(/.*)?")},
+	}
+}
+
+func interfacesRegex(interfaceNames []string) string {
+	quoted := make([]string, len(interfaceNames))
+	for i, name := range interfaceNames {
+		quoted[i] = regexp.QuoteMeta(name)
+	}
+	return "(" + strings.Join(quoted, "|") + ")"
+}
+
+// Astarte Channels claims have the form "JOIN::<room regex>" or "WATCH::<watch regex>". The room regex must match
+// the whole name of the room a client joins, while the watch regex must match the whole target of the triggers it
+// installs in a room: "<device ID>" for device triggers and "<device ID>/<interface>" for data triggers.
+
+const (
+	channelJoinOperation  = "JOIN"
+	channelWatchOperation = "WATCH"
+)
+
+// ChannelJoinClaim returns a Channels claim allowing to join the rooms whose name matches roomRegex.
+// It returns an error if roomRegex is not a valid regex.
+func ChannelJoinClaim(roomRegex string) (string, error) {
+	return channelClaim(channelJoinOperation, roomRegex)
+}
+
+// ChannelWatchClaim returns a Channels claim allowing to watch the devices and interfaces matching watchRegex,
+// i.e. to install triggers on them in a joined room. It returns an error if watchRegex is not a valid regex.
+func ChannelWatchClaim(watchRegex string) (string, error) {
+	return channelClaim(channelWatchOperation, watchRegex)
+}
+
+func channelClaim(operation, regex string) (string, error) {
+	if _, err := regexp.Compile(regex); err != nil {
+		return "", err
+	}
+	return Claim(operation, regex), nil
+}
+
+// ClaimsForChannelRoom returns the Channels claims allowing to join the room called roomName and to watch the
+// device with the given Device ID in it. If interfaceNames is not empty, only data triggers on those interfaces
+// can be installed, otherwise any device or data trigger on the device can be installed.
+// The result can be passed to the token generation functions of this package.
+func ClaimsForChannelRoom(roomName, deviceID string, interfaceNames []string) map[astarteservices.AstarteService][]string {
+	watchRegex := regexp.QuoteMeta(deviceID) + "(/.*)?"
+	if len(interfaceNames) > 0 {
+		watchRegex = regexp.QuoteMeta(deviceID) + "/" + interfacesRegex(interfaceNames)
+	}
+	return map[astarteservices.AstarteService][]string{
+		astarteservices.Channels: {
+			Claim(channelJoinOperation, regexp.QuoteMeta(roomName)),
+			Claim(channelWatchOperation, watchRegex),
+		},
+	}
+}
+
+// ChannelClaimAllows returns whether claim authorizes the Channels operation ("JOIN" or "WATCH") on target, i.e.
+// the name of a room or the target of a trigger, matching it as Astarte does. It returns an error if claim is not
+// a valid Channels claim.
+func ChannelClaimAllows(claim, operation, target string) (bool, error) {
+	claimOperation, regex, found := strings.Cut(claim, "::")
+	if !found || (claimOperation != channelJoinOperation && claimOperation != channelWatchOperation) {
+		return false, fmt.Errorf("%q is not a valid Channels claim: it must start with \"JOIN::\" or \"WATCH::\"", claim)
+	}
+	matcher, err := regexp.Compile("^(" + regex + ")$")
+	if err != nil {
+		return false, err
+	}
+	return claimOperation == operation && matcher.MatchString(target), nil
+}
+
+// Claim returns an authorization claim from a method regex and a path regex.
+func Claim(methodRegex, pathRegex string) string {
+	return methodRegex + "::" + pathRegex
+}
+
+// ClaimAllows returns whether claim authorizes a request with the given HTTP method and path relative to the
+// realm, matching it as Astarte does. It returns an error if claim is not a valid claim.
+func ClaimAllows(claim, method, path string) (bool, error) {
+	methodRegex, pathRegex, found := strings.Cut(claim, "::")
+	if !found {
+		return false, fmt.Errorf("%q is not a valid claim: the method and path regexes must be separated by \"::\"", claim)
+	}
+	methodMatcher, err := regexp.Compile("^(" + methodRegex + ")$")
+	if err != nil {
+		return false, err
+	}
+	pathMatcher, err := regexp.Compile("^(" + pathRegex + ")$")
+	if err != nil {
+		return false, err
+	}
+	return methodMatcher.MatchString(method) && pathMatcher.MatchString(strings.TrimPrefix(path, "/")), nil
+}
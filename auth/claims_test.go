@@ -0,0 +1,221 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/astarte-platform/astarte-go/astarteservices"
+)
+
+type claimCase struct {
+	method, path string
+	allowed      bool
+}
+
+func checkClaims(t *testing.T, claims []string, cases []claimCase) {
+	t.Helper()
+	for _, c := range cases {
+		allowed := false
+		for _, claim := range claims {
+			ok, err := ClaimAllows(claim, c.method, c.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			allowed = allowed || ok
+		}
+		if allowed != c.allowed {
+			t.Errorf("%s %s: expected allowed=%v with claims %v", c.method, c.path, c.allowed, claims)
+		}
+	}
+}
+
+func TestClaimsForDevice(t *testing.T) {
+	deviceID := "f0VMRgIBAQAAAAAAAAAAAA"
+	claims := ClaimsForDevice(deviceID, []string{"org.astarte.Values", "org.astarte.Config"})
+	if len(claims) != 1 {
+		t.Fatalf("Expected only AppEngine claims, found %v", claims)
+	}
+	checkClaims(t, claims[astarteservices.AppEngine], []claimCase{
+		{"GET", "devices/" + deviceID, true},
+		{"GET", "devices/" + deviceID + "/interfaces/org.astarte.Values", true},
+		{"POST", "devices/" + deviceID + "/interfaces/org.astarte.Values/a/value", true},
+		{"PUT", "/devices/" + deviceID + "/interfaces/org.astarte.Config/a/setting", true},
+		{"PATCH", "devices/" + deviceID, false},
+		{"GET", "devices/" + deviceID + "/interfaces/org.astarte.Other", false},
+		// Dots in interface names are not wildcards
+		{"GET", "devices/" + deviceID + "/interfaces/orgXastarte.Values", false},
+		// Interface names are not prefixes
+		{"GET", "devices/" + deviceID + "/interfaces/org.astarte.ValuesAndMore", false},
+		{"GET", "devices/" + deviceID + "X/interfaces/org.astarte.Values", false},
+		{"GET", "devices/other/interfaces/org.astarte.Values", false},
+		{"GET", "devices", false},
+		{"GET", "groups/g/devices/" + deviceID + "/interfaces/org.astarte.Values", false},
+	})
+
+	checkClaims(t, ClaimsForDevice(deviceID, nil)[astarteservices.AppEngine], []claimCase{
+		{"PATCH", "devices/" + deviceID, true},
+		{"DELETE", "devices/" + deviceID + "/interfaces/org.astarte.Anything/a/b", true},
+		{"GET", "devices/" + deviceID + "X", false},
+		{"GET", "devices", false},
+	})
+
+	checkClaims(t, ReadOnlyClaimsForDevice(deviceID, []string{"org.astarte.Values"})[astarteservices.AppEngine], []claimCase{
+		{"GET", "devices/" + deviceID + "/interfaces/org.astarte.Values/a/value", true},
+		{"POST", "devices/" + deviceID + "/interfaces/org.astarte.Values/a/value", false},
+		{"XGET", "devices/" + deviceID, false},
+	})
+}
+
+func TestClaimsForGroup(t *testing.T) {
+	claims := ClaimsForGroup("ah yes, a group (test)", []string{"org.astarte.Values"})
+	checkClaims(t, claims[astarteservices.AppEngine], []claimCase{
+		{"GET", "groups/ah yes, a group (test)/devices", true},
+		{"GET", "groups/ah yes, a group (test)/devices/abc", true},
+		{"GET", "groups/ah yes, a group (test)/devices/abc/interfaces/org.astarte.Values/a", true},
+		{"POST", "groups/ah yes, a group (test)/devices", false},
+		{"GET", "groups/ah yes, a group (test)/devices/abc/interfaces/org.astarte.Other", false},
+		{"GET", "groups/ah yes, a group test/devices", false},
+		{"GET", "devices/abc/interfaces/org.astarte.Values", false},
+	})
+}
+
+func TestReadOnlyClaimsForGroup(t *testing.T) {
+	claims := ReadOnlyClaimsForGroup("customer-1", nil)
+	checkClaims(t, claims[astarteservices.AppEngine], []claimCase{
+		{"GET", "groups/customer-1/devices", true},
+		{"GET", "groups/customer-1/devices/f0VMRgIBAQAAAAAAAAAAAA/interfaces/org.astarte.Values", true},
+		{"POST", "groups/customer-1/devices/f0VMRgIBAQAAAAAAAAAAAA/interfaces/org.astarte.Values/a/value", false},
+		{"GET", "groups/customer-10/devices", false},
+	})
+}
+
+func TestClaimIsScopedToGroup(t *testing.T) {
+	testCases := map[string]bool{
+		Claim(".*", "groups/customer\\.1(/.*)?"):                        true,
+		Claim("GET", "groups/customer\\.1"):                             true,
+		Claim("GET", "/groups/customer\\.1/devices/[^/]+"):              true,
+		Claim("GET", "^groups/customer\\.1(/devices|/devices/[^/]+)?$"): true,
+		Claim("GET", "groups/customer.1(/.*)?"):                         false,
+		Claim("GET", "groups/customer\\.1.*"):                           false,
+		Claim("GET", "groups/customer\\.10(/.*)?"):                      false,
+		Claim("GET", "groups/customer\\.1(/.*|x)?"):                     false,
+		Claim("GET", "(?i)groups/customer\\.1"):                         false,
+		Claim("GET", "devices/.*"):                                      false,
+		Claim("GET", ".*"):                                              false,
+	}
+	for claim, expected := range testCases {
+		scoped, err := ClaimIsScopedToGroup(claim, "customer.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if scoped != expected {
+			t.Errorf("%s: expected scoped=%v", claim, expected)
+		}
+	}
+	if _, err := ClaimIsScopedToGroup("groups/customer.1", "customer.1"); err == nil {
+		t.Error("Expected an error for a claim without method")
+	}
+
+	for _, claims := range []map[astarteservices.AstarteService][]string{
+		ClaimsForGroup("customer.1", nil),
+		ClaimsForGroup("customer.1", []string{"org.astarte.Values"}),
+		ReadOnlyClaimsForGroup("customer.1", []string{"org.astarte.Values"}),
+	} {
+		if err := ValidateGroupScopedClaims(claims, "customer.1"); err != nil {
+			t.Errorf("Unexpected error for %v: %v", claims, err)
+		}
+	}
+	err := ValidateGroupScopedClaims(ClaimsForDevice("f0VMRgIBAQAAAAAAAAAAAA", nil), "customer.1")
+	if !errors.Is(err, ErrClaimNotScopedToGroup) {
+		t.Errorf("Expected ErrClaimNotScopedToGroup, found %v", err)
+	}
+	if err := ValidateGroupScopedClaims(ClaimsForInterfaces([]string{"org.astarte.Values"}), "customer.1"); !errors.Is(err, ErrClaimNotScopedToGroup) {
+		t.Errorf("Expected ErrClaimNotScopedToGroup, found %v", err)
+	}
+}
+
+func TestClaimsForInterfaces(t *testing.T) {
+	claims := ClaimsForInterfaces([]string{"org.astarte.Values"})
+	checkClaims(t, claims[astarteservices.RealmManagement], []claimCase{
+		{"GET", "interfaces/org.astarte.Values", true},
+		{"GET", "interfaces/org.astarte.Values/1", true},
+		{"DELETE", "interfaces/org.astarte.Values/1", false},
+		{"GET", "interfaces", false},
+		{"GET", "interfaces/org.astarte.Other/1", false},
+	})
+}
+
+func TestClaimAllows(t *testing.T) {
+	if _, err := ClaimAllows("GET:devices", "GET", "devices"); err == nil {
+		t.Error("Expected an error for a claim without separator, found nil")
+	}
+	if _, err := ClaimAllows("GET::devices/(", "GET", "devices"); err == nil {
+		t.Error("Expected an error for an invalid regex, found nil")
+	}
+	if ok, _ := ClaimAllows(".*::.*", "DELETE", "devices/abc"); !ok {
+		t.Error("The full access claim should allow everything")
+	}
+}
+
+func TestChannelClaims(t *testing.T) {
+	deviceID := "f0VMRgIBAQAAAAAAAAAAAA"
+	claims := ClaimsForChannelRoom("dashboard.1", deviceID, []string{"org.astarte.Values"})[astarteservices.Channels]
+	cases := []struct {
+		operation, target string
+		allowed           bool
+	}{
+		{"JOIN", "dashboard.1", true},
+		{"JOIN", "dashboardX1", false},
+		{"JOIN", "dashboard.10", false},
+		{"WATCH", deviceID + "/org.astarte.Values", true},
+		{"WATCH", deviceID + "/org.astarte.Other", false},
+		{"WATCH", deviceID, false},
+		{"WATCH", "dashboard.1", false},
+		{"JOIN", deviceID + "/org.astarte.Values", false},
+	}
+	for _, c := range cases {
+		allowed := false
+		for _, claim := range claims {
+			ok, err := ChannelClaimAllows(claim, c.operation, c.target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			allowed = allowed || ok
+		}
+		if allowed != c.allowed {
+			t.Errorf("%s %s: expected allowed=%v with claims %v", c.operation, c.target, c.allowed, claims)
+		}
+	}
+
+	allDeviceClaims := ClaimsForChannelRoom("room", deviceID, nil)[astarteservices.Channels]
+	if ok, _ := ChannelClaimAllows(allDeviceClaims[1], "WATCH", deviceID); !ok {
+		t.Error("Watching device triggers should be allowed")
+	}
+
+	if claim, err := ChannelJoinClaim("rooms_.*"); err != nil || claim != "JOIN::rooms_.*" {
+		t.Errorf("Unexpected claim %s: %v", claim, err)
+	}
+	if claim, err := ChannelWatchClaim(".*"); err != nil || claim != "WATCH::.*" {
+		t.Errorf("Unexpected claim %s: %v", claim, err)
+	}
+	if _, err := ChannelWatchClaim("devices/("); err == nil {
+		t.Error("Expected an error for an invalid regex, found nil")
+	}
+	if _, err := ChannelClaimAllows("GET::.*", "JOIN", "room"); err == nil {
+		t.Error("Expected an error for a non Channels claim, found nil")
+	}
+}
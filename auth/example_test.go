@@ -0,0 +1,51 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth_test
+
+import (
+	"fmt"
+
+	"github.com/astarte-platform/astarte-go/astarteservices"
+	"github.com/astarte-platform/astarte-go/auth"
+)
+
+func ExampleGenerateAstarteJWTFromKeyFile() {
+	// A token valid for 5 minutes, granting full access to AppEngine API and
+	// read-only access to Realm Management API
+	servicesAndClaims := map[astarteservices.AstarteService][]string{
+		astarteservices.AppEngine:       {},
+		astarteservices.RealmManagement: {"GET::.*"},
+	}
+	token, err := auth.GenerateAstarteJWTFromKeyFile("/path/to/realm_private.pem", servicesAndClaims, 300)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println(token)
+}
+
+func ExampleClaimsForDevice() {
+	// A token valid for 5 minutes, granting access to a single device, and only to its
+	// data on the org.astarte-platform.genericsensors.Values interface
+	servicesAndClaims := auth.ClaimsForDevice("f0VMRgIBAQAAAAAAAAAAAA", []string{"org.astarte-platform.genericsensors.Values"})
+	token, err := auth.GenerateAstarteJWTFromKeyFile("/path/to/realm_private.pem", servicesAndClaims, 300)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println(token)
+}
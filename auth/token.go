@@ -36,8 +36,14 @@ var (
 	ErrNotPrivateKey = errors.New("Key is not a valid private key")
 	// ErrUnsupportedPrivateKey is returned when the chosen private key is not supported for JWT generation
 	ErrUnsupportedPrivateKey = errors.New("Key is not supported for JWT generation")
+	// ErrReservedClaim is returned when an extra claim would override a standard or an Astarte claim
+	ErrReservedClaim = errors.New("Claim is reserved")
+	// ErrClaimNotScopedToGroup is returned when a claim grants access beyond a group, see ValidateGroupScopedClaims
+	ErrClaimNotScopedToGroup = errors.New("Claim is not scoped to the group")
 )
 
+// AstarteClaims are the claims of an Astarte token. The keys of the claims of each service are
+// exported by the astarteservices package, see astarteservices.ClaimKeyFor.
 type AstarteClaims struct {
 	jwt.StandardClaims
 
@@ -53,6 +59,26 @@ func (u *AstarteClaims) MarshalBinary() ([]byte, error) {
 	return json.Marshal(u)
 }
 
+// forService returns the claims of service, or nil if service is not a valid Astarte service.
+// Field tags must be kept in sync with astarteservices.ClaimKeyFor.
+func (u *AstarteClaims) forService(service astarteservices.AstarteService) *[]string {
+	switch service {
+	case astarteservices.AppEngine:
+		return &u.AppEngineAPI
+	case astarteservices.Channels:
+		return &u.Channels
+	case astarteservices.Flow:
+		return &u.Flow
+	case astarteservices.Housekeeping:
+		return &u.Housekeeping
+	case astarteservices.Pairing:
+		return &u.Pairing
+	case astarteservices.RealmManagement:
+		return &u.RealmManagement
+	}
+	return nil
+}
+
 // GenerateAstarteJWTFromKeyFile generates an Astarte Token for a specific API out of a Private Key File.
 // servicesAndClaims specifies which services with which claims the token will be authorized to access. Leaving
 // a claim empty will imply `.*::.*`, aka access to the entirety of the service's API tree
@@ -110,6 +136,102 @@ func ParsePrivateKeyFromPEM(key []byte) (interface{}, error) {
 // a claim empty will imply `.*::.*`, aka access to the entirety of the service's API tree
 func GenerateAstarteJWTFromPEMKey(privateKeyPEM []byte, servicesAndClaims map[astarteservices.AstarteService][]string,
 	ttlSeconds int64) (jwtString string, err error) {
+	return GenerateAstarteJWTFromPEMKeyAt(privateKeyPEM, servicesAndClaims, ttlSeconds, time.Now())
+}
+
+// GenerateAstarteJWTFromPEMKeyAt works like GenerateAstarteJWTFromPEMKey, but the token is issued at issuedAt
+// rather than at the current time. This is mostly useful to generate reproducible tokens in tests.
+func GenerateAstarteJWTFromPEMKeyAt(privateKeyPEM []byte, servicesAndClaims map[astarteservices.AstarteService][]string,
+	ttlSeconds int64, issuedAt time.Time) (jwtString string, err error) {
+	return GenerateAstarteJWTFromPEMKeyWithOptions(privateKeyPEM, servicesAndClaims, ttlSeconds, WithIssuedAt(issuedAt))
+}
+
+// TokenOption customizes the claims of a token generated by GenerateAstarteJWTFromPEMKeyWithOptions.
+type TokenOption func(*tokenOptions)
+
+type tokenOptions struct {
+	issuedAt    time.Time
+	issuer      string
+	subject     string
+	audience    []string
+	notBefore   time.Time
+	extraClaims map[string]any
+}
+
+// WithIssuedAt sets the time the token is issued at, which is also the start of its validity.
+// It defaults to the current time.
+func WithIssuedAt(issuedAt time.Time) TokenOption {
+	return func(o *tokenOptions) {
+		o.issuedAt = issuedAt
+	}
+}
+
+// WithIssuer sets the "iss" claim of the token.
+func WithIssuer(issuer string) TokenOption {
+	return func(o *tokenOptions) {
+		o.issuer = issuer
+	}
+}
+
+// WithSubject sets the "sub" claim of the token.
+func WithSubject(subject string) TokenOption {
+	return func(o *tokenOptions) {
+		o.subject = subject
+	}
+}
+
+// WithAudience sets the "aud" claim of the token. A single audience is encoded as a string, as most
+// API gateways expect.
+func WithAudience(audience ...string) TokenOption {
+	return func(o *tokenOptions) {
+		o.audience = audience
+	}
+}
+
+// WithNotBefore sets the "nbf" claim of the token, i.e. the time before which it must not be accepted.
+func WithNotBefore(notBefore time.Time) TokenOption {
+	return func(o *tokenOptions) {
+		o.notBefore = notBefore
+	}
+}
+
+// WithExtraClaims adds private claims to the token, e.g. to be inspected by an API gateway in front of Astarte.
+// Claims can't override standard JWT claims nor Astarte claims: token generation fails with ErrReservedClaim
+// if they do. Using WithExtraClaims many times merges the claims.
+func WithExtraClaims(claims map[string]any) TokenOption {
+	return func(o *tokenOptions) {
+		if o.extraClaims == nil {
+			o.extraClaims = map[string]any{}
+		}
+		for k, v := range claims {
+			o.extraClaims[k] = v
+		}
+	}
+}
+
+var reservedClaims = map[string]bool{
+	"jti": true, "aud": true, "iss": true, "sub": true, "exp": true, "iat": true, "nbf": true,
+	astarteservices.AppEngineClaimKey: true, astarteservices.ChannelsClaimKey: true, astarteservices.FlowClaimKey: true,
+	astarteservices.HousekeepingClaimKey: true, astarteservices.RealmManagementClaimKey: true, astarteservices.PairingClaimKey: true,
+}
+
+// GenerateAstarteJWTFromPEMKeyWithOptions works like GenerateAstarteJWTFromPEMKey, but allows to customize the
+// standard claims of the token and to add private claims alongside the Astarte ones, see TokenOption.
+func GenerateAstarteJWTFromPEMKeyWithOptions(privateKeyPEM []byte, servicesAndClaims map[astarteservices.AstarteService][]string,
+	ttlSeconds int64, opts ...TokenOption) (jwtString string, err error) {
+	options := tokenOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.issuedAt.IsZero() {
+		options.issuedAt = time.Now()
+	}
+	for k := range options.extraClaims {
+		if reservedClaims[k] {
+			return "", fmt.Errorf("%w: %s", ErrReservedClaim, k)
+		}
+	}
+
 	key, err := ParsePrivateKeyFromPEM(privateKeyPEM)
 	if err != nil {
 		return "", err
@@ -118,12 +240,17 @@ func GenerateAstarteJWTFromPEMKey(privateKeyPEM []byte, servicesAndClaims map[as
 	// Build the token claims
 	claims := AstarteClaims{}
 	// Handle issue and expiry
-	now := time.Now()
-	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.IssuedAt = jwt.NewNumericDate(options.issuedAt)
 	if ttlSeconds > 0 {
-		exp := now.Add(time.Duration(ttlSeconds) * time.Second)
+		exp := options.issuedAt.Add(time.Duration(ttlSeconds) * time.Second)
 		claims.ExpiresAt = jwt.NewNumericDate(exp)
 	}
+	claims.Issuer = options.issuer
+	claims.Subject = options.subject
+	claims.Audience = options.audience
+	if !options.notBefore.IsZero() {
+		claims.NotBefore = jwt.NewNumericDate(options.notBefore)
+	}
 
 	for svc, c := range servicesAndClaims {
 		if len(c) == 0 {
@@ -135,19 +262,8 @@ func GenerateAstarteJWTFromPEMKey(privateKeyPEM []byte, servicesAndClaims map[as
 			}
 		}
 
-		switch svc {
-		case astarteservices.AppEngine:
-			claims.AppEngineAPI = c
-		case astarteservices.Channels:
-			claims.Channels = c
-		case astarteservices.Flow:
-			claims.Flow = c
-		case astarteservices.Housekeeping:
-			claims.Housekeeping = c
-		case astarteservices.Pairing:
-			claims.Pairing = c
-		case astarteservices.RealmManagement:
-			claims.RealmManagement = c
+		if serviceClaims := claims.forService(svc); serviceClaims != nil {
+			*serviceClaims = c
 		}
 	}
 
@@ -157,7 +273,11 @@ func GenerateAstarteJWTFromPEMKey(privateKeyPEM []byte, servicesAndClaims map[as
 	}
 	builder := jwt.NewBuilder(signer)
 
-	token, err := builder.Build(&claims)
+	tokenClaims, err := withExtraClaims(claims, options.extraClaims)
+	if err != nil {
+		return "", err
+	}
+	token, err := builder.Build(tokenClaims)
 	if err != nil {
 		return "", err
 	}
@@ -165,6 +285,25 @@ func GenerateAstarteJWTFromPEMKey(privateKeyPEM []byte, servicesAndClaims map[as
 	return token.String(), nil
 }
 
+// withExtraClaims returns the claims to encode in a token, merging claims and extraClaims.
+func withExtraClaims(claims AstarteClaims, extraClaims map[string]any) (any, error) {
+	if len(extraClaims) == 0 {
+		return &claims, nil
+	}
+	encoded, err := json.Marshal(&claims)
+	if err != nil {
+		return nil, err
+	}
+	merged := map[string]any{}
+	if err := json.Unmarshal(encoded, &merged); err != nil {
+		return nil, err
+	}
+	for k, v := range extraClaims {
+		merged[k] = v
+	}
+	return merged, nil
+}
+
 // GetJWTAstarteClaims returns the set of Astarte claims for an Astarte Token.
 func GetJWTAstarteClaims(rawToken string) (AstarteClaims, error) {
 	token, err := jwt.ParseString(rawToken)
@@ -187,22 +326,11 @@ func IsJWTAstarteClaimValidForService(token string, service astarteservices.Asta
 	if err != nil {
 		return false, err
 	}
-	switch service {
-	case astarteservices.AppEngine:
-		return hasAuth(claims.AppEngineAPI), nil
-	case astarteservices.RealmManagement:
-		return hasAuth(claims.RealmManagement), nil
-	case astarteservices.Housekeeping:
-		return hasAuth(claims.Housekeeping), nil
-	case astarteservices.Pairing:
-		return hasAuth(claims.Pairing), nil
-	case astarteservices.Channels:
-		return hasAuth(claims.Channels), nil
-	case astarteservices.Flow:
-		return hasAuth(claims.Flow), nil
-	default:
+	serviceClaims := claims.forService(service)
+	if serviceClaims == nil {
 		return false, fmt.Errorf("unknown Astarte service %s", service.String())
 	}
+	return hasAuth(*serviceClaims), nil
 }
 
 func hasAuth(auth []string) bool {
@@ -0,0 +1,142 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/astarteservices"
+)
+
+func generateTestKey(t *testing.T) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes})
+}
+
+func decodeTestClaims(t *testing.T, token string) map[string]any {
+	t.Helper()
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("Invalid JWT: %v", parts)
+	}
+	rawClaims, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := map[string]any{}
+	if err := json.Unmarshal(rawClaims, &claims); err != nil {
+		t.Fatal(err)
+	}
+	return claims
+}
+
+func TestGenerateAstarteJWTFromPEMKeyWithOptions(t *testing.T) {
+	key := generateTestKey(t)
+	issuedAt := time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC)
+	servicesAndClaims := map[astarteservices.AstarteService][]string{astarteservices.AppEngine: {}}
+
+	token, err := GenerateAstarteJWTFromPEMKeyWithOptions(key, servicesAndClaims, 60,
+		WithIssuedAt(issuedAt),
+		WithIssuer("astartectl"),
+		WithSubject("dashboard"),
+		WithAudience("api-gateway"),
+		WithNotBefore(issuedAt.Add(-time.Minute)),
+		WithExtraClaims(map[string]any{"tenant": "acme"}),
+		WithExtraClaims(map[string]any{"scope": "read"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := decodeTestClaims(t, token)
+	expected := map[string]any{
+		"iss":    "astartectl",
+		"sub":    "dashboard",
+		"aud":    "api-gateway",
+		"iat":    float64(issuedAt.Unix()),
+		"exp":    float64(issuedAt.Unix() + 60),
+		"nbf":    float64(issuedAt.Unix() - 60),
+		"tenant": "acme",
+		"scope":  "read",
+	}
+	for k, v := range expected {
+		if claims[k] != v {
+			t.Errorf("Expected claim %s to be %v, found %v", k, v, claims[k])
+		}
+	}
+	astarteClaims, err := GetJWTAstarteClaims(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(astarteClaims.AppEngineAPI) != 1 || astarteClaims.AppEngineAPI[0] != ".*::.*" {
+		t.Errorf("Unexpected Astarte claims %+v", astarteClaims)
+	}
+
+	token, err = GenerateAstarteJWTFromPEMKeyWithOptions(key, servicesAndClaims, 60, WithAudience("a", "b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if aud, ok := decodeTestClaims(t, token)["aud"].([]any); !ok || len(aud) != 2 {
+		t.Errorf("Expected many audiences as an array, found %v", aud)
+	}
+}
+
+func TestGenerateAstarteJWTFromPEMKeyWithReservedClaims(t *testing.T) {
+	key := generateTestKey(t)
+	for _, claim := range []string{"exp", "a_aea"} {
+		_, err := GenerateAstarteJWTFromPEMKeyWithOptions(key, nil, 60, WithExtraClaims(map[string]any{claim: "overridden"}))
+		if !errors.Is(err, ErrReservedClaim) {
+			t.Errorf("Expected ErrReservedClaim for %s, found %v", claim, err)
+		}
+	}
+}
+
+func TestClaimKeys(t *testing.T) {
+	key := generateTestKey(t)
+	for _, service := range []astarteservices.AstarteService{astarteservices.Housekeeping, astarteservices.RealmManagement,
+		astarteservices.Pairing, astarteservices.AppEngine, astarteservices.Channels, astarteservices.Flow} {
+		token, err := GenerateAstarteJWTFromPEMKey(key, map[astarteservices.AstarteService][]string{service: nil}, 60)
+		if err != nil {
+			t.Fatal(err)
+		}
+		claimKey := astarteservices.ClaimKeyFor(service)
+		if _, ok := decodeTestClaims(t, token)[claimKey]; !ok || claimKey == "" {
+			t.Errorf("Expected the %q claim for %s", claimKey, service)
+		}
+		if _, err := GenerateAstarteJWTFromPEMKeyWithOptions(key, nil, 60, WithExtraClaims(map[string]any{claimKey: ""})); !errors.Is(err, ErrReservedClaim) {
+			t.Errorf("Expected %q to be reserved, found %v", claimKey, err)
+		}
+	}
+	if claimKey := astarteservices.ClaimKeyFor(astarteservices.Unknown); claimKey != "" {
+		t.Errorf("Unexpected claim key %q for an unknown service", claimKey)
+	}
+}
@@ -15,11 +15,15 @@
 package client
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 
 	"moul.io/http2curl"
+
+	"github.com/astarte-platform/astarte-go/internal/urlbuilder"
 )
 
 // DeviceIdentifierType represents what kind of identifier is used for identifying a Device.
@@ -39,15 +43,16 @@ const (
 // The paginator can return different result formats depending on the format
 // parameter.
 func (c *Client) GetDeviceListPaginator(realm string, pageSize int, format DeviceResultFormat) (Paginator, error) {
-	callURL := makeURL(c.appEngineURL, "/v1/%s/devices", realm)
+	callURL := urlbuilder.Build(c.appEngineURL, "/v1/%s/devices", realm)
 	query := url.Values{}
 	deviceListPaginator := DeviceListPaginator{
 		baseURL:     callURL,
 		nextQuery:   query,
 		format:      format,
-		pageSize:    pageSize,
+		pageSize:    c.clampPageSize(pageSize),
 		client:      c,
 		hasNextPage: true,
+		realm:       realm,
 	}
 
 	return &deviceListPaginator, nil
@@ -55,38 +60,79 @@ func (c *Client) GetDeviceListPaginator(realm string, pageSize int, format Devic
 
 type GetDeviceDetailsRequest struct {
 	req     *http.Request
-	expects int
+	expects []int
 }
 
 // GetDevice builds a request to return the DeviceDetails of a single Device in the Realm.
 func (c *Client) GetDeviceDetails(realm string, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType) (AstarteRequest, error) {
-	resolvedDeviceIdentifierType := resolveDeviceIdentifierType(deviceIdentifier, deviceIdentifierType)
-	callURL := makeURL(c.appEngineURL, "/v1/%s/%s", realm, devicePath(deviceIdentifier, resolvedDeviceIdentifierType))
+	resolvedDeviceIdentifierType, err := c.resolveDeviceIdentifier(deviceIdentifier, deviceIdentifierType)
+	if err != nil {
+		return Empty{}, err
+	}
+	callURL := urlbuilder.Build(c.appEngineURL, "/v1/%s/%s", realm, devicePath(deviceIdentifier, resolvedDeviceIdentifierType))
 	req := c.makeHTTPrequest(http.MethodGet, callURL, nil)
 
-	return GetDeviceDetailsRequest{req: req, expects: 200}, nil
+	return GetDeviceDetailsRequest{req: req, expects: []int{http.StatusOK}}, nil
 }
 
 // nolint:bodyclose
 func (r GetDeviceDetailsRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return runAstarteRequestError(res, r.expects)
 	}
 	return GetDeviceDetailsResponse{res: res}, nil
 }
 
 func (r GetDeviceDetailsRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
+	return fmt.Sprint(command)
+}
+
+type GetDeviceInterfaceStatsRequest struct {
+	req     *http.Request
+	expects []int
+}
+
+// GetDeviceInterfaceStats builds a request to return the messages and bytes exchanged by a Device on each interface
+// of its introspection, as DeviceInterfaceStats. This is lighter than GetDeviceDetails for monitoring agents:
+// the request asks Astarte to only return the introspection, on versions which support field filtering, and only the
+// introspection is parsed from the response.
+func (c *Client) GetDeviceInterfaceStats(realm string, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType) (AstarteRequest, error) {
+	resolvedDeviceIdentifierType, err := c.resolveDeviceIdentifier(deviceIdentifier, deviceIdentifierType)
+	if err != nil {
+		return Empty{}, err
+	}
+	callURL := urlbuilder.Build(c.appEngineURL, "/v1/%s/%s", realm, devicePath(deviceIdentifier, resolvedDeviceIdentifierType))
+	callURL.RawQuery = url.Values{"fields": []string{"introspection"}}.Encode()
+	req := c.makeHTTPrequest(http.MethodGet, callURL, nil)
+
+	return GetDeviceInterfaceStatsRequest{req: req, expects: []int{http.StatusOK}}, nil
+}
+
+// nolint:bodyclose
+func (r GetDeviceInterfaceStatsRequest) Run(c *Client) (AstarteResponse, error) {
+	res, err := c.do(r.req)
+	if err != nil {
+		return Empty{}, err
+	}
+	if !c.isExpectedStatusCode(res, r.expects) {
+		return runAstarteRequestError(res, r.expects)
+	}
+	return GetDeviceInterfaceStatsResponse{res: res}, nil
+}
+
+func (r GetDeviceInterfaceStatsRequest) ToCurl(_ *Client) string {
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	return fmt.Sprint(command)
 }
 
 type GetDeviceIDFromAliasRequest struct {
 	req     *http.Request
-	expects int
+	expects []int
 }
 
 // GetDeviceIDFromAlias builds a request to return the Device ID of a device given one of its aliases.
@@ -101,44 +147,47 @@ func (c *Client) GetDeviceIDFromAlias(realm string, deviceAlias string) (Astarte
 
 // nolint:bodyclose
 func (r GetDeviceIDFromAliasRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return runAstarteRequestError(res, r.expects)
 	}
 	return GetDeviceIDFromAliasResponse{res: res}, nil
 }
 
 func (r GetDeviceIDFromAliasRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	// TODO check
 	return fmt.Sprintf("%s | grep 'DeviceID'\n", command)
 }
 
 type ListDeviceInterfacesRequest struct {
 	req     *http.Request
-	expects int
+	expects []int
 }
 
 // ListDeviceInterfaces builds a request to retrieve the list of interfaces exposed by the Device's introspection.
 func (c *Client) ListDeviceInterfaces(realm string, deviceIdentifier string,
 	deviceIdentifierType DeviceIdentifierType) (AstarteRequest, error) {
-	resolvedDeviceIdentifierType := resolveDeviceIdentifierType(deviceIdentifier, deviceIdentifierType)
-	callURL := makeURL(c.appEngineURL, "/v1/%s/%s/interfaces", realm, devicePath(deviceIdentifier, resolvedDeviceIdentifierType))
+	resolvedDeviceIdentifierType, err := c.resolveDeviceIdentifier(deviceIdentifier, deviceIdentifierType)
+	if err != nil {
+		return Empty{}, err
+	}
+	callURL := urlbuilder.Build(c.appEngineURL, "/v1/%s/%s/interfaces", realm, devicePath(deviceIdentifier, resolvedDeviceIdentifierType))
 	req := c.makeHTTPrequest(http.MethodGet, callURL, nil)
 
-	return ListDeviceInterfacesRequest{req: req, expects: 200}, nil
+	return ListDeviceInterfacesRequest{req: req, expects: []int{http.StatusOK}}, nil
 }
 
 // nolint:bodyclose
 func (r ListDeviceInterfacesRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return runAstarteRequestError(res, r.expects)
 	}
 	return ListDeviceInterfacesResponse{res: res}, nil
@@ -150,37 +199,37 @@ func (r ListDeviceInterfacesRequest) ToCurl(_ *Client) string {
 
 type GetDevicesStatsRequest struct {
 	req     *http.Request
-	expects int
+	expects []int
 }
 
 // GetDevicesStats builds a request to return the DevicesStats of a Realm.
 func (c *Client) GetDevicesStats(realm string) (AstarteRequest, error) {
-	callURL := makeURL(c.appEngineURL, "/v1/%s/stats/devices", realm)
+	callURL := urlbuilder.Build(c.appEngineURL, "/v1/%s/stats/devices", realm)
 	req := c.makeHTTPrequest(http.MethodGet, callURL, nil)
 
-	return GetDevicesStatsRequest{req: req, expects: 200}, nil
+	return GetDevicesStatsRequest{req: req, expects: []int{http.StatusOK}}, nil
 }
 
 // nolint:bodyclose
 func (r GetDevicesStatsRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return runAstarteRequestError(res, r.expects)
 	}
 	return GetDeviceStatsResponse{res: res}, nil
 }
 
 func (r GetDevicesStatsRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	return fmt.Sprint(command)
 }
 
 type ListDeviceAliasesRequest struct {
 	req     *http.Request
-	expects int
+	expects []int
 }
 
 // ListDeviceAliases builds a request to list all aliases of a Device.
@@ -196,44 +245,44 @@ func (c *Client) ListDeviceAliases(realm string, deviceIdentifier string,
 
 // nolint:bodyclose
 func (r ListDeviceAliasesRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return runAstarteRequestError(res, r.expects)
 	}
 	return ListDeviceAliasesResponse{res: res}, nil
 }
 
 func (r ListDeviceAliasesRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	// TODO check
 	return fmt.Sprintf("%s | grep 'Aliases'\n", command)
 }
 
 type AddDeviceAliasRequest struct {
 	req     *http.Request
-	expects int
+	expects []int
 }
 
 // AddDeviceAlias builds a request to add an Alias to a Device
 func (c *Client) AddDeviceAlias(realm string, deviceID string, aliasTag string, deviceAlias string) (AstarteRequest, error) {
-	callURL := makeURL(c.appEngineURL, "/v1/%s/devices/%s", realm, deviceID)
+	callURL := urlbuilder.Build(c.appEngineURL, "/v1/%s/devices/%s", realm, deviceID)
 	aliasMap := map[string]map[string]string{"aliases": {aliasTag: deviceAlias}}
 	payload, _ := makeBody(aliasMap)
 	req := c.makeHTTPrequestWithContentType(http.MethodPatch, callURL, payload, "application/merge-patch+json")
 
-	return AddDeviceAliasRequest{req: req, expects: 200}, nil
+	return AddDeviceAliasRequest{req: req, expects: []int{http.StatusOK, http.StatusNoContent}}, nil
 }
 
 // nolint:bodyclose
 func (r AddDeviceAliasRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return runAstarteRequestError(res, r.expects)
 	}
 
@@ -241,68 +290,71 @@ func (r AddDeviceAliasRequest) Run(c *Client) (AstarteResponse, error) {
 }
 
 func (r AddDeviceAliasRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	return fmt.Sprint(command)
 }
 
 type DeleteDeviceAliasRequest struct {
 	req     *http.Request
-	expects int
+	expects []int
 }
 
 // DeleteDeviceAlias builds a request to delete an Alias from a Device based on the Alias' tag.
 func (c *Client) DeleteDeviceAlias(realm string, deviceID string, aliasTag string) (AstarteRequest, error) {
-	callURL := makeURL(c.appEngineURL, "/v1/%s/devices/%s", realm, deviceID)
+	callURL := urlbuilder.Build(c.appEngineURL, "/v1/%s/devices/%s", realm, deviceID)
 	// We're using map[string]interface{} rather than map[string]string since we want to have null
 	// rather than an empty string in the JSON payload, and this is the only way.
 	aliasMap := map[string]map[string]interface{}{"aliases": {aliasTag: nil}}
 	payload, _ := makeBody(aliasMap)
 	req := c.makeHTTPrequestWithContentType(http.MethodPatch, callURL, payload, "application/merge-patch+json")
 
-	return DeleteDeviceAliasRequest{req: req, expects: 200}, nil
+	return DeleteDeviceAliasRequest{req: req, expects: []int{http.StatusOK, http.StatusNoContent}}, nil
 }
 
 // nolint:bodyclose
 func (r DeleteDeviceAliasRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return runAstarteRequestError(res, r.expects)
 	}
 	return NoDataResponse{res: res}, nil
 }
 
 func (r DeleteDeviceAliasRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	// TODO check
 	return fmt.Sprint(command)
 }
 
 type InhibitDeviceRequest struct {
 	req     *http.Request
-	expects int
+	expects []int
 }
 
 // SetDeviceInhibited builds a request to set the Credentials Inhibition state of a Device.
 func (c *Client) SetDeviceInhibited(realm string, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, inhibit bool) (AstarteRequest, error) {
-	resolvedDeviceIdentifierType := resolveDeviceIdentifierType(deviceIdentifier, deviceIdentifierType)
-	callURL := makeURL(c.appEngineURL, "/v1/%s/%s", realm, devicePath(deviceIdentifier, resolvedDeviceIdentifierType))
+	resolvedDeviceIdentifierType, err := c.resolveDeviceIdentifier(deviceIdentifier, deviceIdentifierType)
+	if err != nil {
+		return Empty{}, err
+	}
+	callURL := urlbuilder.Build(c.appEngineURL, "/v1/%s/%s", realm, devicePath(deviceIdentifier, resolvedDeviceIdentifierType))
 	credentialsMap := map[string]bool{"credentials_inhibited": inhibit}
 	payload, _ := makeBody(credentialsMap)
 	req := c.makeHTTPrequestWithContentType(http.MethodPatch, callURL, payload, "application/merge-patch+json")
 
-	return InhibitDeviceRequest{req: req, expects: 200}, nil
+	return InhibitDeviceRequest{req: req, expects: []int{http.StatusOK, http.StatusNoContent}}, nil
 }
 
 // nolint:bodyclose
 func (r InhibitDeviceRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return runAstarteRequestError(res, r.expects)
 	}
 	// no response expected
@@ -310,14 +362,74 @@ func (r InhibitDeviceRequest) Run(c *Client) (AstarteResponse, error) {
 }
 
 func (r InhibitDeviceRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	// TODO check
 	return fmt.Sprint(command)
 }
 
+type PatchDeviceIntrospectionRequest struct {
+	req     *http.Request
+	expects []int
+	patch   IntrospectionPatch
+}
+
+// PatchDeviceIntrospection builds a request to change the introspection of a Device, e.g. to restore it after a
+// firmware rollback. The patch is validated before building the request.
+// WARNING: the introspection is declared by the device each time it connects, and Astarte uses it to route and
+// validate the data the device sends. Patching it from the server side is meant for recovery and administrative
+// tooling only: declaring interfaces the device does not actually use makes Astarte accept or expect data which
+// the device never sends. Not all Astarte versions allow this operation: AppEngine may accept the request and ignore
+// the introspection, hence Run checks the device details Astarte replies with, and returns
+// ErrIntrospectionNotPatched if they don't reflect the patch. On success, Run returns the updated device details.
+func (c *Client) PatchDeviceIntrospection(realm string, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, patch IntrospectionPatch) (AstarteRequest, error) {
+	if err := patch.Validate(); err != nil {
+		return Empty{}, err
+	}
+	resolvedDeviceIdentifierType, err := c.resolveDeviceIdentifier(deviceIdentifier, deviceIdentifierType)
+	if err != nil {
+		return Empty{}, err
+	}
+	callURL := urlbuilder.Build(c.appEngineURL, "/v1/%s/%s", realm, devicePath(deviceIdentifier, resolvedDeviceIdentifierType))
+	payload, _ := makeBody(patch.mergePatch())
+	req := c.makeHTTPrequestWithContentType(http.MethodPatch, callURL, payload, "application/merge-patch+json")
+
+	// The device details in the reply are needed to check that the patch was applied
+	return PatchDeviceIntrospectionRequest{req: req, expects: []int{http.StatusOK}, patch: patch}, nil
+}
+
+// nolint:bodyclose
+func (r PatchDeviceIntrospectionRequest) Run(c *Client) (AstarteResponse, error) {
+	res, err := c.do(r.req)
+	if err != nil {
+		return Empty{}, err
+	}
+	if !c.isExpectedStatusCode(res, r.expects) {
+		return runAstarteRequestError(res, r.expects)
+	}
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return Empty{}, err
+	}
+	payload, err := unmarshalAstartePayload(body, DeviceDetails{})
+	if err != nil {
+		return Empty{}, err
+	}
+	if err := r.patch.appliedTo(payload.Data.Introspection); err != nil {
+		return Empty{}, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(body))
+	return GetDeviceDetailsResponse{res: res}, nil
+}
+
+func (r PatchDeviceIntrospectionRequest) ToCurl(_ *Client) string {
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
+	return fmt.Sprint(command)
+}
+
 type ListDeviceAttributesRequest struct {
 	req     *http.Request
-	expects int
+	expects []int
 }
 
 // ListDeviceAttributes builds a request to list all Attributes of a Device.
@@ -332,85 +444,101 @@ func (c *Client) ListDeviceAttributes(realm, deviceIdentifier string, deviceIden
 
 // nolint:bodyclose
 func (r ListDeviceAttributesRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return runAstarteRequestError(res, r.expects)
 	}
 	return ListDeviceAttributesResponse{res: res}, nil
 }
 
 func (r ListDeviceAttributesRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	return fmt.Sprint(command)
 }
 
 type SetDeviceAttributeRequest struct {
 	req     *http.Request
-	expects int
+	expects []int
 }
 
-// SetDeviceAttribute builds a request to set an Attribute key to a certain value for a Device
+// SetDeviceAttribute builds a request to set an Attribute key to a certain value for a Device.
+// If the client has an AttributeSchema, the attribute must be valid according to it.
 func (c *Client) SetDeviceAttribute(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, attributeKey, attributeValue string) (AstarteRequest, error) {
-	resolvedDeviceIdentifierType := resolveDeviceIdentifierType(deviceIdentifier, deviceIdentifierType)
-	callURL := makeURL(c.appEngineURL, "/v1/%s/%s", realm, devicePath(deviceIdentifier, resolvedDeviceIdentifierType))
-	attributeMap := map[string]map[string]string{"attributes": {attributeKey: attributeValue}}
+	return c.SetDeviceAttributes(realm, deviceIdentifier, deviceIdentifierType, map[string]string{attributeKey: attributeValue})
+}
+
+// SetDeviceAttributes builds a request to set many Attribute keys to their values for a Device at once.
+// If the client has an AttributeSchema, all attributes must be valid according to it.
+func (c *Client) SetDeviceAttributes(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, attributes map[string]string) (AstarteRequest, error) {
+	if err := c.ValidateDeviceAttributes(attributes); err != nil {
+		return Empty{}, err
+	}
+	resolvedDeviceIdentifierType, err := c.resolveDeviceIdentifier(deviceIdentifier, deviceIdentifierType)
+	if err != nil {
+		return Empty{}, err
+	}
+	callURL := urlbuilder.Build(c.appEngineURL, "/v1/%s/%s", realm, devicePath(deviceIdentifier, resolvedDeviceIdentifierType))
+	attributeMap := map[string]map[string]string{"attributes": attributes}
 	payload, _ := makeBody(attributeMap)
 	req := c.makeHTTPrequestWithContentType(http.MethodPatch, callURL, payload, "application/merge-patch+json")
 
-	return SetDeviceAttributeRequest{req: req, expects: 200}, nil
+	return SetDeviceAttributeRequest{req: req, expects: []int{http.StatusOK, http.StatusNoContent}}, nil
 }
 
 // nolint:bodyclose
 func (r SetDeviceAttributeRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return runAstarteRequestError(res, r.expects)
 	}
 	return NoDataResponse{res: res}, nil
 }
 
 func (r SetDeviceAttributeRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	return fmt.Sprint(command)
 }
 
 type DeleteDeviceAttributeRequest struct {
 	req     *http.Request
-	expects int
+	expects []int
 }
 
 // DeleteDeviceAttribute builds a request to delete an Attribute key and its value from a Device
 func (c *Client) DeleteDeviceAttribute(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, attributeKey string) (AstarteRequest, error) {
-	resolvedDeviceIdentifierType := resolveDeviceIdentifierType(deviceIdentifier, deviceIdentifierType)
-	callURL := makeURL(c.appEngineURL, "/v1/%s/%s", realm, devicePath(deviceIdentifier, resolvedDeviceIdentifierType))
+	resolvedDeviceIdentifierType, err := c.resolveDeviceIdentifier(deviceIdentifier, deviceIdentifierType)
+	if err != nil {
+		return Empty{}, err
+	}
+	callURL := urlbuilder.Build(c.appEngineURL, "/v1/%s/%s", realm, devicePath(deviceIdentifier, resolvedDeviceIdentifierType))
 	// We're using map[string]interface{} rather than map[string]string since we want to have null
 	// rather than an empty string in the JSON payload, and this is the only way.
 	attributeMap := map[string]map[string]interface{}{"attributes": {attributeKey: nil}}
 	payload, _ := makeBody(attributeMap)
 	req := c.makeHTTPrequestWithContentType(http.MethodPatch, callURL, payload, "application/merge-patch+json")
 
-	return DeleteDeviceAttributeRequest{req: req, expects: 200}, nil
+	return DeleteDeviceAttributeRequest{req: req, expects: []int{http.StatusOK, http.StatusNoContent}}, nil
 }
 
 // nolint:bodyclose
 func (r DeleteDeviceAttributeRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return runAstarteRequestError(res, r.expects)
 	}
 	return NoDataResponse{res: res}, nil
 }
 
 func (r DeleteDeviceAttributeRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	return fmt.Sprint(command)
 }
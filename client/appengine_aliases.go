@@ -0,0 +1,142 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/astarte-platform/astarte-go/deviceid"
+	"github.com/astarte-platform/astarte-go/internal/runner"
+)
+
+const defaultAliasResolutionConcurrency = 10
+
+type aliasCacheKey struct {
+	realm string
+	alias string
+}
+
+// ResolveAliases resolves aliases to Device IDs, running up to concurrency requests at a time (10 if concurrency
+// is not positive). The first returned map maps the resolved aliases to their Device IDs, the second one maps
+// the aliases which could not be resolved to their error. Resolved aliases are cached in the Client: see ClearAliasCache.
+func (c *Client) ResolveAliases(realm string, aliases []string, concurrency int) (map[string]string, map[string]error) {
+	if concurrency <= 0 {
+		concurrency = defaultAliasResolutionConcurrency
+	}
+
+	resolved := map[string]string{}
+	errs := map[string]error{}
+	var mu sync.Mutex
+	forEachConcurrently(aliases, concurrency, func(_ int, alias string) {
+		deviceID, err := c.resolveAlias(realm, alias)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			errs[alias] = err
+			return
+		}
+		resolved[alias] = deviceID
+	})
+
+	return resolved, errs
+}
+
+// ClearAliasCache removes all Device IDs cached by ResolveAliases. This is needed when aliases
+// are moved to different devices.
+func (c *Client) ClearAliasCache() {
+	c.aliasCache.Range(func(key, _ any) bool {
+		c.aliasCache.Delete(key)
+		return true
+	})
+}
+
+func (c *Client) resolveAlias(realm, alias string) (string, error) {
+	key := aliasCacheKey{realm: realm, alias: alias}
+	if cached, ok := c.aliasCache.Load(key); ok {
+		return cached.(string), nil
+	}
+
+	getDeviceIDCall, err := c.GetDeviceIDFromAlias(realm, alias)
+	if err != nil {
+		return "", err
+	}
+	deviceID, err := runner.RunAndParse[string](c, getDeviceIDCall.Run)
+	if err != nil {
+		return "", err
+	}
+	c.aliasCache.Store(key, deviceID)
+	return deviceID, nil
+}
+
+// DeviceResolution reports which devices an identifier refers to, see ResolveDevice.
+type DeviceResolution struct {
+	Identifier string
+	// ByDeviceID is the Device ID of the device having Identifier as Device ID, if any.
+	ByDeviceID string
+	// ByAlias is the Device ID of the device having Identifier as alias, if any.
+	ByAlias string
+}
+
+// DeviceID returns the Device ID of the device the identifier refers to. It fails with ErrAmbiguousIdentifier
+// if the identifier is the Device ID of a device and the alias of another one, and with ErrDeviceNotFound if
+// it refers to no device.
+func (r DeviceResolution) DeviceID() (string, error) {
+	switch {
+	case r.ByDeviceID != "" && r.ByAlias != "" && r.ByDeviceID != r.ByAlias:
+		return "", fmt.Errorf("%w: %s", ErrAmbiguousIdentifier, r.Identifier)
+	case r.ByDeviceID != "":
+		return r.ByDeviceID, nil
+	case r.ByAlias != "":
+		return r.ByAlias, nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrDeviceNotFound, r.Identifier)
+}
+
+// ResolveDevice looks up identifier both as a Device ID, if it is a valid one, and as an alias, and reports
+// what matched. Use it to disambiguate identifiers rejected with ErrAmbiguousIdentifier.
+func (c *Client) ResolveDevice(realm, identifier string) (DeviceResolution, error) {
+	resolution := DeviceResolution{Identifier: identifier}
+	if deviceid.IsValid(identifier) {
+		getDeviceDetailsCall, err := c.GetDeviceDetails(realm, identifier, AstarteDeviceID)
+		if err != nil {
+			return resolution, err
+		}
+		details, err := runner.RunAndParse[DeviceDetails](c, getDeviceDetailsCall.Run)
+		if err != nil && !isNotFound(err) {
+			return resolution, err
+		}
+		resolution.ByDeviceID = details.DeviceID
+	}
+
+	getDeviceIDCall, err := c.GetDeviceIDFromAlias(realm, identifier)
+	if err != nil {
+		return resolution, err
+	}
+	deviceID, err := runner.RunAndParse[string](c, getDeviceIDCall.Run)
+	if err != nil && !isNotFound(err) {
+		return resolution, err
+	}
+	resolution.ByAlias = deviceID
+	return resolution, nil
+}
+
+func isNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,109 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+)
+
+// AttributeValidator checks the value of a Device attribute, returning an error if it is not valid.
+type AttributeValidator func(value string) error
+
+// AttributeRule associates the attribute keys matching KeyPattern with the Validator for their values.
+// A nil Validator accepts any value.
+type AttributeRule struct {
+	KeyPattern *regexp.Regexp
+	Validator  AttributeValidator
+}
+
+// AttributeSchema describes the Device attributes used in an organization. When set on a client
+// with WithAttributeSchema, attributes are checked against it before building requests which set them.
+type AttributeSchema struct {
+	// Rules are checked in order: a value must be accepted by the validators of all rules matching its key.
+	Rules []AttributeRule
+	// RejectUnknownKeys makes attribute keys which do not match any rule invalid.
+	RejectUnknownKeys bool
+}
+
+// MatchValue returns an AttributeValidator accepting values which match pattern.
+func MatchValue(pattern *regexp.Regexp) AttributeValidator {
+	return func(value string) error {
+		if !pattern.MatchString(value) {
+			return ErrAttributeValueMismatch(pattern)
+		}
+		return nil
+	}
+}
+
+// OneOfValues returns an AttributeValidator accepting only the provided values.
+func OneOfValues(values ...string) AttributeValidator {
+	return func(value string) error {
+		for _, v := range values {
+			if value == v {
+				return nil
+			}
+		}
+		return ErrAttributeValueNotAllowed(values)
+	}
+}
+
+// Validate checks attributes against the schema. If some of them are not valid, the returned error joins
+// an *AttributeSchemaViolation for each of them, sorted by key.
+func (s AttributeSchema) Validate(attributes map[string]string) error {
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	errs := []error{}
+	for _, key := range keys {
+		if err := s.validateAttribute(key, attributes[key]); err != nil {
+			errs = append(errs, &AttributeSchemaViolation{Key: key, Value: attributes[key], Err: err})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s AttributeSchema) validateAttribute(key, value string) error {
+	matched := false
+	for _, rule := range s.Rules {
+		if rule.KeyPattern == nil || !rule.KeyPattern.MatchString(key) {
+			continue
+		}
+		matched = true
+		if rule.Validator == nil {
+			continue
+		}
+		if err := rule.Validator(value); err != nil {
+			return err
+		}
+	}
+	if !matched && s.RejectUnknownKeys {
+		return ErrUnknownAttributeKey
+	}
+	return nil
+}
+
+// ValidateDeviceAttributes checks attributes against the AttributeSchema of the client, if any.
+// SetDeviceAttribute and SetDeviceAttributes call it before building their requests.
+func (c *Client) ValidateDeviceAttributes(attributes map[string]string) error {
+	if c.attributeSchema == nil {
+		return nil
+	}
+	return c.attributeSchema.Validate(attributes)
+}
@@ -0,0 +1,187 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/deviceid"
+	"github.com/astarte-platform/astarte-go/interfaces"
+	"github.com/astarte-platform/astarte-go/internal/urlbuilder"
+)
+
+const (
+	defaultBroadcastConcurrency = 10
+	defaultBroadcastRetries     = 2
+	defaultBroadcastBackoff     = 500 * time.Millisecond
+)
+
+// BroadcastResult is the result of sending data to a single device with BroadcastData.
+type BroadcastResult struct {
+	DeviceID string
+	// Attempts is the number of requests which were sent to the device, including retries.
+	Attempts int
+	// Err is set if the data could not be sent to the device.
+	Err error
+}
+
+type broadcast struct {
+	concurrency int
+	rate        float64
+	retries     int
+	backoff     time.Duration
+}
+
+type broadcastOption func(*broadcast)
+
+// Sets the maximum number of devices data is sent to concurrently. Defaults to 10.
+// nolint:golint,revive
+func WithBroadcastConcurrency(concurrency int) broadcastOption {
+	return func(b *broadcast) {
+		b.concurrency = concurrency
+	}
+}
+
+// Sets the maximum number of requests sent per second, across all devices. Defaults to 0, meaning no limit.
+// nolint:golint,revive
+func WithBroadcastRate(requestsPerSecond float64) broadcastOption {
+	return func(b *broadcast) {
+		b.rate = requestsPerSecond
+	}
+}
+
+// Sets how many times sending data to a device is retried, and how long to wait before the first retry. The wait
+// is doubled at each retry. Defaults to 2 retries, starting after 500ms. Only failures ensuring that Astarte did
+// not receive the data are retried, so that data is never sent twice: a 429 Too Many Requests response, or an
+// error connecting to Astarte. Other failures, such as a 5xx response or a connection closed while waiting for
+// the response, are reported in the BroadcastResult of the device.
+// nolint:golint,revive
+func WithBroadcastRetries(retries int, backoff time.Duration) broadcastOption {
+	return func(b *broadcast) {
+		b.retries = retries
+		b.backoff = backoff
+	}
+}
+
+// BroadcastData sends the same payload on interfacePath of astarteInterface to all devices in deviceIDs, e.g. to
+// push a configuration to a segment of the fleet. payload is validated once as SendData does, and if it is invalid
+// no data is sent. Data is then sent once to each device, even if it is repeated in deviceIDs, concurrently and
+// optionally limiting the rate of the requests, and failures before sending the data are retried (see
+// WithBroadcastRetries). The outcome for each device is returned as a map of device IDs to BroadcastResult: failing
+// to send data to a device does not stop the others. The returned error is set only if the broadcast itself is
+// invalid.
+func (c *Client) BroadcastData(realm string, deviceIDs []string, astarteInterface interfaces.AstarteInterface, interfacePath string,
+	payload any, opts ...broadcastOption) (map[string]BroadcastResult, error) {
+	b := broadcast{concurrency: defaultBroadcastConcurrency, retries: defaultBroadcastRetries, backoff: defaultBroadcastBackoff}
+	for _, f := range opts {
+		f(&b)
+	}
+	if b.concurrency <= 0 || b.rate < 0 || b.retries < 0 || b.backoff < 0 {
+		return nil, errors.New("Concurrency must be strictly positive, rate, retries and backoff must not be negative")
+	}
+	for _, deviceID := range deviceIDs {
+		if !deviceid.IsValid(deviceID) {
+			return nil, ErrInvalidDeviceID(deviceID)
+		}
+	}
+	if err := c.validateSendData(astarteInterface, interfacePath, payload); err != nil {
+		return nil, err
+	}
+
+	limiter := newRateLimiter(b.rate)
+	deviceIDs = uniqueStrings(deviceIDs)
+	sent := make([]BroadcastResult, len(deviceIDs))
+	forEachConcurrently(deviceIDs, b.concurrency, func(i int, deviceID string) {
+		result := BroadcastResult{DeviceID: deviceID}
+		backoff := b.backoff
+		for {
+			limiter.wait()
+			result.Attempts++
+			result.Err = c.sendBroadcastData(realm, deviceID, astarteInterface, interfacePath, payload)
+			if result.Err == nil || result.Attempts > b.retries || !isRetriableBeforeSend(result.Err) {
+				break
+			}
+			c.observeRetry(urlbuilder.Build(c.appEngineURL, "/v1/%s/devices/%s/interfaces/%s%s", realm, deviceID, astarteInterface.Name, urlbuilder.Path(interfacePath)),
+				RetryTransientFailure)
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		sent[i] = result
+	})
+
+	results := make(map[string]BroadcastResult, len(sent))
+	for _, result := range sent {
+		results[result.DeviceID] = result
+	}
+
+	return results, nil
+}
+
+func (c *Client) sendBroadcastData(realm, deviceID string, astarteInterface interfaces.AstarteInterface, interfacePath string, payload any) error {
+	sendDataCall, err := c.sendValidatedData(realm, deviceID, AstarteDeviceID, astarteInterface, interfacePath, payload)
+	if err != nil {
+		return err
+	}
+	return runWithoutParsing(c, sendDataCall)
+}
+
+// isRetriableBeforeSend returns whether err is a transient failure which ensures that the request was not processed,
+// so that it can be retried without duplicating data: a 429 Too Many Requests response, or a failure to connect.
+func isRetriableBeforeSend(err error) bool {
+	var apiError *APIError
+	if errors.As(err, &apiError) {
+		return apiError.StatusCode == http.StatusTooManyRequests
+	}
+	var opError *net.OpError
+	if errors.As(err, &opError) && opError.Op == "dial" {
+		return true
+	}
+	var dnsError *net.DNSError
+	return errors.As(err, &dnsError)
+}
+
+// rateLimiter spaces out the requests of many goroutines so that at most rate requests are sent per second.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newRateLimiter(rate float64) *rateLimiter {
+	if rate == 0 {
+		return &rateLimiter{}
+	}
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / rate)}
+}
+
+// wait blocks until the next request can be sent.
+func (l *rateLimiter) wait() {
+	if l.interval == 0 {
+		return
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+	time.Sleep(delay)
+}
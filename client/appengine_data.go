@@ -22,6 +22,7 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -39,6 +40,7 @@ type Paginator interface {
 
 	computePageState(rawData []byte)
 	parseData(rawData []byte) any
+	astarteClient() *Client
 }
 
 // DeviceResultFormat represents the format of the Device returned in the Device list.
@@ -88,6 +90,43 @@ type DeviceDetails struct {
 	Aliases                  map[string]string                       `json:"aliases"`
 	PreviousInterfaces       []DeviceInterfaceIntrospection          `json:"previous_interfaces,omitempty"`
 	Attributes               map[string]string                       `json:"attributes,omitempty"`
+	Groups                   []string                                `json:"groups,omitempty"`
+}
+
+// InterfaceStats returns the messages and bytes exchanged by the device on each interface of its introspection.
+func (d DeviceDetails) InterfaceStats() DeviceInterfaceStats {
+	return DeviceInterfaceStats(d.Introspection)
+}
+
+// DeviceInterfaceStats maps the interfaces in the introspection of a device to the messages and bytes exchanged on them.
+type DeviceInterfaceStats map[string]DeviceInterfaceIntrospection
+
+// ExchangedMessages returns the number of messages exchanged on interfaceName, or 0 if it is not in the introspection.
+func (s DeviceInterfaceStats) ExchangedMessages(interfaceName string) uint64 {
+	return s[interfaceName].ExchangedMessages
+}
+
+// ExchangedBytes returns the number of bytes exchanged on interfaceName, or 0 if it is not in the introspection.
+func (s DeviceInterfaceStats) ExchangedBytes(interfaceName string) uint64 {
+	return s[interfaceName].ExchangedBytes
+}
+
+// TotalExchangedMessages returns the number of messages exchanged on all interfaces.
+func (s DeviceInterfaceStats) TotalExchangedMessages() uint64 {
+	total := uint64(0)
+	for _, introspection := range s {
+		total += introspection.ExchangedMessages
+	}
+	return total
+}
+
+// TotalExchangedBytes returns the number of bytes exchanged on all interfaces.
+func (s DeviceInterfaceStats) TotalExchangedBytes() uint64 {
+	total := uint64(0)
+	for _, introspection := range s {
+		total += introspection.ExchangedBytes
+	}
+	return total
 }
 
 // DevicesStats maps to the JSON object returned by a Device Stats call to AppEngine API.
@@ -139,22 +178,13 @@ func (r GetNextDeviceListPageResponse) Raw(f func(*http.Response) any) any {
 }
 
 func (d *DeviceListPaginator) parseData(rawData []byte) any {
-	data := gjson.GetBytes(rawData, "data").Array()
 	switch d.format {
 	case DeviceIDFormat:
-		ret := []string{}
-		for _, v := range data {
-			ret = append(ret, v.Str)
-		}
-		return ret
+		payload, _ := unmarshalAstartePayload(rawData, []string{})
+		return payload.Data
 	case DeviceDetailsFormat:
-		ret := []DeviceDetails{}
-		for _, v := range data {
-			details := DeviceDetails{}
-			_ = json.Unmarshal([]byte(v.Raw), &details)
-			ret = append(ret, details)
-		}
-		return ret
+		payload, _ := unmarshalAstartePayload(rawData, []DeviceDetails{})
+		return payload.Data
 	// we'll never get there as there are only 2 formats
 	default:
 		return nil
@@ -162,14 +192,23 @@ func (d *DeviceListPaginator) parseData(rawData []byte) any {
 }
 
 func (d *DeviceListPaginator) computePageState(rawData []byte) {
-	links := Links{}
-	_ = json.Unmarshal(rawData, &links)
-	if links.Next == "" {
+	payload, _ := unmarshalAstartePayload(rawData, json.RawMessage{})
+	d.pageIndex++
+	d.fetchedItems += len(gjson.GetBytes(rawData, "data").Array())
+	// Astarte versions supporting it report the total number of devices in the page metadata
+	if total := gjson.GetBytes(rawData, "meta.total_count"); total.Exists() {
+		d.totalItems, d.hasTotalItems = total.Int(), true
+	}
+	if payload.Links == nil || payload.Links.Next == "" {
 		d.hasNextPage = false
 	} else {
 		d.hasNextPage = true
-		parsedLinks, _ := url.Parse(links.Next)
+		parsedLinks, _ := url.Parse(payload.Links.Next)
 		d.nextQuery = parsedLinks.Query()
+		// The next link holds the limit Astarte actually applied, which might be lower than the requested one
+		if limit, err := strconv.Atoi(d.nextQuery.Get("limit")); err == nil && limit > 0 && limit < d.pageSize {
+			d.pageSize = limit
+		}
 	}
 }
 
@@ -177,11 +216,11 @@ func (d *DeviceListPaginator) computePageState(rawData []byte) {
 // Returns the device ID as a string.
 func (r GetDeviceIDFromAliasResponse) Parse() (any, error) {
 	defer r.res.Body.Close()
-	b, _ := io.ReadAll(r.res.Body)
-	data := gjson.GetBytes(b, "data")
-	details := DeviceDetails{}
-	_ = json.Unmarshal([]byte(data.Raw), &details)
-	return details.DeviceID, nil
+	payload, err := decodeAstartePayload(r.res.Body, DeviceDetails{})
+	if err != nil {
+		return nil, err
+	}
+	return payload.Data.DeviceID, nil
 }
 
 func (r GetDeviceIDFromAliasResponse) Raw(f func(*http.Response) any) any {
@@ -193,11 +232,11 @@ func (r GetDeviceIDFromAliasResponse) Raw(f func(*http.Response) any) any {
 // Returns details as a DeviceDetails structure.
 func (r GetDeviceDetailsResponse) Parse() (any, error) {
 	defer r.res.Body.Close()
-	b, _ := io.ReadAll(r.res.Body)
-	data := gjson.GetBytes(b, "data")
-	details := DeviceDetails{}
-	_ = json.Unmarshal([]byte(data.Raw), &details)
-	return details, nil
+	payload, err := decodeAstartePayload(r.res.Body, DeviceDetails{})
+	if err != nil {
+		return nil, err
+	}
+	return payload.Data, nil
 }
 
 func (r GetDeviceDetailsResponse) Raw(f func(*http.Response) any) any {
@@ -205,17 +244,36 @@ func (r GetDeviceDetailsResponse) Raw(f func(*http.Response) any) any {
 	return f(r.res)
 }
 
+// Parses data obtained by performing a request for the interface stats of a device.
+// Returns the stats as a DeviceInterfaceStats map, ignoring all other device details.
+func (r GetDeviceInterfaceStatsResponse) Parse() (any, error) {
+	defer r.res.Body.Close()
+	payload, err := decodeAstartePayload(r.res.Body, struct {
+		Introspection DeviceInterfaceStats `json:"introspection"`
+	}{})
+	if err != nil {
+		return nil, err
+	}
+	if payload.Data.Introspection == nil {
+		return DeviceInterfaceStats{}, nil
+	}
+	return payload.Data.Introspection, nil
+}
+
+func (r GetDeviceInterfaceStatsResponse) Raw(f func(*http.Response) any) any {
+	defer r.res.Body.Close()
+	return f(r.res)
+}
+
 // Parses data obtained by performing a request a device introspection.
 // Returns the list of interface names as an array of strings.
 func (r ListDeviceInterfacesResponse) Parse() (any, error) {
 	defer r.res.Body.Close()
-	b, _ := io.ReadAll(r.res.Body)
-	data := gjson.GetBytes(b, "data").Array()
-	interfaces := []string{}
-	for _, v := range data {
-		interfaces = append(interfaces, v.Str)
+	payload, err := decodeAstartePayload(r.res.Body, []string{})
+	if err != nil {
+		return nil, err
 	}
-	return interfaces, nil
+	return payload.Data, nil
 }
 
 func (r ListDeviceInterfacesResponse) Raw(f func(*http.Response) any) any {
@@ -227,13 +285,11 @@ func (r ListDeviceInterfacesResponse) Raw(f func(*http.Response) any) any {
 // Returns the list of aliases as a map strings to strings.
 func (r ListDeviceAliasesResponse) Parse() (any, error) {
 	defer r.res.Body.Close()
-	b, _ := io.ReadAll(r.res.Body)
-	data := gjson.GetBytes(b, "data.aliases").Map()
-	aliases := map[string]string{}
-	for k, v := range data {
-		aliases[k] = v.Str
+	payload, err := decodeAstartePayload(r.res.Body, DeviceDetails{Aliases: map[string]string{}})
+	if err != nil {
+		return nil, err
 	}
-	return aliases, nil
+	return payload.Data.Aliases, nil
 }
 
 func (r ListDeviceAliasesResponse) Raw(f func(*http.Response) any) any {
@@ -245,13 +301,11 @@ func (r ListDeviceAliasesResponse) Raw(f func(*http.Response) any) any {
 // Returns the attributes as a map strings to strings.
 func (r ListDeviceAttributesResponse) Parse() (any, error) {
 	defer r.res.Body.Close()
-	b, _ := io.ReadAll(r.res.Body)
-	data := gjson.GetBytes(b, "data.attributes").Map()
-	attributes := map[string]string{}
-	for k, v := range data {
-		attributes[k] = v.Str
+	payload, err := decodeAstartePayload(r.res.Body, DeviceDetails{Attributes: map[string]string{}})
+	if err != nil {
+		return nil, err
 	}
-	return attributes, nil
+	return payload.Data.Attributes, nil
 }
 
 func (r ListDeviceAttributesResponse) Raw(f func(*http.Response) any) any {
@@ -263,11 +317,11 @@ func (r ListDeviceAttributesResponse) Raw(f func(*http.Response) any) any {
 // Returns the stats as a DevicesStats struct.
 func (r GetDeviceStatsResponse) Parse() (any, error) {
 	defer r.res.Body.Close()
-	b, _ := io.ReadAll(r.res.Body)
-	data := gjson.GetBytes(b, "data")
-	stats := DevicesStats{}
-	_ = json.Unmarshal([]byte(data.Raw), &stats)
-	return stats, nil
+	payload, err := decodeAstartePayload(r.res.Body, DevicesStats{})
+	if err != nil {
+		return nil, err
+	}
+	return payload.Data, nil
 }
 
 func (r GetDeviceStatsResponse) Raw(f func(*http.Response) any) any {
@@ -282,10 +336,39 @@ type DatastreamIndividualValue struct {
 	ReceptionTimestamp time.Time   `json:"reception_timestamp,omitempty"`
 }
 
+// UnmarshalJSON unmarshals a DatastreamIndividualValue, preserving the sub-second precision of its timestamps.
+func (v *DatastreamIndividualValue) UnmarshalJSON(b []byte) error {
+	return v.unmarshalJSON(b, false)
+}
+
+// unmarshalJSON unmarshals a DatastreamIndividualValue, decoding numbers as json.Number if preciseNumbers is set,
+// see WithPreciseNumbers.
+func (v *DatastreamIndividualValue) unmarshalJSON(b []byte, preciseNumbers bool) error {
+	var raw struct {
+		Value              any `json:"value"`
+		Timestamp          any `json:"timestamp"`
+		ReceptionTimestamp any `json:"reception_timestamp"`
+	}
+	if err := unmarshalNumbers(b, &raw, preciseNumbers); err != nil {
+		return err
+	}
+	timestamp, err := parseTimestamp(raw.Timestamp)
+	if err != nil {
+		return err
+	}
+	receptionTimestamp, err := parseTimestamp(raw.ReceptionTimestamp)
+	if err != nil {
+		return err
+	}
+	*v = DatastreamIndividualValue{Value: raw.Value, Timestamp: timestamp, ReceptionTimestamp: receptionTimestamp}
+	return nil
+}
+
 // DatastreamIndividualValue represent one Datastream value on an interface with Object aggregation.
 type DatastreamObjectValue struct {
-	Values    orderedmap.OrderedMap
-	Timestamp time.Time
+	Values             ObjectValues
+	Timestamp          time.Time
+	ReceptionTimestamp time.Time
 }
 
 // PropertyValue represent the Property value on a properties interface.
@@ -293,31 +376,79 @@ type PropertyValue any
 
 // UnmarshalJSON unmarshals a quoted json string to a DatastreamObjectValue
 func (s *DatastreamObjectValue) UnmarshalJSON(b []byte) error {
+	return s.unmarshalJSON(b, false)
+}
+
+// unmarshalJSON unmarshals a DatastreamObjectValue, decoding numbers as json.Number if preciseNumbers is set,
+// see WithPreciseNumbers.
+func (s *DatastreamObjectValue) unmarshalJSON(b []byte, preciseNumbers bool) error {
 	var j orderedmap.OrderedMap
 	err := json.Unmarshal(b, &j)
 	if err != nil {
 		return err
 	}
+	if preciseNumbers {
+		// orderedmap always decodes numbers as float64, keep its order but take the values from a precise decoding
+		values := map[string]any{}
+		if err := unmarshalNumbers(b, &values, true); err != nil {
+			return err
+		}
+		for _, k := range j.Keys() {
+			j.Set(k, values[k])
+		}
+	}
 
 	// just to check that JSON did not curse the timestamo
 	timestampInterface, _ := j.Get("timestamp")
-	switch v := timestampInterface.(type) {
-	case time.Time:
-		s.Timestamp = v
-	case string:
-		var err error
-		s.Timestamp, err = time.Parse(time.RFC3339Nano, v)
-		if err != nil {
-			return err
-		}
+	if s.Timestamp, err = parseTimestamp(timestampInterface); err != nil {
+		return err
+	}
+	receptionTimestampInterface, _ := j.Get("reception_timestamp")
+	if s.ReceptionTimestamp, err = parseTimestamp(receptionTimestampInterface); err != nil {
+		return err
 	}
 
 	j.Delete("timestamp")
-	s.Values = j
+	j.Delete("reception_timestamp")
+	s.Values = objectValuesFromOrderedMap(&j)
 
 	return nil
 }
 
+// unmarshalNumbers unmarshals b into v, decoding numbers as json.Number if preciseNumbers is set.
+func unmarshalNumbers(b []byte, v any, preciseNumbers bool) error {
+	if !preciseNumbers {
+		return json.Unmarshal(b, v)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// parseTimestamp parses a timestamp returned by Astarte, preserving its sub-second precision.
+// Timestamps are usually RFC3339 strings, but numbers of milliseconds since the epoch are accepted too.
+// A missing timestamp is parsed as the zero time.
+func parseTimestamp(timestamp any) (time.Time, error) {
+	switch v := timestamp.(type) {
+	case nil:
+		return time.Time{}, nil
+	case time.Time:
+		return v, nil
+	case string:
+		return time.Parse(time.RFC3339Nano, v)
+	case float64:
+		return time.UnixMilli(int64(v)).UTC(), nil
+	case json.Number:
+		millis, err := v.Int64()
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.UnixMilli(millis).UTC(), nil
+	default:
+		return time.Time{}, fmt.Errorf("Invalid timestamp: %v", timestamp)
+	}
+}
+
 // Parses data obtained by performing a request for a DatastreamPaginator page
 // and sets up the paginator for retrieving the next page.
 // According to the interface's aggregation and path, the return value can be one of:
@@ -333,7 +464,14 @@ func (r GetNextDatastreamPageResponse) Parse() (any, error) {
 	data := paginator.parseData(b)
 	paginator.computePageState(b)
 
-	return data, nil
+	data, err := paginator.client.valueTransformer(paginator.interfaceName, paginator.interfacePath).transform(data)
+	if err != nil {
+		return nil, err
+	}
+	if data, err = keyByParameters(paginator.query.parameterKeys, paginator.interfacePath, data); err != nil {
+		return nil, err
+	}
+	return project(paginator.query.projection, data), nil
 }
 
 // Raw allows to supply a custom http Response handling function for the Astarte
@@ -350,34 +488,38 @@ func (r GetNextDatastreamPageResponse) Raw(f func(*http.Response) any) any {
 }
 
 func (d *DatastreamPaginator) parseData(rawData []byte) any {
-	data := gjson.GetBytes(rawData, "data").Raw
-	jsonData := gjson.ParseBytes([]byte(data))
-	return parseDatastream(jsonData, d.aggregation)
+	payload, _ := unmarshalAstartePayload(rawData, json.RawMessage{})
+	jsonData := gjson.ParseBytes(payload.Data)
+	return parseDatastream(jsonData, d.aggregation, d.query.preciseNumbers)
 }
 
-func parseDatastream(jsonData gjson.Result, aggregation interfaces.AstarteInterfaceAggregation) any {
+func parseDatastream(jsonData gjson.Result, aggregation interfaces.AstarteInterfaceAggregation, preciseNumbers bool) any {
 	// handle the case of individual aggregation
 	if aggregation == interfaces.IndividualAggregation {
-		return parseDatastreamWithIndividualAggregation(jsonData)
+		return parseDatastreamWithIndividualAggregation(jsonData, preciseNumbers)
 	}
 
 	// handle object aggregation
-	return parseDatastreamWithObjectAggregation(jsonData)
+	return parseDatastreamWithObjectAggregation(jsonData, preciseNumbers)
 }
 
-func parseDatastreamWithObjectAggregation(jsonData gjson.Result) any {
+func parseDatastreamWithObjectAggregation(jsonData gjson.Result, preciseNumbers bool) any {
 	if jsonData.IsArray() {
 		objectValues := []DatastreamObjectValue{}
 		data := jsonData.Array()
 		for _, v := range data {
 			value := DatastreamObjectValue{}
-			_ = json.Unmarshal([]byte(v.Raw), &value)
+			_ = value.unmarshalJSON([]byte(v.Raw), preciseNumbers)
 			objectValues = append(objectValues, value)
 		}
 		return objectValues
 	}
 	// if not an array, it must be an object
-	obj := jsonData.Value().(map[string]interface{})
+	obj, ok := jsonData.Value().(map[string]interface{})
+	if !ok {
+		// e.g. no data at all, as in the error body of an out of range page
+		return []DatastreamObjectValue{}
+	}
 
 	// now we need to flatten the object so that the common portion of the path can be factored out
 	// from each mapping
@@ -407,31 +549,39 @@ func parseDatastreamWithObjectAggregation(jsonData gjson.Result) any {
 		k := fmt.Sprintf("/%s", strings.ReplaceAll(keys[i], ".", "/"))
 
 		if item.IsArray() {
-			_ = json.Unmarshal([]byte(item.Raw), &values)
+			for _, v := range item.Array() {
+				value := DatastreamObjectValue{}
+				_ = value.unmarshalJSON([]byte(v.Raw), preciseNumbers)
+				values = append(values, value)
+			}
 			ret[k] = append(ret[k], values...)
 		} else {
-			_ = json.Unmarshal([]byte(item.Raw), &value)
+			_ = value.unmarshalJSON([]byte(item.Raw), preciseNumbers)
 			ret[k] = append(ret[k], value)
 		}
 	}
 	return ret
 }
 
-func parseDatastreamWithIndividualAggregation(jsonData gjson.Result) any {
+func parseDatastreamWithIndividualAggregation(jsonData gjson.Result, preciseNumbers bool) any {
 	// first, we check if the complete timeseries is returned
 	individualValues := []DatastreamIndividualValue{}
 	if jsonData.IsArray() {
 		data := jsonData.Array()
 		for _, v := range data {
 			value := DatastreamIndividualValue{}
-			_ = json.Unmarshal([]byte(v.Raw), &value)
+			_ = value.unmarshalJSON([]byte(v.Raw), preciseNumbers)
 			individualValues = append(individualValues, value)
 		}
 		return individualValues
 	}
 
 	// if it's not a timeseries, it must be a snapshot (objects are returned)
-	obj := jsonData.Value().(map[string]interface{})
+	obj, ok := jsonData.Value().(map[string]interface{})
+	if !ok {
+		// e.g. no data at all, as in the error body of an out of range page
+		return individualValues
+	}
 
 	// now we need to flatten the object so that the common portion of the path can be factored out
 	// from each mapping
@@ -451,7 +601,7 @@ func parseDatastreamWithIndividualAggregation(jsonData gjson.Result) any {
 	ret := map[string]DatastreamIndividualValue{}
 	for i, item := range rawIndividualValues {
 		value := DatastreamIndividualValue{}
-		_ = json.Unmarshal([]byte(item.Raw), &value)
+		_ = value.unmarshalJSON([]byte(item.Raw), preciseNumbers)
 		k := fmt.Sprintf("/%s", strings.ReplaceAll(keys[i], ".", "/"))
 		ret[k] = value
 	}
@@ -470,10 +620,15 @@ func removeDuplicateStr(strSlice []string) []string {
 	return list
 }
 
+// computePageState updates the paginator after a page was fetched. A next page exists if Astarte links one, or
+// otherwise if the page is full: since page sizes are clamped to the maximum page size of the client, a page
+// which is not full can't be the result of Astarte capping the limit.
 func (d *DatastreamPaginator) computePageState(rawData []byte) {
 	data := gjson.GetBytes(rawData, "data").Array()
 	resultLength := len(data)
-	if resultLength < d.pageSize {
+	d.pageIndex++
+	hasNextLink := gjson.GetBytes(rawData, "links.next").String() != ""
+	if resultLength == 0 || (resultLength < d.pageSize && !hasNextLink) {
 		d.hasNextPage = false
 	} else {
 		d.hasNextPage = true
@@ -509,46 +664,62 @@ func (d *DatastreamPaginator) updateTimestampValues(updateValue gjson.Result) {
 // depending on the requested interface's aggregation.
 func (r GetDatastreamSnapshotResponse) Parse() (any, error) {
 	defer r.res.Body.Close()
-	b, _ := io.ReadAll(r.res.Body)
-	return parseDatastreamSnapshot(b, r.aggregation)
+	payload, err := decodeAstartePayload(r.res.Body, json.RawMessage{})
+	if err != nil {
+		return nil, err
+	}
+	data, err := parseDatastreamSnapshot(payload.Data, r.aggregation, r.preciseNumbers)
+	if err != nil {
+		return nil, err
+	}
+	if data, err = r.transformer.transform(data); err != nil {
+		return nil, err
+	}
+	if data, err = keyByParameters(r.parameterKeys, "", data); err != nil {
+		return nil, err
+	}
+	return project(r.projection, data), nil
 }
 
-func parseDatastreamSnapshot(jsonValue []byte, aggregation interfaces.AstarteInterfaceAggregation) (any, error) {
-	// clean up useless prefix
-	data := gjson.GetBytes(jsonValue, "data")
+func parseDatastreamSnapshot(data []byte, aggregation interfaces.AstarteInterfaceAggregation, preciseNumbers bool) (any, error) {
 	if aggregation == interfaces.IndividualAggregation {
 		retMap := map[string]any{}
-		parseIndividualDatastreamSnapshot([]byte(data.Raw), "", retMap)
+		parseIndividualDatastreamSnapshot(data, "", retMap, preciseNumbers)
 		return retMap, nil
 	}
 	// else, we're dealing with object aggregation (golint is now happy)
 	retMap := map[string]DatastreamObjectValue{}
-	parseObjectDatastreamSnapshot([]byte(data.Raw), retMap)
+	if err := parseObjectDatastreamSnapshot(data, retMap, preciseNumbers); err != nil {
+		return nil, err
+	}
 	return retMap, nil
 }
 
-func parseIndividualDatastreamSnapshot(jsonValue []byte, prefix string, acc map[string]any) {
+func parseIndividualDatastreamSnapshot(jsonValue []byte, prefix string, acc map[string]any, preciseNumbers bool) {
 	// Base case: we have a {"value": n, "timestamp": t} structure
 	// a "reception_timestamp" field might also exist, this is handled by unmarshal
 	if gjson.GetBytes(jsonValue, "value").Exists() && gjson.GetBytes(jsonValue, "timestamp").Exists() {
 		val := DatastreamIndividualValue{}
-		_ = json.Unmarshal(jsonValue, &val)
+		_ = val.unmarshalJSON(jsonValue, preciseNumbers)
 		acc[prefix] = val
 		// Recursive case: we have a structure like {"path1": {"value": n, "timestamp": t}, "path2": {"piece2": {"value": n, "timestamp": t}}}
 	} else if gjson.ParseBytes(jsonValue).IsObject() {
 		insideMap := gjson.ParseBytes(jsonValue).Map()
 		for k, v := range insideMap {
-			parseIndividualDatastreamSnapshot([]byte(v.Raw), prefix+"/"+k, acc)
+			parseIndividualDatastreamSnapshot([]byte(v.Raw), prefix+"/"+k, acc, preciseNumbers)
 		}
 	}
 	// No third option, maybe we should return an error here
 }
 
-func parseObjectDatastreamSnapshot(jsonValue []byte, acc map[string]DatastreamObjectValue) {
+func parseObjectDatastreamSnapshot(jsonValue []byte, acc map[string]DatastreamObjectValue, preciseNumbers bool) error {
 	jsonData := gjson.ParseBytes(jsonValue)
 
 	// jsonData must be an object
-	obj := jsonData.Value().(map[string]interface{})
+	obj, ok := jsonData.Value().(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("Invalid object datastream snapshot: %s", jsonData.Raw)
+	}
 	flattened, _ := flat.Flatten(obj, &flat.Options{Safe: true, Delimiter: "."})
 
 	keys := []string{}
@@ -572,14 +743,18 @@ func parseObjectDatastreamSnapshot(jsonValue []byte, acc map[string]DatastreamOb
 
 		if item.IsArray() {
 			// since it's a snapshot, we have just one value in the array
-			i := item.Array()[0]
-			_ = json.Unmarshal([]byte(i.Raw), &value)
+			values := item.Array()
+			if len(values) == 0 {
+				continue
+			}
+			_ = value.unmarshalJSON([]byte(values[0].Raw), preciseNumbers)
 			acc[k] = value
 		} else {
-			_ = json.Unmarshal([]byte(item.Raw), &value)
+			_ = value.unmarshalJSON([]byte(item.Raw), preciseNumbers)
 			acc[k] = value
 		}
 	}
+	return nil
 }
 
 func (r GetDatastreamSnapshotResponse) Raw(f func(*http.Response) any) any {
@@ -591,11 +766,12 @@ func (r GetDatastreamSnapshotResponse) Raw(f func(*http.Response) any) any {
 // Returns the value as a PropertyValue.
 func (r GetPropertiesResponse) Parse() (any, error) {
 	defer r.res.Body.Close()
-	b, _ := io.ReadAll(r.res.Body)
-	// clean up useless prefix
-	data := gjson.GetBytes(b, "data")
+	payload, err := decodeAstartePayload(r.res.Body, json.RawMessage{})
+	if err != nil {
+		return nil, err
+	}
 	retMap := map[string]PropertyValue{}
-	parseProperties([]byte(data.Raw), "", retMap)
+	parseProperties(payload.Data, "", retMap)
 	return retMap, nil
 }
 
@@ -619,17 +795,55 @@ func parseProperties(jsonValue []byte, prefix string, acc map[string]PropertyVal
 	// No third option, maybe we should return an error here
 }
 
+// DatastreamWriteAck is the acknowledgment of a datastream value sent to Astarte, holding the value and
+// timestamps Astarte stored. Timestamps are zero when Astarte does not return them.
+type DatastreamWriteAck struct {
+	Value              any
+	Timestamp          time.Time
+	ReceptionTimestamp time.Time
+}
+
+// Parses data obtained by performing a request to send a datastream value.
+// Returns the value echoed by Astarte as a DatastreamWriteAck, which is empty if Astarte returned no data.
+func (r SendDatastreamResponse) Parse() (any, error) {
+	defer r.res.Body.Close()
+	body, err := io.ReadAll(r.res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return DatastreamWriteAck{}, nil
+	}
+	payload, err := unmarshalAstartePayload(body, json.RawMessage{})
+	if err != nil {
+		return nil, err
+	}
+	data := gjson.ParseBytes(payload.Data)
+	if !data.Get("value").Exists() || !(data.Get("timestamp").Exists() || data.Get("reception_timestamp").Exists()) {
+		// Only the value was echoed
+		return DatastreamWriteAck{Value: data.Value()}, nil
+	}
+	value := DatastreamIndividualValue{}
+	if err := json.Unmarshal(payload.Data, &value); err != nil {
+		return nil, err
+	}
+	return DatastreamWriteAck(value), nil
+}
+
+func (r SendDatastreamResponse) Raw(f func(*http.Response) any) any {
+	defer r.res.Body.Close()
+	return f(r.res)
+}
+
 // Parses data obtained by performing a request to list groups for a device.
 // Returns the list of groups as an array of strings.
 func (r ListGroupsResponse) Parse() (any, error) {
 	defer r.res.Body.Close()
-	b, _ := io.ReadAll(r.res.Body)
-	data := gjson.GetBytes(b, "data").Array()
-	groups := []string{}
-	for _, v := range data {
-		groups = append(groups, v.Str)
+	payload, err := decodeAstartePayload(r.res.Body, []string{})
+	if err != nil {
+		return nil, err
 	}
-	return groups, nil
+	return payload.Data, nil
 }
 
 func (r ListGroupsResponse) Raw(f func(*http.Response) any) any {
@@ -641,11 +855,11 @@ func (r ListGroupsResponse) Raw(f func(*http.Response) any) any {
 // Returns the group's details as a DevicesAndGroup struct.
 func (r CreateGroupResponse) Parse() (any, error) {
 	defer r.res.Body.Close()
-	b, _ := io.ReadAll(r.res.Body)
-	data := gjson.GetBytes(b, "data")
-	devicesAndGroup := DevicesAndGroup{}
-	_ = json.Unmarshal([]byte(data.Raw), &devicesAndGroup)
-	return devicesAndGroup, nil
+	payload, err := decodeAstartePayload(r.res.Body, DevicesAndGroup{})
+	if err != nil {
+		return nil, err
+	}
+	return payload.Data, nil
 }
 
 func (r CreateGroupResponse) Raw(f func(*http.Response) any) any {
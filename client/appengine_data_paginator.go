@@ -26,6 +26,9 @@ import (
 )
 
 // ResultSetOrder represents the order of the samples.
+// Astarte returns values in descending order only starting from the newest value in the time window, a page at
+// a time: hence descending datastream paginators can't have a since time, and need a positive page size.
+// Ascending datastream paginators accept both, and return all the values in a single page if the page size is 0.
 type ResultSetOrder int
 
 const (
@@ -35,6 +38,41 @@ const (
 	DescendingOrder
 )
 
+func (o ResultSetOrder) String() string {
+	switch o {
+	case AscendingOrder:
+		return "ascending"
+	case DescendingOrder:
+		return "descending"
+	default:
+		return fmt.Sprintf("ResultSetOrder(%d)", int(o))
+	}
+}
+
+// IsValid returns an error if o is neither AscendingOrder nor DescendingOrder.
+func (o ResultSetOrder) IsValid() error {
+	if o != AscendingOrder && o != DescendingOrder {
+		return fmt.Errorf("%w: %v", ErrInvalidResultSetOrder, o)
+	}
+	return nil
+}
+
+// validateWindow checks that a datastream paginator in order o can start at since with pages of pageSize values,
+// see ResultSetOrder. It is the single place where these rules are enforced, both when creating paginators and
+// when building the URL of their pages.
+func (o ResultSetOrder) validateWindow(since time.Time, pageSize int) error {
+	if err := o.IsValid(); err != nil {
+		return err
+	}
+	if o == DescendingOrder && !since.IsZero() {
+		return ErrSinceWithDescendingOrder
+	}
+	if o == DescendingOrder && pageSize <= 0 {
+		return ErrDescendingOrderNeedsPageSize
+	}
+	return nil
+}
+
 // DatastreamPaginator handles a paginated set of results. It provides a one-directional iterator to call onto
 // Astarte AppEngine API and handle potentially extremely large sets of results in chunk.
 type DatastreamPaginator struct {
@@ -48,16 +86,50 @@ type DatastreamPaginator struct {
 	client         *Client
 	hasNextPage    bool
 	aggregation    interfaces.AstarteInterfaceAggregation
+	query          datastreamQuery
+	interfaceName  string
+	interfacePath  string
+	// windowSince and windowTo are the bounds the paginator was created with, restored by Rewind
+	windowSince time.Time
+	windowTo    time.Time
+	// pageIndex is the index of the next page, see PageError
+	pageIndex int
 }
 
 // Rewind rewinds the paginator to the first page. GetNextPage will then return the first page of the call.
 func (d *DatastreamPaginator) Rewind() {
-	// Invalid time
-	d.since = time.Time{}
-	d.to = time.Time{}
+	d.since = d.windowSince
+	d.to = d.windowTo
 	d.nextQuery = url.Values{}
 	d.hasNextPage = true
 	d.firstPage = true
+	d.pageIndex = 0
+}
+
+// Seek moves the paginator to t, so that GetNextPage returns the page starting at t. When using AscendingOrder,
+// the next page starts with the values at t (inclusive), and the end of the time window is kept. When using
+// DescendingOrder, the next page starts with the newest value before t (exclusive).
+func (d *DatastreamPaginator) Seek(t time.Time) {
+	switch d.resultSetOrder {
+	case AscendingOrder:
+		d.since = t
+	case DescendingOrder:
+		d.to = t
+	}
+	d.nextQuery = url.Values{}
+	d.hasNextPage = true
+	d.firstPage = true
+	d.pageIndex = 0
+}
+
+// SetPageSize sets the page size used from the next page on, e.g. to shrink pages when values are large objects.
+// pageSize must be positive, and is clamped to the maximum page size of the client, see WithMaxPageSize.
+func (d *DatastreamPaginator) SetPageSize(pageSize int) error {
+	if pageSize <= 0 {
+		return fmt.Errorf("Invalid page size %d: it must be positive", pageSize)
+	}
+	d.pageSize = d.client.clampPageSize(pageSize)
+	return nil
 }
 
 // HasNextPage returns whether this paginator can return more pages.
@@ -65,7 +137,7 @@ func (d *DatastreamPaginator) HasNextPage() bool {
 	return d.hasNextPage
 }
 
-// GetPageSize returns the page size for this paginator.
+// GetPageSize returns the page size for this paginator, after clamping it to the maximum page size of the client.
 func (d *DatastreamPaginator) GetPageSize() int {
 	return d.pageSize
 }
@@ -75,6 +147,10 @@ func (d *DatastreamPaginator) GetResultSetOrder() ResultSetOrder {
 	return d.resultSetOrder
 }
 
+func (d *DatastreamPaginator) astarteClient() *Client {
+	return d.client
+}
+
 // GetNextPage returns a request to get the next result page from the paginator.
 // If no more results are available, HasNextPage will return false.
 // GetNextPage throws an error if no more pages are available or if an invalid parameter is specified.
@@ -83,36 +159,42 @@ func (d *DatastreamPaginator) GetNextPage() (AstarteRequest, error) {
 		return nil, errors.New("No more pages available")
 	}
 
+	// The window is captured before setupCallURL, which replaces a zero since with the beginning of time
+	page := PageError{Since: d.since, To: d.to, PageIndex: d.pageIndex}
 	callURL, err := d.setupCallURL()
 	if err != nil {
 		return Empty{}, err
 	}
 	req := d.client.makeHTTPrequest(http.MethodGet, callURL, nil)
+	page.URL, page.Query = callURL.String(), callURL.Query()
 
-	return GetNextDatastreamPageRequest{req: req, expects: 200, paginator: d}, nil
+	return GetNextDatastreamPageRequest{req: req, expects: []int{http.StatusOK}, paginator: d, page: page}, nil
 }
 
 type GetNextDatastreamPageRequest struct {
 	req       *http.Request
-	expects   int
+	expects   []int
 	paginator Paginator
+	// page describes the page in case fetching it fails
+	page PageError
 }
 
 // nolint:bodyclose
 func (r GetNextDatastreamPageRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
-		return Empty{}, err
+		return Empty{}, r.page.wrap(err)
 	}
-	if res.StatusCode != r.expects {
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return r.handleNextDatastreamPageFail(res)
 	}
+	c.observePage("datastream")
 	return GetNextDatastreamPageResponse{res: res, paginator: &r.paginator}, nil
 }
 
 func (r GetNextDatastreamPageRequest) handleNextDatastreamPageFail(res *http.Response) (AstarteResponse, error) {
 	if res.Body == nil {
-		return Empty{}, ErrDifferentStatusCode(r.expects, res.StatusCode)
+		return Empty{}, r.page.wrap(ErrUnexpectedStatusCode(r.expects, res.StatusCode))
 	}
 	// A quirky corner case:
 	// when the size of Astarte data is a multiple of r.paginator.pageSize,
@@ -123,11 +205,12 @@ func (r GetNextDatastreamPageRequest) handleNextDatastreamPageFail(res *http.Res
 		return GetNextDatastreamPageResponse{res: res, paginator: &r.paginator}, nil
 	}
 	// now that the corner case is handled, if we're here we must fail
-	return Empty{}, errorFromJSONErrors(res.Body)
+	defer res.Body.Close()
+	return Empty{}, r.page.wrap(newAPIError(res.StatusCode, res.Body))
 }
 
 func (r GetNextDatastreamPageRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	return fmt.Sprint(command)
 }
 
@@ -135,6 +218,9 @@ func (d *DatastreamPaginator) setupCallURL() (*url.URL, error) {
 	callURL, _ := url.Parse(d.baseURL.String())
 
 	query := d.nextQuery
+	if err := d.resultSetOrder.validateWindow(d.windowSince, d.pageSize); err != nil {
+		return &url.URL{}, err
+	}
 	switch d.resultSetOrder {
 	case AscendingOrder:
 		// If no start is set, let's start from the beginnning of time
@@ -144,35 +230,30 @@ func (d *DatastreamPaginator) setupCallURL() (*url.URL, error) {
 		// All data in the next page come from a time after 'since' (so we descend)
 		if d.firstPage {
 			// first page includes also the starting value
-			query.Set("since", d.since.UTC().Format(time.RFC3339Nano))
+			query.Set("since", d.client.timestampPrecision.Format(d.since))
 		} else {
 			// pages after the first must not include the starting value
-			query.Set("since_after", d.since.UTC().Format(time.RFC3339Nano))
+			query.Set("since_after", d.client.timestampPrecision.Format(d.since))
 			query.Del("since")
 		}
 		if (d.to != time.Time{}) {
 			// All data in the next page come from a time until 'to'
-			query.Set("to", d.to.UTC().Format(time.RFC3339Nano))
+			query.Set("to", d.client.timestampPrecision.Format(d.to))
 		}
 		if d.pageSize != 0 {
 			query.Set("limit", fmt.Sprintf("%d", d.pageSize))
 		}
 
 	case DescendingOrder:
-		if d.pageSize == 0 {
-			return &url.URL{}, fmt.Errorf("A limit parameter must be specified when using DescendingOrder")
-		}
-		if (d.since != time.Time{}) {
-			return &url.URL{}, fmt.Errorf("A since parameter must not be specified when using DescendingOrder")
-		}
 		query.Set("limit", fmt.Sprintf("%d", d.pageSize))
 		// if "to" doesn't exist, default behavior with only "limit" is descending
 		if (d.to != time.Time{}) {
 			// All data in the next page come from a time until 'to' (so we descend)
-			query.Set("to", d.to.UTC().Format(time.RFC3339Nano))
+			query.Set("to", d.client.timestampPrecision.Format(d.to))
 		}
 	}
 
+	d.query.setURLQuery(query)
 	callURL.RawQuery = query.Encode()
 
 	return callURL, nil
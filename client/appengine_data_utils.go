@@ -0,0 +1,303 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/interfaces"
+)
+
+// TimestampField represents which timestamp of a datastream value is taken into account.
+type TimestampField int
+
+const (
+	// ValueTimestamp is the timestamp of the value, either explicit or assigned by Astarte.
+	ValueTimestamp TimestampField = iota
+	// ValueReceptionTimestamp is the time at which Astarte received the value.
+	ValueReceptionTimestamp
+)
+
+// DatastreamPathValue is a DatastreamIndividualValue along with the interface path it was sent on.
+type DatastreamPathValue struct {
+	Path string
+	DatastreamIndividualValue
+}
+
+// DatastreamGap represents a time interval in which no values were sent on a path.
+type DatastreamGap struct {
+	Path string
+	// Start is the timestamp of the last value before the gap.
+	Start time.Time
+	// End is the timestamp of the first value after the gap.
+	End time.Time
+}
+
+// Duration returns the duration of the gap.
+func (g DatastreamGap) Duration() time.Duration {
+	return g.End.Sub(g.Start)
+}
+
+func (v DatastreamIndividualValue) timestamp(field TimestampField) time.Time {
+	if field == ValueReceptionTimestamp {
+		return v.ReceptionTimestamp
+	}
+	return v.Timestamp
+}
+
+// SortDatastreamValues sorts values in place according to field and order. Values with the same timestamp
+// keep their relative order.
+func SortDatastreamValues(values []DatastreamIndividualValue, field TimestampField, order ResultSetOrder) {
+	sort.SliceStable(values, func(i, j int) bool {
+		if order == DescendingOrder {
+			return values[i].timestamp(field).After(values[j].timestamp(field))
+		}
+		return values[i].timestamp(field).Before(values[j].timestamp(field))
+	})
+}
+
+// MergeDatastreamValues merges the values of multiple paths, given as a map of paths to values,
+// in a single slice sorted according to field and order. Values with the same timestamp are sorted by path.
+func MergeDatastreamValues(valuesByPath map[string][]DatastreamIndividualValue, field TimestampField, order ResultSetOrder) []DatastreamPathValue {
+	ret := []DatastreamPathValue{}
+	for path, values := range valuesByPath {
+		for _, v := range values {
+			ret = append(ret, DatastreamPathValue{Path: path, DatastreamIndividualValue: v})
+		}
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		ti, tj := ret[i].timestamp(field), ret[j].timestamp(field)
+		if ti.Equal(tj) {
+			return ret[i].Path < ret[j].Path
+		}
+		if order == DescendingOrder {
+			return ti.After(tj)
+		}
+		return ti.Before(tj)
+	})
+	return ret
+}
+
+// DeduplicateDatastreamValues removes values with the same path and timestamp, keeping only the first
+// occurrence. The order of values is preserved.
+func DeduplicateDatastreamValues(values []DatastreamPathValue) []DatastreamPathValue {
+	type valueKey struct {
+		path      string
+		timestamp int64
+	}
+	seen := map[valueKey]bool{}
+	ret := []DatastreamPathValue{}
+	for _, v := range values {
+		key := valueKey{path: v.Path, timestamp: v.Timestamp.UnixNano()}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		ret = append(ret, v)
+	}
+	return ret
+}
+
+// FindDatastreamGaps returns, for each path, the intervals between consecutive values (according to field)
+// which are longer than threshold. Gaps are sorted by start time, and then by path.
+func FindDatastreamGaps(values []DatastreamPathValue, field TimestampField, threshold time.Duration) []DatastreamGap {
+	timestampsByPath := map[string][]time.Time{}
+	for _, v := range values {
+		timestampsByPath[v.Path] = append(timestampsByPath[v.Path], v.timestamp(field))
+	}
+
+	gaps := []DatastreamGap{}
+	for path, timestamps := range timestampsByPath {
+		sort.Slice(timestamps, func(i, j int) bool { return timestamps[i].Before(timestamps[j]) })
+		for i := 1; i < len(timestamps); i++ {
+			if timestamps[i].Sub(timestamps[i-1]) > threshold {
+				gaps = append(gaps, DatastreamGap{Path: path, Start: timestamps[i-1], End: timestamps[i]})
+			}
+		}
+	}
+	sort.Slice(gaps, func(i, j int) bool {
+		if gaps[i].Start.Equal(gaps[j].Start) {
+			return gaps[i].Path < gaps[j].Path
+		}
+		return gaps[i].Start.Before(gaps[j].Start)
+	})
+	return gaps
+}
+
+// keyByParameters re-keys the values of an object aggregated interface, parsed with paths relative to basePath,
+// by the values of the parameters of astarteInterface. Data is returned as is if astarteInterface is nil.
+func keyByParameters(astarteInterface *interfaces.AstarteInterface, basePath string, data any) (any, error) {
+	if astarteInterface == nil {
+		return data, nil
+	}
+	if astarteInterface.Aggregation != interfaces.ObjectAggregation || !astarteInterface.IsParametric() {
+		return nil, fmt.Errorf("Interface %s is not a parametric object aggregated interface", astarteInterface.Name)
+	}
+	// All mappings of an object aggregated interface share the same object path
+	endpoint := astarteInterface.Mappings[0].Endpoint
+	objectEndpoint := endpoint[:strings.LastIndex(endpoint, "/")]
+	basePath = strings.TrimSuffix(basePath, "/")
+
+	switch values := data.(type) {
+	case map[string]DatastreamObjectValue:
+		ret := map[string]DatastreamObjectValue{}
+		for path, v := range values {
+			key, err := parameterKey(objectEndpoint, basePath+path)
+			if err != nil {
+				return nil, err
+			}
+			ret[key] = v
+		}
+		return ret, nil
+	case map[string][]DatastreamObjectValue:
+		ret := map[string][]DatastreamObjectValue{}
+		for path, v := range values {
+			key, err := parameterKey(objectEndpoint, basePath+path)
+			if err != nil {
+				return nil, err
+			}
+			ret[key] = append(ret[key], v...)
+		}
+		return ret, nil
+	}
+	return data, nil
+}
+
+// parameterKey returns the values the parameters of endpoint take in path, joined by a slash.
+func parameterKey(endpoint, path string) (string, error) {
+	endpointTokens := strings.Split(endpoint, "/")
+	pathTokens := strings.Split(path, "/")
+	if len(endpointTokens) != len(pathTokens) {
+		return "", fmt.Errorf("Path %s does not match endpoint %s", path, endpoint)
+	}
+	parameters := []string{}
+	for i, token := range endpointTokens {
+		switch {
+		case strings.HasPrefix(token, "%{") && strings.HasSuffix(token, "}"):
+			parameters = append(parameters, pathTokens[i])
+		case token != pathTokens[i]:
+			return "", fmt.Errorf("Path %s does not match endpoint %s", path, endpoint)
+		}
+	}
+	return strings.Join(parameters, "/"), nil
+}
+
+// datastreamProjection selects the parts of datastream values returned by Parse.
+type datastreamProjection int
+
+const (
+	fullValues datastreamProjection = iota
+	withoutReceptionTimestamps
+	valuesOnly
+)
+
+// project applies projection to the result of a datastream Parse.
+func project(projection datastreamProjection, data any) any {
+	switch projection {
+	case withoutReceptionTimestamps:
+		return dropReceptionTimestamps(data)
+	case valuesOnly:
+		return projectValues(data)
+	}
+	return data
+}
+
+func dropReceptionTimestamps(data any) any {
+	switch values := data.(type) {
+	case []DatastreamIndividualValue:
+		for i := range values {
+			values[i].ReceptionTimestamp = time.Time{}
+		}
+	case map[string]DatastreamIndividualValue:
+		for k, v := range values {
+			v.ReceptionTimestamp = time.Time{}
+			values[k] = v
+		}
+	case map[string]any:
+		for k, v := range values {
+			if individualValue, ok := v.(DatastreamIndividualValue); ok {
+				individualValue.ReceptionTimestamp = time.Time{}
+				values[k] = individualValue
+			}
+		}
+	case []DatastreamObjectValue:
+		for i := range values {
+			values[i].ReceptionTimestamp = time.Time{}
+		}
+	case map[string][]DatastreamObjectValue:
+		for _, objectValues := range values {
+			for i := range objectValues {
+				objectValues[i].ReceptionTimestamp = time.Time{}
+			}
+		}
+	case map[string]DatastreamObjectValue:
+		for k, v := range values {
+			v.ReceptionTimestamp = time.Time{}
+			values[k] = v
+		}
+	}
+	return data
+}
+
+func projectValues(data any) any {
+	switch values := data.(type) {
+	case []DatastreamIndividualValue:
+		ret := make([]any, len(values))
+		for i, v := range values {
+			ret[i] = v.Value
+		}
+		return ret
+	case map[string]DatastreamIndividualValue:
+		ret := make(map[string]any, len(values))
+		for k, v := range values {
+			ret[k] = v.Value
+		}
+		return ret
+	case map[string]any:
+		ret := make(map[string]any, len(values))
+		for k, v := range values {
+			if individualValue, ok := v.(DatastreamIndividualValue); ok {
+				v = individualValue.Value
+			}
+			ret[k] = v
+		}
+		return ret
+	case []DatastreamObjectValue:
+		ret := make([]ObjectValues, len(values))
+		for i, v := range values {
+			ret[i] = v.Values
+		}
+		return ret
+	case map[string][]DatastreamObjectValue:
+		ret := make(map[string][]ObjectValues, len(values))
+		for k, objectValues := range values {
+			ret[k] = make([]ObjectValues, len(objectValues))
+			for i, v := range objectValues {
+				ret[k][i] = v.Values
+			}
+		}
+		return ret
+	case map[string]DatastreamObjectValue:
+		ret := make(map[string]ObjectValues, len(values))
+		for k, v := range values {
+			ret[k] = v.Values
+		}
+		return ret
+	}
+	return data
+}
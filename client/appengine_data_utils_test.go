@@ -0,0 +1,142 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/interfaces"
+)
+
+var testDatastreamUtilsBaseTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func testDatastreamValueAt(value, minutes, receptionMinutes int) DatastreamIndividualValue {
+	return DatastreamIndividualValue{
+		Value:              value,
+		Timestamp:          testDatastreamUtilsBaseTime.Add(time.Duration(minutes) * time.Minute),
+		ReceptionTimestamp: testDatastreamUtilsBaseTime.Add(time.Duration(receptionMinutes) * time.Minute),
+	}
+}
+
+func TestSortDatastreamValues(t *testing.T) {
+	values := []DatastreamIndividualValue{testDatastreamValueAt(1, 2, 3), testDatastreamValueAt(2, 1, 4), testDatastreamValueAt(3, 3, 1)}
+
+	SortDatastreamValues(values, ValueTimestamp, AscendingOrder)
+	if values[0].Value != 2 || values[1].Value != 1 || values[2].Value != 3 {
+		t.Errorf("Wrong ascending order by timestamp: %v", values)
+	}
+	SortDatastreamValues(values, ValueReceptionTimestamp, DescendingOrder)
+	if values[0].Value != 2 || values[1].Value != 1 || values[2].Value != 3 {
+		t.Errorf("Wrong descending order by reception timestamp: %v", values)
+	}
+}
+
+func TestMergeAndDeduplicateDatastreamValues(t *testing.T) {
+	valuesByPath := map[string][]DatastreamIndividualValue{
+		"/b": {testDatastreamValueAt(1, 1, 1), testDatastreamValueAt(2, 3, 3)},
+		"/a": {testDatastreamValueAt(3, 1, 1), testDatastreamValueAt(4, 2, 2), testDatastreamValueAt(5, 2, 5)},
+	}
+
+	merged := MergeDatastreamValues(valuesByPath, ValueTimestamp, AscendingOrder)
+	expected := []struct {
+		path  string
+		value int
+	}{{"/a", 3}, {"/b", 1}, {"/a", 4}, {"/a", 5}, {"/b", 2}}
+	if len(merged) != len(expected) {
+		t.Fatalf("Expected %d merged values, found %d", len(expected), len(merged))
+	}
+	for i, e := range expected {
+		if merged[i].Path != e.path || merged[i].Value != e.value {
+			t.Errorf("Expected %s: %d at position %d, found %s: %v", e.path, e.value, i, merged[i].Path, merged[i].Value)
+		}
+	}
+
+	deduplicated := DeduplicateDatastreamValues(merged)
+	if len(deduplicated) != 4 {
+		t.Fatalf("Expected 4 deduplicated values, found %d", len(deduplicated))
+	}
+	for _, v := range deduplicated {
+		if v.Value == 5 {
+			t.Error("Duplicate value was not removed")
+		}
+	}
+}
+
+func TestFindDatastreamGaps(t *testing.T) {
+	values := MergeDatastreamValues(map[string][]DatastreamIndividualValue{
+		"/a": {testDatastreamValueAt(1, 0, 0), testDatastreamValueAt(2, 1, 1), testDatastreamValueAt(3, 10, 11)},
+		"/b": {testDatastreamValueAt(4, 0, 0), testDatastreamValueAt(5, 5, 5)},
+	}, ValueTimestamp, AscendingOrder)
+
+	gaps := FindDatastreamGaps(values, ValueTimestamp, 2*time.Minute)
+	if len(gaps) != 2 {
+		t.Fatalf("Expected 2 gaps, found %v", gaps)
+	}
+	if gaps[0].Path != "/b" || gaps[0].Duration() != 5*time.Minute {
+		t.Errorf("Unexpected first gap: %+v", gaps[0])
+	}
+	if gaps[1].Path != "/a" || gaps[1].Duration() != 9*time.Minute {
+		t.Errorf("Unexpected second gap: %+v", gaps[1])
+	}
+
+	gaps = FindDatastreamGaps(values, ValueReceptionTimestamp, 9*time.Minute)
+	if len(gaps) != 1 || gaps[0].Duration() != 10*time.Minute {
+		t.Errorf("Unexpected gaps by reception timestamp: %v", gaps)
+	}
+}
+
+func TestKeyByParameters(t *testing.T) {
+	astarteInterface := interfaces.AstarteInterface{
+		Name:        "org.astarte-platform.genericsensors.Values",
+		Aggregation: interfaces.ObjectAggregation,
+		Mappings: []interfaces.AstarteInterfaceMapping{
+			{Endpoint: "/sensors/%{id}/temperature"},
+			{Endpoint: "/sensors/%{id}/humidity"},
+		},
+	}
+	snapshot, err := parseDatastreamSnapshot([]byte(`{"sensors": {
+		"s1": {"temperature": 21.5, "humidity": 40, "timestamp": "2024-01-01T00:00:00.000Z"},
+		"s2": {"temperature": 19, "humidity": 45, "timestamp": "2024-01-01T00:00:00.000Z"}}}`), interfaces.ObjectAggregation, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyed, err := keyByParameters(&astarteInterface, "", snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := keyed.(map[string]DatastreamObjectValue)
+	if len(values) != 2 {
+		t.Fatalf("Unexpected keys: %v", values)
+	}
+	s2 := values["s2"]
+	if v, _ := s2.Values.Get("temperature"); v != 19.0 {
+		t.Errorf("Unexpected value for s2: %v", v)
+	}
+
+	series := map[string][]DatastreamObjectValue{"/s1": {{}}, "/s2": {{}, {}}}
+	keyed, err = keyByParameters(&astarteInterface, "/sensors/", series)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := keyed.(map[string][]DatastreamObjectValue); len(v["s1"]) != 1 || len(v["s2"]) != 2 {
+		t.Errorf("Unexpected keyed values: %v", v)
+	}
+
+	if _, err := keyByParameters(&astarteInterface, "", map[string]DatastreamObjectValue{"/other/s1": {}}); err == nil {
+		t.Error("Expected an error for a path not matching the interface, found nil")
+	}
+}
@@ -19,8 +19,11 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
 
 	"moul.io/http2curl"
+
+	"github.com/astarte-platform/astarte-go/internal/runner"
 )
 
 // DeviceListPaginator handles a paginated set of results. It provides a one-directional iterator to call onto
@@ -33,12 +36,65 @@ type DeviceListPaginator struct {
 	pageSize    int
 	client      *Client
 	hasNextPage bool
+	realm       string
+	// groupName is set when listing the devices in a group
+	groupName string
+	// fetchedItems is the number of devices in the pages fetched since the last Rewind
+	fetchedItems int
+	// totalItems is the total number of devices in the realm, if known
+	totalItems    int64
+	hasTotalItems bool
+	// pageIndex is the index of the next page, see PageError
+	pageIndex int
 }
 
 // Rewind rewinds the simulator to the first page. GetNextPage will then return the first page of the call.
 func (d *DeviceListPaginator) Rewind() {
 	d.nextQuery = url.Values{}
 	d.hasNextPage = true
+	d.fetchedItems = 0
+	d.pageIndex = 0
+}
+
+// FetchedItems returns the number of devices in the pages fetched since the paginator was created or rewound.
+func (d *DeviceListPaginator) FetchedItems() int {
+	return d.fetchedItems
+}
+
+// EstimatedTotal returns the total number of devices the paginator will go through, e.g. to display the progress
+// of a long export. The total reported by Astarte in the pages is used if available; otherwise the device stats
+// of the realm are retrieved once, and cached for the lifetime of the paginator. Since devices can be registered
+// while paginating, the total is an estimate. When listing the devices in a group, no stats are available and
+// EstimatedTotal returns ErrNoEstimatedTotal until Astarte reports a total.
+func (d *DeviceListPaginator) EstimatedTotal() (int64, error) {
+	if d.hasTotalItems {
+		return d.totalItems, nil
+	}
+	if d.groupName != "" {
+		return 0, ErrNoEstimatedTotal
+	}
+	getDevicesStatsCall, err := d.client.GetDevicesStats(d.realm)
+	if err != nil {
+		return 0, err
+	}
+	stats, err := runner.RunAndParse[DevicesStats](d.client, getDevicesStatsCall.Run)
+	if err != nil {
+		return 0, err
+	}
+	d.totalItems, d.hasTotalItems = stats.TotalDevices, true
+	return d.totalItems, nil
+}
+
+// Progress returns the fraction of the devices fetched so far, between 0 and 1, using EstimatedTotal.
+func (d *DeviceListPaginator) Progress() (float64, error) {
+	total, err := d.EstimatedTotal()
+	if err != nil {
+		return 0, err
+	}
+	if total <= 0 || int64(d.fetchedItems) >= total {
+		return 1, nil
+	}
+	return float64(d.fetchedItems) / float64(total), nil
 }
 
 // HasNextPage returns whether this paginator can return more pages
@@ -46,34 +102,43 @@ func (d *DeviceListPaginator) HasNextPage() bool {
 	return d.hasNextPage
 }
 
-// GetPageSize returns the page size for this paginator
+// GetPageSize returns the page size for this paginator, after clamping it to the maximum page size of the client
+// or to the limit Astarte applied to the last page, if lower.
 func (d *DeviceListPaginator) GetPageSize() int {
 	return d.pageSize
 }
 
+func (d *DeviceListPaginator) astarteClient() *Client {
+	return d.client
+}
+
 type GetNextDeviceListPageRequest struct {
 	req       *http.Request
-	expects   int
+	expects   []int
 	paginator Paginator
+	// page describes the page in case fetching it fails
+	page PageError
 }
 
 // Performs a request to get the next page.
 // Returns either a response that can be parsed with Parse() or an error if the request failed.
 // nolint:bodyclose
 func (r GetNextDeviceListPageRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
-		return Empty{}, err
+		return Empty{}, r.page.wrap(err)
 	}
-	if res.StatusCode != r.expects {
-		return runAstarteRequestError(res, r.expects)
+	if !c.isExpectedStatusCode(res, r.expects) {
+		_, err := runAstarteRequestError(res, r.expects)
+		return Empty{}, r.page.wrap(err)
 	}
+	c.observePage("device_list")
 	return GetNextDeviceListPageResponse{res: res, paginator: &r.paginator}, nil
 }
 
 // Returns the curl command corresponding to the request to get the next page.
 func (r GetNextDeviceListPageRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	return fmt.Sprint(command)
 }
 
@@ -87,8 +152,9 @@ func (d *DeviceListPaginator) GetNextPage() (AstarteRequest, error) {
 
 	callURL := d.setupCallURL()
 	req := d.client.makeHTTPrequest(http.MethodGet, callURL, nil)
+	page := PageError{URL: callURL.String(), Query: callURL.Query(), PageIndex: d.pageIndex}
 
-	return GetNextDeviceListPageRequest{req: req, expects: 200, paginator: d}, nil
+	return GetNextDeviceListPageRequest{req: req, expects: []int{http.StatusOK}, paginator: d, page: page}, nil
 }
 
 func (d *DeviceListPaginator) setupCallURL() *url.URL {
@@ -101,6 +167,9 @@ func (d *DeviceListPaginator) setupCallURL() *url.URL {
 	case DeviceDetailsFormat:
 		query.Set("details", "true")
 	}
+	if d.pageSize > 0 && query.Get("limit") == "" {
+		query.Set("limit", strconv.Itoa(d.pageSize))
+	}
 
 	callURL.RawQuery = query.Encode()
 
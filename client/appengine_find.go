@@ -0,0 +1,64 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"github.com/astarte-platform/astarte-go/internal/runner"
+)
+
+// DeviceFilter selects devices, see FindDevices.
+type DeviceFilter struct {
+	// Attributes are the attributes a device must have, with the given values.
+	Attributes map[string]string
+}
+
+// Matches returns whether device matches the filter.
+func (f DeviceFilter) Matches(device DeviceDetails) bool {
+	for key, value := range f.Attributes {
+		if v, ok := device.Attributes[key]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// FindDevices returns the details of the devices in the realm matching filter, fetching pageSize devices at a time.
+// AppEngine API cannot filter the device list by attributes, so FindDevices lists the details of every device in the
+// realm and checks the filter client-side: the transferred data and the number of requests grow with the size of the
+// realm, regardless of how many devices match.
+func (c *Client) FindDevices(realm string, filter DeviceFilter, pageSize int) ([]DeviceDetails, error) {
+	paginator, err := c.GetDeviceListPaginator(realm, pageSize, DeviceDetailsFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := []DeviceDetails{}
+	for paginator.HasNextPage() {
+		nextPageCall, err := paginator.GetNextPage()
+		if err != nil {
+			return nil, err
+		}
+		devices, err := runner.RunAndParse[[]DeviceDetails](c, nextPageCall.Run)
+		if err != nil {
+			return nil, err
+		}
+		for _, device := range devices {
+			if filter.Matches(device) {
+				ret = append(ret, device)
+			}
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,260 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/deviceid"
+	"github.com/astarte-platform/astarte-go/interfaces"
+	"github.com/astarte-platform/astarte-go/internal/runner"
+)
+
+const (
+	defaultFleetQueryConcurrency = 10
+	defaultFleetQueryPageSize    = 1000
+)
+
+// FleetAggregation represents how the samples of a device are aggregated by QueryFleetDatastream.
+type FleetAggregation int
+
+const (
+	// FleetLastValue returns the last sample in the time window, as a DatastreamIndividualValue.
+	FleetLastValue FleetAggregation = iota
+	// FleetMean returns the mean of the samples in the time window, as a float64.
+	FleetMean
+	// FleetMin returns the minimum of the samples in the time window, as a float64.
+	FleetMin
+	// FleetMax returns the maximum of the samples in the time window, as a float64.
+	FleetMax
+)
+
+// TimeWindow represents a time window for datastream queries. A zero Since means
+// "since the beginning of time", a zero To means "until now".
+type TimeWindow struct {
+	Since time.Time
+	To    time.Time
+}
+
+// FleetDatastreamResult is the aggregated result of QueryFleetDatastream for a single device.
+type FleetDatastreamResult struct {
+	DeviceID string
+	// Value is a DatastreamIndividualValue for FleetLastValue, a float64 otherwise.
+	// It is nil if no samples were found in the time window.
+	Value any
+	// Count is the number of samples which were aggregated.
+	Count int
+	// Err is set if the query for this device failed.
+	Err error
+}
+
+type fleetQuery struct {
+	concurrency int
+	pageSize    int
+	progress    func(completed, total int)
+}
+
+type fleetQueryOption func(*fleetQuery)
+
+// Sets the maximum number of devices queried concurrently. Defaults to 10.
+// nolint:golint,revive
+func WithFleetConcurrency(concurrency int) fleetQueryOption {
+	return func(q *fleetQuery) {
+		q.concurrency = concurrency
+	}
+}
+
+// Sets the page size used when retrieving samples from each device. Defaults to 1000.
+// nolint:golint,revive
+func WithFleetPageSize(pageSize int) fleetQueryOption {
+	return func(q *fleetQuery) {
+		q.pageSize = pageSize
+	}
+}
+
+// Sets a function which is called each time the query for a device is completed. Calls are serialized.
+// nolint:golint,revive
+func WithFleetProgress(progress func(completed, total int)) fleetQueryOption {
+	return func(q *fleetQuery) {
+		q.progress = progress
+	}
+}
+
+// QueryFleetDatastream queries the samples on interfacePath of a datastream interface with individual aggregation
+// for all devices in deviceIDs, and aggregates the samples in window for each device according to aggregation.
+// Devices are queried concurrently, once even if they are repeated in deviceIDs, and the results are returned as a
+// map of device IDs to FleetDatastreamResult.
+// Errors occurring while querying a single device are reported in its result, and do not stop the query. The returned
+// error is set only if the query itself is invalid.
+func (c *Client) QueryFleetDatastream(realm string, deviceIDs []string, astarteInterface interfaces.AstarteInterface, interfacePath string,
+	window TimeWindow, aggregation FleetAggregation, opts ...fleetQueryOption) (map[string]FleetDatastreamResult, error) {
+	query := fleetQuery{concurrency: defaultFleetQueryConcurrency, pageSize: defaultFleetQueryPageSize}
+	for _, f := range opts {
+		f(&query)
+	}
+	if err := validateFleetQuery(query, deviceIDs, astarteInterface, interfacePath, window, aggregation); err != nil {
+		return nil, err
+	}
+	deviceIDs = uniqueStrings(deviceIDs)
+
+	results := make(map[string]FleetDatastreamResult, len(deviceIDs))
+	var mu sync.Mutex
+	forEachConcurrently(deviceIDs, query.concurrency, func(_ int, deviceID string) {
+		result := c.queryDeviceDatastream(realm, deviceID, astarteInterface.Name, interfacePath, window, aggregation, query.pageSize)
+
+		mu.Lock()
+		defer mu.Unlock()
+		results[deviceID] = result
+		if query.progress != nil {
+			query.progress(len(results), len(deviceIDs))
+		}
+	})
+
+	return results, nil
+}
+
+func validateFleetQuery(query fleetQuery, deviceIDs []string, astarteInterface interfaces.AstarteInterface, interfacePath string,
+	window TimeWindow, aggregation FleetAggregation) error {
+	if astarteInterface.Type != interfaces.DatastreamType || astarteInterface.Aggregation != interfaces.IndividualAggregation {
+		return fmt.Errorf("Interface %s is not a datastream with individual aggregation", astarteInterface.Name)
+	}
+	mapping, err := interfaces.InterfaceMappingFromPath(astarteInterface, interfacePath)
+	if err != nil {
+		return err
+	}
+	if aggregation < FleetLastValue || aggregation > FleetMax {
+		return fmt.Errorf("Invalid fleet aggregation: %d", aggregation)
+	}
+	if aggregation != FleetLastValue {
+		switch mapping.Type {
+		case interfaces.Double, interfaces.Integer, interfaces.LongInteger:
+		default:
+			return fmt.Errorf("Cannot aggregate values of type %s", mapping.Type)
+		}
+	}
+	if query.concurrency <= 0 || query.pageSize <= 0 {
+		return errors.New("Concurrency and page size must be strictly positive integers")
+	}
+	if !window.To.IsZero() && window.To.Before(window.Since) {
+		return errors.New("Invalid time window: To comes before Since")
+	}
+	for _, deviceID := range deviceIDs {
+		if !deviceid.IsValid(deviceID) {
+			return ErrInvalidDeviceID(deviceID)
+		}
+	}
+	return nil
+}
+
+func (c *Client) queryDeviceDatastream(realm, deviceID, interfaceName, interfacePath string, window TimeWindow,
+	aggregation FleetAggregation, pageSize int) FleetDatastreamResult {
+	result := FleetDatastreamResult{DeviceID: deviceID}
+	to := window.To
+	if to.IsZero() {
+		to = c.clock()
+	}
+
+	if aggregation == FleetLastValue {
+		// The last value is the first one in descending order
+		paginator, err := c.GetDatastreamIndividualTimeWindowPaginator(realm, deviceID, AstarteDeviceID, interfaceName, interfacePath,
+			time.Time{}, to, DescendingOrder, 1)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		values, err := nextDatastreamIndividualPage(c, paginator)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		if len(values) > 0 && !values[0].Timestamp.Before(window.Since) {
+			result.Value = values[0]
+			result.Count = 1
+		}
+		return result
+	}
+
+	paginator, err := c.GetDatastreamIndividualTimeWindowPaginator(realm, deviceID, AstarteDeviceID, interfaceName, interfacePath,
+		window.Since, to, AscendingOrder, pageSize)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	sum, minValue, maxValue := 0.0, math.Inf(1), math.Inf(-1)
+	for paginator.HasNextPage() {
+		values, err := nextDatastreamIndividualPage(c, paginator)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		for _, v := range values {
+			f, ok := toFloat64(v.Value)
+			if !ok {
+				result.Err = fmt.Errorf("Cannot aggregate value %v of type %T", v.Value, v.Value)
+				return result
+			}
+			sum += f
+			minValue = math.Min(minValue, f)
+			maxValue = math.Max(maxValue, f)
+			result.Count++
+		}
+	}
+
+	if result.Count == 0 {
+		return result
+	}
+	switch aggregation {
+	case FleetMean:
+		result.Value = sum / float64(result.Count)
+	case FleetMin:
+		result.Value = minValue
+	case FleetMax:
+		result.Value = maxValue
+	}
+	return result
+}
+
+func nextDatastreamIndividualPage(c *Client, paginator Paginator) ([]DatastreamIndividualValue, error) {
+	req, err := paginator.GetNextPage()
+	if err != nil {
+		return nil, err
+	}
+	return runner.RunAndParse[[]DatastreamIndividualValue](c, req.Run)
+}
+
+func toFloat64(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		ret, err := v.Float64()
+		return ret, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,110 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/interfaces"
+)
+
+func TestQueryFleetDatastream(t *testing.T) {
+	c, _ := getTestContext(t)
+	iface, err := interfaces.ParseInterface([]byte(testFleetInterface))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[FleetAggregation]func(i int) float64{
+		FleetMean: func(i int) float64 { return float64(i + 2) },
+		FleetMin:  func(i int) float64 { return float64(i + 1) },
+		FleetMax:  func(i int) float64 { return float64(i + 3) },
+	}
+	// Repeated devices are queried once, and progress reaches the number of distinct devices
+	deviceIDs := append([]string{testDeviceIDs[1]}, testDeviceIDs...)
+	for aggregation, expectedValue := range expected {
+		progressCalls := 0
+		results, err := c.QueryFleetDatastream(testRealmName, deviceIDs, iface, "/a/value", TimeWindow{}, aggregation,
+			WithFleetConcurrency(2), WithFleetProgress(func(completed, total int) {
+				progressCalls++
+				if total != len(testDeviceIDs) || completed != progressCalls {
+					t.Errorf("Unexpected progress: %d/%d", completed, total)
+				}
+			}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if progressCalls != len(testDeviceIDs) {
+			t.Errorf("Progress was reported %d times instead of %d", progressCalls, len(testDeviceIDs))
+		}
+		for i, deviceID := range testDeviceIDs {
+			result := results[deviceID]
+			if result.Err != nil {
+				t.Fatal(result.Err)
+			}
+			if result.Count != 3 || result.Value != expectedValue(i) {
+				t.Errorf("Unexpected result for aggregation %d on device %s: %v", aggregation, deviceID, result)
+			}
+		}
+	}
+}
+
+func TestQueryFleetDatastreamLastValue(t *testing.T) {
+	c, _ := getTestContext(t)
+	iface, _ := interfaces.ParseInterface([]byte(testFleetInterface))
+
+	results, err := c.QueryFleetDatastream(testRealmName, testDeviceIDs, iface, "/a/value", TimeWindow{}, FleetLastValue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, deviceID := range testDeviceIDs {
+		value, ok := results[deviceID].Value.(DatastreamIndividualValue)
+		if !ok || value.Value != float64(i+3) {
+			t.Errorf("Unexpected last value for device %s: %v", deviceID, results[deviceID])
+		}
+	}
+
+	// The last value comes before the window
+	window := TimeWindow{Since: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	results, err = c.QueryFleetDatastream(testRealmName, testDeviceIDs, iface, "/a/value", window, FleetLastValue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, deviceID := range testDeviceIDs {
+		if results[deviceID].Value != nil || results[deviceID].Count != 0 {
+			t.Errorf("Unexpected last value for device %s: %v", deviceID, results[deviceID])
+		}
+	}
+}
+
+func TestQueryFleetDatastreamValidation(t *testing.T) {
+	c, _ := getTestContext(t)
+	iface, _ := interfaces.ParseInterface([]byte(testFleetInterface))
+
+	if _, err := c.QueryFleetDatastream(testRealmName, testDeviceIDs, iface, "/a/wrong/path", TimeWindow{}, FleetMean); err == nil {
+		t.Error("Invalid path was given, but no error found")
+	}
+	if _, err := c.QueryFleetDatastream(testRealmName, []string{"not a device ID"}, iface, "/a/value", TimeWindow{}, FleetMean); err == nil {
+		t.Error("Invalid device ID was given, but no error found")
+	}
+	if _, err := c.QueryFleetDatastream(testRealmName, testDeviceIDs, iface, "/a/value", TimeWindow{}, FleetMean, WithFleetConcurrency(0)); err == nil {
+		t.Error("Invalid concurrency was given, but no error found")
+	}
+	window := TimeWindow{Since: time.Now(), To: time.Now().Add(-time.Hour)}
+	if _, err := c.QueryFleetDatastream(testRealmName, testDeviceIDs, iface, "/a/value", window, FleetMean); err == nil {
+		t.Error("Invalid time window was given, but no error found")
+	}
+}
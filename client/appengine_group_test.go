@@ -16,8 +16,18 @@ package client
 
 import (
 	"testing"
+
+	"github.com/astarte-platform/astarte-go/internal/runner"
 )
 
+var testEscapedGroupNames = map[string]string{
+	"ah yes, a group":  "ah%20yes%2C%20a%20group",
+	"with/a/slash":     "with%2Fa%2Fslash",
+	"gruppo-àèìòù":     "gruppo-%C3%A0%C3%A8%C3%AC%C3%B2%C3%B9",
+	"グループ":             "%E3%82%B0%E3%83%AB%E3%83%BC%E3%83%97",
+	"percent%20inside": "percent%2520inside",
+}
+
 func TestListGroupDevices(t *testing.T) {
 	c, _ := getTestContext(t)
 	paginator, err := c.ListGroupDevices(testRealmName, testGroupName, 10, DeviceIDFormat)
@@ -115,3 +125,83 @@ func TestRemoveDeviceFromGroup(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestGroupNameEscaping(t *testing.T) {
+	c, _ := getTestContext(t)
+	for groupName, escapedGroupName := range testEscapedGroupNames {
+		expectedDevicesPath := "/appengine/v1/" + testRealmName + "/groups/" + escapedGroupName + "/devices"
+
+		addDeviceToGroupCall, err := c.AddDeviceToGroup(testRealmName, groupName, testDeviceID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p := addDeviceToGroupCall.(AddDeviceToGroupRequest).req.URL.EscapedPath(); p != expectedDevicesPath {
+			t.Errorf("Unexpected path for group %q: %s instead of %s", groupName, p, expectedDevicesPath)
+		}
+
+		removeDeviceFromGroupCall, err := c.RemoveDeviceFromGroup(testRealmName, groupName, testDeviceID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p := removeDeviceFromGroupCall.(RemoveDeviceFromGroupRequest).req.URL.EscapedPath(); p != expectedDevicesPath+"/"+testDeviceID {
+			t.Errorf("Unexpected path for group %q: %s instead of %s", groupName, p, expectedDevicesPath+"/"+testDeviceID)
+		}
+
+		paginator, err := c.ListGroupDevices(testRealmName, groupName, 10, DeviceIDFormat)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nextPageCall, err := paginator.GetNextPage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p := nextPageCall.(GetNextDeviceListPageRequest).req.URL.EscapedPath(); p != expectedDevicesPath {
+			t.Errorf("Unexpected path for group %q: %s instead of %s", groupName, p, expectedDevicesPath)
+		}
+	}
+}
+
+func TestListGroupDevicesWithDetails(t *testing.T) {
+	c, _ := getTestContext(t)
+	p, err := c.ListGroupDevices(testRealmName, testGroupName, 10, DeviceDetailsFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nextPageCall, err := p.GetNextPage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query := nextPageCall.(GetNextDeviceListPageRequest).req.URL.Query(); query.Get("details") != "true" {
+		t.Errorf("Unexpected query: %v", query)
+	}
+	details, err := runner.RunAndParse[[]DeviceDetails](c, nextPageCall.Run)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(details) != len(testDeviceIDs) || details[1].DeviceID != testDeviceIDs[1] || details[2].Introspection[testInterfaceName].Major != 2 {
+		t.Errorf("Unexpected device details: %v", details)
+	}
+
+	// The device stats of the realm do not apply to a group
+	if _, err := p.(*DeviceListPaginator).EstimatedTotal(); err != ErrNoEstimatedTotal {
+		t.Errorf("Expected ErrNoEstimatedTotal, found %v", err)
+	}
+}
+
+func TestInvalidGroupNames(t *testing.T) {
+	c, _ := getTestContext(t)
+	for _, groupName := range []string{"", "@reserved", "~reserved", ".."} {
+		if _, err := c.CreateGroup(testRealmName, groupName, testDeviceIDs); err == nil {
+			t.Errorf("Group name %q should not be accepted by CreateGroup", groupName)
+		}
+		if _, err := c.AddDeviceToGroup(testRealmName, groupName, testDeviceID); err == nil {
+			t.Errorf("Group name %q should not be accepted by AddDeviceToGroup", groupName)
+		}
+		if _, err := c.RemoveDeviceFromGroup(testRealmName, groupName, testDeviceID); err == nil {
+			t.Errorf("Group name %q should not be accepted by RemoveDeviceFromGroup", groupName)
+		}
+		if _, err := c.ListGroupDevices(testRealmName, groupName, 10, DeviceIDFormat); err == nil {
+			t.Errorf("Group name %q should not be accepted by ListGroupDevices", groupName)
+		}
+	}
+}
@@ -17,9 +17,10 @@ package client
 import (
 	"fmt"
 	"net/http"
-	"net/url"
 
 	"github.com/astarte-platform/astarte-go/deviceid"
+	"github.com/astarte-platform/astarte-go/groups"
+	"github.com/astarte-platform/astarte-go/internal/urlbuilder"
 	"moul.io/http2curl"
 )
 
@@ -35,75 +36,85 @@ type deviceIDPayload struct {
 
 type ListGroupsRequest struct {
 	req     *http.Request
-	expects int
+	expects []int
 }
 
 // ListGroups builds a request to list the groups in a Realm.
 func (c *Client) ListGroups(realm string) (AstarteRequest, error) {
-	callURL := makeURL(c.appEngineURL, "/v1/%s/groups", realm)
+	callURL := urlbuilder.Build(c.appEngineURL, "/v1/%s/groups", realm)
 	req := c.makeHTTPrequest(http.MethodGet, callURL, nil)
 
-	return ListGroupsRequest{req: req, expects: 200}, nil
+	return ListGroupsRequest{req: req, expects: []int{http.StatusOK}}, nil
 }
 
 // nolint:bodyclose
 func (r ListGroupsRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return runAstarteRequestError(res, r.expects)
 	}
 	return ListGroupsResponse{res: res}, nil
 }
 
 func (r ListGroupsRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	return fmt.Sprint(command)
 }
 
 type CreateGroupRequest struct {
 	req     *http.Request
-	expects int
+	expects []int
 }
 
 // CreateGroup builds a request to create a group with the given deviceIDList in the Realm.
 // Only valid Astarte device IDs can be used when adding devices to a group.
 func (c *Client) CreateGroup(realm, groupName string, deviceIDList []string) (AstarteRequest, error) {
+	if !groups.IsValid(groupName) {
+		return Empty{}, ErrInvalidGroupName(groupName)
+	}
 	for _, deviceID := range deviceIDList {
 		if !deviceid.IsValid(deviceID) {
 			return Empty{}, ErrInvalidDeviceID(deviceID)
 		}
 	}
 
-	callURL := makeURL(c.appEngineURL, "/v1/%s/groups", realm)
+	callURL := urlbuilder.Build(c.appEngineURL, "/v1/%s/groups", realm)
 	payload, _ := makeBody(DevicesAndGroup{GroupName: groupName, Devices: deviceIDList})
 	req := c.makeHTTPrequest(http.MethodPost, callURL, payload)
 
-	return CreateGroupRequest{req: req, expects: 201}, nil
+	return CreateGroupRequest{req: req, expects: []int{http.StatusCreated, http.StatusOK}}, nil
 }
 
 // nolint:bodyclose
 func (r CreateGroupRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return runAstarteRequestError(res, r.expects)
 	}
 	return CreateGroupResponse{res: res}, nil
 }
 
 func (r CreateGroupRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	return fmt.Sprint(command)
 }
 
 // ListGroupDevices builds a paginator to request a list of the devices that belong to a group.
+// The group name can contain any character, including slashes: it is escaped when building the URL.
+// As with GetDeviceListPaginator, pages hold either Device IDs or, with DeviceDetailsFormat, the DeviceDetails
+// of the devices, sparing a GetDeviceDetails call for each device in the page.
 func (c *Client) ListGroupDevices(realm, groupName string, pageSize int, format DeviceResultFormat) (Paginator, error) {
-	callURL := makeURL(c.appEngineURL, "/v1/%s/groups/%s/devices", realm, url.PathEscape(groupName))
+	if !groups.IsValid(groupName) {
+		return &DeviceListPaginator{}, ErrInvalidGroupName(groupName)
+	}
+
+	callURL := urlbuilder.Build(c.appEngineURL, "/v1/%s/groups/%s/devices", realm, groupName)
 	paginator, err := c.GetDeviceListPaginator(realm, pageSize, format)
 	if err != nil {
 		return &DeviceListPaginator{}, err
@@ -111,77 +122,84 @@ func (c *Client) ListGroupDevices(realm, groupName string, pageSize int, format
 
 	deviceListPaginator := paginator.(*DeviceListPaginator)
 	deviceListPaginator.baseURL = callURL
+	deviceListPaginator.groupName = groupName
 
 	return deviceListPaginator, nil
 }
 
 type AddDeviceToGroupRequest struct {
 	req     *http.Request
-	expects int
+	expects []int
 }
 
 // AddDeviceToGroup builds a request to add a device to a group.
 // Only valid Astarte device IDs can be used when adding a device to a group.
 func (c *Client) AddDeviceToGroup(realm, groupName, deviceID string) (AstarteRequest, error) {
+	if !groups.IsValid(groupName) {
+		return Empty{}, ErrInvalidGroupName(groupName)
+	}
 	if !deviceid.IsValid(deviceID) {
 		return Empty{}, ErrInvalidDeviceID(deviceID)
 	}
 
-	callURL := makeURL(c.appEngineURL, "/v1/%s/groups/%s/devices", realm, url.PathEscape(groupName))
+	callURL := urlbuilder.Build(c.appEngineURL, "/v1/%s/groups/%s/devices", realm, groupName)
 	payload, _ := makeBody(deviceIDPayload{Device: deviceID})
 	req := c.makeHTTPrequest(http.MethodPost, callURL, payload)
 
-	return AddDeviceToGroupRequest{req: req, expects: 201}, nil
+	return AddDeviceToGroupRequest{req: req, expects: []int{http.StatusCreated, http.StatusOK, http.StatusNoContent}}, nil
 }
 
 // nolint:bodyclose
 func (r AddDeviceToGroupRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return runAstarteRequestError(res, r.expects)
 	}
 	return NoDataResponse{res: res}, nil
 }
 
 func (r AddDeviceToGroupRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	return fmt.Sprint(command)
 }
 
 type RemoveDeviceFromGroupRequest struct {
 	req     *http.Request
-	expects int
+	expects []int
 }
 
 // RemoveDeviceFromGroup builds a request to removes a device from the group.
 // Only valid Astarte device IDs can be used when removing a device from a group.
 func (c *Client) RemoveDeviceFromGroup(realm, groupName, deviceID string) (AstarteRequest, error) {
+	if !groups.IsValid(groupName) {
+		return Empty{}, ErrInvalidGroupName(groupName)
+	}
 	if !deviceid.IsValid(deviceID) {
 		return Empty{}, ErrInvalidDeviceID(deviceID)
 	}
 
-	callURL := makeURL(c.appEngineURL, "/v1/%s/groups/%s/devices/%s", realm, url.PathEscape(groupName), deviceID)
+	callURL := urlbuilder.Build(c.appEngineURL, "/v1/%s/groups/%s/devices/%s", realm, groupName, deviceID)
 	req := c.makeHTTPrequest(http.MethodDelete, callURL, nil)
 
-	return RemoveDeviceFromGroupRequest{req: req, expects: 204}, nil
+	return RemoveDeviceFromGroupRequest{req: req, expects: []int{http.StatusNoContent, http.StatusOK}}, nil
 }
 
 // nolint:bodyclose
 func (r RemoveDeviceFromGroupRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
-		return Empty{}, ErrDifferentStatusCode(r.expects, res.StatusCode)
+	if !c.isExpectedStatusCode(res, r.expects) {
+		return Empty{}, ErrUnexpectedStatusCode(r.expects, res.StatusCode)
 	}
 	return NoDataResponse{res: res}, nil
 }
 
 func (r RemoveDeviceFromGroupRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	return fmt.Sprint(command)
 }
@@ -18,6 +18,7 @@ import (
 	"fmt"
 
 	"github.com/astarte-platform/astarte-go/deviceid"
+	"github.com/astarte-platform/astarte-go/internal/urlbuilder"
 )
 
 // resolveDeviceIdentifierType maps a deviceIdentifier and DeviceIdentifierType to a resolved
@@ -36,15 +37,26 @@ func resolveDeviceIdentifierType(deviceIdentifier string, deviceIdentifierType D
 	}
 }
 
+// resolveDeviceIdentifier works like resolveDeviceIdentifierType, but when the client uses strict device
+// identifiers (see WithStrictDeviceIdentifiers), autodiscovering an identifier which is a valid Device ID fails
+// with ErrAmbiguousIdentifier, since it could be an alias too.
+func (c *Client) resolveDeviceIdentifier(deviceIdentifier string, deviceIdentifierType DeviceIdentifierType) (DeviceIdentifierType, error) {
+	if c.strictDeviceIdentifiers && deviceIdentifierType == AutodiscoverDeviceIdentifier && deviceid.IsValid(deviceIdentifier) {
+		return deviceIdentifierType, fmt.Errorf("%w: %s", ErrAmbiguousIdentifier, deviceIdentifier)
+	}
+	return resolveDeviceIdentifierType(deviceIdentifier, deviceIdentifierType), nil
+}
+
 // devicePath accepts a deviceIdentifier and a resolved DeviceIdentifierType (i.e. AstarteDeviceID
 // or AstarteDeviceAlias) and returns the path for that device. AutodiscoverDeviceIdentifier has to
-// be resolved with resolveDeviceIdentifierType first
-func devicePath(deviceIdentifier string, deviceIdentifierType DeviceIdentifierType) string {
+// be resolved with resolveDeviceIdentifierType first. The identifier is escaped, so that aliases can hold any character.
+func devicePath(deviceIdentifier string, deviceIdentifierType DeviceIdentifierType) urlbuilder.Escaped {
 	switch deviceIdentifierType {
 	case AstarteDeviceID:
-		return fmt.Sprintf("devices/%v", deviceIdentifier)
+		return urlbuilder.Escaped("devices/" + urlbuilder.EscapeSegment(deviceIdentifier))
 	case AstarteDeviceAlias:
-		return fmt.Sprintf("devices-by-alias/%v", deviceIdentifier)
+		// Aliases can contain slashes, which are escaped as part of the alias
+		return urlbuilder.Escaped("devices-by-alias/" + urlbuilder.EscapeSegment(deviceIdentifier))
 	}
 	return ""
 }
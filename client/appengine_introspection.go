@@ -0,0 +1,241 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/astarte-platform/astarte-go/interfaces"
+	"github.com/astarte-platform/astarte-go/internal/runner"
+)
+
+const (
+	defaultInterfaceFetchConcurrency = 5
+	defaultDeviceDetailsPageSize     = 100
+)
+
+type interfaceCacheKey struct {
+	realm string
+	name  string
+	major int
+}
+
+// GetInterfacesForDevice returns the definitions of all interfaces in the introspection of a device, as a map
+// of interface names to interfaces.AstarteInterface. Definitions are retrieved concurrently from Realm Management,
+// using the major version declared by the device, and are cached in the Client: see ClearInterfaceCache.
+// If some definitions can't be retrieved, the returned map contains the ones which could, and the returned error
+// joins the errors for each failed interface.
+// The Client must be authorized to access both AppEngine and Realm Management APIs.
+func (c *Client) GetInterfacesForDevice(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType) (map[string]interfaces.AstarteInterface, error) {
+	getDeviceDetailsCall, err := c.GetDeviceDetails(realm, deviceIdentifier, deviceIdentifierType)
+	if err != nil {
+		return nil, err
+	}
+	details, err := runner.RunAndParse[DeviceDetails](c, getDeviceDetailsCall.Run)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string]interfaces.AstarteInterface, len(details.Introspection))
+	errs := []error{}
+	names := make([]string, 0, len(details.Introspection))
+	for name := range details.Introspection {
+		names = append(names, name)
+	}
+	var mu sync.Mutex
+	forEachConcurrently(names, defaultInterfaceFetchConcurrency, func(_ int, name string) {
+		major := details.Introspection[name].Major
+		iface, err := c.getCachedInterface(realm, name, major)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s v%d: %w", name, major, err))
+			return
+		}
+		ret[name] = iface
+	})
+
+	return ret, errors.Join(errs...)
+}
+
+// ClearInterfaceCache removes all interface definitions cached by GetInterfacesForDevice. This is needed
+// to retrieve interfaces which have been updated to a new minor version.
+func (c *Client) ClearInterfaceCache() {
+	c.interfaceCache.Range(func(key, _ any) bool {
+		c.interfaceCache.Delete(key)
+		return true
+	})
+}
+
+func (c *Client) getCachedInterface(realm, name string, major int) (interfaces.AstarteInterface, error) {
+	key := interfaceCacheKey{realm: realm, name: name, major: major}
+	if cached, ok := c.interfaceCache.Load(key); ok {
+		return cached.(interfaces.AstarteInterface), nil
+	}
+
+	getInterfaceCall, err := c.GetInterface(realm, name, major)
+	if err != nil {
+		return interfaces.AstarteInterface{}, err
+	}
+	iface, err := runner.RunAndParse[interfaces.AstarteInterface](c, getInterfaceCall.Run)
+	if err != nil {
+		return interfaces.AstarteInterface{}, err
+	}
+	c.interfaceCache.Store(key, iface)
+	return iface, nil
+}
+
+// InterfaceVersion is a version of an interface, as declared in the introspection of a device.
+type InterfaceVersion struct {
+	Major int
+	Minor int
+}
+
+// interfaceNameRegexp matches valid interface names, in reverse domain notation.
+var interfaceNameRegexp = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9]*\.([a-zA-Z0-9][a-zA-Z0-9-]*\.)*)?[a-zA-Z][a-zA-Z0-9]*$`)
+
+// IntrospectionPatch describes a change to the introspection of a device.
+type IntrospectionPatch struct {
+	// Set adds interfaces to the introspection, or replaces their version.
+	Set map[string]InterfaceVersion
+	// Remove removes interfaces from the introspection.
+	Remove []string
+}
+
+// Validate checks that p is not empty, that all interface names are valid and appear only once, and that
+// all versions are valid, i.e. have non-negative numbers and are not 0.0.
+func (p IntrospectionPatch) Validate() error {
+	if len(p.Set) == 0 && len(p.Remove) == 0 {
+		return ErrEmptyIntrospectionPatch
+	}
+	for name, version := range p.Set {
+		if !interfaceNameRegexp.MatchString(name) {
+			return ErrInvalidInterfaceName(name)
+		}
+		if version.Major < 0 || version.Minor < 0 || (version.Major == 0 && version.Minor == 0) {
+			return fmt.Errorf("Invalid version %d.%d for interface %s", version.Major, version.Minor, name)
+		}
+	}
+	removed := map[string]bool{}
+	for _, name := range p.Remove {
+		if !interfaceNameRegexp.MatchString(name) {
+			return ErrInvalidInterfaceName(name)
+		}
+		if _, ok := p.Set[name]; ok || removed[name] {
+			return fmt.Errorf("Interface %s appears more than once in the introspection patch", name)
+		}
+		removed[name] = true
+	}
+	return nil
+}
+
+// mergePatch returns the JSON merge patch of the introspection of a device described by p.
+func (p IntrospectionPatch) mergePatch() map[string]map[string]any {
+	introspection := map[string]any{}
+	for name, version := range p.Set {
+		introspection[name] = map[string]int{"major": version.Major, "minor": version.Minor}
+	}
+	for _, name := range p.Remove {
+		introspection[name] = nil
+	}
+	return map[string]map[string]any{"introspection": introspection}
+}
+
+// appliedTo returns ErrIntrospectionNotPatched if introspection, the introspection of a device after patching it,
+// does not reflect p.
+func (p IntrospectionPatch) appliedTo(introspection map[string]DeviceInterfaceIntrospection) error {
+	for name, version := range p.Set {
+		if current, ok := introspection[name]; !ok || current.Major != version.Major || current.Minor != version.Minor {
+			return fmt.Errorf("%w: interface %s is not at version %d.%d", ErrIntrospectionNotPatched, name, version.Major, version.Minor)
+		}
+	}
+	for _, name := range p.Remove {
+		if _, ok := introspection[name]; ok {
+			return fmt.Errorf("%w: interface %s was not removed", ErrIntrospectionNotPatched, name)
+		}
+	}
+	return nil
+}
+
+// InterfaceAdoption reports how many devices of a realm expose each version of an interface.
+type InterfaceAdoption struct {
+	InterfaceName string
+	// TotalDevices is the number of devices in the realm.
+	TotalDevices int
+	// Versions maps each version of the interface to the number of devices exposing it.
+	// Versions exposed by no device are not included.
+	Versions map[InterfaceVersion]int
+	// DeviceIDs maps each version of the interface to the Device IDs of the devices exposing it, in listing order.
+	DeviceIDs map[InterfaceVersion][]string
+}
+
+// DevicesWithMajor returns the number of devices exposing any minor version of the major version of the interface.
+func (a InterfaceAdoption) DevicesWithMajor(major int) int {
+	devices := 0
+	for version, count := range a.Versions {
+		if version.Major == major {
+			devices += count
+		}
+	}
+	return devices
+}
+
+// DeviceIDsWithMajor returns the sorted Device IDs of the devices exposing any minor version of the major version
+// of the interface.
+func (a InterfaceAdoption) DeviceIDsWithMajor(major int) []string {
+	deviceIDs := []string{}
+	for version, versionDeviceIDs := range a.DeviceIDs {
+		if version.Major == major {
+			deviceIDs = append(deviceIDs, versionDeviceIDs...)
+		}
+	}
+	sort.Strings(deviceIDs)
+	return deviceIDs
+}
+
+// InterfaceAdoptionReport walks the details of all devices in a realm and counts how many of them expose each
+// version of interfaceName in their introspection. This is useful to check whether an old major version is still
+// in use before deleting it.
+func (c *Client) InterfaceAdoptionReport(realm, interfaceName string) (InterfaceAdoption, error) {
+	report := InterfaceAdoption{InterfaceName: interfaceName, Versions: map[InterfaceVersion]int{}, DeviceIDs: map[InterfaceVersion][]string{}}
+	paginator, err := c.GetDeviceListPaginator(realm, defaultDeviceDetailsPageSize, DeviceDetailsFormat)
+	if err != nil {
+		return report, err
+	}
+	for paginator.HasNextPage() {
+		nextPageCall, err := paginator.GetNextPage()
+		if err != nil {
+			return report, err
+		}
+		devices, err := runner.RunAndParse[[]DeviceDetails](c, nextPageCall.Run)
+		if err != nil {
+			return report, err
+		}
+		for _, device := range devices {
+			report.TotalDevices++
+			if introspection, ok := device.Introspection[interfaceName]; ok {
+				version := InterfaceVersion{Major: introspection.Major, Minor: introspection.Minor}
+				report.Versions[version]++
+				report.DeviceIDs[version] = append(report.DeviceIDs[version], device.DeviceID)
+			}
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,256 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/astarte-platform/astarte-go/internal/urlbuilder"
+)
+
+// LabelAttributePrefix prefixes the keys of the Device attributes holding labels. Labels are key/value pairs, as in
+// Kubernetes, which group devices orthogonally to Astarte groups: e.g. the label env=prod is stored as the attribute
+// labels/env with value prod. Devices can be selected by their labels with a LabelSelector.
+const LabelAttributePrefix = "labels/"
+
+const maxLabelLength = 63
+
+// labelPattern matches label keys, and non-empty label values: alphanumeric characters, possibly separated by
+// dashes, underscores and dots.
+var labelPattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9_.-]*[A-Za-z0-9])?$`)
+
+// ValidateLabel returns an error if key is not a valid label key or value is not a valid label value. Keys and
+// non-empty values are at most 63 characters long, made of alphanumeric characters, dashes, underscores and dots,
+// and start and end with an alphanumeric character.
+func ValidateLabel(key, value string) error {
+	if len(key) > maxLabelLength || !labelPattern.MatchString(key) {
+		return fmt.Errorf("%w: invalid key %q", ErrInvalidLabel, key)
+	}
+	if value != "" && (len(value) > maxLabelLength || !labelPattern.MatchString(value)) {
+		return fmt.Errorf("%w: invalid value %q for key %s", ErrInvalidLabel, value, key)
+	}
+	return nil
+}
+
+// Labels returns the labels of the device, stored in its attributes with keys prefixed by LabelAttributePrefix.
+func (d DeviceDetails) Labels() map[string]string {
+	labels := map[string]string{}
+	for key, value := range d.Attributes {
+		if strings.HasPrefix(key, LabelAttributePrefix) {
+			labels[strings.TrimPrefix(key, LabelAttributePrefix)] = value
+		}
+	}
+	return labels
+}
+
+// SetDeviceLabels builds a request to set many labels of a Device at once, see LabelAttributePrefix. Other labels of
+// the Device are left as they are. Labels must be valid according to ValidateLabel and, if the client has an
+// AttributeSchema, their attributes must be valid according to it.
+func (c *Client) SetDeviceLabels(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, labels map[string]string) (AstarteRequest, error) {
+	attributes := make(map[string]string, len(labels))
+	for key, value := range labels {
+		if err := ValidateLabel(key, value); err != nil {
+			return Empty{}, err
+		}
+		attributes[LabelAttributePrefix+key] = value
+	}
+	return c.SetDeviceAttributes(realm, deviceIdentifier, deviceIdentifierType, attributes)
+}
+
+// RemoveDeviceLabels builds a request to remove many labels of a Device at once, see LabelAttributePrefix. Label keys
+// must be valid according to ValidateLabel.
+func (c *Client) RemoveDeviceLabels(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, labelKeys ...string) (AstarteRequest, error) {
+	for _, key := range labelKeys {
+		if err := ValidateLabel(key, ""); err != nil {
+			return Empty{}, err
+		}
+	}
+	resolvedDeviceIdentifierType, err := c.resolveDeviceIdentifier(deviceIdentifier, deviceIdentifierType)
+	if err != nil {
+		return Empty{}, err
+	}
+	callURL := urlbuilder.Build(c.appEngineURL, "/v1/%s/%s", realm, devicePath(deviceIdentifier, resolvedDeviceIdentifierType))
+	// null values remove the attributes in the merge patch
+	attributes := make(map[string]any, len(labelKeys))
+	for _, key := range labelKeys {
+		attributes[LabelAttributePrefix+key] = nil
+	}
+	payload, _ := makeBody(map[string]map[string]any{"attributes": attributes})
+	req := c.makeHTTPrequestWithContentType(http.MethodPatch, callURL, payload, "application/merge-patch+json")
+
+	return DeleteDeviceAttributeRequest{req: req, expects: []int{http.StatusOK, http.StatusNoContent}}, nil
+}
+
+// LabelOperator is the operator of a LabelRequirement.
+type LabelOperator string
+
+const (
+	// LabelEquals requires the label to have the only value of the requirement, e.g. env=prod or env==prod.
+	LabelEquals LabelOperator = "="
+	// LabelNotEquals requires the label not to have the only value of the requirement, e.g. env!=prod.
+	// Devices without the label match.
+	LabelNotEquals LabelOperator = "!="
+	// LabelIn requires the label to have one of the values of the requirement, e.g. region in (eu,us).
+	LabelIn LabelOperator = "in"
+	// LabelNotIn requires the label to have none of the values of the requirement, e.g. region notin (eu,us).
+	// Devices without the label match.
+	LabelNotIn LabelOperator = "notin"
+	// LabelExists requires the label to be set, e.g. env.
+	LabelExists LabelOperator = "exists"
+	// LabelDoesNotExist requires the label not to be set, e.g. !env.
+	LabelDoesNotExist LabelOperator = "!"
+)
+
+// LabelRequirement is a requirement on a label of a LabelSelector.
+type LabelRequirement struct {
+	Key      string
+	Operator LabelOperator
+	// Values are sorted, and empty for LabelExists and LabelDoesNotExist.
+	Values []string
+}
+
+// Matches returns whether labels satisfy r.
+func (r LabelRequirement) Matches(labels map[string]string) bool {
+	value, ok := labels[r.Key]
+	switch r.Operator {
+	case LabelExists:
+		return ok
+	case LabelDoesNotExist:
+		return !ok
+	case LabelEquals, LabelIn:
+		return ok && r.hasValue(value)
+	case LabelNotEquals, LabelNotIn:
+		return !ok || !r.hasValue(value)
+	default:
+		return false
+	}
+}
+
+func (r LabelRequirement) hasValue(value string) bool {
+	i := sort.SearchStrings(r.Values, value)
+	return i < len(r.Values) && r.Values[i] == value
+}
+
+func (r LabelRequirement) String() string {
+	switch r.Operator {
+	case LabelExists:
+		return r.Key
+	case LabelDoesNotExist:
+		return "!" + r.Key
+	case LabelIn, LabelNotIn:
+		return fmt.Sprintf("%s %s (%s)", r.Key, r.Operator, strings.Join(r.Values, ","))
+	default:
+		return r.Key + string(r.Operator) + strings.Join(r.Values, ",")
+	}
+}
+
+// LabelSelector selects devices by their labels, see ParseLabelSelector. A device is selected if its labels satisfy
+// all requirements: the empty selector selects all devices.
+type LabelSelector []LabelRequirement
+
+// ParseLabelSelector parses a selector made of comma-separated requirements, with the syntax of Kubernetes label
+// selectors: e.g. "env=prod,region in (eu,us),!deprecated" selects the devices with label env set to prod,
+// label region set to eu or us and without label deprecated. The supported operators are listed in LabelOperator.
+func ParseLabelSelector(selector string) (LabelSelector, error) {
+	labelSelector := LabelSelector{}
+	if strings.TrimSpace(selector) == "" {
+		return labelSelector, nil
+	}
+	for _, requirement := range splitLabelSelector(selector) {
+		r, err := parseLabelRequirement(requirement)
+		if err != nil {
+			return nil, err
+		}
+		labelSelector = append(labelSelector, r)
+	}
+	return labelSelector, nil
+}
+
+// Matches returns whether labels satisfy all the requirements of s.
+func (s LabelSelector) Matches(labels map[string]string) bool {
+	for _, requirement := range s {
+		if !requirement.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchesDevice returns whether the labels of device satisfy all the requirements of s, see DeviceDetails.Labels.
+func (s LabelSelector) MatchesDevice(device DeviceDetails) bool {
+	return s.Matches(device.Labels())
+}
+
+func (s LabelSelector) String() string {
+	requirements := make([]string, 0, len(s))
+	for _, requirement := range s {
+		requirements = append(requirements, requirement.String())
+	}
+	return strings.Join(requirements, ",")
+}
+
+// splitLabelSelector splits selector on the commas which are not within the parentheses of a set of values.
+func splitLabelSelector(selector string) []string {
+	requirements := []string{}
+	depth, start := 0, 0
+	for i, r := range selector {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case r == ',' && depth == 0:
+			requirements = append(requirements, selector[start:i])
+			start = i + 1
+		}
+	}
+	return append(requirements, selector[start:])
+}
+
+var labelSetRequirementPattern = regexp.MustCompile(`^(\S+)\s+(in|notin)\s*\((.*)\)$`)
+
+func parseLabelRequirement(requirement string) (LabelRequirement, error) {
+	trimmed := strings.TrimSpace(requirement)
+	r := LabelRequirement{Values: []string{}}
+	switch match := labelSetRequirementPattern.FindStringSubmatch(trimmed); {
+	case match != nil:
+		r.Key, r.Operator = match[1], LabelOperator(match[2])
+		for _, value := range strings.Split(match[3], ",") {
+			r.Values = append(r.Values, strings.TrimSpace(value))
+		}
+	case strings.HasPrefix(trimmed, "!") && !strings.Contains(trimmed, "="):
+		r.Key, r.Operator = strings.TrimSpace(trimmed[1:]), LabelDoesNotExist
+	case strings.Contains(trimmed, "!="):
+		key, value, _ := strings.Cut(trimmed, "!=")
+		r.Key, r.Operator, r.Values = strings.TrimSpace(key), LabelNotEquals, []string{strings.TrimSpace(value)}
+	case strings.Contains(trimmed, "="):
+		key, value, _ := strings.Cut(strings.Replace(trimmed, "==", "=", 1), "=")
+		r.Key, r.Operator, r.Values = strings.TrimSpace(key), LabelEquals, []string{strings.TrimSpace(value)}
+	default:
+		r.Key, r.Operator = trimmed, LabelExists
+	}
+
+	for _, value := range append([]string{""}, r.Values...) {
+		if err := ValidateLabel(r.Key, value); err != nil {
+			return LabelRequirement{}, fmt.Errorf("%w: %q: %v", ErrInvalidLabelSelector, trimmed, err)
+		}
+	}
+	sort.Strings(r.Values)
+	return r, nil
+}
@@ -0,0 +1,84 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"fmt"
+)
+
+// DefaultMaxPageSize is the largest number of items Astarte returns in a page by default, see WithMaxPageSize.
+const DefaultMaxPageSize = 10000
+
+// clampPageSize returns pageSize, or the maximum page size of the client if pageSize is larger.
+func (c *Client) clampPageSize(pageSize int) int {
+	maxPageSize := c.maxPageSize
+	if maxPageSize == 0 {
+		maxPageSize = DefaultMaxPageSize
+	}
+	if pageSize > maxPageSize {
+		return maxPageSize
+	}
+	return pageSize
+}
+
+// PaginateToChannel runs the page loop of p in a goroutine, sending each item of each page to out.
+// Sending blocks until the item is received, so a slow consumer slows down paginating Astarte.
+// out is closed once all pages are consumed, an error occurs or ctx is done; the returned channel then
+// receives the final error, which is nil if all items were sent, and is closed.
+// T is the element type of the pages returned by p, e.g. string or DeviceDetails for a DeviceListPaginator
+// and DatastreamIndividualValue or DatastreamObjectValue for a DatastreamPaginator on a complete path.
+func PaginateToChannel[T any](ctx context.Context, p Paginator, out chan<- T) <-chan error {
+	errc := make(chan error, 1)
+	go func() {
+		err := paginateToChannel(ctx, p, out)
+		close(out)
+		errc <- err
+		close(errc)
+	}()
+	return errc
+}
+
+func paginateToChannel[T any](ctx context.Context, p Paginator, out chan<- T) error {
+	for p.HasNextPage() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		nextPageCall, err := p.GetNextPage()
+		if err != nil {
+			return err
+		}
+		res, err := nextPageCall.Run(p.astarteClient())
+		if err != nil {
+			return err
+		}
+		data, err := res.Parse()
+		if err != nil {
+			return err
+		}
+		items, ok := data.([]T)
+		if !ok {
+			return fmt.Errorf("Unexpected page of type %T", data)
+		}
+		for _, item := range items {
+			select {
+			case out <- item:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+	return nil
+}
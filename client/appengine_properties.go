@@ -0,0 +1,283 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/interfaces"
+	"github.com/astarte-platform/astarte-go/internal/runner"
+)
+
+// GetAllDeviceProperties returns all the properties currently set on a device, as a map of interface names
+// to maps of paths to values. Only properties interfaces in the introspection of the device are considered, and
+// if ownership is not empty, only the ones with the given ownership. Values are converted to the Go type of
+// their mapping (e.g. int64 for "longinteger", time.Time for "datetime"), see interfaces.AstarteMappingType.
+// Interfaces with no properties set are returned as empty maps.
+// If some interfaces can't be retrieved, the returned map contains the ones which could, and the returned error
+// joins the errors for each failed interface.
+// The Client must be authorized to access both AppEngine and Realm Management APIs.
+func (c *Client) GetAllDeviceProperties(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType,
+	ownership interfaces.AstarteInterfaceOwnership) (map[string]map[string]PropertyValue, error) {
+	if ownership != "" {
+		if err := ownership.IsValid(); err != nil {
+			return nil, err
+		}
+	}
+
+	deviceInterfaces, err := c.GetInterfacesForDevice(realm, deviceIdentifier, deviceIdentifierType)
+	if deviceInterfaces == nil {
+		return nil, err
+	}
+	errs := []error{}
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	ret := map[string]map[string]PropertyValue{}
+	propertiesInterfaces := []interfaces.AstarteInterface{}
+	for _, iface := range deviceInterfaces {
+		if iface.Type == interfaces.PropertiesType && (ownership == "" || iface.Ownership == ownership) {
+			propertiesInterfaces = append(propertiesInterfaces, iface)
+		}
+	}
+	var mu sync.Mutex
+	forEachConcurrently(propertiesInterfaces, defaultInterfaceFetchConcurrency, func(_ int, iface interfaces.AstarteInterface) {
+		properties, err := c.getTypedProperties(realm, deviceIdentifier, deviceIdentifierType, iface)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", iface.Name, err))
+			return
+		}
+		ret[iface.Name] = properties
+	})
+
+	return ret, errors.Join(errs...)
+}
+
+const defaultPropertySetConcurrency = 5
+
+// PropertySetResult is the result of setting a single property with SetProperties.
+type PropertySetResult struct {
+	Path string
+	// Err is set if the property could not be set.
+	Err error
+}
+
+// SetProperties sets many properties on a server-owned properties interface at once, as a map of paths to values.
+// All values are validated against astarteInterface as SendData does before any request is sent: if some of them
+// are invalid, no property is set and the returned error joins the errors for each invalid path. Properties are
+// then set running up to concurrency requests at a time (5 if concurrency is not positive), and the outcome for each
+// path is reported in the returned results, sorted by path. Failing to set a property does not stop the others.
+func (c *Client) SetProperties(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType,
+	astarteInterface interfaces.AstarteInterface, values map[string]any, concurrency int) ([]PropertySetResult, error) {
+	if astarteInterface.Type != interfaces.PropertiesType {
+		return nil, fmt.Errorf("Interface %s %d.%d is not a properties interface", astarteInterface.Name, astarteInterface.MajorVersion, astarteInterface.MinorVersion)
+	}
+	paths := make([]string, 0, len(values))
+	errs := []error{}
+	for path, value := range values {
+		paths = append(paths, path)
+		if err := c.validateSendData(astarteInterface, path, value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	sort.Strings(paths)
+	if concurrency <= 0 {
+		concurrency = defaultPropertySetConcurrency
+	}
+
+	results := make([]PropertySetResult, len(paths))
+	forEachConcurrently(paths, concurrency, func(i int, path string) {
+		results[i] = PropertySetResult{Path: path, Err: c.setProperty(realm, deviceIdentifier, deviceIdentifierType, astarteInterface.Name, path, values[path])}
+	})
+
+	return results, nil
+}
+
+func (c *Client) setProperty(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, interfaceName, interfacePath string, value any) error {
+	setPropertyCall, err := c.SetProperty(realm, deviceIdentifier, deviceIdentifierType, interfaceName, interfacePath, value)
+	if err != nil {
+		return err
+	}
+	return runWithoutParsing(c, setPropertyCall)
+}
+
+func (c *Client) getTypedProperties(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType,
+	iface interfaces.AstarteInterface) (map[string]PropertyValue, error) {
+	getAllPropertiesCall, err := c.GetAllProperties(realm, deviceIdentifier, deviceIdentifierType, iface.Name)
+	if err != nil {
+		return nil, err
+	}
+	properties, err := runner.RunAndParse[map[string]PropertyValue](c, getAllPropertiesCall.Run)
+	if err != nil {
+		return nil, err
+	}
+
+	compiled := interfaces.Compile(iface)
+	ret := make(map[string]PropertyValue, len(properties))
+	for path, value := range properties {
+		if value == nil {
+			// Astarte returns no data when no property is set
+			continue
+		}
+		mapping, err := compiled.MappingFromPath(path)
+		if err != nil {
+			return nil, err
+		}
+		typed, err := typedPropertyValue(mapping.Type, value)
+		if err != nil {
+			return nil, fmt.Errorf("Cannot convert value on %s: %w", path, err)
+		}
+		ret[path] = typed
+	}
+	return ret, nil
+}
+
+// typedPropertyValue converts a value decoded from JSON to the Go type of mappingType.
+func typedPropertyValue(mappingType interfaces.AstarteMappingType, value any) (any, error) {
+	switch mappingType {
+	case interfaces.Double:
+		return convertJSONValue[float64](value, toDouble)
+	case interfaces.Integer:
+		return convertJSONValue[int](value, toInteger)
+	case interfaces.LongInteger:
+		return convertJSONValue[int64](value, toLongInteger)
+	case interfaces.Boolean:
+		return convertJSONValue[bool](value, toBoolean)
+	case interfaces.String:
+		return convertJSONValue[string](value, toString)
+	case interfaces.BinaryBlob:
+		return convertJSONValue[[]byte](value, toBinaryBlob)
+	case interfaces.DateTime:
+		return convertJSONValue[time.Time](value, toDateTime)
+	case interfaces.DoubleArray:
+		return convertJSONArray[float64](value, toDouble)
+	case interfaces.IntegerArray:
+		return convertJSONArray[int](value, toInteger)
+	case interfaces.LongIntegerArray:
+		return convertJSONArray[int64](value, toLongInteger)
+	case interfaces.BooleanArray:
+		return convertJSONArray[bool](value, toBoolean)
+	case interfaces.StringArray:
+		return convertJSONArray[string](value, toString)
+	case interfaces.BinaryBlobArray:
+		return convertJSONArray[[]byte](value, toBinaryBlob)
+	case interfaces.DateTimeArray:
+		return convertJSONArray[time.Time](value, toDateTime)
+	}
+	return nil, fmt.Errorf("Unknown mapping type %s", mappingType)
+}
+
+func convertJSONValue[T any](value any, convert func(any) (T, bool)) (any, error) {
+	ret, ok := convert(value)
+	if !ok {
+		return nil, fmt.Errorf("Unexpected value %v of type %T", value, value)
+	}
+	return ret, nil
+}
+
+func convertJSONArray[T any](value any, convert func(any) (T, bool)) (any, error) {
+	array, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("Value %v is not an array", value)
+	}
+	ret := make([]T, len(array))
+	for i, v := range array {
+		if ret[i], ok = convert(v); !ok {
+			return nil, fmt.Errorf("Unexpected array element %v of type %T", v, v)
+		}
+	}
+	return ret, nil
+}
+
+func toDouble(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case json.Number:
+		// Numbers decoded with WithPreciseNumbers
+		ret, err := v.Float64()
+		return ret, err == nil
+	}
+	return 0, false
+}
+
+func toInteger(value any) (int, bool) {
+	if n, ok := value.(json.Number); ok {
+		v, err := n.Int64()
+		return int(v), err == nil && v == int64(int32(v))
+	}
+	v, ok := value.(float64)
+	if !ok || v != float64(int32(v)) {
+		return 0, false
+	}
+	return int(v), true
+}
+
+func toLongInteger(value any) (int64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return int64(v), v == float64(int64(v))
+	case json.Number:
+		ret, err := v.Int64()
+		return ret, err == nil
+	case string:
+		// Astarte may encode long integers as strings to preserve their precision
+		ret, err := strconv.ParseInt(v, 10, 64)
+		return ret, err == nil
+	}
+	return 0, false
+}
+
+func toBoolean(value any) (bool, bool) {
+	v, ok := value.(bool)
+	return v, ok
+}
+
+func toString(value any) (string, bool) {
+	v, ok := value.(string)
+	return v, ok
+}
+
+func toBinaryBlob(value any) ([]byte, bool) {
+	v, ok := value.(string)
+	if !ok {
+		return nil, false
+	}
+	ret, err := base64.StdEncoding.DecodeString(v)
+	return ret, err == nil
+}
+
+func toDateTime(value any) (time.Time, bool) {
+	v, ok := value.(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	ret, err := time.Parse(time.RFC3339Nano, v)
+	return ret, err == nil
+}
@@ -15,91 +15,186 @@
 package client
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
 	"net/url"
 	"time"
 
 	"github.com/astarte-platform/astarte-go/interfaces"
+	"github.com/astarte-platform/astarte-go/internal/urlbuilder"
 	"moul.io/http2curl"
 )
 
 type GetDatastreamSnapshotRequest struct {
-	req         *http.Request
-	expects     int
-	aggregation interfaces.AstarteInterfaceAggregation
+	req           *http.Request
+	expects       []int
+	aggregation   interfaces.AstarteInterfaceAggregation
+	interfaceName string
+	query         datastreamQuery
+}
+
+type datastreamQuery struct {
+	keepMilliseconds bool
+	// parameterKeys is not sent to Astarte, it is used to key parsed values, see WithParameterKeys
+	parameterKeys *interfaces.AstarteInterface
+	// projection is not sent to Astarte, it is applied to parsed values, see WithValuesOnly
+	projection datastreamProjection
+	// preciseNumbers is not sent to Astarte, it is used to decode values, see WithPreciseNumbers
+	preciseNumbers bool
+}
+
+type datastreamQueryOption func(*datastreamQuery)
+
+// Asks Astarte to return timestamps with millisecond precision, on Astarte versions which support it.
+// By default, Astarte may truncate timestamps to seconds.
+// nolint:golint,revive
+func WithKeepMilliseconds() datastreamQueryOption {
+	return func(q *datastreamQuery) {
+		q.keepMilliseconds = true
+	}
+}
+
+// Keys the values of a parametric object aggregated interface by the values of its parameters
+// rather than by their path, e.g. with endpoints like /sensors/%{id}/temperature a value on path
+// /sensors/s1 is keyed by "s1". Values of interfaces with many parameters are keyed by the parameter
+// values joined with a slash.
+// nolint:golint,revive
+func WithParameterKeys(astarteInterface interfaces.AstarteInterface) datastreamQueryOption {
+	return func(q *datastreamQuery) {
+		q.parameterKeys = &astarteInterface
+	}
+}
+
+// Drops reception timestamps from parsed values, which are then returned with a zero ReceptionTimestamp.
+// nolint:golint,revive
+func WithoutReceptionTimestamps() datastreamQueryOption {
+	return func(q *datastreamQuery) {
+		q.projection = withoutReceptionTimestamps
+	}
+}
+
+// Returns only the values from Parse, without their timestamps: values of individual aggregated interfaces are
+// returned as []any or map[string]any, and values of object aggregated interfaces as []ObjectValues,
+// map[string][]ObjectValues or map[string]ObjectValues, in place of the DatastreamIndividualValue
+// and DatastreamObjectValue returned by default. Value transforms and WithParameterKeys still apply.
+// nolint:golint,revive
+func WithValuesOnly() datastreamQueryOption {
+	return func(q *datastreamQuery) {
+		q.projection = valuesOnly
+	}
+}
+
+// Decodes numeric values as json.Number rather than float64, so that longintegers beyond MaxSafeInteger
+// keep their precision. Use ValueAsInt64 and ValueAsFloat64 to convert them.
+// nolint:golint,revive
+func WithPreciseNumbers() datastreamQueryOption {
+	return func(q *datastreamQuery) {
+		q.preciseNumbers = true
+	}
+}
+
+func newDatastreamQuery(opts []datastreamQueryOption) datastreamQuery {
+	query := datastreamQuery{}
+	for _, f := range opts {
+		f(&query)
+	}
+	return query
+}
+
+func (q datastreamQuery) setURLQuery(query url.Values) {
+	if q.keepMilliseconds {
+		query.Set("keep_milliseconds", "true")
+	}
 }
 
 // GetDatastreamIndividualSnapshot builds a request to return all the last values on all paths for a Datastream individual aggregate interface.
 func (c *Client) GetDatastreamIndividualSnapshot(realm string, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType,
-	interfaceName string) (AstarteRequest, error) {
+	interfaceName string, opts ...datastreamQueryOption) (AstarteRequest, error) {
 	// Let's find the actual device identifier type
-	resolvedDeviceIdentifierType := resolveDeviceIdentifierType(deviceIdentifier, deviceIdentifierType)
+	resolvedDeviceIdentifierType, err := c.resolveDeviceIdentifier(deviceIdentifier, deviceIdentifierType)
+	if err != nil {
+		return Empty{}, err
+	}
 	// and build the URL
-	callURL := makeURL(c.appEngineURL, "/v1/%s/%s/interfaces/%s", realm, devicePath(deviceIdentifier, resolvedDeviceIdentifierType), interfaceName)
+	callURL := urlbuilder.Build(c.appEngineURL, "/v1/%s/%s/interfaces/%s", realm, devicePath(deviceIdentifier, resolvedDeviceIdentifierType), interfaceName)
+	query := url.Values{}
+	snapshotQuery := newDatastreamQuery(opts)
+	snapshotQuery.setURLQuery(query)
+	callURL.RawQuery = query.Encode()
 	req := c.makeHTTPrequest(http.MethodGet, callURL, nil)
 
-	return GetDatastreamSnapshotRequest{req: req, expects: 200, aggregation: interfaces.IndividualAggregation}, nil
+	return GetDatastreamSnapshotRequest{req: req, expects: []int{http.StatusOK}, aggregation: interfaces.IndividualAggregation, interfaceName: interfaceName, query: snapshotQuery}, nil
 }
 
 // GetDatastreamObjectSnapshot builds a request to return the last value for a Datastream object aggregate interface
 func (c *Client) GetDatastreamObjectSnapshot(realm string, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType,
-	interfaceName string) (AstarteRequest, error) {
+	interfaceName string, opts ...datastreamQueryOption) (AstarteRequest, error) {
 	// Let's find the actual device identifier type
-	resolvedDeviceIdentifierType := resolveDeviceIdentifierType(deviceIdentifier, deviceIdentifierType)
+	resolvedDeviceIdentifierType, err := c.resolveDeviceIdentifier(deviceIdentifier, deviceIdentifierType)
+	if err != nil {
+		return Empty{}, err
+	}
 	// and build the URL
-	callURL := makeURL(c.appEngineURL, "/v1/%s/%s/interfaces/%s", realm, devicePath(deviceIdentifier, resolvedDeviceIdentifierType), interfaceName)
+	callURL := urlbuilder.Build(c.appEngineURL, "/v1/%s/%s/interfaces/%s", realm, devicePath(deviceIdentifier, resolvedDeviceIdentifierType), interfaceName)
 	// Quirk: Astarte returns all data, we must limit to the first one
 	query := url.Values{}
 	query.Set("limit", fmt.Sprintf("%d", 1))
+	snapshotQuery := newDatastreamQuery(opts)
+	snapshotQuery.setURLQuery(query)
 	callURL.RawQuery = query.Encode()
 
 	req := c.makeHTTPrequest(http.MethodGet, callURL, nil)
 
-	return GetDatastreamSnapshotRequest{req: req, expects: 200, aggregation: interfaces.ObjectAggregation}, nil
+	return GetDatastreamSnapshotRequest{req: req, expects: []int{http.StatusOK}, aggregation: interfaces.ObjectAggregation, interfaceName: interfaceName, query: snapshotQuery}, nil
 }
 
 // nolint:bodyclose
 func (r GetDatastreamSnapshotRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return runAstarteRequestError(res, r.expects)
 	}
-	return GetDatastreamSnapshotResponse{res: res, aggregation: r.aggregation}, nil
+	return GetDatastreamSnapshotResponse{res: res, aggregation: r.aggregation, transformer: c.valueTransformer(r.interfaceName, ""),
+		parameterKeys: r.query.parameterKeys, projection: r.query.projection, preciseNumbers: r.query.preciseNumbers}, nil
 }
 
 func (r GetDatastreamSnapshotRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	return fmt.Sprint(command)
 }
 
 // GetDatastreamIndividualPaginator returns a Paginator for all the values on a path for a Datastream interface with individual aggregation.
-func (c *Client) GetDatastreamIndividualPaginator(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, interfaceName, interfacePath string, resultSetOrder ResultSetOrder, pageSize int) (Paginator, error) {
-	return c.getDatastreamPaginator(realm, deviceIdentifier, deviceIdentifierType, interfaceName, interfacePath, interfaces.IndividualAggregation, time.Time{}, time.Now(), pageSize, resultSetOrder)
+func (c *Client) GetDatastreamIndividualPaginator(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, interfaceName, interfacePath string, resultSetOrder ResultSetOrder, pageSize int, opts ...datastreamQueryOption) (Paginator, error) {
+	return c.getDatastreamPaginator(realm, deviceIdentifier, deviceIdentifierType, interfaceName, interfacePath, interfaces.IndividualAggregation, time.Time{}, c.clock(), pageSize, resultSetOrder, newDatastreamQuery(opts))
 }
 
 // GetDatastreamIndividualTimeWindowPaginator returns a Paginator for all the values on a path in a specified time window for a Datastream interface with individual aggregation.
-func (c *Client) GetDatastreamIndividualTimeWindowPaginator(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, interfaceName, interfacePath string, since, to time.Time, resultSetOrder ResultSetOrder, pageSize int) (Paginator, error) {
-	return c.getDatastreamPaginator(realm, deviceIdentifier, deviceIdentifierType, interfaceName, interfacePath, interfaces.IndividualAggregation, since, to, pageSize, resultSetOrder)
+func (c *Client) GetDatastreamIndividualTimeWindowPaginator(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, interfaceName, interfacePath string, since, to time.Time, resultSetOrder ResultSetOrder, pageSize int, opts ...datastreamQueryOption) (Paginator, error) {
+	return c.getDatastreamPaginator(realm, deviceIdentifier, deviceIdentifierType, interfaceName, interfacePath, interfaces.IndividualAggregation, since, to, pageSize, resultSetOrder, newDatastreamQuery(opts))
 }
 
 // GetDatastreamObjectPaginator returns a Paginator for all the values on a path for a Datastream interface with object aggregation.
-func (c *Client) GetDatastreamObjectPaginator(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, interfaceName, interfacePath string, resultSetOrder ResultSetOrder, pageSize int) (Paginator, error) {
-	return c.getDatastreamPaginator(realm, deviceIdentifier, deviceIdentifierType, interfaceName, interfacePath, interfaces.ObjectAggregation, time.Time{}, time.Now(), pageSize, resultSetOrder)
+func (c *Client) GetDatastreamObjectPaginator(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, interfaceName, interfacePath string, resultSetOrder ResultSetOrder, pageSize int, opts ...datastreamQueryOption) (Paginator, error) {
+	return c.getDatastreamPaginator(realm, deviceIdentifier, deviceIdentifierType, interfaceName, interfacePath, interfaces.ObjectAggregation, time.Time{}, c.clock(), pageSize, resultSetOrder, newDatastreamQuery(opts))
 }
 
 // GetDatastreamObjectTimeWindowPaginator returns a Paginator for all the values on a path in a specified time window for a Datastream interface with object aggregation.
-func (c *Client) GetDatastreamObjectTimeWindowPaginator(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, interfaceName, interfacePath string, since, to time.Time, resultSetOrder ResultSetOrder, pageSize int) (Paginator, error) {
-	return c.getDatastreamPaginator(realm, deviceIdentifier, deviceIdentifierType, interfaceName, interfacePath, interfaces.ObjectAggregation, since, to, pageSize, resultSetOrder)
+func (c *Client) GetDatastreamObjectTimeWindowPaginator(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, interfaceName, interfacePath string, since, to time.Time, resultSetOrder ResultSetOrder, pageSize int, opts ...datastreamQueryOption) (Paginator, error) {
+	return c.getDatastreamPaginator(realm, deviceIdentifier, deviceIdentifierType, interfaceName, interfacePath, interfaces.ObjectAggregation, since, to, pageSize, resultSetOrder, newDatastreamQuery(opts))
 }
 
 func (c *Client) getDatastreamPaginator(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, interfaceName, interfacePath string,
-	interfaceAggregation interfaces.AstarteInterfaceAggregation, since, to time.Time, pageSize int, resultSetOrder ResultSetOrder) (Paginator, error) {
-	resolvedDeviceIdentifierType := resolveDeviceIdentifierType(deviceIdentifier, deviceIdentifierType)
-	baseURL := makeURL(c.appEngineURL, "/v1/%s/%s/interfaces/%s%s", realm, devicePath(deviceIdentifier, resolvedDeviceIdentifierType), interfaceName, interfacePath)
+	interfaceAggregation interfaces.AstarteInterfaceAggregation, since, to time.Time, pageSize int, resultSetOrder ResultSetOrder, query datastreamQuery) (Paginator, error) {
+	resolvedDeviceIdentifierType, err := c.resolveDeviceIdentifier(deviceIdentifier, deviceIdentifierType)
+	if err != nil {
+		return &DatastreamPaginator{}, err
+	}
+	baseURL := urlbuilder.Build(c.appEngineURL, "/v1/%s/%s/interfaces/%s%s", realm, devicePath(deviceIdentifier, resolvedDeviceIdentifierType), interfaceName, urlbuilder.Path(interfacePath))
 
 	datastreamPaginator := DatastreamPaginator{
 		baseURL:        baseURL,
@@ -107,222 +202,491 @@ func (c *Client) getDatastreamPaginator(realm, deviceIdentifier string, deviceId
 		to:             time.Time{},
 		firstPage:      true,
 		nextQuery:      url.Values{},
-		pageSize:       pageSize,
+		pageSize:       c.clampPageSize(pageSize),
 		client:         c,
 		hasNextPage:    true,
 		resultSetOrder: resultSetOrder,
 		aggregation:    interfaceAggregation,
+		query:          query,
+		interfaceName:  interfaceName,
+		interfacePath:  interfacePath,
 	}
 
 	if (to != time.Time{}) {
 		datastreamPaginator.to = to
 	}
 
-	switch resultSetOrder {
-	case AscendingOrder:
+	if err := resultSetOrder.validateWindow(since, datastreamPaginator.pageSize); err != nil {
+		return &DatastreamPaginator{}, err
+	}
+	if resultSetOrder == AscendingOrder {
 		// If no start is set, let's start from the beginnning of time (1/1/1970)
 		if (since == time.Time{}) {
 			datastreamPaginator.since = time.Unix(0, 0)
 		} else {
 			datastreamPaginator.since = since
 		}
-	case DescendingOrder:
-		if (since != time.Time{}) {
-			return &DatastreamPaginator{}, fmt.Errorf("Specifying \"since\" is not supported when using DescendingOrder")
-		}
 	}
 
+	datastreamPaginator.windowSince = datastreamPaginator.since
+	datastreamPaginator.windowTo = datastreamPaginator.to
+
 	return &datastreamPaginator, nil
 }
 
 type GetPropertiesRequest struct {
 	req     *http.Request
-	expects int
+	expects []int
 }
 
 // GetAllProperties builds a request to return all the currently set Properties on a given interface.
 func (c *Client) GetAllProperties(realm string, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType,
 	interfaceName string) (AstarteRequest, error) {
 	// Let's find the actual device identifier type
-	resolvedDeviceIdentifierType := resolveDeviceIdentifierType(deviceIdentifier, deviceIdentifierType)
+	resolvedDeviceIdentifierType, err := c.resolveDeviceIdentifier(deviceIdentifier, deviceIdentifierType)
+	if err != nil {
+		return Empty{}, err
+	}
 	// and build the URL
-	callURL := makeURL(c.appEngineURL, "/v1/%s/%s/interfaces/%s", realm, devicePath(deviceIdentifier, resolvedDeviceIdentifierType), interfaceName)
+	callURL := urlbuilder.Build(c.appEngineURL, "/v1/%s/%s/interfaces/%s", realm, devicePath(deviceIdentifier, resolvedDeviceIdentifierType), interfaceName)
 	req := c.makeHTTPrequest(http.MethodGet, callURL, nil)
 
-	return GetPropertiesRequest{req: req, expects: 200}, nil
+	return GetPropertiesRequest{req: req, expects: []int{http.StatusOK}}, nil
 }
 
 // nolint:bodyclose
 func (r GetPropertiesRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return runAstarteRequestError(res, r.expects)
 	}
 	return GetPropertiesResponse{res: res}, nil
 }
 
 func (r GetPropertiesRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	return fmt.Sprint(command)
 }
 
 // GetProperty builds a request to return the currently set Property on a given Interface at a given path.
 func (c *Client) GetProperty(realm string, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType,
 	interfaceName string, interfacePath string) (AstarteRequest, error) {
-	resolvedDeviceIdentifierType := resolveDeviceIdentifierType(deviceIdentifier, deviceIdentifierType)
-	callURL := makeURL(c.appEngineURL, "/v1/%s/%s/interfaces/%s%s", realm, devicePath(deviceIdentifier, resolvedDeviceIdentifierType), interfaceName, interfacePath)
+	resolvedDeviceIdentifierType, err := c.resolveDeviceIdentifier(deviceIdentifier, deviceIdentifierType)
+	if err != nil {
+		return Empty{}, err
+	}
+	callURL := urlbuilder.Build(c.appEngineURL, "/v1/%s/%s/interfaces/%s%s", realm, devicePath(deviceIdentifier, resolvedDeviceIdentifierType), interfaceName, urlbuilder.Path(interfacePath))
 	req := c.makeHTTPrequest(http.MethodGet, callURL, nil)
 
-	return GetPropertiesRequest{req: req, expects: 200}, nil
+	return GetPropertiesRequest{req: req, expects: []int{http.StatusOK}}, nil
 }
 
+// ValidationLevel represents how thoroughly SendData validates payloads on the client side.
+type ValidationLevel int
+
+const (
+	// BasicValidation checks that the payload matches the types of the interface mappings. This is the default.
+	BasicValidation ValidationLevel = iota
+	// NoValidation skips payload validation altogether, leaving it to Astarte.
+	NoValidation
+	// StrictValidation performs the same checks as BasicValidation, and additionally rejects
+	// NaN and infinite floating point values and zero datetime values. SendDatastreamAt also rejects,
+	// with ErrTimestampRegression, an explicit timestamp earlier than the last one successfully sent
+	// by the client on the same realm, device, interface and path.
+	StrictValidation
+)
+
 // SendData builds a request to send data on the specified interface. It performs all validity checks on the Interface object before moving forward
 // with the operation, as such it is assumed that the operation will be always validated on the client side. If you have access to a native
 // Interface object, accessing this method rather than the lower level ones is advised.
 // payload must match a compatible type for the Interface path. In case of an aggregate interface, payload *must* be a
 // map[string]interface{}, and each payload will be individually checked.
+// How thoroughly payload is checked depends on the ValidationLevel of the client, see WithValidationLevel.
 func (c *Client) SendData(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType,
 	astarteInterface interfaces.AstarteInterface, interfacePath string, payload any) (AstarteRequest, error) {
+	if err := c.validateSendData(astarteInterface, interfacePath, payload); err != nil {
+		return Empty{}, err
+	}
+	return c.sendValidatedData(realm, deviceIdentifier, deviceIdentifierType, astarteInterface, interfacePath, payload)
+}
+
+// sendValidatedData builds the request SendData builds, once payload has been validated.
+func (c *Client) sendValidatedData(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType,
+	astarteInterface interfaces.AstarteInterface, interfacePath string, payload any) (AstarteRequest, error) {
+	switch {
+	case astarteInterface.Type == interfaces.PropertiesType:
+		return c.SetProperty(realm, deviceIdentifier, deviceIdentifierType, astarteInterface.Name, interfacePath, payload)
+	case astarteInterface.Aggregation == interfaces.IndividualAggregation:
+		return c.SendDatastream(realm, deviceIdentifier, deviceIdentifierType, astarteInterface.Name, interfacePath, payload)
+	case astarteInterface.Aggregation == interfaces.ObjectAggregation:
+		p, ok := payload.(map[string]any)
+		if !ok {
+			return Empty{}, fmt.Errorf("Invalid payload type for object-aggregated interface: payload must be a map, got %T", p)
+		}
+		return c.SendDatastream(realm, deviceIdentifier, deviceIdentifierType, astarteInterface.Name, interfacePath, p)
+	}
+
+	// We should never get here
+	return Empty{}, fmt.Errorf("Interface %s %d.%d has malformed type or aggregation", astarteInterface.Name, astarteInterface.MajorVersion, astarteInterface.MinorVersion)
+}
+
+// SendDatastreamAt builds a request to send data with an explicit timestamp on the specified datastream interface.
+// It performs the same checks as SendData, and additionally checks that the mapping (or, in case of an aggregate
+// interface, the interface) allows explicit timestamps. A zero timestamp means that the timestamp is implicit, and
+// will be assigned by Astarte upon reception: in this case, the request is the same as the one built by SendData.
+func (c *Client) SendDatastreamAt(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType,
+	astarteInterface interfaces.AstarteInterface, interfacePath string, payload any, timestamp time.Time) (AstarteRequest, error) {
+	if astarteInterface.Type != interfaces.DatastreamType {
+		return Empty{}, fmt.Errorf("Interface %s %d.%d is not a datastream", astarteInterface.Name, astarteInterface.MajorVersion, astarteInterface.MinorVersion)
+	}
+	if timestamp.IsZero() {
+		return c.SendData(realm, deviceIdentifier, deviceIdentifierType, astarteInterface, interfacePath, payload)
+	}
+	if err := c.validateSendData(astarteInterface, interfacePath, payload); err != nil {
+		return Empty{}, err
+	}
+	if !allowsExplicitTimestamp(astarteInterface, interfacePath) {
+		return Empty{}, ErrExplicitTimestampNotAllowed(astarteInterface.Name, interfacePath)
+	}
+
+	resolvedDeviceIdentifierType, err := c.resolveDeviceIdentifier(deviceIdentifier, deviceIdentifierType)
+	if err != nil {
+		return Empty{}, err
+	}
+	deviceURLPath := devicePath(deviceIdentifier, resolvedDeviceIdentifierType)
+	timestamp = c.timestampPrecision.Apply(timestamp)
+	timestampKey := ""
+	if c.validationLevel == StrictValidation {
+		timestampKey = fmt.Sprintf("%s/%s/%s%s", realm, deviceURLPath, astarteInterface.Name, interfacePath)
+		if err := c.checkTimestampMonotonicity(timestampKey, timestamp); err != nil {
+			return Empty{}, err
+		}
+	}
+	callURL := urlbuilder.Build(c.appEngineURL, "/v1/%s/%s/interfaces/%s%s", realm, deviceURLPath, astarteInterface.Name, urlbuilder.Path(interfacePath))
+
+	normalizedPayload := c.normalizePayload(payload)
+	body, _ := makeTimestampedBody(normalizedPayload, timestamp)
+	req := c.makeHTTPrequest(http.MethodPost, callURL, body)
+
+	return SendDatastreamRequest{req: req, expects: []int{http.StatusOK, http.StatusNoContent}, timestampKey: timestampKey, timestamp: timestamp}, nil
+}
+
+// checkTimestampMonotonicity returns ErrTimestampRegression if timestamp is earlier than the last one
+// successfully sent on timestampKey. The check is made when building the request, while the timestamp
+// is recorded only once the request succeeds, so that requests which are never run or fail don't count.
+func (c *Client) checkTimestampMonotonicity(timestampKey string, timestamp time.Time) error {
+	last, ok := c.lastTimestamps.Load(timestampKey)
+	if ok && timestamp.Before(last.(time.Time)) {
+		return fmt.Errorf("%w: %s is earlier than %s", ErrTimestampRegression, timestamp.Format(time.RFC3339Nano), last.(time.Time).Format(time.RFC3339Nano))
+	}
+	return nil
+}
+
+// recordTimestamp stores timestamp as the last one sent on timestampKey, unless a later one was already stored.
+func (c *Client) recordTimestamp(timestampKey string, timestamp time.Time) {
+	for {
+		last, loaded := c.lastTimestamps.LoadOrStore(timestampKey, timestamp)
+		if !loaded || !timestamp.After(last.(time.Time)) {
+			return
+		}
+		if c.lastTimestamps.CompareAndSwap(timestampKey, last, timestamp) {
+			return
+		}
+	}
+}
+
+// validateSendData checks that payload can be sent on interfacePath, according to the validation level of the client.
+func (c *Client) validateSendData(astarteInterface interfaces.AstarteInterface, interfacePath string, payload any) error {
 	// Perform a set of checks depending on the interface structure
 	switch {
 	case astarteInterface.Ownership == interfaces.DeviceOwnership:
-		return Empty{}, fmt.Errorf("cannot send data to device-owned interface %s %d.%d", astarteInterface.Name, astarteInterface.MajorVersion, astarteInterface.MinorVersion)
+		return ErrDeviceOwnedInterface(astarteInterface)
+	case c.validationLevel == NoValidation:
+		// The payload is trusted to be valid
+		return nil
 	case astarteInterface.Type == interfaces.PropertiesType, astarteInterface.Aggregation == interfaces.IndividualAggregation:
 		// In this case, validate the individual message
 		if err := interfaces.ValidateIndividualMessage(astarteInterface, interfacePath, payload); err != nil {
-			return Empty{}, err
+			return err
 		}
 	case astarteInterface.Aggregation == interfaces.ObjectAggregation:
 		aggregatePayload, ok := payload.(map[string]interface{})
 		if !ok {
-			return Empty{}, fmt.Errorf("Data sent to interfaces with object aggregation must be a map[string]interface{}")
+			return fmt.Errorf("Data sent to interfaces with object aggregation must be a map[string]interface{}")
 		}
 		if err := interfaces.ValidateAggregateMessage(astarteInterface, interfacePath, aggregatePayload); err != nil {
-			return Empty{}, err
+			return err
 		}
 	}
+	if c.validationLevel == StrictValidation {
+		return validateStrictPayload(payload)
+	}
+	return nil
+}
 
-	// If we got here, it's time to do the right thing.
-	switch {
-	case astarteInterface.Type == interfaces.PropertiesType:
-		return c.SetProperty(realm, deviceIdentifier, deviceIdentifierType, astarteInterface.Name, interfacePath, payload)
-	case astarteInterface.Aggregation == interfaces.IndividualAggregation:
-		return c.SendDatastream(realm, deviceIdentifier, deviceIdentifierType, astarteInterface.Name, interfacePath, payload)
-	case astarteInterface.Aggregation == interfaces.ObjectAggregation:
-		p, ok := payload.(map[string]any)
-		if !ok {
-			return Empty{}, fmt.Errorf("Invalid payload type for object-aggregated interface: payload must be a map, got %T", p)
+// allowsExplicitTimestamp returns whether values sent on interfacePath can have an explicit timestamp.
+// For aggregate interfaces, either the interface or all of its mappings must allow it.
+func allowsExplicitTimestamp(astarteInterface interfaces.AstarteInterface, interfacePath string) bool {
+	if astarteInterface.Aggregation == interfaces.ObjectAggregation {
+		if astarteInterface.ExplicitTimestamp {
+			return true
 		}
-		return c.SendDatastream(realm, deviceIdentifier, deviceIdentifierType, astarteInterface.Name, interfacePath, p)
+		for _, mapping := range astarteInterface.Mappings {
+			if !mapping.ExplicitTimestamp {
+				return false
+			}
+		}
+		return len(astarteInterface.Mappings) > 0
+	}
+	mapping, err := interfaces.InterfaceMappingFromPath(astarteInterface, interfacePath)
+	if err != nil {
+		return false
 	}
+	return mapping.ExplicitTimestamp
+}
 
-	// We should never get here
-	return Empty{}, fmt.Errorf("Interface %s %d.%d has malformed type or aggregation", astarteInterface.Name, astarteInterface.MajorVersion, astarteInterface.MinorVersion)
+// validateStrictPayload rejects values which are accepted by the interface mapping types, but which
+// cannot be meaningfully sent to Astarte: NaN and infinite floats (which cannot be encoded as JSON)
+// and zero datetimes.
+func validateStrictPayload(payload any) error {
+	switch v := payload.(type) {
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("Invalid double value: %v", v)
+		}
+	case float32:
+		return validateStrictPayload(float64(v))
+	case time.Time:
+		if v.IsZero() {
+			return fmt.Errorf("Invalid datetime value: zero time")
+		}
+	case []float64:
+		for _, f := range v {
+			if err := validateStrictPayload(f); err != nil {
+				return err
+			}
+		}
+	case []time.Time:
+		for _, t := range v {
+			if err := validateStrictPayload(t); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, e := range v {
+			if err := validateStrictPayload(e); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		for k, e := range v {
+			if err := validateStrictPayload(e); err != nil {
+				return fmt.Errorf("%s: %w", k, err)
+			}
+		}
+	}
+	return nil
 }
 
 type SendDatastreamRequest struct {
 	req     *http.Request
-	expects int
+	expects []int
+	// timestampKey is set when the explicit timestamp of the request must be recorded on success, see StrictValidation
+	timestampKey string
+	timestamp    time.Time
 }
 
 // SendDatastream builds a request to send a datastream to the given interface without additional checks.
 // payload must be of a type compatible with the interface's endpoint. Any errors will be returned on the server side or
-// in payload marshaling. If you have a native AstarteInterface object, calling SendData is advised
+// in payload marshaling. If you have a native AstarteInterface object, calling SendData is advised.
+// The response of the request parses to a DatastreamWriteAck, holding the value and timestamps stored by Astarte.
 func (c *Client) SendDatastream(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, interfaceName, interfacePath string, payload any) (AstarteRequest, error) {
-	resolvedDeviceIdentifierType := resolveDeviceIdentifierType(deviceIdentifier, deviceIdentifierType)
-	callURL := makeURL(c.appEngineURL, "/v1/%s/%s/interfaces/%s%s", realm, devicePath(deviceIdentifier, resolvedDeviceIdentifierType), interfaceName, interfacePath)
+	resolvedDeviceIdentifierType, err := c.resolveDeviceIdentifier(deviceIdentifier, deviceIdentifierType)
+	if err != nil {
+		return Empty{}, err
+	}
+	callURL := urlbuilder.Build(c.appEngineURL, "/v1/%s/%s/interfaces/%s%s", realm, devicePath(deviceIdentifier, resolvedDeviceIdentifierType), interfaceName, urlbuilder.Path(interfacePath))
 
-	normalizedPayload := interfaces.NormalizePayload(payload, true)
+	normalizedPayload := c.normalizePayload(payload)
 	body, _ := makeBody(normalizedPayload)
 	req := c.makeHTTPrequest(http.MethodPost, callURL, body)
 
-	return SendDatastreamRequest{req: req, expects: 200}, nil
+	return SendDatastreamRequest{req: req, expects: []int{http.StatusOK, http.StatusNoContent}}, nil
+}
+
+// SendDatastreamRaw builds a request to send a datastream to the given interface, using payload as the
+// value of the "data" field of the request body. payload is sent as it is: it is not normalized, hence
+// it must already be encoded as Astarte expects (e.g. base64 strings for binary blobs, RFC3339 UTC timestamps
+// for datetimes), and the caller is responsible for its correctness. The only check is that payload is valid JSON.
+func (c *Client) SendDatastreamRaw(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, interfaceName, interfacePath string, payload json.RawMessage) (AstarteRequest, error) {
+	if !json.Valid(payload) {
+		return Empty{}, ErrInvalidRawPayload
+	}
+	return c.SendDatastream(realm, deviceIdentifier, deviceIdentifierType, interfaceName, interfacePath, payload)
+}
+
+// The PreEncoded function marks payload as already encoded as Astarte expects, so that SendDatastream,
+// SendDatastreamAt and SetProperty send it without normalizing it, e.g. without encoding []byte values
+// to base64 again. The caller is responsible for the correctness of payload. json.RawMessage payloads
+// are always sent as they are, and need not be marked.
+// PreEncoded payloads can't be validated, hence SendData accepts them only with the NoValidation level.
+func PreEncoded(payload any) any {
+	return preEncodedPayload{payload: payload}
+}
+
+type preEncodedPayload struct {
+	payload any
 }
 
 // nolint:bodyclose
 func (r SendDatastreamRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return runAstarteRequestError(res, r.expects)
 	}
-	return NoDataResponse{res: res}, nil
+	if r.timestampKey != "" {
+		c.recordTimestamp(r.timestampKey, r.timestamp)
+	}
+	return SendDatastreamResponse{res: res}, nil
 }
 
 func (r SendDatastreamRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	return fmt.Sprint(command)
 }
 
 type SetPropertyRequest struct {
 	req     *http.Request
-	expects int
+	expects []int
 }
 
 // SetProperty builds a request to set a property on the given interface without additional checks. payload must be of a type
 // compatible with the interface's endpoint. Any errors will be returned on the server side or
 // in payload marshaling. If you have a native AstarteInterface object, calling SendData is advised
 func (c *Client) SetProperty(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, interfaceName, interfacePath string, payload any) (AstarteRequest, error) {
-	resolvedDeviceIdentifierType := resolveDeviceIdentifierType(deviceIdentifier, deviceIdentifierType)
-	callURL := makeURL(c.appEngineURL, "/v1/%s/%s/interfaces/%s%s", realm, devicePath(deviceIdentifier, resolvedDeviceIdentifierType), interfaceName, interfacePath)
+	resolvedDeviceIdentifierType, err := c.resolveDeviceIdentifier(deviceIdentifier, deviceIdentifierType)
+	if err != nil {
+		return Empty{}, err
+	}
+	callURL := urlbuilder.Build(c.appEngineURL, "/v1/%s/%s/interfaces/%s%s", realm, devicePath(deviceIdentifier, resolvedDeviceIdentifierType), interfaceName, urlbuilder.Path(interfacePath))
 
-	normalizedPayload := interfaces.NormalizePayload(payload, true)
+	normalizedPayload := c.normalizePayload(payload)
 	body, _ := makeBody(normalizedPayload)
 	req := c.makeHTTPrequest(http.MethodPut, callURL, body)
 
-	return SetPropertyRequest{req: req, expects: 200}, nil
+	return SetPropertyRequest{req: req, expects: []int{http.StatusOK, http.StatusNoContent}}, nil
 }
 
 // nolint:bodyclose
 func (r SetPropertyRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return runAstarteRequestError(res, r.expects)
 	}
 	return NoDataResponse{res: res}, nil
 }
 
 func (r SetPropertyRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	return fmt.Sprint(command)
 }
 
 type UnsetPropertyRequest struct {
 	req     *http.Request
-	expects int
+	expects []int
 }
 
-// UnsetProperty builds a request to delete a property on the given interface without additional checks.
+// UnsetProperty builds a request to delete a property on the given interface. If the client has cached definitions
+// of the interface (see GetInterfacesForDevice), it checks that at least one of them allows to unset the property.
+// If you have access to a native Interface object, UnsetInterfaceProperty performs the checks on it instead.
 func (c *Client) UnsetProperty(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, interfaceName string, interfacePath string) (AstarteRequest, error) {
-	// TODO check if mapping is unsettable
-	resolvedDeviceIdentifierType := resolveDeviceIdentifierType(deviceIdentifier, deviceIdentifierType)
-	callURL := makeURL(c.appEngineURL, "/v1/%s/%s/interfaces/%s%s", realm, devicePath(deviceIdentifier, resolvedDeviceIdentifierType), interfaceName, interfacePath)
+	if err := c.validateUnsetCachedProperty(realm, interfaceName, interfacePath); err != nil {
+		return Empty{}, err
+	}
+	resolvedDeviceIdentifierType, err := c.resolveDeviceIdentifier(deviceIdentifier, deviceIdentifierType)
+	if err != nil {
+		return Empty{}, err
+	}
+	callURL := urlbuilder.Build(c.appEngineURL, "/v1/%s/%s/interfaces/%s%s", realm, devicePath(deviceIdentifier, resolvedDeviceIdentifierType), interfaceName, urlbuilder.Path(interfacePath))
 	req := c.makeHTTPrequest(http.MethodDelete, callURL, nil)
 
-	return UnsetPropertyRequest{req: req, expects: 204}, nil
+	return UnsetPropertyRequest{req: req, expects: []int{http.StatusNoContent, http.StatusOK}}, nil
+}
+
+// UnsetInterfaceProperty builds a request to delete a property on the given interface, after checking that
+// the interface is a server-owned properties interface, and that the mapping of interfacePath allows unset.
+func (c *Client) UnsetInterfaceProperty(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType,
+	astarteInterface interfaces.AstarteInterface, interfacePath string) (AstarteRequest, error) {
+	if err := validateUnsetProperty(astarteInterface, interfacePath); err != nil {
+		return Empty{}, err
+	}
+	return c.UnsetProperty(realm, deviceIdentifier, deviceIdentifierType, astarteInterface.Name, interfacePath)
+}
+
+// validateUnsetProperty checks that the property on interfacePath can be unset.
+func validateUnsetProperty(astarteInterface interfaces.AstarteInterface, interfacePath string) error {
+	switch {
+	case astarteInterface.Ownership == interfaces.DeviceOwnership:
+		return ErrDeviceOwnedInterface(astarteInterface)
+	case astarteInterface.Type != interfaces.PropertiesType:
+		return fmt.Errorf("Interface %s %d.%d is not a properties interface", astarteInterface.Name, astarteInterface.MajorVersion, astarteInterface.MinorVersion)
+	}
+	mapping, err := interfaces.InterfaceMappingFromPath(astarteInterface, interfacePath)
+	if err != nil {
+		return err
+	}
+	if !mapping.AllowUnset {
+		return ErrUnsetNotAllowed(astarteInterface.Name, interfacePath)
+	}
+	return nil
+}
+
+// validateUnsetCachedProperty checks that the property on interfacePath can be unset according to at least one
+// of the cached definitions of the interface, if any.
+func (c *Client) validateUnsetCachedProperty(realm, interfaceName, interfacePath string) error {
+	var errs []error
+	allowed := false
+	c.interfaceCache.Range(func(key, value any) bool {
+		cacheKey := key.(interfaceCacheKey)
+		if cacheKey.realm != realm || cacheKey.name != interfaceName {
+			return true
+		}
+		err := validateUnsetProperty(value.(interfaces.AstarteInterface), interfacePath)
+		if err == nil {
+			allowed = true
+			return false
+		}
+		errs = append(errs, err)
+		return true
+	})
+	if allowed || len(errs) == 0 {
+		return nil
+	}
+	return errs[0]
 }
 
 // nolint:bodyclose
 func (r UnsetPropertyRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return runAstarteRequestError(res, r.expects)
 	}
 	return NoDataResponse{res: res}, nil
 }
 
 func (r UnsetPropertyRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	return fmt.Sprint(command)
 }
@@ -15,9 +15,20 @@
 package client
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/astarte-platform/astarte-go/interfaces"
+	"github.com/astarte-platform/astarte-go/internal/runner"
 	"github.com/tidwall/gjson"
 )
 
@@ -44,7 +55,7 @@ func TestGetDatastreamIndividualSnapshot(t *testing.T) {
 
 func TestParseDatastreamIndividualSnapshot(t *testing.T) {
 	parsed := map[string]any{}
-	parseIndividualDatastreamSnapshot([]byte(gjson.GetBytes([]byte(testIndividualDatastreamSnapshot), "data").Raw), "", parsed)
+	parseIndividualDatastreamSnapshot([]byte(gjson.GetBytes([]byte(testIndividualDatastreamSnapshot), "data").Raw), "", parsed, false)
 	checkParsedIndividualDatastreamSnapshot(t, parsed)
 }
 
@@ -63,7 +74,9 @@ func TestParseDatastreamObjectSnapshot(t *testing.T) {
 	 }
 	`
 	retMap := map[string]DatastreamObjectValue{}
-	parseObjectDatastreamSnapshot([]byte(gjson.GetBytes([]byte(value), "data").Raw), retMap)
+	if err := parseObjectDatastreamSnapshot([]byte(gjson.GetBytes([]byte(value), "data").Raw), retMap, false); err != nil {
+		t.Fatal(err)
+	}
 	for k, v := range retMap {
 		if k == "/foo" {
 			barV, ok := v.Values.Get("bar")
@@ -105,7 +118,7 @@ func TestParseDatastreamObject(t *testing.T) {
 	`
 	parsed := []DatastreamObjectValue{}
 	jsonData := gjson.ParseBytes([]byte(gjson.GetBytes([]byte(value), "data").Raw))
-	parseDatastream(jsonData, interfaces.ObjectAggregation)
+	parseDatastream(jsonData, interfaces.ObjectAggregation, false)
 	for _, v := range parsed {
 		barV, ok := v.Values.Get("bar")
 		if !ok {
@@ -148,6 +161,49 @@ func TestParseProperties(t *testing.T) {
 	}
 }
 
+func TestUnsetPropertyValidation(t *testing.T) {
+	unsettableMapping := interfaces.AstarteInterfaceMapping{Endpoint: "/an/endpoint", Type: interfaces.Integer, AllowUnset: true}
+	mapping := interfaces.AstarteInterfaceMapping{Endpoint: "/other/endpoint", Type: interfaces.Integer}
+	propertyInterface := interfaces.AstarteInterface{Name: testServerOwnedPropertyInterfaceName, Ownership: interfaces.ServerOwnership, Type: interfaces.PropertiesType,
+		Mappings: []interfaces.AstarteInterfaceMapping{unsettableMapping, mapping}}
+
+	c, _ := getTestContext(t)
+	unsetPropertyCall, err := c.UnsetInterfaceProperty(testRealmName, testDeviceID, AstarteDeviceID, propertyInterface, "/an/endpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := unsetPropertyCall.Run(c); err != nil {
+		t.Error(err)
+	}
+	if _, err := c.UnsetInterfaceProperty(testRealmName, testDeviceID, AstarteDeviceID, propertyInterface, "/other/endpoint"); err == nil || !strings.Contains(err.Error(), "does not allow unset") {
+		t.Errorf("Expected an error for a mapping without allow_unset, found %v", err)
+	}
+	if _, err := c.UnsetInterfaceProperty(testRealmName, testDeviceID, AstarteDeviceID, propertyInterface, "/missing/endpoint"); err == nil {
+		t.Error("Expected an error for a missing mapping, found nil")
+	}
+	datastreamInterface := propertyInterface
+	datastreamInterface.Type = interfaces.DatastreamType
+	if _, err := c.UnsetInterfaceProperty(testRealmName, testDeviceID, AstarteDeviceID, datastreamInterface, "/an/endpoint"); err == nil {
+		t.Error("Expected an error for a datastream interface, found nil")
+	}
+	deviceOwnedInterface := propertyInterface
+	deviceOwnedInterface.Ownership = interfaces.DeviceOwnership
+	if _, err := c.UnsetInterfaceProperty(testRealmName, testDeviceID, AstarteDeviceID, deviceOwnedInterface, "/an/endpoint"); err == nil || !strings.Contains(err.Error(), "device-owned") {
+		t.Errorf("Expected an error for a device-owned interface, found %v", err)
+	}
+
+	// Without a cached definition, UnsetProperty can't check anything
+	if _, err := c.UnsetProperty(testRealmName, testPropertiesDeviceID, AstarteDeviceID, testServerPropertiesInterfaceName, "/config/name"); err != nil {
+		t.Error(err)
+	}
+	if _, err := c.GetInterfacesForDevice(testRealmName, testPropertiesDeviceID, AstarteDeviceID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.UnsetProperty(testRealmName, testPropertiesDeviceID, AstarteDeviceID, testServerPropertiesInterfaceName, "/config/name"); err == nil {
+		t.Error("Expected an error for a cached mapping without allow_unset, found nil")
+	}
+}
+
 func TestSendData(t *testing.T) {
 	simpleMapping := interfaces.AstarteInterfaceMapping{Endpoint: "/an/endpoint", Type: interfaces.Integer, AllowUnset: true}
 	datastreamInterface := interfaces.AstarteInterface{Name: testServerOwnedInterfaceName, Ownership: interfaces.ServerOwnership, Type: interfaces.DatastreamType, Mappings: []interfaces.AstarteInterfaceMapping{simpleMapping}, Aggregation: interfaces.IndividualAggregation}
@@ -209,6 +265,220 @@ func TestSendData(t *testing.T) {
 	}
 }
 
+func TestSendDatastreamWriteAck(t *testing.T) {
+	timestampedMapping := interfaces.AstarteInterfaceMapping{Endpoint: "/an/endpoint", Type: interfaces.Integer, ExplicitTimestamp: true}
+	datastreamInterface := interfaces.AstarteInterface{Name: testServerOwnedInterfaceName, Ownership: interfaces.ServerOwnership, Type: interfaces.DatastreamType, Mappings: []interfaces.AstarteInterfaceMapping{timestampedMapping}, Aggregation: interfaces.IndividualAggregation}
+	timestamp := time.Date(2024, 5, 6, 7, 8, 0, 0, time.UTC)
+
+	c, _ := getTestContext(t)
+	sendDatastreamCall, err := c.SendDatastreamAt(testRealmName, testDeviceID, AstarteDeviceID, datastreamInterface, "/an/endpoint", 42, timestamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ack, err := runner.RunAndParse[DatastreamWriteAck](c, sendDatastreamCall.Run)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ack.Value != float64(42) || !ack.Timestamp.Equal(timestamp) || !ack.ReceptionTimestamp.Equal(testReceptionTimestamp) {
+		t.Errorf("Unexpected write acknowledgment: %+v", ack)
+	}
+
+	// Only the value is echoed
+	sendDatastreamCall, err = c.SendDatastream(testRealmName, testDeviceID, AstarteDeviceID, testServerOwnedInterfaceName, "/other/endpoint", 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ack, err = runner.RunAndParse[DatastreamWriteAck](c, sendDatastreamCall.Run)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ack.Value != "" || !ack.Timestamp.IsZero() || !ack.ReceptionTimestamp.IsZero() {
+		t.Errorf("Unexpected write acknowledgment: %+v", ack)
+	}
+}
+
+func TestSendDatastreamRaw(t *testing.T) {
+	c, _ := getTestContext(t)
+
+	call, err := c.SendDatastreamRaw(testRealmName, testDeviceID, AstarteDeviceID, testServerOwnedInterfaceName, "/an/endpoint", json.RawMessage(`"AQID"`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := map[string]any{}
+	if err := json.NewDecoder(call.(SendDatastreamRequest).req.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body["data"] != "AQID" {
+		t.Errorf("Unexpected body: %v", body)
+	}
+
+	if _, err := c.SendDatastreamRaw(testRealmName, testDeviceID, AstarteDeviceID, testServerOwnedInterfaceName, "/an/endpoint", json.RawMessage(`{"a":`)); !errors.Is(err, ErrInvalidRawPayload) {
+		t.Errorf("Expected ErrInvalidRawPayload, found %v", err)
+	}
+
+	// Pre-encoded base64 strings are not encoded again
+	call, err = c.SetProperty(testRealmName, testDeviceID, AstarteDeviceID, testServerOwnedInterfaceName, "/an/endpoint", PreEncoded([]string{"AQID"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.NewDecoder(call.(SetPropertyRequest).req.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if data, ok := body["data"].([]any); !ok || len(data) != 1 || data[0] != "AQID" {
+		t.Errorf("Unexpected body: %v", body)
+	}
+}
+
+func TestSendDataToDeviceOwnedInterface(t *testing.T) {
+	simpleMapping := interfaces.AstarteInterfaceMapping{Endpoint: "/an/endpoint", Type: interfaces.Integer}
+	datastreamInterface := interfaces.AstarteInterface{Name: testInterfaceName, Ownership: interfaces.DeviceOwnership, Type: interfaces.DatastreamType, Mappings: []interfaces.AstarteInterfaceMapping{simpleMapping}, Aggregation: interfaces.IndividualAggregation}
+	propertyInterface := interfaces.AstarteInterface{Name: testInterfaceName, Ownership: interfaces.DeviceOwnership, Type: interfaces.PropertiesType, Mappings: []interfaces.AstarteInterfaceMapping{simpleMapping}}
+
+	c, _ := getTestContext(t)
+	if _, err := c.SendData(testRealmName, testDeviceID, AstarteDeviceID, datastreamInterface, "/an/endpoint", 42); err == nil || !strings.Contains(err.Error(), "GetDatastreamIndividualSnapshot") {
+		t.Errorf("Expected error suggesting how to read datastreams, got %v", err)
+	}
+	if _, err := c.SendData(testRealmName, testDeviceID, AstarteDeviceID, propertyInterface, "/an/endpoint", 42); err == nil || !strings.Contains(err.Error(), "GetProperty") {
+		t.Errorf("Expected error suggesting how to read properties, got %v", err)
+	}
+}
+
+func TestSendDataValidationLevels(t *testing.T) {
+	doubleMapping := interfaces.AstarteInterfaceMapping{Endpoint: "/an/endpoint", Type: interfaces.Double}
+	datastreamInterface := interfaces.AstarteInterface{Name: testServerOwnedInterfaceName, Ownership: interfaces.ServerOwnership, Type: interfaces.DatastreamType, Mappings: []interfaces.AstarteInterfaceMapping{doubleMapping}, Aggregation: interfaces.IndividualAggregation}
+
+	c, _ := getTestContext(t)
+	if _, err := c.SendData(testRealmName, testDeviceID, AstarteDeviceID, datastreamInterface, "/an/endpoint", "not a double"); err == nil {
+		t.Error("Invalid payload accepted with BasicValidation")
+	}
+	if _, err := c.SendData(testRealmName, testDeviceID, AstarteDeviceID, datastreamInterface, "/an/endpoint", math.NaN()); err != nil {
+		t.Error(err)
+	}
+
+	if err := WithValidationLevel(NoValidation)(c); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.SendData(testRealmName, testDeviceID, AstarteDeviceID, datastreamInterface, "/an/endpoint", "not a double"); err != nil {
+		t.Error(err)
+	}
+
+	if err := WithValidationLevel(StrictValidation)(c); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.SendData(testRealmName, testDeviceID, AstarteDeviceID, datastreamInterface, "/an/endpoint", 4.2); err != nil {
+		t.Error(err)
+	}
+	for _, payload := range []any{"not a double", math.NaN(), math.Inf(1)} {
+		if _, err := c.SendData(testRealmName, testDeviceID, AstarteDeviceID, datastreamInterface, "/an/endpoint", payload); err == nil {
+			t.Errorf("Invalid payload %v accepted with StrictValidation", payload)
+		}
+	}
+
+	if err := WithValidationLevel(ValidationLevel(42))(c); err != ErrInvalidValidationLevel {
+		t.Errorf("Expected ErrInvalidValidationLevel, found %v", err)
+	}
+}
+
+func TestSendDatastreamAt(t *testing.T) {
+	timestampedMapping := interfaces.AstarteInterfaceMapping{Endpoint: "/an/endpoint", Type: interfaces.Integer, ExplicitTimestamp: true}
+	timestampedInterface := interfaces.AstarteInterface{Name: testServerOwnedInterfaceName, Ownership: interfaces.ServerOwnership, Type: interfaces.DatastreamType, Mappings: []interfaces.AstarteInterfaceMapping{timestampedMapping}, Aggregation: interfaces.IndividualAggregation}
+	simpleMapping := interfaces.AstarteInterfaceMapping{Endpoint: "/an/endpoint", Type: interfaces.Integer}
+	simpleInterface := interfaces.AstarteInterface{Name: testServerOwnedInterfaceName, Ownership: interfaces.ServerOwnership, Type: interfaces.DatastreamType, Mappings: []interfaces.AstarteInterfaceMapping{simpleMapping}, Aggregation: interfaces.IndividualAggregation}
+	objectInterface := interfaces.AstarteInterface{Name: testServerOwnedInterfaceName, Ownership: interfaces.ServerOwnership, Type: interfaces.DatastreamType, Mappings: []interfaces.AstarteInterfaceMapping{{Endpoint: "/an/endpoint", Type: interfaces.Integer}}, Aggregation: interfaces.ObjectAggregation, ExplicitTimestamp: true}
+	timestamp := time.Date(2024, 5, 4, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+
+	c, _ := getTestContext(t)
+	call, err := c.SendDatastreamAt(testRealmName, testDeviceID, AstarteDeviceID, timestampedInterface, "/an/endpoint", 42, timestamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := call.Run(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = res.Parse(); err != nil {
+		t.Error(err)
+	}
+
+	call, err = c.SendDatastreamAt(testRealmName, testDeviceID, AstarteDeviceID, timestampedInterface, "/an/endpoint", 42, timestamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body struct {
+		Data      int       `json:"data"`
+		Timestamp time.Time `json:"timestamp"`
+	}
+	if err := json.NewDecoder(call.(SendDatastreamRequest).req.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Data != 42 || !body.Timestamp.Equal(timestamp) || body.Timestamp.Location() != time.UTC {
+		t.Errorf("Unexpected request body: %+v", body)
+	}
+
+	if _, err := c.SendDatastreamAt(testRealmName, testDeviceID, AstarteDeviceID, simpleInterface, "/an/endpoint", 42, timestamp); err == nil {
+		t.Error("Explicit timestamp accepted on a mapping which does not allow it")
+	}
+	if _, err := c.SendDatastreamAt(testRealmName, testDeviceID, AstarteDeviceID, objectInterface, "/an", map[string]any{"endpoint": 42}, timestamp); err != nil {
+		t.Error(err)
+	}
+
+	// A zero timestamp is implicit, and is not sent
+	call, err = c.SendDatastreamAt(testRealmName, testDeviceID, AstarteDeviceID, simpleInterface, "/an/endpoint", 42, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rawBody map[string]any
+	if err := json.NewDecoder(call.(SendDatastreamRequest).req.Body).Decode(&rawBody); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := rawBody["timestamp"]; ok {
+		t.Errorf("Unexpected timestamp in request body: %v", rawBody)
+	}
+}
+
+func TestSendDatastreamAtTimestampRegression(t *testing.T) {
+	timestampedMapping := interfaces.AstarteInterfaceMapping{Endpoint: "/an/endpoint", Type: interfaces.Integer, ExplicitTimestamp: true}
+	timestampedInterface := interfaces.AstarteInterface{Name: testServerOwnedInterfaceName, Ownership: interfaces.ServerOwnership, Type: interfaces.DatastreamType, Mappings: []interfaces.AstarteInterfaceMapping{timestampedMapping}, Aggregation: interfaces.IndividualAggregation}
+	timestamp := time.Date(2024, 5, 4, 12, 0, 0, 0, time.UTC)
+	send := func(c *Client, timestamp time.Time) error {
+		call, err := c.SendDatastreamAt(testRealmName, testDeviceID, AstarteDeviceID, timestampedInterface, "/an/endpoint", 42, timestamp)
+		if err != nil {
+			return err
+		}
+		_, err = call.Run(c)
+		return err
+	}
+
+	c, _ := getTestContext(t)
+	if err := WithValidationLevel(StrictValidation)(c); err != nil {
+		t.Fatal(err)
+	}
+	// A request which is built but never run does not count
+	if _, err := c.SendDatastreamAt(testRealmName, testDeviceID, AstarteDeviceID, timestampedInterface, "/an/endpoint", 42, timestamp.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := send(c, timestamp); err != nil {
+		t.Fatal(err)
+	}
+	if err := send(c, timestamp); err != nil {
+		t.Errorf("Repeated timestamp rejected: %v", err)
+	}
+	if err := send(c, timestamp.Add(-time.Second)); !errors.Is(err, ErrTimestampRegression) {
+		t.Errorf("Expected ErrTimestampRegression, found %v", err)
+	}
+	if err := send(c, timestamp.Add(time.Second)); err != nil {
+		t.Error(err)
+	}
+
+	// Only StrictValidation checks timestamps
+	if err := WithValidationLevel(BasicValidation)(c); err != nil {
+		t.Fatal(err)
+	}
+	if err := send(c, timestamp.Add(-time.Hour)); err != nil {
+		t.Error(err)
+	}
+}
+
 func checkParsedIndividualDatastreamSnapshot(t *testing.T, result map[string]any) {
 	for k, v := range result {
 		if k == "/anotherTest/value" {
@@ -226,3 +496,366 @@ func checkParsedIndividualDatastreamSnapshot(t *testing.T, result map[string]any
 		}
 	}
 }
+
+func TestDatastreamTimestampPrecision(t *testing.T) {
+	expected := time.Date(2022, 9, 26, 14, 37, 0, 468000000, time.UTC)
+
+	individual := DatastreamIndividualValue{}
+	if err := json.Unmarshal([]byte(`{"value": 1, "timestamp": "2022-09-26T14:37:00.468Z", "reception_timestamp": 1664203020468}`), &individual); err != nil {
+		t.Fatal(err)
+	}
+	if !individual.Timestamp.Equal(expected) || !individual.ReceptionTimestamp.Equal(expected) {
+		t.Errorf("Unexpected timestamps: %v, %v", individual.Timestamp, individual.ReceptionTimestamp)
+	}
+
+	object := DatastreamObjectValue{}
+	if err := json.Unmarshal([]byte(`{"bar": 1, "timestamp": "2022-09-26T14:37:00.468Z", "reception_timestamp": "2022-09-26T14:37:00.468Z"}`), &object); err != nil {
+		t.Fatal(err)
+	}
+	if !object.Timestamp.Equal(expected) || !object.ReceptionTimestamp.Equal(expected) {
+		t.Errorf("Unexpected timestamps: %v, %v", object.Timestamp, object.ReceptionTimestamp)
+	}
+	if keys := object.Values.Keys(); len(keys) != 1 || keys[0] != "bar" {
+		t.Errorf("Unexpected object values: %v", keys)
+	}
+
+	if err := json.Unmarshal([]byte(`{"value": 1, "timestamp": "yesterday"}`), &individual); err == nil {
+		t.Error("Expected an error for an invalid timestamp, found nil")
+	}
+}
+
+func TestWithKeepMilliseconds(t *testing.T) {
+	c, _ := getTestContext(t)
+	snapshotCall, err := c.GetDatastreamObjectSnapshot(testRealmName, testDeviceID, AstarteDeviceID, testInterfaceName, WithKeepMilliseconds())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query := snapshotCall.(GetDatastreamSnapshotRequest).req.URL.Query(); query.Get("keep_milliseconds") != "true" || query.Get("limit") != "1" {
+		t.Errorf("Unexpected snapshot query: %v", query)
+	}
+
+	paginator, err := c.GetDatastreamIndividualPaginator(testRealmName, testDeviceID, AstarteDeviceID, testInterfaceName, "/an/endpoint", AscendingOrder, 10, WithKeepMilliseconds())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pageCall, err := paginator.GetNextPage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query := pageCall.(GetNextDatastreamPageRequest).req.URL.Query(); query.Get("keep_milliseconds") != "true" {
+		t.Errorf("Unexpected page query: %v", query)
+	}
+
+	snapshotCall, _ = c.GetDatastreamIndividualSnapshot(testRealmName, testDeviceID, AstarteDeviceID, testInterfaceName)
+	if rawQuery := snapshotCall.(GetDatastreamSnapshotRequest).req.URL.RawQuery; rawQuery != "" {
+		t.Errorf("Unexpected snapshot query: %s", rawQuery)
+	}
+}
+
+func TestDatastreamProjections(t *testing.T) {
+	c, _ := getTestContext(t)
+	snapshotCall, err := c.GetDatastreamIndividualSnapshot(testRealmName, testDeviceID, AstarteDeviceID, testInterfaceName, WithValuesOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if projection := snapshotCall.(GetDatastreamSnapshotRequest).query.projection; projection != valuesOnly {
+		t.Errorf("Unexpected projection %v", projection)
+	}
+
+	snapshot, err := parseDatastreamSnapshot([]byte(`{"s1": {"temperature": {"value": 300.15, "timestamp": "2022-09-26T14:37:00.468Z"}}}`),
+		interfaces.IndividualAggregation, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values, ok := project(valuesOnly, snapshot).(map[string]any); !ok || len(values) != 1 || values["/s1/temperature"] != 300.15 {
+		t.Errorf("Unexpected values: %v", values)
+	}
+
+	receptionTimestamp := time.Date(2023, 1, 26, 15, 21, 38, 0, time.UTC)
+	objectValues := map[string][]DatastreamObjectValue{"/s1": {{Values: ObjectValues{}, Timestamp: receptionTimestamp, ReceptionTimestamp: receptionTimestamp}}}
+	projected := project(withoutReceptionTimestamps, objectValues).(map[string][]DatastreamObjectValue)
+	if v := projected["/s1"][0]; !v.ReceptionTimestamp.IsZero() || !v.Timestamp.Equal(receptionTimestamp) {
+		t.Errorf("Unexpected timestamps: %v, %v", v.Timestamp, v.ReceptionTimestamp)
+	}
+	if onlyValues, ok := project(valuesOnly, objectValues).(map[string][]ObjectValues); !ok || len(onlyValues["/s1"]) != 1 {
+		t.Errorf("Unexpected values: %v", onlyValues)
+	}
+}
+
+func TestDatastreamPaginatorSeekAndSetPageSize(t *testing.T) {
+	c, _ := getTestContext(t)
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	p, err := c.GetDatastreamIndividualTimeWindowPaginator(testRealmName, testDeviceID, AstarteDeviceID, testInterfaceName, "/an/endpoint", since, to, AscendingOrder, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paginator := p.(*DatastreamPaginator)
+	nextPageQuery := func() url.Values {
+		pageCall, err := paginator.GetNextPage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pageCall.(GetNextDatastreamPageRequest).req.URL.Query()
+	}
+
+	// Simulate a page after the first one
+	paginator.firstPage = false
+	paginator.since = since.Add(time.Hour)
+	if query := nextPageQuery(); query.Get("since_after") == "" {
+		t.Errorf("Unexpected page query: %v", query)
+	}
+
+	seek := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	paginator.Seek(seek)
+	if query := nextPageQuery(); query.Get("since") != seek.Format(time.RFC3339Nano) || query.Has("since_after") || query.Get("to") != to.Format(time.RFC3339Nano) {
+		t.Errorf("Unexpected page query after Seek: %v", query)
+	}
+
+	if err := paginator.SetPageSize(0); err == nil {
+		t.Error("Expected an error for page size 0")
+	}
+	if err := paginator.SetPageSize(5); err != nil || paginator.GetPageSize() != 5 {
+		t.Errorf("Unexpected page size %d: %v", paginator.GetPageSize(), err)
+	}
+	if query := nextPageQuery(); query.Get("limit") != "5" {
+		t.Errorf("Unexpected page query after SetPageSize: %v", query)
+	}
+
+	// Rewinding restores the time window of the paginator
+	paginator.Rewind()
+	if query := nextPageQuery(); query.Get("since") != since.Format(time.RFC3339Nano) || query.Get("to") != to.Format(time.RFC3339Nano) {
+		t.Errorf("Unexpected page query after Rewind: %v", query)
+	}
+
+	p, _ = c.GetDatastreamIndividualPaginator(testRealmName, testDeviceID, AstarteDeviceID, testInterfaceName, "/an/endpoint", DescendingOrder, 10)
+	paginator = p.(*DatastreamPaginator)
+	paginator.Seek(seek)
+	if query := nextPageQuery(); query.Get("to") != seek.Format(time.RFC3339Nano) || query.Has("since") {
+		t.Errorf("Unexpected descending page query after Seek: %v", query)
+	}
+}
+
+func TestResultSetOrderValidation(t *testing.T) {
+	c, _ := getTestContext(t)
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := since.Add(time.Hour)
+	testCases := []struct {
+		order    ResultSetOrder
+		since    time.Time
+		pageSize int
+		err      error
+	}{
+		{AscendingOrder, since, 10, nil},
+		{AscendingOrder, time.Time{}, 0, nil},
+		{DescendingOrder, time.Time{}, 10, nil},
+		{DescendingOrder, since, 10, ErrSinceWithDescendingOrder},
+		{DescendingOrder, time.Time{}, 0, ErrDescendingOrderNeedsPageSize},
+		{ResultSetOrder(2), time.Time{}, 10, ErrInvalidResultSetOrder},
+	}
+	for _, tc := range testCases {
+		_, err := c.GetDatastreamIndividualTimeWindowPaginator(testRealmName, testDeviceID, AstarteDeviceID, testInterfaceName, "/an/endpoint",
+			tc.since, to, tc.order, tc.pageSize)
+		if !errors.Is(err, tc.err) || (tc.err == nil && err != nil) {
+			t.Errorf("Unexpected error for %v order, since %v and page size %d: %v", tc.order, tc.since, tc.pageSize, err)
+		}
+	}
+
+	// The same rules apply when building pages
+	paginator := &DatastreamPaginator{baseURL: &url.URL{}, nextQuery: url.Values{}, client: c, resultSetOrder: DescendingOrder, hasNextPage: true}
+	if _, err := paginator.GetNextPage(); !errors.Is(err, ErrDescendingOrderNeedsPageSize) {
+		t.Errorf("Expected ErrDescendingOrderNeedsPageSize, got %v", err)
+	}
+	if DescendingOrder.String() != "descending" || ResultSetOrder(2).String() != "ResultSetOrder(2)" {
+		t.Errorf("Unexpected string representations %v, %v", DescendingOrder, ResultSetOrder(2))
+	}
+}
+
+func TestValueTransforms(t *testing.T) {
+	c, err := New(
+		WithBaseURL("https://api.astarte.example.com"),
+		WithJWT(testTokenValue),
+		WithValueTransform(testInterfaceName, "/%{sensor_id}/temperature", KelvinToCelsius()),
+		WithValueTransform(testInterfaceName, "/%{sensor_id}/voltage", ADCToVolts(10, 3.3)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot, err := parseDatastreamSnapshot([]byte(`{"s1": {"temperature": {"value": 300.15, "timestamp": "2022-09-26T14:37:00.468Z"},
+		"voltage": {"value": [0, 1023], "timestamp": "2022-09-26T14:37:00.468Z"}, "label": {"value": "a", "timestamp": "2022-09-26T14:37:00.468Z"}}}`),
+		interfaces.IndividualAggregation, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transformed, err := c.valueTransformer(testInterfaceName, "").transform(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := transformed.(map[string]any)
+	if v := values["/s1/temperature"].(DatastreamIndividualValue).Value.(float64); math.Abs(v-27) > 1e-9 {
+		t.Errorf("Unexpected temperature: %v", v)
+	}
+	if v := values["/s1/voltage"].(DatastreamIndividualValue).Value.([]any); v[0].(float64) != 0 || math.Abs(v[1].(float64)-3.3) > 1e-9 {
+		t.Errorf("Unexpected voltage: %v", v)
+	}
+	if v := values["/s1/label"].(DatastreamIndividualValue).Value; v != "a" {
+		t.Errorf("Unexpected label: %v", v)
+	}
+
+	series := parseDatastream(gjson.Parse(`[{"temperature": "hot", "timestamp": "2022-09-26T14:37:00.468Z"}]`), interfaces.ObjectAggregation, false)
+	if _, err := c.valueTransformer(testInterfaceName, "/s1/").transform(series); err == nil {
+		t.Error("Expected an error transforming a non numeric value, found nil")
+	}
+}
+
+func TestObjectValues(t *testing.T) {
+	value := DatastreamObjectValue{}
+	payload := `{"timestamp": "2024-01-01T00:00:00Z", "temperature": 21.5, "count": "9007199254740993", "ok": true,
+		"site": "milan", "since": "2023-06-01T12:00:00Z"}`
+	if err := json.Unmarshal([]byte(payload), &value); err != nil {
+		t.Fatal(err)
+	}
+	values := value.Values
+	if keys := values.Keys(); strings.Join(keys, ",") != "temperature,count,ok,site,since" || values.Len() != 5 {
+		t.Errorf("Unexpected keys %v", keys)
+	}
+	if v, ok := values.GetDouble("temperature"); !ok || v != 21.5 {
+		t.Errorf("Unexpected double %v", v)
+	}
+	if v, ok := values.GetInteger("count"); !ok || v != 9007199254740993 {
+		t.Errorf("Unexpected integer %v", v)
+	}
+	if v, ok := values.GetBool("ok"); !ok || !v {
+		t.Errorf("Unexpected bool %v", v)
+	}
+	if v, ok := values.GetString("site"); !ok || v != "milan" {
+		t.Errorf("Unexpected string %v", v)
+	}
+	if v, ok := values.GetTime("since"); !ok || !v.Equal(time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected time %v", v)
+	}
+	if _, ok := values.GetDouble("site"); ok {
+		t.Error("A string should not be returned as a double")
+	}
+	if _, ok := values.GetString("missing"); ok {
+		t.Error("A missing key should not be returned")
+	}
+
+	// Neither the returned keys nor the returned map are shared with values
+	values.Keys()[0] = "changed"
+	values.AsMap()["temperature"] = 0.0
+	if v, _ := values.Get("temperature"); v != 21.5 || values.Keys()[0] != "temperature" {
+		t.Errorf("ObjectValues was modified: %v", values.AsMap())
+	}
+	// Transforms return new values, leaving copies untouched
+	transformed, err := valueTransformer{rules: []valueTransformRule{{interfaceName: "i", endpoint: "/temperature", transform: Linear(2, 0)}}, interfaceName: "i"}.
+		transformObjectValue("", value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := transformed.Values.GetDouble("temperature"); v != 43 {
+		t.Errorf("Unexpected transformed value %v", v)
+	}
+	if v, _ := values.GetDouble("temperature"); v != 21.5 {
+		t.Errorf("The original value was transformed: %v", v)
+	}
+
+	b, err := json.Marshal(NewObjectValues(map[string]any{"b": 1, "a": "x"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"a":"x","b":1}` {
+		t.Errorf("Unexpected JSON %s", b)
+	}
+}
+
+func TestBroadcastData(t *testing.T) {
+	var mu sync.Mutex
+	requests := map[string]int{}
+	c, _ := getTestServerContext(t, func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		requests[req.URL.Path]++
+		attempt := requests[req.URL.Path]
+		mu.Unlock()
+		switch {
+		case strings.Contains(req.URL.Path, testDeviceIDs[1]) && attempt == 1:
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"errors": {"detail": "Too many requests"}}`))
+		case strings.Contains(req.URL.Path, testDeviceIDs[2]):
+			// The data may have been stored, so it must not be sent again
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"errors": {"detail": "Service unavailable"}}`))
+		default:
+			_, _ = w.Write([]byte(`{"data": ""}`))
+		}
+	})
+	datastreamInterface := interfaces.AstarteInterface{Name: testServerOwnedInterfaceName, Ownership: interfaces.ServerOwnership, Type: interfaces.DatastreamType,
+		Mappings: []interfaces.AstarteInterfaceMapping{{Endpoint: "/an/endpoint", Type: interfaces.Integer}}, Aggregation: interfaces.IndividualAggregation}
+
+	if _, err := c.BroadcastData(testRealmName, testDeviceIDs, datastreamInterface, "/an/endpoint", "not an integer"); err == nil {
+		t.Error("Expected an invalid payload to be rejected")
+	}
+	if len(requests) > 0 {
+		t.Errorf("No request should be sent for an invalid payload, got %v", requests)
+	}
+
+	start := time.Now()
+	deviceIDs := append([]string{testDeviceIDs[0]}, testDeviceIDs...)
+	results, err := c.BroadcastData(testRealmName, deviceIDs, datastreamInterface, "/an/endpoint", 42,
+		WithBroadcastConcurrency(2), WithBroadcastRate(100), WithBroadcastRetries(2, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 4 requests at 100 requests per second take at least 30ms
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("Requests were not rate limited: took %v", elapsed)
+	}
+	expectedAttempts := []int{1, 2, 1}
+	for i, deviceID := range testDeviceIDs {
+		result := results[deviceID]
+		if result.DeviceID != deviceID || result.Attempts != expectedAttempts[i] {
+			t.Errorf("Unexpected result for device %s: %v", deviceID, result)
+		}
+		if failed := result.Err != nil; failed != (i == 2) {
+			t.Errorf("Unexpected error for device %s: %v", deviceID, result.Err)
+		}
+	}
+	if len(results) != len(testDeviceIDs) {
+		t.Errorf("Expected a result for each device, found %v", results)
+	}
+	for path, count := range requests {
+		if strings.Contains(path, testDeviceIDs[0]) && count != 1 {
+			t.Errorf("Expected data to be sent once to device %s, found %d requests", testDeviceIDs[0], count)
+		}
+	}
+}
+
+func TestIsRetriableBeforeSend(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+	_, dialErr := http.Get("http://" + address)
+	if dialErr == nil {
+		t.Fatal("Expected connecting to a closed port to fail")
+	}
+
+	testCases := []struct {
+		err       error
+		retriable bool
+	}{
+		{&APIError{StatusCode: http.StatusTooManyRequests}, true},
+		{&APIError{StatusCode: http.StatusServiceUnavailable}, false},
+		{&APIError{StatusCode: http.StatusUnprocessableEntity}, false},
+		{dialErr, true},
+		{&url.Error{Op: "Post", URL: "http://astarte", Err: io.ErrUnexpectedEOF}, false},
+	}
+	for _, tc := range testCases {
+		if retriable := isRetriableBeforeSend(tc.err); retriable != tc.retriable {
+			t.Errorf("Expected isRetriableBeforeSend(%v) to be %v", tc.err, tc.retriable)
+		}
+	}
+}
@@ -15,7 +15,19 @@
 package client
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/interfaces"
+	"github.com/astarte-platform/astarte-go/internal/runner"
 )
 
 func TestListDevices(t *testing.T) {
@@ -55,3 +67,631 @@ func TestListDevices(t *testing.T) {
 		t.Error("Paginator should NOT have next page")
 	}
 }
+
+func TestDeviceListPaginatorFollowsNextLink(t *testing.T) {
+	c, _ := getTestContext(t)
+	paginator, err := c.GetDeviceListPaginator(testRealmName, 2, DeviceIDFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := `{
+		"data": ["fhd0WHcgSjWeVqPGKZv_KA", "t1J1uQSBQRi_1F3zIrjyYw"],
+		"links": {
+			"self": "/v1/test/devices?details=false",
+			"next": "/v1/test/devices?details=false&from_token=42&limit=2"
+		}
+	}`
+	data := paginator.parseData([]byte(page))
+	paginator.computePageState([]byte(page))
+	if ids, ok := data.([]string); !ok || len(ids) != 2 {
+		t.Fatalf("Unexpected page data: %v", data)
+	}
+	if !paginator.HasNextPage() {
+		t.Fatal("Paginator should have next page")
+	}
+	nextPageCall, err := paginator.GetNextPage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := nextPageCall.(GetNextDeviceListPageRequest).req.URL.Query()
+	if query.Get("from_token") != "42" {
+		t.Errorf("Next page does not follow the next link: %v", query)
+	}
+}
+
+func TestPageSizeClamping(t *testing.T) {
+	if _, err := New(WithBaseURL("https://api.example.com"), WithJWT(testTokenValue), WithMaxPageSize(0)); !errors.Is(err, ErrInvalidMaxPageSize) {
+		t.Errorf("Expected ErrInvalidMaxPageSize, found %v", err)
+	}
+	c, err := New(WithBaseURL("https://api.example.com"), WithJWT(testTokenValue), WithMaxPageSize(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := c.GetDatastreamIndividualPaginator(testRealmName, testDeviceID, AstarteDeviceID, testInterfaceName, "/an/endpoint", AscendingOrder, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	datastreamPaginator := p.(*DatastreamPaginator)
+	nextPageCall, err := datastreamPaginator.GetNextPage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limit := nextPageCall.(GetNextDatastreamPageRequest).req.URL.Query().Get("limit"); limit != "100" || p.GetPageSize() != 100 {
+		t.Errorf("Unexpected page size %d and limit %s", p.GetPageSize(), limit)
+	}
+	if err := datastreamPaginator.SetPageSize(1000); err != nil || p.GetPageSize() != 100 {
+		t.Errorf("Unexpected page size %d: %v", p.GetPageSize(), err)
+	}
+
+	// A page which is not full is not the last one if Astarte links a next page
+	page := `{"data": [{"value": 1, "timestamp": "2024-01-01T00:00:00Z"}], "links": {"next": "/v1/test/next"}}`
+	datastreamPaginator.computePageState([]byte(page))
+	if !p.HasNextPage() {
+		t.Error("Paginator should have next page")
+	}
+	datastreamPaginator.computePageState([]byte(`{"data": [{"value": 1, "timestamp": "2024-01-01T00:00:00Z"}]}`))
+	if p.HasNextPage() {
+		t.Error("Paginator should NOT have next page")
+	}
+
+	// The limit applied by Astarte to device lists is read from the next link
+	p, err = c.GetDeviceListPaginator(testRealmName, 50, DeviceIDFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nextPageCall, err = p.GetNextPage(); err != nil {
+		t.Fatal(err)
+	}
+	if limit := nextPageCall.(GetNextDeviceListPageRequest).req.URL.Query().Get("limit"); limit != "50" {
+		t.Errorf("Unexpected limit %s", limit)
+	}
+	p.(*DeviceListPaginator).computePageState([]byte(`{"data": ["fhd0WHcgSjWeVqPGKZv_KA"], "links": {"next": "/v1/test/devices?from_token=1&limit=20"}}`))
+	if p.GetPageSize() != 20 {
+		t.Errorf("Unexpected page size %d", p.GetPageSize())
+	}
+}
+
+func TestDeviceListPaginatorProgress(t *testing.T) {
+	c, _ := getTestContext(t)
+	p, err := c.GetDeviceListPaginator(testRealmName, 10, DeviceIDFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paginator := p.(*DeviceListPaginator)
+
+	// Without totals in the pages, the device stats of the realm are used
+	if total, err := paginator.EstimatedTotal(); err != nil || total != int64(testTotalDevices) {
+		t.Errorf("Unexpected total %d: %v", total, err)
+	}
+	nextPageCall, err := paginator.GetNextPage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runner.RunAndParse[[]string](c, nextPageCall.Run); err != nil {
+		t.Fatal(err)
+	}
+	if paginator.FetchedItems() != len(testDeviceIDs) {
+		t.Errorf("Unexpected fetched items %d", paginator.FetchedItems())
+	}
+	if progress, err := paginator.Progress(); err != nil || progress != float64(len(testDeviceIDs))/float64(testTotalDevices) {
+		t.Errorf("Unexpected progress %f: %v", progress, err)
+	}
+
+	paginator.Rewind()
+	page := `{"data": ["fhd0WHcgSjWeVqPGKZv_KA"], "meta": {"total_count": 4}, "links": {"self": "/v1/test/devices"}}`
+	paginator.computePageState([]byte(page))
+	if total, _ := paginator.EstimatedTotal(); total != 4 {
+		t.Errorf("The total in the page was not used: %d", total)
+	}
+	if progress, _ := paginator.Progress(); progress != 0.25 {
+		t.Errorf("Unexpected progress %f", progress)
+	}
+}
+
+func TestAstartePayload(t *testing.T) {
+	payload, err := unmarshalAstartePayload([]byte(`{"data": {"total_devices": 10, "connected_devices": 3}}`), DevicesStats{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payload.Data.TotalDevices != 10 || payload.Data.ConnectedDevices != 3 {
+		t.Errorf("Unexpected payload data: %v", payload.Data)
+	}
+	if payload.Links != nil {
+		t.Errorf("Unexpected payload links: %v", payload.Links)
+	}
+}
+
+func TestGetDeviceInterfaceStats(t *testing.T) {
+	c, _ := getTestContext(t)
+	getStatsCall, err := c.GetDeviceInterfaceStats(testRealmName, testDeviceID, AstarteDeviceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if curl := getStatsCall.ToCurl(c); !strings.Contains(curl, "fields=introspection") {
+		t.Errorf("Request does not filter fields: %s", curl)
+	}
+	stats, err := runner.RunAndParse[DeviceInterfaceStats](c, getStatsCall.Run)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.ExchangedMessages(testInterfaceName) != 10 || stats.ExchangedBytes(testInterfaceName) != 200 {
+		t.Errorf("Unexpected stats for %s: %+v", testInterfaceName, stats[testInterfaceName])
+	}
+	if stats.TotalExchangedMessages() != 11 || stats.TotalExchangedBytes() != 230 {
+		t.Errorf("Unexpected total stats: %+v", stats)
+	}
+	if stats.ExchangedMessages("ah.yes.an.unknown.Interface") != 0 {
+		t.Error("Expected no messages for an interface not in the introspection")
+	}
+}
+
+func TestGetInterfacesForDevice(t *testing.T) {
+	c, _ := getTestContext(t)
+	requestsBefore := testGetInterfaceRequests.Load()
+
+	for i := 0; i < 2; i++ {
+		ifaces, err := c.GetInterfacesForDevice(testRealmName, testDeviceID, AstarteDeviceID)
+		if err == nil || !strings.Contains(err.Error(), testMissingInterfaceName) {
+			t.Errorf("Expected an error for %s, found %v", testMissingInterfaceName, err)
+		}
+		if len(ifaces) != 1 || ifaces[testInterfaceName].Name != testInterfaceName || ifaces[testInterfaceName].MajorVersion != testInterfaceMajor {
+			t.Errorf("Unexpected interfaces: %v", ifaces)
+		}
+	}
+	if requests := testGetInterfaceRequests.Load() - requestsBefore; requests != 1 {
+		t.Errorf("Expected 1 request for %s, found %d", testInterfaceName, requests)
+	}
+
+	c.ClearInterfaceCache()
+	if _, err := c.GetInterfacesForDevice(testRealmName, testDeviceID, AstarteDeviceID); err == nil {
+		t.Error("Expected an error, found nil")
+	}
+	if requests := testGetInterfaceRequests.Load() - requestsBefore; requests != 2 {
+		t.Errorf("Expected 2 requests for %s after clearing the cache, found %d", testInterfaceName, requests)
+	}
+}
+
+func TestGetAllDeviceProperties(t *testing.T) {
+	c, _ := getTestContext(t)
+
+	properties, err := c.GetAllDeviceProperties(testRealmName, testPropertiesDeviceID, AstarteDeviceID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]map[string]PropertyValue{
+		testDevicePropertiesInterfaceName: {"/a/enabled": true, "/a/counter": int64(9007199254740993), "/b/enabled": false, "/b/counter": int64(42)},
+		testServerPropertiesInterfaceName: {
+			"/config/name":       "ah yes, a name",
+			"/config/updated":    time.Date(2024, 5, 6, 7, 8, 9, 123000000, time.UTC),
+			"/config/thresholds": []int{1, 2, 3},
+		},
+	}
+	if !reflect.DeepEqual(properties, expected) {
+		t.Errorf("Expected %v, found %v", expected, properties)
+	}
+
+	properties, err = c.GetAllDeviceProperties(testRealmName, testPropertiesDeviceID, AstarteDeviceID, interfaces.ServerOwnership)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(properties) != 1 || !reflect.DeepEqual(properties[testServerPropertiesInterfaceName], expected[testServerPropertiesInterfaceName]) {
+		t.Errorf("Expected only %s, found %v", testServerPropertiesInterfaceName, properties)
+	}
+
+	if _, err := c.GetAllDeviceProperties(testRealmName, testPropertiesDeviceID, AstarteDeviceID, "nobody"); err == nil {
+		t.Error("Expected an error for an invalid ownership, found nil")
+	}
+}
+
+func TestSetProperties(t *testing.T) {
+	c, _ := getTestContext(t)
+	propertyInterface := interfaces.AstarteInterface{Name: testServerOwnedPropertyInterfaceName, Ownership: interfaces.ServerOwnership, Type: interfaces.PropertiesType,
+		Mappings: []interfaces.AstarteInterfaceMapping{{Endpoint: "/%{name}/endpoint", Type: interfaces.Integer}}}
+
+	results, err := c.SetProperties(testRealmName, testDeviceID, AstarteDeviceID, propertyInterface, map[string]any{
+		"/an/endpoint":       42,
+		"/other/endpoint":    43,
+		"/rejected/endpoint": 44,
+	}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 || results[0].Path != "/an/endpoint" || results[1].Path != "/other/endpoint" || results[2].Path != "/rejected/endpoint" {
+		t.Fatalf("Unexpected results: %v", results)
+	}
+	if results[0].Err != nil || results[1].Err != nil || !errors.Is(results[2].Err, ErrUnexpectedValueType) {
+		t.Errorf("Unexpected results: %v", results)
+	}
+
+	// Invalid values prevent setting any property
+	if _, err := c.SetProperties(testRealmName, testDeviceID, AstarteDeviceID, propertyInterface, map[string]any{
+		"/an/endpoint":     "not an integer",
+		"/a/path/too/long": 42,
+	}, 0); err == nil || !strings.Contains(err.Error(), "/an/endpoint") || !strings.Contains(err.Error(), "/a/path/too/long") {
+		t.Errorf("Expected errors for both paths, found %v", err)
+	}
+}
+
+func TestSetDeviceAttributesWithSchema(t *testing.T) {
+	c, _ := getTestContext(t)
+	schema := AttributeSchema{
+		Rules: []AttributeRule{
+			{KeyPattern: regexp.MustCompile(`^fw_version$`), Validator: MatchValue(regexp.MustCompile(`^\d+\.\d+\.\d+$`))},
+			{KeyPattern: regexp.MustCompile(`^site$`), Validator: OneOfValues("milan", "turin")},
+			{KeyPattern: regexp.MustCompile(`^x_`)},
+		},
+		RejectUnknownKeys: true,
+	}
+	if err := WithAttributeSchema(schema)(c); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.SetDeviceAttributes(testRealmName, testDeviceID, AstarteDeviceID, map[string]string{"fw_version": "1.2.3", "site": "milan", "x_note": "anything"}); err != nil {
+		t.Errorf("Unexpected error for valid attributes: %v", err)
+	}
+
+	_, err := c.SetDeviceAttributes(testRealmName, testDeviceID, AstarteDeviceID, map[string]string{"fw_version": "latest", "owner": "me"})
+	var violation *AttributeSchemaViolation
+	if !errors.As(err, &violation) || violation.Key != "fw_version" || violation.Value != "latest" {
+		t.Errorf("Expected a violation for fw_version, found %v", err)
+	}
+	if !errors.Is(err, ErrUnknownAttributeKey) {
+		t.Errorf("Expected ErrUnknownAttributeKey for owner, found %v", err)
+	}
+
+	if _, err := c.SetDeviceAttribute(testRealmName, testDeviceID, AstarteDeviceID, "site", "rome"); err == nil {
+		t.Error("Expected an error for site, found nil")
+	}
+}
+
+func TestDeviceLabels(t *testing.T) {
+	c, _ := getTestContext(t)
+	setCall, err := c.SetDeviceLabels(testRealmName, testDeviceID, AstarteDeviceID, map[string]string{"env": "prod"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if curl := setCall.ToCurl(c); !strings.Contains(curl, `"labels/env":"prod"`) {
+		t.Errorf("Unexpected labels patch: %s", curl)
+	}
+	removeCall, err := c.RemoveDeviceLabels(testRealmName, testDeviceID, AstarteDeviceID, "env", "region")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if curl := removeCall.ToCurl(c); !strings.Contains(curl, `"labels/env":null`) || !strings.Contains(curl, `"labels/region":null`) {
+		t.Errorf("Unexpected labels patch: %s", curl)
+	}
+	if _, err := c.SetDeviceLabels(testRealmName, testDeviceID, AstarteDeviceID, map[string]string{"env": "not valid"}); !errors.Is(err, ErrInvalidLabel) {
+		t.Errorf("Expected ErrInvalidLabel, found %v", err)
+	}
+	for _, invalidKey := range []string{"", "a/b", "-env"} {
+		if _, err := c.RemoveDeviceLabels(testRealmName, testDeviceID, AstarteDeviceID, "env", invalidKey); !errors.Is(err, ErrInvalidLabel) {
+			t.Errorf("Expected ErrInvalidLabel removing %q, found %v", invalidKey, err)
+		}
+	}
+
+	device := DeviceDetails{Attributes: map[string]string{"labels/env": "prod", "labels/region": "eu", "site": "milan"}}
+	if labels := device.Labels(); !reflect.DeepEqual(labels, map[string]string{"env": "prod", "region": "eu"}) {
+		t.Errorf("Unexpected labels %v", labels)
+	}
+}
+
+func TestLabelSelector(t *testing.T) {
+	labels := map[string]string{"env": "prod", "region": "eu", "tier": ""}
+	testCases := []struct {
+		selector string
+		matches  bool
+	}{
+		{"", true},
+		{"env=prod", true},
+		{"env==prod, region in (eu, us)", true},
+		{"env!=prod", false},
+		{"region notin (eu,us)", false},
+		{"region in (us)", false},
+		{"tier,!deprecated", true},
+		{"deprecated", false},
+		{"owner!=me,owner notin (me)", true},
+	}
+	for _, tc := range testCases {
+		selector, err := ParseLabelSelector(tc.selector)
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %v", tc.selector, err)
+			continue
+		}
+		if selector.Matches(labels) != tc.matches {
+			t.Errorf("Unexpected match for %q: expected %v", tc.selector, tc.matches)
+		}
+	}
+
+	selector, _ := ParseLabelSelector("region in (us, eu),env==prod,!deprecated")
+	if selector.String() != "region in (eu,us),env=prod,!deprecated" {
+		t.Errorf("Unexpected selector %s", selector)
+	}
+	if !selector.MatchesDevice(DeviceDetails{Attributes: map[string]string{"labels/env": "prod", "labels/region": "us"}}) {
+		t.Errorf("Expected %s to match the device", selector)
+	}
+	for _, invalid := range []string{"env=prod,", "env=not valid", "=prod", "region in (eu,-us)"} {
+		if _, err := ParseLabelSelector(invalid); !errors.Is(err, ErrInvalidLabelSelector) {
+			t.Errorf("Expected ErrInvalidLabelSelector for %q, found %v", invalid, err)
+		}
+	}
+}
+
+func TestPatchDeviceIntrospection(t *testing.T) {
+	c, _ := getTestContext(t)
+	patch := IntrospectionPatch{Set: map[string]InterfaceVersion{testInterfaceName: {Major: 1, Minor: 2}}, Remove: []string{"ah.yes.an.old.Interface"}}
+	patchCall, err := c.PatchDeviceIntrospection(testRealmName, testDeviceID, AstarteDeviceID, patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	curl := patchCall.ToCurl(c)
+	if !strings.Contains(curl, `"ah.yes.an.Interface":{"major":1,"minor":2}`) || !strings.Contains(curl, `"ah.yes.an.old.Interface":null`) {
+		t.Errorf("Unexpected introspection patch: %s", curl)
+	}
+	// The mock ignores the introspection in the patch, as some Astarte versions do
+	if _, err := patchCall.Run(c); !errors.Is(err, ErrIntrospectionNotPatched) {
+		t.Errorf("Expected ErrIntrospectionNotPatched, found %v", err)
+	}
+
+	patching, _ := getTestServerContext(t, func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprintf(w, `{"data": {"id": %q, "introspection": {%q: {"major": 1, "minor": 2}, "another.Interface": {"major": 0, "minor": 1}}}}`,
+			testDeviceID, testInterfaceName)
+	})
+	patchCall, _ = patching.PatchDeviceIntrospection(testRealmName, testDeviceID, AstarteDeviceID, patch)
+	details, err := runner.RunAndParse[DeviceDetails](patching, patchCall.Run)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details.Introspection[testInterfaceName].Minor != 2 {
+		t.Errorf("Unexpected device details %+v", details)
+	}
+
+	invalidPatches := []IntrospectionPatch{
+		{},
+		{Set: map[string]InterfaceVersion{"not a valid name": {Major: 1}}},
+		{Set: map[string]InterfaceVersion{testInterfaceName: {Major: 0, Minor: 0}}},
+		{Set: map[string]InterfaceVersion{testInterfaceName: {Major: 1}}, Remove: []string{testInterfaceName}},
+	}
+	for _, invalidPatch := range invalidPatches {
+		if _, err := c.PatchDeviceIntrospection(testRealmName, testDeviceID, AstarteDeviceID, invalidPatch); err == nil {
+			t.Errorf("Expected an error for %+v, found nil", invalidPatch)
+		}
+	}
+}
+
+func TestResolveAliases(t *testing.T) {
+	c, _ := getTestContext(t)
+	requestsBefore := testResolveAliasRequests.Load()
+
+	for i := 0; i < 2; i++ {
+		resolved, errs := c.ResolveAliases(testRealmName, []string{testDeviceAlias, testMissingDeviceAlias}, 2)
+		if len(resolved) != 1 || resolved[testDeviceAlias] != testDeviceID {
+			t.Errorf("Unexpected resolved aliases: %v", resolved)
+		}
+		if len(errs) != 1 || errs[testMissingDeviceAlias] == nil {
+			t.Errorf("Expected an error for %s, found %v", testMissingDeviceAlias, errs)
+		}
+	}
+	if requests := testResolveAliasRequests.Load() - requestsBefore; requests != 1 {
+		t.Errorf("Expected 1 request for %s, found %d", testDeviceAlias, requests)
+	}
+
+	c.ClearAliasCache()
+	if resolved, _ := c.ResolveAliases(testRealmName, []string{testDeviceAlias}, 0); resolved[testDeviceAlias] != testDeviceID {
+		t.Errorf("Unexpected resolved aliases: %v", resolved)
+	}
+	if requests := testResolveAliasRequests.Load() - requestsBefore; requests != 2 {
+		t.Errorf("Expected 2 requests for %s after clearing the cache, found %d", testDeviceAlias, requests)
+	}
+}
+
+func TestResolveDevice(t *testing.T) {
+	c, _ := getTestContext(t)
+	cases := []struct {
+		identifier string
+		expected   DeviceResolution
+		deviceID   string
+		err        error
+	}{
+		{testDeviceID, DeviceResolution{Identifier: testDeviceID, ByDeviceID: testDeviceID}, testDeviceID, nil},
+		{testDeviceAlias, DeviceResolution{Identifier: testDeviceAlias, ByAlias: testDeviceID}, testDeviceID, nil},
+		{testDeviceIDs[1], DeviceResolution{Identifier: testDeviceIDs[1], ByDeviceID: testDeviceIDs[1], ByAlias: testDeviceID}, "", ErrAmbiguousIdentifier},
+		{testMissingDeviceAlias, DeviceResolution{Identifier: testMissingDeviceAlias}, "", ErrDeviceNotFound},
+	}
+	for _, tc := range cases {
+		resolution, err := c.ResolveDevice(testRealmName, tc.identifier)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resolution != tc.expected {
+			t.Errorf("Expected %+v, found %+v", tc.expected, resolution)
+		}
+		deviceID, err := resolution.DeviceID()
+		if deviceID != tc.deviceID || !errors.Is(err, tc.err) {
+			t.Errorf("%s: expected %s and %v, found %s and %v", tc.identifier, tc.deviceID, tc.err, deviceID, err)
+		}
+	}
+}
+
+func TestStrictDeviceIdentifiers(t *testing.T) {
+	c, err := New(WithBaseURL("api.an-astarte.org"), WithJWT(testTokenValue), WithStrictDeviceIdentifiers())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetDeviceDetails(testRealmName, testDeviceID, AutodiscoverDeviceIdentifier); !errors.Is(err, ErrAmbiguousIdentifier) {
+		t.Errorf("Expected ErrAmbiguousIdentifier, found %v", err)
+	}
+	if _, err := c.GetDatastreamIndividualPaginator(testRealmName, testDeviceID, AutodiscoverDeviceIdentifier, testInterfaceName, "/a", AscendingOrder, 10); !errors.Is(err, ErrAmbiguousIdentifier) {
+		t.Errorf("Expected ErrAmbiguousIdentifier, found %v", err)
+	}
+	unambiguous := map[string]DeviceIdentifierType{testDeviceID: AstarteDeviceID, testDeviceIDs[1]: AstarteDeviceAlias, testDeviceAlias: AutodiscoverDeviceIdentifier}
+	for identifier, identifierType := range unambiguous {
+		if _, err := c.GetDeviceDetails(testRealmName, identifier, identifierType); err != nil {
+			t.Errorf("Unexpected error for an unambiguous identifier: %v", err)
+		}
+	}
+}
+
+func TestInterfaceAdoptionReport(t *testing.T) {
+	c, _ := getTestContext(t)
+	report, err := c.InterfaceAdoptionReport(testRealmName, testInterfaceName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.TotalDevices != len(testDevicesDetails) {
+		t.Errorf("Unexpected total devices: %d", report.TotalDevices)
+	}
+	if report.Versions[InterfaceVersion{Major: testInterfaceMajor, Minor: 0}] != 2 || report.Versions[InterfaceVersion{Major: 2, Minor: 0}] != 1 {
+		t.Errorf("Unexpected versions: %v", report.Versions)
+	}
+	if report.DevicesWithMajor(testInterfaceMajor) != 2 || report.DevicesWithMajor(3) != 0 {
+		t.Errorf("Unexpected devices by major: %v", report.Versions)
+	}
+	if deviceIDs := report.DeviceIDsWithMajor(2); !reflect.DeepEqual(deviceIDs, testDeviceIDs[2:]) {
+		t.Errorf("Unexpected Device IDs by major: %v", deviceIDs)
+	}
+}
+
+func TestPaginateToChannel(t *testing.T) {
+	c, _ := getTestContext(t)
+	paginator, err := c.GetDeviceListPaginator(testRealmName, 10, DeviceIDFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make(chan string)
+	errc := PaginateToChannel(context.Background(), paginator, out)
+	deviceIDs := []string{}
+	for deviceID := range out {
+		deviceIDs = append(deviceIDs, deviceID)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if len(deviceIDs) != len(testDeviceIDs) {
+		t.Errorf("Unexpected device IDs: %v", deviceIDs)
+	}
+
+	paginator.Rewind()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	out = make(chan string)
+	if err := <-PaginateToChannel(ctx, paginator, out); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, found %v", err)
+	}
+	if _, ok := <-out; ok {
+		t.Error("Expected the output channel to be closed")
+	}
+}
+
+func TestFindDevices(t *testing.T) {
+	c, _ := getTestContext(t)
+	devices, err := c.FindDevices(testRealmName, DeviceFilter{}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != len(testDevicesDetails) {
+		t.Errorf("Expected all %d devices, found %d", len(testDevicesDetails), len(devices))
+	}
+	devices, err = c.FindDevices(testRealmName, DeviceFilter{Attributes: map[string]string{"site": "milan"}}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 0 {
+		t.Errorf("Expected no devices to be found client-side, found %v", devices)
+	}
+}
+
+func TestFindDevicesFiltersClientSide(t *testing.T) {
+	queries := []url.Values{}
+	c, _ := getTestServerContext(t, func(w http.ResponseWriter, req *http.Request) {
+		queries = append(queries, req.URL.Query())
+		fmt.Fprintf(w, `{"data": [{"id": "milan-device", "attributes": {"site": "milan"}}, `+
+			`{"id": "rome-device", "attributes": {"site": "rome"}}], "links": {"self": "/v1/%s/devices"}}`, testRealmName)
+	})
+
+	devices, err := c.FindDevices(testRealmName, DeviceFilter{Attributes: map[string]string{"site": "milan"}}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 1 || devices[0].DeviceID != "milan-device" {
+		t.Errorf("Unexpected devices %v", devices)
+	}
+	expected := url.Values{"details": {"true"}, "limit": {"10"}}
+	if len(queries) != 1 || !reflect.DeepEqual(queries[0], expected) {
+		t.Errorf("Expected a single request with query %v, found %v", expected, queries)
+	}
+}
+
+func TestPageError(t *testing.T) {
+	c, _ := getTestServerContext(t, func(w http.ResponseWriter, req *http.Request) {
+		if strings.Contains(req.URL.Path, "/interfaces/") || req.URL.Query().Get("from_token") != "" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"errors": {"detail": "Service unavailable"}}`))
+			return
+		}
+		fmt.Fprintf(w, `{"data": ["%s"], "links": {"self": "/v1/%s/devices", "next": "/v1/%s/devices?from_token=%s"}}`,
+			testDeviceID, testRealmName, testRealmName, testDeviceID)
+	})
+
+	devicesPaginator, _ := c.GetDeviceListPaginator(testRealmName, 1, DeviceIDFormat)
+	_, err := nextDevicesPage(c, devicesPaginator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = nextDevicesPage(c, devicesPaginator)
+	pageError := &PageError{}
+	if !errors.As(err, &pageError) {
+		t.Fatalf("Expected a PageError, got %v", err)
+	}
+	apiError := &APIError{}
+	if pageError.PageIndex != 1 || pageError.Query.Get("from_token") != testDeviceID || !errors.As(err, &apiError) ||
+		apiError.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Unexpected page error %#v", pageError)
+	}
+
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	datastreamPaginator, _ := c.GetDatastreamIndividualTimeWindowPaginator(testRealmName, testDeviceID, AstarteDeviceID,
+		testInterfaceName, "/a/value", since, time.Time{}, AscendingOrder, 10)
+	nextPageCall, _ := datastreamPaginator.GetNextPage()
+	_, err = nextPageCall.Run(c)
+	if !errors.As(err, &pageError) {
+		t.Fatalf("Expected a PageError, got %v", err)
+	}
+	if pageError.PageIndex != 0 || !pageError.Since.Equal(since) || !pageError.To.IsZero() || !strings.Contains(pageError.URL, "/a/value") {
+		t.Errorf("Unexpected page error %#v", pageError)
+	}
+}
+
+func nextDevicesPage(c *Client, paginator Paginator) (any, error) {
+	nextPageCall, err := paginator.GetNextPage()
+	if err != nil {
+		return nil, err
+	}
+	res, err := nextPageCall.Run(c)
+	if err != nil {
+		return nil, err
+	}
+	return res.Parse()
+}
+
+func TestURLEscaping(t *testing.T) {
+	c, _ := getTestContext(t)
+
+	call, err := c.GetDeviceDetails(testRealmName, "building/floor 1", AstarteDeviceAlias)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "/appengine/v1/" + testRealmName + "/devices-by-alias/building%2Ffloor%201"
+	if escapedPath := call.(GetDeviceDetailsRequest).req.URL.EscapedPath(); escapedPath != expected {
+		t.Errorf("Unexpected path %s instead of %s", escapedPath, expected)
+	}
+
+	call, err = c.SetProperty(testRealmName, testDeviceID, AstarteDeviceID, testServerOwnedInterfaceName, "/rooms/a b/../setting", 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = "/appengine/v1/" + testRealmName + "/devices/" + testDeviceID + "/interfaces/" + testServerOwnedInterfaceName + "/rooms/a%20b/%2E%2E/setting"
+	if escapedPath := call.(SetPropertyRequest).req.URL.EscapedPath(); escapedPath != expected {
+		t.Errorf("Unexpected path %s instead of %s", escapedPath, expected)
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/astarte-platform/astarte-go/interfaces"
+)
+
+// ValueTransform converts a datastream value while it is parsed, e.g. to convert it to a different unit.
+// Array values (e.g. doublearray) are passed element by element.
+type ValueTransform func(value any) (any, error)
+
+type valueTransformRule struct {
+	interfaceName string
+	endpoint      string
+	transform     ValueTransform
+}
+
+func (r valueTransformRule) matches(interfaceName, path string) bool {
+	if r.interfaceName != interfaceName {
+		return false
+	}
+	_, err := interfaces.ExtractParameters(interfaces.AstarteInterfaceMapping{Endpoint: r.endpoint}, path)
+	return err == nil
+}
+
+// valueTransformer applies the transforms registered in a Client to the values parsed from
+// an interface, starting from basePath.
+type valueTransformer struct {
+	rules         []valueTransformRule
+	interfaceName string
+	basePath      string
+}
+
+func (c *Client) valueTransformer(interfaceName, basePath string) valueTransformer {
+	return valueTransformer{rules: c.valueTransforms, interfaceName: interfaceName, basePath: strings.TrimSuffix(basePath, "/")}
+}
+
+func (t valueTransformer) transformValue(path string, value any) (any, error) {
+	for _, rule := range t.rules {
+		if !rule.matches(t.interfaceName, path) {
+			continue
+		}
+		var err error
+		if value, err = applyValueTransform(rule.transform, value); err != nil {
+			return nil, fmt.Errorf("Cannot transform value on %s%s: %w", t.interfaceName, path, err)
+		}
+	}
+	return value, nil
+}
+
+func applyValueTransform(transform ValueTransform, value any) (any, error) {
+	array, ok := value.([]any)
+	if !ok {
+		return transform(value)
+	}
+	ret := make([]any, len(array))
+	for i, v := range array {
+		transformed, err := transform(v)
+		if err != nil {
+			return nil, err
+		}
+		ret[i] = transformed
+	}
+	return ret, nil
+}
+
+func (t valueTransformer) transformIndividualValue(path string, value DatastreamIndividualValue) (DatastreamIndividualValue, error) {
+	transformed, err := t.transformValue(path, value.Value)
+	if err != nil {
+		return value, err
+	}
+	value.Value = transformed
+	return value, nil
+}
+
+func (t valueTransformer) transformObjectValue(path string, value DatastreamObjectValue) (DatastreamObjectValue, error) {
+	for _, key := range value.Values.Keys() {
+		v, _ := value.Values.Get(key)
+		transformed, err := t.transformValue(path+"/"+key, v)
+		if err != nil {
+			return value, err
+		}
+		value.Values = value.Values.with(key, transformed)
+	}
+	return value, nil
+}
+
+// transform applies the registered transforms to the result of a datastream Parse.
+// Keys of the returned maps are paths relative to basePath.
+func (t valueTransformer) transform(data any) (any, error) {
+	if len(t.rules) == 0 {
+		return data, nil
+	}
+	var err error
+	switch values := data.(type) {
+	case []DatastreamIndividualValue:
+		for i := range values {
+			if values[i], err = t.transformIndividualValue(t.basePath, values[i]); err != nil {
+				return nil, err
+			}
+		}
+	case map[string]DatastreamIndividualValue:
+		for k, v := range values {
+			if values[k], err = t.transformIndividualValue(t.basePath+k, v); err != nil {
+				return nil, err
+			}
+		}
+	case map[string]any:
+		for k, v := range values {
+			if individualValue, ok := v.(DatastreamIndividualValue); ok {
+				if values[k], err = t.transformIndividualValue(t.basePath+k, individualValue); err != nil {
+					return nil, err
+				}
+			}
+		}
+	case []DatastreamObjectValue:
+		for i := range values {
+			if values[i], err = t.transformObjectValue(t.basePath, values[i]); err != nil {
+				return nil, err
+			}
+		}
+	case map[string][]DatastreamObjectValue:
+		for k, objectValues := range values {
+			for i := range objectValues {
+				if objectValues[i], err = t.transformObjectValue(t.basePath+k, objectValues[i]); err != nil {
+					return nil, err
+				}
+			}
+		}
+	case map[string]DatastreamObjectValue:
+		for k, v := range values {
+			if values[k], err = t.transformObjectValue(t.basePath+k, v); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return data, nil
+}
+
+// Linear returns a ValueTransform which converts numeric values to value*factor + offset.
+func Linear(factor, offset float64) ValueTransform {
+	return func(value any) (any, error) {
+		v, ok := toFloat64(value)
+		if !ok {
+			return nil, fmt.Errorf("Value %v is not a number", value)
+		}
+		return v*factor + offset, nil
+	}
+}
+
+// KelvinToCelsius converts temperatures from Kelvin to Celsius degrees.
+func KelvinToCelsius() ValueTransform {
+	return Linear(1, -273.15)
+}
+
+// CelsiusToKelvin converts temperatures from Celsius degrees to Kelvin.
+func CelsiusToKelvin() ValueTransform {
+	return Linear(1, 273.15)
+}
+
+// CelsiusToFahrenheit converts temperatures from Celsius to Fahrenheit degrees.
+func CelsiusToFahrenheit() ValueTransform {
+	return Linear(9.0/5.0, 32)
+}
+
+// FahrenheitToCelsius converts temperatures from Fahrenheit to Celsius degrees.
+func FahrenheitToCelsius() ValueTransform {
+	return Linear(5.0/9.0, -32*5.0/9.0)
+}
+
+// ADCToVolts converts raw readings of an ADC with the given resolution (in bits) to volts,
+// given the ADC reference voltage.
+func ADCToVolts(resolutionBits int, referenceVoltage float64) ValueTransform {
+	return Linear(referenceVoltage/float64(uint64(1)<<resolutionBits-1), 0)
+}
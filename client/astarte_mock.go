@@ -20,30 +20,58 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/astarte-platform/astarte-go/interfaces"
 )
 
 var (
-	testRealmName         = "test"
-	testTokenValue        = "ah yes, the token"
-	testDeviceID          = "fhd0WHcgSjWeVqPGKZv_KA"
-	testDeviceIDs         = []string{testDeviceID, "t1J1uQSBQRi_1F3zIrjyYw", "V_pY-ZrLQzWz4iGjGu-NuQ"}
-	testBrokerUrl         = "mqtt://ah.yes.the.broker"
-	testClientCrt         = "ah yes, the certificate"
-	testCredentialsSecret = "ah yes, the credentials secret"
-	testPublicKey         = "ah yes, the public key"
-	testReplicationFactor = 3
-	testRealmsList        = []string{testRealmName, "ah yes, another realm"}
-	testRealmDetails      = map[string]interface{}{"realm_name": testRealmName, "jwt_public_key_pem": testPublicKey, "replication_factor": testReplicationFactor}
-	testInterfacesList    = []string{"ah.yes.an.Interface", "ah.yes.another.Interface"}
-	testInterfaceName     = "ah.yes.an.Interface"
-	testInterfaceMajor    = 1
-	testInterfaceMajors   = []int{testInterfaceMajor, 2}
-	testInterfaceMinor    = 1
-	testInterfaceMinors   = []int{testInterfaceMinor, 0}
-	testInterface         = `{
+	testRealmName               = "test"
+	testTokenValue              = "ah yes, the token"
+	testDeviceID                = "fhd0WHcgSjWeVqPGKZv_KA"
+	testDeviceIDs               = []string{testDeviceID, "t1J1uQSBQRi_1F3zIrjyYw", "V_pY-ZrLQzWz4iGjGu-NuQ"}
+	testBrokerUrl               = "mqtt://ah.yes.the.broker"
+	testBrokerCACert            = "-----BEGIN CERTIFICATE-----\nah yes, a CA\n-----END CERTIFICATE-----\n"
+	testClientCrt               = "ah yes, the certificate"
+	testCredentialsSecret       = "ah yes, the credentials secret"
+	testPublicKey               = "ah yes, the public key"
+	testReplicationFactor       = 3
+	testRealmsList              = []string{testRealmName, "ah yes, another realm"}
+	testMissingRealmName        = "ah_yes_a_missing_realm"
+	testRealmDetails            = map[string]interface{}{"realm_name": testRealmName, "jwt_public_key_pem": testPublicKey, "replication_factor": testReplicationFactor, "device_registration_limit": testDeviceRegistrationLimit, "datastream_maximum_storage_retention": testMaximumStorageRetention}
+	testDeviceRegistrationLimit = 10
+	testMaximumStorageRetention = 3600
+	testTotalDevices            = 3
+	testConnectedDevices        = 1
+	testOverLimitDeviceID       = "7Y6NpzM_Q9ipYlrsTKIMhg"
+	testRegisteredDeviceID      = "2TBn-jNESuuHamE2Zo1anA"
+	testInhibitedDeviceID       = "olFkumNuZ_J0f_d6-8XCDg"
+	testInterfacesList          = []string{"ah.yes.an.Interface", "ah.yes.another.Interface"}
+	testMissingInterfaceName    = "ah.yes.a.missing.Interface"
+	testDeviceDetails           = map[string]interface{}{"id": testDeviceID, "connected": true, "introspection": map[string]interface{}{
+		testInterfaceName:        map[string]int{"major": testInterfaceMajor, "minor": 0, "exchanged_msgs": 10, "exchanged_bytes": 200},
+		testMissingInterfaceName: map[string]int{"major": 1, "minor": 0, "exchanged_msgs": 1, "exchanged_bytes": 30},
+	}}
+	testDevicesDetails = []map[string]interface{}{testDeviceDetails,
+		{"id": testDeviceIDs[1], "introspection": map[string]interface{}{testInterfaceName: map[string]int{"major": testInterfaceMajor, "minor": 0}}},
+		{"id": testDeviceIDs[2], "introspection": map[string]interface{}{testInterfaceName: map[string]int{"major": 2, "minor": 0}}},
+	}
+	// testGetInterfaceRequests counts the requests to get testInterfaceName
+	testGetInterfaceRequests atomic.Int64
+	testDeviceAlias          = "ah-yes-an-alias"
+	testMissingDeviceAlias   = "ah-yes-a-missing-alias"
+	// testResolveAliasRequests counts the requests to resolve testDeviceAlias
+	testResolveAliasRequests atomic.Int64
+	testInterfaceName        = "ah.yes.an.Interface"
+	testInterfaceMajor       = 1
+	testInterfaceMajors      = []int{testInterfaceMajor, 2}
+	testInterfaceMinor       = 1
+	testInterfaceMinors      = []int{testInterfaceMinor, 0}
+	testInterface            = `{
 		"interface_name": "ah.yes.an.Interface",
 		"version_major": 1,
 		"version_minor": 1,
@@ -97,11 +125,27 @@ var (
 		}
 	 }
 	`
-	testGroupName    = "ah yes, a group"
-	testGroupLinks   = map[string]string{"self": fmt.Sprintf("/v1/%s/groups/%s/devices", testRealmName, url.PathEscape(testGroupName))}
-	testPolicyName   = "ah_yes_a_policy"
-	testPoliciesList = []string{testPolicyName, "ah_yes_another_policy"}
-	testPolicy       = `{
+	testFleetInterfaceName = "ah.yes.a.fleet.Interface"
+	testFleetInterface     = `{
+		"interface_name": "ah.yes.a.fleet.Interface",
+		"version_major": 0,
+		"version_minor": 1,
+		"type": "datastream",
+		"ownership": "device",
+		"mappings": [
+			{
+				"endpoint": "/%{sensor_id}/value",
+				"type": "double",
+				"explicit_timestamp": true
+			}
+		]
+	}`
+	testGroupName          = "ah yes, a group"
+	testGroupLinks         = map[string]string{"self": fmt.Sprintf("/v1/%s/groups/%s/devices", testRealmName, url.PathEscape(testGroupName))}
+	testReceptionTimestamp = time.Date(2024, 5, 6, 7, 8, 9, 123456000, time.UTC)
+	testPolicyName         = "ah_yes_a_policy"
+	testPoliciesList       = []string{testPolicyName, "ah_yes_another_policy"}
+	testPolicy             = `{
 		"name" : "ah_yes_a_policy",
 		"maximum_capacity" : 100,
 		"error_handlers" : [
@@ -111,6 +155,54 @@ var (
 			}
 		]
 	  }`
+	testPropertiesDeviceID            = "0Cr5y-aFTgugNgGHbGCKgQ"
+	testDevicePropertiesInterfaceName = "ah.yes.a.device.properties.Interface"
+	testServerPropertiesInterfaceName = "ah.yes.a.server.properties.Interface"
+	testPropertiesDeviceDetails       = map[string]interface{}{"id": testPropertiesDeviceID, "introspection": map[string]interface{}{
+		testInterfaceName:                 map[string]int{"major": testInterfaceMajor, "minor": 0},
+		testDevicePropertiesInterfaceName: map[string]int{"major": 0, "minor": 1},
+		testServerPropertiesInterfaceName: map[string]int{"major": 1, "minor": 0},
+	}}
+	testDevicePropertiesInterface = `{
+		"interface_name": "ah.yes.a.device.properties.Interface",
+		"version_major": 0,
+		"version_minor": 1,
+		"type": "properties",
+		"ownership": "device",
+		"mappings": [
+			{
+				"endpoint": "/%{sensor_id}/enabled",
+				"type": "boolean"
+			},
+			{
+				"endpoint": "/%{sensor_id}/counter",
+				"type": "longinteger"
+			}
+		]
+	}`
+	testServerPropertiesInterface = `{
+		"interface_name": "ah.yes.a.server.properties.Interface",
+		"version_major": 1,
+		"version_minor": 0,
+		"type": "properties",
+		"ownership": "server",
+		"mappings": [
+			{
+				"endpoint": "/config/name",
+				"type": "string"
+			},
+			{
+				"endpoint": "/config/updated",
+				"type": "datetime"
+			},
+			{
+				"endpoint": "/config/thresholds",
+				"type": "integerarray"
+			}
+		]
+	}`
+	testDeviceProperties = `{"a": {"enabled": true, "counter": "9007199254740993"}, "b": {"enabled": false, "counter": 42}}`
+	testServerProperties = `{"config": {"name": "ah yes, a name", "updated": "2024-05-06T07:08:09.123Z", "thresholds": [1, 2, 3]}}`
 )
 
 func astarteAPIMock(w http.ResponseWriter, req *http.Request) {
@@ -130,6 +222,20 @@ func astarteAPIMock(w http.ResponseWriter, req *http.Request) {
 	switch {
 	// register device
 	case req.URL.Path == fmt.Sprintf("/pairing/v1/%s/agent/devices", testRealmName):
+		body := struct {
+			Data registerDevicePayload `json:"data"`
+		}{}
+		_ = json.NewDecoder(req.Body).Decode(&body)
+		if body.Data.HwID == testOverLimitDeviceID {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			reply = map[string]interface{}{"errors": map[string]string{"detail": deviceLimitReachedDetail}}
+			break
+		}
+		if body.Data.HwID == testRegisteredDeviceID {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			reply = map[string]interface{}{"errors": map[string]string{"detail": "Device already registered"}}
+			break
+		}
 		credentialsSecret := map[string]string{"credentials_secret": testCredentialsSecret}
 		reply = map[string]interface{}{"data": credentialsSecret}
 		w.WriteHeader(http.StatusCreated)
@@ -142,10 +248,13 @@ func astarteAPIMock(w http.ResponseWriter, req *http.Request) {
 		clientCrt := map[string]string{"client_crt": testClientCrt}
 		reply = map[string]interface{}{"data": clientCrt}
 		w.WriteHeader(http.StatusCreated)
+	case req.URL.Path == fmt.Sprintf("/pairing/v1/%s/devices/%s/protocols/astarte_mqtt_v1/credentials", testRealmName, testInhibitedDeviceID):
+		reply = map[string]interface{}{"errors": map[string]string{"detail": "Credentials request not allowed"}}
+		w.WriteHeader(http.StatusForbidden)
 	// get info
 	case req.URL.Path == fmt.Sprintf("/pairing/v1/%s/devices/%s", testRealmName, testDeviceID):
-		brokerUrl := map[string]string{"broker_url": testBrokerUrl}
-		reply = map[string]interface{}{"data": brokerUrl}
+		protocols := map[string]interface{}{MQTTv1Protocol: map[string]string{"broker_url": testBrokerUrl, "ca_cert": testBrokerCACert}}
+		reply = map[string]interface{}{"data": map[string]interface{}{"version": "1.1.1", "status": "confirmed", "protocols": protocols}}
 	case req.URL.Path == fmt.Sprintf("/housekeeping/v1/realms"):
 		if req.Method == http.MethodGet {
 			// list realms
@@ -157,7 +266,30 @@ func astarteAPIMock(w http.ResponseWriter, req *http.Request) {
 		}
 	// realm details
 	case req.URL.Path == fmt.Sprintf("/housekeeping/v1/realms/%s", testRealmName):
-		reply = map[string]interface{}{"data": testRealmDetails}
+		if req.Method == http.MethodPatch {
+			// update realm, applying the merge patch to the realm details
+			patch := astarteRequestBody{}
+			_ = json.NewDecoder(req.Body).Decode(&patch)
+			details := map[string]interface{}{}
+			for k, v := range testRealmDetails {
+				details[k] = v
+			}
+			for k, v := range patch.Data.(map[string]interface{}) {
+				if v == nil {
+					delete(details, k)
+				} else {
+					details[k] = v
+				}
+			}
+			reply = map[string]interface{}{"data": details}
+		} else {
+			reply = map[string]interface{}{"data": testRealmDetails}
+		}
+	case req.URL.Path == fmt.Sprintf("/housekeeping/v1/realms/%s", testRealmsList[1]):
+		reply = map[string]interface{}{"data": map[string]interface{}{"realm_name": testRealmsList[1], "jwt_public_key_pem": testPublicKey}}
+	case req.URL.Path == fmt.Sprintf("/housekeeping/v1/realms/%s", testMissingRealmName):
+		w.WriteHeader(http.StatusNotFound)
+		reply = map[string]interface{}{"errors": map[string]string{"detail": "Realm not found"}}
 	case req.URL.Path == fmt.Sprintf("/realmmanagement/v1/%s/interfaces", testRealmName):
 		if req.Method == http.MethodGet {
 			// interface list
@@ -175,6 +307,7 @@ func astarteAPIMock(w http.ResponseWriter, req *http.Request) {
 	case req.URL.Path == fmt.Sprintf("/realmmanagement/v1/%s/interfaces/%s/%v", testRealmName, testInterfaceName, testInterfaceMajor):
 		if req.Method == http.MethodGet {
 			// get interface
+			testGetInterfaceRequests.Add(1)
 			iface, _ := interfaces.ParseInterface([]byte(testInterface))
 			reply = map[string]interface{}{"data": iface}
 		} else if req.Method == http.MethodDelete {
@@ -208,6 +341,13 @@ func astarteAPIMock(w http.ResponseWriter, req *http.Request) {
 			reply = map[string]interface{}{"data": ""}
 			w.WriteHeader(http.StatusNoContent)
 		}
+	case req.URL.Path == fmt.Sprintf("/realmmanagement/v1/%s/triggers/%s", testRealmName, testTriggersList[1]) && req.Method == http.MethodGet:
+		// get a trigger with a delivery policy
+		trigger := map[string]any{}
+		_ = json.Unmarshal([]byte(testTrigger), &trigger)
+		trigger["name"] = testTriggersList[1]
+		trigger["policy"] = testPolicyName
+		reply = map[string]interface{}{"data": trigger}
 	case req.URL.Path == fmt.Sprintf("/realmmanagement/v1/%s/policies", testRealmName):
 		if req.Method == http.MethodGet {
 			// policy list
@@ -230,6 +370,40 @@ func astarteAPIMock(w http.ResponseWriter, req *http.Request) {
 			reply = map[string]interface{}{"data": ""}
 			w.WriteHeader(http.StatusNoContent)
 		}
+	case req.URL.Path == fmt.Sprintf("/realmmanagement/v1/%s/interfaces/%s/1", testRealmName, testMissingInterfaceName):
+		w.WriteHeader(http.StatusNotFound)
+		reply = map[string]interface{}{"errors": map[string]string{"detail": "Interface not found"}}
+	case req.URL.Path == fmt.Sprintf("/appengine/v1/%s/devices/%s", testRealmName, testDeviceID):
+		// device details
+		reply = map[string]interface{}{"data": testDeviceDetails}
+	case req.URL.Path == fmt.Sprintf("/appengine/v1/%s/devices/%s", testRealmName, testPropertiesDeviceID):
+		reply = map[string]interface{}{"data": testPropertiesDeviceDetails}
+	case req.URL.Path == fmt.Sprintf("/realmmanagement/v1/%s/interfaces/%s/0", testRealmName, testDevicePropertiesInterfaceName):
+		iface, _ := interfaces.ParseInterface([]byte(testDevicePropertiesInterface))
+		reply = map[string]interface{}{"data": iface}
+	case req.URL.Path == fmt.Sprintf("/realmmanagement/v1/%s/interfaces/%s/1", testRealmName, testServerPropertiesInterfaceName):
+		iface, _ := interfaces.ParseInterface([]byte(testServerPropertiesInterface))
+		reply = map[string]interface{}{"data": iface}
+	case req.URL.Path == fmt.Sprintf("/appengine/v1/%s/devices/%s/interfaces/%s", testRealmName, testPropertiesDeviceID, testDevicePropertiesInterfaceName):
+		reply = map[string]interface{}{"data": json.RawMessage(testDeviceProperties)}
+	case req.URL.Path == fmt.Sprintf("/appengine/v1/%s/devices/%s/interfaces/%s", testRealmName, testPropertiesDeviceID, testServerPropertiesInterfaceName):
+		reply = map[string]interface{}{"data": json.RawMessage(testServerProperties)}
+	case req.URL.Path == fmt.Sprintf("/appengine/v1/%s/devices-by-alias/%s", testRealmName, testDeviceAlias):
+		testResolveAliasRequests.Add(1)
+		reply = map[string]interface{}{"data": testDeviceDetails}
+	case req.URL.Path == fmt.Sprintf("/appengine/v1/%s/devices-by-alias/%s", testRealmName, testDeviceIDs[1]):
+		// an alias which is a valid Device ID too
+		reply = map[string]interface{}{"data": testDeviceDetails}
+	case req.URL.Path == fmt.Sprintf("/appengine/v1/%s/devices/%s", testRealmName, testDeviceIDs[1]):
+		reply = map[string]interface{}{"data": testDevicesDetails[1]}
+	case req.URL.Path == fmt.Sprintf("/appengine/v1/%s/devices-by-alias/%s", testRealmName, testMissingDeviceAlias),
+		req.URL.Path == fmt.Sprintf("/appengine/v1/%s/devices-by-alias/%s", testRealmName, testDeviceID):
+		w.WriteHeader(http.StatusNotFound)
+		reply = map[string]interface{}{"errors": map[string]string{"detail": "Device not found"}}
+	case req.URL.Path == fmt.Sprintf("/appengine/v1/%s/stats/devices", testRealmName):
+		reply = map[string]interface{}{"data": map[string]int{"total_devices": testTotalDevices, "connected_devices": testConnectedDevices}}
+	case req.URL.Path == fmt.Sprintf("/appengine/v1/%s/devices", testRealmName) && req.URL.Query().Get("details") == "true":
+		reply = map[string]interface{}{"data": testDevicesDetails, "links": testDevicesLinks}
 	case req.URL.Path == fmt.Sprintf("/appengine/v1/%s/devices", testRealmName):
 		reply = map[string]interface{}{"data": testDeviceIDs, "links": testDevicesLinks}
 	case req.URL.Path == fmt.Sprintf("/appengine/v1/%s/%s/interfaces/%s", testRealmName, testDeviceID, testInterface):
@@ -238,11 +412,20 @@ func astarteAPIMock(w http.ResponseWriter, req *http.Request) {
 		_ = json.Unmarshal([]byte(testIndividualDatastreamSnapshot), &data)
 		reply = map[string]interface{}{"data": data}
 	case req.URL.Path == fmt.Sprintf("/appengine/v1/%s/devices/%s/interfaces/%s/an/endpoint", testRealmName, testDeviceID, testServerOwnedInterfaceName):
-		// receive data(stream)
-		reply = map[string]interface{}{"data": ""}
+		// receive data(stream), echoing the stored value
+		sent := astarteRequestBody{}
+		_ = json.NewDecoder(req.Body).Decode(&sent)
+		timestamp := testReceptionTimestamp
+		if sent.Timestamp != nil {
+			timestamp = *sent.Timestamp
+		}
+		reply = map[string]interface{}{"data": map[string]any{"value": sent.Data, "timestamp": timestamp, "reception_timestamp": testReceptionTimestamp}}
 	case req.URL.Path == fmt.Sprintf("/appengine/v1/%s/devices/%s/interfaces/%s/other/endpoint", testRealmName, testDeviceID, testServerOwnedInterfaceName):
 		// receive data(stream)
 		reply = map[string]interface{}{"data": ""}
+	case req.URL.Path == fmt.Sprintf("/appengine/v1/%s/devices/%s/interfaces/%s/rejected/endpoint", testRealmName, testDeviceID, testServerOwnedPropertyInterfaceName):
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		reply = map[string]interface{}{"errors": map[string]string{"detail": "Unexpected value type"}}
 	case req.URL.Path == fmt.Sprintf("/appengine/v1/%s/devices/%s/interfaces/%s/an/endpoint", testRealmName, testDeviceID, testServerOwnedPropertyInterfaceName):
 		if req.Method == http.MethodPut {
 			// set property
@@ -252,13 +435,20 @@ func astarteAPIMock(w http.ResponseWriter, req *http.Request) {
 			reply = map[string]interface{}{"data": ""}
 			w.WriteHeader(http.StatusNoContent)
 		}
+	case strings.HasPrefix(req.URL.Path, fmt.Sprintf("/appengine/v1/%s/devices/", testRealmName)) &&
+		strings.HasSuffix(req.URL.Path, fmt.Sprintf("/interfaces/%s/a/value", testFleetInterfaceName)):
+		// datastream values for fleet queries: the i-th test device has values i+1, i+2, i+3
+		reply = map[string]interface{}{"data": testFleetDatastream(req)}
 	case req.URL.Path == fmt.Sprintf("/appengine/v1/%s/groups", testRealmName):
 		// create group
 		payload := DevicesAndGroup{Devices: testDeviceIDs, GroupName: testGroupName}
 		reply = map[string]interface{}{"data": payload}
 		w.WriteHeader(http.StatusCreated)
-	case req.URL.Path == fmt.Sprintf("/appengine/v1/%s/groups/%s/devices", testRealmName, url.PathEscape(testGroupName)):
-		if req.Method == http.MethodGet {
+	case req.URL.Path == fmt.Sprintf("/appengine/v1/%s/groups/%s/devices", testRealmName, testGroupName):
+		if req.Method == http.MethodGet && req.URL.Query().Get("details") == "true" {
+			// list devices in a group, with their details
+			reply = map[string]interface{}{"data": testDevicesDetails, "links": testGroupLinks}
+		} else if req.Method == http.MethodGet {
 			// list devices in a group
 			reply = map[string]interface{}{"data": testDeviceIDs, "links": testGroupLinks}
 		} else if req.Method == http.MethodPost {
@@ -266,7 +456,7 @@ func astarteAPIMock(w http.ResponseWriter, req *http.Request) {
 			reply = map[string]interface{}{"data": ""}
 			w.WriteHeader(http.StatusCreated)
 		}
-	case req.URL.Path == fmt.Sprintf("/appengine/v1/%s/groups/%s/devices/%s", testRealmName, url.PathEscape(testGroupName), testDeviceID):
+	case req.URL.Path == fmt.Sprintf("/appengine/v1/%s/groups/%s/devices/%s", testRealmName, testGroupName, testDeviceID):
 		// remove device from group
 		reply = map[string]interface{}{"data": ""}
 		w.WriteHeader(http.StatusNoContent)
@@ -274,6 +464,34 @@ func astarteAPIMock(w http.ResponseWriter, req *http.Request) {
 	json.NewEncoder(w).Encode(reply)
 }
 
+func testFleetDatastream(req *http.Request) []map[string]interface{} {
+	values := []map[string]interface{}{}
+	if req.URL.Query().Get("since_after") != "" {
+		return values
+	}
+	for i, deviceID := range testDeviceIDs {
+		if !strings.Contains(req.URL.Path, deviceID) {
+			continue
+		}
+		for j := 1; j <= 3; j++ {
+			values = append(values, map[string]interface{}{
+				"value":     float64(i + j),
+				"timestamp": time.Date(2024, 1, 1, 0, j, 0, 0, time.UTC),
+			})
+		}
+	}
+	if req.URL.Query().Get("since") == "" {
+		// descending order: newest first
+		for i, j := 0, len(values)-1; i < j; i, j = i+1, j-1 {
+			values[i], values[j] = values[j], values[i]
+		}
+	}
+	if limit, err := strconv.Atoi(req.URL.Query().Get("limit")); err == nil && limit < len(values) {
+		values = values[:limit]
+	}
+	return values
+}
+
 func getTestContext(t *testing.T) (*Client, *httptest.Server) {
 	// Start a local HTTP server
 	server := httptest.NewServer(http.HandlerFunc(astarteAPIMock))
@@ -289,3 +507,20 @@ func getTestContext(t *testing.T) (*Client, *httptest.Server) {
 
 	return client, server
 }
+
+// getTestServerContext works like getTestContext, but serves requests with handler rather than with the Astarte API
+// mock, and creates the client with opts too. The server is closed when the test ends.
+func getTestServerContext(t *testing.T, handler http.HandlerFunc, opts ...Option) (*Client, *httptest.Server) {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return newTestClient(t, server, opts...), server
+}
+
+// newTestClient creates a client of server, authenticated with the test JWT and with opts.
+func newTestClient(t *testing.T, server *httptest.Server, opts ...Option) *Client {
+	client, err := New(append([]Option{WithBaseURL(server.URL), WithJWT(testTokenValue), WithHTTPClient(server.Client())}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
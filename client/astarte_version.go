@@ -0,0 +1,82 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// AstarteVersion is a version of Astarte, used to gate the features the client uses, see WithAstarteVersion.
+type AstarteVersion struct {
+	Major int
+	Minor int
+}
+
+// ParseAstarteVersion parses an Astarte version such as "1.1", "1.2.0" or "v1.0.4". Only the major and minor
+// versions are kept, and any patch version or pre-release suffix is ignored.
+func ParseAstarteVersion(version string) (AstarteVersion, error) {
+	parts := strings.SplitN(strings.TrimPrefix(strings.TrimSpace(version), "v"), ".", 3)
+	if len(parts) < 2 {
+		return AstarteVersion{}, fmt.Errorf("%w: %q", ErrInvalidAstarteVersion, version)
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil || major < 1 {
+		return AstarteVersion{}, fmt.Errorf("%w: %q", ErrInvalidAstarteVersion, version)
+	}
+	minor, err := strconv.Atoi(strings.SplitN(parts[1], "-", 2)[0])
+	if err != nil || minor < 0 {
+		return AstarteVersion{}, fmt.Errorf("%w: %q", ErrInvalidAstarteVersion, version)
+	}
+	return AstarteVersion{Major: major, Minor: minor}, nil
+}
+
+// AtLeast returns whether v is major.minor or a later version. The zero AstarteVersion stands for the latest
+// Astarte version, hence it is later than any other.
+func (v AstarteVersion) AtLeast(major, minor int) bool {
+	if v == (AstarteVersion{}) {
+		return true
+	}
+	return v.Major > major || (v.Major == major && v.Minor >= minor)
+}
+
+func (v AstarteVersion) String() string {
+	if v == (AstarteVersion{}) {
+		return "latest"
+	}
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+// AstarteVersion returns the version of Astarte the client targets, see WithAstarteVersion.
+func (c *Client) AstarteVersion() AstarteVersion {
+	return c.astarteVersion
+}
+
+// requireAstarteVersion returns ErrUnsupportedAstarteVersion if the client targets a version of Astarte
+// earlier than the one which introduced feature.
+func (c *Client) requireAstarteVersion(feature string, major, minor int) error {
+	if c.astarteVersion.AtLeast(major, minor) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s requires Astarte %d.%d, the client targets Astarte %s", ErrUnsupportedAstarteVersion,
+		feature, major, minor, c.astarteVersion)
+}
+
+// supportsSynchronousOperations returns whether Realm Management can be asked to process changes to interfaces
+// synchronously with the async_operation parameter, which was introduced in Astarte 1.1.
+func (c *Client) supportsSynchronousOperations() bool {
+	return c.astarteVersion.AtLeast(1, 1)
+}
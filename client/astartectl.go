@@ -0,0 +1,81 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"strings"
+
+	"github.com/astarte-platform/astarte-go/config"
+)
+
+// NewFromAstartectlContext returns a Client configured from the astartectl context called name, as found in the
+// astartectl configuration directory (see config.DefaultDir). If name is empty, the current context is used.
+// The Client uses the URLs of the cluster of the context and authenticates with the private key of its realm,
+// or with the Housekeeping key of the cluster if the realm has none. The realm of the context is available
+// through config.LoadContext. options are applied after the ones derived from the context, hence they can
+// override its URLs, or provide e.g. WithJWT if the context holds no private key.
+func NewFromAstartectlContext(name string, options ...Option) (*Client, error) {
+	dir, err := config.DefaultDir()
+	if err != nil {
+		return nil, err
+	}
+	context, err := config.LoadContext(dir, name)
+	if err != nil {
+		return nil, err
+	}
+	contextOptions, err := astartectlContextOptions(context)
+	if err != nil {
+		return nil, err
+	}
+	return New(append(contextOptions, options...)...)
+}
+
+func astartectlContextOptions(context config.Context) ([]Option, error) {
+	options := []Option{}
+	individualURLs := context.Cluster.IndividualURLs
+	if len(individualURLs) == 0 {
+		if context.Cluster.URL != "" {
+			options = append(options, WithBaseURL(context.Cluster.URL))
+		}
+	} else {
+		// Services without an individual URL are reached through the cluster URL, as WithBaseURL would do
+		serviceURL := func(service, path string) string {
+			if url, ok := individualURLs[service]; ok {
+				return url
+			}
+			return strings.TrimSuffix(context.Cluster.URL, "/") + path
+		}
+		options = append(options,
+			WithAppEngineURL(serviceURL("appengine", "/appengine")),
+			WithHousekeepingURL(serviceURL("housekeeping", "/housekeeping")),
+			WithPairingURL(serviceURL("pairing", "/pairing")),
+			WithRealmManagementURL(serviceURL("realm-management", "/realmmanagement")),
+		)
+	}
+
+	key, err := context.Realm.PrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		if key, err = context.Cluster.HousekeepingKey(); err != nil {
+			return nil, err
+		}
+	}
+	if key != nil {
+		options = append(options, WithPrivateKey(key))
+	}
+	return options, nil
+}
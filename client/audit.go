@@ -0,0 +1,104 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// AuditRecord describes a mutating request (i.e. any request but GET, HEAD and OPTIONS) sent by a client, see
+// WithAuditHandler. Requests retried by the client, e.g. after a token refresh, are recorded once.
+type AuditRecord struct {
+	Time time.Time `json:"time"`
+	// Actor is the actor set with WithAuditHandler, e.g. the user of an admin tool.
+	Actor   string `json:"actor,omitempty"`
+	Service string `json:"service"`
+	Method  string `json:"method"`
+	URL     string `json:"url"`
+	// Endpoint is the path of the request within the service, with identifiers replaced by "*", as
+	// reported to the MetricsRecorder of the client.
+	Endpoint string `json:"endpoint"`
+	// PayloadSHA256 is the hex encoded SHA-256 hash of the uncompressed request body, empty if it has none.
+	PayloadSHA256 string `json:"payload_sha256,omitempty"`
+	// StatusCode is 0 if no response was received.
+	StatusCode int    `json:"status_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// The WithAuditHandler function allows to specify a function which is called after each mutating request sent by
+// the client, with an AuditRecord naming actor as the author of the request. Payloads are recorded only as hashes,
+// so that audit logs do not leak device data. The handler is called concurrently if requests are run concurrently.
+// See JSONLinesAuditHandler to write the records to a file.
+func WithAuditHandler(actor string, handler func(AuditRecord)) Option {
+	return func(c *Client) error {
+		c.auditActor = actor
+		c.auditHandler = handler
+		return nil
+	}
+}
+
+// JSONLinesAuditHandler returns an audit handler writing each AuditRecord to w as a line of JSON. Writes are
+// serialized, so that records of concurrent requests do not interleave. Write errors are ignored.
+func JSONLinesAuditHandler(w io.Writer) func(AuditRecord) {
+	var mutex sync.Mutex
+	encoder := json.NewEncoder(w)
+	return func(record AuditRecord) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		_ = encoder.Encode(record)
+	}
+}
+
+// audit reports req to the audit handler of c, if any and if req is mutating.
+func (c *Client) audit(req *http.Request, res *http.Response, err error) {
+	if c.auditHandler == nil {
+		return
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return
+	}
+	service, endpoint := c.endpointOf(req.URL)
+	record := AuditRecord{
+		Time:     c.clock(),
+		Actor:    c.auditActor,
+		Service:  service.String(),
+		Method:   req.Method,
+		URL:      req.URL.String(),
+		Endpoint: endpoint,
+	}
+	if req.GetBody != nil {
+		if body, bodyErr := req.GetBody(); bodyErr == nil {
+			hash := sha256.New()
+			if n, _ := io.Copy(hash, body); n > 0 {
+				record.PayloadSHA256 = hex.EncodeToString(hash.Sum(nil))
+			}
+			body.Close()
+		}
+	}
+	if res != nil {
+		record.StatusCode = res.StatusCode
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	c.auditHandler(record)
+}
@@ -15,28 +15,106 @@
 package client
 
 import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
 	"net/http"
 	"net/url"
 	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/astarte-platform/astarte-go/astarteservices"
 	"github.com/astarte-platform/astarte-go/auth"
+	"github.com/astarte-platform/astarte-go/deviceid"
 )
 
 const defaultJWTExpiry = 300
 
 type Client struct {
+	*clientState
+	credentials
+	// run holds the overrides of the request being run, see withRunOptions
+	run runOptions
+}
+
+// clientState holds the configuration and the caches of a Client, which are shared by the clients
+// returned by withRunOptions and by RealmClient.WithJWT and RealmClient.WithPrivateKey.
+type clientState struct {
 	baseURL            *url.URL
 	appEngineURL       *url.URL
 	housekeepingURL    *url.URL
 	pairingURL         *url.URL
 	realmManagementURL *url.URL
 	userAgent          string
+	userAgentSuffix    string
 	httpClient         *http.Client
-	token              string
-	privateKey         []byte
 	expiry             int
+	tokenOptions       []auth.TokenOption
+	validationLevel    ValidationLevel
+	clock              func() time.Time
+	randomSource       io.Reader
+	interfaceCache     sync.Map
+	aliasCache         sync.Map
+	// retentionLimitsCache holds the retention limits of each realm, see checkRetention
+	retentionLimitsCache sync.Map
+	// lastTimestamps holds the latest explicit timestamp sent on each device path, see StrictValidation
+	lastTimestamps sync.Map
+	// tolerantStatusCodes makes requests accept any 2xx status code, see WithTolerantStatusCodes
+	tolerantStatusCodes      bool
+	statusCodeWarningHandler func(StatusCodeWarning)
+	attributeSchema          *AttributeSchema
+	valueTransforms          []valueTransformRule
+	tokenRefreshHandler      func(TokenRefresh)
+	brokerURLValidator       BrokerURLValidator
+	requestCompression       RequestCompression
+	compressionThreshold     int
+	// compressionRejected disables request compression once the server rejected it, see sendCompressed
+	compressionRejected     atomic.Bool
+	serviceHTTPClients      map[astarteservices.AstarteService]*http.Client
+	serviceConnectionLimits map[astarteservices.AstarteService]int
+	timestampPrecision      TimestampPrecision
+	// strictDeviceIdentifiers disables the autodiscovery of identifiers which could be both a Device ID and an alias
+	strictDeviceIdentifiers bool
+	// astarteVersion is the zero AstarteVersion if the client targets the latest Astarte version
+	astarteVersion AstarteVersion
+	metrics        MetricsRecorder
+	// strictRetention and retentionWarningHandler enable the retention check of interfaces, see checkRetention
+	strictRetention         bool
+	retentionWarningHandler func(RetentionWarning)
+	// maxPageSize is the largest page size paginators request, DefaultMaxPageSize if 0, see WithMaxPageSize
+	maxPageSize int
+	// auditActor and auditHandler record mutating requests, see WithAuditHandler
+	auditActor   string
+	auditHandler func(AuditRecord)
+}
+
+// credentials authenticate the requests of a Client: either token or privateKey is set.
+type credentials struct {
+	token      string
+	privateKey []byte
+}
+
+// runOptions override the configuration of a Client while running a single request, see Tolerant,
+// Timeout, Compressed and Cancelable.
+type runOptions struct {
+	tolerantStatusCodes bool
+	// ctx is the context of the request when not nil
+	ctx context.Context
+	// timeout overrides the timeout of the HTTP client when positive
+	timeout time.Duration
+	// compression overrides the request compression of the client when not nil
+	compression *RequestCompression
+}
+
+// withRunOptions returns a Client sharing the state and the credentials of c, whose run options are
+// the ones of c modified by apply. It is used to run a single request with different options.
+func (c *Client) withRunOptions(apply func(*runOptions)) *Client {
+	run := c.run
+	apply(&run)
+	return &Client{clientState: c.clientState, credentials: c.credentials, run: run}
 }
 
 type Option = func(c *Client) error
@@ -51,26 +129,35 @@ type privateKeyProvider interface {
 // If no other options are specified, the following is assumed:
 // - standard Astarte URL hierarchy
 // - standard HTTP client
-// - "astarte-go" as user agent
+// - "astarte-go/<module version>" as user agent
+// If options are invalid, New reports all the errors at once, joined with errors.Join: use errors.Is to check
+// for a specific error, e.g. ErrConflictingUrls.
 func New(options ...Option) (*Client, error) {
 	// We start with a client with bare zero-valued fields
-	c := &Client{}
+	c := &Client{clientState: &clientState{}}
 
 	// Then we modify it according to user-provided options...
+	errs := []error{}
 	for _, f := range options {
-		err := f(c)
-		if err != nil {
-			return c, err
+		if err := f(c); err != nil {
+			errs = append(errs, err)
 		}
 	}
 
-	// ... and check if the result is valid
-	if err := validate(c); err != nil {
-		return c, err
+	// ... and check if the result is valid, reporting all the errors at once
+	errs = append(errs, validate(c)...)
+	if len(errs) == 1 {
+		return c, errs[0]
+	} else if len(errs) > 1 {
+		return c, errors.Join(errs...)
 	}
 
 	// Finally, we add just a sprinkle of defaults and a new Client is born!
-	return setDefaults(c), nil
+	c = setDefaults(c)
+	if err := setupServiceHTTPClients(c); err != nil {
+		return c, err
+	}
+	return c, nil
 }
 
 // The WithAppEngineURL function allows to specify an
@@ -171,6 +258,43 @@ func WithUserAgent(userAgent string) Option {
 	}
 }
 
+// The WithUserAgentSuffix function allows to append a component (e.g. "mytool/1.2") to the User Agent
+// of the client, so that API traffic can be attributed to the tool using the client.
+func WithUserAgentSuffix(suffix string) Option {
+	return func(c *Client) error {
+		c.userAgentSuffix = suffix
+		return nil
+	}
+}
+
+// The WithBrokerURLValidator function allows to validate the broker URLs returned by Pairing, e.g. against
+// the expected hosts with AllowBrokerHosts. Parsing a device information response fails with ErrInvalidBrokerURL
+// if a broker URL is rejected by the validator.
+func WithBrokerURLValidator(validator BrokerURLValidator) Option {
+	return func(c *Client) error {
+		c.brokerURLValidator = validator
+		return nil
+	}
+}
+
+// The WithRequestCompression function allows to compress the bodies of the requests built by the client, e.g.
+// when installing large interfaces or sending bulk data. Only bodies of at least thresholdBytes bytes are compressed.
+// If the server rejects a compressed body with 415 Unsupported Media Type, the request is sent again uncompressed,
+// and compression is disabled for the client. To set the compression of a single request, see Compressed.
+func WithRequestCompression(compression RequestCompression, thresholdBytes int) Option {
+	return func(c *Client) error {
+		if err := compression.IsValid(); err != nil {
+			return err
+		}
+		if thresholdBytes < 0 {
+			return ErrInvalidRequestCompression
+		}
+		c.requestCompression = compression
+		c.compressionThreshold = thresholdBytes
+		return nil
+	}
+}
+
 // The WithPrivateKey function allows to specify a realm private key,
 // used internally to generate a valid JWT token to all Astarte APIs with 5 minutes expiry.
 // The client will use that token to interact with Astarte.
@@ -205,6 +329,181 @@ func WithExpiry(expirySeconds int) Option {
 	}
 }
 
+// The WithTokenOptions function allows to customize the JWT tokens generated from the private key set with
+// WithPrivateKey, e.g. to set the issuer or audience expected by an API gateway in front of Astarte.
+// Tokens are issued at the time of the clock of the client regardless of auth.WithIssuedAt.
+func WithTokenOptions(opts ...auth.TokenOption) Option {
+	return func(c *Client) error {
+		c.tokenOptions = append(c.tokenOptions, opts...)
+		return nil
+	}
+}
+
+// The WithTimestampPrecision function allows to specify the precision of the timestamps sent to Astarte, in the
+// payloads of datastreams and properties, as explicit timestamps and as time windows of datastream queries.
+// Timestamps are always sent in UTC. If not specified, NanosecondPrecision is used.
+func WithTimestampPrecision(precision TimestampPrecision) Option {
+	return func(c *Client) error {
+		if err := precision.IsValid(); err != nil {
+			return err
+		}
+		c.timestampPrecision = precision
+		return nil
+	}
+}
+
+// The WithStrictDeviceIdentifiers function allows to disable the autodiscovery of device identifiers which could be
+// both a Device ID and an alias: requests built with AutodiscoverDeviceIdentifier for an identifier which is a valid
+// Device ID fail with ErrAmbiguousIdentifier, and the caller must pass either AstarteDeviceID or AstarteDeviceAlias.
+// Identifiers which are not valid Device IDs are still autodiscovered as aliases. See also ResolveDevice.
+func WithStrictDeviceIdentifiers() Option {
+	return func(c *Client) error {
+		c.strictDeviceIdentifiers = true
+		return nil
+	}
+}
+
+// The WithAstarteVersion function allows to specify the version of Astarte the client talks to, e.g. "1.1" or
+// "v1.0.4". Only the major and minor versions are considered. The version only gates features: building requests
+// for operations which the version does not support fails with ErrUnsupportedAstarteVersion, and interfaces are
+// installed and updated synchronously only on Astarte 1.1 and later. The endpoints the client uses have the same
+// URLs and payloads in Astarte 1.0, 1.1 and 1.2, and differ only in whether they are available and in the
+// async_operation parameter: hence the version does not select URLs, payloads or expected status codes, and requests
+// accept the status codes documented for their endpoint across Astarte versions. If not specified, the latest Astarte version is assumed. Clusters running
+// different Astarte versions can be addressed with a client for each of them.
+func WithAstarteVersion(version string) Option {
+	return func(c *Client) error {
+		astarteVersion, err := ParseAstarteVersion(version)
+		if err != nil {
+			return err
+		}
+		c.astarteVersion = astarteVersion
+		return nil
+	}
+}
+
+// The WithValidationLevel function allows to specify how thoroughly SendData validates payloads
+// on the client side before building a request. If not specified, BasicValidation is used.
+func WithValidationLevel(level ValidationLevel) Option {
+	return func(c *Client) error {
+		if level < BasicValidation || level > StrictValidation {
+			return ErrInvalidValidationLevel
+		}
+		c.validationLevel = level
+		return nil
+	}
+}
+
+// The WithClock function allows to specify the function used by the client to get the current time,
+// e.g. when issuing JWT tokens or computing datastream time windows. If not specified, time.Now is used.
+// This is mostly useful to freeze time in tests.
+func WithClock(clock func() time.Time) Option {
+	return func(c *Client) error {
+		c.clock = clock
+		return nil
+	}
+}
+
+// The WithRandomSource function allows to specify the source of randomness used by the client,
+// e.g. when generating random device IDs. If not specified, crypto/rand.Reader is used.
+// This is mostly useful to get reproducible results in tests.
+func WithRandomSource(randomSource io.Reader) Option {
+	return func(c *Client) error {
+		c.randomSource = randomSource
+		return nil
+	}
+}
+
+// The WithTolerantStatusCodes function makes all requests built by the client accept any 2xx
+// status code, and not only the ones documented for each endpoint, since some of them vary among
+// Astarte versions. When an undocumented status code is accepted, a StatusCodeWarning is passed
+// to the handler set with WithStatusCodeWarningHandler, if any.
+// To make a single request tolerant, see Tolerant.
+func WithTolerantStatusCodes() Option {
+	return func(c *Client) error {
+		c.tolerantStatusCodes = true
+		return nil
+	}
+}
+
+// The WithStatusCodeWarningHandler function allows to specify a function which is called
+// whenever a tolerant request accepts an undocumented 2xx status code.
+// The handler might be called concurrently, if requests are run concurrently.
+func WithStatusCodeWarningHandler(handler func(StatusCodeWarning)) Option {
+	return func(c *Client) error {
+		c.statusCodeWarningHandler = handler
+		return nil
+	}
+}
+
+// The WithTokenRefreshHandler function allows to specify a function which is called whenever
+// a request rejected with 401 Unauthorized is retried with a JWT freshly generated from the private key.
+// The handler might be called concurrently, if requests are run concurrently.
+func WithTokenRefreshHandler(handler func(TokenRefresh)) Option {
+	return func(c *Client) error {
+		c.tokenRefreshHandler = handler
+		return nil
+	}
+}
+
+// The WithStrictRetention function makes InstallInterface and UpdateInterface fail if the retention of any mapping of
+// the interface is not valid according to interfaces.ValidateRetention, e.g. because its database_retention_ttl
+// exceeds the maximum datastream storage retention of the realm, which Astarte would silently enforce instead.
+// The check is performed when the request is run, before sending it. The limits of the realm are discovered with
+// GetInterfaceRetentionLimits the first time they are needed and then cached, see ClearRetentionLimitsCache, hence
+// the client must be authorized to access the Housekeeping API: if the limits cannot be fetched, running the
+// request fails without sending it.
+func WithStrictRetention() Option {
+	return func(c *Client) error {
+		c.strictRetention = true
+		return nil
+	}
+}
+
+// The WithRetentionWarningHandler function allows to specify a function which is called whenever a request built by
+// InstallInterface or UpdateInterface is run for an interface whose retention is not valid, as WithStrictRetention
+// does, without failing. If the limits of the realm cannot be fetched, the limits enforced by Astarte regardless of
+// the realm are used. The handler might be called concurrently, if requests are run concurrently.
+func WithRetentionWarningHandler(handler func(RetentionWarning)) Option {
+	return func(c *Client) error {
+		c.retentionWarningHandler = handler
+		return nil
+	}
+}
+
+// The WithMaxPageSize function allows to specify the largest number of items paginators request in a page,
+// i.e. the largest limit Astarte accepts, which defaults to DefaultMaxPageSize. Larger page sizes passed to
+// paginators are clamped to it: otherwise, Astarte would silently return fewer items than requested, which
+// paginators might mistake for the last page.
+func WithMaxPageSize(maxPageSize int) Option {
+	return func(c *Client) error {
+		if maxPageSize <= 0 {
+			return ErrInvalidMaxPageSize
+		}
+		c.maxPageSize = maxPageSize
+		return nil
+	}
+}
+
+// The WithAttributeSchema function allows to specify the AttributeSchema Device attributes
+// are checked against before building requests which set them, see ValidateDeviceAttributes.
+func WithAttributeSchema(schema AttributeSchema) Option {
+	return func(c *Client) error {
+		c.attributeSchema = &schema
+		return nil
+	}
+}
+
+// The WithValueTransform function allows to register a ValueTransform applied to datastream values
+// of interfaceName whose path matches endpoint when parsing datastream responses. endpoint may be
+// parametric, e.g. "/%{sensor_id}/temperature". Transforms are applied in registration order.
+func WithValueTransform(interfaceName, endpoint string, transform ValueTransform) Option {
+	return func(c *Client) error {
+		c.valueTransforms = append(c.valueTransforms, valueTransformRule{interfaceName: interfaceName, endpoint: endpoint, transform: transform})
+		return nil
+	}
+}
+
 func (c *Client) GetPairingURL() (ret *url.URL) {
 	if c.pairingURL != nil {
 		ret, _ = url.Parse(c.pairingURL.String())
@@ -233,24 +532,35 @@ func (c *Client) GetRealmManagementURL() (ret *url.URL) {
 	return
 }
 
+// validate returns all the configuration errors of c.
 // nolint:gocognit
-func validate(c *Client) error {
+func validate(c *Client) []error {
+	errs := []error{}
 	if c.baseURL != nil && (c.appEngineURL != nil || c.realmManagementURL != nil || c.housekeepingURL != nil || c.pairingURL != nil) {
-		return ErrConflictingUrls
+		errs = append(errs, ErrConflictingUrls)
 	}
 	if c.baseURL == nil && c.appEngineURL == nil && c.realmManagementURL == nil && c.housekeepingURL == nil && c.pairingURL == nil {
-		return ErrNoUrlsProvided
+		errs = append(errs, ErrNoUrlsProvided)
 	}
 	if c.token != "" && c.privateKey != nil {
-		return ErrBothJWTAndPrivateKey
+		errs = append(errs, ErrBothJWTAndPrivateKey)
 	}
 	if c.token == "" && c.privateKey == nil {
-		return ErrNoAuthProvided
+		errs = append(errs, ErrNoAuthProvided)
 	}
 	if c.privateKey == nil && c.expiry != 0 {
-		return ErrExpiryButNoPrivateKeyProvided
+		errs = append(errs, ErrExpiryButNoPrivateKeyProvided)
 	}
-	return nil
+	if c.privateKey == nil && len(c.tokenOptions) > 0 {
+		errs = append(errs, ErrTokenOptionsButNoPrivateKey)
+	}
+	if c.privateKey != nil && len(c.tokenOptions) > 0 {
+		// Tokens are generated on the fly, make sure the options are valid beforehand
+		if _, err := auth.GenerateAstarteJWTFromPEMKeyWithOptions(c.privateKey, nil, 0, c.tokenOptions...); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
 }
 
 func setDefaults(c *Client) *Client {
@@ -261,7 +571,10 @@ func setDefaults(c *Client) *Client {
 
 	}
 	if c.userAgent == "" {
-		c.userAgent = "astarte-go"
+		c.userAgent = libraryUserAgent()
+	}
+	if c.userAgentSuffix != "" {
+		c.userAgent += " " + c.userAgentSuffix
 	}
 
 	if c.baseURL != nil {
@@ -275,6 +588,13 @@ func setDefaults(c *Client) *Client {
 		c.expiry = defaultJWTExpiry
 	}
 
+	if c.clock == nil {
+		c.clock = time.Now
+	}
+	if c.randomSource == nil {
+		c.randomSource = rand.Reader
+	}
+
 	return c
 }
 
@@ -290,8 +610,15 @@ func (c *Client) getJWT() string {
 	}
 	if c.token == "" {
 		// if we're here, we can safely assume that the key was OK
-		token, _ := auth.GenerateAstarteJWTFromPEMKey(c.privateKey, servicesAndClaims, int64(c.expiry))
+		opts := append(append([]auth.TokenOption{}, c.tokenOptions...), auth.WithIssuedAt(c.clock()))
+		token, _ := auth.GenerateAstarteJWTFromPEMKeyWithOptions(c.privateKey, servicesAndClaims, int64(c.expiry), opts...)
 		return token
 	}
 	return c.token
 }
+
+// GenerateRandomDeviceID returns a new random Astarte Device ID, using the random source of the client.
+// Do not use in production environments, see deviceid.GenerateRandom.
+func (c *Client) GenerateRandomDeviceID() (string, error) {
+	return deviceid.GenerateRandomFromReader(c.randomSource)
+}
@@ -15,7 +15,35 @@
 package client
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/astarteservices"
+	"github.com/astarte-platform/astarte-go/auth"
+	"github.com/astarte-platform/astarte-go/interfaces"
+	"github.com/astarte-platform/astarte-go/internal/runner"
 )
 
 func TestClientValidation(t *testing.T) {
@@ -70,3 +98,809 @@ func TestClientValidation(t *testing.T) {
 		t.Error("No auth options were given to client, but no error found")
 	}
 }
+
+func TestClientValidationReportsAllErrors(t *testing.T) {
+	_, err := New(
+		WithBaseURL("api.an-astarte.org"),
+		WithAppEngineURL("a.different.appengine-url.com"),
+		WithExpiry(3600),
+	)
+	for _, expected := range []error{ErrConflictingUrls, ErrNoAuthProvided, ErrTooHighExpiry} {
+		if !errors.Is(err, expected) {
+			t.Errorf("Expected %v among the errors, found %v", expected, err)
+		}
+	}
+
+	// A single error is returned as it is
+	if _, err := New(WithBaseURL("api.an-astarte.org")); err != ErrNoAuthProvided {
+		t.Errorf("Expected ErrNoAuthProvided, found %v", err)
+	}
+}
+
+func TestClientWithTokenOptions(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes})
+
+	c, err := New(
+		WithBaseURL("api.an-astarte.org"),
+		WithPrivateKey(keyPEM),
+		WithTokenOptions(auth.WithIssuer("an-issuer"), auth.WithAudience("a-gateway")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenParts := strings.Split(c.getJWT(), ".")
+	if len(tokenParts) != 3 {
+		t.Fatalf("Invalid JWT: %v", tokenParts)
+	}
+	rawClaims, err := base64.RawURLEncoding.DecodeString(tokenParts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var claims struct {
+		Issuer   string `json:"iss"`
+		Audience string `json:"aud"`
+	}
+	if err := json.Unmarshal(rawClaims, &claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims.Issuer != "an-issuer" || claims.Audience != "a-gateway" {
+		t.Errorf("Unexpected JWT claims: %+v", claims)
+	}
+
+	_, err = New(WithBaseURL("api.an-astarte.org"), WithPrivateKey(keyPEM), WithTokenOptions(auth.WithExtraClaims(map[string]any{"exp": 0})))
+	if !errors.Is(err, auth.ErrReservedClaim) {
+		t.Errorf("Expected ErrReservedClaim, found %v", err)
+	}
+	_, err = New(WithBaseURL("api.an-astarte.org"), WithJWT(testTokenValue), WithTokenOptions(auth.WithIssuer("an-issuer")))
+	if !errors.Is(err, ErrTokenOptionsButNoPrivateKey) {
+		t.Errorf("Expected ErrTokenOptionsButNoPrivateKey, found %v", err)
+	}
+}
+
+func TestClientWithClockAndRandomSource(t *testing.T) {
+	frozenTime := time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes})
+
+	newClient := func() *Client {
+		c, err := New(
+			WithBaseURL("api.an-astarte.org"),
+			WithPrivateKey(keyPEM),
+			WithClock(func() time.Time { return frozenTime }),
+			WithRandomSource(bytes.NewReader(bytes.Repeat([]byte{0x2a}, 16))),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	// The JWT is issued at the time returned by the clock
+	c := newClient()
+	tokenParts := strings.Split(c.getJWT(), ".")
+	if len(tokenParts) != 3 {
+		t.Fatalf("Invalid JWT: %v", tokenParts)
+	}
+	rawClaims, err := base64.RawURLEncoding.DecodeString(tokenParts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var claims struct {
+		IssuedAt  int64 `json:"iat"`
+		ExpiresAt int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(rawClaims, &claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims.IssuedAt != frozenTime.Unix() || claims.ExpiresAt != frozenTime.Unix()+defaultJWTExpiry {
+		t.Errorf("Unexpected JWT claims: %+v", claims)
+	}
+
+	// Datastream time windows end at the time returned by the clock
+	paginator, err := c.GetDatastreamIndividualPaginator(testRealmName, testDeviceID, AstarteDeviceID, testServerOwnedInterfaceName, "/an/endpoint", AscendingOrder, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if to := paginator.(*DatastreamPaginator).to; !to.Equal(frozenTime) {
+		t.Errorf("Expected paginator to end at %v, found %v", frozenTime, to)
+	}
+
+	// Device IDs generated from the same random source are the same
+	firstDeviceID, err := c.GenerateRandomDeviceID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	secondDeviceID, err := newClient().GenerateRandomDeviceID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if firstDeviceID != secondDeviceID {
+		t.Errorf("Expected reproducible device IDs, found %s and %s", firstDeviceID, secondDeviceID)
+	}
+}
+
+func TestClientWithTolerantStatusCodes(t *testing.T) {
+	// A server replying 203 Non-Authoritative Information, which is not documented for any endpoint
+	_, server := getTestServerContext(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNonAuthoritativeInfo)
+	})
+
+	warnings := []StatusCodeWarning{}
+	newClient := func(options ...Option) *Client {
+		return newTestClient(t, server, append(options,
+			WithStatusCodeWarningHandler(func(w StatusCodeWarning) { warnings = append(warnings, w) }))...)
+	}
+
+	c := newClient()
+	deleteInterfaceCall, _ := c.DeleteInterface(testRealmName, testInterfaceName, testInterfaceMajor)
+	if _, err := deleteInterfaceCall.Run(c); err == nil {
+		t.Error("Expected an error for a strict request, found nil")
+	}
+	if _, err := Tolerant(deleteInterfaceCall).Run(c); err != nil {
+		t.Errorf("Unexpected error for a tolerant request: %v", err)
+	}
+	// Run options apply to the wrapped request only, and are kept by nested wrappers
+	if _, err := deleteInterfaceCall.Run(c); err == nil {
+		t.Error("Expected an error for a strict request after a tolerant one, found nil")
+	}
+	if _, err := Timeout(Tolerant(deleteInterfaceCall), time.Second).Run(c); err != nil {
+		t.Errorf("Unexpected error for a tolerant request with a timeout: %v", err)
+	}
+
+	c = newClient(WithTolerantStatusCodes())
+	deleteInterfaceCall, _ = c.DeleteInterface(testRealmName, testInterfaceName, testInterfaceMajor)
+	if _, err := deleteInterfaceCall.Run(c); err != nil {
+		t.Errorf("Unexpected error for a tolerant client: %v", err)
+	}
+
+	if len(warnings) != 3 {
+		t.Fatalf("Expected 3 warnings, found %v", warnings)
+	}
+	for _, w := range warnings {
+		if w.Method != http.MethodDelete || w.Received != http.StatusNonAuthoritativeInfo || len(w.Expected) != 2 {
+			t.Errorf("Unexpected warning: %v", w)
+		}
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	_, server := getTestServerContext(t, func(w http.ResponseWriter, _ *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"data": ["a realm"]}`))
+	})
+
+	newClient := func(timeout time.Duration) *Client {
+		httpClient := &http.Client{Transport: server.Client().Transport, Timeout: timeout}
+		c, err := New(WithBaseURL(server.URL), WithJWT(testTokenValue), WithHTTPClient(httpClient))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	c := newClient(50 * time.Millisecond)
+	listRealmsCall, _ := c.ListRealms()
+	if _, err := listRealmsCall.Run(c); err == nil {
+		t.Error("Expected the client timeout to expire")
+	}
+	realms, err := runner.RunAndParse[[]string](c, Timeout(listRealmsCall, 5*time.Second).Run)
+	if err != nil || len(realms) != 1 {
+		t.Errorf("Unexpected result with a longer request timeout: %v, %v", realms, err)
+	}
+
+	c = newClient(5 * time.Second)
+	if _, err := Timeout(listRealmsCall, 50*time.Millisecond).Run(c); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the request timeout to expire, got %v", err)
+	}
+}
+
+func TestCancelableRequest(t *testing.T) {
+	c, _ := getTestServerContext(t, func(w http.ResponseWriter, _ *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"data": ["a realm"]}`))
+	})
+	listRealmsCall, _ := c.ListRealms()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := Cancelable(ctx, listRealmsCall).Run(c); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the request to be interrupted, got %v", err)
+	}
+	realms, err := runner.RunAndParse[[]string](c, Cancelable(context.Background(), listRealmsCall).Run)
+	if err != nil || len(realms) != 1 {
+		t.Errorf("Unexpected result: %v, %v", realms, err)
+	}
+}
+
+func TestRequestBodyCanBeReadManyTimes(t *testing.T) {
+	bodies := []string{}
+	c, _ := getTestServerContext(t, func(w http.ResponseWriter, req *http.Request) {
+		b, _ := io.ReadAll(req.Body)
+		bodies = append(bodies, string(b))
+		w.WriteHeader(http.StatusOK)
+	})
+	setAttributeCall, err := c.SetDeviceAttribute(testRealmName, testDeviceID, AstarteDeviceID, "site", "milan")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Running the same request twice sends the same body
+	for i := 0; i < 2; i++ {
+		if _, err := setAttributeCall.Run(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expectedBody := `{"data":{"attributes":{"site":"milan"}}}` + "\n"
+	if len(bodies) != 2 || bodies[0] != expectedBody || bodies[1] != expectedBody {
+		t.Errorf("Unexpected request bodies: %q", bodies)
+	}
+
+	// The curl command still contains the body after running the request
+	if curl := setAttributeCall.ToCurl(c); !strings.Contains(curl, `"site":"milan"`) {
+		t.Errorf("Curl command does not contain the request body: %s", curl)
+	}
+}
+
+func TestRequestCompression(t *testing.T) {
+	encodings := []string{}
+	bodies := []string{}
+	rejectGzip := false
+	c, server := getTestServerContext(t, func(w http.ResponseWriter, req *http.Request) {
+		encoding := req.Header.Get("Content-Encoding")
+		encodings = append(encodings, encoding)
+		if encoding == "gzip" && rejectGzip {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		body := req.Body
+		if encoding == "gzip" {
+			var err error
+			if body, err = gzip.NewReader(req.Body); err != nil {
+				t.Error(err)
+			}
+		}
+		b, _ := io.ReadAll(body)
+		bodies = append(bodies, string(b))
+		w.WriteHeader(http.StatusOK)
+	}, WithRequestCompression(GzipCompression, 64))
+
+	if _, err := New(WithBaseURL(server.URL), WithJWT(testTokenValue), WithRequestCompression("zstd", 0)); !errors.Is(err, ErrInvalidRequestCompression) {
+		t.Errorf("Expected ErrInvalidRequestCompression, found %v", err)
+	}
+	smallCall, _ := c.SetDeviceAttribute(testRealmName, testDeviceID, AstarteDeviceID, "a", "b")
+	largeCall, _ := c.SetDeviceAttribute(testRealmName, testDeviceID, AstarteDeviceID, "site", strings.Repeat("milan", 10))
+	for _, call := range []AstarteRequest{smallCall, largeCall, Compressed(largeCall, NoCompression)} {
+		if _, err := call.Run(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !reflect.DeepEqual(encodings, []string{"", "gzip", ""}) {
+		t.Errorf("Unexpected content encodings: %v", encodings)
+	}
+	if len(bodies) != 3 || bodies[1] != bodies[2] {
+		t.Errorf("Unexpected request bodies: %q", bodies)
+	}
+
+	// Once the server rejects compressed bodies, the client stops compressing them
+	rejectGzip = true
+	encodings = nil
+	for i := 0; i < 2; i++ {
+		if _, err := largeCall.Run(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !reflect.DeepEqual(encodings, []string{"gzip", "", ""}) {
+		t.Errorf("Unexpected content encodings after rejection: %v", encodings)
+	}
+
+	// Compressed enables compression on clients without WithRequestCompression
+	rejectGzip = false
+	encodings, bodies = nil, nil
+	plain := newTestClient(t, server)
+	plainCall, _ := plain.SetDeviceAttribute(testRealmName, testDeviceID, AstarteDeviceID, "site", strings.Repeat("milan", 10))
+	if _, err := Compressed(plainCall, GzipCompression).Run(plain); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(encodings, []string{"gzip"}) || len(bodies) != 1 || !strings.Contains(bodies[0], "milan") {
+		t.Errorf("Unexpected compressed request: encodings %v, bodies %q", encodings, bodies)
+	}
+}
+
+type countingTransport struct {
+	base     http.RoundTripper
+	requests *atomic.Int64
+}
+
+func (t countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests.Add(1)
+	return t.base.RoundTrip(req)
+}
+
+func TestServiceHTTPClients(t *testing.T) {
+	_, server := getTestServerContext(t, func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"data": []}`))
+	})
+
+	housekeepingRequests := &atomic.Int64{}
+	housekeepingClient := &http.Client{Transport: countingTransport{base: server.Client().Transport, requests: housekeepingRequests}}
+	c := newTestClient(t, server, WithServiceHTTPClient(astarteservices.Housekeeping, housekeepingClient),
+		WithServiceConnectionLimit(astarteservices.AppEngine, 2))
+
+	listRealmsCall, _ := c.ListRealms()
+	listGroupsCall, _ := c.ListGroups(testRealmName)
+	listInterfacesCall, _ := c.ListInterfaces(testRealmName)
+	for _, call := range []AstarteRequest{listRealmsCall, listGroupsCall, listInterfacesCall} {
+		if _, err := call.Run(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if requests := housekeepingRequests.Load(); requests != 1 {
+		t.Errorf("Expected 1 request through the Housekeeping client, found %d", requests)
+	}
+	appEngineClient := c.serviceHTTPClients[astarteservices.AppEngine]
+	transport, ok := appEngineClient.Transport.(*http.Transport)
+	if !ok || transport == server.Client().Transport || transport.MaxConnsPerHost != 2 {
+		t.Errorf("Expected a dedicated AppEngine transport with 2 connections, found %#v", appEngineClient.Transport)
+	}
+
+	if _, err := New(WithBaseURL(server.URL), WithJWT(testTokenValue), WithServiceHTTPClient(astarteservices.Flow, housekeepingClient)); !errors.Is(err, ErrUnsupportedService) {
+		t.Errorf("Expected ErrUnsupportedService, found %v", err)
+	}
+	if _, err := New(WithBaseURL(server.URL), WithJWT(testTokenValue), WithServiceHTTPClient(astarteservices.Housekeeping, housekeepingClient),
+		WithServiceConnectionLimit(astarteservices.Housekeeping, 2)); err == nil {
+		t.Error("Expected an error limiting the connections of a custom transport, found nil")
+	}
+}
+
+func TestServiceOfNestedURLs(t *testing.T) {
+	c, err := New(WithAppEngineURL("https://api.example.com/"), WithHousekeepingURL("https://api.example.com/housekeeping"),
+		WithPairingURL("https://api.example.com/pairing"), WithRealmManagementURL("https://api.example.com/realmmanagement"),
+		WithJWT(testTokenValue))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]astarteservices.AstarteService{
+		"https://api.example.com/housekeeping/v1/realms":         astarteservices.Housekeeping,
+		"https://api.example.com/realmmanagement/v1/test/groups": astarteservices.RealmManagement,
+		"https://api.example.com/v1/test/devices":                astarteservices.AppEngine,
+		"https://other.example.com/v1/test/devices":              astarteservices.Unknown,
+	}
+	// The matching service must not depend on the iteration order
+	for i := 0; i < 20; i++ {
+		for rawURL, service := range expected {
+			u, _ := url.Parse(rawURL)
+			if found := c.serviceOf(u); found != service {
+				t.Fatalf("Expected %s to belong to %v, found %v", rawURL, service, found)
+			}
+		}
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	userAgents := []string{}
+	_, server := getTestServerContext(t, func(w http.ResponseWriter, req *http.Request) {
+		userAgents = append(userAgents, req.Header.Get("User-Agent"))
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for _, opts := range [][]Option{
+		{},
+		{WithUserAgentSuffix("mytool/1.2")},
+		{WithUserAgent("pippo"), WithUserAgentSuffix("mytool/1.2")},
+	} {
+		c := newTestClient(t, server, opts...)
+		listRealmsCall, _ := c.ListRealms()
+		if _, err := listRealmsCall.Run(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := []string{libraryUserAgent(), libraryUserAgent() + " mytool/1.2", "pippo mytool/1.2"}
+	if !reflect.DeepEqual(userAgents, expected) {
+		t.Errorf("Unexpected user agents: %q instead of %q", userAgents, expected)
+	}
+	if !strings.HasPrefix(libraryUserAgent(), "astarte-go") {
+		t.Errorf("Unexpected library user agent: %s", libraryUserAgent())
+	}
+}
+
+func TestResponseMetadata(t *testing.T) {
+	c, _ := getTestServerContext(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set(RequestIDHeader, "FzKdmGtM7vYjp4kAAAJi")
+		w.Header().Set("X-RateLimit-Remaining", "42")
+		w.WriteHeader(http.StatusNoContent)
+	})
+	deleteInterfaceCall, _ := c.DeleteInterface(testRealmName, testInterfaceName, testInterfaceMajor)
+	res, err := deleteInterfaceCall.Run(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := res.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode() != http.StatusNoContent {
+		t.Errorf("Expected status code %d, found %d", http.StatusNoContent, res.StatusCode())
+	}
+	if res.RequestID() != "FzKdmGtM7vYjp4kAAAJi" {
+		t.Errorf("Unexpected request id: %s", res.RequestID())
+	}
+	if res.Headers().Get("X-RateLimit-Remaining") != "42" {
+		t.Errorf("Unexpected rate limit header: %s", res.Headers().Get("X-RateLimit-Remaining"))
+	}
+}
+
+func TestRealmClient(t *testing.T) {
+	c, _ := getTestContext(t)
+	realmClient := c.Realm(testRealmName)
+	if realmClient.Name() != testRealmName || realmClient.Client() != c {
+		t.Fatalf("Unexpected realm client: %v", realmClient)
+	}
+	listInterfacesCall, err := realmClient.ListInterfaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := listInterfacesCall.Run(realmClient.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := res.Parse(); err != nil {
+		t.Fatal(err)
+	}
+
+	overridden := realmClient.WithJWT("a realm token")
+	getInterfaceCall, _ := overridden.GetInterface(testInterfaceName, testInterfaceMajor)
+	req := getInterfaceCall.(GetInterfaceRequest).req
+	if auth := req.Header.Get("Authorization"); auth != "Bearer a realm token" {
+		t.Errorf("Unexpected Authorization header: %s", auth)
+	}
+	if !strings.Contains(req.URL.Path, "/"+testRealmName+"/interfaces/") {
+		t.Errorf("Unexpected URL: %s", req.URL)
+	}
+	if c.getJWT() != testTokenValue {
+		t.Error("Overriding the token of a RealmClient must not change the original Client")
+	}
+}
+
+func TestClientRetriesUnauthorizedWithFreshToken(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes})
+
+	// The server rejects the first rejections requests
+	requests, rejections := 0, 1
+	_, server := getTestServerContext(t, func(w http.ResponseWriter, _ *http.Request) {
+		requests++
+		if requests <= rejections {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	refreshes := []TokenRefresh{}
+	c, err := New(
+		WithBaseURL(server.URL),
+		WithPrivateKey(keyPEM),
+		WithHTTPClient(server.Client()),
+		WithTokenRefreshHandler(func(r TokenRefresh) { refreshes = append(refreshes, r) }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleteInterfaceCall, _ := c.DeleteInterface(testRealmName, testInterfaceName, testInterfaceMajor)
+	if _, err := deleteInterfaceCall.Run(c); err != nil {
+		t.Fatalf("Unexpected error after retrying: %v", err)
+	}
+	if requests != 2 || len(refreshes) != 1 || refreshes[0].RetryStatusCode != http.StatusNoContent {
+		t.Errorf("Unexpected retries: %d requests, %v", requests, refreshes)
+	}
+
+	// A request still rejected after the retry fails, without further retries
+	requests, rejections = 0, 3
+	if _, err := deleteInterfaceCall.Run(c); err == nil {
+		t.Error("Expected an error, found nil")
+	}
+	if requests != 2 {
+		t.Errorf("Expected exactly one retry, found %d requests", requests)
+	}
+}
+
+func TestNewFromAstartectlContext(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes})
+
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	files := map[string]string{
+		"astartectl.yaml":          "context: production\n",
+		"contexts/production.yaml": "cluster: eu\nrealm:\n  name: myrealm\n",
+		"clusters/eu.yaml": "url: https://api.astarte.example.com\nindividual-urls:\n  pairing: https://pairing.example.com\n" +
+			"housekeeping:\n  key: " + base64.StdEncoding.EncodeToString(keyPEM) + "\n",
+		"contexts/nokey.yaml": "cluster: nokey\nrealm:\n  name: myrealm\n",
+		"clusters/nokey.yaml": "url: https://api.astarte.example.com\n",
+	}
+	for path, content := range files {
+		path = filepath.Join(configHome, "astarte", path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c, err := NewFromAstartectlContext("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.appEngineURL.String() != "https://api.astarte.example.com/appengine" || c.pairingURL.String() != "https://pairing.example.com" {
+		t.Errorf("Unexpected URLs: %s, %s", c.appEngineURL, c.pairingURL)
+	}
+	if !bytes.Equal(c.privateKey, keyPEM) {
+		t.Error("The Housekeeping key of the cluster was not used")
+	}
+
+	if _, err := NewFromAstartectlContext("nokey"); err != ErrNoAuthProvided {
+		t.Errorf("Expected ErrNoAuthProvided, found %v", err)
+	}
+	if c, err := NewFromAstartectlContext("nokey", WithJWT("a JWT")); err != nil || c.token != "a JWT" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestTimestampPrecision(t *testing.T) {
+	bodies := []string{}
+	queries := []url.Values{}
+	_, server := getTestServerContext(t, func(w http.ResponseWriter, req *http.Request) {
+		b, _ := io.ReadAll(req.Body)
+		bodies = append(bodies, string(b))
+		queries = append(queries, req.URL.Query())
+		_, _ = w.Write([]byte(`{"data": []}`))
+	})
+
+	if _, err := New(WithBaseURL(server.URL), WithJWT(testTokenValue), WithTimestampPrecision(42)); !errors.Is(err, ErrInvalidTimestampPrecision) {
+		t.Errorf("Expected ErrInvalidTimestampPrecision, found %v", err)
+	}
+	c := newTestClient(t, server, WithTimestampPrecision(MillisecondPrecision))
+	rome := time.FixedZone("Rome", 3600)
+	timestamp := time.Date(2024, 2, 29, 13, 0, 0, 123456789, rome)
+	astarteInterface := interfaces.AstarteInterface{
+		Name:        testServerOwnedInterfaceName,
+		Type:        interfaces.DatastreamType,
+		Ownership:   interfaces.ServerOwnership,
+		Aggregation: interfaces.IndividualAggregation,
+		Mappings:    []interfaces.AstarteInterfaceMapping{{Endpoint: "/%{sensor}/since", Type: interfaces.DateTimeArray, ExplicitTimestamp: true}},
+	}
+	sendCall, err := c.SendDatastreamAt(testRealmName, testDeviceID, AstarteDeviceID, astarteInterface, "/s1/since", []time.Time{timestamp}, timestamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paginator, err := c.GetDatastreamIndividualTimeWindowPaginator(testRealmName, testDeviceID, AstarteDeviceID, testServerOwnedInterfaceName,
+		"/s1/since", timestamp, timestamp.Add(time.Second), AscendingOrder, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pageCall, err := paginator.GetNextPage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, call := range []AstarteRequest{sendCall, pageCall} {
+		if _, err := call.Run(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if expected := `{"data":["2024-02-29T12:00:00.123Z"],"timestamp":"2024-02-29T12:00:00.123Z"}`; strings.TrimSpace(bodies[0]) != expected {
+		t.Errorf("Expected body %s, found %s", expected, bodies[0])
+	}
+	if since, to := queries[1].Get("since"), queries[1].Get("to"); since != "2024-02-29T12:00:00.123Z" || to != "2024-02-29T12:00:01.123Z" {
+		t.Errorf("Unexpected time window %s - %s", since, to)
+	}
+	if got := SecondPrecision.Format(timestamp); got != "2024-02-29T12:00:00Z" {
+		t.Errorf("Unexpected timestamp with second precision %s", got)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes})
+
+	requests := 0
+	_, server := getTestServerContext(t, func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		switch {
+		case requests == 1:
+			w.WriteHeader(http.StatusUnauthorized)
+		case req.Method == http.MethodGet:
+			_, _ = w.Write([]byte(`{"data": [], "links": {"self": "/v1/test/devices"}}`))
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+
+	metrics := NewPrometheusMetrics()
+	c, err := New(WithBaseURL(server.URL), WithPrivateKey(keyPEM), WithHTTPClient(server.Client()), WithMetrics(metrics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleteInterfaceCall, _ := c.DeleteInterface(testRealmName, testInterfaceName, testInterfaceMajor)
+	if _, err := deleteInterfaceCall.Run(c); err != nil {
+		t.Fatal(err)
+	}
+	paginator, _ := c.GetDeviceListPaginator(testRealmName, 10, DeviceIDFormat)
+	nextPageCall, _ := paginator.GetNextPage()
+	if _, err := nextPageCall.Run(c); err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := httptest.NewRecorder()
+	metrics.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	exposed := recorder.Body.String()
+	for _, expected := range []string{
+		`astarte_client_requests_total{service="realm-management",endpoint="/v1/*/interfaces/*",method="DELETE",code="401"} 1`,
+		`astarte_client_requests_total{service="realm-management",endpoint="/v1/*/interfaces/*",method="DELETE",code="204"} 1`,
+		`astarte_client_requests_total{service="appengine",endpoint="/v1/*/devices",method="GET",code="200"} 1`,
+		`astarte_client_request_duration_seconds_count{service="realm-management",endpoint="/v1/*/interfaces/*",method="DELETE"} 2`,
+		`astarte_client_retries_total{service="realm-management",endpoint="/v1/*/interfaces/*",reason="unauthorized"} 1`,
+		`astarte_client_token_refreshes_total 1`,
+		`astarte_client_paginator_pages_total{paginator="device_list"} 1`,
+	} {
+		if !strings.Contains(exposed, expected+"\n") {
+			t.Errorf("Missing metric %s in:\n%s", expected, exposed)
+		}
+	}
+
+	for path, expected := range map[string]string{
+		"/appengine/v1/test/devices/" + testDeviceID + "/interfaces/" + testInterfaceName + "/a/value": "/v1/*/devices/*/interfaces/*",
+		"/pairing/v1/test/agent/devices":                             "/v1/*/agent/devices",
+		"/housekeeping/v1/realms/test":                               "/v1/realms/*",
+		"/appengine/v1/test/devices/" + testDeviceID + "/interfaces": "/v1/*/devices/*/interfaces",
+	} {
+		u, _ := url.Parse(server.URL + path)
+		if _, endpoint := c.endpointOf(u); endpoint != expected {
+			t.Errorf("Unexpected endpoint %s for %s, expected %s", endpoint, path, expected)
+		}
+	}
+}
+
+func TestNonJSONResponse(t *testing.T) {
+	c, _ := getTestServerContext(t, func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/appengine/v1/html/devices/" + testDeviceID:
+			w.Header().Set("Content-Type", "text/html")
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte("<html><body><h1>502 Bad Gateway</h1></body></html>"))
+		case "/appengine/v1/sniffed/devices/" + testDeviceID:
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte("  <!DOCTYPE html><html></html>"))
+		default:
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte(`{"data": {"id": "` + testDeviceID + `"}}`))
+		}
+	})
+
+	for realm, expectedStatusCode := range map[string]int{"html": http.StatusBadGateway, "sniffed": http.StatusOK} {
+		getDeviceCall, err := c.GetDeviceDetails(realm, testDeviceID, AstarteDeviceID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = getDeviceCall.Run(c)
+		nonJSONErr := &NonJSONResponseError{}
+		if !errors.Is(err, ErrNonJSONResponse) || !errors.As(err, &nonJSONErr) {
+			t.Fatalf("Expected ErrNonJSONResponse for %s, found %v", realm, err)
+		}
+		if nonJSONErr.StatusCode != expectedStatusCode || !strings.Contains(nonJSONErr.Body, "html>") {
+			t.Errorf("Unexpected error %+v", nonJSONErr)
+		}
+	}
+
+	// JSON bodies are accepted even with the wrong content type
+	getDeviceCall, err := c.GetDeviceDetails(testRealmName, testDeviceID, AstarteDeviceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	details, err := runner.RunAndParse[DeviceDetails](c, getDeviceCall.Run)
+	if err != nil || details.DeviceID != testDeviceID {
+		t.Errorf("Unexpected device details %+v: %v", details, err)
+	}
+}
+
+func TestAuditHandler(t *testing.T) {
+	_, server := getTestServerContext(t, func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if req.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors": {"detail": "Forbidden"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": {"id": "` + testDeviceID + `"}}`))
+	})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	out := &bytes.Buffer{}
+	c := newTestClient(t, server, WithClock(func() time.Time { return now }), WithAuditHandler("admin@example.com", JSONLinesAuditHandler(out)))
+
+	getDeviceCall, err := c.GetDeviceDetails(testRealmName, testDeviceID, AstarteDeviceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := getDeviceCall.Run(c); err != nil {
+		t.Fatal(err)
+	}
+	sendCall, err := c.SendDatastream(testRealmName, testDeviceID, AstarteDeviceID, testServerOwnedInterfaceName, "/an/endpoint", 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sendCall.Run(c); err != nil {
+		t.Fatal(err)
+	}
+	unsetCall, err := c.UnsetProperty(testRealmName, testDeviceID, AstarteDeviceID, testServerOwnedInterfaceName, "/an/endpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := unsetCall.Run(c); err == nil {
+		t.Fatal("Expected an error")
+	}
+
+	records := []AuditRecord{}
+	decoder := json.NewDecoder(out)
+	for decoder.More() {
+		record := AuditRecord{}
+		if err := decoder.Decode(&record); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, found %v", records)
+	}
+	sent := records[0]
+	body, err := sendCall.(SendDatastreamRequest).req.GetBody()
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, _ := io.ReadAll(body)
+	payloadHash := sha256.Sum256(payload)
+	if sent.Method != http.MethodPost || sent.Actor != "admin@example.com" || !sent.Time.Equal(now) || sent.Service != astarteservices.AppEngine.String() ||
+		sent.Endpoint != "/v1/*/devices/*/interfaces/*" || sent.StatusCode != http.StatusOK || sent.PayloadSHA256 != hex.EncodeToString(payloadHash[:]) {
+		t.Errorf("Unexpected record %+v", sent)
+	}
+	if unset := records[1]; unset.Method != http.MethodDelete || unset.StatusCode != http.StatusForbidden || unset.PayloadSHA256 != "" {
+		t.Errorf("Unexpected record %+v", unset)
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package clienttest provides helpers to test code handling the responses of the client package, e.g. to check
+// that response bodies are closed exactly once and never read after being closed.
+package clienttest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+var (
+	ErrBodyNotClosed        = errors.New("Response body was not closed")
+	ErrBodyClosedManyTimes  = errors.New("Response body was closed more than once")
+	ErrBodyReadAfterClosing = errors.New("Response body was read after being closed")
+)
+
+// ResponseBody is a response body which records how it is used. It can be read until it is closed:
+// reading it afterwards fails, and is reported by Check.
+type ResponseBody struct {
+	mu              sync.Mutex
+	r               io.Reader
+	closes          int
+	readsAfterClose int
+}
+
+// NewResponseBody returns a ResponseBody holding body.
+func NewResponseBody(body []byte) *ResponseBody {
+	return &ResponseBody{r: bytes.NewReader(body)}
+}
+
+func (b *ResponseBody) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closes > 0 {
+		b.readsAfterClose++
+		return 0, ErrBodyReadAfterClosing
+	}
+	return b.r.Read(p)
+}
+
+func (b *ResponseBody) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closes++
+	return nil
+}
+
+// Closes returns how many times the body was closed.
+func (b *ResponseBody) Closes() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.closes
+}
+
+// Check returns an error if the body was not closed exactly once, or if it was read after being closed.
+func (b *ResponseBody) Check() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	errs := []error{}
+	switch {
+	case b.closes == 0:
+		errs = append(errs, ErrBodyNotClosed)
+	case b.closes > 1:
+		errs = append(errs, fmt.Errorf("%w: %d times", ErrBodyClosedManyTimes, b.closes))
+	}
+	if b.readsAfterClose > 0 {
+		errs = append(errs, ErrBodyReadAfterClosing)
+	}
+	return errors.Join(errs...)
+}
+
+// Transport is an http.RoundTripper replying to all requests with StatusCode (200 if 0) and a JSON Body,
+// and recording the ResponseBody of each response. It can be used as the transport of the HTTP client of
+// a client.Client, see client.WithHTTPClient.
+type Transport struct {
+	StatusCode int
+	Body       []byte
+
+	mu     sync.Mutex
+	bodies []*ResponseBody
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	statusCode := t.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	body := NewResponseBody(t.Body)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.bodies = append(t.bodies, body)
+	return &http.Response{
+		StatusCode: statusCode,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       body,
+		Request:    req,
+	}, nil
+}
+
+// Bodies returns the bodies of the responses returned so far.
+func (t *Transport) Bodies() []*ResponseBody {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*ResponseBody{}, t.bodies...)
+}
+
+// Check returns the errors of the bodies of the responses returned so far, see ResponseBody.Check, joined.
+func (t *Transport) Check() error {
+	errs := []error{}
+	for i, body := range t.Bodies() {
+		if err := body.Check(); err != nil {
+			errs = append(errs, fmt.Errorf("response %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
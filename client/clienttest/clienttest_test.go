@@ -0,0 +1,67 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clienttest
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestResponseBody(t *testing.T) {
+	body := NewResponseBody([]byte(`{"data": 42}`))
+	if err := body.Check(); !errors.Is(err, ErrBodyNotClosed) {
+		t.Errorf("Expected ErrBodyNotClosed, found %v", err)
+	}
+	if b, err := io.ReadAll(body); err != nil || string(b) != `{"data": 42}` {
+		t.Errorf("Unexpected body %q: %v", b, err)
+	}
+	body.Close()
+	if err := body.Check(); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := body.Read(make([]byte, 1)); !errors.Is(err, ErrBodyReadAfterClosing) {
+		t.Errorf("Expected ErrBodyReadAfterClosing, found %v", err)
+	}
+	body.Close()
+	if err := body.Check(); !errors.Is(err, ErrBodyClosedManyTimes) || !errors.Is(err, ErrBodyReadAfterClosing) {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	transport := &Transport{Body: []byte(`{"data": null}`)}
+	c := &http.Client{Transport: transport}
+	for i := 0; i < 2; i++ {
+		res, err := c.Get("http://astarte.example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.StatusCode != http.StatusOK {
+			t.Errorf("Unexpected status code %d", res.StatusCode)
+		}
+		if i == 0 {
+			res.Body.Close()
+		}
+	}
+	if len(transport.Bodies()) != 2 || transport.Bodies()[0].Closes() != 1 {
+		t.Errorf("Unexpected bodies %v", transport.Bodies())
+	}
+	if err := transport.Check(); !errors.Is(err, ErrBodyNotClosed) {
+		t.Errorf("Expected ErrBodyNotClosed, found %v", err)
+	}
+}
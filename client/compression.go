@@ -0,0 +1,124 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+)
+
+// RequestCompression is the content coding used to compress request bodies, see WithRequestCompression.
+type RequestCompression string
+
+const (
+	// NoCompression sends request bodies as they are. This is the default.
+	NoCompression RequestCompression = ""
+	// GzipCompression compresses request bodies with gzip.
+	GzipCompression RequestCompression = "gzip"
+)
+
+// IsValid returns an error if RequestCompression is not a supported content coding.
+func (r RequestCompression) IsValid() error {
+	switch r {
+	case NoCompression, GzipCompression:
+		return nil
+	}
+	return ErrInvalidRequestCompression
+}
+
+// The Compressed function returns a request which runs req compressing its body with compression,
+// regardless of the compression of the Client. NoCompression can be used to send a single request uncompressed.
+// The body is compressed only if it is at least as large as the threshold set with WithRequestCompression, if any.
+func Compressed(req AstarteRequest, compression RequestCompression) AstarteRequest {
+	return compressedRequest{req: req, compression: compression}
+}
+
+type compressedRequest struct {
+	req         AstarteRequest
+	compression RequestCompression
+}
+
+func (r compressedRequest) Run(c *Client) (AstarteResponse, error) {
+	if err := r.compression.IsValid(); err != nil {
+		return Empty{}, err
+	}
+	return r.req.Run(c.withRunOptions(func(o *runOptions) { o.compression = &r.compression }))
+}
+
+func (r compressedRequest) ToCurl(c *Client) string {
+	return r.req.ToCurl(c)
+}
+
+// sendCompressed sends req, compressing its body according to the settings of c. If the server rejects the
+// compressed body with 415 Unsupported Media Type, req is sent again uncompressed, and compression is disabled
+// for the rest of the lifetime of the client.
+func (c *Client) sendCompressed(req *http.Request) (*http.Response, error) {
+	compressed, ok := c.compressRequest(req)
+	if !ok {
+		return c.send(req)
+	}
+	res, err := c.send(compressed)
+	if err != nil || res.StatusCode != http.StatusUnsupportedMediaType {
+		return res, err
+	}
+	_, _ = io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+
+	c.compressionRejected.Store(true)
+	c.observeRetry(req.URL, RetryUncompressed)
+	return c.send(req)
+}
+
+// compressRequest returns a copy of req with a compressed body, or false if the body should be sent as it is.
+func (c *Client) compressRequest(req *http.Request) (*http.Request, bool) {
+	compression := c.requestCompression
+	if c.run.compression != nil {
+		compression = *c.run.compression
+	}
+	if compression == NoCompression || c.compressionRejected.Load() ||
+		req.GetBody == nil || req.Header.Get("Content-Encoding") != "" {
+		return nil, false
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, false
+	}
+	defer body.Close()
+	payload, err := io.ReadAll(body)
+	if err != nil || len(payload) == 0 || len(payload) < c.compressionThreshold {
+		return nil, false
+	}
+
+	b := new(bytes.Buffer)
+	w := gzip.NewWriter(b)
+	if _, err := w.Write(payload); err != nil {
+		return nil, false
+	}
+	if err := w.Close(); err != nil {
+		return nil, false
+	}
+	compressedPayload := b.Bytes()
+
+	compressed := req.Clone(req.Context())
+	compressed.Body = io.NopCloser(bytes.NewReader(compressedPayload))
+	compressed.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(compressedPayload)), nil
+	}
+	compressed.ContentLength = int64(len(compressedPayload))
+	compressed.Header.Set("Content-Encoding", string(compression))
+	return compressed, true
+}
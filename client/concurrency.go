@@ -0,0 +1,45 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import "golang.org/x/sync/errgroup"
+
+// forEachConcurrently calls fn on each item, running up to concurrency calls at a time, and returns once all calls
+// have returned. fn receives the index of the item, so that results can be stored without further locking.
+func forEachConcurrently[T any](items []T, concurrency int, fn func(i int, item T)) {
+	var group errgroup.Group
+	group.SetLimit(concurrency)
+	for i, item := range items {
+		i, item := i, item
+		group.Go(func() error {
+			fn(i, item)
+			return nil
+		})
+	}
+	_ = group.Wait()
+}
+
+// uniqueStrings returns values without duplicates, keeping the first occurrence of each value.
+func uniqueStrings(values []string) []string {
+	ret := make([]string, 0, len(values))
+	seen := make(map[string]bool, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			ret = append(ret, value)
+		}
+	}
+	return ret
+}
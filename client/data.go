@@ -15,6 +15,8 @@
 package client
 
 import (
+	"encoding/json"
+	"io"
 	"net/http"
 
 	"github.com/astarte-platform/astarte-go/interfaces"
@@ -28,11 +30,43 @@ type AstarteResponse interface {
 	// response. The function does not need to close the response body.
 	// Raw simply returns the value returned by the handling function.
 	Raw(func(*http.Response) any) any
+	// Headers returns the headers of the Astarte response, e.g. to honour X-RateLimit-* headers.
+	Headers() http.Header
+	// StatusCode returns the HTTP status code of the Astarte response.
+	StatusCode() int
+	// RequestID returns the value of the X-Request-Id header of the Astarte response, if any.
+	RequestID() string
 }
 
 func (e Empty) Parse() (any, error)              { return nil, nil }
 func (e Empty) Raw(func(*http.Response) any) any { return nil }
 
+// AstartePayload represents the envelope Astarte APIs use for their payloads: the actual content is
+// wrapped in a "data" key, and paginated endpoints add a "links" key with pagination metadata.
+// It is used by all Parse implementations, and can be used when handling a response with Raw, e.g.:
+//
+//	payload := AstartePayload[DeviceDetails]{}
+//	err := json.NewDecoder(res.Body).Decode(&payload)
+type AstartePayload[T any] struct {
+	Data  T      `json:"data"`
+	Links *Links `json:"links,omitempty"`
+}
+
+// decodeAstartePayload decodes an Astarte payload from body. data is used as the starting value
+// of the payload content, and it is returned as is if body has no "data" key.
+func decodeAstartePayload[T any](body io.Reader, data T) (AstartePayload[T], error) {
+	payload := AstartePayload[T]{Data: data}
+	err := json.NewDecoder(body).Decode(&payload)
+	return payload, err
+}
+
+// unmarshalAstartePayload works like decodeAstartePayload, but on an already read body.
+func unmarshalAstartePayload[T any](body []byte, data T) (AstartePayload[T], error) {
+	payload := AstartePayload[T]{Data: data}
+	err := json.Unmarshal(body, &payload)
+	return payload, err
+}
+
 // Pairing
 
 type RegisterDeviceResponse struct {
@@ -44,7 +78,13 @@ type NewDeviceCertificateResponse struct {
 }
 
 type Mqttv1DeviceInformationResponse struct {
-	res *http.Response
+	res                *http.Response
+	brokerURLValidator BrokerURLValidator
+}
+
+type DeviceTransportInformationResponse struct {
+	res                *http.Response
+	brokerURLValidator BrokerURLValidator
 }
 
 // Housekeeping
@@ -61,6 +101,10 @@ type CreateRealmResponse struct {
 	res *http.Response
 }
 
+type UpdateRealmResponse struct {
+	res *http.Response
+}
+
 // Realm Management
 
 type ListInterfacesResponse struct {
@@ -118,6 +162,10 @@ type GetDeviceDetailsResponse struct {
 	res *http.Response
 }
 
+type GetDeviceInterfaceStatsResponse struct {
+	res *http.Response
+}
+
 type GetDeviceStatsResponse struct {
 	res *http.Response
 }
@@ -144,14 +192,22 @@ type GetNextDatastreamPageResponse struct {
 }
 
 type GetDatastreamSnapshotResponse struct {
-	res         *http.Response
-	aggregation interfaces.AstarteInterfaceAggregation
+	res            *http.Response
+	aggregation    interfaces.AstarteInterfaceAggregation
+	transformer    valueTransformer
+	parameterKeys  *interfaces.AstarteInterface
+	projection     datastreamProjection
+	preciseNumbers bool
 }
 
 type GetPropertiesResponse struct {
 	res *http.Response
 }
 
+type SendDatastreamResponse struct {
+	res *http.Response
+}
+
 type ListGroupsResponse struct {
 	res *http.Response
 }
@@ -166,6 +222,57 @@ type NoDataResponse struct {
 	res *http.Response
 }
 
+// AsyncAcceptedResponse is returned by requests for operations which Astarte can perform asynchronously
+// (e.g. installing an interface or creating a realm) when Astarte replies 202 Accepted, i.e. when the
+// operation was accepted for later processing rather than applied synchronously.
+type AsyncAcceptedResponse struct {
+	res *http.Response
+}
+
+// AsyncOperation describes an operation Astarte accepted for later processing.
+type AsyncOperation struct {
+	// Location is the URL of the affected resource, if Astarte provides it
+	Location string
+	// OperationID identifies the pending operation, if Astarte provides it
+	OperationID string
+	// Data is the data payload of the response, if any
+	Data json.RawMessage
+}
+
+// IsAsyncAccepted returns whether res is an AsyncAcceptedResponse, i.e. whether the operation was
+// accepted for later processing rather than applied synchronously.
+func IsAsyncAccepted(res AstarteResponse) bool {
+	_, ok := res.(AsyncAcceptedResponse)
+	return ok
+}
+
+// Parses data obtained by performing a request accepted for later processing.
+// Returns the pending operation as an AsyncOperation.
+func (r AsyncAcceptedResponse) Parse() (any, error) {
+	defer r.res.Body.Close()
+	operation := AsyncOperation{Location: r.res.Header.Get("Location")}
+	b, err := io.ReadAll(r.res.Body)
+	if err != nil || len(b) == 0 {
+		return operation, err
+	}
+	payload, err := unmarshalAstartePayload(b, json.RawMessage{})
+	if err != nil {
+		return operation, err
+	}
+	operation.Data = payload.Data
+	var ids struct {
+		OperationID string `json:"operation_id"`
+	}
+	_ = json.Unmarshal(payload.Data, &ids)
+	operation.OperationID = ids.OperationID
+	return operation, nil
+}
+
+func (r AsyncAcceptedResponse) Raw(f func(*http.Response) any) any {
+	defer r.res.Body.Close()
+	return f(r.res)
+}
+
 // Parses data obtained by performing a request to Astarte which does not return data.
 // The returned values do not matter.
 func (r NoDataResponse) Parse() (any, error) {
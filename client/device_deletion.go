@@ -0,0 +1,218 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/internal/runner"
+	"github.com/astarte-platform/astarte-go/pairing/store"
+)
+
+const (
+	defaultDeletionPollInterval = time.Second
+	defaultDeletionTimeout      = time.Minute
+)
+
+// DeviceCleanupReport is the outcome of DeleteDeviceAndCleanup.
+type DeviceCleanupReport struct {
+	DeviceID string
+	// Deleted is set once the device disappeared from the realm.
+	Deleted bool
+	// DeletionTime is the time it took for the device to disappear from the realm.
+	DeletionTime time.Duration
+	// Groups are the groups the device belonged to before being deleted.
+	Groups []string
+	// GroupErrors holds the errors removing the device from each group, if any.
+	GroupErrors map[string]error
+	// CredentialsDeleted is set if the credentials of the device were deleted from the store.
+	CredentialsDeleted bool
+	// CredentialsErr is set if the credentials of the device could not be deleted from the store.
+	CredentialsErr error
+}
+
+// Err joins the errors of the cleanup steps, or returns nil if all of them succeeded.
+func (r DeviceCleanupReport) Err() error {
+	errs := []error{}
+	for _, group := range r.Groups {
+		if err := r.GroupErrors[group]; err != nil {
+			errs = append(errs, fmt.Errorf("group %s: %w", group, err))
+		}
+	}
+	if r.CredentialsErr != nil {
+		errs = append(errs, fmt.Errorf("credentials: %w", r.CredentialsErr))
+	}
+	return errors.Join(errs...)
+}
+
+type deviceCleanup struct {
+	pollInterval     time.Duration
+	timeout          time.Duration
+	removeFromGroups bool
+	credentialsStore store.Store
+}
+
+type deviceCleanupOption func(*deviceCleanup)
+
+// Sets how often the realm is polled while waiting for a device to be deleted. Defaults to 1 second.
+// nolint:golint,revive
+func WithDeletionPollInterval(interval time.Duration) deviceCleanupOption {
+	return func(d *deviceCleanup) {
+		d.pollInterval = interval
+	}
+}
+
+// Sets how long to wait for a device to be deleted before giving up. Defaults to 1 minute.
+// nolint:golint,revive
+func WithDeletionTimeout(timeout time.Duration) deviceCleanupOption {
+	return func(d *deviceCleanup) {
+		d.timeout = timeout
+	}
+}
+
+// Sets whether the device is removed from the groups it belonged to once it is deleted. Defaults to false.
+// nolint:golint,revive
+func WithGroupCleanup(removeFromGroups bool) deviceCleanupOption {
+	return func(d *deviceCleanup) {
+		d.removeFromGroups = removeFromGroups
+	}
+}
+
+// Sets the store the pairing credentials of the device are deleted from once it is deleted. By default,
+// no credentials are deleted.
+// nolint:golint,revive
+func WithCredentialsCleanup(s store.Store) deviceCleanupOption {
+	return func(d *deviceCleanup) {
+		d.credentialsStore = s
+	}
+}
+
+func newDeviceCleanup(opts []deviceCleanupOption) (deviceCleanup, error) {
+	cleanup := deviceCleanup{pollInterval: defaultDeletionPollInterval, timeout: defaultDeletionTimeout}
+	for _, f := range opts {
+		f(&cleanup)
+	}
+	if cleanup.pollInterval <= 0 || cleanup.timeout <= 0 {
+		return cleanup, errors.New("Poll interval and timeout must be strictly positive durations")
+	}
+	return cleanup, nil
+}
+
+// WaitForDeviceDeletion polls the realm until deviceID disappears from it, e.g. after running the request
+// built by DeleteDevice, and returns how long it took. ErrDeviceDeletionTimeout is returned if the device still
+// exists when the timeout set with WithDeletionTimeout expires. Only the timing options are used.
+func (c *Client) WaitForDeviceDeletion(realm, deviceID string, opts ...deviceCleanupOption) (time.Duration, error) {
+	cleanup, err := newDeviceCleanup(opts)
+	if err != nil {
+		return 0, err
+	}
+	return c.waitForDeviceDeletion(realm, deviceID, cleanup)
+}
+
+func (c *Client) waitForDeviceDeletion(realm, deviceID string, cleanup deviceCleanup) (time.Duration, error) {
+	start := time.Now()
+	deadline := start.Add(cleanup.timeout)
+	for {
+		deleted, err := c.isDeviceDeleted(realm, deviceID)
+		if err != nil {
+			return time.Since(start), err
+		}
+		if deleted {
+			return time.Since(start), nil
+		}
+		if time.Now().Add(cleanup.pollInterval).After(deadline) {
+			return time.Since(start), ErrDeviceDeletionTimeout
+		}
+		time.Sleep(cleanup.pollInterval)
+	}
+}
+
+func (c *Client) isDeviceDeleted(realm, deviceID string) (bool, error) {
+	getDeviceCall, err := c.GetDeviceDetails(realm, deviceID, AstarteDeviceID)
+	if err != nil {
+		return false, err
+	}
+	_, err = runner.RunAndParse[DeviceDetails](c, getDeviceCall.Run)
+	switch {
+	case err == nil:
+		return false, nil
+	case errors.Is(err, ErrDeviceNotFound):
+		return true, nil
+	}
+	return false, err
+}
+
+// DeleteDeviceAndCleanup deletes deviceID from realm, waits until the deletion is completed as
+// WaitForDeviceDeletion does, and then cleans up what is left of the device: with WithGroupCleanup the
+// device is removed from the groups it belonged to, and with WithCredentialsCleanup its pairing credentials
+// are deleted from the store. The returned report holds the outcome of each step. The returned error is set if
+// the device could not be deleted, in which case no cleanup is performed, or if any cleanup step failed.
+// The Client must be authorized to access both AppEngine and Realm Management APIs.
+func (c *Client) DeleteDeviceAndCleanup(realm, deviceID string, opts ...deviceCleanupOption) (DeviceCleanupReport, error) {
+	report := DeviceCleanupReport{DeviceID: deviceID}
+	cleanup, err := newDeviceCleanup(opts)
+	if err != nil {
+		return report, err
+	}
+	deleteDeviceCall, err := c.DeleteDevice(realm, deviceID)
+	if err != nil {
+		return report, err
+	}
+	if cleanup.removeFromGroups {
+		// Group memberships must be retrieved before the device disappears
+		getDeviceCall, err := c.GetDeviceDetails(realm, deviceID, AstarteDeviceID)
+		if err != nil {
+			return report, err
+		}
+		details, err := runner.RunAndParse[DeviceDetails](c, getDeviceCall.Run)
+		if err != nil {
+			return report, err
+		}
+		report.Groups = details.Groups
+	}
+
+	if _, err := runner.RunAndParse[any](c, deleteDeviceCall.Run); err != nil {
+		return report, err
+	}
+	if report.DeletionTime, err = c.waitForDeviceDeletion(realm, deviceID, cleanup); err != nil {
+		return report, err
+	}
+	report.Deleted = true
+
+	for _, group := range report.Groups {
+		if err := c.removeDeviceFromGroup(realm, group, deviceID); err != nil {
+			if report.GroupErrors == nil {
+				report.GroupErrors = map[string]error{}
+			}
+			report.GroupErrors[group] = err
+		}
+	}
+	if cleanup.credentialsStore != nil {
+		report.CredentialsErr = cleanup.credentialsStore.Delete(realm, deviceID)
+		report.CredentialsDeleted = report.CredentialsErr == nil
+	}
+	return report, report.Err()
+}
+
+func (c *Client) removeDeviceFromGroup(realm, groupName, deviceID string) error {
+	removeCall, err := c.RemoveDeviceFromGroup(realm, groupName, deviceID)
+	if err != nil {
+		return err
+	}
+	_, err = runner.RunAndParse[any](c, removeCall.Run)
+	return err
+}
@@ -0,0 +1,26 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package client provides a client for the Astarte APIs: AppEngine, Housekeeping, Pairing and Realm Management.
+//
+// Most methods of Client build an AstarteRequest without sending it. The request is sent by its Run method,
+// and its response is parsed by Parse: in between, requests can be inspected, e.g. with ToCurl, or wrapped,
+// e.g. with Tolerant, Timeout, Compressed or Cancelable.
+//
+// # Functions running requests
+//
+// Higher-level functions combining many requests, such as FindDevices, BootstrapRealm or ResolveAliases,
+// return their results rather than an AstarteRequest: they run the requests they build with the Client they
+// are called on, which must be authorized to access all the APIs they use.
+package client
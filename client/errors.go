@@ -20,6 +20,12 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/interfaces"
 )
 
 var (
@@ -34,33 +40,279 @@ var (
 	ErrNoAuthProvided                = errors.New("Neither an Astarte JWT nor an Astarte private key were provided")
 	ErrBothJWTAndPrivateKey          = errors.New("Can't provide both an Astarte JWT and an Astarte private key")
 	ErrExpiryButNoPrivateKeyProvided = errors.New("Expiry was set, but no Astarte private key provided")
+	ErrTokenOptionsButNoPrivateKey   = errors.New("Token options were set, but no Astarte private key provided")
+	ErrDeviceLimitReached            = errors.New("The device registration limit of the realm has been reached")
+	ErrInvalidValidationLevel        = errors.New("Invalid validation level")
+	ErrUnknownAttributeKey           = errors.New("Attribute key does not match any rule of the attribute schema")
+	ErrEmptyIntrospectionPatch       = errors.New("The introspection patch contains no changes")
+	ErrInvalidBrokerURL              = errors.New("Invalid broker URL")
+	ErrInvalidRequestCompression     = errors.New("Invalid request compression")
+	ErrInvalidTimestampPrecision     = errors.New("Invalid timestamp precision")
+	ErrAmbiguousIdentifier           = errors.New("The device identifier could be both a Device ID and an alias")
+	ErrEmptyRealmSettingsPatch       = errors.New("The realm settings patch contains no changes")
+	ErrUnsupportedService            = errors.New("The client does not send requests to this service")
+	ErrNoEstimatedTotal              = errors.New("No estimate of the total number of devices is available")
+	ErrInvalidAstarteVersion         = errors.New("Invalid Astarte version")
+	ErrUnsupportedAstarteVersion     = errors.New("The operation is not supported by the Astarte version of the client")
+	ErrInvalidRawPayload             = errors.New("The raw payload is not valid JSON")
+	ErrDeviceDeletionTimeout         = errors.New("Timed out waiting for the device to be deleted")
+	ErrNonJSONResponse               = errors.New("The response is not JSON")
+	ErrInvalidMaxPageSize            = errors.New("The maximum page size must be positive")
+	ErrNotNumeric                    = errors.New("The value is not a number")
+	ErrUnsafeInteger                 = errors.New("The integer exceeds the precision of float64")
+	ErrIntegerOverflow               = errors.New("The number does not fit in an int64")
+	ErrInterfaceInUse                = errors.New("Some devices still declare the interface in their introspection")
+	ErrInterfaceDataRetained         = errors.New("Data on the interface is retained with no expiry")
+	ErrInvalidResultSetOrder         = errors.New("Invalid result set order")
+	ErrSinceWithDescendingOrder      = errors.New("A since time can't be specified when using DescendingOrder")
+	ErrDescendingOrderNeedsPageSize  = errors.New("A positive page size must be specified when using DescendingOrder")
+	ErrInvalidLabel                  = errors.New("Invalid label")
+	ErrInvalidLabelSelector          = errors.New("Invalid label selector")
+	ErrTimestampRegression           = errors.New("Timestamp is earlier than the last one sent on the same path")
+	ErrIntrospectionNotPatched       = errors.New("Astarte did not apply the introspection patch")
+)
+
+// Well-known failures reported by Astarte. They can be matched with errors.Is against the APIError returned
+// when running a request.
+var (
+	ErrUnauthorized                  = errors.New("Unauthorized")
+	ErrForbidden                     = errors.New("Forbidden")
+	ErrRealmClaimMismatch            = errors.New("The token is not valid for the realm")
+	ErrRealmNotFound                 = errors.New("Realm not found")
+	ErrDeviceNotFound                = errors.New("Device not found")
+	ErrInterfaceNotFound             = errors.New("Interface not found")
+	ErrInterfaceMajorVersionNotFound = errors.New("Interface major version does not exist")
+	ErrInterfaceAlreadyInstalled     = errors.New("Interface already installed")
+	ErrPathNotFound                  = errors.New("Path not found")
+	ErrCannotWriteToDeviceOwned      = errors.New("Cannot write to a device-owned interface")
+	ErrUnexpectedValueType           = errors.New("Unexpected value type")
+	ErrAlreadyRegistered             = errors.New("The device is already registered")
+	ErrCredentialsInhibited          = errors.New("The credentials requests of the device are inhibited")
 )
 
+// apiErrorKinds maps the errors reported by Astarte, normalized to snake_case, to well-known failures.
+// More specific entries come first.
+var apiErrorKinds = []struct {
+	match string
+	kind  error
+	hint  string
+}{
+	{"interface_major_version_does_not_exist", ErrInterfaceMajorVersionNotFound,
+		"check the installed major versions of the interface with ListInterfaceMajorVersions"},
+	{"interface_not_found", ErrInterfaceNotFound,
+		"check the interface name, and that the interface is installed in the realm with ListInterfaces"},
+	{"already_installed_interface", ErrInterfaceAlreadyInstalled,
+		"use UpdateInterface to install a new minor version, or bump the major version"},
+	{"interface_already_exists", ErrInterfaceAlreadyInstalled,
+		"use UpdateInterface to install a new minor version, or bump the major version"},
+	{"path_not_found", ErrPathNotFound,
+		"check that the path matches a mapping of the interface, and that a value was published on it"},
+	{"already_registered", ErrAlreadyRegistered,
+		"the credentials secret of a registered device cannot be obtained again: unregister the device with UnregisterDevice to register it anew"},
+	{"credentials_request_not_allowed", ErrCredentialsInhibited,
+		"enable the credentials requests of the device with SetDeviceInhibited"},
+	{"inhibited", ErrCredentialsInhibited,
+		"enable the credentials requests of the device with SetDeviceInhibited"},
+	{"device_not_found", ErrDeviceNotFound,
+		"check the device ID or alias, and that the device is registered in the realm"},
+	{"realm_not_found", ErrRealmNotFound,
+		"check the realm name, realms can be listed with ListRealms"},
+	{"realm_claim", ErrRealmClaimMismatch,
+		"check that the token was generated with the private key of the realm and for the same realm name"},
+	{"cannot_write_to_device_owned", ErrCannotWriteToDeviceOwned,
+		"only the device can publish data on device-owned interfaces, read it with GetProperty or the datastream functions"},
+	{"unexpected_value_type", ErrUnexpectedValueType,
+		"check that the value matches the type of the mapping, see SendData for client side validation"},
+}
+
+// APIError is returned when Astarte replies to a request with an unexpected status code. Well-known failures
+// can be matched with errors.Is (e.g. errors.Is(err, ErrInterfaceNotFound)), and come with a Hint on how to fix them.
+type APIError struct {
+	StatusCode int
+	// Errors holds the "errors" object of the response, if it could be decoded
+	Errors map[string]any
+	// Kind is the well-known failure, if recognized
+	Kind error
+	// Hint is a human-friendly remediation for Kind, if any
+	Hint string
+}
+
+// Detail returns the "detail" field of the errors reported by Astarte, if any.
+func (e *APIError) Detail() string {
+	detail, _ := e.Errors["detail"].(string)
+	return detail
+}
+
+func (e *APIError) Error() string {
+	message := fmt.Sprintf("Received unexpected status code %d", e.StatusCode)
+	if e.Errors != nil {
+		errJSON, _ := json.MarshalIndent(map[string]any{"errors": e.Errors}, "", "  ")
+		message = string(errJSON)
+	}
+	if e.Hint != "" {
+		message += "\nHint: " + e.Hint
+	}
+	return message
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Kind
+}
+
+func newAPIError(statusCode int, responseBody io.Reader) *APIError {
+	apiError := &APIError{StatusCode: statusCode}
+	var errorBody struct {
+		Errors map[string]any `json:"errors"`
+	}
+	if err := json.NewDecoder(responseBody).Decode(&errorBody); err == nil {
+		apiError.Errors = errorBody.Errors
+	}
+	apiError.Kind, apiError.Hint = classifyAPIError(statusCode, apiError.Errors)
+	return apiError
+}
+
+func classifyAPIError(statusCode int, errs map[string]any) (error, string) {
+	if len(errs) > 0 {
+		raw, _ := json.Marshal(errs)
+		normalized := strings.NewReplacer(" ", "_", "-", "_").Replace(strings.ToLower(string(raw)))
+		for _, k := range apiErrorKinds {
+			if strings.Contains(normalized, k.match) {
+				return k.kind, k.hint
+			}
+		}
+	}
+	switch statusCode {
+	case http.StatusUnauthorized:
+		return ErrUnauthorized, "check that the token is valid and not expired"
+	case http.StatusForbidden:
+		return ErrForbidden, "check that the claims of the token grant access to this API and realm"
+	}
+	return nil, ""
+}
+
 func ErrInvalidDeviceID(deviceID string) error {
 	return fmt.Errorf("%s is not a valid Astarte device ID", deviceID)
 }
 
+func ErrInvalidGroupName(groupName string) error {
+	return fmt.Errorf("%s is not a valid Astarte group name", groupName)
+}
+
+func ErrInvalidInterfaceName(interfaceName string) error {
+	return fmt.Errorf("%s is not a valid Astarte interface name", interfaceName)
+}
+
+func ErrExplicitTimestampNotAllowed(interfaceName, interfacePath string) error {
+	return fmt.Errorf("%s%s does not allow explicit timestamps", interfaceName, interfacePath)
+}
+
+// ErrUnsetNotAllowed is returned when trying to unset a property whose mapping does not allow unset.
+func ErrUnsetNotAllowed(interfaceName, interfacePath string) error {
+	return fmt.Errorf("%s%s does not allow unset: the property can only be set to a new value", interfaceName, interfacePath)
+}
+
+// ErrDeviceOwnedInterface is returned when trying to send data to a device-owned interface. Data on
+// device-owned interfaces is published by the device, so the error suggests the API to read it instead.
+func ErrDeviceOwnedInterface(astarteInterface interfaces.AstarteInterface) error {
+	suggestion := "GetProperty or GetAllProperties"
+	switch {
+	case astarteInterface.Type != interfaces.DatastreamType:
+	case astarteInterface.Aggregation == interfaces.ObjectAggregation:
+		suggestion = "GetDatastreamObjectSnapshot or GetDatastreamObjectPaginator"
+	default:
+		suggestion = "GetDatastreamIndividualSnapshot or GetDatastreamIndividualPaginator"
+	}
+	return fmt.Errorf("cannot send data to device-owned interface %s %d.%d: only the device can publish data on it, use %s to read its values",
+		astarteInterface.Name, astarteInterface.MajorVersion, astarteInterface.MinorVersion, suggestion)
+}
+
 func ErrDifferentStatusCode(expected, received int) error {
 	return fmt.Errorf("Received unexpeced status code: %d instead of %d", received, expected)
 }
 
-func errorFromJSONErrors(responseBody io.Reader) error {
-	var errorBody struct {
-		Errors map[string]interface{} `json:"errors"`
+func ErrUnexpectedStatusCode(expected []int, received int) error {
+	if len(expected) == 1 {
+		return ErrDifferentStatusCode(expected[0], received)
 	}
+	return fmt.Errorf("Received unexpected status code: %d instead of one of %v", received, expected)
+}
 
-	err := json.NewDecoder(responseBody).Decode(&errorBody)
-	if err != nil {
-		return err
-	}
+func ErrAttributeValueMismatch(pattern *regexp.Regexp) error {
+	return fmt.Errorf("Attribute value does not match %s", pattern)
+}
+
+func ErrAttributeValueNotAllowed(allowed []string) error {
+	return fmt.Errorf("Attribute value is not one of %v", allowed)
+}
+
+// AttributeSchemaViolation is returned when a Device attribute is not valid according
+// to the AttributeSchema of the client.
+type AttributeSchemaViolation struct {
+	Key   string
+	Value string
+	Err   error
+}
+
+func (e *AttributeSchemaViolation) Error() string {
+	return fmt.Sprintf("Invalid attribute %s=%q: %v", e.Key, e.Value, e.Err)
+}
+
+func (e *AttributeSchemaViolation) Unwrap() error {
+	return e.Err
+}
+
+// NonJSONResponseError is returned when a response is not JSON, e.g. when a reverse proxy in front of Astarte
+// replies with an HTML error page. It can be matched with errors.Is(err, ErrNonJSONResponse).
+type NonJSONResponseError struct {
+	StatusCode  int
+	ContentType string
+	// Body holds the first bytes of the response body
+	Body string
+}
+
+func (e *NonJSONResponseError) Error() string {
+	return fmt.Sprintf("Received a non-JSON response with status code %d and content type %q: %q",
+		e.StatusCode, e.ContentType, e.Body)
+}
+
+func (e *NonJSONResponseError) Unwrap() error {
+	return ErrNonJSONResponse
+}
+
+// PageError is returned when a paginator fails to fetch a page. It describes the page, so that long-running
+// exports can report where they failed and resume from there, e.g. with DatastreamPaginator.Seek. The error
+// which caused the failure can be retrieved with errors.As or errors.Is, e.g. to get an APIError.
+type PageError struct {
+	// URL is the URL of the page, including its query
+	URL   string
+	Query url.Values
+	// Since and To are the bounds of the time window of the page, for datastream paginators. Either of them is
+	// zero if the window is open on that side.
+	Since time.Time
+	To    time.Time
+	// PageIndex is the index of the page since the paginator was created, rewound or moved, starting from 0
+	PageIndex int
+	Err       error
+}
+
+func (e *PageError) Error() string {
+	return fmt.Sprintf("Cannot fetch page %d (%s): %v", e.PageIndex, e.URL, e.Err)
+}
+
+func (e *PageError) Unwrap() error {
+	return e.Err
+}
 
-	errJSON, _ := json.MarshalIndent(&errorBody, "", "  ")
-	return fmt.Errorf("%s", errJSON)
+// wrap returns a copy of e caused by err.
+func (e PageError) wrap(err error) error {
+	e.Err = err
+	return &e
 }
 
-func runAstarteRequestError(res *http.Response, expectedCode int) (AstarteResponse, error) {
+func runAstarteRequestError(res *http.Response, expectedCodes []int) (AstarteResponse, error) {
 	if res.Body != nil {
-		return Empty{}, errorFromJSONErrors(res.Body)
+		defer res.Body.Close()
+		return Empty{}, newAPIError(res.StatusCode, res.Body)
 	}
-	return Empty{}, ErrDifferentStatusCode(expectedCode, res.StatusCode)
+	return Empty{}, ErrUnexpectedStatusCode(expectedCodes, res.StatusCode)
 }
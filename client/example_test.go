@@ -0,0 +1,145 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client_test
+
+import (
+	"fmt"
+
+	"github.com/astarte-platform/astarte-go/client"
+	"github.com/astarte-platform/astarte-go/interfaces"
+)
+
+func ExampleNew() {
+	// The client generates tokens valid for 60 seconds from the realm private key
+	c, err := client.New(
+		client.WithBaseURL("https://api.astarte.example.com"),
+		client.WithPrivateKey("/path/to/realm_private.pem"),
+		client.WithExpiry(60),
+	)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println(c.GetAppengineURL())
+}
+
+func ExampleClient_GetDeviceListPaginator() {
+	c, err := client.New(
+		client.WithBaseURL("https://api.astarte.example.com"),
+		client.WithJWT("a JWT"),
+	)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	paginator, err := c.GetDeviceListPaginator("myrealm", 100, client.DeviceDetailsFormat)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	for paginator.HasNextPage() {
+		nextPageReq, err := paginator.GetNextPage()
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		nextPageRes, err := nextPageReq.Run(c)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		rawNextPageData, err := nextPageRes.Parse()
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		devices, _ := rawNextPageData.([]client.DeviceDetails)
+		for _, d := range devices {
+			fmt.Printf("Device: %s, connected: %v\n", d.DeviceID, d.Connected)
+		}
+	}
+}
+
+func ExampleClient_SendData() {
+	c, err := client.New(
+		client.WithBaseURL("https://api.astarte.example.com"),
+		client.WithJWT("a JWT"),
+	)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// A server-owned datastream interface with object aggregation
+	iface, err := interfaces.ParseInterface([]byte(`{
+		"interface_name": "org.astarte-platform.genericcommands.Configuration",
+		"version_major": 1,
+		"version_minor": 0,
+		"type": "datastream",
+		"ownership": "server",
+		"aggregation": "object",
+		"mappings": [
+			{"endpoint": "/%{device}/threshold", "type": "double"},
+			{"endpoint": "/%{device}/enabled", "type": "boolean"}
+		]
+	}`))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// Object-aggregated data must be a map[string]any, whose keys are the last token of mapping endpoints
+	payload := map[string]any{"threshold": 42.5, "enabled": true}
+	sendDataReq, err := c.SendData("myrealm", "glO6LullTKmwxebForU-eg", client.AstarteDeviceID, iface, "/thermostat", payload)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if _, err := sendDataReq.Run(c); err != nil {
+		fmt.Println(err)
+	}
+}
+
+func ExampleClient_InstallInterface() {
+	c, err := client.New(
+		client.WithBaseURL("https://api.astarte.example.com"),
+		client.WithPrivateKey("/path/to/realm_private.pem"),
+	)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	iface, err := interfaces.ParseInterfaceFrom("/path/to/org.astarte-platform.genericsensors.Values.json")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// Install the interface synchronously, so that it can be used as soon as the request returns
+	installReq, err := c.InstallInterface("myrealm", iface, false)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	// The curl command equivalent to the request can be printed for debugging purposes
+	fmt.Println(installReq.ToCurl(c))
+	if _, err := installReq.Run(c); err != nil {
+		fmt.Println(err)
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"testing"
+
+	"github.com/astarte-platform/astarte-go/interfaces"
+	"github.com/tidwall/gjson"
+)
+
+func FuzzParseDatastreamSnapshot(f *testing.F) {
+	f.Add([]byte(`{"s1": {"temperature": {"value": 300.15, "timestamp": "2022-09-26T14:37:00.468Z"}}}`))
+	f.Add([]byte(`{"sensors": {"s1": [{"temperature": 300.15, "timestamp": "2022-09-26T14:37:00.468Z"}]}}`))
+	f.Add([]byte(`{"sensors": {"s1": []}}`))
+	f.Add([]byte(`[{"value": 1, "timestamp": 1664203020468}]`))
+	f.Add([]byte(`"not an object"`))
+	f.Add([]byte(``))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, aggregation := range []interfaces.AstarteInterfaceAggregation{interfaces.IndividualAggregation, interfaces.ObjectAggregation} {
+			_, _ = parseDatastreamSnapshot(data, aggregation, false)
+			_ = parseDatastream(gjson.ParseBytes(data), aggregation, false)
+		}
+	})
+}
@@ -15,75 +15,342 @@
 package client
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"sort"
+	"sync"
 
+	"github.com/astarte-platform/astarte-go/interfaces"
+	"github.com/astarte-platform/astarte-go/internal/runner"
+	"github.com/astarte-platform/astarte-go/internal/urlbuilder"
 	"moul.io/http2curl"
 )
 
 type ListRealmsRequest struct {
 	req     *http.Request
-	expects int
+	expects []int
 }
 
 // ListRealms builds a request to list all realms in the cluster.
 func (c *Client) ListRealms() (AstarteRequest, error) {
-	callURL := makeURL(c.housekeepingURL, "/v1/realms")
+	callURL := urlbuilder.Build(c.housekeepingURL, "/v1/realms")
 	req := c.makeHTTPrequest(http.MethodGet, callURL, nil)
 
-	return ListRealmsRequest{req: req, expects: 200}, nil
+	return ListRealmsRequest{req: req, expects: []int{http.StatusOK}}, nil
 }
 
 // nolint:bodyclose
 func (r ListRealmsRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return runAstarteRequestError(res, r.expects)
 	}
 	return ListRealmsResponse{res: res}, nil
 }
 
 func (r ListRealmsRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	return fmt.Sprint(command)
 }
 
 type GetRealmRequest struct {
 	req     *http.Request
-	expects int
+	expects []int
 }
 
 // GetRealm builds a request to get data about a single Realm.
 func (c *Client) GetRealm(realm string) (AstarteRequest, error) {
-	callURL := makeURL(c.housekeepingURL, "/v1/realms/%s", realm)
+	callURL := urlbuilder.Build(c.housekeepingURL, "/v1/realms/%s", realm)
 	req := c.makeHTTPrequest(http.MethodGet, callURL, nil)
 
-	return GetRealmRequest{req: req, expects: 200}, nil
+	return GetRealmRequest{req: req, expects: []int{http.StatusOK}}, nil
 }
 
 // nolint:bodyclose
 func (r GetRealmRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return runAstarteRequestError(res, r.expects)
 	}
 	return GetRealmResponse{res: res}, nil
 }
 
 func (r GetRealmRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	return fmt.Sprint(command)
 }
 
+// RealmExists returns whether realm exists in the cluster. The Client must be authorized to access the Housekeeping API.
+func (c *Client) RealmExists(realm string) (bool, error) {
+	getRealmCall, _ := c.GetRealm(realm)
+	getRealmRequest := getRealmCall.(GetRealmRequest)
+	getRealmRequest.expects = []int{http.StatusOK, http.StatusNotFound}
+	res, err := getRealmRequest.Run(c)
+	if err != nil {
+		return false, err
+	}
+	_ = res.Raw(func(*http.Response) any { return nil })
+	return res.StatusCode() != http.StatusNotFound, nil
+}
+
+// ListRealmSummaries returns the RealmSummary of the realms in the cluster for which filter returns true, or
+// of all realms if filter is nil, sorted by name. Housekeeping does not paginate realms, so their names are listed
+// in a single request, while their details are retrieved running up to concurrency requests at a time
+// (10 if concurrency is not positive). Summaries are returned for the realms whose details could be retrieved,
+// while the returned error joins the errors of the other ones.
+func (c *Client) ListRealmSummaries(ctx context.Context, filter func(realm string) bool, concurrency int) ([]RealmSummary, error) {
+	listRealmsCall, _ := c.ListRealms()
+	realms, err := runner.RunAndParse[[]string](c, listRealmsCall.Run)
+	if err != nil {
+		return nil, err
+	}
+	if filter != nil {
+		filtered := []string{}
+		for _, realm := range realms {
+			if filter(realm) {
+				filtered = append(filtered, realm)
+			}
+		}
+		realms = filtered
+	}
+
+	summaries := make([]RealmSummary, 0, len(realms))
+	var mu sync.Mutex
+	report := ForEachRealm(ctx, realms, func(ctx context.Context, realm string) error {
+		getRealmCall, _ := c.GetRealm(realm)
+		details, err := runner.RunAndParse[RealmDetails](c, Cancelable(ctx, getRealmCall).Run)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		summaries = append(summaries, details.Summary())
+		return nil
+	}, concurrency)
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Name < summaries[j].Name })
+	return summaries, report.Err()
+}
+
+// GetRealmDeviceQuota returns the device registration quota of a Realm, combining the Realm details
+// from Housekeeping with the devices stats from AppEngine. The Client must be authorized to access both APIs.
+func (c *Client) GetRealmDeviceQuota(realm string) (RealmDeviceQuota, error) {
+	getRealmCall, _ := c.GetRealm(realm)
+	realmDetails, err := runner.RunAndParse[RealmDetails](c, getRealmCall.Run)
+	if err != nil {
+		return RealmDeviceQuota{}, err
+	}
+
+	getDevicesStatsCall, _ := c.GetDevicesStats(realm)
+	stats, err := runner.RunAndParse[DevicesStats](c, getDevicesStatsCall.Run)
+	if err != nil {
+		return RealmDeviceQuota{}, err
+	}
+
+	quota := RealmDeviceQuota{DeviceRegistrationLimit: realmDetails.DeviceRegistrationLimit, TotalDevices: stats.TotalDevices}
+	if quota.DeviceRegistrationLimit != nil {
+		remaining := int64(*quota.DeviceRegistrationLimit) - stats.TotalDevices
+		if remaining < 0 {
+			remaining = 0
+		}
+		quota.RemainingRegistrations = &remaining
+	}
+	return quota, nil
+}
+
+// GetInterfaceRetentionLimits returns the interfaces.RetentionLimits for the interfaces of a Realm, taking into account
+// its maximum datastream storage retention, if any. The result can be used with interfaces.ValidateRetention
+// before installing an interface. The Client must be authorized to access the Housekeeping API.
+func (c *Client) GetInterfaceRetentionLimits(realm string) (interfaces.RetentionLimits, error) {
+	limits := interfaces.DefaultRetentionLimits()
+	getRealmCall, _ := c.GetRealm(realm)
+	realmDetails, err := runner.RunAndParse[RealmDetails](c, getRealmCall.Run)
+	if err != nil {
+		return limits, err
+	}
+	if maxRetention := realmDetails.DatastreamMaximumStorageRetention; maxRetention != nil && *maxRetention > 0 && *maxRetention < limits.MaxDatabaseRetentionTTL {
+		limits.MaxDatabaseRetentionTTL = *maxRetention
+	}
+	return limits, nil
+}
+
+// RealmSettingsPatch describes a change to the settings of a realm. Settings which are neither set nor removed
+// are left unchanged.
+type RealmSettingsPatch struct {
+	// DeviceRegistrationLimit sets the maximum number of devices which can be registered in the realm.
+	DeviceRegistrationLimit *int
+	// RemoveDeviceRegistrationLimit removes the device registration limit of the realm.
+	RemoveDeviceRegistrationLimit bool
+	// DatastreamMaximumStorageRetention sets the maximum database retention TTL of datastreams, in seconds.
+	// Not all Astarte versions support changing it.
+	DatastreamMaximumStorageRetention *int
+	// RemoveDatastreamMaximumStorageRetention removes the maximum database retention TTL of datastreams.
+	RemoveDatastreamMaximumStorageRetention bool
+	// JwtPublicKeyPEM replaces the public key of the realm, if not empty.
+	JwtPublicKeyPEM string
+}
+
+// Validate checks that p is not empty, that no setting is both set and removed, that the device registration
+// limit is not negative and that the datastream maximum storage retention is positive.
+func (p RealmSettingsPatch) Validate() error {
+	if p.DeviceRegistrationLimit == nil && !p.RemoveDeviceRegistrationLimit && p.DatastreamMaximumStorageRetention == nil &&
+		!p.RemoveDatastreamMaximumStorageRetention && p.JwtPublicKeyPEM == "" {
+		return ErrEmptyRealmSettingsPatch
+	}
+	if p.DeviceRegistrationLimit != nil && p.RemoveDeviceRegistrationLimit {
+		return errors.New("The device registration limit can't be both set and removed")
+	}
+	if p.DeviceRegistrationLimit != nil && *p.DeviceRegistrationLimit < 0 {
+		return fmt.Errorf("Invalid device registration limit %d", *p.DeviceRegistrationLimit)
+	}
+	if p.DatastreamMaximumStorageRetention != nil && p.RemoveDatastreamMaximumStorageRetention {
+		return errors.New("The datastream maximum storage retention can't be both set and removed")
+	}
+	if p.DatastreamMaximumStorageRetention != nil && *p.DatastreamMaximumStorageRetention <= 0 {
+		return fmt.Errorf("Invalid datastream maximum storage retention %d", *p.DatastreamMaximumStorageRetention)
+	}
+	return nil
+}
+
+// mergePatch returns the JSON merge patch of the realm details described by p.
+func (p RealmSettingsPatch) mergePatch() map[string]any {
+	patch := map[string]any{}
+	switch {
+	case p.DeviceRegistrationLimit != nil:
+		patch["device_registration_limit"] = *p.DeviceRegistrationLimit
+	case p.RemoveDeviceRegistrationLimit:
+		patch["device_registration_limit"] = nil
+	}
+	switch {
+	case p.DatastreamMaximumStorageRetention != nil:
+		patch["datastream_maximum_storage_retention"] = *p.DatastreamMaximumStorageRetention
+	case p.RemoveDatastreamMaximumStorageRetention:
+		patch["datastream_maximum_storage_retention"] = nil
+	}
+	if p.JwtPublicKeyPEM != "" {
+		patch["jwt_public_key_pem"] = p.JwtPublicKeyPEM
+	}
+	return patch
+}
+
+type UpdateRealmRequest struct {
+	req     *http.Request
+	expects []int
+	realm   string
+}
+
+// UpdateRealm builds a request to update the settings of a Realm according to patch, which must be valid.
+func (c *Client) UpdateRealm(realm string, patch RealmSettingsPatch) (AstarteRequest, error) {
+	if err := patch.Validate(); err != nil {
+		return Empty{}, err
+	}
+	if patch.DatastreamMaximumStorageRetention != nil || patch.RemoveDatastreamMaximumStorageRetention {
+		if err := c.requireAstarteVersion("the datastream maximum storage retention", 1, 1); err != nil {
+			return Empty{}, err
+		}
+	}
+	callURL := urlbuilder.Build(c.housekeepingURL, "/v1/realms/%s", realm)
+	payload, _ := makeBody(patch.mergePatch())
+	req := c.makeHTTPrequestWithContentType(http.MethodPatch, callURL, payload, "application/merge-patch+json")
+
+	return UpdateRealmRequest{req: req, expects: []int{http.StatusOK}, realm: realm}, nil
+}
+
+// nolint:bodyclose
+func (r UpdateRealmRequest) Run(c *Client) (AstarteResponse, error) {
+	// The retention limits of the realm may change, see checkRetention
+	c.retentionLimitsCache.Delete(r.realm)
+	res, err := c.do(r.req)
+	if err != nil {
+		return Empty{}, err
+	}
+	if !c.isExpectedStatusCode(res, r.expects) {
+		return runAstarteRequestError(res, r.expects)
+	}
+	return UpdateRealmResponse{res: res}, nil
+}
+
+func (r UpdateRealmRequest) ToCurl(_ *Client) string {
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
+	return fmt.Sprint(command)
+}
+
+// RealmSettings are the settings of a realm which can be changed after its creation, see ModifyRealm.
+type RealmSettings struct {
+	// DeviceRegistrationLimit is nil if the realm has no device registration limit.
+	DeviceRegistrationLimit *int
+	// DatastreamMaximumStorageRetention is nil if the realm does not set one.
+	DatastreamMaximumStorageRetention *int
+	JwtPublicKeyPEM                   string
+}
+
+// ModifyRealm reads the settings of a realm, lets modify change them, and updates the realm with the changed
+// settings only, returning the details of the realm after the update. If modify returns an error, the realm is
+// not updated and the error is returned. If no setting is changed, the realm is not updated either.
+// The Client must be authorized to access the Housekeeping API.
+func (c *Client) ModifyRealm(realm string, modify func(settings *RealmSettings) error) (RealmDetails, error) {
+	getRealmCall, err := c.GetRealm(realm)
+	if err != nil {
+		return RealmDetails{}, err
+	}
+	details, err := runner.RunAndParse[RealmDetails](c, getRealmCall.Run)
+	if err != nil {
+		return RealmDetails{}, err
+	}
+
+	current := RealmSettings{
+		DeviceRegistrationLimit:           details.DeviceRegistrationLimit,
+		DatastreamMaximumStorageRetention: details.DatastreamMaximumStorageRetention,
+		JwtPublicKeyPEM:                   details.JwtPublicKeyPEM,
+	}
+	modified := current
+	if err := modify(&modified); err != nil {
+		return RealmDetails{}, err
+	}
+
+	patch := RealmSettingsPatch{}
+	if !equalOptionalInts(current.DeviceRegistrationLimit, modified.DeviceRegistrationLimit) {
+		patch.DeviceRegistrationLimit = modified.DeviceRegistrationLimit
+		patch.RemoveDeviceRegistrationLimit = modified.DeviceRegistrationLimit == nil
+	}
+	if !equalOptionalInts(current.DatastreamMaximumStorageRetention, modified.DatastreamMaximumStorageRetention) {
+		patch.DatastreamMaximumStorageRetention = modified.DatastreamMaximumStorageRetention
+		patch.RemoveDatastreamMaximumStorageRetention = modified.DatastreamMaximumStorageRetention == nil
+	}
+	if modified.JwtPublicKeyPEM != current.JwtPublicKeyPEM {
+		if modified.JwtPublicKeyPEM == "" {
+			return RealmDetails{}, ErrRealmPublicKeyNotProvided
+		}
+		patch.JwtPublicKeyPEM = modified.JwtPublicKeyPEM
+	}
+	if errors.Is(patch.Validate(), ErrEmptyRealmSettingsPatch) {
+		return details, nil
+	}
+
+	updateRealmCall, err := c.UpdateRealm(realm, patch)
+	if err != nil {
+		return RealmDetails{}, err
+	}
+	return runner.RunAndParse[RealmDetails](c, updateRealmCall.Run)
+}
+
+func equalOptionalInts(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
 type CreateRealmRequest struct {
 	req     *http.Request
-	expects int
+	expects []int
 }
 
 type newRealmRequestBuilder struct {
@@ -101,6 +368,7 @@ type realmOption func(*newRealmRequestBuilder)
 // replication factor > 1.
 // You can create a realm with:
 // c.NewRealm(client.WithRealmName("test"), client.WithRealmPublicKey("YOUR_REALM_PUBLIC_KEY"), client.WithReplicationFactor(3))
+// If Astarte accepts the request for later processing, Run returns an AsyncAcceptedResponse.
 func (c *Client) CreateRealm(opts ...realmOption) (AstarteRequest, error) {
 	newRealm := newRealmRequestBuilder{}
 	for _, f := range opts {
@@ -113,11 +381,11 @@ func (c *Client) CreateRealm(opts ...realmOption) (AstarteRequest, error) {
 
 	// TODO check if setting default replicationFactor is needed
 
-	callURL := makeURL(c.housekeepingURL, "/v1/realms")
+	callURL := urlbuilder.Build(c.housekeepingURL, "/v1/realms")
 	reqBody, _ := makeBody(newRealm)
 	req := c.makeHTTPrequest(http.MethodPost, callURL, reqBody)
 
-	return CreateRealmRequest{req: req, expects: 201}, nil
+	return CreateRealmRequest{req: req, expects: []int{http.StatusCreated, http.StatusOK}}, nil
 }
 
 func (r *newRealmRequestBuilder) validate() error {
@@ -175,17 +443,20 @@ func WithDatacenterReplicationFactors(datacenterReplicationFactors map[string]in
 
 // nolint:bodyclose
 func (r CreateRealmRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
+	if res.StatusCode == http.StatusAccepted {
+		return AsyncAcceptedResponse{res: res}, nil
+	}
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return runAstarteRequestError(res, r.expects)
 	}
 	return CreateRealmResponse{res: res}, nil
 }
 
 func (r CreateRealmRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	return fmt.Sprint(command)
 }
@@ -15,24 +15,18 @@
 package client
 
 import (
-	"encoding/json"
-	"io"
 	"net/http"
-
-	"github.com/tidwall/gjson"
 )
 
 // Parses data obtained by performing a request to list realms.
 // Returns the list of realms as an array of strings.
 func (r ListRealmsResponse) Parse() (any, error) {
 	defer r.res.Body.Close()
-	b, _ := io.ReadAll(r.res.Body)
-	body := string(b)
-	ret := []string{}
-	for _, v := range gjson.Get(body, "data").Array() {
-		ret = append(ret, v.Str)
+	payload, err := decodeAstartePayload(r.res.Body, []string{})
+	if err != nil {
+		return nil, err
 	}
-	return ret, nil
+	return payload.Data, nil
 }
 func (r ListRealmsResponse) Raw(f func(*http.Response) any) any {
 	defer r.res.Body.Close()
@@ -46,35 +40,79 @@ type RealmDetails struct {
 	ReplicationClass             string         `json:"replication_class,omitempty"`
 	ReplicationFactor            int            `json:"replication_factor,omitempty"`
 	DatacenterReplicationFactors map[string]int `json:"datacenter_replication_factors,omitempty"`
+	// DeviceRegistrationLimit is nil if the realm has no device registration limit.
+	DeviceRegistrationLimit *int `json:"device_registration_limit,omitempty"`
+	// DatastreamMaximumStorageRetention is the maximum database retention TTL of datastreams in the realm,
+	// in seconds. It is nil if the realm does not set one.
+	DatastreamMaximumStorageRetention *int `json:"datastream_maximum_storage_retention,omitempty"`
+}
+
+// RealmSummary summarizes a Realm, leaving out its public key and replication settings.
+type RealmSummary struct {
+	Name string
+	// DeviceRegistrationLimit is nil if the realm has no device registration limit.
+	DeviceRegistrationLimit *int
+	// DatastreamMaximumStorageRetention is nil if the realm does not set one.
+	DatastreamMaximumStorageRetention *int
+}
+
+// Summary returns the RealmSummary of the realm.
+func (d RealmDetails) Summary() RealmSummary {
+	return RealmSummary{
+		Name:                              d.Name,
+		DeviceRegistrationLimit:           d.DeviceRegistrationLimit,
+		DatastreamMaximumStorageRetention: d.DatastreamMaximumStorageRetention,
+	}
+}
+
+// RealmDeviceQuota represents the device registration quota of a Realm.
+type RealmDeviceQuota struct {
+	// DeviceRegistrationLimit is nil if the realm has no device registration limit.
+	DeviceRegistrationLimit *int
+	TotalDevices            int64
+	// RemainingRegistrations is nil if the realm has no device registration limit.
+	RemainingRegistrations *int64
 }
 
 // Parses data obtained by performing a request to get a realm's details.
 // Returns the details as a RealmDetails struct.
 func (r GetRealmResponse) Parse() (any, error) {
 	defer r.res.Body.Close()
-	b, _ := io.ReadAll(r.res.Body)
-	v := []byte(gjson.GetBytes(b, "data").Raw)
-	ret := RealmDetails{}
-	// TODO check err
-	_ = json.Unmarshal(v, &ret)
-	return ret, nil
-
+	payload, err := decodeAstartePayload(r.res.Body, RealmDetails{})
+	if err != nil {
+		return nil, err
+	}
+	return payload.Data, nil
 }
 func (r GetRealmResponse) Raw(f func(*http.Response) any) any {
 	defer r.res.Body.Close()
 	return f(r.res)
 }
 
+// Parses data obtained by performing a request to update a realm.
+// Returns the realm's updated details as a RealmDetails struct.
+func (r UpdateRealmResponse) Parse() (any, error) {
+	defer r.res.Body.Close()
+	payload, err := decodeAstartePayload(r.res.Body, RealmDetails{})
+	if err != nil {
+		return nil, err
+	}
+	return payload.Data, nil
+}
+func (r UpdateRealmResponse) Raw(f func(*http.Response) any) any {
+	defer r.res.Body.Close()
+	return f(r.res)
+}
+
 // Parses data obtained by performing a request to create a realm.
 // Returns the realm's details as a RealmDetails struct.
 func (r CreateRealmResponse) Parse() (any, error) {
 	defer r.res.Body.Close()
-	b, _ := io.ReadAll(r.res.Body)
-	v := []byte(gjson.GetBytes(b, "data").Raw)
-	ret := RealmDetails{}
-	// TODO check err
-	_ = json.Unmarshal(v, &ret)
-	return ret, nil
+	payload, err := decodeAstartePayload(r.res.Body, RealmDetails{})
+	if err != nil {
+		return nil, err
+	}
+	return payload.Data, nil
 }
 func (r CreateRealmResponse) Raw(f func(*http.Response) any) any {
 	defer r.res.Body.Close()
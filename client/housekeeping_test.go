@@ -15,7 +15,15 @@
 package client
 
 import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/internal/runner"
 )
 
 func TestListRealms(t *testing.T) {
@@ -83,3 +91,161 @@ func TestCreateRealm(t *testing.T) {
 		t.Error("Failed realm creations, different realm details")
 	}
 }
+
+func TestGetRealmDeviceQuota(t *testing.T) {
+	c, _ := getTestContext(t)
+	quota, err := c.GetRealmDeviceQuota(testRealmName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quota.DeviceRegistrationLimit == nil || *quota.DeviceRegistrationLimit != testDeviceRegistrationLimit {
+		t.Errorf("Unexpected device registration limit: %v", quota.DeviceRegistrationLimit)
+	}
+	if quota.TotalDevices != int64(testTotalDevices) {
+		t.Errorf("Unexpected total devices: %d", quota.TotalDevices)
+	}
+	if quota.RemainingRegistrations == nil || *quota.RemainingRegistrations != int64(testDeviceRegistrationLimit-testTotalDevices) {
+		t.Errorf("Unexpected remaining registrations: %v", quota.RemainingRegistrations)
+	}
+}
+
+func TestGetInterfaceRetentionLimits(t *testing.T) {
+	c, _ := getTestContext(t)
+	limits, err := c.GetInterfaceRetentionLimits(testRealmName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limits.MaxDatabaseRetentionTTL != testMaximumStorageRetention {
+		t.Errorf("Unexpected maximum database retention TTL: %d", limits.MaxDatabaseRetentionTTL)
+	}
+}
+
+func TestForEachRealm(t *testing.T) {
+	realms := []string{"a", "b", "c", "d", "e", "a"}
+	var running, maxRunning atomic.Int32
+	calls := sync.Map{}
+	report := ForEachRealm(context.Background(), realms, func(ctx context.Context, realm string) error {
+		if _, loaded := calls.LoadOrStore(realm, true); loaded {
+			t.Errorf("fn called more than once on %s", realm)
+		}
+		if ctx == nil {
+			t.Error("fn called without a context")
+		}
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			if m := maxRunning.Load(); n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		if realm == "c" {
+			return errors.New("ah yes, an error")
+		}
+		return nil
+	}, 2)
+
+	if !reflect.DeepEqual(report.Succeeded, []string{"a", "b", "d", "e"}) || !reflect.DeepEqual(report.Failed, []string{"c"}) {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if err := report.Err(); err == nil || err.Error() != "c: ah yes, an error" {
+		t.Errorf("Unexpected report error: %v", err)
+	}
+	if maxRunning.Load() > 2 {
+		t.Errorf("Ran %d calls at a time instead of at most 2", maxRunning.Load())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report = ForEachRealm(ctx, realms, func(context.Context, string) error { return nil }, 0)
+	if len(report.Succeeded) != 0 || !errors.Is(report.Errors["a"], context.Canceled) {
+		t.Errorf("Expected all realms to fail with a cancelled context: %+v", report)
+	}
+}
+
+func TestRealmExists(t *testing.T) {
+	c, _ := getTestContext(t)
+	if exists, err := c.RealmExists(testRealmName); err != nil || !exists {
+		t.Errorf("Expected %s to exist: %v", testRealmName, err)
+	}
+	if exists, err := c.RealmExists(testMissingRealmName); err != nil || exists {
+		t.Errorf("Expected %s not to exist: %v", testMissingRealmName, err)
+	}
+}
+
+func TestListRealmSummaries(t *testing.T) {
+	c, _ := getTestContext(t)
+	summaries, err := c.ListRealmSummaries(context.Background(), nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summaries) != 2 || summaries[0].Name != testRealmsList[1] || summaries[1].Name != testRealmName {
+		t.Fatalf("Unexpected realm summaries: %+v", summaries)
+	}
+	if summaries[0].DeviceRegistrationLimit != nil {
+		t.Errorf("Unexpected device registration limit for %s", summaries[0].Name)
+	}
+	if limit := summaries[1].DeviceRegistrationLimit; limit == nil || *limit != testDeviceRegistrationLimit {
+		t.Errorf("Unexpected device registration limit for %s: %v", summaries[1].Name, limit)
+	}
+
+	summaries, err = c.ListRealmSummaries(context.Background(), func(realm string) bool { return realm == testRealmName }, 1)
+	if err != nil || len(summaries) != 1 || summaries[0].Name != testRealmName {
+		t.Errorf("Unexpected filtered realm summaries: %+v, %v", summaries, err)
+	}
+}
+
+func TestUpdateRealm(t *testing.T) {
+	c, _ := getTestContext(t)
+	limit, retention := 100, 60
+	invalid := []RealmSettingsPatch{
+		{},
+		{DeviceRegistrationLimit: &limit, RemoveDeviceRegistrationLimit: true},
+		{DatastreamMaximumStorageRetention: &retention, RemoveDatastreamMaximumStorageRetention: true},
+	}
+	for _, patch := range invalid {
+		if _, err := c.UpdateRealm(testRealmName, patch); err == nil {
+			t.Errorf("Expected an error for %+v, found nil", patch)
+		}
+	}
+
+	updateRealmCall, err := c.UpdateRealm(testRealmName, RealmSettingsPatch{DeviceRegistrationLimit: &limit, RemoveDatastreamMaximumStorageRetention: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	details, err := runner.RunAndParse[RealmDetails](c, updateRealmCall.Run)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details.DeviceRegistrationLimit == nil || *details.DeviceRegistrationLimit != limit || details.DatastreamMaximumStorageRetention != nil {
+		t.Errorf("Unexpected realm details: %+v", details)
+	}
+}
+
+func TestModifyRealm(t *testing.T) {
+	c, _ := getTestContext(t)
+	details, err := c.ModifyRealm(testRealmName, func(settings *RealmSettings) error {
+		if *settings.DeviceRegistrationLimit != testDeviceRegistrationLimit {
+			t.Errorf("Unexpected device registration limit: %d", *settings.DeviceRegistrationLimit)
+		}
+		settings.DeviceRegistrationLimit = nil
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details.DeviceRegistrationLimit != nil || details.DatastreamMaximumStorageRetention == nil || *details.DatastreamMaximumStorageRetention != testMaximumStorageRetention {
+		t.Errorf("Unexpected realm details: %+v", details)
+	}
+
+	expectedErr := errors.New("ah yes, an error")
+	if _, err := c.ModifyRealm(testRealmName, func(*RealmSettings) error { return expectedErr }); !errors.Is(err, expectedErr) {
+		t.Errorf("Expected %v, found %v", expectedErr, err)
+	}
+	if _, err := c.ModifyRealm(testRealmName, func(settings *RealmSettings) error {
+		settings.JwtPublicKeyPEM = ""
+		return nil
+	}); !errors.Is(err, ErrRealmPublicKeyNotProvided) {
+		t.Errorf("Expected ErrRealmPublicKeyNotProvided, found %v", err)
+	}
+}
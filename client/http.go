@@ -15,13 +15,17 @@
 package client
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"net/url"
-	"path"
+	"strings"
+	"time"
 )
 
 type AstarteRequest interface {
@@ -39,11 +43,121 @@ type Empty struct{}
 func (r Empty) Run(_ *Client) (AstarteResponse, error) { return Empty{}, nil }
 func (r Empty) ToCurl(_ *Client) string                { return "" }
 
+// The Tolerant function returns a request which runs req accepting any 2xx status code,
+// as if the client was created with WithTolerantStatusCodes.
+func Tolerant(req AstarteRequest) AstarteRequest {
+	return tolerantRequest{req: req}
+}
+
+type tolerantRequest struct {
+	req AstarteRequest
+}
+
+func (r tolerantRequest) Run(c *Client) (AstarteResponse, error) {
+	return r.req.Run(c.withRunOptions(func(o *runOptions) { o.tolerantStatusCodes = true }))
+}
+
+func (r tolerantRequest) ToCurl(c *Client) string {
+	return r.req.ToCurl(c)
+}
+
+// The Timeout function returns a request which runs req with its own timeout, rather than with the timeout of
+// the HTTP client of the Client, e.g. to allow more time to large datastream pages or less to quick status calls.
+// The timeout covers sending the request and reading its response, including its body while it is parsed.
+func Timeout(req AstarteRequest, timeout time.Duration) AstarteRequest {
+	return timeoutRequest{req: req, timeout: timeout}
+}
+
+type timeoutRequest struct {
+	req     AstarteRequest
+	timeout time.Duration
+}
+
+func (r timeoutRequest) Run(c *Client) (AstarteResponse, error) {
+	return r.req.Run(c.withRunOptions(func(o *runOptions) { o.timeout = r.timeout }))
+}
+
+func (r timeoutRequest) ToCurl(c *Client) string {
+	return r.req.ToCurl(c)
+}
+
+// The Cancelable function returns a request which runs req with ctx, so that the request is interrupted
+// as soon as ctx is done, including while its response body is read.
+func Cancelable(ctx context.Context, req AstarteRequest) AstarteRequest {
+	return cancelableRequest{req: req, ctx: ctx}
+}
+
+type cancelableRequest struct {
+	req AstarteRequest
+	ctx context.Context
+}
+
+func (r cancelableRequest) Run(c *Client) (AstarteResponse, error) {
+	return r.req.Run(c.withRunOptions(func(o *runOptions) { o.ctx = r.ctx }))
+}
+
+func (r cancelableRequest) ToCurl(c *Client) string {
+	return r.req.ToCurl(c)
+}
+
+// runWithoutParsing runs req, discarding its response.
+func runWithoutParsing(c *Client, req AstarteRequest) error {
+	res, err := req.Run(c)
+	if err != nil {
+		return err
+	}
+	_ = res.Raw(func(*http.Response) any { return nil })
+	return nil
+}
+
+// StatusCodeWarning describes a response which was accepted by a tolerant request
+// even though its status code is not among the documented ones.
+type StatusCodeWarning struct {
+	Method   string
+	URL      string
+	Expected []int
+	Received int
+}
+
+func (w StatusCodeWarning) String() string {
+	return fmt.Sprintf("%s %s: accepted status code %d instead of one of %v", w.Method, w.URL, w.Received, w.Expected)
+}
+
+// isExpectedStatusCode checks whether the status code of res is one of expected. If the client
+// is tolerant, any 2xx status code is accepted, and a StatusCodeWarning is issued for undocumented ones.
+func (c *Client) isExpectedStatusCode(res *http.Response, expected []int) bool {
+	for _, code := range expected {
+		if res.StatusCode == code {
+			return true
+		}
+	}
+	if !(c.tolerantStatusCodes || c.run.tolerantStatusCodes) || res.StatusCode < 200 || res.StatusCode > 299 {
+		return false
+	}
+	if c.statusCodeWarningHandler != nil {
+		warning := StatusCodeWarning{Expected: expected, Received: res.StatusCode}
+		if res.Request != nil {
+			warning.Method = res.Request.Method
+			warning.URL = res.Request.URL.String()
+		}
+		c.statusCodeWarningHandler(warning)
+	}
+	return true
+}
+
 func (c *Client) makeHTTPrequest(method string, url *url.URL, payload io.Reader) *http.Request {
 	return c.makeHTTPrequestWithContentType(method, url, payload, "application/json")
 }
 
 func (c *Client) makeHTTPrequestWithContentType(method string, url *url.URL, payload io.Reader, contentType string) *http.Request {
+	// Buffer the payload, so that the request body can be read many times through GetBody
+	// (e.g. when the request is retried or rendered with ToCurl)
+	if payload != nil {
+		if _, ok := payload.(*bytes.Reader); !ok {
+			b, _ := io.ReadAll(payload)
+			payload = bytes.NewReader(b)
+		}
+	}
 	// TODO check err
 	req, _ := http.NewRequest(method, url.String(), payload)
 	req.Header.Add("Authorization", "Bearer "+c.getJWT())
@@ -53,24 +167,160 @@ func (c *Client) makeHTTPrequestWithContentType(method string, url *url.URL, pay
 	return req
 }
 
+// TokenRefresh describes a request which was rejected with 401 Unauthorized and was retried
+// once with a JWT freshly generated from the private key of the client.
+type TokenRefresh struct {
+	Method string
+	URL    string
+	// RetryStatusCode is the status code of the retried request, or 0 if it failed altogether
+	RetryStatusCode int
+}
+
+func (r TokenRefresh) String() string {
+	return fmt.Sprintf("%s %s: retried with a fresh token, got status code %d", r.Method, r.URL, r.RetryStatusCode)
+}
+
+// do sends a copy of req, so that its body can still be read afterwards and req can be run again.
+// The body of the copy is compressed according to the settings of the client, see WithRequestCompression.
+// If the client generates its tokens from a private key, a 401 Unauthorized response (e.g. due to clock
+// skew, or to the token expiring in flight) is handled by sending the request again with a fresh token, once.
+// Responses which are not JSON, e.g. HTML error pages of reverse proxies, are reported as NonJSONResponseError.
+// Mutating requests are reported to the audit handler of the client, if any, see WithAuditHandler.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if c.run.ctx != nil {
+		req = req.WithContext(c.run.ctx)
+	}
+	res, err := c.doWithTokenRefresh(req)
+	if err == nil {
+		res, err = checkJSONResponse(res)
+	}
+	c.audit(req, res, err)
+	return res, err
+}
+
+func (c *Client) doWithTokenRefresh(req *http.Request) (*http.Response, error) {
+	res, err := c.sendCompressed(cloneRequest(req))
+	if err != nil || res.StatusCode != http.StatusUnauthorized || c.privateKey == nil {
+		return res, err
+	}
+	_, _ = io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+
+	retry := cloneRequest(req)
+	retry.Header.Set("Authorization", "Bearer "+c.getJWT())
+	if c.metrics != nil {
+		c.metrics.ObserveTokenRefresh()
+	}
+	c.observeRetry(req.URL, RetryUnauthorized)
+	res, err = c.sendCompressed(retry)
+	if c.tokenRefreshHandler != nil {
+		refresh := TokenRefresh{Method: req.Method, URL: req.URL.String()}
+		if err == nil {
+			refresh.RetryStatusCode = res.StatusCode
+		}
+		c.tokenRefreshHandler(refresh)
+	}
+	return res, err
+}
+
+// nonJSONBodyPrefixLength is how much of a non-JSON response body is reported in a NonJSONResponseError.
+const nonJSONBodyPrefixLength = 512
+
+// checkJSONResponse returns a NonJSONResponseError if res is not JSON. Responses are not JSON if their content type
+// is HTML or XML, or if their content type is not JSON and their body looks like markup, e.g. error pages of reverse
+// proxies. Responses with no content type are not checked, since some test servers and proxies omit it.
+func checkJSONResponse(res *http.Response) (*http.Response, error) {
+	contentType := res.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if res.Body == nil || mediaType == "" || strings.Contains(mediaType, "json") {
+		return res, nil
+	}
+	body := bufio.NewReaderSize(res.Body, nonJSONBodyPrefixLength)
+	prefix, _ := body.Peek(nonJSONBodyPrefixLength)
+	res.Body = struct {
+		io.Reader
+		io.Closer
+	}{body, res.Body}
+
+	switch {
+	case strings.Contains(mediaType, "html"), strings.Contains(mediaType, "xml"):
+	case bytes.HasPrefix(bytes.TrimSpace(prefix), []byte("<")):
+	default:
+		return res, nil
+	}
+	res.Body.Close()
+	return res, &NonJSONResponseError{StatusCode: res.StatusCode, ContentType: contentType, Body: string(prefix)}
+}
+
+// send sends req with the HTTP client of c for the service req is addressed to. If c has a request timeout, it is
+// enforced with a context deadline in place of the timeout of the HTTP client, and the deadline is released when
+// the response body is closed.
+// Each request is reported to the MetricsRecorder of c, if any.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	res, err := c.sendWithTimeout(req)
+	c.observeRequest(req, res, start)
+	return res, err
+}
+
+func (c *Client) sendWithTimeout(req *http.Request) (*http.Response, error) {
+	if c.run.timeout <= 0 {
+		return c.httpClientFor(req).Do(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), c.run.timeout)
+	httpClient := *c.httpClientFor(req)
+	httpClient.Timeout = 0
+	res, err := httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	res.Body = cancelOnCloseBody{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelOnCloseBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// cloneRequest returns a copy of req with a fresh body, obtained from req.GetBody.
+func cloneRequest(req *http.Request) *http.Request {
+	clone := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			clone.Body = body
+		}
+	}
+	return clone
+}
+
 type astarteRequestBody struct {
-	Data any `json:"data"`
+	Data      any        `json:"data"`
+	Timestamp *time.Time `json:"timestamp,omitempty"`
 }
 
 func makeBody(payload any) (io.Reader, error) {
-	data := astarteRequestBody{Data: payload}
+	return encodeRequestBody(astarteRequestBody{Data: payload})
+}
+
+func makeTimestampedBody(payload any, timestamp time.Time) (io.Reader, error) {
+	utcTimestamp := timestamp.UTC()
+	return encodeRequestBody(astarteRequestBody{Data: payload, Timestamp: &utcTimestamp})
+}
+
+func encodeRequestBody(data astarteRequestBody) (io.Reader, error) {
 	b := new(bytes.Buffer)
 	err := json.NewEncoder(b).Encode(data)
 	if err != nil {
-		return b, err
+		return bytes.NewReader(b.Bytes()), err
 	}
-	return b, nil
-}
-
-func makeURL(base *url.URL, pathFormat string, args ...interface{}) *url.URL {
-	callURL, _ := url.Parse(base.String())
-	callURL.Path = path.Join(callURL.Path, fmt.Sprintf(pathFormat, args...))
-	return callURL
+	return bytes.NewReader(b.Bytes()), nil
 }
 
 // setupURLQuery setups URL query parameters
@@ -0,0 +1,134 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/astarteservices"
+)
+
+// MetricsRecorder receives metrics about the operation of a Client, see WithMetrics. PrometheusMetrics is a
+// MetricsRecorder which exposes them to Prometheus, but any other metrics system can be plugged in implementing
+// this interface. Methods are called concurrently if requests are run concurrently, and must not block.
+type MetricsRecorder interface {
+	// ObserveRequest is called once for each HTTP request sent to Astarte, including retries. endpoint is the path
+	// of the request within service, with identifiers such as realm names or Device IDs replaced by "*", e.g.
+	// "/v1/*/devices/*/interfaces/*". statusCode is 0 if no response was received.
+	ObserveRequest(service astarteservices.AstarteService, endpoint, method string, statusCode int, duration time.Duration)
+	// ObserveRetry is called each time a request is sent again, with the reason why it is, see RetryReason.
+	ObserveRetry(service astarteservices.AstarteService, endpoint string, reason RetryReason)
+	// ObserveTokenRefresh is called each time a JWT is generated again to retry a request rejected with 401
+	// Unauthorized, see WithTokenRefreshHandler.
+	ObserveTokenRefresh()
+	// ObservePage is called each time a paginator fetches a page, with the kind of paginator, e.g. "datastream"
+	// or "device_list".
+	ObservePage(paginator string)
+}
+
+// RetryReason is the reason why a request is sent again, see MetricsRecorder.
+type RetryReason string
+
+const (
+	// RetryUnauthorized is reported when a request is retried with a fresh token after 401 Unauthorized.
+	RetryUnauthorized RetryReason = "unauthorized"
+	// RetryUncompressed is reported when a request is retried without compressing its body after 415
+	// Unsupported Media Type, see WithRequestCompression.
+	RetryUncompressed RetryReason = "uncompressed"
+	// RetryTransientFailure is reported when a request is retried after a transient failure ensuring that it
+	// was not processed, e.g. by BroadcastData.
+	RetryTransientFailure RetryReason = "transient_failure"
+)
+
+// The WithMetrics function allows to specify a MetricsRecorder which receives metrics about the requests sent by
+// the client, such as their number and duration for each service and endpoint, retries, token refreshes and pages
+// fetched by paginators. If not specified, no metrics are recorded.
+func WithMetrics(recorder MetricsRecorder) Option {
+	return func(c *Client) error {
+		c.metrics = recorder
+		return nil
+	}
+}
+
+// endpointSegments are the segments of the paths of the Astarte APIs which do not identify a resource.
+var endpointSegments = map[string]bool{
+	"v1": true, "agent": true, "devices": true, "devices-by-alias": true, "groups": true, "interfaces": true,
+	"policies": true, "triggers": true, "realms": true, "stats": true, "protocols": true, "astarte_mqtt_v1": true,
+	"credentials": true,
+}
+
+// endpointOf returns the service u belongs to, and the path of u within the service with identifiers replaced
+// by "*". Consecutive identifiers (e.g. an interface name followed by a path within the interface) are replaced
+// by a single "*", so that the number of different endpoints stays bounded.
+func (c *Client) endpointOf(u *url.URL) (astarteservices.AstarteService, string) {
+	service := c.serviceOf(u)
+	urlPath := u.Path
+	if serviceURL := c.serviceURL(service); serviceURL != nil {
+		urlPath = strings.TrimPrefix(urlPath, strings.TrimSuffix(serviceURL.Path, "/"))
+	}
+	segments := []string{}
+	for _, segment := range strings.Split(strings.Trim(urlPath, "/"), "/") {
+		switch {
+		case endpointSegments[segment]:
+			segments = append(segments, segment)
+		case len(segments) == 0 || segments[len(segments)-1] != "*":
+			segments = append(segments, "*")
+		}
+	}
+	return service, "/" + strings.Join(segments, "/")
+}
+
+func (c *Client) serviceURL(service astarteservices.AstarteService) *url.URL {
+	switch service {
+	case astarteservices.AppEngine:
+		return c.appEngineURL
+	case astarteservices.Housekeeping:
+		return c.housekeepingURL
+	case astarteservices.Pairing:
+		return c.pairingURL
+	case astarteservices.RealmManagement:
+		return c.realmManagementURL
+	}
+	return nil
+}
+
+func (c *Client) observeRequest(req *http.Request, res *http.Response, start time.Time) {
+	if c.metrics == nil {
+		return
+	}
+	statusCode := 0
+	if res != nil {
+		statusCode = res.StatusCode
+	}
+	service, endpoint := c.endpointOf(req.URL)
+	c.metrics.ObserveRequest(service, endpoint, req.Method, statusCode, time.Since(start))
+}
+
+func (c *Client) observeRetry(u *url.URL, reason RetryReason) {
+	if c.metrics == nil {
+		return
+	}
+	service, endpoint := c.endpointOf(u)
+	c.metrics.ObserveRetry(service, endpoint, reason)
+}
+
+func (c *Client) observePage(paginator string) {
+	if c.metrics != nil {
+		c.metrics.ObservePage(paginator)
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/astarteservices"
+)
+
+// defaultDurationBuckets are the upper bounds of the buckets of the request duration histogram, in seconds.
+var defaultDurationBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// PrometheusMetrics is a MetricsRecorder which keeps the metrics of a Client in memory and exposes them in the
+// Prometheus text format, without depending on the Prometheus client library. It serves the metrics over HTTP,
+// e.g. with http.Handle("/metrics", metrics), or they can be written to any io.Writer with WritePrometheus.
+// The following metrics are exposed:
+//   - astarte_client_requests_total, a counter of the requests, by service, endpoint, method and status code
+//   - astarte_client_request_duration_seconds, a histogram of the durations of the requests, by service, endpoint
+//     and method
+//   - astarte_client_retries_total, a counter of the retried requests, by service, endpoint and reason
+//   - astarte_client_token_refreshes_total, a counter of the tokens generated again after 401 Unauthorized
+//   - astarte_client_paginator_pages_total, a counter of the pages fetched by paginators, by kind of paginator
+//
+// The same PrometheusMetrics can be shared among many clients.
+type PrometheusMetrics struct {
+	mu             sync.Mutex
+	requests       map[string]uint64
+	durations      map[string]*durationHistogram
+	retries        map[string]uint64
+	tokenRefreshes uint64
+	pages          map[string]uint64
+}
+
+type durationHistogram struct {
+	buckets []uint64
+	sum     float64
+	count   uint64
+}
+
+// NewPrometheusMetrics returns a new PrometheusMetrics, with no metrics recorded.
+func NewPrometheusMetrics() *PrometheusMetrics {
+	return &PrometheusMetrics{
+		requests:  map[string]uint64{},
+		durations: map[string]*durationHistogram{},
+		retries:   map[string]uint64{},
+		pages:     map[string]uint64{},
+	}
+}
+
+// ObserveRequest implements MetricsRecorder.
+func (m *PrometheusMetrics) ObserveRequest(service astarteservices.AstarteService, endpoint, method string, statusCode int,
+	duration time.Duration) {
+	labels := prometheusLabels("service", serviceLabel(service), "endpoint", endpoint, "method", method)
+	seconds := duration.Seconds()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[labels+","+prometheusLabels("code", strconv.Itoa(statusCode))]++
+	histogram, ok := m.durations[labels]
+	if !ok {
+		histogram = &durationHistogram{buckets: make([]uint64, len(defaultDurationBuckets))}
+		m.durations[labels] = histogram
+	}
+	for i, upperBound := range defaultDurationBuckets {
+		if seconds <= upperBound {
+			histogram.buckets[i]++
+		}
+	}
+	histogram.sum += seconds
+	histogram.count++
+}
+
+// ObserveRetry implements MetricsRecorder.
+func (m *PrometheusMetrics) ObserveRetry(service astarteservices.AstarteService, endpoint string, reason RetryReason) {
+	labels := prometheusLabels("service", serviceLabel(service), "endpoint", endpoint, "reason", string(reason))
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retries[labels]++
+}
+
+// ObserveTokenRefresh implements MetricsRecorder.
+func (m *PrometheusMetrics) ObserveTokenRefresh() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tokenRefreshes++
+}
+
+// ObservePage implements MetricsRecorder.
+func (m *PrometheusMetrics) ObservePage(paginator string) {
+	labels := prometheusLabels("paginator", paginator)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pages[labels]++
+}
+
+// ServeHTTP serves the metrics in the Prometheus text format.
+func (m *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = m.WritePrometheus(w)
+}
+
+// WritePrometheus writes the metrics to w in the Prometheus text format.
+func (m *PrometheusMetrics) WritePrometheus(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b := bufio.NewWriter(w)
+	writeCounters(b, "astarte_client_requests_total", "Requests sent to Astarte.", m.requests)
+	writeHeader(b, "astarte_client_request_duration_seconds", "Duration of the requests sent to Astarte.", "histogram")
+	for _, labels := range sortedLabels(m.durations) {
+		histogram := m.durations[labels]
+		for i, upperBound := range defaultDurationBuckets {
+			bucketLabels := labels + "," + prometheusLabels("le", strconv.FormatFloat(upperBound, 'g', -1, 64))
+			fmt.Fprintf(b, "astarte_client_request_duration_seconds_bucket{%s} %d\n", bucketLabels, histogram.buckets[i])
+		}
+		fmt.Fprintf(b, "astarte_client_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, histogram.count)
+		fmt.Fprintf(b, "astarte_client_request_duration_seconds_sum{%s} %s\n", labels, strconv.FormatFloat(histogram.sum, 'g', -1, 64))
+		fmt.Fprintf(b, "astarte_client_request_duration_seconds_count{%s} %d\n", labels, histogram.count)
+	}
+	writeCounters(b, "astarte_client_retries_total", "Requests sent to Astarte again.", m.retries)
+	writeHeader(b, "astarte_client_token_refreshes_total", "Tokens generated again after 401 Unauthorized.", "counter")
+	fmt.Fprintf(b, "astarte_client_token_refreshes_total %d\n", m.tokenRefreshes)
+	writeCounters(b, "astarte_client_paginator_pages_total", "Pages fetched by paginators.", m.pages)
+	return b.Flush()
+}
+
+func writeHeader(w io.Writer, name, help, metricType string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+func writeCounters(w io.Writer, name, help string, counters map[string]uint64) {
+	writeHeader(w, name, help, "counter")
+	for _, labels := range sortedLabels(counters) {
+		fmt.Fprintf(w, "%s{%s} %d\n", name, labels, counters[labels])
+	}
+}
+
+func sortedLabels[T any](m map[string]T) []string {
+	ret := make([]string, 0, len(m))
+	for labels := range m {
+		ret = append(ret, labels)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+var prometheusLabelValueEscaper = strings.NewReplacer(`\`, `\\`, "\"", `\"`, "\n", `\n`)
+
+// prometheusLabels formats pairs of label names and values as Prometheus labels, e.g. `service="appengine"`.
+func prometheusLabels(namesAndValues ...string) string {
+	labels := make([]string, 0, len(namesAndValues)/2)
+	for i := 0; i+1 < len(namesAndValues); i += 2 {
+		labels = append(labels, fmt.Sprintf("%s=\"%s\"", namesAndValues[i], prometheusLabelValueEscaper.Replace(namesAndValues[i+1])))
+	}
+	return strings.Join(labels, ",")
+}
+
+func serviceLabel(service astarteservices.AstarteService) string {
+	if service == astarteservices.Unknown {
+		return "unknown"
+	}
+	return service.String()
+}
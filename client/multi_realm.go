@@ -0,0 +1,89 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"golang.org/x/sync/errgroup"
+)
+
+const defaultMultiRealmConcurrency = 10
+
+// MultiRealmReport reports the outcome of an operation run by ForEachRealm on each realm.
+type MultiRealmReport struct {
+	// Succeeded lists the realms on which the operation succeeded, in the order they were given.
+	Succeeded []string
+	// Failed lists the realms on which the operation failed or was not run, in the order they were given.
+	Failed []string
+	// Errors maps the realms in Failed to their error.
+	Errors map[string]error
+}
+
+// Err returns nil if the operation succeeded on all realms, and the errors of all failed realms otherwise.
+func (r MultiRealmReport) Err() error {
+	errs := make([]error, 0, len(r.Failed))
+	for _, realm := range r.Failed {
+		errs = append(errs, fmt.Errorf("%s: %w", realm, r.Errors[realm]))
+	}
+	return errors.Join(errs...)
+}
+
+// ForEachRealm runs fn on each realm in realms, running up to concurrency calls at a time (10 if concurrency
+// is not positive). fn can run any realm-scoped operation, e.g. building and running requests with a Client or
+// with its RealmClient for the realm, and should stop once the context it is given is done. Realms listed more
+// than once are run only once. Errors returned by fn are collected in the returned report, and do not stop the
+// other calls. Once ctx is done, fn is not called on the remaining realms, which fail with the error of ctx.
+func ForEachRealm(ctx context.Context, realms []string, fn func(ctx context.Context, realm string) error, concurrency int) MultiRealmReport {
+	if concurrency <= 0 {
+		concurrency = defaultMultiRealmConcurrency
+	}
+
+	unique := uniqueStrings(realms)
+
+	errs := make([]error, len(unique))
+	// Errors of fn are collected rather than returned to the group, so that they don't cancel the other calls
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(concurrency)
+	for i, realm := range unique {
+		if err := ctx.Err(); err != nil {
+			errs[i] = err
+			continue
+		}
+		i, realm := i, realm
+		group.Go(func() error {
+			if err := groupCtx.Err(); err != nil {
+				errs[i] = err
+				return nil
+			}
+			errs[i] = fn(groupCtx, realm)
+			return nil
+		})
+	}
+	_ = group.Wait()
+
+	report := MultiRealmReport{Succeeded: []string{}, Failed: []string{}, Errors: map[string]error{}}
+	for i, realm := range unique {
+		if errs[i] != nil {
+			report.Failed = append(report.Failed, realm)
+			report.Errors[realm] = errs[i]
+		} else {
+			report.Succeeded = append(report.Succeeded, realm)
+		}
+	}
+	return report
+}
@@ -0,0 +1,125 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// MaxSafeInteger is the largest integer float64 represents exactly, along with all the smaller ones. Astarte
+// longintegers beyond it lose precision when decoded from JSON as float64, which is the default: decode them
+// with WithPreciseNumbers to keep them exact.
+const MaxSafeInteger = 1<<53 - 1
+
+// ValueAsFloat64 converts a numeric value parsed from Astarte to float64. Integers beyond MaxSafeInteger, which
+// float64 cannot represent exactly, are reported with ErrUnsafeInteger, and values which are not numbers (or
+// strings holding a number, as Astarte may encode longintegers) with ErrNotNumeric.
+func ValueAsFloat64(value any) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int:
+		return safeIntegerAsFloat64(int64(v))
+	case int32:
+		return float64(v), nil
+	case int64:
+		return safeIntegerAsFloat64(v)
+	case json.Number:
+		return numberAsFloat64(string(v))
+	case string:
+		return numberAsFloat64(v)
+	}
+	return 0, fmt.Errorf("%w: %v of type %T", ErrNotNumeric, value, value)
+}
+
+// ValueAsInt64 converts a numeric value parsed from Astarte to int64. Values with a fractional part or beyond the
+// int64 range are reported with ErrIntegerOverflow. float64 values beyond MaxSafeInteger are reported with
+// ErrUnsafeInteger, since they might have been rounded when decoded: decode them with WithPreciseNumbers.
+// Values which are not numbers (or strings holding a number, as Astarte may encode longintegers) are reported
+// with ErrNotNumeric.
+func ValueAsInt64(value any) (int64, error) {
+	switch v := value.(type) {
+	case float64:
+		return floatAsInt64(v)
+	case float32:
+		return floatAsInt64(float64(v))
+	case int:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case json.Number:
+		return numberAsInt64(string(v))
+	case string:
+		return numberAsInt64(v)
+	}
+	return 0, fmt.Errorf("%w: %v of type %T", ErrNotNumeric, value, value)
+}
+
+func safeIntegerAsFloat64(v int64) (float64, error) {
+	if v > MaxSafeInteger || v < -MaxSafeInteger {
+		return 0, fmt.Errorf("%w: %d", ErrUnsafeInteger, v)
+	}
+	return float64(v), nil
+}
+
+func floatAsInt64(v float64) (int64, error) {
+	switch {
+	case math.IsNaN(v) || math.IsInf(v, 0) || v != math.Trunc(v) || v >= math.MaxInt64 || v < math.MinInt64:
+		return 0, fmt.Errorf("%w: %v", ErrIntegerOverflow, v)
+	case v > MaxSafeInteger || v < -MaxSafeInteger:
+		return 0, fmt.Errorf("%w: %v", ErrUnsafeInteger, v)
+	}
+	return int64(v), nil
+}
+
+func numberAsFloat64(s string) (float64, error) {
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err == nil {
+		return safeIntegerAsFloat64(i)
+	}
+	if errors.Is(err, strconv.ErrRange) {
+		// An integer beyond the int64 range, hence beyond MaxSafeInteger
+		return 0, fmt.Errorf("%w: %s", ErrUnsafeInteger, s)
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrNotNumeric, s)
+	}
+	return f, nil
+}
+
+func numberAsInt64(s string) (int64, error) {
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err == nil {
+		return i, nil
+	}
+	if errors.Is(err, strconv.ErrRange) {
+		return 0, fmt.Errorf("%w: %s", ErrIntegerOverflow, s)
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil && !errors.Is(err, strconv.ErrRange) {
+		return 0, fmt.Errorf("%w: %q", ErrNotNumeric, s)
+	}
+	// Whole-valued numbers such as "42.0" or "1e3" are integers
+	return floatAsInt64(f)
+}
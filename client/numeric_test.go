@@ -0,0 +1,109 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/astarte-platform/astarte-go/interfaces"
+	"github.com/tidwall/gjson"
+)
+
+func TestValueAsInt64(t *testing.T) {
+	testCases := []struct {
+		value    any
+		expected int64
+		err      error
+	}{
+		{value: 42.0, expected: 42},
+		{value: json.Number("9007199254740993"), expected: 9007199254740993},
+		{value: "-9223372036854775808", expected: -9223372036854775808},
+		{value: int64(1) << 60, expected: 1 << 60},
+		{value: float64(1 << 60), err: ErrUnsafeInteger},
+		{value: 1.5, err: ErrIntegerOverflow},
+		{value: json.Number("9223372036854775808"), err: ErrIntegerOverflow},
+		{value: json.Number("1.5"), err: ErrIntegerOverflow},
+		{value: json.Number("42.0"), expected: 42},
+		{value: "1e3", expected: 1000},
+		{value: json.Number("1e400"), err: ErrIntegerOverflow},
+		{value: "forty-two", err: ErrNotNumeric},
+		{value: true, err: ErrNotNumeric},
+	}
+	for _, tc := range testCases {
+		v, err := ValueAsInt64(tc.value)
+		if !errors.Is(err, tc.err) || (tc.err == nil && v != tc.expected) {
+			t.Errorf("Unexpected result for %v: %d, %v", tc.value, v, err)
+		}
+	}
+}
+
+func TestValueAsFloat64(t *testing.T) {
+	testCases := []struct {
+		value    any
+		expected float64
+		err      error
+	}{
+		{value: 21.5, expected: 21.5},
+		{value: json.Number("21.5"), expected: 21.5},
+		{value: json.Number("9007199254740991"), expected: MaxSafeInteger},
+		{value: 42, expected: 42},
+		{value: json.Number("9007199254740993"), err: ErrUnsafeInteger},
+		{value: json.Number("-92233720368547758080"), err: ErrUnsafeInteger},
+		{value: "1e3", expected: 1000},
+		{value: int64(-1) << 60, err: ErrUnsafeInteger},
+		{value: nil, err: ErrNotNumeric},
+	}
+	for _, tc := range testCases {
+		v, err := ValueAsFloat64(tc.value)
+		if !errors.Is(err, tc.err) || (tc.err == nil && v != tc.expected) {
+			t.Errorf("Unexpected result for %v: %v, %v", tc.value, v, err)
+		}
+	}
+}
+
+func TestPreciseNumbers(t *testing.T) {
+	series := `[{"value": 9007199254740993, "timestamp": "2024-01-01T00:00:00.000Z"}]`
+	values := parseDatastream(gjson.Parse(series), interfaces.IndividualAggregation, false).([]DatastreamIndividualValue)
+	if _, err := ValueAsInt64(values[0].Value); !errors.Is(err, ErrUnsafeInteger) {
+		t.Errorf("Expected ErrUnsafeInteger, found %v", err)
+	}
+	values = parseDatastream(gjson.Parse(series), interfaces.IndividualAggregation, true).([]DatastreamIndividualValue)
+	if v, err := ValueAsInt64(values[0].Value); err != nil || v != 9007199254740993 {
+		t.Errorf("Unexpected value %d: %v", v, err)
+	}
+
+	objects := `[{"count": 9007199254740993, "temperature": 21.5, "timestamp": "2024-01-01T00:00:00.000Z"}]`
+	objectValues := parseDatastream(gjson.Parse(objects), interfaces.ObjectAggregation, true).([]DatastreamObjectValue)
+	if count, ok := objectValues[0].Values.GetInteger("count"); !ok || count != 9007199254740993 {
+		t.Errorf("Unexpected count %d", count)
+	}
+	if temperature, ok := objectValues[0].Values.GetDouble("temperature"); !ok || temperature != 21.5 {
+		t.Errorf("Unexpected temperature %v", temperature)
+	}
+	if keys := objectValues[0].Values.Keys(); len(keys) != 2 || keys[0] != "count" || objectValues[0].Timestamp.IsZero() {
+		t.Errorf("Unexpected object value %v", objectValues[0])
+	}
+
+	snapshot, err := parseDatastreamSnapshot([]byte(`{"counter": {"value": 9007199254740993, "timestamp": "2024-01-01T00:00:00.000Z"}}`),
+		interfaces.IndividualAggregation, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := snapshot.(map[string]any)["/counter"].(DatastreamIndividualValue).Value; v != json.Number("9007199254740993") {
+		t.Errorf("Unexpected snapshot value %v", v)
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"sort"
+	"time"
+
+	"github.com/iancoleman/orderedmap"
+)
+
+// ObjectValues holds the values of an object aggregated datastream sample, keyed by the last segment of their
+// mapping endpoint, in the order Astarte returned them. ObjectValues is immutable, hence it can be copied and
+// shared among goroutines safely. Values are returned as decoded from JSON: see the typed getters to convert them.
+type ObjectValues struct {
+	keys   []string
+	values map[string]any
+}
+
+// NewObjectValues returns ObjectValues holding values, with keys sorted alphabetically.
+// values is copied, so changing it later does not affect the returned ObjectValues.
+func NewObjectValues(values map[string]any) ObjectValues {
+	ret := ObjectValues{keys: make([]string, 0, len(values)), values: make(map[string]any, len(values))}
+	for k, v := range values {
+		ret.keys = append(ret.keys, k)
+		ret.values[k] = v
+	}
+	sort.Strings(ret.keys)
+	return ret
+}
+
+func objectValuesFromOrderedMap(o *orderedmap.OrderedMap) ObjectValues {
+	keys := o.Keys()
+	values := make(map[string]any, len(keys))
+	for _, k := range keys {
+		values[k], _ = o.Get(k)
+	}
+	return ObjectValues{keys: keys, values: values}
+}
+
+// Get returns the value for key, and whether it is present.
+func (o ObjectValues) Get(key string) (any, bool) {
+	v, ok := o.values[key]
+	return v, ok
+}
+
+// Keys returns the keys of the values, in order.
+func (o ObjectValues) Keys() []string {
+	return append([]string{}, o.keys...)
+}
+
+// Len returns the number of values.
+func (o ObjectValues) Len() int {
+	return len(o.keys)
+}
+
+// AsMap returns a copy of the values as a map.
+func (o ObjectValues) AsMap() map[string]any {
+	ret := make(map[string]any, len(o.values))
+	for k, v := range o.values {
+		ret[k] = v
+	}
+	return ret
+}
+
+// GetDouble returns the value for key as a float64, and whether it is present and a number.
+func (o ObjectValues) GetDouble(key string) (float64, bool) {
+	return getObjectValue(o, key, toDouble)
+}
+
+// GetInteger returns the value for key as an int64, and whether it is present and an integer. Long integers
+// encoded as strings are converted too.
+func (o ObjectValues) GetInteger(key string) (int64, bool) {
+	return getObjectValue(o, key, toLongInteger)
+}
+
+// GetBool returns the value for key as a bool, and whether it is present and a boolean.
+func (o ObjectValues) GetBool(key string) (bool, bool) {
+	return getObjectValue(o, key, toBoolean)
+}
+
+// GetString returns the value for key as a string, and whether it is present and a string.
+func (o ObjectValues) GetString(key string) (string, bool) {
+	return getObjectValue(o, key, toString)
+}
+
+// GetTime returns the value for key as a time.Time, and whether it is present and a valid datetime.
+func (o ObjectValues) GetTime(key string) (time.Time, bool) {
+	if t, ok := o.values[key].(time.Time); ok {
+		return t, true
+	}
+	return getObjectValue(o, key, toDateTime)
+}
+
+func getObjectValue[T any](o ObjectValues, key string, convert func(any) (T, bool)) (T, bool) {
+	v, ok := o.values[key]
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	return convert(v)
+}
+
+// with returns a copy of o where key is set to value.
+func (o ObjectValues) with(key string, value any) ObjectValues {
+	values := o.AsMap()
+	keys := o.keys
+	if _, ok := values[key]; !ok {
+		keys = append(o.Keys(), key)
+	}
+	values[key] = value
+	return ObjectValues{keys: keys, values: values}
+}
+
+// MarshalJSON encodes the values as a JSON object, keeping their order.
+func (o ObjectValues) MarshalJSON() ([]byte, error) {
+	m := orderedmap.New()
+	for _, k := range o.keys {
+		m.Set(k, o.values[k])
+	}
+	return m.MarshalJSON()
+}
+
+// UnmarshalJSON decodes a JSON object, keeping the order of its keys.
+func (o *ObjectValues) UnmarshalJSON(b []byte) error {
+	m := orderedmap.New()
+	if err := m.UnmarshalJSON(b); err != nil {
+		return err
+	}
+	*o = objectValuesFromOrderedMap(m)
+	return nil
+}
@@ -0,0 +1,463 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/interfaces"
+	"gopkg.in/yaml.v3"
+)
+
+// openAPIServicePrefixes maps the name of each vendored spec in testdata/openapi to the path
+// its API is exposed on when using the standard Astarte URL hierarchy.
+var openAPIServicePrefixes = map[string]string{
+	"appengine":       "/appengine",
+	"housekeeping":    "/housekeeping",
+	"pairing":         "/pairing",
+	"realmmanagement": "/realmmanagement",
+}
+
+// openAPIUncoveredEndpoints lists the endpoints no request builder covers yet, as "<service> <METHOD> <path>".
+// TestOpenAPICoverage fails if an endpoint is neither covered nor listed here, or if a listed endpoint
+// becomes covered or is not in the specs.
+var openAPIUncoveredEndpoints = map[string]bool{
+	"appengine GET /v1/{realm_name}/groups/{group_name}":                                        true,
+	"housekeeping PUT /v1/realms/{realm_name}":                                                  true,
+	"housekeeping DELETE /v1/realms/{realm_name}":                                               true,
+	"pairing POST /v1/{realm_name}/devices/{device_id}/protocols/{protocol}/credentials/verify": true,
+	"realmmanagement GET /v1/{realm_name}/config/auth":                                          true,
+	"realmmanagement PUT /v1/{realm_name}/config/auth":                                          true,
+	"realmmanagement GET /v1/{realm_name}/config/device_registration_limit":                     true,
+	"realmmanagement GET /v1/{realm_name}/config/datastream_maximum_storage_retention":          true,
+}
+
+type openAPIEndpoint struct {
+	service string
+	method  string
+	path    string
+	pattern *regexp.Regexp
+}
+
+func (e openAPIEndpoint) String() string {
+	return fmt.Sprintf("%s %s %s", e.service, e.method, e.path)
+}
+
+type openAPISpec struct {
+	Servers []struct {
+		URL string `yaml:"url"`
+	} `yaml:"servers"`
+	Paths map[string]map[string]yaml.Node `yaml:"paths"`
+}
+
+// openAPIMethods are the keys of an OpenAPI path item which are operations, rather than e.g. shared parameters.
+var openAPIMethods = map[string]bool{
+	"get": true, "put": true, "post": true, "delete": true, "options": true, "head": true, "patch": true, "trace": true,
+}
+
+// openAPIPathPattern returns a regexp matching the concrete paths of an OpenAPI path template.
+// A trailing {path} parameter, used for interface paths, matches many path segments.
+func openAPIPathPattern(servicePrefix, path string) *regexp.Regexp {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		switch {
+		case segment == "{path}" && i == len(segments)-1:
+			segments[i] = ".+"
+		case strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}"):
+			segments[i] = "[^/]+"
+		default:
+			segments[i] = regexp.QuoteMeta(segment)
+		}
+	}
+	return regexp.MustCompile("^" + regexp.QuoteMeta(servicePrefix) + strings.Join(segments, "/") + "$")
+}
+
+// openAPIServerPath returns the path of the first server of spec relative to servicePrefix, e.g. "/v1" for
+// "https://{base_url}/appengine/v1". Server URLs can hold variables, hence they are not parsed as URLs.
+func openAPIServerPath(spec openAPISpec, servicePrefix string) string {
+	if len(spec.Servers) == 0 {
+		return ""
+	}
+	path := spec.Servers[0].URL
+	if i := strings.Index(path, "://"); i >= 0 {
+		path = path[i+len("://"):]
+		if j := strings.Index(path, "/"); j >= 0 {
+			path = path[j:]
+		} else {
+			path = ""
+		}
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(path, servicePrefix), "/")
+}
+
+// loadOpenAPIEndpoints loads the endpoints of the specs in testdata/openapi, failing t if the spec of any service
+// was not vendored.
+func loadOpenAPIEndpoints(t *testing.T) []openAPIEndpoint {
+	files, err := filepath.Glob(filepath.Join("testdata", "openapi", "*.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	missing := []string{}
+	for service := range openAPIServicePrefixes {
+		if _, err := os.Stat(filepath.Join("testdata", "openapi", service+".yaml")); err != nil {
+			missing = append(missing, service)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		t.Fatalf("The Astarte OpenAPI specs of %v are not vendored: run testdata/openapi/update.sh, see testdata/openapi/README.md", missing)
+	}
+	endpoints := []openAPIEndpoint{}
+	for _, file := range files {
+		service := strings.TrimSuffix(filepath.Base(file), ".yaml")
+		servicePrefix, ok := openAPIServicePrefixes[service]
+		if !ok {
+			t.Fatalf("Unknown service for spec %s", file)
+		}
+		b, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		spec := openAPISpec{}
+		if err := yaml.Unmarshal(b, &spec); err != nil {
+			t.Fatalf("Invalid spec %s: %v", file, err)
+		}
+		serverPath := openAPIServerPath(spec, servicePrefix)
+		for path, operations := range spec.Paths {
+			for method := range operations {
+				if !openAPIMethods[method] {
+					continue
+				}
+				endpoints = append(endpoints, openAPIEndpoint{
+					service: service,
+					method:  strings.ToUpper(method),
+					path:    serverPath + path,
+					pattern: openAPIPathPattern(servicePrefix, serverPath+path),
+				})
+			}
+		}
+	}
+	sort.Slice(endpoints, func(i, j int) bool { return endpoints[i].String() < endpoints[j].String() })
+	return endpoints
+}
+
+// recordingTransport records the requests it receives, replying to all of them with an empty payload.
+type recordingTransport struct {
+	mu       sync.Mutex
+	requests []*http.Request
+}
+
+func (r *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, req)
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(`{"data": null}`)),
+		Request:    req,
+	}, nil
+}
+
+func firstPage(p Paginator, err error) (AstarteRequest, error) {
+	if err != nil {
+		return nil, err
+	}
+	return p.GetNextPage()
+}
+
+// openAPIDatastreamInterface is a server-owned datastream interface accepting explicit timestamps.
+var openAPIDatastreamInterface = interfaces.AstarteInterface{Name: testInterfaceName, Type: interfaces.DatastreamType, Ownership: interfaces.ServerOwnership,
+	Aggregation: interfaces.IndividualAggregation, Mappings: []interfaces.AstarteInterfaceMapping{{Endpoint: "/an/endpoint", Type: interfaces.Integer, ExplicitTimestamp: true}}}
+
+// openAPIRequestBuilders builds one request for each request builder of the package, using all
+// the device identifier types they support.
+func openAPIRequestBuilders(c *Client) map[string]func() (AstarteRequest, error) {
+	builders := map[string]func() (AstarteRequest, error){
+		"GetDeviceListPaginator": func() (AstarteRequest, error) {
+			return firstPage(c.GetDeviceListPaginator(testRealmName, 10, DeviceIDFormat))
+		},
+		"GetDeviceIDFromAlias": func() (AstarteRequest, error) { return c.GetDeviceIDFromAlias(testRealmName, testDeviceAlias) },
+		"GetDevicesStats":      func() (AstarteRequest, error) { return c.GetDevicesStats(testRealmName) },
+		"AddDeviceAlias": func() (AstarteRequest, error) {
+			return c.AddDeviceAlias(testRealmName, testDeviceID, "name", testDeviceAlias)
+		},
+		"DeleteDeviceAlias": func() (AstarteRequest, error) { return c.DeleteDeviceAlias(testRealmName, testDeviceID, "name") },
+		"ListGroups":        func() (AstarteRequest, error) { return c.ListGroups(testRealmName) },
+		"CreateGroup": func() (AstarteRequest, error) {
+			return c.CreateGroup(testRealmName, testGroupName, []string{testDeviceID})
+		},
+		"ListGroupDevices": func() (AstarteRequest, error) {
+			return firstPage(c.ListGroupDevices(testRealmName, testGroupName, 10, DeviceIDFormat))
+		},
+		"AddDeviceToGroup": func() (AstarteRequest, error) {
+			return c.AddDeviceToGroup(testRealmName, testGroupName, testDeviceID)
+		},
+		"RemoveDeviceFromGroup": func() (AstarteRequest, error) {
+			return c.RemoveDeviceFromGroup(testRealmName, testGroupName, testDeviceID)
+		},
+		"ListRealms": c.ListRealms,
+		"GetRealm":   func() (AstarteRequest, error) { return c.GetRealm(testRealmName) },
+		"UpdateRealm": func() (AstarteRequest, error) {
+			limit := 10
+			return c.UpdateRealm(testRealmName, RealmSettingsPatch{DeviceRegistrationLimit: &limit})
+		},
+		"CreateRealm": func() (AstarteRequest, error) {
+			return c.CreateRealm(WithRealmName(testRealmName), WithRealmPublicKey("public key"))
+		},
+		"RegisterDevice":   func() (AstarteRequest, error) { return c.RegisterDevice(testRealmName, testDeviceID) },
+		"UnregisterDevice": func() (AstarteRequest, error) { return c.UnregisterDevice(testRealmName, testDeviceID) },
+		"ObtainNewMQTTv1CertificateForDevice": func() (AstarteRequest, error) {
+			return c.ObtainNewMQTTv1CertificateForDevice(testRealmName, testDeviceID, "csr")
+		},
+		"GetMQTTv1ProtocolInformationForDevice": func() (AstarteRequest, error) {
+			return c.GetMQTTv1ProtocolInformationForDevice(testRealmName, testDeviceID)
+		},
+		"GetDeviceTransportInformation": func() (AstarteRequest, error) {
+			return c.GetDeviceTransportInformation(testRealmName, testDeviceID)
+		},
+		"ListInterfaces": func() (AstarteRequest, error) { return c.ListInterfaces(testRealmName) },
+		"ListInterfaceMajorVersions": func() (AstarteRequest, error) {
+			return c.ListInterfaceMajorVersions(testRealmName, testInterfaceName)
+		},
+		"GetInterface": func() (AstarteRequest, error) {
+			return c.GetInterface(testRealmName, testInterfaceName, testInterfaceMajor)
+		},
+		"InstallInterface": func() (AstarteRequest, error) {
+			return c.InstallInterface(testRealmName, interfaces.AstarteInterface{}, false)
+		},
+		"DeleteInterface": func() (AstarteRequest, error) {
+			return c.DeleteInterface(testRealmName, testInterfaceName, testInterfaceMajor)
+		},
+		"UpdateInterface": func() (AstarteRequest, error) {
+			return c.UpdateInterface(testRealmName, testInterfaceName, testInterfaceMajor, interfaces.AstarteInterface{}, false)
+		},
+		"DeleteDevice":   func() (AstarteRequest, error) { return c.DeleteDevice(testRealmName, testDeviceID) },
+		"ListTriggers":   func() (AstarteRequest, error) { return c.ListTriggers(testRealmName) },
+		"GetTrigger":     func() (AstarteRequest, error) { return c.GetTrigger(testRealmName, testTriggerName) },
+		"InstallTrigger": func() (AstarteRequest, error) { return c.InstallTrigger(testRealmName, map[string]any{}) },
+		"DeleteTrigger":  func() (AstarteRequest, error) { return c.DeleteTrigger(testRealmName, testTriggerName) },
+		"ListTriggerDeliveryPolicies": func() (AstarteRequest, error) {
+			return c.ListTriggerDeliveryPolicies(testRealmName)
+		},
+		"GetTriggerDeliveryPolicy": func() (AstarteRequest, error) {
+			return c.GetTriggerDeliveryPolicy(testRealmName, testPolicyName)
+		},
+		"InstallTriggerDeliveryPolicy": func() (AstarteRequest, error) {
+			return c.InstallTriggerDeliveryPolicy(testRealmName, map[string]any{})
+		},
+		"DeleteTriggerDeliveryPolicy": func() (AstarteRequest, error) {
+			return c.DeleteTriggerDeliveryPolicy(testRealmName, testPolicyName)
+		},
+	}
+
+	for identifierType, identifier := range map[DeviceIdentifierType]string{AstarteDeviceID: testDeviceID, AstarteDeviceAlias: testDeviceAlias} {
+		identifierType, identifier := identifierType, identifier
+		suffix := fmt.Sprintf(" (%v)", identifierType)
+		deviceBuilders := map[string]func() (AstarteRequest, error){
+			"GetDeviceDetails": func() (AstarteRequest, error) {
+				return c.GetDeviceDetails(testRealmName, identifier, identifierType)
+			},
+			"GetDeviceInterfaceStats": func() (AstarteRequest, error) {
+				return c.GetDeviceInterfaceStats(testRealmName, identifier, identifierType)
+			},
+			"ListDeviceInterfaces": func() (AstarteRequest, error) {
+				return c.ListDeviceInterfaces(testRealmName, identifier, identifierType)
+			},
+			"SetDeviceInhibited": func() (AstarteRequest, error) {
+				return c.SetDeviceInhibited(testRealmName, identifier, identifierType, true)
+			},
+			"PatchDeviceIntrospection": func() (AstarteRequest, error) {
+				patch := IntrospectionPatch{Set: map[string]InterfaceVersion{testInterfaceName: {Major: 1, Minor: 0}}}
+				return c.PatchDeviceIntrospection(testRealmName, identifier, identifierType, patch)
+			},
+			"SetDeviceAttributes": func() (AstarteRequest, error) {
+				return c.SetDeviceAttributes(testRealmName, identifier, identifierType, map[string]string{"key": "value"})
+			},
+			"GetDatastreamIndividualSnapshot": func() (AstarteRequest, error) {
+				return c.GetDatastreamIndividualSnapshot(testRealmName, identifier, identifierType, testInterfaceName)
+			},
+			"GetDatastreamIndividualPaginator": func() (AstarteRequest, error) {
+				return firstPage(c.GetDatastreamIndividualPaginator(testRealmName, identifier, identifierType, testInterfaceName, "/an/endpoint", AscendingOrder, 10))
+			},
+			"GetProperty": func() (AstarteRequest, error) {
+				return c.GetProperty(testRealmName, identifier, identifierType, testInterfaceName, "/an/endpoint")
+			},
+			"SendDatastream": func() (AstarteRequest, error) {
+				return c.SendDatastream(testRealmName, identifier, identifierType, testInterfaceName, "/an/endpoint", 42)
+			},
+			"SendDatastreamRaw": func() (AstarteRequest, error) {
+				return c.SendDatastreamRaw(testRealmName, identifier, identifierType, testInterfaceName, "/an/endpoint", json.RawMessage("42"))
+			},
+			"SetProperty": func() (AstarteRequest, error) {
+				return c.SetProperty(testRealmName, identifier, identifierType, testInterfaceName, "/an/endpoint", 42)
+			},
+			"UnsetProperty": func() (AstarteRequest, error) {
+				return c.UnsetProperty(testRealmName, identifier, identifierType, testInterfaceName, "/an/endpoint")
+			},
+			"ListDeviceAliases": func() (AstarteRequest, error) {
+				return c.ListDeviceAliases(testRealmName, identifier, identifierType)
+			},
+			"ListDeviceAttributes": func() (AstarteRequest, error) {
+				return c.ListDeviceAttributes(testRealmName, identifier, identifierType)
+			},
+			"SetDeviceAttribute": func() (AstarteRequest, error) {
+				return c.SetDeviceAttribute(testRealmName, identifier, identifierType, "key", "value")
+			},
+			"DeleteDeviceAttribute": func() (AstarteRequest, error) {
+				return c.DeleteDeviceAttribute(testRealmName, identifier, identifierType, "key")
+			},
+			"SetDeviceLabels": func() (AstarteRequest, error) {
+				return c.SetDeviceLabels(testRealmName, identifier, identifierType, map[string]string{"key": "value"})
+			},
+			"RemoveDeviceLabels": func() (AstarteRequest, error) {
+				return c.RemoveDeviceLabels(testRealmName, identifier, identifierType, "key")
+			},
+			"GetAllProperties": func() (AstarteRequest, error) {
+				return c.GetAllProperties(testRealmName, identifier, identifierType, testInterfaceName)
+			},
+			"GetDatastreamObjectSnapshot": func() (AstarteRequest, error) {
+				return c.GetDatastreamObjectSnapshot(testRealmName, identifier, identifierType, testInterfaceName)
+			},
+			"GetDatastreamIndividualTimeWindowPaginator": func() (AstarteRequest, error) {
+				return firstPage(c.GetDatastreamIndividualTimeWindowPaginator(testRealmName, identifier, identifierType, testInterfaceName, "/an/endpoint",
+					time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), AscendingOrder, 10))
+			},
+			"GetDatastreamObjectPaginator": func() (AstarteRequest, error) {
+				return firstPage(c.GetDatastreamObjectPaginator(testRealmName, identifier, identifierType, testInterfaceName, "/an", AscendingOrder, 10))
+			},
+			"GetDatastreamObjectTimeWindowPaginator": func() (AstarteRequest, error) {
+				return firstPage(c.GetDatastreamObjectTimeWindowPaginator(testRealmName, identifier, identifierType, testInterfaceName, "/an",
+					time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), AscendingOrder, 10))
+			},
+			"SendData": func() (AstarteRequest, error) {
+				return c.SendData(testRealmName, identifier, identifierType, openAPIDatastreamInterface, "/an/endpoint", 42)
+			},
+			"SendDatastreamAt": func() (AstarteRequest, error) {
+				return c.SendDatastreamAt(testRealmName, identifier, identifierType, openAPIDatastreamInterface, "/an/endpoint", 42,
+					time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+			},
+			"UnsetInterfaceProperty": func() (AstarteRequest, error) {
+				propertyInterface := interfaces.AstarteInterface{Name: testInterfaceName, Type: interfaces.PropertiesType, Ownership: interfaces.ServerOwnership,
+					Mappings: []interfaces.AstarteInterfaceMapping{{Endpoint: "/an/endpoint", Type: interfaces.Integer, AllowUnset: true}}}
+				return c.UnsetInterfaceProperty(testRealmName, identifier, identifierType, propertyInterface, "/an/endpoint")
+			},
+		}
+		for name, builder := range deviceBuilders {
+			builders[name+suffix] = builder
+		}
+	}
+	return builders
+}
+
+func TestOpenAPICoverage(t *testing.T) {
+	endpoints := loadOpenAPIEndpoints(t)
+
+	transport := &recordingTransport{}
+	c, err := New(WithBaseURL("https://api.astarte.example.com"), WithJWT(testTokenValue), WithHTTPClient(&http.Client{Transport: transport}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, builder := range openAPIRequestBuilders(c) {
+		req, err := builder()
+		if err != nil {
+			t.Fatalf("Cannot build request with %s: %v", name, err)
+		}
+		// The outcome does not matter, only the request sent to Astarte does
+		_, _ = req.Run(c)
+	}
+
+	covered := map[string]bool{}
+	for _, req := range transport.requests {
+		matched := false
+		for _, endpoint := range endpoints {
+			if endpoint.method == req.Method && endpoint.pattern.MatchString(req.URL.Path) {
+				covered[endpoint.String()] = true
+				matched = true
+			}
+		}
+		if !matched {
+			t.Errorf("Request %s %s does not match any OpenAPI endpoint", req.Method, req.URL.Path)
+		}
+	}
+
+	coveredByService := map[string]int{}
+	totalByService := map[string]int{}
+	for _, endpoint := range endpoints {
+		totalByService[endpoint.service]++
+		switch {
+		case covered[endpoint.String()]:
+			coveredByService[endpoint.service]++
+			if openAPIUncoveredEndpoints[endpoint.String()] {
+				t.Errorf("%s is covered, remove it from the known uncovered endpoints", endpoint)
+			}
+		case openAPIUncoveredEndpoints[endpoint.String()]:
+			t.Logf("not covered: %s", endpoint)
+		default:
+			t.Errorf("%s is not covered by any request builder", endpoint)
+		}
+	}
+	for endpoint := range openAPIUncoveredEndpoints {
+		found := false
+		for _, e := range endpoints {
+			found = found || e.String() == endpoint
+		}
+		if !found {
+			t.Errorf("%s is not in the OpenAPI specs, remove it from the known uncovered endpoints", endpoint)
+		}
+	}
+	for service, total := range totalByService {
+		t.Logf("%s: %d/%d endpoints covered", service, coveredByService[service], total)
+	}
+}
+
+func TestOpenAPIRequestBuildersAreListed(t *testing.T) {
+	c, err := New(WithBaseURL("https://api.astarte.example.com"), WithJWT(testTokenValue))
+	if err != nil {
+		t.Fatal(err)
+	}
+	listed := map[string]bool{}
+	for name := range openAPIRequestBuilders(c) {
+		listed[strings.SplitN(name, " ", 2)[0]] = true
+	}
+	requestType := reflect.TypeOf((*AstarteRequest)(nil)).Elem()
+	paginatorType := reflect.TypeOf((*Paginator)(nil)).Elem()
+	errorType := reflect.TypeOf((*error)(nil)).Elem()
+	clientType := reflect.TypeOf(c)
+	for i := 0; i < clientType.NumMethod(); i++ {
+		method := clientType.Method(i)
+		if method.Type.NumOut() != 2 || method.Type.Out(1) != errorType {
+			continue
+		}
+		if out := method.Type.Out(0); !out.Implements(requestType) && !out.Implements(paginatorType) {
+			continue
+		}
+		if !listed[method.Name] {
+			t.Errorf("Request builder %s is not in openAPIRequestBuilders", method.Name)
+		}
+	}
+}
@@ -17,8 +17,11 @@ package client
 import (
 	"fmt"
 	"net/http"
+	"strings"
 
 	"moul.io/http2curl"
+
+	"github.com/astarte-platform/astarte-go/internal/urlbuilder"
 )
 
 type registerDevicePayload struct {
@@ -31,105 +34,131 @@ type getMQTTv1CertificatePayload struct {
 
 type RegisterDeviceRequest struct {
 	req     *http.Request
-	expects int
+	expects []int
 }
 
 // RegisterDevice builds a request to register a new device into the Realm.
+// If the realm has a device registration limit and it has been reached, running the request
+// returns an APIError matching ErrDeviceLimitReached. If the device is already registered and has requested its credentials,
+// running the request returns an APIError matching ErrAlreadyRegistered.
 // TODO: add support for initial_introspection
 func (c *Client) RegisterDevice(realm string, deviceID string) (AstarteRequest, error) {
-	callURL := makeURL(c.pairingURL, "/v1/%s/agent/devices", realm)
+	callURL := urlbuilder.Build(c.pairingURL, "/v1/%s/agent/devices", realm)
 	payload, _ := makeBody(registerDevicePayload{HwID: deviceID})
 	req := c.makeHTTPrequest(http.MethodPost, callURL, payload)
 
-	return RegisterDeviceRequest{req: req, expects: 201}, nil
+	return RegisterDeviceRequest{req: req, expects: []int{http.StatusCreated, http.StatusOK}}, nil
 }
 
 // nolint:bodyclose
 func (r RegisterDeviceRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
+	if res.StatusCode == http.StatusUnprocessableEntity && res.Body != nil {
+		return Empty{}, registerDeviceError(res)
+	}
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return runAstarteRequestError(res, r.expects)
 	}
 	return RegisterDeviceResponse{res: res}, nil
 }
 
+// deviceLimitReachedDetail is the detail of the error Astarte Pairing replies with when the device registration
+// limit of the realm has been reached.
+const deviceLimitReachedDetail = "Device registration limit reached"
+
+// registerDeviceError returns the APIError for a failed registration, recognizing ErrDeviceLimitReached from the
+// detail of the error. Details which merely mention the registration limit are recognized as well, as a fallback
+// for Astarte versions phrasing the error differently.
+func registerDeviceError(res *http.Response) error {
+	defer res.Body.Close()
+	apiError := newAPIError(res.StatusCode, res.Body)
+	detail := apiError.Detail()
+	if detail == deviceLimitReachedDetail || strings.Contains(strings.ToLower(detail), "registration limit") {
+		apiError.Kind = ErrDeviceLimitReached
+		apiError.Hint = "raise the device registration limit of the realm with UpdateRealm, or unregister unused devices"
+	}
+	return apiError
+}
+
 func (r RegisterDeviceRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	return fmt.Sprint(command)
 }
 
 type UnregisterDeviceRequest struct {
 	req     *http.Request
-	expects int
+	expects []int
 }
 
 // UnregisterDevice builds a request to reset the registration state of a device.
 // Once the request is run, this makes it possible to register it again.
 // All data belonging to the device will be left as is in Astarte.
 func (c *Client) UnregisterDevice(realm string, deviceID string) (AstarteRequest, error) {
-	callURL := makeURL(c.pairingURL, "/v1/%s/agent/devices/%s", realm, deviceID)
+	callURL := urlbuilder.Build(c.pairingURL, "/v1/%s/agent/devices/%s", realm, deviceID)
 	req := c.makeHTTPrequest(http.MethodDelete, callURL, nil)
 
-	return UnregisterDeviceRequest{req: req, expects: 204}, nil
+	return UnregisterDeviceRequest{req: req, expects: []int{http.StatusNoContent, http.StatusOK}}, nil
 }
 
 // nolint:bodyclose
 func (r UnregisterDeviceRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return runAstarteRequestError(res, r.expects)
 	}
 	return NoDataResponse{res: res}, nil
 }
 
 func (r UnregisterDeviceRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	return fmt.Sprint(command)
 }
 
 type NewDeviceCertificateRequest struct {
 	req     *http.Request
-	expects int
+	expects []int
 }
 
 // ObtainNewMQTTv1CertificateForDevice builds a request for retrieving a valid SSL Certificate for Devices
 // running on astarte_mqtt_v1.
 // This API is meant to be called by the device, and the Client that executes (Runs) the request needs to
 // have the Device's Credentials Secret as its token.
+// If the credentials requests of the device are inhibited, running the request returns an APIError matching
+// ErrCredentialsInhibited.
 func (c *Client) ObtainNewMQTTv1CertificateForDevice(realm, deviceID, csr string) (AstarteRequest, error) {
-	callURL := makeURL(c.pairingURL, "/v1/%s/devices/%s/protocols/astarte_mqtt_v1/credentials", realm, deviceID)
+	callURL := urlbuilder.Build(c.pairingURL, "/v1/%s/devices/%s/protocols/astarte_mqtt_v1/credentials", realm, deviceID)
 	payload, _ := makeBody(getMQTTv1CertificatePayload{CSR: csr})
 	req := c.makeHTTPrequest(http.MethodPost, callURL, payload)
 
-	return NewDeviceCertificateRequest{req: req, expects: 201}, nil
+	return NewDeviceCertificateRequest{req: req, expects: []int{http.StatusCreated, http.StatusOK}}, nil
 }
 
 // nolint:bodyclose
 func (r NewDeviceCertificateRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return runAstarteRequestError(res, r.expects)
 	}
 	return NewDeviceCertificateResponse{res: res}, nil
 }
 
 func (r NewDeviceCertificateRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	return fmt.Sprint(command)
 }
 
 type Mqttv1DeviceInformationRequest struct {
 	req     *http.Request
-	expects int
+	expects []int
 }
 
 // GetMQTTv1ProtocolInformationForDevice builds a request for retrieving protocol information (such as
@@ -137,25 +166,58 @@ type Mqttv1DeviceInformationRequest struct {
 // This API is meant to be called by the device, and the Client that executes (Runs) the request needs to
 // have the Device's Credentials Secret as its token.
 func (c *Client) GetMQTTv1ProtocolInformationForDevice(realm, deviceID string) (AstarteRequest, error) {
-	callURL := makeURL(c.pairingURL, "/v1/%s/devices/%s", realm, deviceID)
+	callURL := urlbuilder.Build(c.pairingURL, "/v1/%s/devices/%s", realm, deviceID)
 	req := c.makeHTTPrequest(http.MethodGet, callURL, nil)
 
-	return Mqttv1DeviceInformationRequest{req: req, expects: 200}, nil
+	return Mqttv1DeviceInformationRequest{req: req, expects: []int{http.StatusOK}}, nil
 }
 
 // nolint:bodyclose
 func (r Mqttv1DeviceInformationRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return runAstarteRequestError(res, r.expects)
 	}
-	return Mqttv1DeviceInformationResponse{res: res}, nil
+	return Mqttv1DeviceInformationResponse{res: res, brokerURLValidator: c.brokerURLValidator}, nil
 }
 
 func (r Mqttv1DeviceInformationRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
+	return fmt.Sprint(command)
+}
+
+type DeviceTransportInformationRequest struct {
+	req     *http.Request
+	expects []int
+}
+
+// GetDeviceTransportInformation builds a request for retrieving the status of a device and the information
+// (such as the broker URL) of all the protocols (transports) it can use to connect to Astarte.
+// This API is meant to be called by the device, and the Client that executes (Runs) the request needs to
+// have the Device's Credentials Secret as its token.
+func (c *Client) GetDeviceTransportInformation(realm, deviceID string) (AstarteRequest, error) {
+	callURL := urlbuilder.Build(c.pairingURL, "/v1/%s/devices/%s", realm, deviceID)
+	req := c.makeHTTPrequest(http.MethodGet, callURL, nil)
+
+	return DeviceTransportInformationRequest{req: req, expects: []int{http.StatusOK}}, nil
+}
+
+// nolint:bodyclose
+func (r DeviceTransportInformationRequest) Run(c *Client) (AstarteResponse, error) {
+	res, err := c.do(r.req)
+	if err != nil {
+		return Empty{}, err
+	}
+	if !c.isExpectedStatusCode(res, r.expects) {
+		return runAstarteRequestError(res, r.expects)
+	}
+	return DeviceTransportInformationResponse{res: res, brokerURLValidator: c.brokerURLValidator}, nil
+}
+
+func (r DeviceTransportInformationRequest) ToCurl(_ *Client) string {
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	return fmt.Sprint(command)
 }
@@ -15,24 +15,127 @@
 package client
 
 import (
-	"encoding/json"
-	"io"
+	"fmt"
 	"net/http"
-
-	"github.com/tidwall/gjson"
+	"net/url"
+	"strings"
 )
 
+// MQTTv1Protocol is the name of the astarte_mqtt_v1 protocol in AstarteDeviceTransportInformation.
+const MQTTv1Protocol = "astarte_mqtt_v1"
+
 type AstarteMQTTv1ProtocolInformation struct {
 	BrokerURL string `json:"broker_url"`
+	// CACertificate is the PEM-encoded CA certificate of the broker, returned by deployments using a private CA.
+	CACertificate string `json:"ca_cert,omitempty"`
+}
+
+// UsesTLS returns whether the broker must be reached over TLS, according to the scheme of its URL.
+func (i AstarteMQTTv1ProtocolInformation) UsesTLS() bool {
+	return isTLSBrokerScheme(i.BrokerURL)
+}
+
+// BrokerAddress returns the host and port of the broker, defaulting to the standard MQTT ports
+// (8883 over TLS, 1883 otherwise) when the broker URL has no port.
+func (i AstarteMQTTv1ProtocolInformation) BrokerAddress() (string, string, error) {
+	brokerURL, err := url.Parse(i.BrokerURL)
+	if err != nil {
+		return "", "", err
+	}
+	port := brokerURL.Port()
+	switch {
+	case port != "":
+	case i.UsesTLS():
+		port = "8883"
+	default:
+		port = "1883"
+	}
+	return brokerURL.Hostname(), port, nil
+}
+
+func isTLSBrokerScheme(brokerURL string) bool {
+	scheme, _, _ := strings.Cut(brokerURL, "://")
+	switch strings.ToLower(scheme) {
+	case "mqtts", "ssl", "tls", "wss":
+		return true
+	}
+	return false
+}
+
+// BrokerURLValidator validates broker URLs returned by Pairing, see WithBrokerURLValidator.
+type BrokerURLValidator func(brokerURL *url.URL) error
+
+// AllowBrokerHosts returns a BrokerURLValidator which accepts only TLS broker URLs pointing to one of hosts.
+func AllowBrokerHosts(hosts ...string) BrokerURLValidator {
+	return func(brokerURL *url.URL) error {
+		if !isTLSBrokerScheme(brokerURL.String()) {
+			return fmt.Errorf("Broker URL %s does not use TLS", brokerURL)
+		}
+		host := brokerURL.Hostname()
+		for _, allowed := range hosts {
+			if strings.EqualFold(host, allowed) {
+				return nil
+			}
+		}
+		return fmt.Errorf("Broker host %s is not one of %v", host, hosts)
+	}
+}
+
+// validateBrokerURL checks brokerURL with validator, if any.
+func validateBrokerURL(validator BrokerURLValidator, brokerURL string) error {
+	if validator == nil || brokerURL == "" {
+		return nil
+	}
+	parsedURL, err := url.Parse(brokerURL)
+	if err == nil {
+		err = validator(parsedURL)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBrokerURL, err)
+	}
+	return nil
+}
+
+// AstarteProtocolParameters are the connection parameters of a protocol, as returned by Pairing.
+type AstarteProtocolParameters map[string]any
+
+// BrokerURL returns the broker_url parameter of the protocol, if any.
+func (p AstarteProtocolParameters) BrokerURL() string {
+	brokerURL, _ := p["broker_url"].(string)
+	return brokerURL
+}
+
+// CACertificate returns the ca_cert parameter of the protocol, if any.
+func (p AstarteProtocolParameters) CACertificate() string {
+	caCertificate, _ := p["ca_cert"].(string)
+	return caCertificate
+}
+
+// AstarteDeviceTransportInformation holds the status of a device and the parameters of all the protocols
+// it can use to connect to Astarte, keyed by protocol name (e.g. MQTTv1Protocol).
+type AstarteDeviceTransportInformation struct {
+	Version   string                               `json:"version"`
+	Status    string                               `json:"status"`
+	Protocols map[string]AstarteProtocolParameters `json:"protocols"`
+}
+
+type registerDeviceResponsePayload struct {
+	CredentialsSecret string `json:"credentials_secret"`
+}
+
+type newDeviceCertificateResponsePayload struct {
+	ClientCrt string `json:"client_crt"`
 }
 
 // Parses data obtained by performing a request to register a device.
 // Returns the new credentials secret as a string.
 func (r RegisterDeviceResponse) Parse() (any, error) {
 	defer r.res.Body.Close()
-	b, _ := io.ReadAll(r.res.Body)
-	value := gjson.GetBytes(b, "data.credentials_secret").String()
-	return value, nil
+	payload, err := decodeAstartePayload(r.res.Body, registerDeviceResponsePayload{})
+	if err != nil {
+		return nil, err
+	}
+	return payload.Data.CredentialsSecret, nil
 }
 func (r RegisterDeviceResponse) Raw(f func(*http.Response) any) any {
 	defer r.res.Body.Close()
@@ -43,9 +146,11 @@ func (r RegisterDeviceResponse) Raw(f func(*http.Response) any) any {
 // Returns the new device certificate as a PEM-encoded string.
 func (r NewDeviceCertificateResponse) Parse() (any, error) {
 	defer r.res.Body.Close()
-	b, _ := io.ReadAll(r.res.Body)
-	value := gjson.GetBytes(b, "data.client_crt").String()
-	return value, nil
+	payload, err := decodeAstartePayload(r.res.Body, newDeviceCertificateResponsePayload{})
+	if err != nil {
+		return nil, err
+	}
+	return payload.Data.ClientCrt, nil
 }
 func (r NewDeviceCertificateResponse) Raw(f func(*http.Response) any) any {
 	defer r.res.Body.Close()
@@ -57,13 +162,37 @@ func (r NewDeviceCertificateResponse) Raw(f func(*http.Response) any) any {
 // Returns the information as an AstarteMQTTv1ProtocolInformation struct.
 func (r Mqttv1DeviceInformationResponse) Parse() (any, error) {
 	defer r.res.Body.Close()
-	b, _ := io.ReadAll(r.res.Body)
-	data := gjson.GetBytes(b, "data").Raw
-	value := AstarteMQTTv1ProtocolInformation{}
-	_ = json.Unmarshal([]byte(data), &value)
-	return value, nil
+	payload, err := decodeAstartePayload(r.res.Body, AstarteDeviceTransportInformation{})
+	if err != nil {
+		return nil, err
+	}
+	parameters := payload.Data.Protocols[MQTTv1Protocol]
+	if err := validateBrokerURL(r.brokerURLValidator, parameters.BrokerURL()); err != nil {
+		return nil, err
+	}
+	return AstarteMQTTv1ProtocolInformation{BrokerURL: parameters.BrokerURL(), CACertificate: parameters.CACertificate()}, nil
 }
 func (r Mqttv1DeviceInformationResponse) Raw(f func(*http.Response) any) any {
 	defer r.res.Body.Close()
 	return f(r.res)
 }
+
+// Parses data obtained by performing a request for the transport information of a device.
+// Returns the information as an AstarteDeviceTransportInformation struct.
+func (r DeviceTransportInformationResponse) Parse() (any, error) {
+	defer r.res.Body.Close()
+	payload, err := decodeAstartePayload(r.res.Body, AstarteDeviceTransportInformation{})
+	if err != nil {
+		return nil, err
+	}
+	for protocol, parameters := range payload.Data.Protocols {
+		if err := validateBrokerURL(r.brokerURLValidator, parameters.BrokerURL()); err != nil {
+			return nil, fmt.Errorf("%s: %w", protocol, err)
+		}
+	}
+	return payload.Data, nil
+}
+func (r DeviceTransportInformationResponse) Raw(f func(*http.Response) any) any {
+	defer r.res.Body.Close()
+	return f(r.res)
+}
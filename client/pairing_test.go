@@ -15,7 +15,14 @@
 package client
 
 import (
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
 	"testing"
+
+	"github.com/astarte-platform/astarte-go/internal/runner"
 )
 
 func TestRegisterDevice(t *testing.T) {
@@ -37,6 +44,62 @@ func TestRegisterDevice(t *testing.T) {
 	}
 }
 
+func TestRegisterDeviceLimitReached(t *testing.T) {
+	c, _ := getTestContext(t)
+	registerDeviceCall, err := c.RegisterDevice(testRealmName, testOverLimitDeviceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := registerDeviceCall.Run(c); !errors.Is(err, ErrDeviceLimitReached) {
+		t.Errorf("Expected ErrDeviceLimitReached, found %v", err)
+	}
+}
+
+func TestRegisterDeviceError(t *testing.T) {
+	testCases := []struct {
+		body    string
+		limited bool
+	}{
+		{`{"errors": {"detail": "Device registration limit reached"}}`, true},
+		// Fallback for differently phrased details
+		{`{"errors": {"detail": "The realm registration limit has been exceeded"}}`, true},
+		{`{"errors": {"hw_id": ["is invalid"]}}`, false},
+		{`not JSON`, false},
+	}
+	for _, tc := range testCases {
+		res := &http.Response{StatusCode: http.StatusUnprocessableEntity, Body: io.NopCloser(strings.NewReader(tc.body))}
+		err := registerDeviceError(res)
+		apiError := &APIError{}
+		if !errors.As(err, &apiError) || apiError.StatusCode != http.StatusUnprocessableEntity {
+			t.Errorf("Expected an APIError for %s, found %v", tc.body, err)
+		}
+		if errors.Is(err, ErrDeviceLimitReached) != tc.limited {
+			t.Errorf("Unexpected match of ErrDeviceLimitReached for %s: %v", tc.body, err)
+		}
+	}
+}
+
+func TestPairingErrors(t *testing.T) {
+	c, _ := getTestContext(t)
+	registerDeviceCall, err := c.RegisterDevice(testRealmName, testRegisteredDeviceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = registerDeviceCall.Run(c)
+	apiError := &APIError{}
+	if !errors.Is(err, ErrAlreadyRegistered) || !errors.As(err, &apiError) || apiError.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("Expected ErrAlreadyRegistered, found %v", err)
+	}
+
+	credentialsCall, err := c.ObtainNewMQTTv1CertificateForDevice(testRealmName, testInhibitedDeviceID, "a csr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := credentialsCall.Run(c); !errors.Is(err, ErrCredentialsInhibited) || errors.Is(err, ErrForbidden) {
+		t.Errorf("Expected ErrCredentialsInhibited, found %v", err)
+	}
+}
+
 func TestUnregisterDevice(t *testing.T) {
 	c, _ := getTestContext(t)
 	unregisterDeviceCall, err := c.UnregisterDevice(testRealmName, testDeviceID)
@@ -75,3 +138,65 @@ func TestGetMQTTv1ProtocolInformationForDevice(t *testing.T) {
 		t.Errorf("Failed broker url: %s\n", data)
 	}
 }
+
+func TestGetDeviceTransportInformation(t *testing.T) {
+	c, _ := getTestContext(t)
+	getInfoCall, _ := c.GetDeviceTransportInformation(testRealmName, testDeviceID)
+	getInfoResponse, err := getInfoCall.Run(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawData, err := getInfoResponse.Parse()
+	if err != nil {
+		t.Error(err)
+	}
+	data, _ := rawData.(AstarteDeviceTransportInformation)
+	if data.Status != "confirmed" {
+		t.Errorf("Failed status: %s\n", data.Status)
+	}
+	if data.Protocols[MQTTv1Protocol].BrokerURL() != testBrokerUrl {
+		t.Errorf("Failed broker url: %v\n", data.Protocols)
+	}
+}
+
+func TestBrokerURLValidator(t *testing.T) {
+	c, _ := getTestContext(t)
+	if err := WithBrokerURLValidator(AllowBrokerHosts("ah.yes.the.broker"))(c); err != nil {
+		t.Fatal(err)
+	}
+	// The test broker does not use TLS
+	getInfoCall, _ := c.GetMQTTv1ProtocolInformationForDevice(testRealmName, testDeviceID)
+	if _, err := runner.RunAndParse[AstarteMQTTv1ProtocolInformation](c, getInfoCall.Run); !errors.Is(err, ErrInvalidBrokerURL) {
+		t.Errorf("Expected ErrInvalidBrokerURL, got %v", err)
+	}
+	getTransportCall, _ := c.GetDeviceTransportInformation(testRealmName, testDeviceID)
+	if _, err := runner.RunAndParse[AstarteDeviceTransportInformation](c, getTransportCall.Run); !errors.Is(err, ErrInvalidBrokerURL) {
+		t.Errorf("Expected ErrInvalidBrokerURL, got %v", err)
+	}
+
+	if err := WithBrokerURLValidator(func(u *url.URL) error { return nil })(c); err != nil {
+		t.Fatal(err)
+	}
+	info, err := runner.RunAndParse[AstarteMQTTv1ProtocolInformation](c, getInfoCall.Run)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.CACertificate != testBrokerCACert || info.UsesTLS() {
+		t.Errorf("Unexpected protocol information: %+v", info)
+	}
+	if host, port, err := info.BrokerAddress(); err != nil || host != "ah.yes.the.broker" || port != "1883" {
+		t.Errorf("Unexpected broker address: %s %s %v", host, port, err)
+	}
+
+	tlsInfo := AstarteMQTTv1ProtocolInformation{BrokerURL: "mqtts://ah.yes.the.broker"}
+	if host, port, err := tlsInfo.BrokerAddress(); err != nil || host != "ah.yes.the.broker" || port != "8883" || !tlsInfo.UsesTLS() {
+		t.Errorf("Unexpected TLS broker address: %s %s %v", host, port, err)
+	}
+	brokerURL, _ := url.Parse(tlsInfo.BrokerURL)
+	if err := AllowBrokerHosts("ah.yes.the.broker")(brokerURL); err != nil {
+		t.Error(err)
+	}
+	if err := AllowBrokerHosts("another.broker")(brokerURL); err == nil {
+		t.Error("Expected an error for a host not in the allowed ones")
+	}
+}
@@ -0,0 +1,250 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/astarte-platform/astarte-go/interfaces"
+	"github.com/astarte-platform/astarte-go/internal/runner"
+)
+
+// BootstrapSpec describes the resources BootstrapRealm installs in a realm, e.g. when creating the environment of a
+// new tenant.
+type BootstrapSpec struct {
+	Interfaces []interfaces.AstarteInterface
+	// Policies and Triggers can be any value which marshals to a valid payload, as for InstallTriggersAndPolicies.
+	Policies []any
+	Triggers []any
+	// Groups maps the name of each group to the Device IDs it holds. Astarte does not allow empty groups, so each
+	// group must hold at least one device.
+	Groups map[string][]string
+}
+
+// BootstrapReport reports what BootstrapRealm did, or would do in a dry run.
+type BootstrapReport struct {
+	// DryRun is set if nothing was installed: ResourceInstalled and ResourceUpdated outcomes are what would happen.
+	DryRun bool
+	// Results holds a result for each resource, in installation order: interfaces, trigger delivery policies,
+	// triggers and groups, the latter sorted by name.
+	Results []ResourceInstallResult
+}
+
+// Err returns the errors of the resources which were not installed, joined, or nil if all of them were.
+func (r BootstrapReport) Err() error {
+	errs := []error{}
+	for _, result := range r.Results {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", result.Kind, result.Name, result.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type bootstrapOptions struct {
+	dryRun bool
+}
+
+type bootstrapOption func(*bootstrapOptions)
+
+// Compares the resources with the installed ones without installing anything, see BootstrapReport.
+// nolint:golint,revive
+func WithDryRun() bootstrapOption {
+	return func(o *bootstrapOptions) {
+		o.dryRun = true
+	}
+}
+
+// BootstrapRealm installs the resources of spec in realm, in dependency order: interfaces first, then trigger
+// delivery policies and the triggers using them, and finally groups. Installation is idempotent, so that
+// BootstrapRealm can be run again on the same realm:
+//   - interfaces which are already installed are compared by their fingerprint (see interfaces.Fingerprint):
+//     identical ones are skipped, ones with a lower minor version are updated, and other ones are conflicting;
+//   - policies and triggers are handled as by InstallTriggersAndPolicies;
+//   - groups which already exist get the devices they lack.
+//
+// Triggers on interfaces, and on policies, which could not be installed are not installed either. A result is
+// returned for each resource, see BootstrapReport.Err to check whether all of them were installed. The returned
+// error is set only if the installed resources cannot be listed.
+func (c *Client) BootstrapRealm(realm string, spec BootstrapSpec, opts ...bootstrapOption) (BootstrapReport, error) {
+	options := bootstrapOptions{}
+	for _, f := range opts {
+		f(&options)
+	}
+	report := BootstrapReport{DryRun: options.dryRun}
+
+	installedInterfaces, err := c.listInstalledResources(c.ListInterfaces(realm))
+	if err != nil {
+		return report, err
+	}
+	unavailableInterfaces := map[string]bool{}
+	for _, astarteInterface := range spec.Interfaces {
+		result := c.bootstrapInterface(realm, astarteInterface, installedInterfaces, options.dryRun)
+		if result.Err != nil {
+			unavailableInterfaces[interfaceKey(astarteInterface.Name, astarteInterface.MajorVersion)] = true
+		}
+		report.Results = append(report.Results, result)
+	}
+
+	results, err := c.installTriggersAndPolicies(realm, spec.Policies, spec.Triggers, options.dryRun, unavailableInterfaces)
+	if err != nil {
+		return report, err
+	}
+	report.Results = append(report.Results, results...)
+
+	installedGroups, err := c.listInstalledResources(c.ListGroups(realm))
+	if err != nil {
+		return report, err
+	}
+	groupNames := make([]string, 0, len(spec.Groups))
+	for groupName := range spec.Groups {
+		groupNames = append(groupNames, groupName)
+	}
+	sort.Strings(groupNames)
+	for _, groupName := range groupNames {
+		report.Results = append(report.Results, c.bootstrapGroup(realm, groupName, spec.Groups[groupName], installedGroups[groupName], options.dryRun))
+	}
+	return report, nil
+}
+
+// interfaceKey identifies a major version of an interface.
+func interfaceKey(interfaceName string, major int) string {
+	return fmt.Sprintf("%s v%d", interfaceName, major)
+}
+
+func (c *Client) bootstrapInterface(realm string, astarteInterface interfaces.AstarteInterface, installed map[string]bool, dryRun bool) ResourceInstallResult {
+	result := ResourceInstallResult{Kind: "interface", Name: interfaceKey(astarteInterface.Name, astarteInterface.MajorVersion)}
+	installCall, outcome, err := c.interfaceInstallCall(realm, astarteInterface, installed[astarteInterface.Name])
+	result.Outcome, result.Err = outcome, err
+	if err != nil || installCall == nil || dryRun {
+		return result
+	}
+	if err := runWithoutParsing(c, installCall); err != nil {
+		result.Outcome, result.Err = ResourceFailed, err
+	}
+	return result
+}
+
+// interfaceInstallCall compares astarteInterface with the installed interfaces and returns the request installing
+// or updating it, if needed, along with the outcome of running it.
+func (c *Client) interfaceInstallCall(realm string, astarteInterface interfaces.AstarteInterface, nameInstalled bool) (AstarteRequest, ResourceInstallOutcome, error) {
+	majorInstalled := false
+	if nameInstalled {
+		majorsCall, err := c.ListInterfaceMajorVersions(realm, astarteInterface.Name)
+		if err != nil {
+			return nil, ResourceFailed, err
+		}
+		majors, err := runner.RunAndParse[[]int](c, majorsCall.Run)
+		if err != nil {
+			return nil, ResourceFailed, err
+		}
+		for _, major := range majors {
+			majorInstalled = majorInstalled || major == astarteInterface.MajorVersion
+		}
+	}
+	if !majorInstalled {
+		installCall, err := c.InstallInterface(realm, astarteInterface, false)
+		if err != nil {
+			return nil, ResourceFailed, err
+		}
+		return installCall, ResourceInstalled, nil
+	}
+
+	getCall, err := c.GetInterface(realm, astarteInterface.Name, astarteInterface.MajorVersion)
+	if err != nil {
+		return nil, ResourceFailed, err
+	}
+	existing, err := runner.RunAndParse[interfaces.AstarteInterface](c, getCall.Run)
+	switch {
+	case err != nil:
+		return nil, ResourceFailed, err
+	case interfaces.Fingerprint(existing) == interfaces.Fingerprint(astarteInterface):
+		return nil, ResourceUnchanged, nil
+	case existing.MinorVersion < astarteInterface.MinorVersion:
+		updateCall, err := c.UpdateInterface(realm, astarteInterface.Name, astarteInterface.MajorVersion, astarteInterface, false)
+		if err != nil {
+			return nil, ResourceFailed, err
+		}
+		return updateCall, ResourceUpdated, nil
+	}
+	return nil, ResourceConflicting, fmt.Errorf("A different interface %s is already installed with minor version %d",
+		interfaceKey(astarteInterface.Name, astarteInterface.MajorVersion), existing.MinorVersion)
+}
+
+func (c *Client) bootstrapGroup(realm, groupName string, deviceIDs []string, exists, dryRun bool) ResourceInstallResult {
+	result := ResourceInstallResult{Kind: "group", Name: groupName, Outcome: ResourceFailed}
+	if !exists {
+		createCall, err := c.CreateGroup(realm, groupName, deviceIDs)
+		if err == nil && len(deviceIDs) == 0 {
+			err = errors.New("Groups must hold at least one device")
+		}
+		if err == nil && !dryRun {
+			err = runWithoutParsing(c, createCall)
+		}
+		if result.Err = err; err == nil {
+			result.Outcome = ResourceInstalled
+		}
+		return result
+	}
+
+	groupDevices, err := c.listGroupDeviceIDs(realm, groupName)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Outcome = ResourceUnchanged
+	for _, deviceID := range deviceIDs {
+		if groupDevices[deviceID] {
+			continue
+		}
+		result.Outcome = ResourceUpdated
+		if dryRun {
+			continue
+		}
+		addCall, err := c.AddDeviceToGroup(realm, groupName, deviceID)
+		if err == nil {
+			err = runWithoutParsing(c, addCall)
+		}
+		if err != nil {
+			result.Outcome, result.Err = ResourceFailed, err
+			return result
+		}
+	}
+	return result
+}
+
+func (c *Client) listGroupDeviceIDs(realm, groupName string) (map[string]bool, error) {
+	paginator, err := c.ListGroupDevices(realm, groupName, DefaultMaxPageSize, DeviceIDFormat)
+	if err != nil {
+		return nil, err
+	}
+	deviceIDs := map[string]bool{}
+	for paginator.HasNextPage() {
+		nextPageCall, err := paginator.GetNextPage()
+		if err != nil {
+			return nil, err
+		}
+		page, err := runner.RunAndParse[[]string](c, nextPageCall.Run)
+		if err != nil {
+			return nil, err
+		}
+		for _, deviceID := range page {
+			deviceIDs[deviceID] = true
+		}
+	}
+	return deviceIDs, nil
+}
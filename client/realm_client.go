@@ -0,0 +1,321 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/interfaces"
+)
+
+// RealmClient is a Client bound to a realm: it exposes the AppEngine and Realm Management request builders
+// of Client without the realm argument. Requests built by a RealmClient can be run with its Client.
+type RealmClient struct {
+	client *Client
+	realm  string
+}
+
+// Realm returns a RealmClient bound to realm, sharing the configuration of c.
+func (c *Client) Realm(realm string) *RealmClient {
+	return &RealmClient{client: c, realm: realm}
+}
+
+// Name returns the name of the realm r is bound to.
+func (r *RealmClient) Name() string {
+	return r.realm
+}
+
+// Client returns the Client r uses to build requests, which should be used to run them.
+func (r *RealmClient) Client() *Client {
+	return r.client
+}
+
+// WithJWT returns a RealmClient bound to the same realm which authenticates with token rather than with
+// the credentials of the original Client, e.g. for backends holding a different token for each realm.
+func (r *RealmClient) WithJWT(token string) *RealmClient {
+	client := &Client{clientState: r.client.clientState, credentials: credentials{token: token}}
+	return &RealmClient{client: client, realm: r.realm}
+}
+
+// WithPrivateKey works like WithJWT, but tokens are generated from the realm private key privateKey.
+func (r *RealmClient) WithPrivateKey(privateKey []byte) *RealmClient {
+	client := &Client{clientState: r.client.clientState, credentials: credentials{privateKey: privateKey}}
+	return &RealmClient{client: client, realm: r.realm}
+}
+
+// AppEngine
+
+// GetDeviceListPaginator works like Client.GetDeviceListPaginator on the realm bound to r.
+func (r *RealmClient) GetDeviceListPaginator(pageSize int, format DeviceResultFormat) (Paginator, error) {
+	return r.client.GetDeviceListPaginator(r.realm, pageSize, format)
+}
+
+// GetDeviceDetails works like Client.GetDeviceDetails on the realm bound to r.
+func (r *RealmClient) GetDeviceDetails(deviceIdentifier string, deviceIdentifierType DeviceIdentifierType) (AstarteRequest, error) {
+	return r.client.GetDeviceDetails(r.realm, deviceIdentifier, deviceIdentifierType)
+}
+
+// GetDeviceIDFromAlias works like Client.GetDeviceIDFromAlias on the realm bound to r.
+func (r *RealmClient) GetDeviceIDFromAlias(deviceAlias string) (AstarteRequest, error) {
+	return r.client.GetDeviceIDFromAlias(r.realm, deviceAlias)
+}
+
+// ListDeviceInterfaces works like Client.ListDeviceInterfaces on the realm bound to r.
+func (r *RealmClient) ListDeviceInterfaces(deviceIdentifier string, deviceIdentifierType DeviceIdentifierType) (AstarteRequest, error) {
+	return r.client.ListDeviceInterfaces(r.realm, deviceIdentifier, deviceIdentifierType)
+}
+
+// GetDevicesStats works like Client.GetDevicesStats on the realm bound to r.
+func (r *RealmClient) GetDevicesStats() (AstarteRequest, error) {
+	return r.client.GetDevicesStats(r.realm)
+}
+
+// ListDeviceAliases works like Client.ListDeviceAliases on the realm bound to r.
+func (r *RealmClient) ListDeviceAliases(deviceIdentifier string, deviceIdentifierType DeviceIdentifierType) (AstarteRequest, error) {
+	return r.client.ListDeviceAliases(r.realm, deviceIdentifier, deviceIdentifierType)
+}
+
+// AddDeviceAlias works like Client.AddDeviceAlias on the realm bound to r.
+func (r *RealmClient) AddDeviceAlias(deviceID string, aliasTag string, deviceAlias string) (AstarteRequest, error) {
+	return r.client.AddDeviceAlias(r.realm, deviceID, aliasTag, deviceAlias)
+}
+
+// DeleteDeviceAlias works like Client.DeleteDeviceAlias on the realm bound to r.
+func (r *RealmClient) DeleteDeviceAlias(deviceID string, aliasTag string) (AstarteRequest, error) {
+	return r.client.DeleteDeviceAlias(r.realm, deviceID, aliasTag)
+}
+
+// GetDeviceInterfaceStats works like Client.GetDeviceInterfaceStats on the realm bound to r.
+func (r *RealmClient) GetDeviceInterfaceStats(deviceIdentifier string, deviceIdentifierType DeviceIdentifierType) (AstarteRequest, error) {
+	return r.client.GetDeviceInterfaceStats(r.realm, deviceIdentifier, deviceIdentifierType)
+}
+
+// PatchDeviceIntrospection works like Client.PatchDeviceIntrospection on the realm bound to r.
+func (r *RealmClient) PatchDeviceIntrospection(deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, patch IntrospectionPatch) (AstarteRequest, error) {
+	return r.client.PatchDeviceIntrospection(r.realm, deviceIdentifier, deviceIdentifierType, patch)
+}
+
+// SetDeviceInhibited works like Client.SetDeviceInhibited on the realm bound to r.
+func (r *RealmClient) SetDeviceInhibited(deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, inhibit bool) (AstarteRequest, error) {
+	return r.client.SetDeviceInhibited(r.realm, deviceIdentifier, deviceIdentifierType, inhibit)
+}
+
+// ListDeviceAttributes works like Client.ListDeviceAttributes on the realm bound to r.
+func (r *RealmClient) ListDeviceAttributes(deviceIdentifier string, deviceIdentifierType DeviceIdentifierType) (AstarteRequest, error) {
+	return r.client.ListDeviceAttributes(r.realm, deviceIdentifier, deviceIdentifierType)
+}
+
+// SetDeviceAttribute works like Client.SetDeviceAttribute on the realm bound to r.
+func (r *RealmClient) SetDeviceAttribute(deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, attributeKey, attributeValue string) (AstarteRequest, error) {
+	return r.client.SetDeviceAttribute(r.realm, deviceIdentifier, deviceIdentifierType, attributeKey, attributeValue)
+}
+
+// SetDeviceAttributes works like Client.SetDeviceAttributes on the realm bound to r.
+func (r *RealmClient) SetDeviceAttributes(deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, attributes map[string]string) (AstarteRequest, error) {
+	return r.client.SetDeviceAttributes(r.realm, deviceIdentifier, deviceIdentifierType, attributes)
+}
+
+// DeleteDeviceAttribute works like Client.DeleteDeviceAttribute on the realm bound to r.
+func (r *RealmClient) DeleteDeviceAttribute(deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, attributeKey string) (AstarteRequest, error) {
+	return r.client.DeleteDeviceAttribute(r.realm, deviceIdentifier, deviceIdentifierType, attributeKey)
+}
+
+// SetDeviceLabels works like Client.SetDeviceLabels on the realm bound to r.
+func (r *RealmClient) SetDeviceLabels(deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, labels map[string]string) (AstarteRequest, error) {
+	return r.client.SetDeviceLabels(r.realm, deviceIdentifier, deviceIdentifierType, labels)
+}
+
+// RemoveDeviceLabels works like Client.RemoveDeviceLabels on the realm bound to r.
+func (r *RealmClient) RemoveDeviceLabels(deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, labelKeys ...string) (AstarteRequest, error) {
+	return r.client.RemoveDeviceLabels(r.realm, deviceIdentifier, deviceIdentifierType, labelKeys...)
+}
+
+// ListGroups works like Client.ListGroups on the realm bound to r.
+func (r *RealmClient) ListGroups() (AstarteRequest, error) {
+	return r.client.ListGroups(r.realm)
+}
+
+// CreateGroup works like Client.CreateGroup on the realm bound to r.
+func (r *RealmClient) CreateGroup(groupName string, deviceIDList []string) (AstarteRequest, error) {
+	return r.client.CreateGroup(r.realm, groupName, deviceIDList)
+}
+
+// ListGroupDevices works like Client.ListGroupDevices on the realm bound to r.
+func (r *RealmClient) ListGroupDevices(groupName string, pageSize int, format DeviceResultFormat) (Paginator, error) {
+	return r.client.ListGroupDevices(r.realm, groupName, pageSize, format)
+}
+
+// AddDeviceToGroup works like Client.AddDeviceToGroup on the realm bound to r.
+func (r *RealmClient) AddDeviceToGroup(groupName, deviceID string) (AstarteRequest, error) {
+	return r.client.AddDeviceToGroup(r.realm, groupName, deviceID)
+}
+
+// RemoveDeviceFromGroup works like Client.RemoveDeviceFromGroup on the realm bound to r.
+func (r *RealmClient) RemoveDeviceFromGroup(groupName, deviceID string) (AstarteRequest, error) {
+	return r.client.RemoveDeviceFromGroup(r.realm, groupName, deviceID)
+}
+
+// GetDatastreamIndividualSnapshot works like Client.GetDatastreamIndividualSnapshot on the realm bound to r.
+func (r *RealmClient) GetDatastreamIndividualSnapshot(deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, interfaceName string, opts ...datastreamQueryOption) (AstarteRequest, error) {
+	return r.client.GetDatastreamIndividualSnapshot(r.realm, deviceIdentifier, deviceIdentifierType, interfaceName, opts...)
+}
+
+// GetDatastreamObjectSnapshot works like Client.GetDatastreamObjectSnapshot on the realm bound to r.
+func (r *RealmClient) GetDatastreamObjectSnapshot(deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, interfaceName string, opts ...datastreamQueryOption) (AstarteRequest, error) {
+	return r.client.GetDatastreamObjectSnapshot(r.realm, deviceIdentifier, deviceIdentifierType, interfaceName, opts...)
+}
+
+// GetDatastreamIndividualPaginator works like Client.GetDatastreamIndividualPaginator on the realm bound to r.
+func (r *RealmClient) GetDatastreamIndividualPaginator(deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, interfaceName, interfacePath string, resultSetOrder ResultSetOrder, pageSize int, opts ...datastreamQueryOption) (Paginator, error) {
+	return r.client.GetDatastreamIndividualPaginator(r.realm, deviceIdentifier, deviceIdentifierType, interfaceName, interfacePath, resultSetOrder, pageSize, opts...)
+}
+
+// GetDatastreamIndividualTimeWindowPaginator works like Client.GetDatastreamIndividualTimeWindowPaginator on the realm bound to r.
+func (r *RealmClient) GetDatastreamIndividualTimeWindowPaginator(deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, interfaceName, interfacePath string, since, to time.Time, resultSetOrder ResultSetOrder, pageSize int, opts ...datastreamQueryOption) (Paginator, error) {
+	return r.client.GetDatastreamIndividualTimeWindowPaginator(r.realm, deviceIdentifier, deviceIdentifierType, interfaceName, interfacePath, since, to, resultSetOrder, pageSize, opts...)
+}
+
+// GetDatastreamObjectPaginator works like Client.GetDatastreamObjectPaginator on the realm bound to r.
+func (r *RealmClient) GetDatastreamObjectPaginator(deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, interfaceName, interfacePath string, resultSetOrder ResultSetOrder, pageSize int, opts ...datastreamQueryOption) (Paginator, error) {
+	return r.client.GetDatastreamObjectPaginator(r.realm, deviceIdentifier, deviceIdentifierType, interfaceName, interfacePath, resultSetOrder, pageSize, opts...)
+}
+
+// GetDatastreamObjectTimeWindowPaginator works like Client.GetDatastreamObjectTimeWindowPaginator on the realm bound to r.
+func (r *RealmClient) GetDatastreamObjectTimeWindowPaginator(deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, interfaceName, interfacePath string, since, to time.Time, resultSetOrder ResultSetOrder, pageSize int, opts ...datastreamQueryOption) (Paginator, error) {
+	return r.client.GetDatastreamObjectTimeWindowPaginator(r.realm, deviceIdentifier, deviceIdentifierType, interfaceName, interfacePath, since, to, resultSetOrder, pageSize, opts...)
+}
+
+// GetAllProperties works like Client.GetAllProperties on the realm bound to r.
+func (r *RealmClient) GetAllProperties(deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, interfaceName string) (AstarteRequest, error) {
+	return r.client.GetAllProperties(r.realm, deviceIdentifier, deviceIdentifierType, interfaceName)
+}
+
+// GetProperty works like Client.GetProperty on the realm bound to r.
+func (r *RealmClient) GetProperty(deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, interfaceName string, interfacePath string) (AstarteRequest, error) {
+	return r.client.GetProperty(r.realm, deviceIdentifier, deviceIdentifierType, interfaceName, interfacePath)
+}
+
+// SendData works like Client.SendData on the realm bound to r.
+func (r *RealmClient) SendData(deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, astarteInterface interfaces.AstarteInterface, interfacePath string, payload any) (AstarteRequest, error) {
+	return r.client.SendData(r.realm, deviceIdentifier, deviceIdentifierType, astarteInterface, interfacePath, payload)
+}
+
+// SendDatastreamAt works like Client.SendDatastreamAt on the realm bound to r.
+func (r *RealmClient) SendDatastreamAt(deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, astarteInterface interfaces.AstarteInterface, interfacePath string, payload any, timestamp time.Time) (AstarteRequest, error) {
+	return r.client.SendDatastreamAt(r.realm, deviceIdentifier, deviceIdentifierType, astarteInterface, interfacePath, payload, timestamp)
+}
+
+// SendDatastream works like Client.SendDatastream on the realm bound to r.
+func (r *RealmClient) SendDatastream(deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, interfaceName, interfacePath string, payload any) (AstarteRequest, error) {
+	return r.client.SendDatastream(r.realm, deviceIdentifier, deviceIdentifierType, interfaceName, interfacePath, payload)
+}
+
+// SendDatastreamRaw works like Client.SendDatastreamRaw on the realm bound to r.
+func (r *RealmClient) SendDatastreamRaw(deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, interfaceName, interfacePath string, payload json.RawMessage) (AstarteRequest, error) {
+	return r.client.SendDatastreamRaw(r.realm, deviceIdentifier, deviceIdentifierType, interfaceName, interfacePath, payload)
+}
+
+// SetProperty works like Client.SetProperty on the realm bound to r.
+func (r *RealmClient) SetProperty(deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, interfaceName, interfacePath string, payload any) (AstarteRequest, error) {
+	return r.client.SetProperty(r.realm, deviceIdentifier, deviceIdentifierType, interfaceName, interfacePath, payload)
+}
+
+// UnsetProperty works like Client.UnsetProperty on the realm bound to r.
+func (r *RealmClient) UnsetProperty(deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, interfaceName string, interfacePath string) (AstarteRequest, error) {
+	return r.client.UnsetProperty(r.realm, deviceIdentifier, deviceIdentifierType, interfaceName, interfacePath)
+}
+
+// UnsetInterfaceProperty works like Client.UnsetInterfaceProperty on the realm bound to r.
+func (r *RealmClient) UnsetInterfaceProperty(deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, astarteInterface interfaces.AstarteInterface, interfacePath string) (AstarteRequest, error) {
+	return r.client.UnsetInterfaceProperty(r.realm, deviceIdentifier, deviceIdentifierType, astarteInterface, interfacePath)
+}
+
+// Realm Management
+
+// ListInterfaces works like Client.ListInterfaces on the realm bound to r.
+func (r *RealmClient) ListInterfaces() (AstarteRequest, error) {
+	return r.client.ListInterfaces(r.realm)
+}
+
+// ListInterfaceMajorVersions works like Client.ListInterfaceMajorVersions on the realm bound to r.
+func (r *RealmClient) ListInterfaceMajorVersions(interfaceName string) (AstarteRequest, error) {
+	return r.client.ListInterfaceMajorVersions(r.realm, interfaceName)
+}
+
+// GetInterface works like Client.GetInterface on the realm bound to r.
+func (r *RealmClient) GetInterface(interfaceName string, interfaceMajor int) (AstarteRequest, error) {
+	return r.client.GetInterface(r.realm, interfaceName, interfaceMajor)
+}
+
+// InstallInterface works like Client.InstallInterface on the realm bound to r.
+func (r *RealmClient) InstallInterface(interfacePayload interfaces.AstarteInterface, isAsync bool) (AstarteRequest, error) {
+	return r.client.InstallInterface(r.realm, interfacePayload, isAsync)
+}
+
+// DeleteDevice works like Client.DeleteDevice on the realm bound to r.
+func (r *RealmClient) DeleteDevice(deviceID string) (AstarteRequest, error) {
+	return r.client.DeleteDevice(r.realm, deviceID)
+}
+
+// DeleteInterface works like Client.DeleteInterface on the realm bound to r.
+func (r *RealmClient) DeleteInterface(interfaceName string, interfaceMajor int) (AstarteRequest, error) {
+	return r.client.DeleteInterface(r.realm, interfaceName, interfaceMajor)
+}
+
+// UpdateInterface works like Client.UpdateInterface on the realm bound to r.
+func (r *RealmClient) UpdateInterface(interfaceName string, interfaceMajor int, interfacePayload interfaces.AstarteInterface, isAsync bool) (AstarteRequest, error) {
+	return r.client.UpdateInterface(r.realm, interfaceName, interfaceMajor, interfacePayload, isAsync)
+}
+
+// ListTriggers works like Client.ListTriggers on the realm bound to r.
+func (r *RealmClient) ListTriggers() (AstarteRequest, error) {
+	return r.client.ListTriggers(r.realm)
+}
+
+// GetTrigger works like Client.GetTrigger on the realm bound to r.
+func (r *RealmClient) GetTrigger(triggerName string) (AstarteRequest, error) {
+	return r.client.GetTrigger(r.realm, triggerName)
+}
+
+// InstallTrigger works like Client.InstallTrigger on the realm bound to r.
+func (r *RealmClient) InstallTrigger(triggerPayload any) (AstarteRequest, error) {
+	return r.client.InstallTrigger(r.realm, triggerPayload)
+}
+
+// DeleteTrigger works like Client.DeleteTrigger on the realm bound to r.
+func (r *RealmClient) DeleteTrigger(triggerName string) (AstarteRequest, error) {
+	return r.client.DeleteTrigger(r.realm, triggerName)
+}
+
+// ListTriggerDeliveryPolicies works like Client.ListTriggerDeliveryPolicies on the realm bound to r.
+func (r *RealmClient) ListTriggerDeliveryPolicies() (AstarteRequest, error) {
+	return r.client.ListTriggerDeliveryPolicies(r.realm)
+}
+
+// GetTriggerDeliveryPolicy works like Client.GetTriggerDeliveryPolicy on the realm bound to r.
+func (r *RealmClient) GetTriggerDeliveryPolicy(policyName string) (AstarteRequest, error) {
+	return r.client.GetTriggerDeliveryPolicy(r.realm, policyName)
+}
+
+// InstallTriggerDeliveryPolicy works like Client.InstallTriggerDeliveryPolicy on the realm bound to r.
+func (r *RealmClient) InstallTriggerDeliveryPolicy(policyPayload any) (AstarteRequest, error) {
+	return r.client.InstallTriggerDeliveryPolicy(r.realm, policyPayload)
+}
+
+// DeleteTriggerDeliveryPolicy works like Client.DeleteTriggerDeliveryPolicy on the realm bound to r.
+func (r *RealmClient) DeleteTriggerDeliveryPolicy(policyName string) (AstarteRequest, error) {
+	return r.client.DeleteTriggerDeliveryPolicy(r.realm, policyName)
+}
@@ -19,110 +19,119 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/astarte-platform/astarte-go/deviceid"
 	"github.com/astarte-platform/astarte-go/interfaces"
+	"github.com/astarte-platform/astarte-go/internal/urlbuilder"
 	"moul.io/http2curl"
 )
 
 type ListInterfacesRequest struct {
 	req     *http.Request
-	expects int
+	expects []int
 }
 
 // ListInterfaces builds a request to return all interfaces in a Realm.
 func (c *Client) ListInterfaces(realm string) (AstarteRequest, error) {
-	callURL := makeURL(c.realmManagementURL, "/v1/%s/interfaces", realm)
+	callURL := urlbuilder.Build(c.realmManagementURL, "/v1/%s/interfaces", realm)
 	req := c.makeHTTPrequest(http.MethodGet, callURL, nil)
 
-	return ListInterfacesRequest{req: req, expects: 200}, nil
+	return ListInterfacesRequest{req: req, expects: []int{http.StatusOK}}, nil
 }
 
 // nolint:bodyclose
 func (r ListInterfacesRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return runAstarteRequestError(res, r.expects)
 	}
 	return ListInterfacesResponse{res: res}, nil
 }
 
 func (r ListInterfacesRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	return fmt.Sprint(command)
 }
 
 type ListInterfaceMajorVersionsRequest struct {
 	req     *http.Request
-	expects int
+	expects []int
 }
 
 // ListInterfaceMajorVersions builds a request to return all available major versions for a given Interface in a Realm.
 func (c *Client) ListInterfaceMajorVersions(realm string, interfaceName string) (AstarteRequest, error) {
-	callURL := makeURL(c.realmManagementURL, "/v1/%s/interfaces/%s", realm, interfaceName)
+	callURL := urlbuilder.Build(c.realmManagementURL, "/v1/%s/interfaces/%s", realm, interfaceName)
 	req := c.makeHTTPrequest(http.MethodGet, callURL, nil)
 
-	return ListInterfaceMajorVersionsRequest{req: req, expects: 200}, nil
+	return ListInterfaceMajorVersionsRequest{req: req, expects: []int{http.StatusOK}}, nil
 }
 
 // nolint:bodyclose
 func (r ListInterfaceMajorVersionsRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return runAstarteRequestError(res, r.expects)
 	}
 	return ListInterfaceMajorVersionsResponse{res: res}, nil
 }
 
 func (r ListInterfaceMajorVersionsRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	return fmt.Sprint(command)
 }
 
 type GetInterfaceRequest struct {
 	req     *http.Request
-	expects int
+	expects []int
 }
 
 // GetInterface builds a request retrieve an interface, identified by a Major version, in a Realm.
 func (c *Client) GetInterface(realm string, interfaceName string, interfaceMajor int) (AstarteRequest, error) {
-	callURL := makeURL(c.realmManagementURL, "/v1/%s/interfaces/%s/%s", realm, interfaceName, fmt.Sprintf("%v", interfaceMajor))
+	callURL := urlbuilder.Build(c.realmManagementURL, "/v1/%s/interfaces/%s/%s", realm, interfaceName, fmt.Sprintf("%v", interfaceMajor))
 	req := c.makeHTTPrequest(http.MethodGet, callURL, nil)
 
-	return GetInterfaceRequest{req: req, expects: 200}, nil
+	return GetInterfaceRequest{req: req, expects: []int{http.StatusOK}}, nil
 }
 
 // nolint:bodyclose
 func (r GetInterfaceRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return runAstarteRequestError(res, r.expects)
 	}
 	return GetInterfaceResponse{res: res}, nil
 }
 
 func (r GetInterfaceRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	return fmt.Sprint(command)
 }
 
 type InstallInterfaceRequest struct {
 	req     *http.Request
-	expects int
+	expects []int
+	// realm and astarteInterface are used to check the retention of the interface, see checkRetention
+	realm            string
+	astarteInterface interfaces.AstarteInterface
 }
 
 // InstallInterface builds a request to install a new major version of an Interface into the Realm.
+// If Astarte accepts the request for later processing, Run returns an AsyncAcceptedResponse. Astarte 1.0 always
+// processes interfaces asynchronously, hence isAsync is ignored if the client targets it.
+// The retention of the interface is checked against the limits of the realm when the request is run, if the client
+// is configured to do so, see WithStrictRetention and WithRetentionWarningHandler.
 func (c *Client) InstallInterface(realm string, interfacePayload interfaces.AstarteInterface, isAsync bool) (AstarteRequest, error) {
-	callURL := makeURL(c.realmManagementURL, "/v1/%s/interfaces", realm)
+	callURL := urlbuilder.Build(c.realmManagementURL, "/v1/%s/interfaces", realm)
 
-	if !isAsync {
+	if !isAsync && c.supportsSynchronousOperations() {
 		query := map[string]string{"async_operation": strconv.FormatBool(false)}
 		callURL = setupURLQuery(callURL, query)
 	}
@@ -130,66 +139,192 @@ func (c *Client) InstallInterface(realm string, interfacePayload interfaces.Asta
 	payload, _ := makeBody(interfacePayload)
 	req := c.makeHTTPrequest(http.MethodPost, callURL, payload)
 
-	return InstallInterfaceRequest{req: req, expects: 201}, nil
+	return InstallInterfaceRequest{req: req, expects: []int{http.StatusCreated, http.StatusOK}, realm: realm, astarteInterface: interfacePayload}, nil
 }
 
 // nolint:bodyclose
 func (r InstallInterfaceRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	if err := c.checkRetention(r.realm, r.astarteInterface); err != nil {
+		return Empty{}, err
+	}
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
+	if res.StatusCode == http.StatusAccepted {
+		return AsyncAcceptedResponse{res: res}, nil
+	}
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return runAstarteRequestError(res, r.expects)
 	}
 	return InstallInterfaceResponse{res: res}, nil
 }
 
 func (r InstallInterfaceRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
+	return fmt.Sprint(command)
+}
+
+// RetentionWarning describes an interface whose retention is not valid for the realm it is installed in,
+// see WithRetentionWarningHandler.
+type RetentionWarning struct {
+	Realm         string
+	InterfaceName string
+	MajorVersion  int
+	MinorVersion  int
+	Limits        interfaces.RetentionLimits
+	// Err joins the errors for each offending mapping, see interfaces.ValidateRetention.
+	Err error
+}
+
+func (w RetentionWarning) String() string {
+	return fmt.Sprintf("%s: interface %s %d.%d: %v", w.Realm, w.InterfaceName, w.MajorVersion, w.MinorVersion, w.Err)
+}
+
+// checkRetention validates the retention of astarteInterface against the limits of realm, if the client is
+// configured to do so with WithStrictRetention or WithRetentionWarningHandler. In strict mode, failing to
+// discover the limits of the realm fails the check.
+func (c *Client) checkRetention(realm string, astarteInterface interfaces.AstarteInterface) error {
+	if !c.strictRetention && c.retentionWarningHandler == nil {
+		return nil
+	}
+	limits, err := c.realmRetentionLimits(realm)
+	if err != nil {
+		if c.strictRetention {
+			return fmt.Errorf("Could not check the retention of interface %s: %w", astarteInterface.Name, err)
+		}
+		// The realm limits are not exposed to the client, fall back to the ones of Astarte
+		limits = interfaces.DefaultRetentionLimits()
+	}
+	err = interfaces.ValidateRetention(astarteInterface, limits)
+	if err == nil {
+		return nil
+	}
+	if c.strictRetention {
+		return err
+	}
+	c.retentionWarningHandler(RetentionWarning{Realm: realm, InterfaceName: astarteInterface.Name, MajorVersion: astarteInterface.MajorVersion,
+		MinorVersion: astarteInterface.MinorVersion, Limits: limits, Err: err})
+	return nil
+}
+
+// realmRetentionLimits returns the retention limits of realm, fetching them with GetInterfaceRetentionLimits
+// the first time they are needed.
+func (c *Client) realmRetentionLimits(realm string) (interfaces.RetentionLimits, error) {
+	if cached, ok := c.retentionLimitsCache.Load(realm); ok {
+		return cached.(interfaces.RetentionLimits), nil
+	}
+	limits, err := c.GetInterfaceRetentionLimits(realm)
+	if err != nil {
+		return limits, err
+	}
+	c.retentionLimitsCache.Store(realm, limits)
+	return limits, nil
+}
+
+// ClearRetentionLimitsCache removes the realm retention limits cached to check the retention of interfaces,
+// see WithStrictRetention. This is needed when the settings of a realm are changed by another client: UpdateRealm
+// clears the limits of the realm it updates.
+func (c *Client) ClearRetentionLimitsCache() {
+	c.retentionLimitsCache.Range(func(key, _ any) bool {
+		c.retentionLimitsCache.Delete(key)
+		return true
+	})
+}
+
+type DeleteDeviceRequest struct {
+	req     *http.Request
+	expects []int
+}
+
+// DeleteDevice builds a request to delete a device and all its data from the Realm. Astarte deletes devices
+// asynchronously: once the request is run, the device keeps existing until its deletion is completed,
+// see WaitForDeviceDeletion. Device deletion was introduced in Astarte 1.2.
+func (c *Client) DeleteDevice(realm, deviceID string) (AstarteRequest, error) {
+	if err := c.requireAstarteVersion("device deletion", 1, 2); err != nil {
+		return Empty{}, err
+	}
+	if !deviceid.IsValid(deviceID) {
+		return Empty{}, ErrInvalidDeviceID(deviceID)
+	}
+	callURL := urlbuilder.Build(c.realmManagementURL, "/v1/%s/devices/%s", realm, deviceID)
+	req := c.makeHTTPrequest(http.MethodDelete, callURL, nil)
+
+	return DeleteDeviceRequest{req: req, expects: []int{http.StatusNoContent, http.StatusOK}}, nil
+}
+
+// nolint:bodyclose
+func (r DeleteDeviceRequest) Run(c *Client) (AstarteResponse, error) {
+	res, err := c.do(r.req)
+	if err != nil {
+		return Empty{}, err
+	}
+	if res.StatusCode == http.StatusAccepted {
+		return AsyncAcceptedResponse{res: res}, nil
+	}
+	if !c.isExpectedStatusCode(res, r.expects) {
+		return runAstarteRequestError(res, r.expects)
+	}
+	return NoDataResponse{res: res}, nil
+}
+
+func (r DeleteDeviceRequest) ToCurl(_ *Client) string {
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	return fmt.Sprint(command)
 }
 
 type DeleteInterfaceRequest struct {
 	req     *http.Request
-	expects int
+	expects []int
 }
 
 // DeleteInterface builds a request to delete a major version of an Interface into the Realm.
+// If Astarte accepts the request for later processing, Run returns an AsyncAcceptedResponse.
+// Use CanSafelyDeleteInterface beforehand to check whether devices still declare the major version.
 func (c *Client) DeleteInterface(realm string, interfaceName string, interfaceMajor int) (AstarteRequest, error) {
-	callURL := makeURL(c.realmManagementURL, "/v1/%s/interfaces/%s/%s", realm, interfaceName, fmt.Sprintf("%v", interfaceMajor))
+	callURL := urlbuilder.Build(c.realmManagementURL, "/v1/%s/interfaces/%s/%s", realm, interfaceName, fmt.Sprintf("%v", interfaceMajor))
 	req := c.makeHTTPrequest(http.MethodDelete, callURL, nil)
 
-	return DeleteInterfaceRequest{req: req, expects: 204}, nil
+	return DeleteInterfaceRequest{req: req, expects: []int{http.StatusNoContent, http.StatusOK}}, nil
 }
 
 // nolint:bodyclose
 func (r DeleteInterfaceRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
+	if res.StatusCode == http.StatusAccepted {
+		return AsyncAcceptedResponse{res: res}, nil
+	}
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return runAstarteRequestError(res, r.expects)
 	}
 	return NoDataResponse{res: res}, nil
 }
 
 func (r DeleteInterfaceRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	return fmt.Sprint(command)
 }
 
 type UpdateInterfaceRequest struct {
 	req     *http.Request
-	expects int
+	expects []int
+	// realm and astarteInterface are used to check the retention of the interface, see checkRetention
+	realm            string
+	astarteInterface interfaces.AstarteInterface
 }
 
 // UpdateInterface builds a request to update an existing major version of an Interface to a new minor.
+// If Astarte accepts the request for later processing, Run returns an AsyncAcceptedResponse. Astarte 1.0 always
+// processes interfaces asynchronously, hence isAsync is ignored if the client targets it.
+// The retention of the interface is checked against the limits of the realm when the request is run, if the client
+// is configured to do so, see WithStrictRetention and WithRetentionWarningHandler.
 func (c *Client) UpdateInterface(realm string, interfaceName string, interfaceMajor int, interfacePayload interfaces.AstarteInterface, isAsync bool) (AstarteRequest, error) {
-	callURL := makeURL(c.realmManagementURL, "/v1/%s/interfaces/%s/%s", realm, interfaceName, fmt.Sprintf("%v", interfaceMajor))
+	callURL := urlbuilder.Build(c.realmManagementURL, "/v1/%s/interfaces/%s/%s", realm, interfaceName, fmt.Sprintf("%v", interfaceMajor))
 
-	if !isAsync {
+	if !isAsync && c.supportsSynchronousOperations() {
 		query := map[string]string{"async_operation": strconv.FormatBool(false)}
 		callURL = setupURLQuery(callURL, query)
 	}
@@ -197,264 +332,282 @@ func (c *Client) UpdateInterface(realm string, interfaceName string, interfaceMa
 	payload, _ := makeBody(interfacePayload)
 	req := c.makeHTTPrequest(http.MethodPut, callURL, payload)
 
-	return UpdateInterfaceRequest{req: req, expects: 204}, nil
+	return UpdateInterfaceRequest{req: req, expects: []int{http.StatusNoContent, http.StatusOK}, realm: realm, astarteInterface: interfacePayload}, nil
 }
 
 // nolint:bodyclose
 func (r UpdateInterfaceRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	if err := c.checkRetention(r.realm, r.astarteInterface); err != nil {
+		return Empty{}, err
+	}
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
+	if res.StatusCode == http.StatusAccepted {
+		return AsyncAcceptedResponse{res: res}, nil
+	}
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return runAstarteRequestError(res, r.expects)
 	}
 	return NoDataResponse{res: res}, nil
 }
 
 func (r UpdateInterfaceRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	return fmt.Sprint(command)
 }
 
 type ListTriggersRequest struct {
 	req     *http.Request
-	expects int
+	expects []int
 }
 
 // ListTriggers builds a request to return all triggers in a Realm.
 func (c *Client) ListTriggers(realm string) (AstarteRequest, error) {
-	callURL := makeURL(c.realmManagementURL, "/v1/%s/triggers", realm)
+	callURL := urlbuilder.Build(c.realmManagementURL, "/v1/%s/triggers", realm)
 	req := c.makeHTTPrequest(http.MethodGet, callURL, nil)
 
-	return ListTriggersRequest{req: req, expects: 200}, nil
+	return ListTriggersRequest{req: req, expects: []int{http.StatusOK}}, nil
 }
 
 // nolint:bodyclose
 func (r ListTriggersRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return runAstarteRequestError(res, r.expects)
 	}
 	return ListTriggersResponse{res: res}, nil
 }
 
 func (r ListTriggersRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	return fmt.Sprint(command)
 }
 
 type GetTriggerRequest struct {
 	req     *http.Request
-	expects int
+	expects []int
 }
 
 // GetTrigger builds a request to return a trigger installed in a Realm.
 func (c *Client) GetTrigger(realm string, triggerName string) (AstarteRequest, error) {
-	callURL := makeURL(c.realmManagementURL, "/v1/%s/triggers/%s", realm, triggerName)
+	callURL := urlbuilder.Build(c.realmManagementURL, "/v1/%s/triggers/%s", realm, triggerName)
 	req := c.makeHTTPrequest(http.MethodGet, callURL, nil)
 
-	return GetTriggerRequest{req: req, expects: 200}, nil
+	return GetTriggerRequest{req: req, expects: []int{http.StatusOK}}, nil
 }
 
 // nolint:bodyclose
 func (r GetTriggerRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return runAstarteRequestError(res, r.expects)
 	}
 	return GetTriggerResponse{res: res}, nil
 }
 
 func (r GetTriggerRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	return fmt.Sprint(command)
 }
 
 type InstallTriggerRequest struct {
 	req     *http.Request
-	expects int
+	expects []int
 }
 
 // InstallTrigger builds a request to install a Trigger into the Realm.
 func (c *Client) InstallTrigger(realm string, triggerPayload any) (AstarteRequest, error) {
-	callURL := makeURL(c.realmManagementURL, "/v1/%s/triggers", realm)
+	callURL := urlbuilder.Build(c.realmManagementURL, "/v1/%s/triggers", realm)
 	payload, _ := makeBody(triggerPayload)
 	req := c.makeHTTPrequest(http.MethodPost, callURL, payload)
 
-	return InstallTriggerRequest{req: req, expects: 201}, nil
+	return InstallTriggerRequest{req: req, expects: []int{http.StatusCreated, http.StatusOK}}, nil
 }
 
 // nolint:bodyclose
 func (r InstallTriggerRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return runAstarteRequestError(res, r.expects)
 	}
 	return InstallTriggerResponse{res: res}, nil
 }
 
 func (r InstallTriggerRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	return fmt.Sprint(command)
 }
 
 type DeleteTriggerRequest struct {
 	req     *http.Request
-	expects int
+	expects []int
 }
 
 // DeleteTrigger builds a request to delete a Trigger from the Realm.
 func (c *Client) DeleteTrigger(realm string, triggerName string) (AstarteRequest, error) {
-	callURL := makeURL(c.realmManagementURL, "/v1/%s/triggers/%s", realm, triggerName)
+	callURL := urlbuilder.Build(c.realmManagementURL, "/v1/%s/triggers/%s", realm, triggerName)
 	req := c.makeHTTPrequest(http.MethodDelete, callURL, nil)
 
-	return DeleteTriggerRequest{req: req, expects: 204}, nil
+	return DeleteTriggerRequest{req: req, expects: []int{http.StatusNoContent, http.StatusOK}}, nil
 }
 
 // nolint:bodyclose
 func (r DeleteTriggerRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return runAstarteRequestError(res, r.expects)
 	}
 	return NoDataResponse{res: res}, nil
 }
 
 func (r DeleteTriggerRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	return fmt.Sprint(command)
 }
 
 type ListTriggerDeliveryPoliciesRequest struct {
 	req     *http.Request
-	expects int
+	expects []int
 }
 
 // ListTriggerDeliveryPolicies builds a request to return all triggers delivery policies in a Realm.
 func (c *Client) ListTriggerDeliveryPolicies(realm string) (AstarteRequest, error) {
-	callURL := makeURL(c.realmManagementURL, "/v1/%s/policies", realm)
+	if err := c.requireAstarteVersion("trigger delivery policies", 1, 1); err != nil {
+		return Empty{}, err
+	}
+	callURL := urlbuilder.Build(c.realmManagementURL, "/v1/%s/policies", realm)
 	req := c.makeHTTPrequest(http.MethodGet, callURL, nil)
 
-	return ListTriggersRequest{req: req, expects: 200}, nil
+	return ListTriggersRequest{req: req, expects: []int{http.StatusOK}}, nil
 }
 
 // nolint:bodyclose
 func (r ListTriggerDeliveryPoliciesRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return runAstarteRequestError(res, r.expects)
 	}
 	return ListTriggerDeliveryPoliciesResponse{res: res}, nil
 }
 
 func (r ListTriggerDeliveryPoliciesRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	return fmt.Sprint(command)
 }
 
 type GetTriggerDeliveryPolicyRequest struct {
 	req     *http.Request
-	expects int
+	expects []int
 }
 
 // GetTriggerDeliveryPolicy builds a request to return a trigger delivery policy installed in a Realm.
 func (c *Client) GetTriggerDeliveryPolicy(realm string, policyName string) (AstarteRequest, error) {
-	callURL := makeURL(c.realmManagementURL, "/v1/%s/policies/%s", realm, policyName)
+	if err := c.requireAstarteVersion("trigger delivery policies", 1, 1); err != nil {
+		return Empty{}, err
+	}
+	callURL := urlbuilder.Build(c.realmManagementURL, "/v1/%s/policies/%s", realm, policyName)
 	req := c.makeHTTPrequest(http.MethodGet, callURL, nil)
 
-	return GetTriggerDeliveryPolicyRequest{req: req, expects: 200}, nil
+	return GetTriggerDeliveryPolicyRequest{req: req, expects: []int{http.StatusOK}}, nil
 }
 
 // nolint:bodyclose
 func (r GetTriggerDeliveryPolicyRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return runAstarteRequestError(res, r.expects)
 	}
 	return GetTriggerDeliveryPolicyResponse{res: res}, nil
 }
 
 func (r GetTriggerDeliveryPolicyRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	return fmt.Sprint(command)
 }
 
 type InstallTriggerDeliveryPolicyRequest struct {
 	req     *http.Request
-	expects int
+	expects []int
 }
 
 // InstallTriggerDeliveryPolicy builds a request to install a Trigger delivery policy into the Realm.
 func (c *Client) InstallTriggerDeliveryPolicy(realm string, policyPayload any) (AstarteRequest, error) {
-	callURL := makeURL(c.realmManagementURL, "/v1/%s/policies", realm)
+	if err := c.requireAstarteVersion("trigger delivery policies", 1, 1); err != nil {
+		return Empty{}, err
+	}
+	callURL := urlbuilder.Build(c.realmManagementURL, "/v1/%s/policies", realm)
 	payload, _ := makeBody(policyPayload)
 	req := c.makeHTTPrequest(http.MethodPost, callURL, payload)
 
-	return InstallTriggerDeliveryPolicyRequest{req: req, expects: 201}, nil
+	return InstallTriggerDeliveryPolicyRequest{req: req, expects: []int{http.StatusCreated, http.StatusOK}}, nil
 }
 
 // nolint:bodyclose
 func (r InstallTriggerDeliveryPolicyRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return runAstarteRequestError(res, r.expects)
 	}
 	return InstallTriggerDeliveryPolicyResponse{res: res}, nil
 }
 
 func (r InstallTriggerDeliveryPolicyRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	return fmt.Sprint(command)
 }
 
 type DeleteTriggerDeliveryPolicyRequest struct {
 	req     *http.Request
-	expects int
+	expects []int
 }
 
 // DeleteTriggerDeliveryPolicy builds a request to delete a Trigger delivery policy from the Realm.
 func (c *Client) DeleteTriggerDeliveryPolicy(realm string, policyName string) (AstarteRequest, error) {
-	callURL := makeURL(c.realmManagementURL, "/v1/%s/policies/%s", realm, policyName)
+	if err := c.requireAstarteVersion("trigger delivery policies", 1, 1); err != nil {
+		return Empty{}, err
+	}
+	callURL := urlbuilder.Build(c.realmManagementURL, "/v1/%s/policies/%s", realm, policyName)
 	req := c.makeHTTPrequest(http.MethodDelete, callURL, nil)
 
-	return DeleteTriggerDeliveryPolicyRequest{req: req, expects: 204}, nil
+	return DeleteTriggerDeliveryPolicyRequest{req: req, expects: []int{http.StatusNoContent, http.StatusOK}}, nil
 }
 
 // nolint:bodyclose
 func (r DeleteTriggerDeliveryPolicyRequest) Run(c *Client) (AstarteResponse, error) {
-	res, err := c.httpClient.Do(r.req)
+	res, err := c.do(r.req)
 	if err != nil {
 		return Empty{}, err
 	}
-	if res.StatusCode != r.expects {
+	if !c.isExpectedStatusCode(res, r.expects) {
 		return runAstarteRequestError(res, r.expects)
 	}
 	return NoDataResponse{res: res}, nil
 }
 
 func (r DeleteTriggerDeliveryPolicyRequest) ToCurl(_ *Client) string {
-	command, _ := http2curl.GetCurlCommand(r.req)
+	command, _ := http2curl.GetCurlCommand(cloneRequest(r.req))
 	return fmt.Sprint(command)
 }
@@ -0,0 +1,302 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/astarte-platform/astarte-go/internal/runner"
+	"github.com/astarte-platform/astarte-go/policies"
+	"github.com/astarte-platform/astarte-go/triggers"
+)
+
+// ResourceInstallOutcome is the outcome of the installation of a single resource by InstallTriggersAndPolicies.
+type ResourceInstallOutcome int
+
+const (
+	// ResourceInstalled means the resource was installed.
+	ResourceInstalled ResourceInstallOutcome = iota
+	// ResourceUnchanged means an identical resource was already installed, and the installation was skipped.
+	ResourceUnchanged
+	// ResourceConflicting means a different resource with the same name was already installed. Triggers
+	// and trigger delivery policies cannot be updated, so the existing resource must be deleted first.
+	ResourceConflicting
+	// ResourceFailed means the resource could not be installed.
+	ResourceFailed
+	// ResourceUpdated means an older version of the resource was already installed, and it was updated,
+	// e.g. an interface with the same major version and a lower minor version. See BootstrapRealm.
+	ResourceUpdated
+)
+
+func (o ResourceInstallOutcome) String() string {
+	switch o {
+	case ResourceInstalled:
+		return "installed"
+	case ResourceUnchanged:
+		return "unchanged"
+	case ResourceConflicting:
+		return "conflicting"
+	case ResourceFailed:
+		return "failed"
+	case ResourceUpdated:
+		return "updated"
+	}
+	return fmt.Sprintf("ResourceInstallOutcome(%d)", int(o))
+}
+
+// ResourceInstallResult reports the outcome of the installation of a trigger or trigger delivery policy, or of
+// the other resources installed by BootstrapRealm.
+type ResourceInstallResult struct {
+	// Kind is either "policy" or "trigger", or "interface" or "group" for BootstrapRealm.
+	Kind    string
+	Name    string
+	Outcome ResourceInstallOutcome
+	// Err is set when Outcome is ResourceConflicting or ResourceFailed.
+	Err error
+}
+
+var errMissingResourceName = errors.New("Resource has no name")
+
+// InstallTriggersAndPolicies installs trigger delivery policies and triggers in realm. Policies are installed
+// before triggers, and triggers referencing a policy which could not be installed are not installed at all.
+// Resources which are already installed are compared with the given ones after normalizing both: triggers by
+// their triggers.Fingerprint, which fills in defaults, and policies by their policies.AstarteTriggerDeliveryPolicy
+// representation, so that fields added or omitted by Astarte don't cause conflicts. Identical resources are
+// skipped, different ones are reported as conflicting.
+// policies and triggers can be any value which marshals to a valid payload, such as the
+// policies.AstarteTriggerDeliveryPolicy type or a map[string]any read from a JSON file.
+// A result is returned for each resource, in installation order. The returned error is set only if the
+// installed resources cannot be listed.
+func (c *Client) InstallTriggersAndPolicies(realm string, policies, triggers []any) ([]ResourceInstallResult, error) {
+	return c.installTriggersAndPolicies(realm, policies, triggers, false, nil)
+}
+
+// installTriggersAndPolicies works like InstallTriggersAndPolicies. If dryRun is set, resources are compared with
+// the installed ones but not installed. Triggers on an interface in unavailableInterfaces (see interfaceKey) are
+// not installed.
+func (c *Client) installTriggersAndPolicies(realm string, policies, triggers []any, dryRun bool, unavailableInterfaces map[string]bool) ([]ResourceInstallResult, error) {
+	installedPolicies, err := c.listInstalledResources(c.ListTriggerDeliveryPolicies(realm))
+	if err != nil {
+		return nil, err
+	}
+	installedTriggers, err := c.listInstalledResources(c.ListTriggers(realm))
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]ResourceInstallResult, 0, len(policies)+len(triggers))
+	unavailablePolicies := map[string]bool{}
+	for _, policy := range policies {
+		result := c.installResource(policy, "policy", policyFingerprint, installedPolicies,
+			func(name string) (AstarteRequest, error) { return c.GetTriggerDeliveryPolicy(realm, name) },
+			func(payload any) (AstarteRequest, error) { return c.InstallTriggerDeliveryPolicy(realm, payload) }, dryRun)
+		if result.Outcome == ResourceFailed && result.Name != "" {
+			unavailablePolicies[result.Name] = true
+		}
+		results = append(results, result)
+	}
+	for _, trigger := range triggers {
+		if policyName := triggerPolicyName(trigger); unavailablePolicies[policyName] {
+			name, _ := resourceName(trigger)
+			err := fmt.Errorf("Trigger delivery policy %s could not be installed", policyName)
+			results = append(results, ResourceInstallResult{Kind: "trigger", Name: name, Outcome: ResourceFailed, Err: err})
+			continue
+		}
+		if interfaceName := triggerInterface(trigger, unavailableInterfaces); interfaceName != "" {
+			name, _ := resourceName(trigger)
+			err := fmt.Errorf("Interface %s could not be installed", interfaceName)
+			results = append(results, ResourceInstallResult{Kind: "trigger", Name: name, Outcome: ResourceFailed, Err: err})
+			continue
+		}
+		results = append(results, c.installResource(trigger, "trigger", triggerFingerprint, installedTriggers,
+			func(name string) (AstarteRequest, error) { return c.GetTrigger(realm, name) },
+			func(payload any) (AstarteRequest, error) { return c.InstallTrigger(realm, payload) }, dryRun))
+	}
+	return results, nil
+}
+
+func (c *Client) listInstalledResources(listCall AstarteRequest, err error) (map[string]bool, error) {
+	if err != nil {
+		return nil, err
+	}
+	names, err := runner.RunAndParse[[]string](c, listCall.Run)
+	if err != nil {
+		return nil, err
+	}
+	installed := make(map[string]bool, len(names))
+	for _, name := range names {
+		installed[name] = true
+	}
+	return installed, nil
+}
+
+func (c *Client) installResource(payload any, kind string, fingerprint func(payload any) (string, error), installed map[string]bool,
+	get func(name string) (AstarteRequest, error), install func(payload any) (AstarteRequest, error), dryRun bool) ResourceInstallResult {
+	name, err := resourceName(payload)
+	result := ResourceInstallResult{Kind: kind, Name: name, Outcome: ResourceFailed, Err: err}
+	if err != nil {
+		return result
+	}
+
+	if installed[name] {
+		getCall, err := get(name)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		existing, err := runner.RunAndParse[map[string]any](c, getCall.Run)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		if equal, err := sameFingerprint(fingerprint, existing, payload); err != nil {
+			result.Err = err
+		} else if equal {
+			result.Outcome, result.Err = ResourceUnchanged, nil
+		} else {
+			result.Outcome, result.Err = ResourceConflicting, fmt.Errorf("A different %s named %s is already installed", kind, name)
+		}
+		return result
+	}
+
+	installCall, err := install(payload)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	if dryRun {
+		result.Outcome, result.Err = ResourceInstalled, nil
+		return result
+	}
+	if err := runWithoutParsing(c, installCall); err != nil {
+		result.Err = err
+		return result
+	}
+	result.Outcome, result.Err = ResourceInstalled, nil
+	return result
+}
+
+// toJSONObject converts a resource payload to its generic JSON representation.
+func toJSONObject(payload any) (map[string]any, error) {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	object := map[string]any{}
+	if err := json.Unmarshal(encoded, &object); err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+func resourceName(payload any) (string, error) {
+	object, err := toJSONObject(payload)
+	if err != nil {
+		return "", err
+	}
+	name, _ := object["name"].(string)
+	if name == "" {
+		return "", errMissingResourceName
+	}
+	return name, nil
+}
+
+func triggerPolicyName(trigger any) string {
+	object, err := toJSONObject(trigger)
+	if err != nil {
+		return ""
+	}
+	policyName, _ := object["policy"].(string)
+	return policyName
+}
+
+// triggerInterface returns the name of the first interface in unavailableInterfaces (see interfaceKey) which is
+// referenced by the simple triggers of trigger, or an empty string if there is none. Simple triggers on any major
+// of an interface reference all of its majors.
+func triggerInterface(trigger any, unavailableInterfaces map[string]bool) string {
+	if len(unavailableInterfaces) == 0 {
+		return ""
+	}
+	object, err := toJSONObject(trigger)
+	if err != nil {
+		return ""
+	}
+	simpleTriggers, _ := object["simple_triggers"].([]any)
+	for _, simpleTrigger := range simpleTriggers {
+		simpleTriggerObject, _ := simpleTrigger.(map[string]any)
+		interfaceName, _ := simpleTriggerObject["interface_name"].(string)
+		if interfaceName == "" {
+			continue
+		}
+		// interface_major is either a number or a string holding it, and triggers on any major use "*"
+		major := fmt.Sprint(simpleTriggerObject["interface_major"])
+		if unavailableInterfaces[interfaceName+" v"+major] {
+			return interfaceName
+		}
+		if major == "*" {
+			for key := range unavailableInterfaces {
+				if strings.HasPrefix(key, interfaceName+" v") {
+					return interfaceName
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// sameFingerprint compares a and b by their fingerprint.
+func sameFingerprint(fingerprint func(payload any) (string, error), a, b any) (bool, error) {
+	fingerprintA, err := fingerprint(a)
+	if err != nil {
+		return false, err
+	}
+	fingerprintB, err := fingerprint(b)
+	if err != nil {
+		return false, err
+	}
+	return fingerprintA == fingerprintB, nil
+}
+
+// triggerFingerprint decodes a trigger payload and returns its triggers.Fingerprint.
+func triggerFingerprint(payload any) (string, error) {
+	trigger := triggers.AstarteTrigger{}
+	if err := decodeResource(payload, &trigger); err != nil {
+		return "", err
+	}
+	return triggers.Fingerprint(trigger), nil
+}
+
+// policyFingerprint decodes a policy payload and returns its JSON representation, in which omitted fields
+// and unknown fields added by Astarte are normalized away.
+func policyFingerprint(payload any) (string, error) {
+	policy := policies.AstarteTriggerDeliveryPolicy{}
+	if err := decodeResource(payload, &policy); err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(policy)
+	return string(b), err
+}
+
+// decodeResource decodes a resource payload into v through its JSON representation.
+func decodeResource(payload any, v any) error {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(encoded, v)
+}
@@ -15,24 +15,20 @@
 package client
 
 import (
-	"encoding/json"
-	"io"
 	"net/http"
 
 	"github.com/astarte-platform/astarte-go/interfaces"
-	"github.com/tidwall/gjson"
 )
 
 // Parses data obtained by performing a request to list interfaces in a realm.
 // Returns the list of interface names as an array of strings.
 func (r ListInterfacesResponse) Parse() (any, error) {
 	defer r.res.Body.Close()
-	b, _ := io.ReadAll(r.res.Body)
-	ret := []string{}
-	for _, v := range gjson.GetBytes(b, "data").Array() {
-		ret = append(ret, v.Str)
+	payload, err := decodeAstartePayload(r.res.Body, []string{})
+	if err != nil {
+		return nil, err
 	}
-	return ret, nil
+	return payload.Data, nil
 }
 func (r ListInterfacesResponse) Raw(f func(*http.Response) any) any {
 	defer r.res.Body.Close()
@@ -43,12 +39,11 @@ func (r ListInterfacesResponse) Raw(f func(*http.Response) any) any {
 // Returns the list of versions as an array of ints.
 func (r ListInterfaceMajorVersionsResponse) Parse() (any, error) {
 	defer r.res.Body.Close()
-	b, _ := io.ReadAll(r.res.Body)
-	ret := []int{}
-	for _, v := range gjson.GetBytes(b, "data").Array() {
-		ret = append(ret, int(v.Num))
+	payload, err := decodeAstartePayload(r.res.Body, []int{})
+	if err != nil {
+		return nil, err
 	}
-	return ret, nil
+	return payload.Data, nil
 }
 func (r ListInterfaceMajorVersionsResponse) Raw(f func(*http.Response) any) any {
 	defer r.res.Body.Close()
@@ -59,13 +54,11 @@ func (r ListInterfaceMajorVersionsResponse) Raw(f func(*http.Response) any) any
 // Returns the interface as an interfaces.AstarteInterface.
 func (r GetInterfaceResponse) Parse() (any, error) {
 	defer r.res.Body.Close()
-	b, _ := io.ReadAll(r.res.Body)
-	v := []byte(gjson.GetBytes(b, "data").Raw)
-	ret := interfaces.AstarteInterface{}
-	// TODO check err
-	_ = json.Unmarshal(v, &ret)
-	return interfaces.EnsureInterfaceDefaults(ret), nil
-
+	payload, err := decodeAstartePayload(r.res.Body, interfaces.AstarteInterface{})
+	if err != nil {
+		return nil, err
+	}
+	return interfaces.EnsureInterfaceDefaults(payload.Data), nil
 }
 func (r GetInterfaceResponse) Raw(f func(*http.Response) any) any {
 	defer r.res.Body.Close()
@@ -76,12 +69,11 @@ func (r GetInterfaceResponse) Raw(f func(*http.Response) any) any {
 // Returns the interface as an interfaces.AstarteInterface.
 func (r InstallInterfaceResponse) Parse() (any, error) {
 	defer r.res.Body.Close()
-	b, _ := io.ReadAll(r.res.Body)
-	v := []byte(gjson.GetBytes(b, "data").Raw)
-	ret := interfaces.AstarteInterface{}
-	// TODO check err
-	_ = json.Unmarshal(v, &ret)
-	return interfaces.EnsureInterfaceDefaults(ret), nil
+	payload, err := decodeAstartePayload(r.res.Body, interfaces.AstarteInterface{})
+	if err != nil {
+		return nil, err
+	}
+	return interfaces.EnsureInterfaceDefaults(payload.Data), nil
 }
 
 func (r InstallInterfaceResponse) Raw(f func(*http.Response) any) any {
@@ -93,12 +85,11 @@ func (r InstallInterfaceResponse) Raw(f func(*http.Response) any) any {
 // Returns the list of triggers names as an array of strings.
 func (r ListTriggersResponse) Parse() (any, error) {
 	defer r.res.Body.Close()
-	b, _ := io.ReadAll(r.res.Body)
-	ret := []string{}
-	for _, v := range gjson.GetBytes(b, "data").Array() {
-		ret = append(ret, v.Str)
+	payload, err := decodeAstartePayload(r.res.Body, []string{})
+	if err != nil {
+		return nil, err
 	}
-	return ret, nil
+	return payload.Data, nil
 }
 func (r ListTriggersResponse) Raw(f func(*http.Response) any) any {
 	defer r.res.Body.Close()
@@ -109,14 +100,11 @@ func (r ListTriggersResponse) Raw(f func(*http.Response) any) any {
 // Returns the trigger payload as a map[string]any.
 func (r GetTriggerResponse) Parse() (any, error) {
 	defer r.res.Body.Close()
-	b, _ := io.ReadAll(r.res.Body)
-	v := []byte(gjson.GetBytes(b, "data").Raw)
-	ret := map[string]any{}
-	err := json.Unmarshal(v, &ret)
+	payload, err := decodeAstartePayload(r.res.Body, map[string]any{})
 	if err != nil {
 		return nil, err
 	}
-	return ret, nil
+	return payload.Data, nil
 }
 
 func (r GetTriggerResponse) Raw(f func(*http.Response) any) any {
@@ -128,14 +116,11 @@ func (r GetTriggerResponse) Raw(f func(*http.Response) any) any {
 // Returns the trigger payload as a map[string]any.
 func (r InstallTriggerResponse) Parse() (any, error) {
 	defer r.res.Body.Close()
-	b, _ := io.ReadAll(r.res.Body)
-	v := []byte(gjson.GetBytes(b, "data").Raw)
-	ret := map[string]any{}
-	err := json.Unmarshal(v, &ret)
+	payload, err := decodeAstartePayload(r.res.Body, map[string]any{})
 	if err != nil {
 		return nil, err
 	}
-	return ret, nil
+	return payload.Data, nil
 }
 
 func (r InstallTriggerResponse) Raw(f func(*http.Response) any) any {
@@ -147,12 +132,11 @@ func (r InstallTriggerResponse) Raw(f func(*http.Response) any) any {
 // Returns the list of trigger delivery policy names as an array of strings.
 func (r ListTriggerDeliveryPoliciesResponse) Parse() (any, error) {
 	defer r.res.Body.Close()
-	b, _ := io.ReadAll(r.res.Body)
-	ret := []string{}
-	for _, v := range gjson.GetBytes(b, "data").Array() {
-		ret = append(ret, v.Str)
+	payload, err := decodeAstartePayload(r.res.Body, []string{})
+	if err != nil {
+		return nil, err
 	}
-	return ret, nil
+	return payload.Data, nil
 }
 func (r ListTriggerDeliveryPoliciesResponse) Raw(f func(*http.Response) any) any {
 	defer r.res.Body.Close()
@@ -163,14 +147,11 @@ func (r ListTriggerDeliveryPoliciesResponse) Raw(f func(*http.Response) any) any
 // Returns the trigger delivery policy payload as a map[string]any.
 func (r GetTriggerDeliveryPolicyResponse) Parse() (any, error) {
 	defer r.res.Body.Close()
-	b, _ := io.ReadAll(r.res.Body)
-	v := []byte(gjson.GetBytes(b, "data").Raw)
-	ret := map[string]any{}
-	err := json.Unmarshal(v, &ret)
+	payload, err := decodeAstartePayload(r.res.Body, map[string]any{})
 	if err != nil {
 		return nil, err
 	}
-	return ret, nil
+	return payload.Data, nil
 }
 
 func (r GetTriggerDeliveryPolicyResponse) Raw(f func(*http.Response) any) any {
@@ -182,14 +163,11 @@ func (r GetTriggerDeliveryPolicyResponse) Raw(f func(*http.Response) any) any {
 // Returns the trigger delivery policy payload as a map[string]any.
 func (r InstallTriggerDeliveryPolicyResponse) Parse() (any, error) {
 	defer r.res.Body.Close()
-	b, _ := io.ReadAll(r.res.Body)
-	v := []byte(gjson.GetBytes(b, "data").Raw)
-	ret := map[string]any{}
-	err := json.Unmarshal(v, &ret)
+	payload, err := decodeAstartePayload(r.res.Body, map[string]any{})
 	if err != nil {
 		return nil, err
 	}
-	return ret, nil
+	return payload.Data, nil
 }
 
 func (r InstallTriggerDeliveryPolicyResponse) Raw(f func(*http.Response) any) any {
@@ -0,0 +1,186 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/astarte-platform/astarte-go/interfaces"
+	"github.com/astarte-platform/astarte-go/internal/runner"
+)
+
+// PolicyUsage reports which triggers of a realm reference each trigger delivery policy.
+type PolicyUsage struct {
+	// Triggers maps each installed policy to the sorted names of the triggers referencing it.
+	// Policies referenced by no trigger map to an empty slice.
+	Triggers map[string][]string
+	// UnusedPolicies are the sorted names of the installed policies referenced by no trigger.
+	UnusedPolicies []string
+	// MissingPolicies maps the policies referenced by some triggers, but not installed, to the sorted
+	// names of those triggers.
+	MissingPolicies map[string][]string
+}
+
+// IsUsed returns whether some trigger references policyName, i.e. whether deleting it would affect triggers.
+func (u PolicyUsage) IsUsed(policyName string) bool {
+	return len(u.Triggers[policyName]) > 0 || len(u.MissingPolicies[policyName]) > 0
+}
+
+// PolicyUsageReport lists the trigger delivery policies and the triggers installed in realm, and cross-references
+// them, reporting unused policies and triggers referencing missing policies. Triggers without a policy use the
+// default delivery policy of Astarte, and are not reported.
+func (c *Client) PolicyUsageReport(realm string) (PolicyUsage, error) {
+	listPoliciesCall, err := c.ListTriggerDeliveryPolicies(realm)
+	if err != nil {
+		return PolicyUsage{}, err
+	}
+	policies, err := runner.RunAndParse[[]string](c, listPoliciesCall.Run)
+	if err != nil {
+		return PolicyUsage{}, err
+	}
+	listTriggersCall, err := c.ListTriggers(realm)
+	if err != nil {
+		return PolicyUsage{}, err
+	}
+	triggerNames, err := runner.RunAndParse[[]string](c, listTriggersCall.Run)
+	if err != nil {
+		return PolicyUsage{}, err
+	}
+
+	triggerPolicies := make(map[string]string, len(triggerNames))
+	for _, triggerName := range triggerNames {
+		getTriggerCall, err := c.GetTrigger(realm, triggerName)
+		if err != nil {
+			return PolicyUsage{}, err
+		}
+		trigger, err := runner.RunAndParse[map[string]any](c, getTriggerCall.Run)
+		if err != nil {
+			return PolicyUsage{}, fmt.Errorf("%s: %w", triggerName, err)
+		}
+		triggerPolicies[triggerName] = triggerPolicyName(trigger)
+	}
+	return policyUsage(policies, triggerPolicies), nil
+}
+
+// InterfaceDeletionVerdict reports whether a major version of an interface can be deleted without breaking
+// devices, and which of its data could be lost, see CanSafelyDeleteInterface.
+type InterfaceDeletionVerdict struct {
+	InterfaceName string
+	Major         int
+	// DeviceIDs are the sorted Device IDs of the devices which still declare the major version in their
+	// introspection. They would no longer be able to publish data on it.
+	DeviceIDs []string
+	// RetainedEndpoints are the endpoints whose data is retained with no expiry, i.e. all the endpoints of
+	// properties interfaces and those of datastream interfaces with the no_ttl database retention policy.
+	// They are reported based on the interface alone: data stored on them, if any, would be lost.
+	RetainedEndpoints []string
+}
+
+// Safe returns whether deleting the major version affects no devices. Whether data would be lost is reported
+// separately, see RetainsData.
+func (v InterfaceDeletionVerdict) Safe() bool {
+	return len(v.DeviceIDs) == 0
+}
+
+// Err returns why deleting the major version is not safe, or nil if it is. The returned error matches
+// ErrInterfaceInUse with errors.Is.
+func (v InterfaceDeletionVerdict) Err() error {
+	if v.Safe() {
+		return nil
+	}
+	return fmt.Errorf("%w: %d devices declare %s v%d", ErrInterfaceInUse, len(v.DeviceIDs), v.InterfaceName, v.Major)
+}
+
+// RetainsData returns whether the major version has endpoints whose data is retained with no expiry, which
+// deleting it might lose.
+func (v InterfaceDeletionVerdict) RetainsData() bool {
+	return len(v.RetainedEndpoints) > 0
+}
+
+// RetentionErr returns an error matching ErrInterfaceDataRetained with errors.Is if the major version retains
+// data, or nil otherwise, for callers which don't want to delete interfaces whose data might be lost.
+func (v InterfaceDeletionVerdict) RetentionErr() error {
+	if !v.RetainsData() {
+		return nil
+	}
+	return fmt.Errorf("%w: endpoints %v of %s v%d", ErrInterfaceDataRetained, v.RetainedEndpoints, v.InterfaceName, v.Major)
+}
+
+// CanSafelyDeleteInterface checks whether the major version interfaceMajor of interfaceName can be deleted from realm
+// with DeleteInterface without breaking devices: it walks the introspections of all devices of the realm with
+// InterfaceAdoptionReport. It also inspects the database retention of the mappings of the interface, to report
+// the endpoints whose data might be lost.
+func (c *Client) CanSafelyDeleteInterface(realm, interfaceName string, interfaceMajor int) (InterfaceDeletionVerdict, error) {
+	verdict := InterfaceDeletionVerdict{InterfaceName: interfaceName, Major: interfaceMajor}
+	getInterfaceCall, err := c.GetInterface(realm, interfaceName, interfaceMajor)
+	if err != nil {
+		return verdict, err
+	}
+	astarteInterface, err := runner.RunAndParse[interfaces.AstarteInterface](c, getInterfaceCall.Run)
+	if err != nil {
+		return verdict, err
+	}
+	verdict.RetainedEndpoints = retainedEndpoints(astarteInterface)
+
+	adoption, err := c.InterfaceAdoptionReport(realm, interfaceName)
+	if err != nil {
+		return verdict, err
+	}
+	verdict.DeviceIDs = adoption.DeviceIDsWithMajor(interfaceMajor)
+	return verdict, nil
+}
+
+// retainedEndpoints returns the sorted endpoints of astarteInterface whose data is retained with no expiry.
+func retainedEndpoints(astarteInterface interfaces.AstarteInterface) []string {
+	endpoints := []string{}
+	for _, mapping := range astarteInterface.Mappings {
+		if astarteInterface.Type == interfaces.PropertiesType || mapping.DatabaseRetentionPolicy != interfaces.UseTTL {
+			endpoints = append(endpoints, mapping.Endpoint)
+		}
+	}
+	sort.Strings(endpoints)
+	return endpoints
+}
+
+// policyUsage cross-references the installed policies with the policies referenced by triggers,
+// given as a map of trigger names to policy names.
+func policyUsage(policies []string, triggerPolicies map[string]string) PolicyUsage {
+	usage := PolicyUsage{Triggers: map[string][]string{}, UnusedPolicies: []string{}, MissingPolicies: map[string][]string{}}
+	for _, policy := range policies {
+		usage.Triggers[policy] = []string{}
+	}
+	for trigger, policy := range triggerPolicies {
+		if policy == "" {
+			continue
+		}
+		if _, ok := usage.Triggers[policy]; ok {
+			usage.Triggers[policy] = append(usage.Triggers[policy], trigger)
+		} else {
+			usage.MissingPolicies[policy] = append(usage.MissingPolicies[policy], trigger)
+		}
+	}
+	for policy, triggers := range usage.Triggers {
+		sort.Strings(triggers)
+		if len(triggers) == 0 {
+			usage.UnusedPolicies = append(usage.UnusedPolicies, policy)
+		}
+	}
+	for _, triggers := range usage.MissingPolicies {
+		sort.Strings(triggers)
+	}
+	sort.Strings(usage.UnusedPolicies)
+	return usage
+}
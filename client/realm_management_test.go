@@ -16,11 +16,20 @@ package client
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"reflect"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/astarte-platform/astarte-go/events"
 	"github.com/astarte-platform/astarte-go/interfaces"
+	"github.com/astarte-platform/astarte-go/pairing/store"
+	"github.com/astarte-platform/astarte-go/triggers"
 )
 
 func TestListInterfaces(t *testing.T) {
@@ -89,6 +98,45 @@ func TestGetInterface(t *testing.T) {
 	}
 }
 
+func TestAPIErrorHints(t *testing.T) {
+	c, _ := getTestContext(t)
+	getInterfaceCall, err := c.GetInterface(testRealmName, testMissingInterfaceName, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = getInterfaceCall.Run(c)
+	if !errors.Is(err, ErrInterfaceNotFound) {
+		t.Fatalf("Expected ErrInterfaceNotFound, found %v", err)
+	}
+	var apiError *APIError
+	if !errors.As(err, &apiError) || apiError.StatusCode != http.StatusNotFound || apiError.Detail() != "Interface not found" {
+		t.Errorf("Unexpected APIError: %#v", apiError)
+	}
+	if !strings.Contains(err.Error(), "ListInterfaces") {
+		t.Errorf("Expected a hint in the error, found %s", err)
+	}
+
+	for _, tc := range []struct {
+		statusCode int
+		body       string
+		expected   error
+	}{
+		{http.StatusNotFound, `{"errors": {"detail": "Interface major version does not exist"}}`, ErrInterfaceMajorVersionNotFound},
+		{http.StatusNotFound, `{"errors": {"detail": "path_not_found"}}`, ErrPathNotFound},
+		{http.StatusForbidden, `{"errors": {"detail": "unauthorized realm claim"}}`, ErrRealmClaimMismatch},
+		{http.StatusForbidden, `{"errors": {"detail": "Forbidden"}}`, ErrForbidden},
+		{http.StatusUnauthorized, ``, ErrUnauthorized},
+	} {
+		apiError := newAPIError(tc.statusCode, strings.NewReader(tc.body))
+		if !errors.Is(apiError, tc.expected) || apiError.Hint == "" {
+			t.Errorf("Expected %v with a hint for %s, found %v", tc.expected, tc.body, apiError)
+		}
+	}
+	if apiError := newAPIError(http.StatusInternalServerError, strings.NewReader(`{"errors": {"detail": "Oops"}}`)); apiError.Kind != nil || apiError.Hint != "" {
+		t.Errorf("Unexpected kind for an unknown error: %v", apiError.Kind)
+	}
+}
+
 func TestInstallInterface(t *testing.T) {
 	testIface, _ := interfaces.ParseInterface([]byte(testInterface))
 	fmt.Println(testIface)
@@ -394,3 +442,524 @@ func TestDeleteTriggerDeliveryPolicy(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestAsyncAcceptedResponse(t *testing.T) {
+	c, _ := getTestServerContext(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Location", "/v1/test/interfaces/ah.yes.an.Interface/1")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"data": {"operation_id": "42"}}`))
+	})
+	installInterfaceCall, _ := c.InstallInterface(testRealmName, interfaces.AstarteInterface{}, true)
+	res, err := installInterfaceCall.Run(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsAsyncAccepted(res) {
+		t.Fatalf("Expected an AsyncAcceptedResponse, found %T", res)
+	}
+	data, err := res.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	operation := data.(AsyncOperation)
+	if operation.OperationID != "42" || operation.Location != "/v1/test/interfaces/ah.yes.an.Interface/1" {
+		t.Errorf("Unexpected operation: %+v", operation)
+	}
+}
+
+func TestInstallTriggersAndPolicies(t *testing.T) {
+	c, _ := getTestContext(t)
+	existingPolicy := map[string]any{}
+	_ = json.Unmarshal([]byte(testPolicy), &existingPolicy)
+	newPolicy := map[string]any{"name": "ah_yes_a_new_policy", "maximum_capacity": 10,
+		"error_handlers": []any{map[string]any{"on": "any_error", "strategy": "discard"}}}
+	unnamedPolicy := map[string]any{"maximum_capacity": 10}
+	existingTrigger := map[string]any{}
+	_ = json.Unmarshal([]byte(testTrigger), &existingTrigger)
+	conflictingTrigger := map[string]any{"name": testTriggerName, "action": map[string]any{"http_url": "http://example.com"}}
+	newTrigger := map[string]any{"name": "ah_yes_a_new_trigger", "policy": "ah_yes_a_new_policy"}
+	// Resources which only differ from the installed ones by defaults and legacy fields are not conflicting
+	equivalentPolicy := map[string]any{"retry_times": 0, "event_ttl": 0}
+	for k, v := range existingPolicy {
+		equivalentPolicy[k] = v
+	}
+	equivalentTrigger := map[string]any{}
+	for k, v := range existingTrigger {
+		equivalentTrigger[k] = v
+	}
+	equivalentTrigger["action"] = map[string]any{"http_url": "http://example.com/my_post_url", "http_method": "post"}
+
+	results, err := c.InstallTriggersAndPolicies(testRealmName,
+		[]any{existingPolicy, newPolicy, unnamedPolicy, equivalentPolicy},
+		[]any{existingTrigger, conflictingTrigger, newTrigger, equivalentTrigger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []ResourceInstallResult{
+		{Kind: "policy", Name: testPolicyName, Outcome: ResourceUnchanged},
+		{Kind: "policy", Name: "ah_yes_a_new_policy", Outcome: ResourceInstalled},
+		{Kind: "policy", Outcome: ResourceFailed},
+		{Kind: "policy", Name: testPolicyName, Outcome: ResourceUnchanged},
+		{Kind: "trigger", Name: testTriggerName, Outcome: ResourceUnchanged},
+		{Kind: "trigger", Name: testTriggerName, Outcome: ResourceConflicting},
+		{Kind: "trigger", Name: "ah_yes_a_new_trigger", Outcome: ResourceInstalled},
+		{Kind: "trigger", Name: testTriggerName, Outcome: ResourceUnchanged},
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("Expected %d results, got %v", len(expected), results)
+	}
+	for i, result := range results {
+		if result.Kind != expected[i].Kind || result.Name != expected[i].Name || result.Outcome != expected[i].Outcome {
+			t.Errorf("Unexpected result %d: %+v, expected %+v", i, result, expected[i])
+		}
+		if (result.Err != nil) != (result.Outcome == ResourceConflicting || result.Outcome == ResourceFailed) {
+			t.Errorf("Unexpected error for result %d: %v", i, result.Err)
+		}
+	}
+}
+
+func TestTestTriggerAction(t *testing.T) {
+	type received struct {
+		method, contentType, token, body string
+	}
+	requests := []received{}
+	_, server := getTestServerContext(t, func(w http.ResponseWriter, req *http.Request) {
+		b, _ := io.ReadAll(req.Body)
+		requests = append(requests, received{req.Method, req.Header.Get("Content-Type"), req.Header.Get("X-Token"), string(b)})
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	c, _ := getTestContext(t)
+	event := events.SimpleEvent{
+		Timestamp: time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC),
+		DeviceID:  testDeviceID,
+		Event:     events.Event{Type: triggers.IncomingData, Interface: testInterfaceName, Path: "/a/value", Value: 4.2},
+	}
+
+	action := triggers.AstarteTriggerAction{HTTPUrl: server.URL, HTTPMethod: triggers.PutMethod, HTTPHeaders: map[string]string{"X-Token": "secret"}}
+	result, err := c.TestTriggerAction(action, event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eventJSON, _ := json.Marshal(event)
+	if !result.Succeeded() || result.StatusCode != http.StatusAccepted || string(result.Body) != "ok" || string(result.Payload) != string(eventJSON) {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if requests[0] != (received{http.MethodPut, "application/json", "secret", string(eventJSON)}) {
+		t.Errorf("Unexpected request: %+v", requests[0])
+	}
+
+	action = triggers.AstarteTriggerAction{HTTPPostURL: server.URL, TemplateType: triggers.Mustache,
+		Template: `{"device": "{{device_id}}", "value": {{event.value}}, "path": "{{{ event.path }}}"{{! ignored }}}`}
+	if _, err := c.TestTriggerAction(action, event); err != nil {
+		t.Fatal(err)
+	}
+	expectedBody := `{"device": "` + testDeviceID + `", "value": 4.2, "path": "/a/value"}`
+	if requests[1].method != http.MethodPost || requests[1].body != expectedBody {
+		t.Errorf("Unexpected request: %+v", requests[1])
+	}
+
+	action.Template = "{{#event}}{{value}}{{/event}}"
+	if _, err := c.TestTriggerAction(action, event); err == nil {
+		t.Error("Expected an error for an unsupported template, found nil")
+	}
+	if _, err := c.TestTriggerAction(triggers.AstarteTriggerAction{AMQPExchange: "ah_yes_an_exchange"}, event); err == nil {
+		t.Error("Expected an error for an AMQP action, found nil")
+	}
+}
+
+func TestPolicyUsageReport(t *testing.T) {
+	c, _ := getTestContext(t)
+	usage, err := c.PolicyUsageReport(testRealmName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := PolicyUsage{
+		Triggers:        map[string][]string{testPolicyName: {testTriggersList[1]}, testPoliciesList[1]: {}},
+		UnusedPolicies:  []string{testPoliciesList[1]},
+		MissingPolicies: map[string][]string{},
+	}
+	if !reflect.DeepEqual(usage, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, usage)
+	}
+	if !usage.IsUsed(testPolicyName) || usage.IsUsed(testPoliciesList[1]) {
+		t.Errorf("Unexpected usage of policies: %+v", usage)
+	}
+
+	usage = policyUsage([]string{"a"}, map[string]string{"t1": "a", "t2": "missing", "t3": "missing", "t4": ""})
+	if !reflect.DeepEqual(usage.MissingPolicies, map[string][]string{"missing": {"t2", "t3"}}) || len(usage.UnusedPolicies) != 0 {
+		t.Errorf("Unexpected usage with missing policies: %+v", usage)
+	}
+	if !usage.IsUsed("missing") {
+		t.Error("A missing policy referenced by triggers should be reported as used")
+	}
+}
+
+func TestCanSafelyDeleteInterface(t *testing.T) {
+	c, _ := getTestContext(t)
+	verdict, err := c.CanSafelyDeleteInterface(testRealmName, testInterfaceName, testInterfaceMajor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedDeviceIDs := []string{testDeviceID, testDeviceIDs[1]}
+	if !reflect.DeepEqual(verdict.DeviceIDs, expectedDeviceIDs) || !reflect.DeepEqual(verdict.RetainedEndpoints, []string{"/%{sensor_id}/value"}) {
+		t.Errorf("Unexpected verdict %+v", verdict)
+	}
+	if err := verdict.Err(); verdict.Safe() || !errors.Is(err, ErrInterfaceInUse) || errors.Is(err, ErrInterfaceDataRetained) {
+		t.Errorf("Expected the deletion not to be safe, got %v", err)
+	}
+	if err := verdict.RetentionErr(); !verdict.RetainsData() || !errors.Is(err, ErrInterfaceDataRetained) {
+		t.Errorf("Expected data to be retained, got %v", err)
+	}
+
+	// With no devices, deleting an interface retaining data is safe, and its data is still reported
+	unused := InterfaceDeletionVerdict{RetainedEndpoints: verdict.RetainedEndpoints}
+	if !unused.Safe() || unused.Err() != nil || !unused.RetainsData() {
+		t.Errorf("Expected the deletion to be safe, got %+v", unused)
+	}
+
+	expiring := interfaces.AstarteInterface{Type: interfaces.DatastreamType, Mappings: []interfaces.AstarteInterfaceMapping{
+		{Endpoint: "/value", DatabaseRetentionPolicy: interfaces.UseTTL, DatabaseRetentionTTL: 60},
+	}}
+	verdict = InterfaceDeletionVerdict{RetainedEndpoints: retainedEndpoints(expiring)}
+	if !verdict.Safe() || verdict.Err() != nil || verdict.RetainsData() || verdict.RetentionErr() != nil {
+		t.Errorf("Expected the deletion to be safe, got %+v", verdict)
+	}
+}
+
+func TestAstarteVersion(t *testing.T) {
+	for version, expected := range map[string]AstarteVersion{"1.0": {1, 0}, "v1.1.3": {1, 1}, "1.2.0-rc.1": {1, 2}} {
+		parsed, err := ParseAstarteVersion(version)
+		if err != nil || parsed != expected {
+			t.Errorf("Unexpected version %v parsing %q: %v", parsed, version, err)
+		}
+	}
+	for _, version := range []string{"", "1", "latest", "0.11", "1.x"} {
+		if _, err := ParseAstarteVersion(version); !errors.Is(err, ErrInvalidAstarteVersion) {
+			t.Errorf("Expected ErrInvalidAstarteVersion parsing %q, got %v", version, err)
+		}
+	}
+
+	iface, _ := interfaces.ParseInterface([]byte(testInterface))
+	requests := map[string]bool{}
+	for _, version := range []string{"1.0", "1.1"} {
+		c, err := New(WithBaseURL("https://api.example.com"), WithJWT(testTokenValue), WithAstarteVersion(version))
+		if err != nil {
+			t.Fatal(err)
+		}
+		supported := version != "1.0"
+
+		installInterfaceCall, err := c.InstallInterface(testRealmName, iface, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if synchronous := strings.Contains(installInterfaceCall.ToCurl(c), "async_operation=false"); synchronous != supported {
+			t.Errorf("Unexpected request for Astarte %s: %s", version, installInterfaceCall.ToCurl(c))
+		}
+		if _, err := c.ListTriggerDeliveryPolicies(testRealmName); errors.Is(err, ErrUnsupportedAstarteVersion) == supported {
+			t.Errorf("Unexpected error listing policies on Astarte %s: %v", version, err)
+		}
+		retention := 60
+		_, err = c.UpdateRealm(testRealmName, RealmSettingsPatch{DatastreamMaximumStorageRetention: &retention})
+		if errors.Is(err, ErrUnsupportedAstarteVersion) == supported {
+			t.Errorf("Unexpected error updating the realm on Astarte %s: %v", version, err)
+		}
+		// The version gates features, but doesn't change how supported requests are built
+		listInterfacesCall, err := c.ListInterfaces(testRealmName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		requests[listInterfacesCall.ToCurl(c)] = true
+	}
+	if len(requests) != 1 {
+		t.Errorf("Expected the same request for all Astarte versions, found %v", requests)
+	}
+
+	if _, err := New(WithBaseURL("https://api.example.com"), WithJWT(testTokenValue), WithAstarteVersion("1")); !errors.Is(err, ErrInvalidAstarteVersion) {
+		t.Errorf("Expected ErrInvalidAstarteVersion, got %v", err)
+	}
+}
+
+func TestInstallInterfaceRetentionCheck(t *testing.T) {
+	_, server := getTestContext(t)
+	defer server.Close()
+	iface, _ := interfaces.ParseInterface([]byte(testInterface))
+	// The test realm has a maximum datastream storage retention of testMaximumStorageRetention
+	iface.Mappings[0].DatabaseRetentionPolicy = interfaces.UseTTL
+	iface.Mappings[0].DatabaseRetentionTTL = testMaximumStorageRetention * 2
+
+	strict := newTestClient(t, server, WithStrictRetention())
+	installInterfaceCall, err := strict.InstallInterface(testRealmName, iface, false)
+	if err != nil {
+		t.Fatalf("Expected the retention to be checked when running the request, got %v", err)
+	}
+	if _, err := installInterfaceCall.Run(strict); err == nil {
+		t.Error("Expected the retention check to fail")
+	}
+
+	warnings := []RetentionWarning{}
+	tolerant := newTestClient(t, server, WithRetentionWarningHandler(func(w RetentionWarning) { warnings = append(warnings, w) }))
+	updateInterfaceCall, err := tolerant.UpdateInterface(testRealmName, iface.Name, iface.MajorVersion, iface, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("Expected no warnings before running the request, found %v", warnings)
+	}
+	if _, err := updateInterfaceCall.Run(tolerant); err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || warnings[0].Limits.MaxDatabaseRetentionTTL != testMaximumStorageRetention || warnings[0].Err == nil {
+		t.Errorf("Unexpected warnings %v", warnings)
+	}
+
+	iface.Mappings[0].DatabaseRetentionTTL = testMaximumStorageRetention
+	installInterfaceCall, _ = strict.InstallInterface(testRealmName, iface, false)
+	if _, err := installInterfaceCall.Run(strict); err != nil {
+		t.Errorf("Unexpected error for a valid retention: %v", err)
+	}
+}
+
+func TestInstallInterfaceRetentionLimitsUnavailable(t *testing.T) {
+	realmFetches := 0
+	installed := 0
+	c, _ := getTestServerContext(t, func(w http.ResponseWriter, req *http.Request) {
+		if strings.HasPrefix(req.URL.Path, "/housekeeping/") {
+			realmFetches++
+			if realmFetches == 1 {
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(`{"errors": {"detail": "Forbidden"}}`))
+				return
+			}
+			_, _ = w.Write([]byte(fmt.Sprintf(`{"data": {"realm_name": %q}}`, testRealmName)))
+			return
+		}
+		installed++
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"data": {}}`))
+	}, WithStrictRetention())
+	iface, _ := interfaces.ParseInterface([]byte(testInterface))
+
+	installInterfaceCall, _ := c.InstallInterface(testRealmName, iface, false)
+	if _, err := installInterfaceCall.Run(c); err == nil || installed != 0 {
+		t.Errorf("Expected the request to fail without being sent, got %v after %d requests", err, installed)
+	}
+	// Limits are fetched again after a failure, and then cached
+	for i := 0; i < 2; i++ {
+		if _, err := installInterfaceCall.Run(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if realmFetches != 2 || installed != 2 {
+		t.Errorf("Expected limits to be fetched twice and the interface to be installed twice, found %d and %d", realmFetches, installed)
+	}
+	c.ClearRetentionLimitsCache()
+	if _, err := installInterfaceCall.Run(c); err != nil || realmFetches != 3 {
+		t.Errorf("Expected limits to be fetched again after clearing the cache, found %d fetches: %v", realmFetches, err)
+	}
+}
+
+func TestDeleteDeviceAndCleanup(t *testing.T) {
+	var mu sync.Mutex
+	polls := 0
+	removedFromGroups := []string{}
+	c, server := getTestServerContext(t, func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case req.Method == http.MethodDelete && req.URL.Path == fmt.Sprintf("/realmmanagement/v1/%s/devices/%s", testRealmName, testDeviceID):
+			w.WriteHeader(http.StatusNoContent)
+		case req.Method == http.MethodGet && req.URL.Path == fmt.Sprintf("/appengine/v1/%s/devices/%s", testRealmName, testDeviceID):
+			// The device disappears after being polled twice
+			polls++
+			if polls > 2 {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"errors": {"detail": "Device not found"}}`))
+				return
+			}
+			_, _ = w.Write([]byte(fmt.Sprintf(`{"data": {"id": %q, "groups": [%q, "other"]}}`, testDeviceID, testGroupName)))
+		case req.Method == http.MethodDelete && strings.HasPrefix(req.URL.Path, fmt.Sprintf("/appengine/v1/%s/groups/", testRealmName)):
+			group := strings.Split(strings.TrimPrefix(req.URL.Path, fmt.Sprintf("/appengine/v1/%s/groups/", testRealmName)), "/")[0]
+			removedFromGroups = append(removedFromGroups, group)
+			if group == "other" {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	credentials := store.NewMemoryStore()
+	_ = credentials.Put(testRealmName, testDeviceID, store.Credentials{CredentialsSecret: "secret"})
+
+	report, err := c.DeleteDeviceAndCleanup(testRealmName, testDeviceID, WithDeletionPollInterval(time.Millisecond),
+		WithGroupCleanup(true), WithCredentialsCleanup(credentials))
+	if err == nil || len(report.GroupErrors) != 1 || report.GroupErrors["other"] == nil {
+		t.Errorf("Expected the removal from the other group to fail, got %v, %+v", err, report)
+	}
+	if !report.Deleted || !reflect.DeepEqual(report.Groups, []string{testGroupName, "other"}) || !report.CredentialsDeleted {
+		t.Errorf("Unexpected report %+v", report)
+	}
+	if !reflect.DeepEqual(removedFromGroups, report.Groups) {
+		t.Errorf("Unexpected group removals %v", removedFromGroups)
+	}
+	if _, err := credentials.Get(testRealmName, testDeviceID); !errors.Is(err, store.ErrCredentialsNotFound) {
+		t.Errorf("Expected the credentials to be deleted, got %v", err)
+	}
+
+	// The device is no longer found
+	if _, err := c.WaitForDeviceDeletion(testRealmName, testDeviceID); err != nil {
+		t.Error(err)
+	}
+
+	mu.Lock()
+	polls = 0
+	mu.Unlock()
+	if _, err := c.WaitForDeviceDeletion(testRealmName, testDeviceID, WithDeletionPollInterval(time.Millisecond),
+		WithDeletionTimeout(time.Millisecond)); !errors.Is(err, ErrDeviceDeletionTimeout) {
+		t.Errorf("Expected ErrDeviceDeletionTimeout, got %v", err)
+	}
+
+	old := newTestClient(t, server, WithAstarteVersion("1.1"))
+	if _, err := old.DeleteDevice(testRealmName, testDeviceID); !errors.Is(err, ErrUnsupportedAstarteVersion) {
+		t.Errorf("Expected ErrUnsupportedAstarteVersion, got %v", err)
+	}
+}
+
+func TestBootstrapRealm(t *testing.T) {
+	installed := map[string]interfaces.AstarteInterface{
+		"org.astarte.Same":     {Name: "org.astarte.Same", MajorVersion: 1, MinorVersion: 1, Type: interfaces.DatastreamType, Ownership: interfaces.DeviceOwnership},
+		"org.astarte.Outdated": {Name: "org.astarte.Outdated", MajorVersion: 1, MinorVersion: 0, Type: interfaces.DatastreamType, Ownership: interfaces.DeviceOwnership},
+		"org.astarte.Newer":    {Name: "org.astarte.Newer", MajorVersion: 0, MinorVersion: 3, Type: interfaces.DatastreamType, Ownership: interfaces.DeviceOwnership},
+	}
+	var mu sync.Mutex
+	mutations := []string{}
+	c, _ := getTestServerContext(t, func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		realmManagement := fmt.Sprintf("/realmmanagement/v1/%s/", testRealmName)
+		appEngine := fmt.Sprintf("/appengine/v1/%s/", testRealmName)
+		if req.Method != http.MethodGet {
+			mutations = append(mutations, req.Method+" "+req.URL.Path)
+			if req.Method == http.MethodPut {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"data": {}}`))
+			return
+		}
+		var data any
+		switch path := req.URL.Path; {
+		case path == realmManagement+"interfaces":
+			data = []string{"org.astarte.Same", "org.astarte.Outdated", "org.astarte.Newer"}
+		case strings.HasPrefix(path, realmManagement+"interfaces/"):
+			segments := strings.Split(strings.TrimPrefix(path, realmManagement+"interfaces/"), "/")
+			iface := installed[segments[0]]
+			if len(segments) == 1 {
+				data = []int{iface.MajorVersion}
+			} else {
+				data = iface
+			}
+		case path == realmManagement+"policies" || path == realmManagement+"triggers":
+			data = []string{}
+		case path == appEngine+"groups":
+			data = []string{testGroupName}
+		case path == appEngine+"groups/"+testGroupName+"/devices":
+			data = testDeviceIDs[:1]
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": data})
+	})
+	newer := installed["org.astarte.Newer"]
+	newer.MinorVersion = 2
+	outdated := installed["org.astarte.Outdated"]
+	outdated.MinorVersion = 1
+	spec := BootstrapSpec{
+		Interfaces: []interfaces.AstarteInterface{
+			installed["org.astarte.Same"], outdated, newer,
+			{Name: "org.astarte.New", MajorVersion: 1, Type: interfaces.PropertiesType, Ownership: interfaces.ServerOwnership},
+		},
+		Triggers: []any{
+			map[string]any{"name": "on_newer", "simple_triggers": []any{
+				map[string]any{"type": "data_trigger", "interface_name": "org.astarte.Newer", "interface_major": 0},
+			}},
+			map[string]any{"name": "on_new", "simple_triggers": []any{
+				map[string]any{"type": "data_trigger", "interface_name": "org.astarte.New", "interface_major": 1},
+			}},
+		},
+		Groups: map[string][]string{
+			testGroupName: testDeviceIDs[:2],
+			"new_group":   testDeviceIDs[:1],
+			"empty_group": {},
+		},
+	}
+	expected := []ResourceInstallResult{
+		{Kind: "interface", Name: "org.astarte.Same v1", Outcome: ResourceUnchanged},
+		{Kind: "interface", Name: "org.astarte.Outdated v1", Outcome: ResourceUpdated},
+		{Kind: "interface", Name: "org.astarte.Newer v0", Outcome: ResourceConflicting},
+		{Kind: "interface", Name: "org.astarte.New v1", Outcome: ResourceInstalled},
+		{Kind: "trigger", Name: "on_newer", Outcome: ResourceFailed},
+		{Kind: "trigger", Name: "on_new", Outcome: ResourceInstalled},
+		{Kind: "group", Name: testGroupName, Outcome: ResourceUpdated},
+		{Kind: "group", Name: "empty_group", Outcome: ResourceFailed},
+		{Kind: "group", Name: "new_group", Outcome: ResourceInstalled},
+	}
+
+	for _, dryRun := range []bool{true, false} {
+		opts := []bootstrapOption{}
+		if dryRun {
+			opts = append(opts, WithDryRun())
+		}
+		report, err := c.BootstrapRealm(testRealmName, spec, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.DryRun != dryRun || len(report.Results) != len(expected) {
+			t.Fatalf("Unexpected report %+v", report)
+		}
+		for i, result := range report.Results {
+			if result.Kind != expected[i].Kind || result.Name != expected[i].Name || result.Outcome != expected[i].Outcome {
+				t.Errorf("Unexpected result %d: %+v, expected %+v", i, result, expected[i])
+			}
+		}
+		if err := report.Err(); err == nil || !strings.Contains(err.Error(), "org.astarte.Newer") {
+			t.Errorf("Expected the conflicting interface to be reported, got %v", err)
+		}
+	}
+
+	expectedMutations := []string{
+		"PUT " + fmt.Sprintf("/realmmanagement/v1/%s/interfaces/org.astarte.Outdated/1", testRealmName),
+		"POST " + fmt.Sprintf("/realmmanagement/v1/%s/interfaces", testRealmName),
+		"POST " + fmt.Sprintf("/realmmanagement/v1/%s/triggers", testRealmName),
+		"POST " + fmt.Sprintf("/appengine/v1/%s/groups/%s/devices", testRealmName, testGroupName),
+		"POST " + fmt.Sprintf("/appengine/v1/%s/groups", testRealmName),
+	}
+	if !reflect.DeepEqual(mutations, expectedMutations) {
+		t.Errorf("Unexpected requests %v, expected %v", mutations, expectedMutations)
+	}
+}
+
+func TestTriggerInterface(t *testing.T) {
+	unavailable := map[string]bool{interfaceKey("org.astarte.Broken", 2): true}
+	trigger := func(major any) map[string]any {
+		return map[string]any{"name": "t", "simple_triggers": []any{
+			map[string]any{"type": "data_trigger", "interface_name": "org.astarte.Broken", "interface_major": major},
+		}}
+	}
+	for major, expected := range map[any]string{2: "org.astarte.Broken", "2": "org.astarte.Broken", "*": "org.astarte.Broken", 1: ""} {
+		if found := triggerInterface(trigger(major), unavailable); found != expected {
+			t.Errorf("Unexpected interface %q for a trigger on major %v", found, major)
+		}
+	}
+	other := map[string]any{"name": "t", "simple_triggers": []any{
+		map[string]any{"type": "data_trigger", "interface_name": "org.astarte.BrokenToo", "interface_major": "*"},
+	}}
+	if found := triggerInterface(other, unavailable); found != "" {
+		t.Errorf("Unexpected interface %q for a trigger on another interface", found)
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/astarte-platform/astarte-go/client/clienttest"
+)
+
+// TestResponseBodyLifecycle runs the requests built by all request builders, and checks that the body of each
+// response is closed exactly once, and never read after being closed, both by Parse and by Raw.
+func TestResponseBodyLifecycle(t *testing.T) {
+	consumers := map[string]func(AstarteResponse){
+		"Parse": func(res AstarteResponse) { _, _ = res.Parse() },
+		"Raw": func(res AstarteResponse) {
+			_ = res.Raw(func(r *http.Response) any {
+				b, _ := io.ReadAll(r.Body)
+				return b
+			})
+		},
+	}
+	for consumerName, consume := range consumers {
+		transport := &clienttest.Transport{Body: []byte(`{"data": null}`)}
+		c, err := New(WithBaseURL("https://api.astarte.example.com"), WithJWT(testTokenValue), WithHTTPClient(&http.Client{Transport: transport}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, builder := range openAPIRequestBuilders(c) {
+			req, err := builder()
+			if err != nil {
+				t.Fatalf("Cannot build request with %s: %v", name, err)
+			}
+			responses := len(transport.Bodies())
+			// The payload is not meant to be valid for all responses, only their life-cycle matters
+			if res, err := Tolerant(req).Run(c); err == nil {
+				consume(res)
+			}
+			for _, body := range transport.Bodies()[responses:] {
+				if err := body.Check(); err != nil {
+					t.Errorf("%s with %s: %v", name, consumerName, err)
+				}
+			}
+		}
+	}
+}
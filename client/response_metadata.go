@@ -0,0 +1,178 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import "net/http"
+
+// RequestIDHeader is the header Astarte uses to identify a request in its logs.
+const RequestIDHeader = "X-Request-Id"
+
+func responseHeaders(res *http.Response) http.Header {
+	if res == nil {
+		return http.Header{}
+	}
+	return res.Header
+}
+
+func responseStatusCode(res *http.Response) int {
+	if res == nil {
+		return 0
+	}
+	return res.StatusCode
+}
+
+func responseRequestID(res *http.Response) string {
+	return responseHeaders(res).Get(RequestIDHeader)
+}
+
+func (e Empty) Headers() http.Header { return http.Header{} }
+func (e Empty) StatusCode() int      { return 0 }
+func (e Empty) RequestID() string    { return "" }
+
+func (r RegisterDeviceResponse) Headers() http.Header { return responseHeaders(r.res) }
+func (r RegisterDeviceResponse) StatusCode() int      { return responseStatusCode(r.res) }
+func (r RegisterDeviceResponse) RequestID() string    { return responseRequestID(r.res) }
+
+func (r NewDeviceCertificateResponse) Headers() http.Header { return responseHeaders(r.res) }
+func (r NewDeviceCertificateResponse) StatusCode() int      { return responseStatusCode(r.res) }
+func (r NewDeviceCertificateResponse) RequestID() string    { return responseRequestID(r.res) }
+
+func (r Mqttv1DeviceInformationResponse) Headers() http.Header { return responseHeaders(r.res) }
+func (r Mqttv1DeviceInformationResponse) StatusCode() int      { return responseStatusCode(r.res) }
+func (r Mqttv1DeviceInformationResponse) RequestID() string    { return responseRequestID(r.res) }
+
+func (r DeviceTransportInformationResponse) Headers() http.Header { return responseHeaders(r.res) }
+func (r DeviceTransportInformationResponse) StatusCode() int      { return responseStatusCode(r.res) }
+func (r DeviceTransportInformationResponse) RequestID() string    { return responseRequestID(r.res) }
+
+func (r ListRealmsResponse) Headers() http.Header { return responseHeaders(r.res) }
+func (r ListRealmsResponse) StatusCode() int      { return responseStatusCode(r.res) }
+func (r ListRealmsResponse) RequestID() string    { return responseRequestID(r.res) }
+
+func (r GetRealmResponse) Headers() http.Header { return responseHeaders(r.res) }
+func (r GetRealmResponse) StatusCode() int      { return responseStatusCode(r.res) }
+func (r GetRealmResponse) RequestID() string    { return responseRequestID(r.res) }
+
+func (r CreateRealmResponse) Headers() http.Header { return responseHeaders(r.res) }
+func (r CreateRealmResponse) StatusCode() int      { return responseStatusCode(r.res) }
+func (r CreateRealmResponse) RequestID() string    { return responseRequestID(r.res) }
+
+func (r UpdateRealmResponse) Headers() http.Header { return responseHeaders(r.res) }
+func (r UpdateRealmResponse) StatusCode() int      { return responseStatusCode(r.res) }
+func (r UpdateRealmResponse) RequestID() string    { return responseRequestID(r.res) }
+
+func (r ListInterfacesResponse) Headers() http.Header { return responseHeaders(r.res) }
+func (r ListInterfacesResponse) StatusCode() int      { return responseStatusCode(r.res) }
+func (r ListInterfacesResponse) RequestID() string    { return responseRequestID(r.res) }
+
+func (r ListInterfaceMajorVersionsResponse) Headers() http.Header { return responseHeaders(r.res) }
+func (r ListInterfaceMajorVersionsResponse) StatusCode() int      { return responseStatusCode(r.res) }
+func (r ListInterfaceMajorVersionsResponse) RequestID() string    { return responseRequestID(r.res) }
+
+func (r GetInterfaceResponse) Headers() http.Header { return responseHeaders(r.res) }
+func (r GetInterfaceResponse) StatusCode() int      { return responseStatusCode(r.res) }
+func (r GetInterfaceResponse) RequestID() string    { return responseRequestID(r.res) }
+
+func (r InstallInterfaceResponse) Headers() http.Header { return responseHeaders(r.res) }
+func (r InstallInterfaceResponse) StatusCode() int      { return responseStatusCode(r.res) }
+func (r InstallInterfaceResponse) RequestID() string    { return responseRequestID(r.res) }
+
+func (r ListTriggersResponse) Headers() http.Header { return responseHeaders(r.res) }
+func (r ListTriggersResponse) StatusCode() int      { return responseStatusCode(r.res) }
+func (r ListTriggersResponse) RequestID() string    { return responseRequestID(r.res) }
+
+func (r GetTriggerResponse) Headers() http.Header { return responseHeaders(r.res) }
+func (r GetTriggerResponse) StatusCode() int      { return responseStatusCode(r.res) }
+func (r GetTriggerResponse) RequestID() string    { return responseRequestID(r.res) }
+
+func (r InstallTriggerResponse) Headers() http.Header { return responseHeaders(r.res) }
+func (r InstallTriggerResponse) StatusCode() int      { return responseStatusCode(r.res) }
+func (r InstallTriggerResponse) RequestID() string    { return responseRequestID(r.res) }
+
+func (r ListTriggerDeliveryPoliciesResponse) Headers() http.Header { return responseHeaders(r.res) }
+func (r ListTriggerDeliveryPoliciesResponse) StatusCode() int      { return responseStatusCode(r.res) }
+func (r ListTriggerDeliveryPoliciesResponse) RequestID() string    { return responseRequestID(r.res) }
+
+func (r GetTriggerDeliveryPolicyResponse) Headers() http.Header { return responseHeaders(r.res) }
+func (r GetTriggerDeliveryPolicyResponse) StatusCode() int      { return responseStatusCode(r.res) }
+func (r GetTriggerDeliveryPolicyResponse) RequestID() string    { return responseRequestID(r.res) }
+
+func (r InstallTriggerDeliveryPolicyResponse) Headers() http.Header { return responseHeaders(r.res) }
+func (r InstallTriggerDeliveryPolicyResponse) StatusCode() int      { return responseStatusCode(r.res) }
+func (r InstallTriggerDeliveryPolicyResponse) RequestID() string    { return responseRequestID(r.res) }
+
+func (r GetNextDeviceListPageResponse) Headers() http.Header { return responseHeaders(r.res) }
+func (r GetNextDeviceListPageResponse) StatusCode() int      { return responseStatusCode(r.res) }
+func (r GetNextDeviceListPageResponse) RequestID() string    { return responseRequestID(r.res) }
+
+func (r GetDeviceIDFromAliasResponse) Headers() http.Header { return responseHeaders(r.res) }
+func (r GetDeviceIDFromAliasResponse) StatusCode() int      { return responseStatusCode(r.res) }
+func (r GetDeviceIDFromAliasResponse) RequestID() string    { return responseRequestID(r.res) }
+
+func (r GetDeviceDetailsResponse) Headers() http.Header { return responseHeaders(r.res) }
+func (r GetDeviceDetailsResponse) StatusCode() int      { return responseStatusCode(r.res) }
+func (r GetDeviceDetailsResponse) RequestID() string    { return responseRequestID(r.res) }
+
+func (r GetDeviceInterfaceStatsResponse) Headers() http.Header { return responseHeaders(r.res) }
+func (r GetDeviceInterfaceStatsResponse) StatusCode() int      { return responseStatusCode(r.res) }
+func (r GetDeviceInterfaceStatsResponse) RequestID() string    { return responseRequestID(r.res) }
+
+func (r GetDeviceStatsResponse) Headers() http.Header { return responseHeaders(r.res) }
+func (r GetDeviceStatsResponse) StatusCode() int      { return responseStatusCode(r.res) }
+func (r GetDeviceStatsResponse) RequestID() string    { return responseRequestID(r.res) }
+
+func (r ListDeviceInterfacesResponse) Headers() http.Header { return responseHeaders(r.res) }
+func (r ListDeviceInterfacesResponse) StatusCode() int      { return responseStatusCode(r.res) }
+func (r ListDeviceInterfacesResponse) RequestID() string    { return responseRequestID(r.res) }
+
+func (r ListDeviceAliasesResponse) Headers() http.Header { return responseHeaders(r.res) }
+func (r ListDeviceAliasesResponse) StatusCode() int      { return responseStatusCode(r.res) }
+func (r ListDeviceAliasesResponse) RequestID() string    { return responseRequestID(r.res) }
+
+func (r ListDeviceAttributesResponse) Headers() http.Header { return responseHeaders(r.res) }
+func (r ListDeviceAttributesResponse) StatusCode() int      { return responseStatusCode(r.res) }
+func (r ListDeviceAttributesResponse) RequestID() string    { return responseRequestID(r.res) }
+
+func (r GetNextDatastreamPageResponse) Headers() http.Header { return responseHeaders(r.res) }
+func (r GetNextDatastreamPageResponse) StatusCode() int      { return responseStatusCode(r.res) }
+func (r GetNextDatastreamPageResponse) RequestID() string    { return responseRequestID(r.res) }
+
+func (r GetDatastreamSnapshotResponse) Headers() http.Header { return responseHeaders(r.res) }
+func (r GetDatastreamSnapshotResponse) StatusCode() int      { return responseStatusCode(r.res) }
+func (r GetDatastreamSnapshotResponse) RequestID() string    { return responseRequestID(r.res) }
+
+func (r GetPropertiesResponse) Headers() http.Header { return responseHeaders(r.res) }
+func (r GetPropertiesResponse) StatusCode() int      { return responseStatusCode(r.res) }
+func (r GetPropertiesResponse) RequestID() string    { return responseRequestID(r.res) }
+
+func (r SendDatastreamResponse) Headers() http.Header { return responseHeaders(r.res) }
+func (r SendDatastreamResponse) StatusCode() int      { return responseStatusCode(r.res) }
+func (r SendDatastreamResponse) RequestID() string    { return responseRequestID(r.res) }
+
+func (r ListGroupsResponse) Headers() http.Header { return responseHeaders(r.res) }
+func (r ListGroupsResponse) StatusCode() int      { return responseStatusCode(r.res) }
+func (r ListGroupsResponse) RequestID() string    { return responseRequestID(r.res) }
+
+func (r CreateGroupResponse) Headers() http.Header { return responseHeaders(r.res) }
+func (r CreateGroupResponse) StatusCode() int      { return responseStatusCode(r.res) }
+func (r CreateGroupResponse) RequestID() string    { return responseRequestID(r.res) }
+
+func (r NoDataResponse) Headers() http.Header { return responseHeaders(r.res) }
+func (r NoDataResponse) StatusCode() int      { return responseStatusCode(r.res) }
+func (r NoDataResponse) RequestID() string    { return responseRequestID(r.res) }
+
+func (r AsyncAcceptedResponse) Headers() http.Header { return responseHeaders(r.res) }
+func (r AsyncAcceptedResponse) StatusCode() int      { return responseStatusCode(r.res) }
+func (r AsyncAcceptedResponse) RequestID() string    { return responseRequestID(r.res) }
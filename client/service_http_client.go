@@ -0,0 +1,135 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/astarte-platform/astarte-go/astarteservices"
+)
+
+// The WithServiceHTTPClient function allows to specify the HTTP client used for the requests to a single Astarte
+// service, e.g. with its own transport and connection pool, so that a slow or saturated service does not degrade
+// the requests to the other ones. Requests to the other services use the client set with WithHTTPClient.
+func WithServiceHTTPClient(service astarteservices.AstarteService, httpClient *http.Client) Option {
+	return func(c *Client) error {
+		if err := validateClientService(service); err != nil {
+			return err
+		}
+		if c.serviceHTTPClients == nil {
+			c.serviceHTTPClients = map[astarteservices.AstarteService]*http.Client{}
+		}
+		c.serviceHTTPClients[service] = httpClient
+		return nil
+	}
+}
+
+// The WithServiceConnectionLimit function allows to isolate the requests to a single Astarte service in their own
+// connection pool, opening at most maxConnections connections to the service: further requests wait for a
+// connection to become available. The pool is created from the transport of the HTTP client used for the service
+// (see WithServiceHTTPClient and WithHTTPClient), which must be an *http.Transport, or the default one.
+func WithServiceConnectionLimit(service astarteservices.AstarteService, maxConnections int) Option {
+	return func(c *Client) error {
+		if err := validateClientService(service); err != nil {
+			return err
+		}
+		if maxConnections <= 0 {
+			return fmt.Errorf("Invalid connection limit %d for %s", maxConnections, service)
+		}
+		if c.serviceConnectionLimits == nil {
+			c.serviceConnectionLimits = map[astarteservices.AstarteService]int{}
+		}
+		c.serviceConnectionLimits[service] = maxConnections
+		return nil
+	}
+}
+
+func validateClientService(service astarteservices.AstarteService) error {
+	switch service {
+	case astarteservices.AppEngine, astarteservices.Housekeeping, astarteservices.Pairing, astarteservices.RealmManagement:
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrUnsupportedService, service)
+}
+
+// setupServiceHTTPClients creates the HTTP clients of the services with a connection limit. It must be called
+// after setDefaults.
+func setupServiceHTTPClients(c *Client) error {
+	for service, maxConnections := range c.serviceConnectionLimits {
+		httpClient, ok := c.serviceHTTPClients[service]
+		if !ok {
+			httpClient = c.httpClient
+		}
+		var transport *http.Transport
+		switch t := httpClient.Transport.(type) {
+		case nil:
+			transport = http.DefaultTransport.(*http.Transport).Clone()
+		case *http.Transport:
+			transport = t.Clone()
+		default:
+			return fmt.Errorf("Can't limit the connections to %s: the HTTP client transport is a %T, not an *http.Transport", service, t)
+		}
+		transport.MaxConnsPerHost = maxConnections
+		limitedClient := *httpClient
+		limitedClient.Transport = transport
+		if c.serviceHTTPClients == nil {
+			c.serviceHTTPClients = map[astarteservices.AstarteService]*http.Client{}
+		}
+		c.serviceHTTPClients[service] = &limitedClient
+	}
+	return nil
+}
+
+// httpClientFor returns the HTTP client used to send req, according to the Astarte service it is addressed to.
+func (c *Client) httpClientFor(req *http.Request) *http.Client {
+	if len(c.serviceHTTPClients) == 0 {
+		return c.httpClient
+	}
+	if httpClient, ok := c.serviceHTTPClients[c.serviceOf(req.URL)]; ok {
+		return httpClient
+	}
+	return c.httpClient
+}
+
+// serviceOf returns the Astarte service which u belongs to, or astarteservices.Unknown. When the URL of a service
+// is a prefix of the URL of another one, e.g. AppEngine at https://host/ and Housekeeping at https://host/housekeeping,
+// u belongs to the service with the longest matching URL.
+func (c *Client) serviceOf(u *url.URL) astarteservices.AstarteService {
+	target := u.String()
+	match := astarteservices.Unknown
+	matchLength := -1
+	for _, candidate := range []struct {
+		service astarteservices.AstarteService
+		url     *url.URL
+	}{
+		{astarteservices.AppEngine, c.appEngineURL},
+		{astarteservices.Housekeeping, c.housekeepingURL},
+		{astarteservices.Pairing, c.pairingURL},
+		{astarteservices.RealmManagement, c.realmManagementURL},
+	} {
+		if candidate.url == nil {
+			continue
+		}
+		base := strings.TrimSuffix(candidate.url.String(), "/")
+		if (target == base || strings.HasPrefix(target, base+"/")) && len(base) > matchLength {
+			match = candidate.service
+			matchLength = len(base)
+		}
+	}
+	return match
+}
@@ -0,0 +1,12 @@
+#!/bin/sh
+# Downloads the OpenAPI specs published by Astarte for the given release (default v1.2.0) into this directory.
+# The specs are vendored as they are: never edit them, update them by running this script again.
+set -eu
+
+version="${1:-v1.2.0}"
+cd "$(dirname "$0")"
+for service in appengine housekeeping pairing realm_management; do
+	curl -fsSL -o "$(echo "$service" | tr -d _).yaml" \
+		"https://raw.githubusercontent.com/astarte-platform/astarte/$version/apps/astarte_${service}_api/priv/static/astarte_${service}_api.yaml"
+done
+echo "$version" >VERSION
@@ -0,0 +1,91 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/interfaces"
+)
+
+// TimestampPrecision is the precision of the timestamps sent to Astarte, see WithTimestampPrecision.
+type TimestampPrecision int
+
+const (
+	// NanosecondPrecision sends timestamps with all their digits. This is the default.
+	NanosecondPrecision TimestampPrecision = iota
+	// MillisecondPrecision truncates timestamps to milliseconds, the precision Astarte stores.
+	MillisecondPrecision
+	// SecondPrecision truncates timestamps to seconds.
+	SecondPrecision
+)
+
+// IsValid returns an error if TimestampPrecision is not a known precision.
+func (p TimestampPrecision) IsValid() error {
+	switch p {
+	case NanosecondPrecision, MillisecondPrecision, SecondPrecision:
+		return nil
+	}
+	return ErrInvalidTimestampPrecision
+}
+
+// Apply returns t in UTC, truncated to the precision.
+func (p TimestampPrecision) Apply(t time.Time) time.Time {
+	switch p {
+	case MillisecondPrecision:
+		return t.UTC().Truncate(time.Millisecond)
+	case SecondPrecision:
+		return t.UTC().Truncate(time.Second)
+	default:
+		return t.UTC()
+	}
+}
+
+// Format returns t as an RFC3339 timestamp in UTC, truncated to the precision.
+func (p TimestampPrecision) Format(t time.Time) string {
+	return p.Apply(t).Format(time.RFC3339Nano)
+}
+
+// normalizePayload normalizes payload as interfaces.NormalizePayload does, and applies the timestamp precision
+// of the client to the datetime values it holds. Pre-encoded payloads are returned as they are.
+func (c *Client) normalizePayload(payload any) any {
+	switch v := payload.(type) {
+	case json.RawMessage:
+		return v
+	case preEncodedPayload:
+		return v.payload
+	}
+	return applyTimestampPrecision(c.timestampPrecision, interfaces.NormalizePayload(payload, true))
+}
+
+func applyTimestampPrecision(p TimestampPrecision, payload any) any {
+	if p == NanosecondPrecision {
+		return payload
+	}
+	switch v := payload.(type) {
+	case time.Time:
+		return p.Apply(v)
+	case []any:
+		for i, e := range v {
+			v[i] = applyTimestampPrecision(p, e)
+		}
+	case map[string]any:
+		for k, e := range v {
+			v[k] = applyTimestampPrecision(p, e)
+		}
+	}
+	return payload
+}
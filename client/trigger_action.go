@@ -0,0 +1,199 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/events"
+	"github.com/astarte-platform/astarte-go/triggers"
+)
+
+// TriggerActionTestResult is the outcome of TestTriggerAction.
+type TriggerActionTestResult struct {
+	// Payload is the rendered payload which was sent to the webhook
+	Payload    []byte
+	StatusCode int
+	Headers    http.Header
+	Body       []byte
+	Duration   time.Duration
+}
+
+// Succeeded returns whether the webhook replied with a 2xx status code.
+func (r TriggerActionTestResult) Succeeded() bool {
+	return r.StatusCode >= 200 && r.StatusCode <= 299
+}
+
+// TestTriggerAction fires the HTTP action of a trigger against its webhook with sampleEvent, as Astarte would when
+// the trigger is activated, so that the webhook can be validated before installing the trigger. The payload is the
+// JSON representation of sampleEvent, or the rendered template if the action has a Mustache template, and it is
+// sent with the static headers of the action. The returned error is set only if the webhook could not be reached:
+// use Succeeded to check the status code of the response.
+// AMQP actions can't be tested, since they are delivered to the AMQP broker of Astarte.
+// The request is sent with the HTTP client of the Client, without the Astarte token.
+func (c *Client) TestTriggerAction(action triggers.AstarteTriggerAction, sampleEvent events.SimpleEvent) (TriggerActionTestResult, error) {
+	if action.AMQPExchange != "" {
+		return TriggerActionTestResult{}, errors.New("AMQP trigger actions can't be tested")
+	}
+	actionURL, method := action.HTTPUrl, action.HTTPMethod
+	if actionURL == "" {
+		// Legacy actions imply a post method
+		actionURL, method = action.HTTPPostURL, triggers.PostMethod
+	}
+	if actionURL == "" {
+		return TriggerActionTestResult{}, errors.New("The trigger action has no URL")
+	}
+	if err := method.IsValid(); err != nil {
+		return TriggerActionTestResult{}, err
+	}
+
+	payload, err := renderTriggerActionPayload(action, sampleEvent)
+	if err != nil {
+		return TriggerActionTestResult{}, err
+	}
+	req, err := http.NewRequest(strings.ToUpper(string(method)), actionURL, bytes.NewReader(payload))
+	if err != nil {
+		return TriggerActionTestResult{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range action.HTTPHeaders {
+		req.Header.Set(k, v)
+	}
+
+	start := c.clock()
+	res, err := c.triggerActionHTTPClient(action).Do(req)
+	if err != nil {
+		return TriggerActionTestResult{Payload: payload}, err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	return TriggerActionTestResult{
+		Payload:    payload,
+		StatusCode: res.StatusCode,
+		Headers:    res.Header,
+		Body:       body,
+		Duration:   c.clock().Sub(start),
+	}, err
+}
+
+// triggerActionHTTPClient returns the HTTP client of c, skipping TLS verification if the action ignores SSL errors.
+func (c *Client) triggerActionHTTPClient(action triggers.AstarteTriggerAction) *http.Client {
+	if !action.IgnoreSslErrors {
+		return c.httpClient
+	}
+	transport, ok := c.httpClient.Transport.(*http.Transport)
+	if !ok {
+		transport = http.DefaultTransport.(*http.Transport)
+	}
+	transport = transport.Clone()
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	// nolint:gosec
+	transport.TLSClientConfig.InsecureSkipVerify = true
+	httpClient := *c.httpClient
+	httpClient.Transport = transport
+	return &httpClient
+}
+
+func renderTriggerActionPayload(action triggers.AstarteTriggerAction, event events.SimpleEvent) ([]byte, error) {
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	switch action.TemplateType {
+	case "":
+		return eventJSON, nil
+	case triggers.Mustache:
+		data := map[string]any{}
+		if err := json.Unmarshal(eventJSON, &data); err != nil {
+			return nil, err
+		}
+		rendered, err := renderMustache(action.Template, data)
+		return []byte(rendered), err
+	}
+	return nil, action.TemplateType.IsValid()
+}
+
+// renderMustache renders the variables and comments of a Mustache template. Dotted names (e.g. "event.value")
+// access nested values. Sections and partials are not supported.
+func renderMustache(template string, data map[string]any) (string, error) {
+	var b strings.Builder
+	for {
+		start := strings.Index(template, "{{")
+		if start < 0 {
+			b.WriteString(template)
+			return b.String(), nil
+		}
+		b.WriteString(template[:start])
+		template = template[start+2:]
+
+		closing, escape := "}}", true
+		switch {
+		case strings.HasPrefix(template, "{"):
+			closing, escape, template = "}}}", false, template[1:]
+		case strings.HasPrefix(template, "&"):
+			escape, template = false, template[1:]
+		}
+		end := strings.Index(template, closing)
+		if end < 0 {
+			return "", errors.New("Unclosed Mustache tag")
+		}
+		tag := strings.TrimSpace(template[:end])
+		template = template[end+len(closing):]
+
+		switch {
+		case strings.HasPrefix(tag, "!"):
+			continue
+		case tag == "" || strings.ContainsAny(tag[:1], "#^/>=<"):
+			return "", fmt.Errorf("Unsupported Mustache tag {{%s}}", tag)
+		}
+		value := mustacheValue(tag, data)
+		if escape {
+			value = html.EscapeString(value)
+		}
+		b.WriteString(value)
+	}
+}
+
+func mustacheValue(name string, data map[string]any) string {
+	var value any = data
+	if name != "." {
+		for _, key := range strings.Split(name, ".") {
+			m, ok := value.(map[string]any)
+			if !ok {
+				return ""
+			}
+			value = m[key]
+		}
+	}
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+	b, _ := json.Marshal(value)
+	return string(b)
+}
@@ -0,0 +1,45 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import "runtime/debug"
+
+const (
+	astarteGoModulePath = "github.com/astarte-platform/astarte-go"
+	defaultUserAgent    = "astarte-go"
+)
+
+// libraryUserAgent returns the default User Agent of the client, embedding the version of the astarte-go
+// module when it is available in the build information, e.g. "astarte-go/v0.92.2".
+func libraryUserAgent() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return defaultUserAgent
+	}
+	version := info.Main.Version
+	if info.Main.Path != astarteGoModulePath {
+		version = ""
+		for _, dep := range info.Deps {
+			if dep.Path == astarteGoModulePath {
+				version = dep.Version
+				break
+			}
+		}
+	}
+	if version == "" || version == "(devel)" {
+		return defaultUserAgent
+	}
+	return defaultUserAgent + "/" + version
+}
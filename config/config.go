@@ -0,0 +1,188 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package config reads the cluster and context configuration of astartectl, so that Go tools can
+// share the Astarte credentials configured for the CLI. astartectl keeps its configuration in a
+// directory (see DefaultDir) holding:
+//
+//   - astartectl.yaml, whose "context" field is the name of the current context;
+//   - clusters/<name>.yaml, holding the "url" of a cluster, optional "individual-urls" for each
+//     Astarte service, and an optional housekeeping "key";
+//   - contexts/<name>.yaml, holding the name of a "cluster" and the "name" and optional "key"
+//     of a realm.
+//
+// Private keys are stored either base64 encoded or as plain PEM.
+package config
+
+import (
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrNoCurrentContext = errors.New("No current astartectl context is set")
+	ErrContextNotFound  = errors.New("astartectl context not found")
+	ErrClusterNotFound  = errors.New("astartectl cluster not found")
+	ErrInvalidName      = errors.New("Invalid astartectl context or cluster name")
+)
+
+// Cluster is the configuration of an Astarte cluster.
+type Cluster struct {
+	Name string `yaml:"-"`
+	// URL is the base URL of the cluster APIs.
+	URL string `yaml:"url,omitempty"`
+	// IndividualURLs maps service names (e.g. "appengine", "realm-management") to their URL, overriding URL.
+	IndividualURLs map[string]string `yaml:"individual-urls,omitempty"`
+	Housekeeping   struct {
+		Key string `yaml:"key,omitempty"`
+	} `yaml:"housekeeping,omitempty"`
+}
+
+// HousekeepingKey returns the PEM encoded Housekeeping private key of the cluster, or nil if it has none.
+func (c Cluster) HousekeepingKey() ([]byte, error) {
+	return decodeKey(c.Housekeeping.Key)
+}
+
+// Realm is the configuration of a realm in a Context.
+type Realm struct {
+	Name string `yaml:"name,omitempty"`
+	Key  string `yaml:"key,omitempty"`
+}
+
+// PrivateKey returns the PEM encoded private key of the realm, or nil if it has none.
+func (r Realm) PrivateKey() ([]byte, error) {
+	return decodeKey(r.Key)
+}
+
+// Context is an astartectl context, i.e. a realm in a cluster.
+type Context struct {
+	Name        string `yaml:"-"`
+	ClusterName string `yaml:"cluster,omitempty"`
+	Realm       Realm  `yaml:"realm,omitempty"`
+	// Cluster is the cluster named by ClusterName.
+	Cluster Cluster `yaml:"-"`
+}
+
+type baseConfig struct {
+	CurrentContext string `yaml:"context"`
+}
+
+// DefaultDir returns the directory in which astartectl keeps its configuration, i.e. "astarte"
+// in the user configuration directory (e.g. ~/.config/astarte on Linux).
+func DefaultDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "astarte"), nil
+}
+
+// CurrentContextName returns the name of the current context in the configuration directory dir.
+func CurrentContextName(dir string) (string, error) {
+	base := baseConfig{}
+	if err := readYAML(filepath.Join(dir, "astartectl.yaml"), &base); errors.Is(err, fs.ErrNotExist) {
+		return "", ErrNoCurrentContext
+	} else if err != nil {
+		return "", err
+	}
+	if base.CurrentContext == "" {
+		return "", ErrNoCurrentContext
+	}
+	return base.CurrentContext, nil
+}
+
+// LoadContext loads the context called name from the configuration directory dir, together with its cluster.
+// If name is empty, the current context is loaded.
+func LoadContext(dir, name string) (Context, error) {
+	if name == "" {
+		var err error
+		if name, err = CurrentContextName(dir); err != nil {
+			return Context{}, err
+		}
+	}
+	if !isValidName(name) {
+		return Context{}, ErrInvalidName
+	}
+	context := Context{}
+	if err := readYAML(filepath.Join(dir, "contexts", name+".yaml"), &context); errors.Is(err, fs.ErrNotExist) {
+		return Context{}, fmt.Errorf("%w: %s", ErrContextNotFound, name)
+	} else if err != nil {
+		return Context{}, err
+	}
+	context.Name = name
+
+	cluster, err := LoadCluster(dir, context.ClusterName)
+	if err != nil {
+		return Context{}, err
+	}
+	context.Cluster = cluster
+	return context, nil
+}
+
+// LoadCluster loads the cluster called name from the configuration directory dir.
+func LoadCluster(dir, name string) (Cluster, error) {
+	if !isValidName(name) {
+		return Cluster{}, ErrInvalidName
+	}
+	cluster := Cluster{}
+	if err := readYAML(filepath.Join(dir, "clusters", name+".yaml"), &cluster); errors.Is(err, fs.ErrNotExist) {
+		return Cluster{}, fmt.Errorf("%w: %s", ErrClusterNotFound, name)
+	} else if err != nil {
+		return Cluster{}, err
+	}
+	cluster.Name = name
+	return cluster, nil
+}
+
+func readYAML(path string, out any) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(b, out); err != nil {
+		return fmt.Errorf("Invalid astartectl configuration file %s: %w", path, err)
+	}
+	return nil
+}
+
+// isValidName makes sure name can't escape the configuration directory.
+func isValidName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
+
+func decodeKey(key string) ([]byte, error) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return nil, nil
+	}
+	if block, _ := pem.Decode([]byte(key)); block != nil {
+		return []byte(key), nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid private key: %w", err)
+	}
+	if block, _ := pem.Decode(decoded); block == nil {
+		return nil, errors.New("Invalid private key: not PEM encoded")
+	}
+	return decoded, nil
+}
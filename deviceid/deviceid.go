@@ -15,7 +15,9 @@
 package deviceid
 
 import (
+	"crypto/rand"
 	"encoding/base64"
+	"io"
 
 	"github.com/google/uuid"
 )
@@ -38,7 +40,14 @@ func IsValid(deviceID string) bool {
 // GenerateRandom returns a new Astarte Device ID on a fully Random basis.
 // Do not use in production environments.
 func GenerateRandom() (string, error) {
-	randomUUID, err := uuid.NewRandom()
+	return GenerateRandomFromReader(rand.Reader)
+}
+
+// GenerateRandomFromReader works like GenerateRandom, but reads random bytes from r.
+// This is mostly useful to generate reproducible device IDs in tests.
+// Do not use in production environments.
+func GenerateRandomFromReader(r io.Reader) (string, error) {
+	randomUUID, err := uuid.NewRandomFromReader(r)
 	if err != nil {
 		return "", err
 	}
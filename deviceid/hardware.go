@@ -0,0 +1,75 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deviceid
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidHardwareID is returned when a hardware identifier cannot be used to derive a Device ID.
+var ErrInvalidHardwareID = errors.New("invalid hardware identifier")
+
+// ToBytes returns the 16 bytes of the UUID encoded by deviceID, in big-endian (network) order.
+func ToBytes(deviceID string) ([16]byte, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(deviceID)
+	if err != nil {
+		return [16]byte{}, err
+	}
+	deviceUUID, err := uuid.FromBytes(decoded)
+	if err != nil {
+		return [16]byte{}, err
+	}
+	return deviceUUID, nil
+}
+
+// FromBytes returns the Device ID encoding the 16 big-endian bytes of a UUID.
+func FromBytes(uuidBytes [16]byte) string {
+	return base64.RawURLEncoding.EncodeToString(uuidBytes[:])
+}
+
+// FromHardwareID deterministically derives a Device ID from a hardware identifier, generating
+// a UUIDv5 in uuidNamespace as Generate does. Devices and provisioning tools agree on the
+// Device ID only if they share the namespace and hash exactly the same bytes.
+func FromHardwareID(uuidNamespace string, hardwareID []byte) (string, error) {
+	if len(hardwareID) == 0 {
+		return "", ErrInvalidHardwareID
+	}
+	return Generate(uuidNamespace, hardwareID)
+}
+
+// FromMACAddress derives a Device ID from a MAC address. Any notation accepted by net.ParseMAC
+// can be used: the address is normalized to its lowercase, colon-separated form before hashing,
+// so e.g. "00-1A-2B-3C-4D-5E" and "00:1a:2b:3c:4d:5e" yield the same Device ID. This normalization
+// is a convention of this library, not of Astarte device SDKs: a device deriving its own Device ID
+// must hash the same string, rather than e.g. the raw bytes of the address, to get the same result.
+func FromMACAddress(uuidNamespace, macAddress string) (string, error) {
+	hw, err := net.ParseMAC(strings.TrimSpace(macAddress))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidHardwareID, err)
+	}
+	return FromHardwareID(uuidNamespace, []byte(hw.String()))
+}
+
+// FromSerialNumber derives a Device ID from a serial number. Leading and trailing whitespace
+// is ignored, while the serial number is otherwise hashed as is.
+func FromSerialNumber(uuidNamespace, serialNumber string) (string, error) {
+	return FromHardwareID(uuidNamespace, []byte(strings.TrimSpace(serialNumber)))
+}
@@ -0,0 +1,60 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deviceid
+
+import "testing"
+
+const testNamespace = "f79ad91f-c638-4889-ae74-9d001a3b4cf8"
+
+func TestFromMACAddress(t *testing.T) {
+	for _, mac := range []string{"00:1a:2b:3c:4d:5e", "00-1A-2B-3C-4D-5E", " 00:1A:2B:3C:4D:5E "} {
+		deviceID, err := FromMACAddress(testNamespace, mac)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if deviceID != "gK3htpUTVNS68sLhvHGtqQ" {
+			t.Errorf("Unexpected Device ID for %q: %s", mac, deviceID)
+		}
+	}
+	if _, err := FromMACAddress(testNamespace, "not a mac"); err == nil {
+		t.Error("Expected an error for an invalid MAC address, found nil")
+	}
+}
+
+func TestFromSerialNumber(t *testing.T) {
+	deviceID, err := FromSerialNumber(testNamespace, "SN-0042\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deviceID != "PEqEFuNpUYuo1I31UMLHlQ" {
+		t.Errorf("Unexpected Device ID: %s", deviceID)
+	}
+	if _, err := FromSerialNumber(testNamespace, "  "); err == nil {
+		t.Error("Expected an error for an empty serial number, found nil")
+	}
+}
+
+func TestBytesRoundTrip(t *testing.T) {
+	uuidBytes, err := ToBytes("gK3htpUTVNS68sLhvHGtqQ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uuidBytes[0] != 0x80 || uuidBytes[6]>>4 != 5 {
+		t.Errorf("Unexpected UUID bytes: %x", uuidBytes)
+	}
+	if deviceID := FromBytes(uuidBytes); deviceID != "gK3htpUTVNS68sLhvHGtqQ" {
+		t.Errorf("Unexpected Device ID: %s", deviceID)
+	}
+}
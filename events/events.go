@@ -0,0 +1,60 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package events provides types for the events which Astarte delivers through trigger actions.
+package events
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/triggers"
+)
+
+// SimpleEvent represents an event generated by a simple trigger, as delivered by a trigger action
+// with the v1 event format.
+type SimpleEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	DeviceID  string    `json:"device_id"`
+	Event     Event     `json:"event"`
+}
+
+// Event represents the content of a SimpleEvent. Which fields are set depends on Type.
+type Event struct {
+	Type triggers.AstarteTriggerOn `json:"type"`
+
+	// DeviceIPAddress is set by DeviceConnected events.
+	DeviceIPAddress string `json:"device_ip_address,omitempty"`
+	// ErrorName and Metadata are set by DeviceError events.
+	ErrorName string            `json:"error_name,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+
+	// Interface and Path are set by data trigger events.
+	Interface string `json:"interface,omitempty"`
+	Path      string `json:"path,omitempty"`
+	// Value is set by IncomingData, ValueStored, PathCreated and ValueChangeApplied events.
+	Value any `json:"value,omitempty"`
+	// OldValue and NewValue are set by ValueChange events, OldValue also by PathRemoved events.
+	OldValue any `json:"old_value,omitempty"`
+	NewValue any `json:"new_value,omitempty"`
+}
+
+// ParseSimpleEvent parses a SimpleEvent from its JSON representation.
+func ParseSimpleEvent(b []byte) (SimpleEvent, error) {
+	event := SimpleEvent{}
+	if err := json.Unmarshal(b, &event); err != nil {
+		return SimpleEvent{}, err
+	}
+	return event, nil
+}
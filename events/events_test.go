@@ -0,0 +1,52 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/triggers"
+)
+
+func TestSimpleEventJSON(t *testing.T) {
+	event := SimpleEvent{
+		Timestamp: time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC),
+		DeviceID:  "f0VMRgIBAQAAAAAAAAAAAA",
+		Event:     Event{Type: triggers.IncomingData, Interface: "ah.yes.an.Interface", Path: "/a/value", Value: false},
+	}
+	b, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"timestamp":"2024-05-06T07:08:09Z","device_id":"f0VMRgIBAQAAAAAAAAAAAA",` +
+		`"event":{"type":"incoming_data","interface":"ah.yes.an.Interface","path":"/a/value","value":false}}`
+	if string(b) != expected {
+		t.Errorf("Expected %s, found %s", expected, b)
+	}
+
+	parsed, err := ParseSimpleEvent(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.DeviceID != event.DeviceID || parsed.Event.Type != triggers.IncomingData || parsed.Event.Value != false {
+		t.Errorf("Unexpected parsed event: %+v", parsed)
+	}
+
+	if _, err := ParseSimpleEvent([]byte(`{"event": {"type": "not_an_event"}}`)); err == nil {
+		t.Error("Expected an error for an invalid event type, found nil")
+	}
+}
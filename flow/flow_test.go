@@ -0,0 +1,130 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flow
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/interfaces"
+)
+
+const testMessages = `{"schema": "astarte_flow/message/v0.1", "key": "temperature", "metadata": {"unit": "C"}, "type": "real", "timestamp": 1704067200000000, "data": 21.5}
+{"schema": "astarte_flow/message/v0.1", "key": "counters", "metadata": {}, "type": {"array": "integer"}, "timestamp": 1704067200000001, "data": [1, 2, 3]}
+{"schema": "astarte_flow/message/v0.1", "key": "blob", "metadata": {}, "type": "binary", "subtype": "text/plain", "timestamp": 0, "data": "aGVsbG8="}
+`
+
+func TestDecodeMessages(t *testing.T) {
+	decoder := NewDecoder(strings.NewReader(testMessages))
+	messages := []Message{}
+	for {
+		m, err := decoder.Decode()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, m)
+	}
+	if len(messages) != 3 {
+		t.Fatalf("Expected 3 messages, found %d", len(messages))
+	}
+
+	if messages[0].Type != interfaces.Double || messages[0].Data != 21.5 || messages[0].Metadata["unit"] != "C" ||
+		!messages[0].Timestamp.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected first message: %+v", messages[0])
+	}
+	counters, ok := messages[1].Data.([]int64)
+	if messages[1].Type != interfaces.LongIntegerArray || !ok || len(counters) != 3 || counters[2] != 3 {
+		t.Errorf("Unexpected second message: %+v", messages[1])
+	}
+	blob, ok := messages[2].Data.([]byte)
+	if messages[2].Type != interfaces.BinaryBlob || !ok || string(blob) != "hello" || messages[2].Subtype != "text/plain" {
+		t.Errorf("Unexpected third message: %+v", messages[2])
+	}
+
+	invalidMessages := []string{
+		`{"schema": "astarte_flow/message/v42", "key": "k", "type": "real", "data": 1.0}`,
+		`{"schema": "astarte_flow/message/v0.1", "key": "k", "type": "map", "data": {}}`,
+		`{"schema": "astarte_flow/message/v0.1", "key": "k", "type": "integer", "data": "not an integer"}`,
+	}
+	for _, invalid := range invalidMessages {
+		if _, err := ParseMessage([]byte(invalid)); err == nil {
+			t.Errorf("Invalid message was parsed: %s", invalid)
+		}
+	}
+}
+
+func TestMessageRoundTrip(t *testing.T) {
+	m := Message{
+		Key:       "datetimes",
+		Type:      interfaces.DateTimeArray,
+		Timestamp: time.Date(2024, 1, 1, 0, 0, 0, 1000, time.UTC),
+		Data:      []time.Time{time.Date(2024, 2, 2, 0, 0, 0, 0, time.UTC)},
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseMessage(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	datetimes, ok := parsed.Data.([]time.Time)
+	if parsed.Key != m.Key || parsed.Type != m.Type || !parsed.Timestamp.Equal(m.Timestamp) || !ok || !datetimes[0].Equal(m.Data.([]time.Time)[0]) {
+		t.Errorf("Message %+v does not match original message %+v", parsed, m)
+	}
+
+	if _, err := json.Marshal(Message{Type: interfaces.Integer, Data: 42}); err == nil {
+		t.Error("Message with a type unsupported by Astarte Flow was marshaled")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	handler := Handler(func(m Message, r *Responder) error {
+		if m.Type != interfaces.Double {
+			return nil
+		}
+		m.Data = m.Data.(float64) * 2
+		return r.Respond(m)
+	})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(testMessages)))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != NDJSONContentType {
+		t.Fatalf("Unexpected response: %d %s", rec.Code, rec.Body.String())
+	}
+	response, err := NewDecoder(rec.Body).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Data != 43.0 {
+		t.Errorf("Unexpected response message: %+v", response)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"schema": "unknown"}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, found %d", http.StatusBadRequest, rec.Code)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flow
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// NDJSONContentType is the content type of streams of newline-delimited Astarte Flow messages.
+const NDJSONContentType = "application/x-ndjson"
+
+// Decoder reads a stream of Astarte Flow messages, either newline-delimited or concatenated.
+type Decoder struct {
+	dec *json.Decoder
+}
+
+// NewDecoder returns a Decoder reading messages from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{dec: json.NewDecoder(r)}
+}
+
+// Decode returns the next message in the stream. It returns io.EOF when the stream is over.
+func (d *Decoder) Decode() (Message, error) {
+	m := Message{}
+	err := d.dec.Decode(&m)
+	return m, err
+}
+
+// Responder writes Astarte Flow messages as a newline-delimited stream. It can be used on its own,
+// e.g. to publish messages to an AMQP exchange, or within a Handler.
+type Responder struct {
+	enc *json.Encoder
+}
+
+// NewResponder returns a Responder writing messages to w.
+func NewResponder(w io.Writer) *Responder {
+	return &Responder{enc: json.NewEncoder(w)}
+}
+
+// Respond writes m to the stream.
+func (r *Responder) Respond(m Message) error {
+	return r.enc.Encode(m)
+}
+
+// HandlerFunc processes a single Astarte Flow message, optionally responding with any number of messages.
+type HandlerFunc func(m Message, r *Responder) error
+
+// Handler returns an http.Handler accepting a stream of Astarte Flow messages in the request body and
+// calling f for each message. Messages sent through the Responder are returned as a newline-delimited
+// stream in the response body. If a message can't be decoded, or f returns an error, no messages are
+// returned and the request fails with an Astarte-style JSON error.
+func Handler(f HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, errors.New("Only POST requests are accepted"))
+			return
+		}
+
+		b := new(bytes.Buffer)
+		responder := NewResponder(b)
+		decoder := NewDecoder(req.Body)
+		for {
+			m, err := decoder.Decode()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+			if err := f(m, responder); err != nil {
+				writeError(w, http.StatusUnprocessableEntity, err)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", NDJSONContentType)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(b.Bytes())
+	})
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{"errors": map[string]string{"detail": err.Error()}})
+}
@@ -0,0 +1,204 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package flow provides utilities to build custom Astarte Flow blocks, decoding and encoding
+// Astarte Flow messages.
+package flow
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/interfaces"
+)
+
+// MessageSchema is the schema of the Astarte Flow messages handled by this package.
+const MessageSchema = "astarte_flow/message/v0.1"
+
+// Message represents an Astarte Flow message.
+type Message struct {
+	Key      string
+	Metadata map[string]string
+	// Type is the type of Data, expressed as an Astarte mapping type. Data is a float64 for Double,
+	// an int64 for LongInteger, a bool for Boolean, a string for String, a time.Time for DateTime,
+	// a []byte for BinaryBlob, or a slice of the corresponding type for array types.
+	Type interfaces.AstarteMappingType
+	// Subtype is the MIME type of binary data, if any.
+	Subtype   string
+	Timestamp time.Time
+	Data      any
+}
+
+type rawMessage struct {
+	Schema    string            `json:"schema"`
+	Key       string            `json:"key"`
+	Metadata  map[string]string `json:"metadata"`
+	Type      json.RawMessage   `json:"type"`
+	Subtype   string            `json:"subtype,omitempty"`
+	Timestamp int64             `json:"timestamp"`
+	Data      json.RawMessage   `json:"data"`
+}
+
+type rawArrayType struct {
+	Array string `json:"array"`
+}
+
+var flowToMappingType = map[string]interfaces.AstarteMappingType{
+	"real":     interfaces.Double,
+	"integer":  interfaces.LongInteger,
+	"boolean":  interfaces.Boolean,
+	"string":   interfaces.String,
+	"datetime": interfaces.DateTime,
+	"binary":   interfaces.BinaryBlob,
+}
+
+var flowToMappingArrayType = map[string]interfaces.AstarteMappingType{
+	"real":     interfaces.DoubleArray,
+	"integer":  interfaces.LongIntegerArray,
+	"boolean":  interfaces.BooleanArray,
+	"string":   interfaces.StringArray,
+	"datetime": interfaces.DateTimeArray,
+	"binary":   interfaces.BinaryBlobArray,
+}
+
+// ParseMessage parses an Astarte Flow message from its JSON representation.
+func ParseMessage(b []byte) (Message, error) {
+	m := Message{}
+	err := json.Unmarshal(b, &m)
+	return m, err
+}
+
+// UnmarshalJSON unmarshals an Astarte Flow message, decoding its data according to its type
+func (m *Message) UnmarshalJSON(b []byte) error {
+	raw := rawMessage{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	if raw.Schema != MessageSchema {
+		return fmt.Errorf("Unsupported Astarte Flow message schema: '%s'", raw.Schema)
+	}
+	mappingType, err := parseMessageType(raw.Type)
+	if err != nil {
+		return err
+	}
+	data, err := decodeMessageData(mappingType, raw.Data)
+	if err != nil {
+		return err
+	}
+
+	*m = Message{
+		Key:       raw.Key,
+		Metadata:  raw.Metadata,
+		Type:      mappingType,
+		Subtype:   raw.Subtype,
+		Timestamp: time.UnixMicro(raw.Timestamp).UTC(),
+		Data:      data,
+	}
+	return nil
+}
+
+// MarshalJSON marshals an Astarte Flow message. Data is expected to match Type, but this is not checked.
+func (m Message) MarshalJSON() ([]byte, error) {
+	messageType, err := formatMessageType(m.Type)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(interfaces.NormalizePayload(m.Data, true))
+	if err != nil {
+		return nil, err
+	}
+	metadata := m.Metadata
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	return json.Marshal(rawMessage{
+		Schema:    MessageSchema,
+		Key:       m.Key,
+		Metadata:  metadata,
+		Type:      messageType,
+		Subtype:   m.Subtype,
+		Timestamp: m.Timestamp.UnixMicro(),
+		Data:      data,
+	})
+}
+
+func parseMessageType(b json.RawMessage) (interfaces.AstarteMappingType, error) {
+	var scalar string
+	if err := json.Unmarshal(b, &scalar); err == nil {
+		if t, ok := flowToMappingType[scalar]; ok {
+			return t, nil
+		}
+		return "", fmt.Errorf("Unsupported Astarte Flow message type: '%s'", scalar)
+	}
+	array := rawArrayType{}
+	if err := json.Unmarshal(b, &array); err != nil {
+		return "", fmt.Errorf("Invalid Astarte Flow message type: %s", b)
+	}
+	if t, ok := flowToMappingArrayType[array.Array]; ok {
+		return t, nil
+	}
+	return "", fmt.Errorf("Unsupported Astarte Flow message array type: '%s'", array.Array)
+}
+
+func formatMessageType(t interfaces.AstarteMappingType) (json.RawMessage, error) {
+	for flowType, mappingType := range flowToMappingType {
+		if mappingType == t {
+			return json.Marshal(flowType)
+		}
+	}
+	for flowType, mappingType := range flowToMappingArrayType {
+		if mappingType == t {
+			return json.Marshal(rawArrayType{Array: flowType})
+		}
+	}
+	return nil, fmt.Errorf("Type %s cannot be used in Astarte Flow messages", t)
+}
+
+func decodeMessageData(t interfaces.AstarteMappingType, b json.RawMessage) (any, error) {
+	switch t {
+	case interfaces.Double:
+		return decodeData[float64](b)
+	case interfaces.LongInteger:
+		return decodeData[int64](b)
+	case interfaces.Boolean:
+		return decodeData[bool](b)
+	case interfaces.String:
+		return decodeData[string](b)
+	case interfaces.DateTime:
+		return decodeData[time.Time](b)
+	case interfaces.BinaryBlob:
+		return decodeData[[]byte](b)
+	case interfaces.DoubleArray:
+		return decodeData[[]float64](b)
+	case interfaces.LongIntegerArray:
+		return decodeData[[]int64](b)
+	case interfaces.BooleanArray:
+		return decodeData[[]bool](b)
+	case interfaces.StringArray:
+		return decodeData[[]string](b)
+	case interfaces.DateTimeArray:
+		return decodeData[[]time.Time](b)
+	case interfaces.BinaryBlobArray:
+		return decodeData[[][]byte](b)
+	}
+	return nil, errors.New("Unsupported Astarte Flow message type")
+}
+
+func decodeData[T any](b json.RawMessage) (T, error) {
+	var ret T
+	err := json.Unmarshal(b, &ret)
+	return ret, err
+}
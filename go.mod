@@ -9,6 +9,8 @@ require (
 	github.com/iancoleman/orderedmap v0.3.0
 	github.com/nqd/flat v0.2.0
 	github.com/tidwall/gjson v1.17.0
+	golang.org/x/sync v0.9.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
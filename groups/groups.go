@@ -0,0 +1,45 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package groups
+
+import (
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// IsValid returns whether the provided name is a valid Astarte group name or not.
+// Group names must be non-empty valid UTF-8 strings and cannot start with '@' or '~',
+// which are reserved by Astarte. Since group names are used as a single path segment
+// when calling Astarte APIs, "." and ".." are not valid group names either.
+func IsValid(groupName string) bool {
+	if groupName == "" || !utf8.ValidString(groupName) {
+		return false
+	}
+	if strings.HasPrefix(groupName, "@") || strings.HasPrefix(groupName, "~") {
+		return false
+	}
+	if groupName == "." || groupName == ".." {
+		return false
+	}
+	return true
+}
+
+// EscapePath escapes a group name so that it can be safely used as a single path segment
+// in an Astarte API URL. Any character which is not allowed in a path segment, including
+// slashes, is percent-encoded.
+func EscapePath(groupName string) string {
+	return url.PathEscape(groupName)
+}
@@ -0,0 +1,50 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package groups
+
+import (
+	"testing"
+)
+
+func TestIsValid(t *testing.T) {
+	valid := []string{"group", "ah yes, a group", "gruppo/à/ünicode", "日本語のグループ", "a@b", "a~b", "..."}
+	for _, name := range valid {
+		if !IsValid(name) {
+			t.Errorf("%q should be a valid group name", name)
+		}
+	}
+
+	invalid := []string{"", "@group", "~group", ".", "..", string([]byte{0xff, 0xfe})}
+	for _, name := range invalid {
+		if IsValid(name) {
+			t.Errorf("%q should not be a valid group name", name)
+		}
+	}
+}
+
+func TestEscapePath(t *testing.T) {
+	cases := map[string]string{
+		"group":            "group",
+		"ah yes, a group":  "ah%20yes%2C%20a%20group",
+		"with/slash":       "with%2Fslash",
+		"gruppo-à":         "gruppo-%C3%A0",
+		"percent%20inside": "percent%2520inside",
+	}
+	for name, expected := range cases {
+		if escaped := EscapePath(name); escaped != expected {
+			t.Errorf("Unexpected escaping for %q: %s instead of %s", name, escaped, expected)
+		}
+	}
+}
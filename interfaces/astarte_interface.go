@@ -290,6 +290,8 @@ type AstarteInterfaceMapping struct {
 	AllowUnset              bool                                  `json:"allow_unset,omitempty"`
 	Description             string                                `json:"description,omitempty"`
 	Documentation           string                                `json:"doc,omitempty"`
+	// unknownFields holds the fields this package does not know about, see UnknownFields.
+	unknownFields map[string]json.RawMessage
 }
 
 // AstarteInterface represents an Astarte Interface
@@ -305,6 +307,8 @@ type AstarteInterface struct {
 	Description       string                      `json:"description,omitempty"`
 	Documentation     string                      `json:"doc,omitempty"`
 	Mappings          []AstarteInterfaceMapping   `json:"mappings"`
+	// unknownFields holds the fields this package does not know about, see UnknownFields.
+	unknownFields map[string]json.RawMessage
 }
 
 // requiredAstarteInterface is an helper struct used for validating required fields when unmarshalling an
@@ -16,6 +16,8 @@ package interfaces
 
 import (
 	"encoding/json"
+	"errors"
+	"reflect"
 	"testing"
 )
 
@@ -583,3 +585,77 @@ func TestFailedOwnershipParsing(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestUnknownFields(t *testing.T) {
+	futureInterface := `
+	{
+		"interface_name": "org.astarte-platform.genericsensors.Values",
+		"version_major": 1,
+		"version_minor": 0,
+		"type": "datastream",
+		"ownership": "device",
+		"x_future": {"nested": [1, 2]},
+		"mappings": [
+			{
+				"endpoint": "/%{sensor_id}/value",
+				"type": "double",
+				"x_unit": "K"
+			}
+		]
+	}`
+
+	i, err := ParseInterface([]byte(futureInterface))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(i.UnknownFields(), []string{"x_future"}) {
+		t.Errorf("Unexpected unknown fields %v", i.UnknownFields())
+	}
+	if !reflect.DeepEqual(i.Mappings[0].UnknownFields(), []string{"x_unit"}) {
+		t.Errorf("Unexpected unknown mapping fields %v", i.Mappings[0].UnknownFields())
+	}
+
+	b, err := json.Marshal(i)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roundTrip := map[string]any{}
+	if err := json.Unmarshal(b, &roundTrip); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(roundTrip["x_future"], map[string]any{"nested": []any{1.0, 2.0}}) {
+		t.Errorf("Unknown field not preserved: %s", b)
+	}
+	mapping := roundTrip["mappings"].([]any)[0].(map[string]any)
+	if mapping["x_unit"] != "K" || mapping["type"] != "double" {
+		t.Errorf("Unknown mapping field not preserved: %s", b)
+	}
+
+	known := i
+	known.unknownFields = nil
+	known.Mappings = []AstarteInterfaceMapping{i.Mappings[0]}
+	known.Mappings[0].unknownFields = nil
+	if Fingerprint(i) != Fingerprint(known) {
+		t.Error("Unknown fields should not change the fingerprint")
+	}
+
+	if _, err := ParseInterfaceStrict([]byte(futureInterface)); !errors.Is(err, ErrUnknownField) {
+		t.Errorf("Expected ErrUnknownField, got %v", err)
+	}
+	strict, err := ParseInterfaceStrict(mustMarshal(t, known))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(strict.UnknownFields()) > 0 {
+		t.Errorf("Unexpected unknown fields %v", strict.UnknownFields())
+	}
+}
+
+func mustMarshal(t *testing.T, v any) []byte {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
@@ -0,0 +1,164 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interfaces
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CompiledInterface is an AstarteInterface whose endpoints are compiled into a trie, resolving concrete paths to
+// mappings and extracting their parameters in a single pass over the path. It is safe for concurrent use, and
+// should be preferred over InterfaceMappingFromPath and the Validate functions when validating many messages.
+type CompiledInterface struct {
+	Interface AstarteInterface
+	root      *endpointNode
+}
+
+// endpointNode is a level of the endpoints of an interface.
+type endpointNode struct {
+	children      map[string]*endpointNode
+	parameter     *endpointNode
+	parameterName string
+	// mapping is the index of the mapping whose endpoint ends at this node, or -1.
+	mapping int
+}
+
+func newEndpointNode() *endpointNode {
+	return &endpointNode{children: map[string]*endpointNode{}, mapping: -1}
+}
+
+var compiledInterfaces sync.Map
+
+// Compile returns the CompiledInterface for astarteInterface. Compiled interfaces are cached, so compiling
+// the same interface many times is cheap.
+func Compile(astarteInterface AstarteInterface) *CompiledInterface {
+	key := compiledInterfaceKey(astarteInterface)
+	if compiled, ok := compiledInterfaces.Load(key); ok {
+		return compiled.(*CompiledInterface)
+	}
+	compiled := &CompiledInterface{Interface: astarteInterface, root: newEndpointNode()}
+	for i, mapping := range astarteInterface.Mappings {
+		node := compiled.root
+		for _, token := range strings.Split(strings.TrimPrefix(mapping.Endpoint, "/"), "/") {
+			if parameterName, ok := parameterNameFromToken(token); ok {
+				if node.parameter == nil {
+					node.parameter = newEndpointNode()
+					node.parameterName = parameterName
+				}
+				node = node.parameter
+				continue
+			}
+			if node.children[token] == nil {
+				node.children[token] = newEndpointNode()
+			}
+			node = node.children[token]
+		}
+		node.mapping = i
+	}
+	actual, _ := compiledInterfaces.LoadOrStore(key, compiled)
+	return actual.(*CompiledInterface)
+}
+
+// compiledInterfaceKey identifies an interface by its name, version and endpoints, since interfaces with the
+// same name and version might still differ while being developed.
+func compiledInterfaceKey(astarteInterface AstarteInterface) string {
+	var b strings.Builder
+	b.WriteString(astarteInterface.Name)
+	b.WriteByte(' ')
+	b.WriteString(strconv.Itoa(astarteInterface.MajorVersion))
+	b.WriteByte('.')
+	b.WriteString(strconv.Itoa(astarteInterface.MinorVersion))
+	for _, mapping := range astarteInterface.Mappings {
+		b.WriteByte(' ')
+		b.WriteString(mapping.Endpoint)
+	}
+	return b.String()
+}
+
+// Resolve returns the mapping matching interfacePath, together with the values of its parameters.
+// Parameters cannot have empty values. When a level of interfacePath matches both a constant and a parametric
+// level of the endpoints, the constant one is preferred.
+func (c *CompiledInterface) Resolve(interfacePath string) (AstarteInterfaceMapping, map[string]string, error) {
+	var parameters map[string]string
+	if rest, ok := strings.CutPrefix(interfacePath, "/"); ok {
+		if mapping := c.resolve(c.root, rest, &parameters); mapping >= 0 {
+			if parameters == nil {
+				parameters = map[string]string{}
+			}
+			return c.Interface.Mappings[mapping], parameters, nil
+		}
+	}
+	return AstarteInterfaceMapping{}, nil, fmt.Errorf("Path %s does not exist on Interface %s", interfacePath, c.Interface.Name)
+}
+
+func (c *CompiledInterface) resolve(node *endpointNode, rest string, parameters *map[string]string) int {
+	token, rest, more := strings.Cut(rest, "/")
+	if child := node.children[token]; child != nil {
+		if mapping := c.resolveChild(child, rest, more, parameters); mapping >= 0 {
+			return mapping
+		}
+	}
+	if node.parameter == nil || token == "" {
+		return -1
+	}
+	mapping := c.resolveChild(node.parameter, rest, more, parameters)
+	if mapping >= 0 {
+		if *parameters == nil {
+			*parameters = map[string]string{}
+		}
+		(*parameters)[node.parameterName] = token
+	}
+	return mapping
+}
+
+func (c *CompiledInterface) resolveChild(child *endpointNode, rest string, more bool, parameters *map[string]string) int {
+	if !more {
+		return child.mapping
+	}
+	return c.resolve(child, rest, parameters)
+}
+
+// MappingFromPath works like InterfaceMappingFromPath.
+func (c *CompiledInterface) MappingFromPath(interfacePath string) (AstarteInterfaceMapping, error) {
+	mapping, _, err := c.Resolve(interfacePath)
+	return mapping, err
+}
+
+// ValidateIndividualMessage works like ValidateIndividualMessage.
+func (c *CompiledInterface) ValidateIndividualMessage(interfacePath string, value interface{}) error {
+	mapping, err := c.MappingFromPath(interfacePath)
+	if err != nil {
+		return err
+	}
+	return validateType(mapping.Type, value)
+}
+
+// ValidateAggregateMessage works like ValidateAggregateMessage.
+func (c *CompiledInterface) ValidateAggregateMessage(interfacePath string, values map[string]interface{}) error {
+	for k, v := range values {
+		if strings.Contains(k, "/") {
+			return errors.New("values must contain keys without slash")
+		}
+		if err := c.ValidateIndividualMessage(path.Join(interfacePath, k), v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
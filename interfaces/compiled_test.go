@@ -0,0 +1,102 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interfaces
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompiledInterfaceResolve(t *testing.T) {
+	astarteInterface := AstarteInterface{
+		Name:         "org.astarte-platform.test.Compiled",
+		MajorVersion: 1,
+		Mappings: []AstarteInterfaceMapping{
+			{Endpoint: "/%{sensor_id}/value", Type: Double},
+			{Endpoint: "/%{sensor_id}/name", Type: String},
+			{Endpoint: "/common/value", Type: Integer},
+			{Endpoint: "/%{sensor_id}/%{field}/raw", Type: Integer},
+		},
+	}
+	compiled := Compile(astarteInterface)
+
+	testCases := []struct {
+		path       string
+		endpoint   string
+		parameters map[string]string
+	}{
+		{"/s1/value", "/%{sensor_id}/value", map[string]string{"sensor_id": "s1"}},
+		{"/s1/name", "/%{sensor_id}/name", map[string]string{"sensor_id": "s1"}},
+		{"/common/value", "/common/value", map[string]string{}},
+		{"/common/name", "/%{sensor_id}/name", map[string]string{"sensor_id": "common"}},
+		{"/s1/temperature/raw", "/%{sensor_id}/%{field}/raw", map[string]string{"sensor_id": "s1", "field": "temperature"}},
+		{"/s1/other", "", nil},
+		{"//value", "", nil},
+		{"s1/value", "", nil},
+		{"/s1/value/", "", nil},
+		{"/s1/temperature", "", nil},
+	}
+	for _, tc := range testCases {
+		mapping, parameters, err := compiled.Resolve(tc.path)
+		if tc.endpoint == "" {
+			if err == nil {
+				t.Errorf("%s: expected an error, resolved %s", tc.path, mapping.Endpoint)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tc.path, err)
+			continue
+		}
+		if mapping.Endpoint != tc.endpoint || !reflect.DeepEqual(parameters, tc.parameters) {
+			t.Errorf("%s: resolved %s with %v, expected %s with %v", tc.path, mapping.Endpoint, parameters, tc.endpoint, tc.parameters)
+		}
+	}
+
+	if err := compiled.ValidateIndividualMessage("/s1/value", 4.2); err != nil {
+		t.Error(err)
+	}
+	if err := compiled.ValidateIndividualMessage("/s1/value", "not a double"); err == nil {
+		t.Error("Expected an error for a value of the wrong type")
+	}
+	if err := compiled.ValidateAggregateMessage("/s1", map[string]interface{}{"value": 4.2, "name": "a sensor"}); err != nil {
+		t.Error(err)
+	}
+
+	if Compile(astarteInterface) != compiled {
+		t.Error("Compiling the same interface twice did not use the cache")
+	}
+	changed := astarteInterface
+	changed.Mappings = astarteInterface.Mappings[:1]
+	if _, err := Compile(changed).MappingFromPath("/s1/name"); err == nil {
+		t.Error("Compiling an interface with different mappings used a stale cache entry")
+	}
+}
+
+func BenchmarkCompiledInterfaceResolve(b *testing.B) {
+	astarteInterface := AstarteInterface{
+		Name: "org.astarte-platform.test.Compiled",
+		Mappings: []AstarteInterfaceMapping{
+			{Endpoint: "/%{sensor_id}/value", Type: Double},
+			{Endpoint: "/%{sensor_id}/name", Type: String},
+			{Endpoint: "/%{sensor_id}/unit", Type: String},
+		},
+	}
+	compiled := Compile(astarteInterface)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = compiled.MappingFromPath("/s1/unit")
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interfaces_test
+
+import (
+	"fmt"
+
+	"github.com/astarte-platform/astarte-go/interfaces"
+)
+
+func ExampleParseInterface() {
+	iface, err := interfaces.ParseInterface([]byte(`{
+		"interface_name": "org.astarte-platform.genericsensors.Values",
+		"version_major": 1,
+		"version_minor": 0,
+		"type": "datastream",
+		"ownership": "device",
+		"mappings": [
+			{"endpoint": "/%{sensor_id}/value", "type": "double", "explicit_timestamp": true}
+		]
+	}`))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// Missing fields are set to their defaults
+	fmt.Println(iface.Name, iface.MajorVersion, iface.Aggregation, iface.Mappings[0].Reliability)
+
+	// Values are validated against the mapping matching the path
+	fmt.Println(interfaces.ValidateIndividualMessage(iface, "/mysensor/value", 21.5))
+	fmt.Println(interfaces.ValidateIndividualMessage(iface, "/mysensor/value", "not a double") != nil)
+	// Output:
+	// org.astarte-platform.genericsensors.Values 1 individual unreliable
+	// <nil>
+	// true
+}
+
+func ExampleExtractParameters() {
+	mapping := interfaces.AstarteInterfaceMapping{Endpoint: "/%{room}/%{sensor_id}/value", Type: interfaces.Double}
+
+	parameters, err := interfaces.ExtractParameters(mapping, "/kitchen/thermometer/value")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(parameters["room"], parameters["sensor_id"])
+
+	parameters["room"] = "bedroom"
+	path, err := interfaces.SubstituteParameters(mapping, parameters)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(path)
+	// Output:
+	// kitchen thermometer
+	// /bedroom/thermometer/value
+}
@@ -0,0 +1,38 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interfaces
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+)
+
+// Fingerprint returns a stable SHA-256 hash of an interface, as a hex string, computed over its canonical JSON
+// representation. Defaults are set and mappings are sorted by endpoint before hashing, so e.g. an interface parsed
+// from a local file and the same interface retrieved from a realm have the same fingerprint even if some defaults
+// are implicit or mappings are listed in a different order. Unknown fields (see AstarteInterface.UnknownFields) are
+// not part of the fingerprint.
+func Fingerprint(astarteInterface AstarteInterface) string {
+	canonical := EnsureInterfaceDefaults(withoutUnknownFields(astarteInterface))
+	sort.SliceStable(canonical.Mappings, func(i, j int) bool {
+		return canonical.Mappings[i].Endpoint < canonical.Mappings[j].Endpoint
+	})
+	// Marshaling can't fail, since interfaces hold no arbitrary values once unknown fields are removed
+	b, _ := json.Marshal(canonical)
+	hash := sha256.Sum256(b)
+	return hex.EncodeToString(hash[:])
+}
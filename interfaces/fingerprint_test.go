@@ -0,0 +1,66 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interfaces
+
+import (
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	local, err := ParseInterface([]byte(`{
+		"interface_name": "org.astarte-platform.genericsensors.Values",
+		"version_major": 1,
+		"version_minor": 0,
+		"type": "datastream",
+		"ownership": "device",
+		"mappings": [
+			{"endpoint": "/%{sensor_id}/value", "type": "double"},
+			{"endpoint": "/%{sensor_id}/name", "type": "string"}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	installed, err := ParseInterface([]byte(`{
+		"interface_name": "org.astarte-platform.genericsensors.Values",
+		"version_major": 1,
+		"version_minor": 0,
+		"type": "datastream",
+		"ownership": "device",
+		"aggregation": "individual",
+		"mappings": [
+			{"endpoint": "/%{sensor_id}/name", "type": "string", "reliability": "unreliable"},
+			{"endpoint": "/%{sensor_id}/value", "type": "double", "retention": "discard"}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fingerprint := Fingerprint(local)
+	if len(fingerprint) != 64 {
+		t.Errorf("Expected a hex SHA-256 hash, found %s", fingerprint)
+	}
+	if Fingerprint(installed) != fingerprint {
+		t.Error("Equivalent interfaces should have the same fingerprint")
+	}
+	if local.Mappings[0].Endpoint != "/%{sensor_id}/value" {
+		t.Error("Fingerprint should not modify the interface")
+	}
+	installed.MinorVersion = 1
+	if Fingerprint(installed) == fingerprint {
+		t.Error("Different interfaces should have different fingerprints")
+	}
+}
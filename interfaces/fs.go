@@ -0,0 +1,59 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interfaces
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+)
+
+// ParseInterfaceFromFS works like ParseInterfaceFrom, but reads the interface named name in fsys, e.g. an embed.FS
+// holding the interfaces of an application.
+func ParseInterfaceFromFS(fsys fs.FS, name string) (AstarteInterface, error) {
+	b, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return AstarteInterface{}, err
+	}
+	return ParseInterface(b)
+}
+
+// ParseInterfacesFromFS parses all the interfaces in the JSON files found walking the directory root of fsys, in
+// lexical order. Other files are ignored. The interfaces which can be parsed are returned even if some can't,
+// along with the errors for the latter, joined.
+func ParseInterfacesFromFS(fsys fs.FS, root string) ([]AstarteInterface, error) {
+	astarteInterfaces := []AstarteInterface{}
+	errs := []error{}
+	err := fs.WalkDir(fsys, root, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || path.Ext(filePath) != ".json" {
+			return nil
+		}
+		astarteInterface, err := ParseInterfaceFromFS(fsys, filePath)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", filePath, err))
+			return nil
+		}
+		astarteInterfaces = append(astarteInterfaces, astarteInterface)
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return astarteInterfaces, errors.Join(errs...)
+}
@@ -0,0 +1,55 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interfaces
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseInterfacesFromFS(t *testing.T) {
+	valid := `{"interface_name": "org.astarte-platform.%s", "version_major": 1, "version_minor": 0, "type": "properties",
+		"ownership": "server", "mappings": [{"endpoint": "/value", "type": "integer"}]}`
+	fsys := fstest.MapFS{
+		"interfaces/b.json":        {Data: []byte(fmt.Sprintf(valid, "B"))},
+		"interfaces/nested/a.json": {Data: []byte(fmt.Sprintf(valid, "A"))},
+		"interfaces/invalid.json":  {Data: []byte(`{"interface_name": "org.astarte-platform.Invalid"}`)},
+		"interfaces/README.md":     {Data: []byte("Not an interface")},
+		"other/org.astarte.C.json": {Data: []byte(fmt.Sprintf(valid, "C"))},
+	}
+
+	astarteInterface, err := ParseInterfaceFromFS(fsys, "interfaces/b.json")
+	if err != nil || astarteInterface.Name != "org.astarte-platform.B" || astarteInterface.Aggregation != IndividualAggregation {
+		t.Errorf("Unexpected interface %+v, %v", astarteInterface, err)
+	}
+	if _, err := ParseInterfaceFromFS(fsys, "interfaces/missing.json"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected fs.ErrNotExist, got %v", err)
+	}
+
+	astarteInterfaces, err := ParseInterfacesFromFS(fsys, "interfaces")
+	if err == nil || !strings.Contains(err.Error(), "interfaces/invalid.json") {
+		t.Errorf("Expected an error for the invalid interface, got %v", err)
+	}
+	if len(astarteInterfaces) != 2 || astarteInterfaces[0].Name != "org.astarte-platform.B" || astarteInterfaces[1].Name != "org.astarte-platform.A" {
+		t.Errorf("Unexpected interfaces %+v", astarteInterfaces)
+	}
+	if _, err := ParseInterfacesFromFS(fsys, "missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected fs.ErrNotExist, got %v", err)
+	}
+}
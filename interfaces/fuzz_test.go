@@ -0,0 +1,44 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interfaces
+
+import (
+	"testing"
+)
+
+func FuzzParseInterface(f *testing.F) {
+	f.Add([]byte(`{"interface_name": "org.astarte-platform.genericsensors.Values", "version_major": 0, "version_minor": 1,
+		"type": "datastream", "ownership": "device", "aggregation": "object",
+		"mappings": [{"endpoint": "/%{sensor_id}/value", "type": "double", "explicit_timestamp": true}]}`))
+	f.Add([]byte(`{"interface_name": "a.b", "version_major": 1, "version_minor": 0, "type": "properties",
+		"ownership": "server", "mappings": [{"endpoint": "/a", "type": "integerarray", "allow_unset": true}]}`))
+	f.Add([]byte(`{"interface_name": "a.b", "version_major": 1, "version_minor": 0, "type": "properties",
+		"ownership": "server", "mappings": [null]}`))
+	f.Add([]byte(`[]`))
+	f.Add([]byte(`null`))
+
+	f.Fuzz(func(t *testing.T, interfaceContent []byte) {
+		astarteInterface, err := ParseInterface(interfaceContent)
+		if err != nil {
+			return
+		}
+		// Whatever was parsed must be usable without panicking
+		_ = Fingerprint(astarteInterface)
+		compiled := Compile(astarteInterface)
+		for _, m := range astarteInterface.Mappings {
+			_, _ = compiled.MappingFromPath(m.Endpoint)
+		}
+	})
+}
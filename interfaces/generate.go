@@ -0,0 +1,143 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interfaces
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+const (
+	// maxGeneratedLength is the maximum length of generated strings, binary blobs and arrays.
+	maxGeneratedLength = 16
+	generatedRunes     = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 "
+)
+
+var (
+	// Generated datetimes fall between minGeneratedTime and maxGeneratedTime.
+	minGeneratedTime = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	maxGeneratedTime = time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// GenerateValue returns a random value valid for mapping, drawn from r, as the Go type SendData expects for its
+// type: float64 for "double", int for "integer", int64 for "longinteger", bool for "boolean", string for "string",
+// []byte for "binaryblob", time.Time for "datetime", and slices of them for arrays. Doubles are always finite,
+// datetimes are in UTC with millisecond precision, and strings are printable ASCII. Arrays are never empty.
+// Using the same seed for r yields the same values, which makes tests using them reproducible.
+func GenerateValue(mapping AstarteInterfaceMapping, r *rand.Rand) (any, error) {
+	switch mapping.Type {
+	case Double:
+		return generateDouble(r), nil
+	case Integer:
+		return generateInteger(r), nil
+	case LongInteger:
+		return generateLongInteger(r), nil
+	case Boolean:
+		return generateBoolean(r), nil
+	case String:
+		return generateString(r), nil
+	case BinaryBlob:
+		return generateBinaryBlob(r), nil
+	case DateTime:
+		return generateDateTime(r), nil
+	case DoubleArray:
+		return generateArray(r, generateDouble), nil
+	case IntegerArray:
+		return generateArray(r, generateInteger), nil
+	case LongIntegerArray:
+		return generateArray(r, generateLongInteger), nil
+	case BooleanArray:
+		return generateArray(r, generateBoolean), nil
+	case StringArray:
+		return generateArray(r, generateString), nil
+	case BinaryBlobArray:
+		return generateArray(r, generateBinaryBlob), nil
+	case DateTimeArray:
+		return generateArray(r, generateDateTime), nil
+	}
+	return nil, fmt.Errorf("Cannot generate values for endpoint %s of unknown type %s", mapping.Endpoint, mapping.Type)
+}
+
+// GenerateAggregate returns a random object valid for interfacePath of an interface with object aggregation, drawn
+// from r, as a map of the last level of each endpoint to its value. interfacePath must match the common prefix of
+// the endpoints, with concrete values for any parameter, e.g. "/sensor1" for endpoints such as
+// "/%{sensor_id}/value". Values are generated as GenerateValue does.
+func GenerateAggregate(astarteInterface AstarteInterface, interfacePath string, r *rand.Rand) (map[string]any, error) {
+	if astarteInterface.Aggregation != ObjectAggregation {
+		return nil, fmt.Errorf("Interface %s is not aggregated", astarteInterface.Name)
+	}
+	ret := map[string]any{}
+	for _, mapping := range astarteInterface.Mappings {
+		lastSlash := strings.LastIndex(mapping.Endpoint, "/")
+		prefix := AstarteInterfaceMapping{Endpoint: mapping.Endpoint[:lastSlash]}
+		if _, err := ExtractParameters(prefix, interfacePath); err != nil {
+			continue
+		}
+		value, err := GenerateValue(mapping, r)
+		if err != nil {
+			return nil, err
+		}
+		ret[mapping.Endpoint[lastSlash+1:]] = value
+	}
+	if len(ret) == 0 {
+		return nil, fmt.Errorf("Path %s does not match any endpoint of interface %s", interfacePath, astarteInterface.Name)
+	}
+	return ret, nil
+}
+
+func generateDouble(r *rand.Rand) float64 {
+	return (r.Float64()*2 - 1) * 1e6
+}
+
+func generateInteger(r *rand.Rand) int {
+	return int(int32(r.Uint32()))
+}
+
+func generateLongInteger(r *rand.Rand) int64 {
+	return int64(r.Uint64())
+}
+
+func generateBoolean(r *rand.Rand) bool {
+	return r.Intn(2) == 1
+}
+
+func generateString(r *rand.Rand) string {
+	b := make([]byte, r.Intn(maxGeneratedLength+1))
+	for i := range b {
+		b[i] = generatedRunes[r.Intn(len(generatedRunes))]
+	}
+	return string(b)
+}
+
+func generateBinaryBlob(r *rand.Rand) []byte {
+	b := make([]byte, r.Intn(maxGeneratedLength+1))
+	_, _ = r.Read(b)
+	return b
+}
+
+func generateDateTime(r *rand.Rand) time.Time {
+	span := maxGeneratedTime.UnixMilli() - minGeneratedTime.UnixMilli()
+	return time.UnixMilli(minGeneratedTime.UnixMilli() + r.Int63n(span)).UTC()
+}
+
+func generateArray[T any](r *rand.Rand, generate func(*rand.Rand) T) []T {
+	ret := make([]T, 1+r.Intn(maxGeneratedLength))
+	for i := range ret {
+		ret[i] = generate(r)
+	}
+	return ret
+}
@@ -0,0 +1,84 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interfaces
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestGenerateValue(t *testing.T) {
+	types := []AstarteMappingType{Double, Integer, LongInteger, Boolean, String, BinaryBlob, DateTime, DoubleArray,
+		IntegerArray, LongIntegerArray, BooleanArray, StringArray, BinaryBlobArray, DateTimeArray}
+	mappings := make([]AstarteInterfaceMapping, len(types))
+	for i, mappingType := range types {
+		mappings[i] = AstarteInterfaceMapping{Endpoint: "/%{sensor_id}/" + string(mappingType), Type: mappingType}
+	}
+	iface := AstarteInterface{Name: "org.astarte-platform.genericsensors.Values", Type: DatastreamType, Ownership: ServerOwnership,
+		Aggregation: IndividualAggregation, Mappings: mappings}
+
+	r := rand.New(rand.NewSource(42))
+	for i := 0; i < 100; i++ {
+		for _, mapping := range mappings {
+			value, err := GenerateValue(mapping, r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := ValidateIndividualMessage(iface, "/s1/"+string(mapping.Type), value); err != nil {
+				t.Errorf("Generated invalid value %v for %s: %v", value, mapping.Type, err)
+			}
+		}
+	}
+
+	// The same seed yields the same values
+	first, _ := GenerateValue(mappings[0], rand.New(rand.NewSource(1)))
+	second, _ := GenerateValue(mappings[0], rand.New(rand.NewSource(1)))
+	if first != second {
+		t.Errorf("Expected the same value with the same seed, got %v and %v", first, second)
+	}
+
+	if _, err := GenerateValue(AstarteInterfaceMapping{Endpoint: "/a", Type: "complex"}, r); err == nil {
+		t.Error("Expected an error generating a value of an unknown type")
+	}
+}
+
+func TestGenerateAggregate(t *testing.T) {
+	iface := AstarteInterface{Name: "org.astarte-platform.genericsensors.Values", Type: DatastreamType, Ownership: ServerOwnership,
+		Aggregation: ObjectAggregation, Mappings: []AstarteInterfaceMapping{
+			{Endpoint: "/%{sensor_id}/value", Type: Double},
+			{Endpoint: "/%{sensor_id}/samples", Type: IntegerArray},
+			{Endpoint: "/%{sensor_id}/updated", Type: DateTime},
+		}}
+	r := rand.New(rand.NewSource(42))
+
+	values, err := GenerateAggregate(iface, "/s1", r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 3 {
+		t.Errorf("Unexpected values %v", values)
+	}
+	if err := ValidateAggregateMessage(iface, "/s1", values); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := GenerateAggregate(iface, "/s1/value", r); err == nil {
+		t.Error("Expected an error for a path matching no endpoint")
+	}
+	iface.Aggregation = IndividualAggregation
+	if _, err := GenerateAggregate(iface, "/s1", r); err == nil {
+		t.Error("Expected an error for an individual interface")
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interfaces
+
+import (
+	"path"
+	"strings"
+)
+
+// CanServerWrite returns whether data can be written from the server side on interfacePath, i.e. whether
+// astarteInterface is server-owned and interfacePath can be resolved on it. For interfaces with object
+// aggregation, interfacePath is the path of the whole object, without its last token.
+// Server-owned properties are set (and unset), while server-owned datastreams are published to the device.
+func CanServerWrite(astarteInterface AstarteInterface, interfacePath string) bool {
+	return astarteInterface.Ownership == ServerOwnership && isWritablePath(astarteInterface, interfacePath)
+}
+
+// CanDeviceWrite returns whether data can be written from the device side on interfacePath, i.e. whether
+// astarteInterface is device-owned and interfacePath can be resolved on it. For interfaces with object
+// aggregation, interfacePath is the path of the whole object, without its last token.
+// Data on device-owned interfaces can only be read from the server side.
+func CanDeviceWrite(astarteInterface AstarteInterface, interfacePath string) bool {
+	return astarteInterface.Ownership == DeviceOwnership && isWritablePath(astarteInterface, interfacePath)
+}
+
+func isWritablePath(astarteInterface AstarteInterface, interfacePath string) bool {
+	if astarteInterface.Aggregation != ObjectAggregation {
+		return ValidateInterfacePath(astarteInterface, interfacePath) == nil
+	}
+	for _, m := range astarteInterface.Mappings {
+		tokens := strings.Split(m.Endpoint, "/")
+		if ValidateInterfacePath(astarteInterface, path.Join(interfacePath, tokens[len(tokens)-1])) == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interfaces
+
+import "testing"
+
+func TestOwnershipWriteHelpers(t *testing.T) {
+	individual := AstarteInterface{
+		Ownership:   ServerOwnership,
+		Type:        DatastreamType,
+		Aggregation: IndividualAggregation,
+		Mappings:    []AstarteInterfaceMapping{{Endpoint: "/%{sensor_id}/value", Type: Double}},
+	}
+	object := AstarteInterface{
+		Ownership:   DeviceOwnership,
+		Type:        DatastreamType,
+		Aggregation: ObjectAggregation,
+		Mappings:    []AstarteInterfaceMapping{{Endpoint: "/%{sensor_id}/value", Type: Double}, {Endpoint: "/%{sensor_id}/unit", Type: String}},
+	}
+
+	testCases := []struct {
+		name           string
+		iface          AstarteInterface
+		path           string
+		canServerWrite bool
+		canDeviceWrite bool
+	}{
+		{"server-owned individual", individual, "/s1/value", true, false},
+		{"server-owned individual, wrong path", individual, "/s1/other", false, false},
+		{"device-owned object", object, "/s1", false, true},
+		{"device-owned object, mapping path", object, "/s1/value", false, false},
+	}
+	for _, tc := range testCases {
+		if got := CanServerWrite(tc.iface, tc.path); got != tc.canServerWrite {
+			t.Errorf("%s: CanServerWrite returned %v", tc.name, got)
+		}
+		if got := CanDeviceWrite(tc.iface, tc.path); got != tc.canDeviceWrite {
+			t.Errorf("%s: CanDeviceWrite returned %v", tc.name, got)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interfaces
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// MinDatabaseRetentionTTL is the minimum database_retention_ttl accepted by Astarte, in seconds.
+	MinDatabaseRetentionTTL = 60
+	// MaxDatabaseRetentionTTL is the maximum database_retention_ttl accepted by Astarte, in seconds (20 years).
+	MaxDatabaseRetentionTTL = 631540000
+)
+
+// RetentionLimits are the bounds for the retention fields of interface mappings. A zero value means no bound.
+type RetentionLimits struct {
+	// MaxExpiry is the maximum expiry of samples retained on devices, in seconds.
+	MaxExpiry int
+	// MaxDatabaseRetentionTTL is the maximum database_retention_ttl, in seconds. A realm might set a bound lower
+	// than MaxDatabaseRetentionTTL, see client.GetInterfaceRetentionLimits.
+	MaxDatabaseRetentionTTL int
+}
+
+// DefaultRetentionLimits returns the RetentionLimits enforced by Astarte regardless of the realm.
+func DefaultRetentionLimits() RetentionLimits {
+	return RetentionLimits{MaxDatabaseRetentionTTL: MaxDatabaseRetentionTTL}
+}
+
+// ValidateRetention checks that the retention fields of all mappings of astarteInterface are consistent
+// and within limits: expiry can be set only with volatile or stored retention, database_retention_ttl must be
+// set if and only if database_retention_policy is use_ttl. The returned error joins the errors for each
+// offending mapping, naming its endpoint.
+func ValidateRetention(astarteInterface AstarteInterface, limits RetentionLimits) error {
+	errs := []error{}
+	for _, mapping := range astarteInterface.Mappings {
+		if err := validateMappingRetention(mapping, limits); err != nil {
+			errs = append(errs, fmt.Errorf("Invalid retention for endpoint %s of interface %s: %w", mapping.Endpoint, astarteInterface.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// nolint:gocognit
+func validateMappingRetention(mapping AstarteInterfaceMapping, limits RetentionLimits) error {
+	switch {
+	case mapping.Expiry < 0:
+		return fmt.Errorf("expiry must not be negative, found %d", mapping.Expiry)
+	case mapping.Expiry > 0 && mapping.Retention != VolatileRetention && mapping.Retention != StoredRetention:
+		return fmt.Errorf("expiry can be set only with volatile or stored retention, found %s retention", mapping.Retention)
+	case limits.MaxExpiry > 0 && mapping.Expiry > limits.MaxExpiry:
+		return fmt.Errorf("expiry must be at most %d, found %d", limits.MaxExpiry, mapping.Expiry)
+	}
+
+	if mapping.DatabaseRetentionPolicy != UseTTL {
+		if mapping.DatabaseRetentionTTL != 0 {
+			return fmt.Errorf("database_retention_ttl can be set only with use_ttl database retention policy, found %s", mapping.DatabaseRetentionPolicy)
+		}
+		return nil
+	}
+	switch {
+	case mapping.DatabaseRetentionTTL == 0:
+		return errors.New("database_retention_ttl must be set with use_ttl database retention policy")
+	case mapping.DatabaseRetentionTTL < MinDatabaseRetentionTTL:
+		return fmt.Errorf("database_retention_ttl must be at least %d, found %d", MinDatabaseRetentionTTL, mapping.DatabaseRetentionTTL)
+	case limits.MaxDatabaseRetentionTTL > 0 && mapping.DatabaseRetentionTTL > limits.MaxDatabaseRetentionTTL:
+		return fmt.Errorf("database_retention_ttl must be at most %d, found %d", limits.MaxDatabaseRetentionTTL, mapping.DatabaseRetentionTTL)
+	}
+	return nil
+}
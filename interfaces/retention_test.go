@@ -0,0 +1,53 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interfaces
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateRetention(t *testing.T) {
+	testCases := []struct {
+		mapping AstarteInterfaceMapping
+		limits  RetentionLimits
+		err     string
+	}{
+		{mapping: AstarteInterfaceMapping{Retention: StoredRetention, Expiry: 3600, DatabaseRetentionPolicy: UseTTL, DatabaseRetentionTTL: 86400}},
+		{mapping: AstarteInterfaceMapping{Retention: DiscardRetention, DatabaseRetentionPolicy: NoTTL}},
+		{mapping: AstarteInterfaceMapping{Retention: DiscardRetention, Expiry: 60}, err: "expiry can be set only"},
+		{mapping: AstarteInterfaceMapping{Retention: VolatileRetention, Expiry: -1}, err: "must not be negative"},
+		{mapping: AstarteInterfaceMapping{Retention: VolatileRetention, Expiry: 120}, limits: RetentionLimits{MaxExpiry: 60}, err: "at most 60"},
+		{mapping: AstarteInterfaceMapping{DatabaseRetentionPolicy: NoTTL, DatabaseRetentionTTL: 86400}, err: "database_retention_ttl can be set only"},
+		{mapping: AstarteInterfaceMapping{DatabaseRetentionPolicy: UseTTL}, err: "must be set"},
+		{mapping: AstarteInterfaceMapping{DatabaseRetentionPolicy: UseTTL, DatabaseRetentionTTL: 10}, err: "at least 60"},
+		{mapping: AstarteInterfaceMapping{DatabaseRetentionPolicy: UseTTL, DatabaseRetentionTTL: MaxDatabaseRetentionTTL + 1}, limits: DefaultRetentionLimits(), err: "at most"},
+		{mapping: AstarteInterfaceMapping{DatabaseRetentionPolicy: UseTTL, DatabaseRetentionTTL: 7200}, limits: RetentionLimits{MaxDatabaseRetentionTTL: 3600}, err: "at most 3600"},
+	}
+
+	for _, tc := range testCases {
+		tc.mapping.Endpoint = "/value"
+		iface := AstarteInterface{Name: "org.astarte.Test", Mappings: []AstarteInterfaceMapping{tc.mapping}}
+		err := ValidateRetention(iface, tc.limits)
+		switch {
+		case tc.err == "" && err != nil:
+			t.Errorf("Unexpected error for %+v: %v", tc.mapping, err)
+		case tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)):
+			t.Errorf("Expected an error containing %q for %+v, found %v", tc.err, tc.mapping, err)
+		case err != nil && !strings.Contains(err.Error(), "/value"):
+			t.Errorf("Error does not name the endpoint: %v", err)
+		}
+	}
+}
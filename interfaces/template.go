@@ -0,0 +1,188 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interfaces
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrTemplateCycle     = errors.New("Invalid interface templates: inheritance cycle")
+	ErrUnknownTemplate   = errors.New("Invalid interface templates: unknown template")
+	ErrTemplateCollision = errors.New("Invalid interface templates: collision")
+)
+
+// InterfaceTemplate is a base interface definition which expands into one concrete interface for each of its
+// instances, see ExpandTemplates.
+type InterfaceTemplate struct {
+	// Name identifies the template, so that other templates can extend it.
+	Name string
+	// Extends is the name of the template this one inherits from, if any. Interface is merged over the
+	// interface of the parent template: fields which are set override the ones of the parent, and mappings
+	// replace the ones of the parent with the same endpoint, or are added to them.
+	Extends string
+	// Interface is the base definition. Its name is the prefix of the names of the concrete interfaces.
+	Interface AstarteInterface
+	// Instances are the parameters of the concrete interfaces. Templates with no instances are only
+	// meant to be extended.
+	Instances []TemplateInstance
+}
+
+// TemplateInstance holds the parameters of a concrete interface expanded from an InterfaceTemplate.
+type TemplateInstance struct {
+	// NameSuffix is appended to the name of the template interface.
+	NameSuffix string
+	// EndpointPrefix, if set, is prepended to all endpoints, e.g. "/room1".
+	EndpointPrefix string
+	// Description and Documentation, if set, replace the ones of the template interface.
+	Description   string
+	Documentation string
+}
+
+// ExpandTemplates returns the concrete interfaces defined by templates, in order, with all defaults set.
+// It fails with ErrUnknownTemplate if a template extends one not in templates, with ErrTemplateCycle if
+// templates extend each other in a cycle, and with ErrTemplateCollision if two templates have the same name,
+// if a template defines the same endpoint twice or if two instances expand to interfaces with the same name
+// and major version.
+func ExpandTemplates(templates []InterfaceTemplate) ([]AstarteInterface, error) {
+	byName := map[string]InterfaceTemplate{}
+	for _, template := range templates {
+		if _, ok := byName[template.Name]; ok {
+			return nil, fmt.Errorf("%w: template %s is defined twice", ErrTemplateCollision, template.Name)
+		}
+		byName[template.Name] = template
+	}
+
+	resolved := map[string]AstarteInterface{}
+	ret := []AstarteInterface{}
+	expanded := map[string]string{}
+	for _, template := range templates {
+		base, err := resolveTemplate(template.Name, byName, resolved, map[string]bool{})
+		if err != nil {
+			return nil, err
+		}
+		for _, instance := range template.Instances {
+			astarteInterface := instantiateTemplate(base, instance)
+			key := fmt.Sprintf("%s v%d", astarteInterface.Name, astarteInterface.MajorVersion)
+			if other, ok := expanded[key]; ok {
+				return nil, fmt.Errorf("%w: templates %s and %s both expand to interface %s", ErrTemplateCollision, other, template.Name, key)
+			}
+			expanded[key] = template.Name
+			ret = append(ret, astarteInterface)
+		}
+	}
+	return ret, nil
+}
+
+// resolveTemplate returns the interface of template name merged over the ones of its ancestors. visiting
+// holds the templates whose resolution is in progress, to detect cycles.
+func resolveTemplate(name string, templates map[string]InterfaceTemplate, resolved map[string]AstarteInterface, visiting map[string]bool) (AstarteInterface, error) {
+	if astarteInterface, ok := resolved[name]; ok {
+		return astarteInterface, nil
+	}
+	template, ok := templates[name]
+	if !ok {
+		return AstarteInterface{}, fmt.Errorf("%w: %s", ErrUnknownTemplate, name)
+	}
+	if visiting[name] {
+		return AstarteInterface{}, fmt.Errorf("%w: template %s extends itself", ErrTemplateCycle, name)
+	}
+	visiting[name] = true
+
+	endpoints := map[string]bool{}
+	for _, mapping := range template.Interface.Mappings {
+		if endpoints[mapping.Endpoint] {
+			return AstarteInterface{}, fmt.Errorf("%w: template %s defines endpoint %s twice", ErrTemplateCollision, name, mapping.Endpoint)
+		}
+		endpoints[mapping.Endpoint] = true
+	}
+
+	astarteInterface := template.Interface
+	if template.Extends != "" {
+		parent, err := resolveTemplate(template.Extends, templates, resolved, visiting)
+		if err != nil {
+			return AstarteInterface{}, err
+		}
+		astarteInterface = mergeTemplateInterface(parent, template.Interface)
+	}
+	resolved[name] = astarteInterface
+	return astarteInterface, nil
+}
+
+// nolint:gocognit
+func mergeTemplateInterface(parent, child AstarteInterface) AstarteInterface {
+	merged := parent
+	if child.Name != "" {
+		merged.Name = child.Name
+	}
+	if child.MajorVersion != 0 || child.MinorVersion != 0 {
+		merged.MajorVersion, merged.MinorVersion = child.MajorVersion, child.MinorVersion
+	}
+	if child.Type != "" {
+		merged.Type = child.Type
+	}
+	if child.Ownership != "" {
+		merged.Ownership = child.Ownership
+	}
+	if child.Aggregation != "" {
+		merged.Aggregation = child.Aggregation
+	}
+	merged.ExplicitTimestamp = merged.ExplicitTimestamp || child.ExplicitTimestamp
+	merged.HasMetadata = merged.HasMetadata || child.HasMetadata
+	if child.Description != "" {
+		merged.Description = child.Description
+	}
+	if child.Documentation != "" {
+		merged.Documentation = child.Documentation
+	}
+
+	merged.Mappings = []AstarteInterfaceMapping{}
+	overridden := map[string]bool{}
+	for _, mapping := range parent.Mappings {
+		for _, childMapping := range child.Mappings {
+			if childMapping.Endpoint == mapping.Endpoint {
+				mapping = childMapping
+				overridden[mapping.Endpoint] = true
+			}
+		}
+		merged.Mappings = append(merged.Mappings, mapping)
+	}
+	for _, mapping := range child.Mappings {
+		if !overridden[mapping.Endpoint] {
+			merged.Mappings = append(merged.Mappings, mapping)
+		}
+	}
+	return merged
+}
+
+func instantiateTemplate(base AstarteInterface, instance TemplateInstance) AstarteInterface {
+	astarteInterface := base
+	astarteInterface.Name += instance.NameSuffix
+	if instance.Description != "" {
+		astarteInterface.Description = instance.Description
+	}
+	if instance.Documentation != "" {
+		astarteInterface.Documentation = instance.Documentation
+	}
+	prefix := strings.TrimSuffix(instance.EndpointPrefix, "/")
+	astarteInterface.Mappings = make([]AstarteInterfaceMapping, len(base.Mappings))
+	for i, mapping := range base.Mappings {
+		mapping.Endpoint = prefix + mapping.Endpoint
+		astarteInterface.Mappings[i] = mapping
+	}
+	return EnsureInterfaceDefaults(astarteInterface)
+}
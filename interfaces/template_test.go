@@ -0,0 +1,131 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interfaces
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExpandTemplates(t *testing.T) {
+	templates := []InterfaceTemplate{
+		{
+			Name: "sensor",
+			Interface: AstarteInterface{
+				Name:         "org.astarte-platform.sensors.",
+				MajorVersion: 1,
+				Type:         DatastreamType,
+				Ownership:    DeviceOwnership,
+				Description:  "A sensor",
+				Mappings: []AstarteInterfaceMapping{
+					{Endpoint: "/value", Type: Double},
+					{Endpoint: "/unit", Type: String},
+				},
+			},
+		},
+		{
+			Name:    "thermometer",
+			Extends: "sensor",
+			Interface: AstarteInterface{
+				Mappings: []AstarteInterfaceMapping{
+					{Endpoint: "/value", Type: Double, Reliability: GuaranteedReliability},
+					{Endpoint: "/calibrated", Type: Boolean},
+				},
+			},
+			Instances: []TemplateInstance{
+				{NameSuffix: "Kitchen", EndpointPrefix: "/kitchen/"},
+				{NameSuffix: "Garage", Description: "The garage thermometer"},
+			},
+		},
+	}
+	ifaces, err := ExpandTemplates(templates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ifaces) != 2 {
+		t.Fatalf("Unexpected interfaces %v", ifaces)
+	}
+
+	kitchen := ifaces[0]
+	if kitchen.Name != "org.astarte-platform.sensors.Kitchen" || kitchen.Description != "A sensor" || kitchen.Aggregation != IndividualAggregation {
+		t.Errorf("Unexpected interface %v", kitchen)
+	}
+	expected := []AstarteInterfaceMapping{
+		{Endpoint: "/kitchen/value", Type: Double, Reliability: GuaranteedReliability},
+		{Endpoint: "/kitchen/unit", Type: String, Reliability: UnreliableReliability},
+		{Endpoint: "/kitchen/calibrated", Type: Boolean, Reliability: UnreliableReliability},
+	}
+	for i, mapping := range kitchen.Mappings {
+		if mapping.Endpoint != expected[i].Endpoint || mapping.Type != expected[i].Type || mapping.Reliability != expected[i].Reliability {
+			t.Errorf("Unexpected mapping %v, expected %v", mapping, expected[i])
+		}
+	}
+
+	garage := ifaces[1]
+	if garage.Name != "org.astarte-platform.sensors.Garage" || garage.Description != "The garage thermometer" || garage.Mappings[0].Endpoint != "/value" {
+		t.Errorf("Unexpected interface %v", garage)
+	}
+	if templates[1].Interface.Mappings[0].Endpoint != "/value" {
+		t.Error("Expanding templates must not modify them")
+	}
+}
+
+func TestExpandTemplatesErrors(t *testing.T) {
+	base := AstarteInterface{Name: "org.astarte-platform.Base", MajorVersion: 1, Mappings: []AstarteInterfaceMapping{{Endpoint: "/value", Type: Double}}}
+	cases := map[string]struct {
+		templates []InterfaceTemplate
+		expected  error
+	}{
+		"unknown parent": {
+			templates: []InterfaceTemplate{{Name: "a", Extends: "b", Interface: base}},
+			expected:  ErrUnknownTemplate,
+		},
+		"cycle": {
+			templates: []InterfaceTemplate{
+				{Name: "a", Extends: "c", Interface: base},
+				{Name: "b", Extends: "a"},
+				{Name: "c", Extends: "b"},
+			},
+			expected: ErrTemplateCycle,
+		},
+		"self cycle": {
+			templates: []InterfaceTemplate{{Name: "a", Extends: "a", Interface: base}},
+			expected:  ErrTemplateCycle,
+		},
+		"duplicate template": {
+			templates: []InterfaceTemplate{{Name: "a", Interface: base}, {Name: "a", Interface: base}},
+			expected:  ErrTemplateCollision,
+		},
+		"duplicate endpoint": {
+			templates: []InterfaceTemplate{{
+				Name:      "a",
+				Interface: AstarteInterface{Mappings: []AstarteInterfaceMapping{{Endpoint: "/v"}, {Endpoint: "/v"}}},
+			}},
+			expected: ErrTemplateCollision,
+		},
+		"duplicate interface": {
+			templates: []InterfaceTemplate{
+				{Name: "a", Interface: base, Instances: []TemplateInstance{{NameSuffix: "One"}}},
+				{Name: "b", Extends: "a", Instances: []TemplateInstance{{NameSuffix: "One", EndpointPrefix: "/other"}}},
+			},
+			expected: ErrTemplateCollision,
+		},
+	}
+	for name, c := range cases {
+		if _, err := ExpandTemplates(c.templates); !errors.Is(err, c.expected) {
+			t.Errorf("%s: expected %v, found %v", name, c.expected, err)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interfaces
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ErrUnknownField is returned by ParseInterfaceStrict when an interface contains a field this package does not know.
+var ErrUnknownField = errors.New("Invalid interface: unknown field")
+
+var (
+	knownInterfaceFields = jsonFieldNames(reflect.TypeOf(AstarteInterface{}))
+	knownMappingFields   = jsonFieldNames(reflect.TypeOf(AstarteInterfaceMapping{}))
+)
+
+// jsonFieldNames returns the names of the JSON fields of a struct type, as set in their json tags.
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	ret := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			ret[name] = true
+		}
+	}
+	return ret
+}
+
+// UnmarshalJSON unmarshals an interface, keeping any field this package does not know about so that it is
+// emitted again when the interface is marshaled. This allows tools to handle interfaces defined for newer Astarte
+// versions without losing information.
+func (a *AstarteInterface) UnmarshalJSON(b []byte) error {
+	type astarteInterface AstarteInterface
+	if err := json.Unmarshal(b, (*astarteInterface)(a)); err != nil {
+		return err
+	}
+	unknownFields, err := unmarshalUnknownFields(b, knownInterfaceFields)
+	a.unknownFields = unknownFields
+	return err
+}
+
+// MarshalJSON marshals an interface, including any unknown field it was unmarshaled with.
+func (a AstarteInterface) MarshalJSON() ([]byte, error) {
+	type astarteInterface AstarteInterface
+	b, err := json.Marshal(astarteInterface(a))
+	if err != nil {
+		return nil, err
+	}
+	return marshalUnknownFields(b, a.unknownFields)
+}
+
+// UnknownFields returns the names of the fields of the interface this package does not know about, sorted.
+// Unknown fields of the mappings are not included, see AstarteInterfaceMapping.UnknownFields.
+func (a AstarteInterface) UnknownFields() []string {
+	return sortedKeys(a.unknownFields)
+}
+
+// UnmarshalJSON unmarshals an interface mapping, keeping any field this package does not know about so that it is
+// emitted again when the mapping is marshaled.
+func (m *AstarteInterfaceMapping) UnmarshalJSON(b []byte) error {
+	type astarteInterfaceMapping AstarteInterfaceMapping
+	if err := json.Unmarshal(b, (*astarteInterfaceMapping)(m)); err != nil {
+		return err
+	}
+	unknownFields, err := unmarshalUnknownFields(b, knownMappingFields)
+	m.unknownFields = unknownFields
+	return err
+}
+
+// MarshalJSON marshals an interface mapping, including any unknown field it was unmarshaled with.
+func (m AstarteInterfaceMapping) MarshalJSON() ([]byte, error) {
+	type astarteInterfaceMapping AstarteInterfaceMapping
+	b, err := json.Marshal(astarteInterfaceMapping(m))
+	if err != nil {
+		return nil, err
+	}
+	return marshalUnknownFields(b, m.unknownFields)
+}
+
+// UnknownFields returns the names of the fields of the mapping this package does not know about, sorted.
+func (m AstarteInterfaceMapping) UnknownFields() []string {
+	return sortedKeys(m.unknownFields)
+}
+
+// withoutUnknownFields returns a copy of astarteInterface and its mappings with no unknown fields.
+func withoutUnknownFields(astarteInterface AstarteInterface) AstarteInterface {
+	astarteInterface.unknownFields = nil
+	mappings := make([]AstarteInterfaceMapping, len(astarteInterface.Mappings))
+	for i, m := range astarteInterface.Mappings {
+		m.unknownFields = nil
+		mappings[i] = m
+	}
+	astarteInterface.Mappings = mappings
+	return astarteInterface
+}
+
+func unmarshalUnknownFields(b []byte, knownFields map[string]bool) (map[string]json.RawMessage, error) {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	for name := range fields {
+		if knownFields[name] {
+			delete(fields, name)
+		}
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return fields, nil
+}
+
+// marshalUnknownFields appends unknownFields, sorted by name, to the JSON object b.
+func marshalUnknownFields(b []byte, unknownFields map[string]json.RawMessage) ([]byte, error) {
+	if len(unknownFields) == 0 {
+		return b, nil
+	}
+	ret := bytes.NewBuffer(bytes.TrimSuffix(b, []byte("}")))
+	for _, name := range sortedKeys(unknownFields) {
+		key, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		if ret.Len() > 1 {
+			ret.WriteByte(',')
+		}
+		ret.Write(key)
+		ret.WriteByte(':')
+		ret.Write(unknownFields[name])
+	}
+	ret.WriteByte('}')
+	return ret.Bytes(), nil
+}
+
+func sortedKeys(m map[string]json.RawMessage) []string {
+	ret := make([]string, 0, len(m))
+	for k := range m {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// ParseInterfaceStrict parses an interface as ParseInterface does, but returns ErrUnknownField if the interface
+// or any of its mappings contain a field this package does not know about.
+func ParseInterfaceStrict(interfaceContent []byte) (AstarteInterface, error) {
+	astarteInterface, err := ParseInterface(interfaceContent)
+	if err != nil {
+		return astarteInterface, err
+	}
+	if unknownFields := astarteInterface.UnknownFields(); len(unknownFields) > 0 {
+		return astarteInterface, fmt.Errorf("%w %s", ErrUnknownField, unknownFields[0])
+	}
+	for _, m := range astarteInterface.Mappings {
+		if unknownFields := m.UnknownFields(); len(unknownFields) > 0 {
+			return astarteInterface, fmt.Errorf("%w %s in mapping %s", ErrUnknownField, unknownFields[0], m.Endpoint)
+		}
+	}
+	return astarteInterface, nil
+}
@@ -0,0 +1,189 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interfaces
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// deviceIDLength is the length of a Device ID in the MQTT topic of a sample.
+	deviceIDLength = 22
+	// mqttPublishOverhead is the size of the fixed header of an MQTT PUBLISH packet, the length of its
+	// topic and its packet identifier.
+	mqttPublishOverhead = 2 + 2 + 2
+	// storedRowOverhead is the size of the columns Astarte stores alongside each datastream sample: the device,
+	// interface and endpoint IDs, the reception timestamp and its sub-millisecond part.
+	storedRowOverhead = 16 + 16 + 16 + 8 + 2
+	// storedTimestampSize is the size of the explicit timestamp of a sample, when stored.
+	storedTimestampSize = 8
+	// storedArrayElementOverhead is the size of the length stored with each element of an array.
+	storedArrayElementOverhead = 4
+
+	monthDuration = 30 * 24 * time.Hour
+)
+
+// SampleSize is the estimated size of a sample published by a device, in bytes.
+type SampleSize struct {
+	// Payload is the size of the BSON payload of the sample.
+	Payload int
+	// Wire is the size of the MQTT PUBLISH packet carrying the sample, excluding the realm name
+	// which prefixes its topic.
+	Wire int
+	// Stored is the size of the sample stored by Astarte, excluding the overhead and the compression
+	// of the database.
+	Stored int
+	// RetentionTTL is the database retention TTL of the sample, or 0 if it is stored forever.
+	RetentionTTL time.Duration
+
+	// property is set if the sample overwrites the previous one, rather than being stored alongside it.
+	property bool
+}
+
+// EstimateSampleSize estimates the size of a sample with payload published on interfacePath of astarteInterface,
+// both on the wire and once stored by Astarte. payload must be valid for the mapping, as ValidateIndividualMessage
+// or ValidateAggregateMessage would check: for interfaces with object aggregation, it must be a map of the last level
+// of each endpoint to its value. Estimates are meant to compare the storage cost of interface designs, see
+// SampleSize.MonthlyStorage, and are not exact.
+func EstimateSampleSize(astarteInterface AstarteInterface, interfacePath string, payload any) (SampleSize, error) {
+	if astarteInterface.Aggregation == ObjectAggregation {
+		values, ok := payload.(map[string]any)
+		if !ok {
+			return SampleSize{}, fmt.Errorf("Payload %T for interface %s is not an object", payload, astarteInterface.Name)
+		}
+		return estimateObjectSampleSize(astarteInterface, interfacePath, values)
+	}
+	if err := ValidateIndividualMessage(astarteInterface, interfacePath, payload); err != nil {
+		return SampleSize{}, err
+	}
+	mapping, err := InterfaceMappingFromPath(astarteInterface, interfacePath)
+	if err != nil {
+		return SampleSize{}, err
+	}
+	wireValue, storedValue := valueSize(mapping.Type, payload)
+	return newSampleSize(astarteInterface, interfacePath, mapping, wireValue, storedValue), nil
+}
+
+func estimateObjectSampleSize(astarteInterface AstarteInterface, interfacePath string, values map[string]any) (SampleSize, error) {
+	if len(values) == 0 {
+		return SampleSize{}, fmt.Errorf("Empty object for interface %s", astarteInterface.Name)
+	}
+	if err := ValidateAggregateMessage(astarteInterface, interfacePath, values); err != nil {
+		return SampleSize{}, err
+	}
+	// The object is a BSON document, and each of its values is stored in its own column
+	wireValue, storedValue := 4+1, 0
+	var mapping AstarteInterfaceMapping
+	for k, v := range values {
+		var err error
+		if mapping, err = InterfaceMappingFromPath(astarteInterface, path.Join(interfacePath, k)); err != nil {
+			return SampleSize{}, err
+		}
+		wireElement, storedElement := valueSize(mapping.Type, v)
+		wireValue += bsonElementSize(k, wireElement)
+		storedValue += storedElement
+	}
+	return newSampleSize(astarteInterface, interfacePath, mapping, wireValue, storedValue), nil
+}
+
+func newSampleSize(astarteInterface AstarteInterface, interfacePath string, mapping AstarteInterfaceMapping, wireValue, storedValue int) SampleSize {
+	// The payload is a BSON document holding the value as "v", and the explicit timestamp, if any, as "t"
+	payload := 4 + bsonElementSize("v", wireValue) + 1
+	stored := storedRowOverhead + len(interfacePath) + storedValue
+	if mapping.ExplicitTimestamp {
+		payload += bsonElementSize("t", 8)
+		stored += storedTimestampSize
+	}
+	topic := deviceIDLength + 1 + len(astarteInterface.Name) + len(interfacePath)
+	wire := mqttPublishOverhead + topic + payload
+	if mapping.Reliability == "" || mapping.Reliability == UnreliableReliability {
+		// QoS 0 packets have no packet identifier
+		wire -= 2
+	}
+
+	size := SampleSize{Payload: payload, Wire: wire, Stored: stored, property: astarteInterface.Type == PropertiesType}
+	if !size.property && mapping.DatabaseRetentionPolicy == UseTTL {
+		size.RetentionTTL = time.Duration(mapping.DatabaseRetentionTTL) * time.Second
+	}
+	return size
+}
+
+func bsonElementSize(key string, valueSize int) int {
+	return 1 + len(key) + 1 + valueSize
+}
+
+// valueSize returns the size of value, of mappingType, in BSON and once stored by Astarte.
+func valueSize(mappingType AstarteMappingType, value any) (wire, stored int) {
+	if !strings.HasSuffix(string(mappingType), "array") {
+		return scalarSize(mappingType, value)
+	}
+	elementType := AstarteMappingType(strings.TrimSuffix(string(mappingType), "array"))
+	elements := reflect.ValueOf(value)
+	// Arrays are BSON documents with the indexes of the elements as keys
+	wire = 4 + 1
+	for i := 0; i < elements.Len(); i++ {
+		wireElement, storedElement := scalarSize(elementType, elements.Index(i).Interface())
+		wire += bsonElementSize(strconv.Itoa(i), wireElement)
+		stored += storedArrayElementOverhead + storedElement
+	}
+	return wire, stored
+}
+
+func scalarSize(mappingType AstarteMappingType, value any) (wire, stored int) {
+	switch mappingType {
+	case Integer:
+		return 4, 4
+	case Boolean:
+		return 1, 1
+	case String:
+		s, _ := value.(string)
+		return 4 + len(s) + 1, len(s)
+	case BinaryBlob:
+		b, _ := value.([]byte)
+		return 4 + 1 + len(b), len(b)
+	}
+	// Doubles, long integers and datetimes
+	return 8, 8
+}
+
+// ProjectStorage returns the storage used by a device publishing samples of size s at samplesPerSecond for period,
+// in bytes. Samples older than the retention TTL are discarded, so storage stops growing after it. Properties
+// overwrite their previous value, so their storage does not grow at all.
+func (s SampleSize) ProjectStorage(samplesPerSecond float64, period time.Duration) int64 {
+	if s.property {
+		return int64(s.Stored)
+	}
+	if s.RetentionTTL > 0 && s.RetentionTTL < period {
+		period = s.RetentionTTL
+	}
+	return int64(float64(s.Stored) * samplesPerSecond * period.Seconds())
+}
+
+// MonthlyStorage returns the storage used by a device publishing samples of size s at samplesPerSecond
+// after a 30 days month, in bytes.
+func (s SampleSize) MonthlyStorage(samplesPerSecond float64) int64 {
+	return s.ProjectStorage(samplesPerSecond, monthDuration)
+}
+
+// MonthlyTraffic returns the traffic generated in a 30 days month by a device publishing samples of size s at
+// samplesPerSecond, in bytes.
+func (s SampleSize) MonthlyTraffic(samplesPerSecond float64) int64 {
+	return int64(float64(s.Wire) * samplesPerSecond * monthDuration.Seconds())
+}
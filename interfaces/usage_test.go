@@ -0,0 +1,82 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interfaces
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEstimateSampleSize(t *testing.T) {
+	iface := AstarteInterface{Name: "org.astarte-platform.genericsensors.Values", Type: DatastreamType, Ownership: DeviceOwnership,
+		Aggregation: IndividualAggregation, Mappings: []AstarteInterfaceMapping{
+			{Endpoint: "/%{sensor_id}/value", Type: Double, ExplicitTimestamp: true, Reliability: GuaranteedReliability},
+			{Endpoint: "/%{sensor_id}/name", Type: String, DatabaseRetentionPolicy: UseTTL, DatabaseRetentionTTL: 3600},
+			{Endpoint: "/%{sensor_id}/samples", Type: IntegerArray},
+		}}
+
+	// {"v": double, "t": datetime}
+	size, err := EstimateSampleSize(iface, "/s1/value", 21.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := 4 + (1 + 2 + 8) + (1 + 2 + 8) + 1; size.Payload != expected {
+		t.Errorf("Unexpected payload size %d instead of %d", size.Payload, expected)
+	}
+	if expected := 6 + 22 + 1 + len(iface.Name) + len("/s1/value") + size.Payload; size.Wire != expected {
+		t.Errorf("Unexpected wire size %d instead of %d", size.Wire, expected)
+	}
+	if size.RetentionTTL != 0 || size.MonthlyStorage(1) != int64(size.Stored)*30*24*3600 {
+		t.Errorf("Unexpected monthly storage %d for %+v", size.MonthlyStorage(1), size)
+	}
+
+	short, _ := EstimateSampleSize(iface, "/s1/name", "a")
+	long, _ := EstimateSampleSize(iface, "/s1/name", "a longer name")
+	if long.Payload-short.Payload != 12 || long.Stored-short.Stored != 12 {
+		t.Errorf("Unexpected sizes %+v and %+v", short, long)
+	}
+	// Samples expire after an hour
+	if long.RetentionTTL != time.Hour || long.MonthlyStorage(2) != long.ProjectStorage(2, time.Hour) {
+		t.Errorf("Unexpected storage %d for %+v", long.MonthlyStorage(2), long)
+	}
+
+	array, _ := EstimateSampleSize(iface, "/s1/samples", []int{1, 2, 3})
+	if expected := 4 + 3*(1+1+1+4) + 1; array.Payload-4-(1+2)-1 != expected {
+		t.Errorf("Unexpected payload size %d for an array", array.Payload)
+	}
+
+	if _, err := EstimateSampleSize(iface, "/s1/value", "not a double"); err == nil {
+		t.Error("Expected an error for an invalid payload")
+	}
+
+	object := iface
+	object.Aggregation = ObjectAggregation
+	object.Mappings = object.Mappings[:1]
+	objectSize, err := EstimateSampleSize(object, "/s1", map[string]any{"value": 21.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The object is nested in "v" as a document
+	if objectSize.Payload != size.Payload+4+(1+len("value")+1)+1 {
+		t.Errorf("Unexpected payload size %d for an object", objectSize.Payload)
+	}
+
+	properties := AstarteInterface{Name: "org.astarte-platform.Settings", Type: PropertiesType, Ownership: ServerOwnership,
+		Mappings: []AstarteInterfaceMapping{{Endpoint: "/enabled", Type: Boolean}}}
+	property, _ := EstimateSampleSize(properties, "/enabled", true)
+	if property.MonthlyStorage(10) != int64(property.Stored) {
+		t.Errorf("Unexpected storage %d for a property", property.MonthlyStorage(10))
+	}
+}
@@ -155,6 +155,71 @@ func ValidateInterfacePath(astarteInterface AstarteInterface, interfacePath stri
 	return err
 }
 
+// ExtractParameters returns the values of the parameters of a parametric mapping, given a concrete path matching
+// the mapping's endpoint. As an example, given a mapping with endpoint `/%{sensor_id}/value` and the concrete path
+// `/s1/value`, the returned map will be `{"sensor_id": "s1"}`. An error is returned if the path does not match the
+// mapping's endpoint. Non parametric mappings return an empty map when the path matches the endpoint.
+func ExtractParameters(mapping AstarteInterfaceMapping, concretePath string) (map[string]string, error) {
+	endpointTokens := strings.Split(mapping.Endpoint, "/")
+	pathTokens := strings.Split(concretePath, "/")
+	if len(endpointTokens) != len(pathTokens) {
+		return nil, fmt.Errorf("Path %s does not match endpoint %s", concretePath, mapping.Endpoint)
+	}
+
+	parameters := map[string]string{}
+	for i, token := range endpointTokens {
+		if parameterName, ok := parameterNameFromToken(token); ok {
+			if pathTokens[i] == "" {
+				return nil, fmt.Errorf("Path %s has an empty value for parameter %s", concretePath, parameterName)
+			}
+			parameters[parameterName] = pathTokens[i]
+			continue
+		}
+		if token != pathTokens[i] {
+			return nil, fmt.Errorf("Path %s does not match endpoint %s", concretePath, mapping.Endpoint)
+		}
+	}
+
+	return parameters, nil
+}
+
+// SubstituteParameters is the inverse of ExtractParameters: it returns the concrete path obtained by replacing
+// each parameter in the mapping's endpoint with its value in parameters. As an example, given a mapping with
+// endpoint `/%{sensor_id}/value` and parameters `{"sensor_id": "s1"}`, the returned path will be `/s1/value`.
+// An error is returned if a parameter is missing, or if its value is empty or contains a slash.
+func SubstituteParameters(mapping AstarteInterfaceMapping, parameters map[string]string) (string, error) {
+	endpointTokens := strings.Split(mapping.Endpoint, "/")
+	pathTokens := make([]string, 0, len(endpointTokens))
+	for _, token := range endpointTokens {
+		parameterName, ok := parameterNameFromToken(token)
+		if !ok {
+			pathTokens = append(pathTokens, token)
+			continue
+		}
+		value, found := parameters[parameterName]
+		switch {
+		case !found:
+			return "", fmt.Errorf("Missing value for parameter %s of endpoint %s", parameterName, mapping.Endpoint)
+		case value == "":
+			return "", fmt.Errorf("Empty value for parameter %s of endpoint %s", parameterName, mapping.Endpoint)
+		case strings.Contains(value, "/"):
+			return "", fmt.Errorf("Value %s for parameter %s cannot contain slashes", value, parameterName)
+		}
+		pathTokens = append(pathTokens, value)
+	}
+
+	return strings.Join(pathTokens, "/"), nil
+}
+
+// parameterNameFromToken returns the name of the parameter if token, a single level of an endpoint,
+// is parametric (e.g. `%{sensor_id}`).
+func parameterNameFromToken(token string) (string, bool) {
+	if !strings.HasPrefix(token, "%{") || !strings.HasSuffix(token, "}") {
+		return "", false
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(token, "%{"), "}"), true
+}
+
 // NormalizePayload returns a normalized payload, ready to be used for calling APIs or, in general, interact with
 // Astarte. encodeBytes controls whether []byte types should be encoded in base64, used for data structures which do not
 // support bytes (e.g.: JSON)
@@ -569,3 +569,62 @@ func TestPayloadNormalization(t *testing.T) {
 		t.Error("Multimap conversion failed", NormalizePayload(inMultiMap, false), outMultiMapNonEncoded)
 	}
 }
+
+func TestExtractParameters(t *testing.T) {
+	mapping := AstarteInterfaceMapping{Endpoint: "/%{sensor_id}/value/%{index}", Type: Double}
+
+	parameters, err := ExtractParameters(mapping, "/s1/value/42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parameters, map[string]string{"sensor_id": "s1", "index": "42"}) {
+		t.Errorf("Unexpected parameters: %v", parameters)
+	}
+
+	for _, p := range []string{"/s1/value", "/s1/other/42", "/s1/value/42/more", "//value/42", "s1/value/42"} {
+		if _, err := ExtractParameters(mapping, p); err == nil {
+			t.Errorf("Path %s should not match endpoint %s", p, mapping.Endpoint)
+		}
+	}
+
+	simpleMapping := AstarteInterfaceMapping{Endpoint: "/a/simple/value", Type: Double}
+	parameters, err = ExtractParameters(simpleMapping, "/a/simple/value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parameters) != 0 {
+		t.Errorf("Unexpected parameters for non parametric mapping: %v", parameters)
+	}
+}
+
+func TestSubstituteParameters(t *testing.T) {
+	mapping := AstarteInterfaceMapping{Endpoint: "/%{sensor_id}/value/%{index}", Type: Double}
+
+	p, err := SubstituteParameters(mapping, map[string]string{"sensor_id": "s1", "index": "42"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != "/s1/value/42" {
+		t.Errorf("Unexpected path: %s", p)
+	}
+
+	// Round trip
+	parameters, err := ExtractParameters(mapping, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parameters["sensor_id"] != "s1" || parameters["index"] != "42" {
+		t.Errorf("Unexpected parameters: %v", parameters)
+	}
+
+	invalidParameters := []map[string]string{
+		{"sensor_id": "s1"},
+		{"sensor_id": "", "index": "42"},
+		{"sensor_id": "s1/s2", "index": "42"},
+	}
+	for _, params := range invalidParameters {
+		if _, err := SubstituteParameters(mapping, params); err == nil {
+			t.Errorf("Parameters %v should not be valid for endpoint %s", params, mapping.Endpoint)
+		}
+	}
+}
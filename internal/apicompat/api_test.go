@@ -0,0 +1,256 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apicompat
+
+import (
+	"bytes"
+	"flag"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+const (
+	moduleRoot = "../.."
+	goldenFile = "testdata/api.golden"
+)
+
+var update = flag.Bool("update", false, "update the golden file with the current exported API")
+
+func TestAPICompatibility(t *testing.T) {
+	current, err := exportedAPI(moduleRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *update {
+		if err := os.WriteFile(goldenFile, []byte(strings.Join(current, "\n")+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	b, err := os.ReadFile(goldenFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden := strings.Split(strings.TrimSpace(string(b)), "\n")
+	currentSet := make(map[string]bool, len(current))
+	for _, declaration := range current {
+		currentSet[declaration] = true
+	}
+	goldenSet := make(map[string]bool, len(golden))
+	for _, declaration := range golden {
+		goldenSet[declaration] = true
+		if !currentSet[declaration] {
+			t.Errorf("Incompatible change, the declaration was removed or changed: %s", declaration)
+		}
+	}
+	// Additions are compatible, and are recorded in the golden file with -update
+	for _, declaration := range current {
+		if !goldenSet[declaration] {
+			t.Logf("New declaration: %s", declaration)
+		}
+	}
+}
+
+// exportedAPI returns the exported declarations of all the packages under root, one per line, sorted.
+// Test files, testdata, internal packages and nested modules are skipped.
+func exportedAPI(root string) ([]string, error) {
+	declarations := []string{}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		if name := d.Name(); path != root && (name == "testdata" || name == "internal" || strings.HasPrefix(name, ".")) {
+			return filepath.SkipDir
+		}
+		if _, err := os.Stat(filepath.Join(path, "go.mod")); path != root && err == nil {
+			// Nested modules are not part of the API of this module
+			return filepath.SkipDir
+		}
+		fset := token.NewFileSet()
+		packages, err := parser.ParseDir(fset, path, func(info fs.FileInfo) bool {
+			return !strings.HasSuffix(info.Name(), "_test.go")
+		}, 0)
+		if err != nil {
+			return err
+		}
+		relativePath, _ := filepath.Rel(root, path)
+		for _, pkg := range packages {
+			for _, file := range pkg.Files {
+				for _, declaration := range fileDeclarations(fset, file) {
+					declarations = append(declarations, filepath.ToSlash(relativePath)+": "+declaration)
+				}
+			}
+		}
+		return nil
+	})
+	sort.Strings(declarations)
+	return declarations, err
+}
+
+func fileDeclarations(fset *token.FileSet, file *ast.File) []string {
+	declarations := []string{}
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if !decl.Name.IsExported() {
+				continue
+			}
+			if decl.Recv == nil {
+				declarations = append(declarations, "func "+decl.Name.Name+typeParams(fset, decl.Type.TypeParams)+signature(fset, decl.Type))
+				continue
+			}
+			receiver := exprString(fset, decl.Recv.List[0].Type)
+			if !ast.IsExported(strings.TrimLeft(receiverTypeName(receiver), "*")) {
+				continue
+			}
+			declarations = append(declarations, "method ("+receiver+") "+decl.Name.Name+signature(fset, decl.Type))
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				declarations = append(declarations, specDeclarations(fset, decl.Tok, spec)...)
+			}
+		}
+	}
+	return declarations
+}
+
+func specDeclarations(fset *token.FileSet, tok token.Token, spec ast.Spec) []string {
+	declarations := []string{}
+	switch spec := spec.(type) {
+	case *ast.ValueSpec:
+		for _, name := range spec.Names {
+			if !name.IsExported() {
+				continue
+			}
+			declaration := tok.String() + " " + name.Name
+			if spec.Type != nil {
+				declaration += " " + exprString(fset, spec.Type)
+			}
+			declarations = append(declarations, declaration)
+		}
+	case *ast.TypeSpec:
+		if !spec.Name.IsExported() {
+			return declarations
+		}
+		name := spec.Name.Name + typeParams(fset, spec.TypeParams)
+		if spec.Assign.IsValid() {
+			name += " ="
+		}
+		switch typ := spec.Type.(type) {
+		case *ast.StructType:
+			// Adding fields is compatible, so each field is a separate declaration
+			declarations = append(declarations, "type "+name+" struct")
+			for _, field := range typ.Fields.List {
+				for _, fieldName := range fieldNames(field) {
+					if ast.IsExported(fieldName) {
+						declarations = append(declarations, "field "+spec.Name.Name+"."+fieldName+" "+exprString(fset, field.Type))
+					}
+				}
+			}
+		case *ast.InterfaceType:
+			// Adding methods to an interface is incompatible, so the whole interface is a single declaration
+			methods := []string{}
+			for _, method := range typ.Methods.List {
+				methods = append(methods, methodString(fset, method))
+			}
+			sort.Strings(methods)
+			declarations = append(declarations, "type "+name+" interface { "+strings.Join(methods, "; ")+" }")
+		default:
+			declarations = append(declarations, "type "+name+" "+exprString(fset, spec.Type))
+		}
+	}
+	return declarations
+}
+
+func fieldNames(field *ast.Field) []string {
+	if len(field.Names) == 0 {
+		// Embedded field
+		return []string{strings.TrimLeft(receiverTypeName(exprString(token.NewFileSet(), field.Type)), "*")}
+	}
+	names := make([]string, len(field.Names))
+	for i, name := range field.Names {
+		names[i] = name.Name
+	}
+	return names
+}
+
+func methodString(fset *token.FileSet, method *ast.Field) string {
+	funcType, ok := method.Type.(*ast.FuncType)
+	if !ok || len(method.Names) == 0 {
+		return exprString(fset, method.Type)
+	}
+	return method.Names[0].Name + signature(fset, funcType)
+}
+
+// signature returns the parameter and result types of funcType, without their names.
+func signature(fset *token.FileSet, funcType *ast.FuncType) string {
+	s := "(" + strings.Join(fieldTypes(fset, funcType.Params), ", ") + ")"
+	if results := fieldTypes(fset, funcType.Results); len(results) == 1 {
+		s += " " + results[0]
+	} else if len(results) > 1 {
+		s += " (" + strings.Join(results, ", ") + ")"
+	}
+	return s
+}
+
+func typeParams(fset *token.FileSet, params *ast.FieldList) string {
+	if params == nil || len(params.List) == 0 {
+		return ""
+	}
+	typeParams := []string{}
+	for _, field := range params.List {
+		for _, name := range field.Names {
+			typeParams = append(typeParams, name.Name+" "+exprString(fset, field.Type))
+		}
+	}
+	return "[" + strings.Join(typeParams, ", ") + "]"
+}
+
+func fieldTypes(fset *token.FileSet, fields *ast.FieldList) []string {
+	types := []string{}
+	if fields == nil {
+		return types
+	}
+	for _, field := range fields.List {
+		n := len(field.Names)
+		if n == 0 {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			types = append(types, exprString(fset, field.Type))
+		}
+	}
+	return types
+}
+
+// receiverTypeName strips the type parameters from a receiver type, e.g. "*T[K]" becomes "*T".
+func receiverTypeName(receiver string) string {
+	name, _, _ := strings.Cut(receiver, "[")
+	return name
+}
+
+func exprString(fset *token.FileSet, expr ast.Expr) string {
+	var b bytes.Buffer
+	_ = printer.Fprint(&b, fset, expr)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
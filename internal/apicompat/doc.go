@@ -0,0 +1,24 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package apicompat records the exported API of the packages of this module, and checks that it only changes
+// compatibly. Its test compares the exported declarations of each package with the golden file in testdata, and
+// fails when a declaration is removed or changed. Declarations are recorded so that compatible changes are
+// additions: each exported struct field is a separate declaration, while an interface is a single declaration,
+// since adding methods to it breaks its implementations. New declarations are logged, and recorded by running
+//
+//	go test ./internal/apicompat -update
+//
+// which is also how an intended incompatible change is accepted: such changes are listed as BREAKING in the changelog.
+package apicompat
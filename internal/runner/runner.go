@@ -0,0 +1,43 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package runner runs the requests built by the client package and parses their responses. It is shared by the
+// client, ops and pairing packages, and is generic over the client type so that it does not import the client package.
+package runner
+
+import "fmt"
+
+// Response is the part of an Astarte response needed to parse it.
+type Response interface {
+	Parse() (any, error)
+}
+
+// RunAndParse runs a request with run, which is usually the Run method of a request built by the client,
+// and returns its parsed response as T.
+func RunAndParse[T any, C any, R Response](c C, run func(C) (R, error)) (T, error) {
+	var ret T
+	res, err := run(c)
+	if err != nil {
+		return ret, err
+	}
+	data, err := res.Parse()
+	if err != nil {
+		return ret, err
+	}
+	ret, ok := data.(T)
+	if !ok {
+		return ret, fmt.Errorf("Unexpected response data of type %T", data)
+	}
+	return ret, nil
+}
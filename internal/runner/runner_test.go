@@ -0,0 +1,57 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runner
+
+import (
+	"errors"
+	"testing"
+)
+
+type testResponse struct {
+	data any
+	err  error
+}
+
+func (r testResponse) Parse() (any, error) {
+	return r.data, r.err
+}
+
+func TestRunAndParse(t *testing.T) {
+	run := func(res testResponse, err error) func(string) (testResponse, error) {
+		return func(c string) (testResponse, error) {
+			if c != "client" {
+				t.Errorf("Unexpected client %q", c)
+			}
+			return res, err
+		}
+	}
+
+	data, err := RunAndParse[int]("client", run(testResponse{data: 42}, nil))
+	if err != nil || data != 42 {
+		t.Errorf("Unexpected result %v, %v", data, err)
+	}
+
+	runErr := errors.New("run failed")
+	if _, err := RunAndParse[int]("client", run(testResponse{}, runErr)); !errors.Is(err, runErr) {
+		t.Errorf("Expected run error, found %v", err)
+	}
+	parseErr := errors.New("parse failed")
+	if _, err := RunAndParse[int]("client", run(testResponse{err: parseErr}, nil)); !errors.Is(err, parseErr) {
+		t.Errorf("Expected parse error, found %v", err)
+	}
+	if _, err := RunAndParse[int]("client", run(testResponse{data: "42"}, nil)); err == nil {
+		t.Error("Response data of the wrong type accepted")
+	}
+}
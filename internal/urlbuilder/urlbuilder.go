@@ -0,0 +1,107 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package urlbuilder builds the URLs of the Astarte APIs, escaping the identifiers in their paths. Realm names,
+// Device IDs, device aliases, group names, interface names, trigger and policy names are single path segments:
+// any character which is not allowed in a segment, including slashes, is percent-encoded, and the segments "."
+// and ".." are encoded too, so that they are not resolved as relative references by servers and proxies.
+// Interface paths are made of many segments, each escaped the same way, and keep their slashes. Slashes are
+// never added, removed or collapsed: a trailing slash in an interface path is sent as is.
+package urlbuilder
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Path is an argument of Build spanning many path segments, such as an interface path: its slashes are kept,
+// while each of its segments is escaped.
+type Path string
+
+// Escaped is an argument of Build which is already escaped, e.g. a path built with EscapeSegment,
+// and is inserted as is.
+type Escaped string
+
+// Build returns the URL obtained appending pathFormat to the path of base. Each "%s" in pathFormat is replaced by
+// the corresponding argument, escaped as a single path segment unless it is a Path or Escaped. Arguments which are
+// not strings are formatted with fmt.Sprint first. The query and fragment of base are dropped.
+func Build(base *url.URL, pathFormat string, args ...any) *url.URL {
+	parts := strings.Split(pathFormat, "%s")
+	if len(parts) != len(args)+1 {
+		panic(fmt.Sprintf("urlbuilder: %q expects %d arguments, found %d", pathFormat, len(parts)-1, len(args)))
+	}
+	var escapedPath strings.Builder
+	escapedPath.WriteString(strings.TrimSuffix(base.EscapedPath(), "/"))
+	for i, part := range parts {
+		if i > 0 {
+			escapedPath.WriteString(escapeArgument(args[i-1]))
+		}
+		escapedPath.WriteString(part)
+	}
+	return FromEscapedPath(base, escapedPath.String())
+}
+
+// FromEscapedPath returns a copy of base with escapedPath as its path, keeping the escaping of escapedPath.
+func FromEscapedPath(base *url.URL, escapedPath string) *url.URL {
+	ret := *base
+	ret.User = nil
+	if base.User != nil {
+		user := *base.User
+		ret.User = &user
+	}
+	ret.RawQuery = ""
+	ret.Fragment = ""
+	ret.RawFragment = ""
+	ret.RawPath = escapedPath
+	unescapedPath, err := url.PathUnescape(escapedPath)
+	if err != nil {
+		// Escaped arguments might be malformed, keep them as they are
+		unescapedPath = escapedPath
+	}
+	ret.Path = unescapedPath
+	return &ret
+}
+
+// EscapeSegment escapes segment so that it is a single path segment.
+func EscapeSegment(segment string) string {
+	switch segment {
+	case ".":
+		return "%2E"
+	case "..":
+		return "%2E%2E"
+	}
+	return url.PathEscape(segment)
+}
+
+// EscapePath escapes each of the segments of p, keeping its slashes.
+func EscapePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, segment := range segments {
+		segments[i] = EscapeSegment(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func escapeArgument(arg any) string {
+	switch a := arg.(type) {
+	case Path:
+		return EscapePath(string(a))
+	case Escaped:
+		return string(a)
+	case string:
+		return EscapeSegment(a)
+	}
+	return EscapeSegment(fmt.Sprint(arg))
+}
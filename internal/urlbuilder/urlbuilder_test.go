@@ -0,0 +1,66 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package urlbuilder
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestBuild(t *testing.T) {
+	base, _ := url.Parse("https://api.example.com/appengine/?token=secret#fragment")
+	testCases := []struct {
+		format   string
+		args     []any
+		expected string
+	}{
+		{"/v1/%s/devices", []any{"test"}, "/appengine/v1/test/devices"},
+		{"/v1/%s/devices-by-alias/%s", []any{"test", "a/b c"}, "/appengine/v1/test/devices-by-alias/a%2Fb%20c"},
+		{"/v1/%s/groups/%s/devices", []any{"test", "ah yes, a group?"}, "/appengine/v1/test/groups/ah%20yes%2C%20a%20group%3F/devices"},
+		{"/v1/%s/groups/%s", []any{"test", "percent%20"}, "/appengine/v1/test/groups/percent%2520"},
+		{"/v1/%s/groups/%s", []any{"test", ".."}, "/appengine/v1/test/groups/%2E%2E"},
+		{"/v1/%s/groups/%s", []any{"test", "."}, "/appengine/v1/test/groups/%2E"},
+		{"/v1/%s/interfaces/%s/%s", []any{"test", "org.astarte-platform.Values", 1}, "/appengine/v1/test/interfaces/org.astarte-platform.Values/1"},
+		{"/v1/%s/interfaces/%s%s", []any{"test", "org.Values", Path("/a b/../c#d")}, "/appengine/v1/test/interfaces/org.Values/a%20b/%2E%2E/c%23d"},
+		{"/v1/%s/interfaces/%s%s", []any{"test", "org.Values", Path("/trailing/")}, "/appengine/v1/test/interfaces/org.Values/trailing/"},
+		{"/v1/%s/%s", []any{"test", Escaped("devices/a%2Fb")}, "/appengine/v1/test/devices/a%2Fb"},
+		{"/v1/realms", nil, "/appengine/v1/realms"},
+	}
+	for _, tc := range testCases {
+		u := Build(base, tc.format, tc.args...)
+		if u.EscapedPath() != tc.expected {
+			t.Errorf("%s %v: expected %s, found %s", tc.format, tc.args, tc.expected, u.EscapedPath())
+		}
+		if u.RawQuery != "" || u.Fragment != "" || u.Host != "api.example.com" {
+			t.Errorf("Unexpected URL %s", u)
+		}
+		if parsed, err := url.Parse(u.String()); err != nil || parsed.EscapedPath() != tc.expected {
+			t.Errorf("%s does not survive a round trip: %v", u, err)
+		}
+	}
+	if base.Path != "/appengine/" || base.RawQuery == "" {
+		t.Errorf("Base URL was modified: %s", base)
+	}
+}
+
+func TestBuildPanicsOnWrongArguments(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic")
+		}
+	}()
+	base, _ := url.Parse("https://api.example.com")
+	Build(base, "/v1/%s/devices/%s", "test")
+}
@@ -0,0 +1,256 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package liveness computes the uptime of devices over a time range from the history of their connection state,
+// e.g. to track the availability of a fleet against its service level objectives. The history is made of
+// observations, which can be inferred from the DeviceDetails of devices sampled over time or from the
+// device_connected and device_disconnected events delivered by triggers.
+package liveness
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/client"
+	"github.com/astarte-platform/astarte-go/events"
+	"github.com/astarte-platform/astarte-go/triggers"
+)
+
+// Observation records that a device was connected, or disconnected, at a point in time. A device is assumed to stay
+// in the same state until the next observation.
+type Observation struct {
+	DeviceID  string
+	Time      time.Time
+	Connected bool
+}
+
+// ObservationsFromDeviceDetails returns the observations inferred from the details of a device sampled at
+// sampledAt: the device was in its current state at sampledAt, and has been since its last connection or
+// disconnection, if any.
+func ObservationsFromDeviceDetails(details client.DeviceDetails, sampledAt time.Time) []Observation {
+	since := details.LastDisconnection
+	if details.Connected {
+		since = details.LastConnection
+	}
+	observations := []Observation{}
+	if !since.IsZero() && since.Before(sampledAt) {
+		observations = append(observations, Observation{DeviceID: details.DeviceID, Time: since, Connected: details.Connected})
+	}
+	return append(observations, Observation{DeviceID: details.DeviceID, Time: sampledAt, Connected: details.Connected})
+}
+
+// ObservationFromEvent returns the observation recorded by a device_connected or device_disconnected event.
+// ok is false for other events.
+func ObservationFromEvent(event events.SimpleEvent) (observation Observation, ok bool) {
+	switch event.Event.Type {
+	case triggers.DeviceConnected, triggers.DeviceDisconnected:
+		return Observation{DeviceID: event.DeviceID, Time: event.Timestamp, Connected: event.Event.Type == triggers.DeviceConnected}, true
+	default:
+		return Observation{}, false
+	}
+}
+
+// History holds the observations of the connection state of devices. It is safe for concurrent use, so that e.g.
+// an HTTP handler receiving trigger events can add observations while reports are computed.
+type History struct {
+	mu           sync.Mutex
+	observations map[string][]Observation
+}
+
+// NewHistory returns an empty History.
+func NewHistory() *History {
+	return &History{observations: map[string][]Observation{}}
+}
+
+// Add adds observations to h, in any order. When two observations of a device have the same time, the one added
+// last wins.
+func (h *History) Add(observations ...Observation) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, observation := range observations {
+		h.observations[observation.DeviceID] = append(h.observations[observation.DeviceID], observation)
+	}
+}
+
+// AddDeviceDetails adds the observations inferred from the details of a device sampled at sampledAt, see
+// ObservationsFromDeviceDetails.
+func (h *History) AddDeviceDetails(details client.DeviceDetails, sampledAt time.Time) {
+	h.Add(ObservationsFromDeviceDetails(details, sampledAt)...)
+}
+
+// AddEvent adds the observation recorded by event, if any, see ObservationFromEvent. It returns whether one was
+// added.
+func (h *History) AddEvent(event events.SimpleEvent) bool {
+	observation, ok := ObservationFromEvent(event)
+	if ok {
+		h.Add(observation)
+	}
+	return ok
+}
+
+// Window is a time range, from Start included to End excluded.
+type Window struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Duration returns the duration of w.
+func (w Window) Duration() time.Duration {
+	return w.End.Sub(w.Start)
+}
+
+// FlappingThreshold defines when a device is flapping: when its connection state changes at least Transitions
+// times within Window. The zero value disables flapping detection.
+type FlappingThreshold struct {
+	Transitions int
+	Window      time.Duration
+}
+
+// DefaultFlappingThreshold considers a device flapping when it connects or disconnects 6 times within an hour.
+var DefaultFlappingThreshold = FlappingThreshold{Transitions: 6, Window: time.Hour}
+
+// DeviceReport reports the liveness of a device over a time range.
+type DeviceReport struct {
+	DeviceID string
+	Range    Window
+	// Uptime and Downtime are the time spent connected and disconnected in the range, Unknown is the time before
+	// the first observation of the device.
+	Uptime   time.Duration
+	Downtime time.Duration
+	Unknown  time.Duration
+	// OfflineWindows are the windows in which the device was disconnected, in chronological order. Windows are
+	// clipped to the range.
+	OfflineWindows []Window
+	// Transitions counts the changes of the connection state within the range.
+	Transitions int
+	// Flapping is set if the transitions exceed the FlappingThreshold the report was computed with.
+	Flapping bool
+}
+
+// UptimePercentage returns the percentage of time the device was connected out of the time its state is known, or
+// 0 if it is never known.
+func (r DeviceReport) UptimePercentage() float64 {
+	known := r.Uptime + r.Downtime
+	if known == 0 {
+		return 0
+	}
+	return 100 * float64(r.Uptime) / float64(known)
+}
+
+// LongestOfflineWindows returns the n longest offline windows of the device, longest first. All windows are returned
+// if n is negative.
+func (r DeviceReport) LongestOfflineWindows(n int) []Window {
+	windows := append([]Window{}, r.OfflineWindows...)
+	sort.SliceStable(windows, func(i, j int) bool {
+		return windows[i].Duration() > windows[j].Duration()
+	})
+	if n >= 0 && n < len(windows) {
+		windows = windows[:n]
+	}
+	return windows
+}
+
+// Report returns the reports of all devices in h over the range from from to to, sorted by Device ID.
+func (h *History) Report(from, to time.Time, flapping FlappingThreshold) []DeviceReport {
+	h.mu.Lock()
+	deviceIDs := make([]string, 0, len(h.observations))
+	for deviceID := range h.observations {
+		deviceIDs = append(deviceIDs, deviceID)
+	}
+	h.mu.Unlock()
+	sort.Strings(deviceIDs)
+
+	reports := make([]DeviceReport, 0, len(deviceIDs))
+	for _, deviceID := range deviceIDs {
+		reports = append(reports, h.DeviceReport(deviceID, from, to, flapping))
+	}
+	return reports
+}
+
+// DeviceReport returns the report of the device with Device ID deviceID over the range from from to to. If h holds no
+// observations of the device, its state is unknown for the whole range.
+func (h *History) DeviceReport(deviceID string, from, to time.Time, flapping FlappingThreshold) DeviceReport {
+	h.mu.Lock()
+	observations := append([]Observation{}, h.observations[deviceID]...)
+	h.mu.Unlock()
+	sort.SliceStable(observations, func(i, j int) bool {
+		return observations[i].Time.Before(observations[j].Time)
+	})
+
+	report := DeviceReport{DeviceID: deviceID, Range: Window{Start: from, End: to}}
+	if !to.After(from) {
+		return report
+	}
+
+	// Observations up to the start of the range set the initial state
+	known, connected := false, false
+	i := 0
+	for ; i < len(observations) && !observations[i].Time.After(from); i++ {
+		known, connected = true, observations[i].Connected
+	}
+
+	cursor, offlineSince := from, from
+	transitions := []time.Time{}
+	advance := func(until time.Time) {
+		switch {
+		case !known:
+			report.Unknown += until.Sub(cursor)
+		case connected:
+			report.Uptime += until.Sub(cursor)
+		default:
+			report.Downtime += until.Sub(cursor)
+		}
+		cursor = until
+	}
+	for _, observation := range observations[i:] {
+		if !observation.Time.Before(to) {
+			break
+		}
+		if known && observation.Connected == connected {
+			continue
+		}
+		advance(observation.Time)
+		if known {
+			transitions = append(transitions, observation.Time)
+		}
+		if observation.Connected && known {
+			report.OfflineWindows = append(report.OfflineWindows, Window{Start: offlineSince, End: observation.Time})
+		} else if !observation.Connected {
+			offlineSince = observation.Time
+		}
+		known, connected = true, observation.Connected
+	}
+	advance(to)
+	if known && !connected {
+		report.OfflineWindows = append(report.OfflineWindows, Window{Start: offlineSince, End: to})
+	}
+
+	report.Transitions = len(transitions)
+	report.Flapping = isFlapping(transitions, flapping)
+	return report
+}
+
+// isFlapping returns whether any threshold.Transitions consecutive transitions happen within threshold.Window.
+func isFlapping(transitions []time.Time, threshold FlappingThreshold) bool {
+	if threshold.Transitions <= 0 {
+		return false
+	}
+	for i := 0; i+threshold.Transitions <= len(transitions); i++ {
+		if transitions[i+threshold.Transitions-1].Sub(transitions[i]) <= threshold.Window {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package liveness
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/client"
+	"github.com/astarte-platform/astarte-go/events"
+	"github.com/astarte-platform/astarte-go/triggers"
+)
+
+const (
+	testDeviceID      = "glO6LullTKmwxebForU-eg"
+	testOtherDeviceID = "t1J1uQSBQRi_1F3zIrjyYw"
+)
+
+var testStart = time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+func at(minutes int) time.Time {
+	return testStart.Add(time.Duration(minutes) * time.Minute)
+}
+
+func TestDeviceReport(t *testing.T) {
+	h := NewHistory()
+	// Connected before the range, offline from 10 to 30 and from 50 on, with repeated samples in between
+	h.Add(
+		Observation{DeviceID: testDeviceID, Time: at(50), Connected: false},
+		Observation{DeviceID: testDeviceID, Time: at(-5), Connected: true},
+		Observation{DeviceID: testDeviceID, Time: at(10), Connected: false},
+		Observation{DeviceID: testDeviceID, Time: at(20), Connected: false},
+		Observation{DeviceID: testDeviceID, Time: at(30), Connected: true},
+		Observation{DeviceID: testDeviceID, Time: at(120), Connected: true},
+	)
+
+	report := h.DeviceReport(testDeviceID, at(0), at(60), DefaultFlappingThreshold)
+	if report.Uptime != 30*time.Minute || report.Downtime != 30*time.Minute || report.Unknown != 0 {
+		t.Errorf("Unexpected durations %+v", report)
+	}
+	if report.UptimePercentage() != 50 {
+		t.Errorf("Unexpected uptime percentage %v", report.UptimePercentage())
+	}
+	expectedWindows := []Window{{Start: at(10), End: at(30)}, {Start: at(50), End: at(60)}}
+	if !reflect.DeepEqual(report.OfflineWindows, expectedWindows) {
+		t.Errorf("Unexpected offline windows %v", report.OfflineWindows)
+	}
+	if longest := report.LongestOfflineWindows(1); !reflect.DeepEqual(longest, expectedWindows[:1]) {
+		t.Errorf("Unexpected longest offline windows %v", longest)
+	}
+	if report.Transitions != 3 || report.Flapping {
+		t.Errorf("Unexpected transitions %+v", report)
+	}
+
+	// The state of the device is unknown before its first observation
+	report = h.DeviceReport(testDeviceID, at(-10), at(0), DefaultFlappingThreshold)
+	if report.Unknown != 5*time.Minute || report.Uptime != 5*time.Minute || report.Transitions != 0 {
+		t.Errorf("Unexpected report %+v", report)
+	}
+	report = h.DeviceReport(testOtherDeviceID, at(0), at(60), DefaultFlappingThreshold)
+	if report.Unknown != time.Hour || report.UptimePercentage() != 0 {
+		t.Errorf("Unexpected report %+v", report)
+	}
+}
+
+func TestFlapping(t *testing.T) {
+	h := NewHistory()
+	for i := 0; i < 6; i++ {
+		h.Add(Observation{DeviceID: testDeviceID, Time: at(i * 5), Connected: i%2 == 0})
+	}
+	h.Add(Observation{DeviceID: testOtherDeviceID, Time: at(0), Connected: true})
+
+	reports := h.Report(at(-1), at(60), DefaultFlappingThreshold)
+	if len(reports) != 2 || reports[0].DeviceID != testDeviceID || reports[1].DeviceID != testOtherDeviceID {
+		t.Fatalf("Unexpected reports %+v", reports)
+	}
+	// The first observation is not a transition
+	if reports[0].Transitions != 5 || reports[0].Flapping {
+		t.Errorf("Unexpected report %+v", reports[0])
+	}
+	if reports[1].Flapping {
+		t.Errorf("Unexpected report %+v", reports[1])
+	}
+	if report := h.DeviceReport(testDeviceID, at(-1), at(60), FlappingThreshold{Transitions: 5, Window: 20 * time.Minute}); !report.Flapping {
+		t.Errorf("Expected the device to be flapping, got %+v", report)
+	}
+	if report := h.DeviceReport(testDeviceID, at(-1), at(60), FlappingThreshold{}); report.Flapping {
+		t.Errorf("Expected flapping detection to be disabled, got %+v", report)
+	}
+}
+
+func TestObservations(t *testing.T) {
+	details := client.DeviceDetails{DeviceID: testDeviceID, Connected: true, LastConnection: at(10), LastDisconnection: at(5)}
+	observations := ObservationsFromDeviceDetails(details, at(20))
+	expected := []Observation{
+		{DeviceID: testDeviceID, Time: at(10), Connected: true},
+		{DeviceID: testDeviceID, Time: at(20), Connected: true},
+	}
+	if !reflect.DeepEqual(observations, expected) {
+		t.Errorf("Unexpected observations %+v", observations)
+	}
+
+	h := NewHistory()
+	h.AddDeviceDetails(client.DeviceDetails{DeviceID: testDeviceID}, at(0))
+	if !h.AddEvent(events.SimpleEvent{DeviceID: testDeviceID, Timestamp: at(30), Event: events.Event{Type: triggers.DeviceConnected}}) {
+		t.Error("Expected the device_connected event to be added")
+	}
+	if h.AddEvent(events.SimpleEvent{DeviceID: testDeviceID, Timestamp: at(40), Event: events.Event{Type: triggers.IncomingData}}) {
+		t.Error("Expected the incoming_data event to be ignored")
+	}
+	report := h.DeviceReport(testDeviceID, at(0), at(60), DefaultFlappingThreshold)
+	if report.Downtime != 30*time.Minute || report.Uptime != 30*time.Minute || len(report.OfflineWindows) != 1 {
+		t.Errorf("Unexpected report %+v", report)
+	}
+}
@@ -0,0 +1,250 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package manifests converts Astarte interfaces and triggers to and from the Kubernetes custom resources
+// describing them in the manifests applied through astarte-kubernetes-operator, so that GitOps repositories
+// can be checked and generated with the parsers of this library. A custom resource holds the JSON definition
+// of the interface or trigger, written as YAML, in its spec:
+//
+//	apiVersion: api.astarte-platform.org/v1alpha1
+//	kind: AstarteInterface
+//	metadata:
+//	  name: org.astarte-platform.genericsensors.values-v1
+//	spec:
+//	  interface_name: org.astarte-platform.genericsensors.Values
+//	  version_major: 1
+//	  ...
+package manifests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/astarte-platform/astarte-go/interfaces"
+	"github.com/astarte-platform/astarte-go/triggers"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// APIGroup is the API group of the Astarte custom resources.
+	APIGroup = "api.astarte-platform.org"
+	// APIVersion is the apiVersion of the custom resources built by this package.
+	APIVersion = APIGroup + "/v1alpha1"
+	// InterfaceKind is the kind of the custom resources holding an interface.
+	InterfaceKind = "AstarteInterface"
+	// TriggerKind is the kind of the custom resources holding a trigger.
+	TriggerKind = "AstarteTrigger"
+	// MaxNameLength is the maximum length of the name of a custom resource.
+	MaxNameLength = 253
+)
+
+var (
+	ErrNotAstarteResource = errors.New("Not an Astarte custom resource")
+	ErrUnexpectedKind     = errors.New("Unexpected custom resource kind")
+	ErrMissingSpec        = errors.New("Custom resource has no spec")
+)
+
+// ObjectMeta is the metadata of a custom resource.
+type ObjectMeta struct {
+	Name        string            `yaml:"name"`
+	Namespace   string            `yaml:"namespace,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+// CustomResource is a Kubernetes custom resource, as found in a YAML manifest.
+type CustomResource struct {
+	APIVersion string     `yaml:"apiVersion"`
+	Kind       string     `yaml:"kind"`
+	Metadata   ObjectMeta `yaml:"metadata"`
+	// Spec holds the definition of the interface or trigger.
+	Spec yaml.Node `yaml:"spec"`
+}
+
+// IsAstarteResource returns whether r belongs to the Astarte API group, regardless of its version.
+func (r CustomResource) IsAstarteResource() bool {
+	return strings.HasPrefix(r.APIVersion, APIGroup+"/")
+}
+
+// Definition returns the spec of r as JSON, i.e. the definition of the interface or trigger it holds.
+func (r CustomResource) Definition() ([]byte, error) {
+	if r.Spec.Kind == 0 {
+		return nil, fmt.Errorf("%w: %s %s", ErrMissingSpec, r.Kind, r.Metadata.Name)
+	}
+	var spec interface{}
+	if err := r.Spec.Decode(&spec); err != nil {
+		return nil, err
+	}
+	return json.Marshal(spec)
+}
+
+// ParseCustomResources parses all the documents of a YAML manifest, skipping empty ones. Documents which
+// are not Astarte custom resources are returned as well, so that callers can tell them apart with
+// IsAstarteResource.
+func ParseCustomResources(manifest []byte) ([]CustomResource, error) {
+	resources := []CustomResource{}
+	decoder := yaml.NewDecoder(bytes.NewReader(manifest))
+	for {
+		var document yaml.Node
+		if err := decoder.Decode(&document); errors.Is(err, io.EOF) {
+			return resources, nil
+		} else if err != nil {
+			return resources, err
+		}
+		if len(document.Content) == 0 || document.Content[0].Tag == "!!null" {
+			continue
+		}
+		var resource CustomResource
+		if err := document.Decode(&resource); err != nil {
+			return resources, err
+		}
+		resources = append(resources, resource)
+	}
+}
+
+// InterfaceFromCustomResource parses the interface held by r, which must be an Astarte custom resource
+// of kind InterfaceKind.
+func InterfaceFromCustomResource(r CustomResource) (interfaces.AstarteInterface, error) {
+	definition, err := definitionOfKind(r, InterfaceKind)
+	if err != nil {
+		return interfaces.AstarteInterface{}, err
+	}
+	return interfaces.ParseInterface(definition)
+}
+
+// TriggerFromCustomResource parses the trigger held by r, which must be an Astarte custom resource
+// of kind TriggerKind.
+func TriggerFromCustomResource(r CustomResource) (triggers.AstarteTrigger, error) {
+	definition, err := definitionOfKind(r, TriggerKind)
+	if err != nil {
+		return triggers.AstarteTrigger{}, err
+	}
+	return triggers.ParseTrigger(definition)
+}
+
+// ExtractInterfaces parses all the interfaces held by the custom resources of a YAML manifest. Documents
+// of any other kind are skipped.
+func ExtractInterfaces(manifest []byte) ([]interfaces.AstarteInterface, error) {
+	return extract(manifest, InterfaceKind, InterfaceFromCustomResource)
+}
+
+// ExtractTriggers parses all the triggers held by the custom resources of a YAML manifest. Documents
+// of any other kind are skipped.
+func ExtractTriggers(manifest []byte) ([]triggers.AstarteTrigger, error) {
+	return extract(manifest, TriggerKind, TriggerFromCustomResource)
+}
+
+// WrapInterface returns the YAML custom resource holding astarteInterface. When metadata has no name,
+// it is derived from the name and major version of the interface with ResourceName.
+func WrapInterface(astarteInterface interfaces.AstarteInterface, metadata ObjectMeta) ([]byte, error) {
+	if metadata.Name == "" {
+		metadata.Name = ResourceName(fmt.Sprintf("%s-v%d", astarteInterface.Name, astarteInterface.MajorVersion))
+	}
+	return wrap(InterfaceKind, metadata, astarteInterface)
+}
+
+// WrapTrigger returns the YAML custom resource holding trigger. When metadata has no name, it is derived
+// from the name of the trigger with ResourceName.
+func WrapTrigger(trigger triggers.AstarteTrigger, metadata ObjectMeta) ([]byte, error) {
+	if metadata.Name == "" {
+		metadata.Name = ResourceName(trigger.Name)
+	}
+	return wrap(TriggerKind, metadata, trigger)
+}
+
+// ResourceName turns name into a valid Kubernetes resource name: letters are lowercased, any character
+// other than letters, digits, '-' and '.' is replaced by '-', and the result is trimmed to MaxNameLength
+// characters starting and ending with a letter or digit.
+func ResourceName(name string) string {
+	resourceName := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '.':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		}
+		return '-'
+	}, name)
+	if len(resourceName) > MaxNameLength {
+		resourceName = resourceName[:MaxNameLength]
+	}
+	return strings.Trim(resourceName, "-.")
+}
+
+func definitionOfKind(r CustomResource, kind string) ([]byte, error) {
+	if !r.IsAstarteResource() {
+		return nil, fmt.Errorf("%w: %s", ErrNotAstarteResource, r.APIVersion)
+	}
+	if r.Kind != kind {
+		return nil, fmt.Errorf("%w: expected %s, found %s", ErrUnexpectedKind, kind, r.Kind)
+	}
+	return r.Definition()
+}
+
+func extract[T any](manifest []byte, kind string, parse func(CustomResource) (T, error)) ([]T, error) {
+	resources, err := ParseCustomResources(manifest)
+	if err != nil {
+		return nil, err
+	}
+	ret := []T{}
+	for _, r := range resources {
+		if !r.IsAstarteResource() || r.Kind != kind {
+			continue
+		}
+		parsed, err := parse(r)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", r.Kind, r.Metadata.Name, err)
+		}
+		ret = append(ret, parsed)
+	}
+	return ret, nil
+}
+
+func wrap(kind string, metadata ObjectMeta, definition any) ([]byte, error) {
+	b, err := json.Marshal(definition)
+	if err != nil {
+		return nil, err
+	}
+	// JSON is YAML: parsing it as a node keeps the order of the fields
+	var spec yaml.Node
+	if err := yaml.Unmarshal(b, &spec); err != nil {
+		return nil, err
+	}
+	resource := CustomResource{APIVersion: APIVersion, Kind: kind, Metadata: metadata, Spec: *spec.Content[0]}
+	blockStyle(&resource.Spec)
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(resource); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// blockStyle drops the JSON flow and quoting styles of n, strings which would be read back as a
+// different type are still quoted by the encoder.
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		blockStyle(c)
+	}
+}
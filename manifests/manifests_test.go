@@ -0,0 +1,162 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifests
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/astarte-platform/astarte-go/interfaces"
+	"github.com/astarte-platform/astarte-go/triggers"
+)
+
+const testManifest = `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: unrelated
+---
+apiVersion: api.astarte-platform.org/v1alpha1
+kind: AstarteInterface
+metadata:
+  name: org.astarte-platform.test.values-v1
+spec:
+  interface_name: org.astarte-platform.test.Values
+  version_major: 1
+  version_minor: 2
+  type: datastream
+  ownership: device
+  mappings:
+    - endpoint: /%{sensor_id}/value
+      type: double
+      explicit_timestamp: true
+---
+apiVersion: api.astarte-platform.org/v1alpha1
+kind: AstarteTrigger
+metadata:
+  name: value-trigger
+spec:
+  name: value_trigger
+  action:
+    http_url: https://example.com/hook
+    http_method: post
+  simple_triggers:
+    - type: data_trigger
+      on: incoming_data
+      interface_name: org.astarte-platform.test.Values
+      interface_major: 1
+      match_path: /*
+      value_match_operator: "*"
+---
+`
+
+func TestExtract(t *testing.T) {
+	resources, err := ParseCustomResources([]byte(testManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resources) != 3 || resources[0].IsAstarteResource() || !resources[1].IsAstarteResource() {
+		t.Fatalf("Unexpected resources %v", resources)
+	}
+
+	ifaces, err := ExtractInterfaces([]byte(testManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ifaces) != 1 || ifaces[0].Name != "org.astarte-platform.test.Values" || ifaces[0].MinorVersion != 2 {
+		t.Fatalf("Unexpected interfaces %v", ifaces)
+	}
+	if m := ifaces[0].Mappings[0]; m.Type != interfaces.Double || !m.ExplicitTimestamp || m.Reliability != interfaces.UnreliableReliability {
+		t.Errorf("Unexpected mapping %v", m)
+	}
+
+	trgs, err := ExtractTriggers([]byte(testManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trgs) != 1 || trgs[0].Name != "value_trigger" || trgs[0].SimpleTriggers[0].ValueMatchOperator != triggers.All {
+		t.Fatalf("Unexpected triggers %v", trgs)
+	}
+
+	if _, err := TriggerFromCustomResource(resources[1]); !errors.Is(err, ErrUnexpectedKind) {
+		t.Errorf("Expected ErrUnexpectedKind, found %v", err)
+	}
+	if _, err := InterfaceFromCustomResource(resources[0]); !errors.Is(err, ErrNotAstarteResource) {
+		t.Errorf("Expected ErrNotAstarteResource, found %v", err)
+	}
+	noSpec := "apiVersion: api.astarte-platform.org/v1alpha1\nkind: AstarteInterface\nmetadata:\n  name: empty\n"
+	if _, err := ExtractInterfaces([]byte(noSpec)); !errors.Is(err, ErrMissingSpec) {
+		t.Errorf("Expected ErrMissingSpec, found %v", err)
+	}
+}
+
+func TestWrap(t *testing.T) {
+	ifaces, err := ExtractInterfaces([]byte(testManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Strings looking like other types must survive the round trip
+	ifaces[0].Description = "true"
+	b, err := WrapInterface(ifaces[0], ObjectMeta{Namespace: "astarte"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "name: org.astarte-platform.test.values-v1\n  namespace: astarte\n") {
+		t.Errorf("Unexpected metadata in %s", b)
+	}
+	wrapped, err := ExtractInterfaces(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(wrapped) != 1 || !reflect.DeepEqual(wrapped[0], ifaces[0]) {
+		t.Errorf("Interface changed after wrapping: %v", wrapped)
+	}
+
+	trgs, err := ExtractTriggers([]byte(testManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err = WrapTrigger(trgs[0], ObjectMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resources, err := ParseCustomResources(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resources) != 1 || resources[0].Kind != TriggerKind || resources[0].Metadata.Name != "value-trigger" {
+		t.Fatalf("Unexpected resources %v", resources)
+	}
+	wrappedTrigger, err := TriggerFromCustomResource(resources[0])
+	if err != nil || !reflect.DeepEqual(wrappedTrigger, trgs[0]) {
+		t.Errorf("Trigger changed after wrapping: %v, %v", wrappedTrigger, err)
+	}
+}
+
+func TestResourceName(t *testing.T) {
+	cases := map[string]string{
+		"org.astarte-platform.Values-v1": "org.astarte-platform.values-v1",
+		"my_trigger":                     "my-trigger",
+		"_leading and trailing_":         "leading-and-trailing",
+		strings.Repeat("a", 300):         strings.Repeat("a", MaxNameLength),
+	}
+	for name, expected := range cases {
+		if resourceName := ResourceName(name); resourceName != expected {
+			t.Errorf("Unexpected resource name for %q: %s instead of %s", name, resourceName, expected)
+		}
+	}
+}
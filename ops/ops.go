@@ -0,0 +1,370 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ops provides coarse-grained operations on an Astarte realm, composed from the requests
+// built by the client package. Unlike the client package, all functions in this package run
+// the requests they build and return parsed results.
+//
+// Long operations have a Context variant, which reports its progress to a Progress and runs its requests
+// with its context, so that it stops as soon as the context is done, even while a request is running.
+package ops
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/astarte-platform/astarte-go/client"
+	"github.com/astarte-platform/astarte-go/interfaces"
+	"github.com/astarte-platform/astarte-go/internal/runner"
+	"github.com/astarte-platform/astarte-go/triggers"
+)
+
+const devicesPageSize = 100
+
+// Realm performs operations on an Astarte realm.
+type Realm struct {
+	client *client.Client
+	name   string
+}
+
+// DeviceReport gathers the details of a device and the current values on all of its server-owned
+// and device-owned interfaces.
+type DeviceReport struct {
+	Details client.DeviceDetails
+	// Properties maps the names of properties interfaces to their values, by path.
+	Properties map[string]map[string]client.PropertyValue
+	// Datastreams maps the names of datastream interfaces to their last values, by path. Values are
+	// DatastreamIndividualValue or DatastreamObjectValue, depending on the aggregation of the interface.
+	Datastreams map[string]map[string]any
+}
+
+// NewRealm returns a Realm performing operations on the realm named name using c.
+func NewRealm(c *client.Client, name string) *Realm {
+	return &Realm{client: c, name: name}
+}
+
+// ListDevicesWithState returns the details of all devices in the realm.
+//
+// Deprecated: ListDevicesWithState holds the details of all devices in memory, which does not scale to large
+// realms. Use ForEachDevice, which processes devices a page at a time.
+func (r *Realm) ListDevicesWithState() ([]client.DeviceDetails, error) {
+	return r.ListDevicesWithStateContext(context.Background(), nil)
+}
+
+// ListDevicesWithStateContext works like ListDevicesWithState, reporting listed devices to progress, if not nil.
+// If ctx is done, it returns the devices listed so far and the error of ctx.
+//
+// Deprecated: ListDevicesWithStateContext holds the details of all devices in memory, which does not scale to large
+// realms. Use ForEachDeviceContext, which processes devices a page at a time.
+func (r *Realm) ListDevicesWithStateContext(ctx context.Context, progress Progress) ([]client.DeviceDetails, error) {
+	devices := []client.DeviceDetails{}
+	err := r.ForEachDevicePageContext(ctx, progress, func(page []client.DeviceDetails) error {
+		devices = append(devices, page...)
+		return nil
+	})
+	if err != nil && ctx.Err() == nil {
+		return nil, err
+	}
+	return devices, err
+}
+
+// ForEachDevice calls fn with the details of each device in the realm. Devices are listed a page at a time,
+// so that memory usage does not depend on the size of the realm. If fn returns an error, listing stops and
+// ForEachDevice returns it.
+func (r *Realm) ForEachDevice(fn func(client.DeviceDetails) error) error {
+	return r.ForEachDeviceContext(context.Background(), nil, fn)
+}
+
+// ForEachDeviceContext works like ForEachDevice, reporting listed devices to progress, if not nil.
+// If ctx is done, it returns the error of ctx.
+func (r *Realm) ForEachDeviceContext(ctx context.Context, progress Progress, fn func(client.DeviceDetails) error) error {
+	return r.ForEachDevicePageContext(ctx, progress, func(page []client.DeviceDetails) error {
+		for _, device := range page {
+			if err := fn(device); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ForEachDevicePage works like ForEachDevice, but calls fn with each page of devices. Pages hold up to 100 devices,
+// and are not retained after fn returns.
+func (r *Realm) ForEachDevicePage(fn func([]client.DeviceDetails) error) error {
+	return r.ForEachDevicePageContext(context.Background(), nil, fn)
+}
+
+// ForEachDevicePageContext works like ForEachDevicePage, reporting listed devices to progress, if not nil.
+// If ctx is done, it returns the error of ctx.
+func (r *Realm) ForEachDevicePageContext(ctx context.Context, progress Progress, fn func([]client.DeviceDetails) error) error {
+	// The total number of devices is only needed to report progress
+	total := 0
+	if progress != nil {
+		stats, err := runAndParse[client.DevicesStats](ctx, r.client)(r.client.GetDevicesStats(r.name))
+		if err != nil {
+			return err
+		}
+		total = int(stats.TotalDevices)
+	}
+	paginator, err := r.client.GetDeviceListPaginator(r.name, devicesPageSize, client.DeviceDetailsFormat)
+	if err != nil {
+		return err
+	}
+	tracker := newProgressTracker(progress, total)
+	for paginator.HasNextPage() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		req, err := paginator.GetNextPage()
+		if err != nil {
+			return err
+		}
+		page, err := runAndParse[[]client.DeviceDetails](ctx, r.client)(req, nil)
+		if err != nil {
+			return err
+		}
+		if err := fn(page); err != nil {
+			return err
+		}
+		tracker.done(len(page))
+	}
+	return nil
+}
+
+// DeployInterfaces installs all interfaces found in the JSON files in dir. Interfaces whose major
+// version is already installed are updated instead. Returns the names of the deployed interfaces.
+func (r *Realm) DeployInterfaces(dir string) ([]string, error) {
+	return r.DeployInterfacesContext(context.Background(), dir, nil)
+}
+
+// DeployInterfacesContext works like DeployInterfaces, reporting deployed files to progress, if not nil.
+// If ctx is done, it returns the names of the interfaces deployed so far and the error of ctx.
+func (r *Realm) DeployInterfacesContext(ctx context.Context, dir string, progress Progress) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	installed, err := runAndParse[[]string](ctx, r.client)(r.client.ListInterfaces(r.name))
+	if err != nil {
+		return nil, err
+	}
+
+	tracker := newProgressTracker(progress, len(files))
+	deployed := []string{}
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return deployed, err
+		}
+		name, err := r.deployInterface(ctx, installed, file)
+		if err != nil {
+			tracker.failed()
+			return deployed, err
+		}
+		deployed = append(deployed, name)
+		tracker.done(1)
+	}
+	return deployed, nil
+}
+
+func (r *Realm) deployInterface(ctx context.Context, installed []string, file string) (string, error) {
+	iface, err := interfaces.ParseInterfaceFrom(file)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", file, err)
+	}
+	update, err := r.isMajorInstalled(ctx, installed, iface)
+	if err != nil {
+		return "", err
+	}
+	if update {
+		_, err = runAndParse[any](ctx, r.client)(r.client.UpdateInterface(r.name, iface.Name, iface.MajorVersion, iface, false))
+	} else {
+		_, err = runAndParse[any](ctx, r.client)(r.client.InstallInterface(r.name, iface, false))
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", file, err)
+	}
+	return iface.Name, nil
+}
+
+func (r *Realm) isMajorInstalled(ctx context.Context, installed []string, iface interfaces.AstarteInterface) (bool, error) {
+	found := false
+	for _, name := range installed {
+		if name == iface.Name {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return false, nil
+	}
+	majors, err := runAndParse[[]int](ctx, r.client)(r.client.ListInterfaceMajorVersions(r.name, iface.Name))
+	if err != nil {
+		return false, err
+	}
+	for _, major := range majors {
+		if major == iface.MajorVersion {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// DeployTriggers installs all triggers found in the JSON files in dir. Triggers can't be updated,
+// so triggers which are already installed are left untouched. Returns the names of the installed triggers.
+func (r *Realm) DeployTriggers(dir string) ([]string, error) {
+	return r.DeployTriggersContext(context.Background(), dir, nil)
+}
+
+// DeployTriggersContext works like DeployTriggers, reporting processed files to progress, if not nil.
+// If ctx is done, it returns the names of the triggers installed so far and the error of ctx.
+func (r *Realm) DeployTriggersContext(ctx context.Context, dir string, progress Progress) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	installed, err := runAndParse[[]string](ctx, r.client)(r.client.ListTriggers(r.name))
+	if err != nil {
+		return nil, err
+	}
+	isInstalled := map[string]bool{}
+	for _, name := range installed {
+		isInstalled[name] = true
+	}
+
+	tracker := newProgressTracker(progress, len(files))
+	deployed := []string{}
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return deployed, err
+		}
+		trigger, err := triggers.ParseTriggerFrom(file)
+		if err != nil {
+			tracker.failed()
+			return deployed, fmt.Errorf("%s: %w", file, err)
+		}
+		if isInstalled[trigger.Name] {
+			tracker.done(1)
+			continue
+		}
+		if _, err := runAndParse[any](ctx, r.client)(r.client.InstallTrigger(r.name, trigger)); err != nil {
+			tracker.failed()
+			return deployed, fmt.Errorf("%s: %w", file, err)
+		}
+		deployed = append(deployed, trigger.Name)
+		tracker.done(1)
+	}
+	return deployed, nil
+}
+
+// SendCommand sends value on path of the server-owned interface interfaceName of a device. The
+// interface definition is retrieved from the realm, using the major version in the device introspection,
+// and value is validated against it.
+func (r *Realm) SendCommand(deviceID, interfaceName, path string, value any) error {
+	details, err := runAndParse[client.DeviceDetails](context.Background(), r.client)(r.client.GetDeviceDetails(r.name, deviceID, client.AstarteDeviceID))
+	if err != nil {
+		return err
+	}
+	introspection, ok := details.Introspection[interfaceName]
+	if !ok {
+		return fmt.Errorf("Interface %s is not in the introspection of device %s", interfaceName, deviceID)
+	}
+	iface, err := runAndParse[interfaces.AstarteInterface](context.Background(), r.client)(r.client.GetInterface(r.name, interfaceName, introspection.Major))
+	if err != nil {
+		return err
+	}
+	_, err = runAndParse[any](context.Background(), r.client)(r.client.SendData(r.name, deviceID, client.AstarteDeviceID, iface, path, value))
+	return err
+}
+
+// GetDeviceReport returns the details of a device and the current values on all interfaces in its introspection.
+func (r *Realm) GetDeviceReport(deviceID string) (DeviceReport, error) {
+	return r.GetDeviceReportContext(context.Background(), deviceID, nil)
+}
+
+// GetDeviceReportContext works like GetDeviceReport, reporting the interfaces whose values were retrieved
+// to progress, if not nil. If ctx is done, it returns the partial report and the error of ctx.
+func (r *Realm) GetDeviceReportContext(ctx context.Context, deviceID string, progress Progress) (DeviceReport, error) {
+	report := DeviceReport{
+		Properties:  map[string]map[string]client.PropertyValue{},
+		Datastreams: map[string]map[string]any{},
+	}
+	details, err := runAndParse[client.DeviceDetails](ctx, r.client)(r.client.GetDeviceDetails(r.name, deviceID, client.AstarteDeviceID))
+	if err != nil {
+		return report, err
+	}
+	report.Details = details
+
+	tracker := newProgressTracker(progress, len(details.Introspection))
+	for name, introspection := range details.Introspection {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		if err := r.addInterfaceToReport(ctx, &report, deviceID, name, introspection.Major); err != nil {
+			tracker.failed()
+			return report, err
+		}
+		tracker.done(1)
+	}
+	return report, nil
+}
+
+func (r *Realm) addInterfaceToReport(ctx context.Context, report *DeviceReport, deviceID, name string, major int) error {
+	iface, err := runAndParse[interfaces.AstarteInterface](ctx, r.client)(r.client.GetInterface(r.name, name, major))
+	if err != nil {
+		return err
+	}
+	switch {
+	case iface.Type == interfaces.PropertiesType:
+		values, err := runAndParse[map[string]client.PropertyValue](ctx, r.client)(r.client.GetAllProperties(r.name, deviceID, client.AstarteDeviceID, name))
+		if err != nil {
+			return err
+		}
+		report.Properties[name] = values
+	case iface.Aggregation == interfaces.ObjectAggregation:
+		values, err := runAndParse[map[string]client.DatastreamObjectValue](ctx, r.client)(r.client.GetDatastreamObjectSnapshot(r.name, deviceID, client.AstarteDeviceID, name))
+		if err != nil {
+			return err
+		}
+		report.Datastreams[name] = map[string]any{}
+		for path, value := range values {
+			report.Datastreams[name][path] = value
+		}
+	default:
+		values, err := runAndParse[map[string]any](ctx, r.client)(r.client.GetDatastreamIndividualSnapshot(r.name, deviceID, client.AstarteDeviceID, name))
+		if err != nil {
+			return err
+		}
+		report.Datastreams[name] = values
+	}
+	return nil
+}
+
+// runAndParse returns a function which runs the request built by a client function with ctx and parses its result
+// as T. It is meant to be called directly on the results of the client function, e.g.
+// runAndParse[[]string](ctx, c)(c.ListInterfaces(realm)).
+func runAndParse[T any](ctx context.Context, c *client.Client) func(client.AstarteRequest, error) (T, error) {
+	return func(req client.AstarteRequest, err error) (T, error) {
+		if err != nil {
+			var ret T
+			return ret, err
+		}
+		ret, err := runner.RunAndParse[T](c, client.Cancelable(ctx, req).Run)
+		if err != nil && ctx.Err() != nil {
+			// Report the error of ctx as it is, rather than wrapped by the HTTP client
+			return ret, ctx.Err()
+		}
+		return ret, err
+	}
+}
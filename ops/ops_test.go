@@ -0,0 +1,289 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/astarte-platform/astarte-go/client"
+)
+
+const (
+	testRealmName         = "test"
+	testDeviceID          = "glO6LullTKmwxebForU-eg"
+	testInstalledName     = "org.astarte.Installed"
+	testNewInterfaceName  = "org.astarte.New"
+	testInstalledTrigger  = "installed_trigger"
+	testNewTrigger        = "new_trigger"
+	testInterfaceTemplate = `{
+		"interface_name": "%s",
+		"version_major": 1,
+		"version_minor": 0,
+		"type": "%s",
+		"ownership": "server",
+		"mappings": [{"endpoint": "/value", "type": "integer"}]
+	}`
+	testTriggerTemplate = `{
+		"name": "%s",
+		"action": {"http_url": "https://example.com", "http_method": "post"},
+		"simple_triggers": [{"type": "device_trigger", "on": "device_connected", "device_id": "glO6LullTKmwxebForU-eg"}]
+	}`
+	testDeviceDetails = `{
+		"id": "glO6LullTKmwxebForU-eg",
+		"connected": true,
+		"introspection": {"org.astarte.Installed": {"major": 1, "minor": 0}, "org.astarte.New": {"major": 1, "minor": 0}}
+	}`
+)
+
+// testRealmMock records the non-GET requests it receives, as "METHOD path", and the paths of GET requests
+type testRealmMock struct {
+	calls []string
+	gets  []string
+}
+
+// nolint:gocognit
+func (m *testRealmMock) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	rm := fmt.Sprintf("/realmmanagement/v1/%s", testRealmName)
+	ae := fmt.Sprintf("/appengine/v1/%s", testRealmName)
+	if req.Method != http.MethodGet {
+		m.calls = append(m.calls, req.Method+" "+req.URL.Path)
+	} else {
+		m.gets = append(m.gets, req.URL.Path)
+	}
+	body, _ := io.ReadAll(req.Body)
+
+	switch {
+	case req.Method == http.MethodGet && req.URL.Path == rm+"/interfaces":
+		fmt.Fprintf(w, `{"data": ["%s"]}`, testInstalledName)
+	case req.Method == http.MethodGet && req.URL.Path == rm+"/interfaces/"+testInstalledName:
+		_, _ = w.Write([]byte(`{"data": [1]}`))
+	case req.Method == http.MethodGet && req.URL.Path == rm+"/interfaces/"+testInstalledName+"/1":
+		fmt.Fprintf(w, `{"data": `+testInterfaceTemplate+`}`, testInstalledName, "properties")
+	case req.Method == http.MethodGet && req.URL.Path == rm+"/interfaces/"+testNewInterfaceName+"/1":
+		fmt.Fprintf(w, `{"data": `+testInterfaceTemplate+`}`, testNewInterfaceName, "datastream")
+	case req.Method == http.MethodPost && req.URL.Path == rm+"/interfaces":
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write(body)
+	case req.Method == http.MethodPut && req.URL.Path == rm+"/interfaces/"+testInstalledName+"/1":
+		w.WriteHeader(http.StatusNoContent)
+	case req.Method == http.MethodGet && req.URL.Path == rm+"/triggers":
+		fmt.Fprintf(w, `{"data": ["%s"]}`, testInstalledTrigger)
+	case req.Method == http.MethodPost && req.URL.Path == rm+"/triggers":
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write(body)
+	case req.Method == http.MethodGet && req.URL.Path == ae+"/stats/devices":
+		_, _ = w.Write([]byte(`{"data": {"total_devices": 1, "connected_devices": 1}}`))
+	case req.Method == http.MethodGet && req.URL.Path == ae+"/devices":
+		_, _ = w.Write([]byte(`{"data": [` + testDeviceDetails + `], "links": {"self": "/v1/test/devices"}}`))
+	case req.Method == http.MethodGet && req.URL.Path == ae+"/devices/"+testDeviceID:
+		_, _ = w.Write([]byte(`{"data": ` + testDeviceDetails + `}`))
+	case req.Method == http.MethodGet && req.URL.Path == ae+"/devices/"+testDeviceID+"/interfaces/"+testInstalledName:
+		_, _ = w.Write([]byte(`{"data": {"value": 42}}`))
+	case req.Method == http.MethodGet && req.URL.Path == ae+"/devices/"+testDeviceID+"/interfaces/"+testNewInterfaceName:
+		_, _ = w.Write([]byte(`{"data": {"value": {"value": 7, "timestamp": "2024-01-01T00:00:00Z"}}}`))
+	case req.Method == http.MethodPost && req.URL.Path == ae+"/devices/"+testDeviceID+"/interfaces/"+testNewInterfaceName+"/value":
+		_, _ = w.Write([]byte(`{"data": 42}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"errors": {"detail": "Not found"}}`))
+	}
+}
+
+func getTestRealm(t *testing.T) (*Realm, *testRealmMock) {
+	mock := &testRealmMock{}
+	server := httptest.NewServer(mock)
+	t.Cleanup(server.Close)
+	c, err := client.New(client.WithBaseURL(server.URL), client.WithJWT("a JWT"), client.WithHTTPClient(server.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewRealm(c, testRealmName), mock
+}
+
+func writeTestFiles(t *testing.T, template string, args ...[]any) string {
+	dir := t.TempDir()
+	for i, a := range args {
+		content := fmt.Sprintf(template, a...)
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("%d.json", i)), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestDeployInterfaces(t *testing.T) {
+	realm, mock := getTestRealm(t)
+	dir := writeTestFiles(t, testInterfaceTemplate, []any{testInstalledName, "properties"}, []any{testNewInterfaceName, "datastream"})
+
+	deployed, err := realm.DeployInterfaces(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(deployed)
+	if strings.Join(deployed, ",") != testInstalledName+","+testNewInterfaceName {
+		t.Errorf("Unexpected deployed interfaces: %v", deployed)
+	}
+	expectedCalls := []string{
+		fmt.Sprintf("PUT /realmmanagement/v1/%s/interfaces/%s/1", testRealmName, testInstalledName),
+		fmt.Sprintf("POST /realmmanagement/v1/%s/interfaces", testRealmName),
+	}
+	if strings.Join(mock.calls, ",") != strings.Join(expectedCalls, ",") {
+		t.Errorf("Unexpected calls: %v", mock.calls)
+	}
+}
+
+func TestDeployTriggers(t *testing.T) {
+	realm, mock := getTestRealm(t)
+	dir := writeTestFiles(t, testTriggerTemplate, []any{testInstalledTrigger}, []any{testNewTrigger})
+
+	deployed, err := realm.DeployTriggers(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deployed) != 1 || deployed[0] != testNewTrigger {
+		t.Errorf("Unexpected deployed triggers: %v", deployed)
+	}
+	if len(mock.calls) != 1 {
+		t.Errorf("Unexpected calls: %v", mock.calls)
+	}
+}
+
+func TestListDevicesWithState(t *testing.T) {
+	realm, mock := getTestRealm(t)
+	devices, err := realm.ListDevicesWithState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 1 || devices[0].DeviceID != testDeviceID || !devices[0].Connected {
+		t.Errorf("Unexpected devices: %+v", devices)
+	}
+	// Device stats are only needed to report progress
+	if len(mock.gets) != 1 || !strings.HasSuffix(mock.gets[0], "/devices") {
+		t.Errorf("Unexpected GET requests: %v", mock.gets)
+	}
+}
+
+func TestForEachDevice(t *testing.T) {
+	realm, _ := getTestRealm(t)
+	devices := []client.DeviceDetails{}
+	err := realm.ForEachDevice(func(device client.DeviceDetails) error {
+		devices = append(devices, device)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 1 || devices[0].DeviceID != testDeviceID {
+		t.Errorf("Unexpected devices: %+v", devices)
+	}
+
+	// Errors of the callback stop the listing
+	errStop := errors.New("stop")
+	if err := realm.ForEachDevicePage(func([]client.DeviceDetails) error { return errStop }); err != errStop {
+		t.Errorf("Expected the error of the callback, found %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := realm.ForEachDeviceContext(ctx, nil, func(client.DeviceDetails) error { return nil }); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, found %v", err)
+	}
+}
+
+func TestSendCommand(t *testing.T) {
+	realm, mock := getTestRealm(t)
+	if err := realm.SendCommand(testDeviceID, testNewInterfaceName, "/value", 42); err != nil {
+		t.Fatal(err)
+	}
+	if len(mock.calls) != 1 {
+		t.Errorf("Unexpected calls: %v", mock.calls)
+	}
+	if err := realm.SendCommand(testDeviceID, testNewInterfaceName, "/value", "not an integer"); err == nil {
+		t.Error("Invalid value was sent")
+	}
+	if err := realm.SendCommand(testDeviceID, "org.astarte.Missing", "/value", 42); err == nil {
+		t.Error("Value was sent on an interface which is not in the introspection")
+	}
+}
+
+func TestGetDeviceReport(t *testing.T) {
+	realm, _ := getTestRealm(t)
+	report, err := realm.GetDeviceReport(testDeviceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Details.DeviceID != testDeviceID {
+		t.Errorf("Unexpected device details: %+v", report.Details)
+	}
+	if fmt.Sprint(report.Properties[testInstalledName]["/value"]) != "42" {
+		t.Errorf("Unexpected properties: %v", report.Properties)
+	}
+	value, ok := report.Datastreams[testNewInterfaceName]["/value"].(client.DatastreamIndividualValue)
+	if !ok || fmt.Sprint(value.Value) != "7" {
+		t.Errorf("Unexpected datastreams: %v", report.Datastreams)
+	}
+}
+
+func TestDeployInterfacesContext(t *testing.T) {
+	realm, mock := getTestRealm(t)
+	dir := writeTestFiles(t, testInterfaceTemplate, []any{testInstalledName, "properties"}, []any{testNewInterfaceName, "datastream"})
+
+	updates := []string{}
+	progress := ProgressFunc(func(total, processed, errors int) {
+		updates = append(updates, fmt.Sprintf("%d/%d/%d", total, processed, errors))
+	})
+	if _, err := realm.DeployInterfacesContext(context.Background(), dir, progress); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(updates, ",") != "2/1/0,2/2/0" {
+		t.Errorf("Unexpected progress updates: %v", updates)
+	}
+
+	// Nothing is deployed once the context is done
+	mock.calls = nil
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	deployed, err := realm.DeployInterfacesContext(ctx, dir, nil)
+	if err != context.Canceled || len(deployed) != 0 || len(mock.calls) != 0 {
+		t.Errorf("Unexpected result after cancellation: %v, %v, calls %v", deployed, err, mock.calls)
+	}
+}
+
+func TestListDevicesWithStateContext(t *testing.T) {
+	realm, mock := getTestRealm(t)
+	updates := []string{}
+	progress := ProgressFunc(func(total, processed, errors int) {
+		updates = append(updates, fmt.Sprintf("%d/%d/%d", total, processed, errors))
+	})
+	if _, err := realm.ListDevicesWithStateContext(context.Background(), progress); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(updates, ",") != "1/1/0" {
+		t.Errorf("Unexpected progress updates: %v", updates)
+	}
+	if len(mock.gets) != 2 || !strings.HasSuffix(mock.gets[0], "/stats/devices") {
+		t.Errorf("Unexpected GET requests: %v", mock.gets)
+	}
+}
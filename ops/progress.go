@@ -0,0 +1,59 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ops
+
+// Progress receives updates about long operations, e.g. to render a progress bar.
+// Update is called each time some items are processed, with the total number of items
+// to process, the number of processed items and how many of them failed.
+type Progress interface {
+	Update(total, processed, errors int)
+}
+
+// ProgressFunc adapts a function to the Progress interface.
+type ProgressFunc func(total, processed, errors int)
+
+// Update calls f(total, processed, errors).
+func (f ProgressFunc) Update(total, processed, errors int) {
+	f(total, processed, errors)
+}
+
+// progressTracker keeps the state of an operation and reports it to a Progress, which might be nil.
+type progressTracker struct {
+	progress  Progress
+	total     int
+	processed int
+	errors    int
+}
+
+func newProgressTracker(progress Progress, total int) *progressTracker {
+	return &progressTracker{progress: progress, total: total}
+}
+
+func (t *progressTracker) done(processed int) {
+	t.processed += processed
+	t.update()
+}
+
+func (t *progressTracker) failed() {
+	t.processed++
+	t.errors++
+	t.update()
+}
+
+func (t *progressTracker) update() {
+	if t.progress != nil {
+		t.progress.Update(t.total, t.processed, t.errors)
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pairing
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/astarte-platform/astarte-go/client"
+	"github.com/astarte-platform/astarte-go/internal/runner"
+	"github.com/astarte-platform/astarte-go/pairing/store"
+)
+
+// ErrIntrospectionMismatch is returned by Onboard when the introspection of the device does not (yet)
+// declare the expected interfaces, e.g. because it never connected to Astarte.
+var ErrIntrospectionMismatch = errors.New("The device introspection does not declare the expected interfaces")
+
+// OnboardingStep is a step of the onboarding of a device, see Onboard. Steps are completed in order.
+type OnboardingStep int
+
+const (
+	// NotOnboarded means no onboarding step has been completed yet.
+	NotOnboarded OnboardingStep = iota
+	// Registered means the device was registered and its credentials secret stored.
+	Registered
+	// AliasesSet means the aliases of the device were set.
+	AliasesSet
+	// GroupsJoined means the device was added to its groups.
+	GroupsJoined
+	// InterfacesVerified means the introspection of the device declares the expected interfaces.
+	// This is the last step: the device is fully onboarded.
+	InterfacesVerified
+)
+
+func (s OnboardingStep) String() string {
+	switch s {
+	case NotOnboarded:
+		return "not onboarded"
+	case Registered:
+		return "registered"
+	case AliasesSet:
+		return "aliases set"
+	case GroupsJoined:
+		return "groups joined"
+	case InterfacesVerified:
+		return "interfaces verified"
+	}
+	return fmt.Sprintf("unknown step %d", int(s))
+}
+
+// OnboardingStore persists the last completed OnboardingStep of each device, so that Onboard can resume.
+// GetStep returns NotOnboarded if no step is stored for the device.
+// Implementations must be safe for concurrent use.
+type OnboardingStore interface {
+	GetStep(realm, deviceID string) (OnboardingStep, error)
+	PutStep(realm, deviceID string, step OnboardingStep) error
+}
+
+type onboardingKey struct {
+	realm    string
+	deviceID string
+}
+
+// MemoryOnboardingStore is an OnboardingStore keeping steps in memory, mostly useful for tests.
+type MemoryOnboardingStore struct {
+	mu    sync.RWMutex
+	steps map[onboardingKey]OnboardingStep
+}
+
+// NewMemoryOnboardingStore returns an empty MemoryOnboardingStore.
+func NewMemoryOnboardingStore() *MemoryOnboardingStore {
+	return &MemoryOnboardingStore{steps: map[onboardingKey]OnboardingStep{}}
+}
+
+func (s *MemoryOnboardingStore) GetStep(realm, deviceID string) (OnboardingStep, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.steps[onboardingKey{realm: realm, deviceID: deviceID}], nil
+}
+
+func (s *MemoryOnboardingStore) PutStep(realm, deviceID string, step OnboardingStep) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.steps[onboardingKey{realm: realm, deviceID: deviceID}] = step
+	return nil
+}
+
+// DeviceOnboarding describes how a device is onboarded by Onboard.
+type DeviceOnboarding struct {
+	// Aliases maps alias tags to the aliases of the device.
+	Aliases map[string]string
+	// Groups are the groups the device joins. Groups which do not exist are created.
+	Groups []string
+	// Interfaces maps the names of the interfaces the device must declare in its introspection to their major version.
+	Interfaces map[string]int
+}
+
+// Onboard onboards deviceID in realm, going through the OnboardingSteps in order: it registers the device storing
+// its credentials in credentials, sets its aliases, adds it to its groups and verifies its introspection declares
+// the expected interfaces. After each step, the step is stored in steps, and Onboard resumes from the step following
+// the last one stored, so that a pipeline which failed or crashed midway can simply call Onboard again.
+// A step which completes but cannot be stored is run again on the next call: setting aliases and joining groups
+// are idempotent, while registering a device again fails if it already requested its credentials.
+// The introspection of a device is only available after it connected to Astarte: until then, Onboard returns
+// ErrIntrospectionMismatch and can be called again later.
+// Onboard returns the last completed step. c must be authorized to access the Pairing and AppEngine APIs of the realm.
+func Onboard(c *client.Client, credentials store.Store, steps OnboardingStore, realm, deviceID string,
+	onboarding DeviceOnboarding) (OnboardingStep, error) {
+	step, err := steps.GetStep(realm, deviceID)
+	if err != nil {
+		return NotOnboarded, err
+	}
+
+	for step < InterfacesVerified {
+		next := step + 1
+		if err := runOnboardingStep(c, credentials, realm, deviceID, onboarding, next); err != nil {
+			return step, fmt.Errorf("Onboarding step \"%s\" failed: %w", next, err)
+		}
+		if err := steps.PutStep(realm, deviceID, next); err != nil {
+			return step, fmt.Errorf("Onboarding step \"%s\" completed, but it could not be stored: %w", next, err)
+		}
+		step = next
+	}
+	return step, nil
+}
+
+func runOnboardingStep(c *client.Client, credentials store.Store, realm, deviceID string, onboarding DeviceOnboarding, step OnboardingStep) error {
+	switch step {
+	case Registered:
+		_, err := RegisterDevice(c, credentials, realm, deviceID)
+		return err
+	case AliasesSet:
+		return setAliases(c, realm, deviceID, onboarding.Aliases)
+	case GroupsJoined:
+		return joinGroups(c, realm, deviceID, onboarding.Groups)
+	case InterfacesVerified:
+		return verifyInterfaces(c, realm, deviceID, onboarding.Interfaces)
+	}
+	return fmt.Errorf("Unknown onboarding step %d", int(step))
+}
+
+func setAliases(c *client.Client, realm, deviceID string, aliases map[string]string) error {
+	tags := make([]string, 0, len(aliases))
+	for tag := range aliases {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	for _, tag := range tags {
+		aliasCall, err := c.AddDeviceAlias(realm, deviceID, tag, aliases[tag])
+		if err != nil {
+			return err
+		}
+		if _, err := runner.RunAndParse[any](c, aliasCall.Run); err != nil {
+			return fmt.Errorf("Cannot set alias %s: %w", tag, err)
+		}
+	}
+	return nil
+}
+
+func joinGroups(c *client.Client, realm, deviceID string, groups []string) error {
+	for _, group := range groups {
+		addCall, err := c.AddDeviceToGroup(realm, group, deviceID)
+		if err != nil {
+			return err
+		}
+		_, err = runner.RunAndParse[any](c, addCall.Run)
+		apiErr := &client.APIError{}
+		switch {
+		case err == nil:
+			continue
+		case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusConflict:
+			// The device already joined the group
+			continue
+		case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound:
+			createCall, err := c.CreateGroup(realm, group, []string{deviceID})
+			if err != nil {
+				return err
+			}
+			if _, err := runner.RunAndParse[any](c, createCall.Run); err != nil {
+				return fmt.Errorf("Cannot create group %s: %w", group, err)
+			}
+		default:
+			return fmt.Errorf("Cannot join group %s: %w", group, err)
+		}
+	}
+	return nil
+}
+
+func verifyInterfaces(c *client.Client, realm, deviceID string, expected map[string]int) error {
+	if len(expected) == 0 {
+		return nil
+	}
+	detailsCall, err := c.GetDeviceDetails(realm, deviceID, client.AstarteDeviceID)
+	if err != nil {
+		return err
+	}
+	details, err := runner.RunAndParse[client.DeviceDetails](c, detailsCall.Run)
+	if err != nil {
+		return err
+	}
+	missing := []string{}
+	for name, major := range expected {
+		if declared, ok := details.Introspection[name]; !ok || declared.Major != major {
+			missing = append(missing, fmt.Sprintf("%s v%d", name, major))
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("%w: missing %v", ErrIntrospectionMismatch, missing)
+	}
+	return nil
+}
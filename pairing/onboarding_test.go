@@ -0,0 +1,127 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pairing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/astarte-platform/astarte-go/client"
+	"github.com/astarte-platform/astarte-go/pairing/store"
+)
+
+const (
+	testInterfaceName = "org.astarte-platform.genericsensors.Values"
+	testNewGroup      = "new-group"
+	testExistingGroup = "existing-group"
+)
+
+type onboardingMock struct {
+	mu            sync.Mutex
+	registrations int
+	aliases       map[string]string
+	groups        map[string][]string
+	connected     bool
+}
+
+func (m *onboardingMock) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch {
+	case req.Method == http.MethodPost && req.URL.Path == fmt.Sprintf("/pairing/v1/%s/agent/devices", testRealmName):
+		m.registrations++
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"data": {"credentials_secret": "%s"}}`, testCredentialsSecret)
+	case req.Method == http.MethodPatch && req.URL.Path == fmt.Sprintf("/appengine/v1/%s/devices/%s", testRealmName, testDeviceID):
+		body := map[string]map[string]map[string]string{}
+		_ = json.NewDecoder(req.Body).Decode(&body)
+		for tag, alias := range body["data"]["aliases"] {
+			m.aliases[tag] = alias
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"data": {}}`))
+	case req.Method == http.MethodPost && req.URL.Path == fmt.Sprintf("/appengine/v1/%s/groups/%s/devices", testRealmName, testExistingGroup):
+		m.groups[testExistingGroup] = append(m.groups[testExistingGroup], testDeviceID)
+		w.WriteHeader(http.StatusCreated)
+	case req.Method == http.MethodPost && req.URL.Path == fmt.Sprintf("/appengine/v1/%s/groups", testRealmName):
+		body := map[string]map[string]any{}
+		_ = json.NewDecoder(req.Body).Decode(&body)
+		m.groups[fmt.Sprint(body["data"]["group_name"])] = []string{testDeviceID}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"data": {}}`))
+	case req.Method == http.MethodGet && req.URL.Path == fmt.Sprintf("/appengine/v1/%s/devices/%s", testRealmName, testDeviceID):
+		introspection := map[string]any{}
+		if m.connected {
+			introspection[testInterfaceName] = map[string]int{"major": 1, "minor": 0}
+		}
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"id": testDeviceID, "introspection": introspection}})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"errors": {"detail": "Not found"}}`))
+	}
+}
+
+func TestOnboard(t *testing.T) {
+	mock := &onboardingMock{aliases: map[string]string{}, groups: map[string][]string{}}
+	server := httptest.NewServer(mock)
+	defer server.Close()
+	c, err := client.New(client.WithBaseURL(server.URL), client.WithJWT("a JWT"), client.WithHTTPClient(server.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentials := store.NewMemoryStore()
+	steps := NewMemoryOnboardingStore()
+	onboarding := DeviceOnboarding{
+		Aliases:    map[string]string{"name": "a device"},
+		Groups:     []string{testExistingGroup, testNewGroup},
+		Interfaces: map[string]int{testInterfaceName: 1},
+	}
+
+	// The device never connected, so onboarding stops before verifying its interfaces
+	step, err := Onboard(c, credentials, steps, testRealmName, testDeviceID, onboarding)
+	if !errors.Is(err, ErrIntrospectionMismatch) || step != GroupsJoined {
+		t.Fatalf("Expected onboarding to stop at %s with ErrIntrospectionMismatch, found %s: %v", GroupsJoined, step, err)
+	}
+	if stored, _ := steps.GetStep(testRealmName, testDeviceID); stored != GroupsJoined {
+		t.Errorf("Unexpected stored step %s", stored)
+	}
+	if stored, _ := credentials.Get(testRealmName, testDeviceID); stored.CredentialsSecret != testCredentialsSecret {
+		t.Errorf("Unexpected stored credentials %v", stored)
+	}
+	if mock.aliases["name"] != "a device" || len(mock.groups[testExistingGroup]) != 1 || len(mock.groups[testNewGroup]) != 1 {
+		t.Errorf("Unexpected aliases %v or groups %v", mock.aliases, mock.groups)
+	}
+
+	// Once the device connects, onboarding resumes from the verification
+	mock.connected = true
+	step, err = Onboard(c, credentials, steps, testRealmName, testDeviceID, onboarding)
+	if err != nil || step != InterfacesVerified {
+		t.Fatalf("Unexpected step %s: %v", step, err)
+	}
+	if mock.registrations != 1 || len(mock.groups[testExistingGroup]) != 1 {
+		t.Errorf("Completed steps were run again: %d registrations, groups %v", mock.registrations, mock.groups)
+	}
+
+	// Onboarding an onboarded device is a no-op
+	if step, err := Onboard(c, credentials, steps, testRealmName, testDeviceID, onboarding); err != nil || step != InterfacesVerified {
+		t.Errorf("Unexpected step %s: %v", step, err)
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pairing provides helpers to provision devices through Astarte Pairing, keeping
+// their credentials in a store.Store.
+package pairing
+
+import (
+	"fmt"
+
+	"github.com/astarte-platform/astarte-go/client"
+	"github.com/astarte-platform/astarte-go/internal/runner"
+	"github.com/astarte-platform/astarte-go/pairing/store"
+)
+
+// RegisterDevice registers deviceID in realm, and stores the credentials secret returned by Astarte in s.
+// c must be authorized to access the Pairing API of the realm.
+func RegisterDevice(c *client.Client, s store.Store, realm, deviceID string) (string, error) {
+	registerCall, err := c.RegisterDevice(realm, deviceID)
+	if err != nil {
+		return "", err
+	}
+	credentialsSecret, err := runner.RunAndParse[string](c, registerCall.Run)
+	if err != nil {
+		return "", err
+	}
+	if err := s.Put(realm, deviceID, store.Credentials{CredentialsSecret: credentialsSecret}); err != nil {
+		return credentialsSecret, fmt.Errorf("Device registered, but its credentials secret could not be stored: %w", err)
+	}
+	return credentialsSecret, nil
+}
+
+// RenewCertificate obtains a new MQTT v1 certificate for deviceID signing csr, authenticating with
+// the credentials secret of the device found in s, and stores the certificate in s.
+// options are used to build the client authenticated as the device, and must specify the Astarte URLs
+// (e.g. client.WithBaseURL): the credentials secret is used as its token.
+func RenewCertificate(s store.Store, realm, deviceID, csr string, options ...client.Option) (string, error) {
+	credentials, err := s.Get(realm, deviceID)
+	if err != nil {
+		return "", err
+	}
+	c, err := client.New(append(options, client.WithJWT(credentials.CredentialsSecret))...)
+	if err != nil {
+		return "", err
+	}
+	certificateCall, err := c.ObtainNewMQTTv1CertificateForDevice(realm, deviceID, csr)
+	if err != nil {
+		return "", err
+	}
+	certificate, err := runner.RunAndParse[string](c, certificateCall.Run)
+	if err != nil {
+		return "", err
+	}
+	credentials.Certificate = certificate
+	if err := s.Put(realm, deviceID, credentials); err != nil {
+		return certificate, fmt.Errorf("Certificate obtained, but it could not be stored: %w", err)
+	}
+	return certificate, nil
+}
+
+// UnregisterDevice unregisters deviceID from realm, so that it can register again, and deletes its credentials from s.
+// c must be authorized to access the Pairing API of the realm.
+func UnregisterDevice(c *client.Client, s store.Store, realm, deviceID string) error {
+	unregisterCall, err := c.UnregisterDevice(realm, deviceID)
+	if err != nil {
+		return err
+	}
+	if _, err := runner.RunAndParse[any](c, unregisterCall.Run); err != nil {
+		return err
+	}
+	return s.Delete(realm, deviceID)
+}
@@ -0,0 +1,92 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pairing
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/astarte-platform/astarte-go/client"
+	"github.com/astarte-platform/astarte-go/pairing/store"
+)
+
+const (
+	testRealmName         = "test"
+	testDeviceID          = "glO6LullTKmwxebForU-eg"
+	testCredentialsSecret = "a credentials secret"
+	testCertificate       = "a certificate"
+)
+
+func testPairingMock(w http.ResponseWriter, req *http.Request) {
+	switch {
+	case req.Method == http.MethodPost && req.URL.Path == fmt.Sprintf("/pairing/v1/%s/agent/devices", testRealmName):
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"data": {"credentials_secret": "%s"}}`, testCredentialsSecret)
+	case req.Method == http.MethodDelete && req.URL.Path == fmt.Sprintf("/pairing/v1/%s/agent/devices/%s", testRealmName, testDeviceID):
+		w.WriteHeader(http.StatusNoContent)
+	case req.Method == http.MethodPost && req.URL.Path == fmt.Sprintf("/pairing/v1/%s/devices/%s/protocols/astarte_mqtt_v1/credentials", testRealmName, testDeviceID):
+		if req.Header.Get("Authorization") != "Bearer "+testCredentialsSecret {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"errors": {"detail": "Unauthorized"}}`))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"data": {"client_crt": "%s"}}`, testCertificate)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"errors": {"detail": "Not found"}}`))
+	}
+}
+
+func TestDeviceProvisioning(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(testPairingMock))
+	defer server.Close()
+	c, err := client.New(client.WithBaseURL(server.URL), client.WithJWT("a JWT"), client.WithHTTPClient(server.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := store.NewMemoryStore()
+
+	if _, err := RenewCertificate(s, testRealmName, testDeviceID, "a CSR", client.WithBaseURL(server.URL)); err != store.ErrCredentialsNotFound {
+		t.Errorf("Expected ErrCredentialsNotFound for an unregistered device, found %v", err)
+	}
+
+	credentialsSecret, err := RegisterDevice(c, s, testRealmName, testDeviceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if credentialsSecret != testCredentialsSecret {
+		t.Errorf("Unexpected credentials secret: %s", credentialsSecret)
+	}
+
+	certificate, err := RenewCertificate(s, testRealmName, testDeviceID, "a CSR",
+		client.WithBaseURL(server.URL), client.WithHTTPClient(server.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentials, _ := s.Get(testRealmName, testDeviceID)
+	if certificate != testCertificate || credentials.Certificate != testCertificate || credentials.CredentialsSecret != testCredentialsSecret {
+		t.Errorf("Unexpected credentials: %s, %+v", certificate, credentials)
+	}
+
+	if err := UnregisterDevice(c, s, testRealmName, testDeviceID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(testRealmName, testDeviceID); err != store.ErrCredentialsNotFound {
+		t.Errorf("Expected credentials to be deleted, found %v", err)
+	}
+}
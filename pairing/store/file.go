@@ -0,0 +1,111 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// FileStore is a Store keeping the credentials of each device in a JSON file,
+// named <dir>/<realm>/<device ID>.json and readable only by its owner.
+type FileStore struct {
+	mu  sync.RWMutex
+	dir string
+}
+
+// NewFileStore returns a FileStore keeping credentials in dir, which is created if it does not exist.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) Get(realm, deviceID string) (Credentials, error) {
+	path, err := s.path(realm, deviceID)
+	if err != nil {
+		return Credentials{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Credentials{}, ErrCredentialsNotFound
+	} else if err != nil {
+		return Credentials{}, err
+	}
+	credentials := Credentials{}
+	err = json.Unmarshal(b, &credentials)
+	return credentials, err
+}
+
+func (s *FileStore) Put(realm, deviceID string, credentials Credentials) error {
+	path, err := s.path(realm, deviceID)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(credentials)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	// Write to a temporary file first, so that a failure never leaves a truncated file behind
+	tmp, err := os.CreateTemp(filepath.Dir(path), deviceID+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *FileStore) Delete(realm, deviceID string) error {
+	path, err := s.path(realm, deviceID)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path returns the path of the file for a device, making sure realm and deviceID can't escape s.dir.
+func (s *FileStore) path(realm, deviceID string) (string, error) {
+	for _, segment := range []string{realm, deviceID} {
+		if segment == "" || segment == "." || segment == ".." || strings.ContainsAny(segment, `/\`) {
+			return "", ErrInvalidKey
+		}
+	}
+	return filepath.Join(s.dir, realm, deviceID+".json"), nil
+}
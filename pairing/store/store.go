@@ -0,0 +1,83 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package store persists the credentials which Astarte Pairing returns for devices, i.e. credentials
+// secrets and certificates, keyed by realm and device ID.
+package store
+
+import (
+	"errors"
+	"sync"
+)
+
+var (
+	ErrCredentialsNotFound = errors.New("No credentials stored for the device")
+	ErrInvalidKey          = errors.New("Invalid realm or device ID")
+)
+
+// Credentials are the credentials of a device obtained from Astarte Pairing.
+type Credentials struct {
+	CredentialsSecret string `json:"credentials_secret,omitempty"`
+	// Certificate is the last PEM encoded client certificate obtained by the device, if any.
+	Certificate string `json:"certificate,omitempty"`
+}
+
+// Store persists device Credentials. Get returns ErrCredentialsNotFound if no credentials are stored
+// for the device, while Delete does not fail in that case.
+// Implementations must be safe for concurrent use.
+type Store interface {
+	Get(realm, deviceID string) (Credentials, error)
+	Put(realm, deviceID string, credentials Credentials) error
+	Delete(realm, deviceID string) error
+}
+
+type key struct {
+	realm    string
+	deviceID string
+}
+
+// MemoryStore is a Store keeping credentials in memory, mostly useful for tests.
+type MemoryStore struct {
+	mu          sync.RWMutex
+	credentials map[key]Credentials
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{credentials: map[key]Credentials{}}
+}
+
+func (s *MemoryStore) Get(realm, deviceID string) (Credentials, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	credentials, ok := s.credentials[key{realm: realm, deviceID: deviceID}]
+	if !ok {
+		return Credentials{}, ErrCredentialsNotFound
+	}
+	return credentials, nil
+}
+
+func (s *MemoryStore) Put(realm, deviceID string, credentials Credentials) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.credentials[key{realm: realm, deviceID: deviceID}] = credentials
+	return nil
+}
+
+func (s *MemoryStore) Delete(realm, deviceID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.credentials, key{realm: realm, deviceID: deviceID})
+	return nil
+}
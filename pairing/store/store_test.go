@@ -0,0 +1,87 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	testRealmName = "test"
+	testDeviceID  = "glO6LullTKmwxebForU-eg"
+)
+
+func testStore(t *testing.T, s Store) {
+	if _, err := s.Get(testRealmName, testDeviceID); err != ErrCredentialsNotFound {
+		t.Errorf("Expected ErrCredentialsNotFound, found %v", err)
+	}
+
+	credentials := Credentials{CredentialsSecret: "a secret", Certificate: "a certificate"}
+	if err := s.Put(testRealmName, testDeviceID, credentials); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := s.Get(testRealmName, testDeviceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored != credentials {
+		t.Errorf("Unexpected credentials: %+v", stored)
+	}
+	if _, err := s.Get("another", testDeviceID); err != ErrCredentialsNotFound {
+		t.Errorf("Expected ErrCredentialsNotFound for another realm, found %v", err)
+	}
+
+	if err := s.Delete(testRealmName, testDeviceID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(testRealmName, testDeviceID); err != ErrCredentialsNotFound {
+		t.Errorf("Expected ErrCredentialsNotFound after deletion, found %v", err)
+	}
+	if err := s.Delete(testRealmName, testDeviceID); err != nil {
+		t.Errorf("Unexpected error deleting missing credentials: %v", err)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
+
+func TestFileStore(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, s)
+
+	if err := s.Put(testRealmName, testDeviceID, Credentials{CredentialsSecret: "a secret"}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filepath.Join(dir, testRealmName, testDeviceID+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("Unexpected file permissions: %v", info.Mode().Perm())
+	}
+
+	for _, deviceID := range []string{"", "..", "../escape", `a\b`} {
+		if err := s.Put(testRealmName, deviceID, Credentials{}); err != ErrInvalidKey {
+			t.Errorf("Expected ErrInvalidKey for %q, found %v", deviceID, err)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package policies provides types to parse, validate and build Astarte trigger delivery policies.
+package policies
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	// MaxNameLength is the maximum length of the name of a trigger delivery policy.
+	MaxNameLength = 128
+	// MinHTTPErrorCode and MaxHTTPErrorCode are the bounds of the HTTP status codes an error handler can match.
+	MinHTTPErrorCode = 400
+	MaxHTTPErrorCode = 599
+)
+
+// AstarteErrorKeyword represents a class of HTTP errors an error handler can match
+type AstarteErrorKeyword string
+
+const (
+	AnyError    AstarteErrorKeyword = "any_error"
+	ClientError AstarteErrorKeyword = "client_error"
+	ServerError AstarteErrorKeyword = "server_error"
+)
+
+// IsValid returns an error if AstarteErrorKeyword does not represent a valid AstarteErrorKeyword
+func (k AstarteErrorKeyword) IsValid() error {
+	switch k {
+	case AnyError, ClientError, ServerError:
+		return nil
+	}
+	return fmt.Errorf("'%v' is not a valid AstarteErrorKeyword", k)
+}
+
+func (k AstarteErrorKeyword) codes() []int {
+	from, to := MinHTTPErrorCode, MaxHTTPErrorCode
+	switch k {
+	case ClientError:
+		to = 499
+	case ServerError:
+		from = 500
+	}
+	codes := []int{}
+	for code := from; code <= to; code++ {
+		codes = append(codes, code)
+	}
+	return codes
+}
+
+// AstarteErrorStrategy represents what happens to an event whose delivery failed
+type AstarteErrorStrategy string
+
+const (
+	// Discard drops the event
+	Discard AstarteErrorStrategy = "discard"
+	// Retry delivers the event again, up to the policy RetryTimes
+	Retry AstarteErrorStrategy = "retry"
+)
+
+// IsValid returns an error if AstarteErrorStrategy does not represent a valid AstarteErrorStrategy
+func (s AstarteErrorStrategy) IsValid() error {
+	switch s {
+	case Discard, Retry:
+		return nil
+	}
+	return fmt.Errorf("'%v' is not a valid AstarteErrorStrategy", s)
+}
+
+// AstarteErrorRange represents the errors an error handler matches: either a class of errors
+// (Keyword) or a list of HTTP status codes (Codes). It is encoded as a string or as a list of integers.
+type AstarteErrorRange struct {
+	Keyword AstarteErrorKeyword
+	Codes   []int
+}
+
+// MarshalJSON marshals an AstarteErrorRange to a json string or to a list of integers
+func (r AstarteErrorRange) MarshalJSON() ([]byte, error) {
+	if r.Keyword != "" {
+		return json.Marshal(r.Keyword)
+	}
+	return json.Marshal(r.Codes)
+}
+
+// UnmarshalJSON unmarshals a json string or a list of integers to an AstarteErrorRange
+func (r *AstarteErrorRange) UnmarshalJSON(b []byte) error {
+	var keyword AstarteErrorKeyword
+	if err := json.Unmarshal(b, &keyword); err == nil {
+		*r = AstarteErrorRange{Keyword: keyword}
+		return keyword.IsValid()
+	}
+	codes := []int{}
+	if err := json.Unmarshal(b, &codes); err != nil {
+		return fmt.Errorf("'%s' is neither an error keyword nor a list of error codes", b)
+	}
+	*r = AstarteErrorRange{Codes: codes}
+	return nil
+}
+
+func (r AstarteErrorRange) codes() []int {
+	if r.Keyword != "" {
+		return r.Keyword.codes()
+	}
+	return r.Codes
+}
+
+func (r AstarteErrorRange) String() string {
+	if r.Keyword != "" {
+		return string(r.Keyword)
+	}
+	return fmt.Sprint(r.Codes)
+}
+
+// AstarteErrorHandler represents how a trigger delivery policy handles the errors in On
+type AstarteErrorHandler struct {
+	On       AstarteErrorRange    `json:"on"`
+	Strategy AstarteErrorStrategy `json:"strategy"`
+}
+
+// AstarteTriggerDeliveryPolicy represents an Astarte Trigger Delivery Policy
+type AstarteTriggerDeliveryPolicy struct {
+	Name            string                `json:"name"`
+	ErrorHandlers   []AstarteErrorHandler `json:"error_handlers"`
+	MaximumCapacity int                   `json:"maximum_capacity"`
+	RetryTimes      int                   `json:"retry_times,omitempty"`
+	// EventTTL is the time to live of events in the policy queue, in seconds. 0 means that events do not expire
+	EventTTL      int `json:"event_ttl,omitempty"`
+	PrefetchCount int `json:"prefetch_count,omitempty"`
+}
+
+// Validate returns an error if the policy would be rejected by Astarte.
+func (p AstarteTriggerDeliveryPolicy) Validate() error {
+	switch {
+	case p.Name == "":
+		return errors.New("Invalid policy: name must be set")
+	case len(p.Name) > MaxNameLength:
+		return fmt.Errorf("Invalid policy: name must be at most %d characters long", MaxNameLength)
+	case strings.HasPrefix(p.Name, "@"):
+		return errors.New("Invalid policy: names starting with @ are reserved")
+	case p.MaximumCapacity <= 0:
+		return errors.New("Invalid policy: maximum_capacity must be positive")
+	case p.EventTTL < 0:
+		return errors.New("Invalid policy: event_ttl must not be negative")
+	case p.PrefetchCount < 0:
+		return errors.New("Invalid policy: prefetch_count must not be negative")
+	case len(p.ErrorHandlers) == 0:
+		return errors.New("Invalid policy: at least an error handler must be set")
+	}
+
+	handled := map[int]AstarteErrorRange{}
+	retries := false
+	for _, handler := range p.ErrorHandlers {
+		if err := handler.Strategy.IsValid(); err != nil {
+			return fmt.Errorf("Invalid policy: %w", err)
+		}
+		retries = retries || handler.Strategy == Retry
+		if handler.On.Keyword != "" {
+			if err := handler.On.Keyword.IsValid(); err != nil {
+				return fmt.Errorf("Invalid policy: %w", err)
+			}
+		} else if len(handler.On.Codes) == 0 {
+			return errors.New("Invalid policy: error handlers must match at least an error code")
+		}
+		for _, code := range handler.On.codes() {
+			if code < MinHTTPErrorCode || code > MaxHTTPErrorCode {
+				return fmt.Errorf("Invalid policy: %d is not an HTTP error code", code)
+			}
+			if other, ok := handled[code]; ok {
+				return fmt.Errorf("Invalid policy: error handlers on %v and %v overlap", other, handler.On)
+			}
+			handled[code] = handler.On
+		}
+	}
+
+	switch {
+	case retries && p.RetryTimes <= 0:
+		return errors.New("Invalid policy: retry_times must be positive when an error handler retries")
+	case !retries && p.RetryTimes != 0:
+		return errors.New("Invalid policy: retry_times must not be set when no error handler retries")
+	}
+	return nil
+}
+
+type policyProvider interface {
+	string | []byte
+}
+
+// ParsePolicyFrom is a convenience function to call ParsePolicy with an input.
+// The input can be either a string, that is interpreted as a file path, or a byteslice.
+func ParsePolicyFrom[T policyProvider](provider T) (AstarteTriggerDeliveryPolicy, error) {
+	switch p := any(provider).(type) {
+	case string:
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return AstarteTriggerDeliveryPolicy{}, err
+		}
+		return ParsePolicy(b)
+	case []byte:
+		return ParsePolicy(p)
+	default:
+		return AstarteTriggerDeliveryPolicy{}, errors.New("Provided value cannot be used as an Astarte Trigger Delivery Policy")
+	}
+}
+
+// ParsePolicy parses and validates a trigger delivery policy from its JSON content.
+func ParsePolicy(policyContent []byte) (AstarteTriggerDeliveryPolicy, error) {
+	policy := AstarteTriggerDeliveryPolicy{}
+	if err := json.Unmarshal(policyContent, &policy); err != nil {
+		return policy, err
+	}
+	return policy, policy.Validate()
+}
@@ -0,0 +1,99 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policies
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestBuilder(t *testing.T) {
+	policy, err := NewBuilder("webhook_policy").
+		RetryOnServerErrors(5).
+		DiscardOnClientErrors().
+		WithMaximumCapacity(500).
+		WithEventTTL(3600).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"name":"webhook_policy","error_handlers":[{"on":"server_error","strategy":"retry"},` +
+		`{"on":"client_error","strategy":"discard"}],"maximum_capacity":500,"retry_times":5,"event_ttl":3600}`
+	if string(b) != expected {
+		t.Errorf("Unexpected policy JSON: %s", b)
+	}
+
+	parsed, err := ParsePolicy(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.ErrorHandlers[0].On.Keyword != ServerError || parsed.RetryTimes != 5 {
+		t.Errorf("Unexpected parsed policy: %+v", parsed)
+	}
+}
+
+func TestBuilderCopiesCodes(t *testing.T) {
+	codes := []int{502, 503}
+	builder := NewBuilder("p").RetryOnCodes(3, codes...)
+	codes[0] = 302
+	policy, err := builder.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy.ErrorHandlers[0].On.Codes[1] = 304
+	other, err := builder.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(other.ErrorHandlers[0].On.Codes) != "[502 503]" {
+		t.Errorf("Unexpected codes: %v", other.ErrorHandlers[0].On.Codes)
+	}
+
+	// A zero event TTL is unset
+	if _, err := NewBuilder("p").DiscardOnAnyError().WithEventTTL(0).Build(); err != nil {
+		t.Errorf("Unexpected error for a zero event TTL: %v", err)
+	}
+}
+
+func TestInvalidPolicies(t *testing.T) {
+	invalid := map[string]*Builder{
+		"overlapping handlers":   NewBuilder("p").DiscardOnAnyError().RetryOnCodes(3, 503),
+		"no handlers":            NewBuilder("p"),
+		"reserved name":          NewBuilder("@p").DiscardOnAnyError(),
+		"no retry times":         NewBuilder("p").RetryOnServerErrors(0),
+		"not an error code":      NewBuilder("p").RetryOnCodes(3, 302),
+		"non positive capacity":  NewBuilder("p").DiscardOnAnyError().WithMaximumCapacity(0),
+		"retry without handlers": NewBuilder("p").DiscardOnClientErrors(),
+		"negative event TTL":     NewBuilder("p").DiscardOnAnyError().WithEventTTL(-1),
+	}
+	invalid["retry without handlers"].policy.RetryTimes = 3
+	for name, builder := range invalid {
+		if _, err := builder.Build(); err == nil {
+			t.Errorf("Expected an error for %s, found nil", name)
+		}
+	}
+
+	if _, err := ParsePolicy([]byte(`{"name": "p", "maximum_capacity": 10, "error_handlers": [{"on": [404, 410], "strategy": "discard"}]}`)); err != nil {
+		t.Errorf("Unexpected error for a policy with error codes: %v", err)
+	}
+	if _, err := ParsePolicy([]byte(`{"name": "p", "maximum_capacity": 10, "error_handlers": [{"on": "some_error", "strategy": "discard"}]}`)); err == nil {
+		t.Error("Expected an error for an invalid error keyword, found nil")
+	}
+}
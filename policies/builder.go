@@ -0,0 +1,94 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policies
+
+// DefaultMaximumCapacity is the maximum capacity of policies built by a Builder, unless
+// WithMaximumCapacity is used.
+const DefaultMaximumCapacity = 100
+
+// Builder assembles an AstarteTriggerDeliveryPolicy from presets, e.g.:
+//
+//	policy, err := policies.NewBuilder("webhook_policy").
+//		RetryOnServerErrors(5).
+//		DiscardOnClientErrors().
+//		WithEventTTL(3600).
+//		Build()
+type Builder struct {
+	policy AstarteTriggerDeliveryPolicy
+}
+
+// NewBuilder returns a Builder for a policy named name.
+func NewBuilder(name string) *Builder {
+	return &Builder{policy: AstarteTriggerDeliveryPolicy{Name: name, MaximumCapacity: DefaultMaximumCapacity}}
+}
+
+// On adds an error handler applying strategy to errors in errorRange.
+func (b *Builder) On(errorRange AstarteErrorRange, strategy AstarteErrorStrategy) *Builder {
+	b.policy.ErrorHandlers = append(b.policy.ErrorHandlers, AstarteErrorHandler{On: errorRange, Strategy: strategy})
+	return b
+}
+
+// RetryOnServerErrors retries events whose delivery failed with a 5xx status code, up to times times.
+func (b *Builder) RetryOnServerErrors(times int) *Builder {
+	b.policy.RetryTimes = times
+	return b.On(AstarteErrorRange{Keyword: ServerError}, Retry)
+}
+
+// RetryOnCodes retries events whose delivery failed with one of codes, up to times times.
+func (b *Builder) RetryOnCodes(times int, codes ...int) *Builder {
+	b.policy.RetryTimes = times
+	return b.On(AstarteErrorRange{Codes: append([]int{}, codes...)}, Retry)
+}
+
+// DiscardOnClientErrors discards events whose delivery failed with a 4xx status code.
+func (b *Builder) DiscardOnClientErrors() *Builder {
+	return b.On(AstarteErrorRange{Keyword: ClientError}, Discard)
+}
+
+// DiscardOnAnyError discards events whose delivery failed with any error.
+func (b *Builder) DiscardOnAnyError() *Builder {
+	return b.On(AstarteErrorRange{Keyword: AnyError}, Discard)
+}
+
+// WithMaximumCapacity sets how many events the policy queue can hold.
+func (b *Builder) WithMaximumCapacity(capacity int) *Builder {
+	b.policy.MaximumCapacity = capacity
+	return b
+}
+
+// WithEventTTL sets for how many seconds events are kept in the policy queue.
+func (b *Builder) WithEventTTL(seconds int) *Builder {
+	b.policy.EventTTL = seconds
+	return b
+}
+
+// WithPrefetchCount sets how many events are delivered concurrently.
+func (b *Builder) WithPrefetchCount(prefetchCount int) *Builder {
+	b.policy.PrefetchCount = prefetchCount
+	return b
+}
+
+// Build returns the assembled policy, or an error if it would be rejected by Astarte.
+func (b *Builder) Build() (AstarteTriggerDeliveryPolicy, error) {
+	policy := b.policy
+	policy.ErrorHandlers = make([]AstarteErrorHandler, len(b.policy.ErrorHandlers))
+	for i, handler := range b.policy.ErrorHandlers {
+		if handler.On.Codes != nil {
+			handler.On.Codes = append([]int{}, handler.On.Codes...)
+		}
+		policy.ErrorHandlers[i] = handler
+	}
+	return policy, policy.Validate()
+}
@@ -0,0 +1,353 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sqlexport converts datastream values retrieved from Astarte into rows suitable for database/sql, e.g. to
+// load them into Postgres or TimescaleDB. Rows share the same columns for all interfaces (see Columns): each value
+// is stored in the column matching the type of its mapping, while the other value columns are NULL.
+package sqlexport
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/client"
+	"github.com/astarte-platform/astarte-go/interfaces"
+)
+
+// Columns are the names of the columns of a Row, in the order of Row.Values.
+var Columns = []string{
+	"device_id", "interface", "path", "ts", "reception_ts",
+	"value_double", "value_integer", "value_boolean", "value_text", "value_timestamp", "value_binary", "value_json",
+}
+
+// Row is a single datastream value. Values of object aggregated interfaces are split in a Row for each key.
+type Row struct {
+	DeviceID           string
+	Interface          string
+	Path               string
+	Timestamp          time.Time
+	ReceptionTimestamp sql.NullTime
+	// ValueDouble holds "double" values.
+	ValueDouble sql.NullFloat64
+	// ValueInteger holds "integer" and "longinteger" values.
+	ValueInteger sql.NullInt64
+	// ValueBoolean holds "boolean" values.
+	ValueBoolean sql.NullBool
+	// ValueText holds "string" values.
+	ValueText sql.NullString
+	// ValueTimestamp holds "datetime" values.
+	ValueTimestamp sql.NullTime
+	// ValueBinary holds "binaryblob" values, decoded.
+	ValueBinary []byte
+	// ValueJSON holds array values, encoded as JSON.
+	ValueJSON sql.NullString
+}
+
+// Values returns the values of the columns of r, in the order of Columns.
+func (r Row) Values() []any {
+	return []any{
+		r.DeviceID, r.Interface, r.Path, r.Timestamp, r.ReceptionTimestamp,
+		r.ValueDouble, r.ValueInteger, r.ValueBoolean, r.ValueText, r.ValueTimestamp, r.ValueBinary, r.ValueJSON,
+	}
+}
+
+// Inserter stores batches of rows, e.g. in a database table.
+type Inserter interface {
+	InsertRows(ctx context.Context, rows []Row) error
+}
+
+// Execer is implemented by *sql.DB, *sql.Tx and *sql.Conn.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// PlaceholderStyle is the style of the query placeholders of a database driver.
+type PlaceholderStyle int
+
+const (
+	// QuestionMark placeholders (?) are used e.g. by MySQL and SQLite.
+	QuestionMark PlaceholderStyle = iota
+	// Dollar placeholders ($1, $2...) are used e.g. by Postgres.
+	Dollar
+)
+
+// MaxQueryParameters is the largest number of parameters a statement can have in Postgres and MySQL.
+const MaxQueryParameters = 65535
+
+// SQLInserter is an Inserter running multi-row INSERTs for each batch. The table must have the Columns, and
+// its name is used verbatim in the query, hence it must never come from untrusted input.
+type SQLInserter struct {
+	DB          Execer
+	Table       string
+	Placeholder PlaceholderStyle
+	// MaxRowsPerStatement is the largest number of rows inserted by a single statement. If it is not positive,
+	// it defaults to the largest number of rows which fit in MaxQueryParameters.
+	MaxRowsPerStatement int
+}
+
+// InsertRows inserts rows in the table, splitting them in statements of at most MaxRowsPerStatement rows.
+// Statements are run in order, and the first failing one stops the insertion: use a *sql.Tx as DB to insert
+// all rows or none.
+func (s SQLInserter) InsertRows(ctx context.Context, rows []Row) error {
+	maxRows := s.MaxRowsPerStatement
+	if maxRows <= 0 {
+		maxRows = MaxQueryParameters / len(Columns)
+	}
+	for len(rows) > 0 {
+		chunk := rows[:min(maxRows, len(rows))]
+		rows = rows[len(chunk):]
+		query, args := s.insertQuery(chunk)
+		if _, err := s.DB.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s SQLInserter) insertQuery(rows []Row) (string, []any) {
+	b := strings.Builder{}
+	fmt.Fprintf(&b, "INSERT INTO %s (%s) VALUES ", s.Table, strings.Join(Columns, ", "))
+	args := make([]any, 0, len(rows)*len(Columns))
+	placeholders := make([]string, len(Columns))
+	for i, row := range rows {
+		for j := range placeholders {
+			placeholders[j] = "?"
+			if s.Placeholder == Dollar {
+				placeholders[j] = "$" + strconv.Itoa(len(args)+j+1)
+			}
+		}
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString("(" + strings.Join(placeholders, ", ") + ")")
+		args = append(args, row.Values()...)
+	}
+	return b.String(), args
+}
+
+// Export retrieves all the pages of p, a datastream paginator built by c for deviceID and the interfacePath of
+// astarteInterface, and stores each page as a batch with inserter. It returns the number of stored rows.
+// Export stops between pages as soon as ctx is done, returning the error of ctx.
+func Export(ctx context.Context, c *client.Client, p client.Paginator, deviceID string, astarteInterface interfaces.AstarteInterface,
+	interfacePath string, inserter Inserter) (int, error) {
+	converter := NewConverter(deviceID, astarteInterface)
+	exported := 0
+	for p.HasNextPage() {
+		if err := ctx.Err(); err != nil {
+			return exported, err
+		}
+		req, err := p.GetNextPage()
+		if err != nil {
+			return exported, err
+		}
+		res, err := req.Run(c)
+		if err != nil {
+			return exported, err
+		}
+		data, err := res.Parse()
+		if err != nil {
+			return exported, err
+		}
+		rows, err := converter.Rows(interfacePath, data)
+		if err != nil {
+			return exported, err
+		}
+		if err := inserter.InsertRows(ctx, rows); err != nil {
+			return exported, err
+		}
+		exported += len(rows)
+	}
+	return exported, nil
+}
+
+// Converter converts the datastream values of a device on an interface into rows.
+type Converter struct {
+	deviceID         string
+	astarteInterface interfaces.AstarteInterface
+	compiled         *interfaces.CompiledInterface
+}
+
+// NewConverter returns a Converter for the values of deviceID on astarteInterface.
+func NewConverter(deviceID string, astarteInterface interfaces.AstarteInterface) *Converter {
+	return &Converter{deviceID: deviceID, astarteInterface: astarteInterface, compiled: interfaces.Compile(astarteInterface)}
+}
+
+// Rows converts data, as returned by parsing a page of a datastream paginator or a datastream snapshot, into rows.
+// basePath is the path the data was requested for: keys of maps in data are relative to it.
+// data can be any of []DatastreamIndividualValue, map[string]DatastreamIndividualValue, []DatastreamObjectValue,
+// map[string][]DatastreamObjectValue, map[string]DatastreamObjectValue, or a map[string]any of
+// DatastreamIndividualValue.
+func (c *Converter) Rows(basePath string, data any) ([]Row, error) {
+	basePath = strings.TrimSuffix(basePath, "/")
+	rows := []Row{}
+	var err error
+	switch values := data.(type) {
+	case []client.DatastreamIndividualValue:
+		for _, v := range values {
+			if rows, err = c.appendIndividual(rows, basePath, v); err != nil {
+				return nil, err
+			}
+		}
+	case map[string]client.DatastreamIndividualValue:
+		for path, v := range values {
+			if rows, err = c.appendIndividual(rows, basePath+path, v); err != nil {
+				return nil, err
+			}
+		}
+	case map[string]any:
+		for path, v := range values {
+			individualValue, ok := v.(client.DatastreamIndividualValue)
+			if !ok {
+				return nil, fmt.Errorf("Unexpected value of type %T on %s", v, basePath+path)
+			}
+			if rows, err = c.appendIndividual(rows, basePath+path, individualValue); err != nil {
+				return nil, err
+			}
+		}
+	case []client.DatastreamObjectValue:
+		for _, v := range values {
+			if rows, err = c.appendObject(rows, basePath, v); err != nil {
+				return nil, err
+			}
+		}
+	case map[string][]client.DatastreamObjectValue:
+		for path, objectValues := range values {
+			for _, v := range objectValues {
+				if rows, err = c.appendObject(rows, basePath+path, v); err != nil {
+					return nil, err
+				}
+			}
+		}
+	case map[string]client.DatastreamObjectValue:
+		for path, v := range values {
+			if rows, err = c.appendObject(rows, basePath+path, v); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("Unsupported datastream data of type %T", data)
+	}
+	return rows, nil
+}
+
+func (c *Converter) appendIndividual(rows []Row, path string, v client.DatastreamIndividualValue) ([]Row, error) {
+	row, err := c.row(path, v.Value, v.Timestamp, v.ReceptionTimestamp)
+	if err != nil {
+		return nil, err
+	}
+	return append(rows, row), nil
+}
+
+func (c *Converter) appendObject(rows []Row, path string, v client.DatastreamObjectValue) ([]Row, error) {
+	for _, key := range v.Values.Keys() {
+		value, _ := v.Values.Get(key)
+		row, err := c.row(path+"/"+key, value, v.Timestamp, v.ReceptionTimestamp)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (c *Converter) row(path string, value any, timestamp, receptionTimestamp time.Time) (Row, error) {
+	mapping, err := c.compiled.MappingFromPath(path)
+	if err != nil {
+		return Row{}, err
+	}
+	row := Row{
+		DeviceID:           c.deviceID,
+		Interface:          c.astarteInterface.Name,
+		Path:               path,
+		Timestamp:          timestamp,
+		ReceptionTimestamp: sql.NullTime{Time: receptionTimestamp, Valid: !receptionTimestamp.IsZero()},
+	}
+	if value == nil {
+		return row, nil
+	}
+	if err := setValue(&row, mapping.Type, value); err != nil {
+		return Row{}, fmt.Errorf("Cannot convert value on %s: %w", path, err)
+	}
+	return row, nil
+}
+
+// nolint:gocyclo
+func setValue(row *Row, mappingType interfaces.AstarteMappingType, value any) error {
+	switch mappingType {
+	case interfaces.Double:
+		// Values decoded with client.WithPreciseNumbers are json.Number
+		v, err := client.ValueAsFloat64(value)
+		if err != nil {
+			return err
+		}
+		row.ValueDouble = sql.NullFloat64{Float64: v, Valid: true}
+	case interfaces.Integer, interfaces.LongInteger:
+		// Long integers beyond client.MaxSafeInteger decoded as float64 might have been rounded, and are rejected
+		v, err := client.ValueAsInt64(value)
+		if err != nil {
+			return err
+		}
+		row.ValueInteger = sql.NullInt64{Int64: v, Valid: true}
+	case interfaces.Boolean:
+		v, ok := value.(bool)
+		if !ok {
+			return unexpectedValue(value)
+		}
+		row.ValueBoolean = sql.NullBool{Bool: v, Valid: true}
+	case interfaces.String:
+		v, ok := value.(string)
+		if !ok {
+			return unexpectedValue(value)
+		}
+		row.ValueText = sql.NullString{String: v, Valid: true}
+	case interfaces.DateTime:
+		v, ok := value.(string)
+		if !ok {
+			return unexpectedValue(value)
+		}
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return err
+		}
+		row.ValueTimestamp = sql.NullTime{Time: t, Valid: true}
+	case interfaces.BinaryBlob:
+		v, ok := value.(string)
+		if !ok {
+			return unexpectedValue(value)
+		}
+		b, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return err
+		}
+		row.ValueBinary = b
+	default:
+		// Arrays are stored as JSON
+		b, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		row.ValueJSON = sql.NullString{String: string(b), Valid: true}
+	}
+	return nil
+}
+
+func unexpectedValue(value any) error {
+	return fmt.Errorf("Unexpected value %v of type %T", value, value)
+}
@@ -0,0 +1,316 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlexport
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/client"
+	"github.com/astarte-platform/astarte-go/interfaces"
+)
+
+const (
+	testRealmName = "test"
+	testDeviceID  = "glO6LullTKmwxebForU-eg"
+)
+
+var (
+	testIndividualInterface = interfaces.AstarteInterface{
+		Name:         "org.astarte.Sensors",
+		MajorVersion: 1,
+		Type:         interfaces.DatastreamType,
+		Ownership:    interfaces.DeviceOwnership,
+		Aggregation:  interfaces.IndividualAggregation,
+		Mappings: []interfaces.AstarteInterfaceMapping{
+			{Endpoint: "/%{sensor}/value", Type: interfaces.Double},
+			{Endpoint: "/%{sensor}/count", Type: interfaces.LongInteger},
+			{Endpoint: "/%{sensor}/name", Type: interfaces.String},
+			{Endpoint: "/%{sensor}/since", Type: interfaces.DateTime},
+			{Endpoint: "/%{sensor}/raw", Type: interfaces.BinaryBlob},
+			{Endpoint: "/%{sensor}/samples", Type: interfaces.IntegerArray},
+		},
+	}
+	testObjectInterface = interfaces.AstarteInterface{
+		Name:         "org.astarte.Readings",
+		MajorVersion: 1,
+		Type:         interfaces.DatastreamType,
+		Ownership:    interfaces.DeviceOwnership,
+		Aggregation:  interfaces.ObjectAggregation,
+		Mappings: []interfaces.AstarteInterfaceMapping{
+			{Endpoint: "/%{sensor}/value", Type: interfaces.Double},
+			{Endpoint: "/%{sensor}/valid", Type: interfaces.Boolean},
+		},
+	}
+	testTimestamp          = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	testReceptionTimestamp = time.Date(2024, 1, 1, 0, 0, 1, 0, time.UTC)
+)
+
+type testInserter struct {
+	batches [][]Row
+}
+
+func (i *testInserter) InsertRows(_ context.Context, rows []Row) error {
+	i.batches = append(i.batches, rows)
+	return nil
+}
+
+type testExecer struct {
+	query string
+	args  []any
+	// statements is the number of arguments of each statement
+	statements []int
+}
+
+func (e *testExecer) ExecContext(_ context.Context, query string, args ...any) (sql.Result, error) {
+	e.query = query
+	e.args = args
+	e.statements = append(e.statements, len(args))
+	return nil, nil
+}
+
+func TestConverterIndividualValues(t *testing.T) {
+	converter := NewConverter(testDeviceID, testIndividualInterface)
+	data := map[string]any{
+		"/value":   client.DatastreamIndividualValue{Value: 21.5, Timestamp: testTimestamp, ReceptionTimestamp: testReceptionTimestamp},
+		"/count":   client.DatastreamIndividualValue{Value: "9007199254740993", Timestamp: testTimestamp},
+		"/name":    client.DatastreamIndividualValue{Value: "kitchen", Timestamp: testTimestamp},
+		"/since":   client.DatastreamIndividualValue{Value: "2023-06-01T12:00:00Z", Timestamp: testTimestamp},
+		"/raw":     client.DatastreamIndividualValue{Value: "AQID", Timestamp: testTimestamp},
+		"/samples": client.DatastreamIndividualValue{Value: []any{1.0, 2.0}, Timestamp: testTimestamp},
+	}
+
+	rows, err := converter.Rows("/kitchen/", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byPath := map[string]Row{}
+	for _, row := range rows {
+		if row.DeviceID != testDeviceID || row.Interface != testIndividualInterface.Name || !row.Timestamp.Equal(testTimestamp) {
+			t.Errorf("Unexpected row %+v", row)
+		}
+		byPath[row.Path] = row
+	}
+	if len(byPath) != len(data) {
+		t.Fatalf("Expected %d rows, got %d", len(data), len(byPath))
+	}
+
+	value := byPath["/kitchen/value"]
+	if !value.ValueDouble.Valid || value.ValueDouble.Float64 != 21.5 || value.ValueText.Valid {
+		t.Errorf("Unexpected double row %+v", value)
+	}
+	if !value.ReceptionTimestamp.Valid || !value.ReceptionTimestamp.Time.Equal(testReceptionTimestamp) {
+		t.Errorf("Unexpected reception timestamp %+v", value.ReceptionTimestamp)
+	}
+	if byPath["/kitchen/name"].ReceptionTimestamp.Valid {
+		t.Error("Reception timestamp should be NULL when missing")
+	}
+	if count := byPath["/kitchen/count"].ValueInteger; !count.Valid || count.Int64 != 9007199254740993 {
+		t.Errorf("Unexpected long integer %+v", count)
+	}
+	if name := byPath["/kitchen/name"].ValueText; !name.Valid || name.String != "kitchen" {
+		t.Errorf("Unexpected string %+v", name)
+	}
+	if since := byPath["/kitchen/since"].ValueTimestamp; !since.Valid || !since.Time.Equal(time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected datetime %+v", since)
+	}
+	if raw := byPath["/kitchen/raw"].ValueBinary; !reflect.DeepEqual(raw, []byte{1, 2, 3}) {
+		t.Errorf("Unexpected binary blob %v", raw)
+	}
+	if samples := byPath["/kitchen/samples"].ValueJSON; !samples.Valid || samples.String != "[1,2]" {
+		t.Errorf("Unexpected array %+v", samples)
+	}
+}
+
+func TestConverterErrors(t *testing.T) {
+	converter := NewConverter(testDeviceID, testIndividualInterface)
+	if _, err := converter.Rows("/kitchen/value", []client.DatastreamIndividualValue{{Value: "hot"}}); err == nil {
+		t.Error("Expected an error for a mistyped value")
+	}
+	if _, err := converter.Rows("/kitchen/unknown", []client.DatastreamIndividualValue{{Value: 1.0}}); err == nil {
+		t.Error("Expected an error for a path matching no mapping")
+	}
+	if _, err := converter.Rows("/kitchen/value", 42); err == nil {
+		t.Error("Expected an error for unsupported data")
+	}
+	// Values decoded with client.WithPreciseNumbers are json.Number
+	rows, err := converter.Rows("/kitchen", map[string]client.DatastreamIndividualValue{
+		"/value": {Value: json.Number("21.5")},
+		"/count": {Value: json.Number("9007199254740993")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range rows {
+		if (row.Path == "/kitchen/value" && row.ValueDouble.Float64 != 21.5) || (row.Path == "/kitchen/count" && row.ValueInteger.Int64 != 9007199254740993) {
+			t.Errorf("Unexpected row for a precise number %+v", row)
+		}
+	}
+	// The long integer might have been rounded when decoded as float64
+	if _, err := converter.Rows("/kitchen/count", []client.DatastreamIndividualValue{{Value: float64(1 << 60)}}); !errors.Is(err, client.ErrUnsafeInteger) {
+		t.Errorf("Expected ErrUnsafeInteger for a rounded long integer, got %v", err)
+	}
+}
+
+func TestSQLInserter(t *testing.T) {
+	rows := []Row{
+		{DeviceID: testDeviceID, Path: "/a", ValueDouble: sql.NullFloat64{Float64: 1, Valid: true}},
+		{DeviceID: testDeviceID, Path: "/b", ValueBoolean: sql.NullBool{Bool: true, Valid: true}},
+	}
+	columns := "device_id, interface, path, ts, reception_ts, value_double, value_integer, value_boolean, value_text, value_timestamp, value_binary, value_json"
+
+	db := &testExecer{}
+	if err := (SQLInserter{DB: db, Table: "samples"}).InsertRows(context.Background(), rows); err != nil {
+		t.Fatal(err)
+	}
+	expected := "INSERT INTO samples (" + columns + ") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?), (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	if db.query != expected {
+		t.Errorf("Unexpected query %q", db.query)
+	}
+	if len(db.args) != 2*len(Columns) || db.args[len(Columns)+2] != "/b" {
+		t.Errorf("Unexpected args %v", db.args)
+	}
+
+	db = &testExecer{}
+	if err := (SQLInserter{DB: db, Table: "samples", Placeholder: Dollar}).InsertRows(context.Background(), rows[:1]); err != nil {
+		t.Fatal(err)
+	}
+	expected = "INSERT INTO samples (" + columns + ") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)"
+	if db.query != expected {
+		t.Errorf("Unexpected query %q", db.query)
+	}
+
+	db = &testExecer{}
+	if err := (SQLInserter{DB: db, Table: "samples"}).InsertRows(context.Background(), nil); err != nil || db.query != "" {
+		t.Errorf("No statement should run for an empty batch, got %q, %v", db.query, err)
+	}
+
+	// A page of client.DefaultMaxPageSize rows exceeds the parameters a statement can have
+	db = &testExecer{}
+	if err := (SQLInserter{DB: db, Table: "samples"}).InsertRows(context.Background(), make([]Row, client.DefaultMaxPageSize)); err != nil {
+		t.Fatal(err)
+	}
+	total := 0
+	for _, args := range db.statements {
+		if args > MaxQueryParameters {
+			t.Errorf("Statement with %d parameters", args)
+		}
+		total += args
+	}
+	if len(db.statements) != 2 || total != client.DefaultMaxPageSize*len(Columns) {
+		t.Errorf("Unexpected statements %v", db.statements)
+	}
+
+	db = &testExecer{}
+	if err := (SQLInserter{DB: db, Table: "samples", MaxRowsPerStatement: 2}).InsertRows(context.Background(), make([]Row, 5)); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(db.statements, []int{2 * len(Columns), 2 * len(Columns), len(Columns)}) {
+		t.Errorf("Unexpected statements %v", db.statements)
+	}
+}
+
+func testDatastreamMock(w http.ResponseWriter, req *http.Request) {
+	base := "/appengine/v1/" + testRealmName + "/devices/" + testDeviceID + "/interfaces/"
+	switch req.URL.Path {
+	case base + testIndividualInterface.Name + "/kitchen/value":
+		_, _ = w.Write([]byte(`{"data": [
+			{"value": 20.5, "timestamp": "2024-01-01T00:00:00Z", "reception_timestamp": "2024-01-01T00:00:01Z"},
+			{"value": 21, "timestamp": "2024-01-01T00:01:00Z", "reception_timestamp": "2024-01-01T00:01:01Z"}
+		]}`))
+	case base + testObjectInterface.Name + "/kitchen":
+		_, _ = w.Write([]byte(`{"data": [
+			{"value": 20.5, "valid": true, "timestamp": "2024-01-01T00:00:00Z"}
+		]}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"errors": {"detail": "Not found"}}`))
+	}
+}
+
+func getTestClient(t *testing.T) *client.Client {
+	server := httptest.NewServer(http.HandlerFunc(testDatastreamMock))
+	t.Cleanup(server.Close)
+	c, err := client.New(client.WithBaseURL(server.URL), client.WithJWT("a JWT"), client.WithHTTPClient(server.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestExport(t *testing.T) {
+	c := getTestClient(t)
+
+	p, err := c.GetDatastreamIndividualPaginator(testRealmName, testDeviceID, client.AstarteDeviceID, testIndividualInterface.Name,
+		"/kitchen/value", client.AscendingOrder, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inserter := &testInserter{}
+	exported, err := Export(context.Background(), c, p, testDeviceID, testIndividualInterface, "/kitchen/value", inserter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exported != 2 || len(inserter.batches) != 1 || len(inserter.batches[0]) != 2 {
+		t.Fatalf("Unexpected export of %d rows in %v", exported, inserter.batches)
+	}
+	if row := inserter.batches[0][1]; row.Path != "/kitchen/value" || row.ValueDouble.Float64 != 21 {
+		t.Errorf("Unexpected row %+v", row)
+	}
+
+	p, err = c.GetDatastreamObjectPaginator(testRealmName, testDeviceID, client.AstarteDeviceID, testObjectInterface.Name,
+		"/kitchen", client.AscendingOrder, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inserter = &testInserter{}
+	if exported, err = Export(context.Background(), c, p, testDeviceID, testObjectInterface, "/kitchen", inserter); err != nil {
+		t.Fatal(err)
+	}
+	if exported != 2 {
+		t.Fatalf("Expected a row for each object key, got %d", exported)
+	}
+	paths := []string{inserter.batches[0][0].Path, inserter.batches[0][1].Path}
+	sort.Strings(paths)
+	if !reflect.DeepEqual(paths, []string{"/kitchen/valid", "/kitchen/value"}) {
+		t.Errorf("Unexpected paths %v", paths)
+	}
+}
+
+func TestExportCanceled(t *testing.T) {
+	c := getTestClient(t)
+	p, err := c.GetDatastreamIndividualPaginator(testRealmName, testDeviceID, client.AstarteDeviceID, testIndividualInterface.Name,
+		"/kitchen/value", client.AscendingOrder, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	inserter := &testInserter{}
+	if _, err := Export(ctx, c, p, testDeviceID, testIndividualInterface, "/kitchen/value", inserter); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if len(inserter.batches) != 0 {
+		t.Error("Nothing should be exported after cancellation")
+	}
+}
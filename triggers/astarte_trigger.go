@@ -184,13 +184,52 @@ func (t *AstarteTemplateType) UnmarshalJSON(b []byte) error {
 	return t.IsValid()
 }
 
+// AstarteEventFormat represents the format of the events delivered by a trigger action
+type AstarteEventFormat string
+
+const (
+	// EventFormatV1 is the event format used by Astarte 1.x
+	EventFormatV1 AstarteEventFormat = "v1"
+)
+
+// IsValid returns an error if AstarteEventFormat does not represent a valid AstarteEventFormat
+func (f AstarteEventFormat) IsValid() error {
+	if f == EventFormatV1 {
+		return nil
+	}
+	return fmt.Errorf("'%v' is not a valid AstarteEventFormat", f)
+}
+
+// UnmarshalJSON unmashals a quoted json string to the enum value
+func (f *AstarteEventFormat) UnmarshalJSON(b []byte) error {
+	var j string
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+
+	*f = AstarteEventFormat(j)
+
+	return f.IsValid()
+}
+
 type AstarteTriggerAction struct {
-	HTTPUrl         string              `json:"http_url"`
-	HTTPMethod      AstarteHTTPMethod   `json:"http_method"`
-	HTTPHeaders     map[string]string   `json:"http_static_headers"`
+	HTTPUrl    string            `json:"http_url,omitempty"`
+	HTTPMethod AstarteHTTPMethod `json:"http_method,omitempty"`
+	// HTTPPostURL is the legacy name of HTTPUrl, implying a post method. ParseTrigger
+	// moves it to HTTPUrl, so it is never set in parsed triggers.
+	HTTPPostURL     string              `json:"http_post_url,omitempty"`
+	HTTPHeaders     map[string]string   `json:"http_static_headers,omitempty"`
 	IgnoreSslErrors bool                `default:"false"`
 	TemplateType    AstarteTemplateType `json:"template_type,omitempty"`
 	Template        string              `json:"template,omitempty"`
+	EventFormat     AstarteEventFormat  `json:"event_format,omitempty"`
+
+	AMQPExchange                      string            `json:"amqp_exchange,omitempty"`
+	AMQPRoutingKey                    string            `json:"amqp_routing_key,omitempty"`
+	AMQPStaticHeaders                 map[string]string `json:"amqp_static_headers,omitempty"`
+	AMQPMessageExpirationMilliseconds int               `json:"amqp_message_expiration_ms,omitempty"`
+	AMQPMessagePersistent             bool              `json:"amqp_message_persistent,omitempty"`
+	AMQPMessagePriority               int               `json:"amqp_message_priority,omitempty"`
 }
 type AstarteSimpleTrigger struct {
 	Type               AstarteTriggerType          `json:"type"`
@@ -220,8 +259,10 @@ type requiredAstarteTrigger struct {
 	SimpleTriggers []requiredAstarteSimpleTrigger `json:"simple_triggers"`
 }
 type requiredAstarteTriggerAction struct {
-	HTTPUrl    *string            `json:"http_url"`
-	HTTPMethod *AstarteHTTPMethod `json:"http_method"`
+	HTTPUrl      *string            `json:"http_url"`
+	HTTPMethod   *AstarteHTTPMethod `json:"http_method"`
+	HTTPPostURL  *string            `json:"http_post_url"`
+	AMQPExchange *string            `json:"amqp_exchange"`
 }
 
 type requiredAstarteSimpleTrigger struct {
@@ -253,11 +294,23 @@ func (r *requiredAstarteTrigger) ensureRequiredFields(b []byte) error {
 	if required.Action == nil {
 		return errors.New("Invalid trigger: action must be set")
 	}
-	if required.Action.HTTPUrl == nil || required.Action.HTTPMethod == nil {
-		return errors.New("Invalid trigger: action must have at least an url and a method set")
-	}
-	if required.Action.HTTPMethod.IsValid() != nil {
-		return errors.New("Invalid trigger: invalid method for action")
+	switch {
+	case required.Action.AMQPExchange != nil:
+		if required.Action.HTTPUrl != nil || required.Action.HTTPPostURL != nil {
+			return errors.New("Invalid trigger: action cannot be both an HTTP and an AMQP action")
+		}
+	case required.Action.HTTPPostURL != nil:
+		// Legacy actions imply a post method
+		if required.Action.HTTPUrl != nil {
+			return errors.New("Invalid trigger: action cannot have both http_url and http_post_url set")
+		}
+	default:
+		if required.Action.HTTPUrl == nil || required.Action.HTTPMethod == nil {
+			return errors.New("Invalid trigger: action must have at least an url and a method set")
+		}
+		if required.Action.HTTPMethod.IsValid() != nil {
+			return errors.New("Invalid trigger: invalid method for action")
+		}
 	}
 
 	if len(required.SimpleTriggers) == 0 {
@@ -381,8 +434,17 @@ func ParseTrigger(triggerContent []byte) (AstarteTrigger, error) {
 // json.Decoder to parse Trigger information
 func EnsureTriggerDefaults(astarteTrigger AstarteTrigger) AstarteTrigger {
 
+	// Normalize legacy field names
+	if astarteTrigger.Action.HTTPPostURL != "" {
+		if astarteTrigger.Action.HTTPUrl == "" {
+			astarteTrigger.Action.HTTPUrl = astarteTrigger.Action.HTTPPostURL
+			astarteTrigger.Action.HTTPMethod = PostMethod
+		}
+		astarteTrigger.Action.HTTPPostURL = ""
+	}
+
 	// Ensure we have all defaults set
-	if err := astarteTrigger.Action.HTTPMethod.IsValid(); err != nil {
+	if err := astarteTrigger.Action.HTTPMethod.IsValid(); err != nil && astarteTrigger.Action.AMQPExchange == "" {
 		astarteTrigger.Action.HTTPMethod = GetMethod
 	}
 
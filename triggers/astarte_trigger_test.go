@@ -729,3 +729,72 @@ func TestParsing(t *testing.T) {
 		t.Error("This trigger should have passed ", err.Error())
 	}
 }
+
+func TestParsingActions(t *testing.T) {
+	LegacyTrigger := `
+	{
+		"name": "test",
+		"action": {
+			"http_post_url": "https://example.com/my_hook",
+			"event_format": "v1"
+		},
+		"simple_triggers": [
+		  {
+			"type": "device_trigger",
+			"on": "device_connected",
+			"device_id": "45336"
+		  }
+		]
+	  }`
+
+	trigger, err := ParseTriggerFrom([]byte(LegacyTrigger))
+	if err != nil {
+		t.Fatal("This trigger should have passed ", err.Error())
+	}
+	if trigger.Action.HTTPUrl != "https://example.com/my_hook" || trigger.Action.HTTPMethod != PostMethod || trigger.Action.HTTPPostURL != "" {
+		t.Error("Legacy action was not normalized", trigger.Action)
+	}
+	if trigger.Action.EventFormat != EventFormatV1 {
+		t.Error("Wrong event format detected", trigger.Action.EventFormat)
+	}
+
+	AMQPTrigger := `
+	{
+		"name": "test",
+		"action": {
+			"amqp_exchange": "astarte_events_myrealm_exchange",
+			"amqp_routing_key": "my_routing_key",
+			"amqp_static_headers": {"key": "value"},
+			"amqp_message_persistent": true
+		},
+		"simple_triggers": [
+		  {
+			"type": "device_trigger",
+			"on": "device_connected",
+			"device_id": "45336"
+		  }
+		]
+	  }`
+
+	trigger, err = ParseTriggerFrom([]byte(AMQPTrigger))
+	if err != nil {
+		t.Fatal("This trigger should have passed ", err.Error())
+	}
+	if trigger.Action.AMQPExchange != "astarte_events_myrealm_exchange" || trigger.Action.AMQPStaticHeaders["key"] != "value" || trigger.Action.HTTPMethod != "" {
+		t.Error("Wrong AMQP action detected", trigger.Action)
+	}
+
+	InvalidTriggers := []string{
+		`{"name": "test", "action": {"http_url": "https://example.com", "http_post_url": "https://example.com"},
+		"simple_triggers": [{"type": "device_trigger", "on": "device_connected", "device_id": "45336"}]}`,
+		`{"name": "test", "action": {"http_post_url": "https://example.com", "amqp_exchange": "an_exchange"},
+		"simple_triggers": [{"type": "device_trigger", "on": "device_connected", "device_id": "45336"}]}`,
+		`{"name": "test", "action": {"http_post_url": "https://example.com", "event_format": "v42"},
+		"simple_triggers": [{"type": "device_trigger", "on": "device_connected", "device_id": "45336"}]}`,
+	}
+	for _, invalid := range InvalidTriggers {
+		if _, err := ParseTriggerFrom([]byte(invalid)); err == nil {
+			t.Error("This trigger should have failed ", invalid)
+		}
+	}
+}
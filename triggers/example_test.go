@@ -0,0 +1,58 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers_test
+
+import (
+	"fmt"
+
+	"github.com/astarte-platform/astarte-go/triggers"
+)
+
+func ExampleParseTrigger() {
+	trigger, err := triggers.ParseTrigger([]byte(`{
+		"name": "high_temperature",
+		"action": {
+			"http_url": "https://example.com/my_hook",
+			"http_method": "post"
+		},
+		"simple_triggers": [
+			{
+				"type": "data_trigger",
+				"on": "incoming_data",
+				"interface_name": "org.astarte-platform.genericsensors.Values",
+				"interface_major": 1,
+				"match_path": "/%{sensor_id}/value",
+				"value_match_operator": ">",
+				"known_value": 40
+			}
+		]
+	}`))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	condition := trigger.SimpleTriggers[0]
+	fmt.Println(trigger.Name, trigger.Action.HTTPMethod)
+	fmt.Println(condition.Type, condition.On, condition.ValueMatchOperator, condition.KnownValue)
+
+	// Invalid triggers are rejected
+	_, err = triggers.ParseTrigger([]byte(`{"name": "no_action", "simple_triggers": []}`))
+	fmt.Println(err)
+	// Output:
+	// high_temperature post
+	// data_trigger incoming_data > 40
+	// Invalid trigger: action must be set
+}
@@ -0,0 +1,36 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// Fingerprint returns a stable SHA-256 hash of a trigger, as a hex string, computed over its canonical JSON
+// representation. Defaults are set and legacy fields are normalized before hashing, so e.g. a trigger using
+// http_post_url and the same trigger using http_url with a post method have the same fingerprint.
+func Fingerprint(astarteTrigger AstarteTrigger) string {
+	canonical := EnsureTriggerDefaults(astarteTrigger)
+	b, err := json.Marshal(canonical)
+	if err != nil {
+		// e.g. an invalid KnownValue, fall back to the Go representation of the trigger
+		b = []byte(fmt.Sprintf("%#v", canonical))
+	}
+	hash := sha256.Sum256(b)
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,50 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	legacy, err := ParseTrigger([]byte(`{
+		"name": "ah_yes_a_trigger",
+		"action": {"http_post_url": "https://example.com/my_hook", "http_static_headers": {"b": "2", "a": "1"}},
+		"simple_triggers": [{"type": "device_trigger", "on": "device_connected", "device_id": "glO6LullTKmwxebForU-eg"}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := ParseTrigger([]byte(`{
+		"simple_triggers": [{"on": "device_connected", "type": "device_trigger", "device_id": "glO6LullTKmwxebForU-eg"}],
+		"action": {"http_static_headers": {"a": "1", "b": "2"}, "http_method": "post", "http_url": "https://example.com/my_hook"},
+		"name": "ah_yes_a_trigger"
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fingerprint := Fingerprint(legacy)
+	if len(fingerprint) != 64 {
+		t.Errorf("Expected a hex SHA-256 hash, found %s", fingerprint)
+	}
+	if Fingerprint(current) != fingerprint {
+		t.Error("Equivalent triggers should have the same fingerprint")
+	}
+	current.Action.HTTPMethod = PutMethod
+	if Fingerprint(current) == fingerprint {
+		t.Error("Different triggers should have different fingerprints")
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+)
+
+// ParseTriggerFromFS works like ParseTriggerFrom, but reads the trigger named name in fsys, e.g. an embed.FS
+// holding the triggers of an application.
+func ParseTriggerFromFS(fsys fs.FS, name string) (AstarteTrigger, error) {
+	b, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return AstarteTrigger{}, err
+	}
+	return ParseTrigger(b)
+}
+
+// ParseTriggersFromFS parses all the triggers in the JSON files found walking the directory root of fsys, in
+// lexical order. Other files are ignored. The triggers which can be parsed are returned even if some can't,
+// along with the errors for the latter, joined.
+func ParseTriggersFromFS(fsys fs.FS, root string) ([]AstarteTrigger, error) {
+	astarteTriggers := []AstarteTrigger{}
+	errs := []error{}
+	err := fs.WalkDir(fsys, root, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || path.Ext(filePath) != ".json" {
+			return nil
+		}
+		astarteTrigger, err := ParseTriggerFromFS(fsys, filePath)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", filePath, err))
+			return nil
+		}
+		astarteTriggers = append(astarteTriggers, astarteTrigger)
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return astarteTriggers, errors.Join(errs...)
+}
@@ -0,0 +1,48 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseTriggersFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"triggers/connected.json": {Data: []byte(`{"name": "connected", "action": {"http_post_url": "https://example.com"},
+			"simple_triggers": [{"type": "device_trigger", "on": "device_connected", "device_id": "*"}]}`)},
+		"triggers/invalid.json": {Data: []byte(`{"name": "invalid"}`)},
+		"triggers/notes.txt":    {Data: []byte("Not a trigger")},
+	}
+
+	trigger, err := ParseTriggerFromFS(fsys, "triggers/connected.json")
+	if err != nil || trigger.Name != "connected" || trigger.Action.HTTPUrl != "https://example.com" {
+		t.Errorf("Unexpected trigger %+v, %v", trigger, err)
+	}
+
+	astarteTriggers, err := ParseTriggersFromFS(fsys, ".")
+	if err == nil || !strings.Contains(err.Error(), "triggers/invalid.json") {
+		t.Errorf("Expected an error for the invalid trigger, got %v", err)
+	}
+	if len(astarteTriggers) != 1 || astarteTriggers[0].Name != "connected" {
+		t.Errorf("Unexpected triggers %+v", astarteTriggers)
+	}
+	if _, err := ParseTriggersFromFS(fsys, "missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected fs.ErrNotExist, got %v", err)
+	}
+}
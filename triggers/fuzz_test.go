@@ -0,0 +1,39 @@
+// Copyright © 2024 SECO Mind Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"testing"
+)
+
+func FuzzParseTrigger(f *testing.F) {
+	f.Add([]byte(`{"name": "example_trigger", "action": {"http_url": "https://example.com", "http_method": "post"},
+		"simple_triggers": [{"type": "data_trigger", "on": "incoming_data", "interface_name": "*", "value_match_operator": "*",
+		"match_path": "/*"}]}`))
+	f.Add([]byte(`{"name": "example_trigger", "action": {"amqp_exchange": "astarte_events_test_custom", "amqp_message_expiration_ms": 1000},
+		"simple_triggers": [{"type": "device_trigger", "on": "device_connected", "device_id": "*"}]}`))
+	f.Add([]byte(`{"name": "example_trigger", "action": {"http_url": "https://example.com"}, "simple_triggers": [null]}`))
+	f.Add([]byte(`[]`))
+	f.Add([]byte(`null`))
+
+	f.Fuzz(func(t *testing.T, triggerContent []byte) {
+		astarteTrigger, err := ParseTrigger(triggerContent)
+		if err != nil {
+			return
+		}
+		// Whatever was parsed must be usable without panicking
+		_ = Fingerprint(astarteTrigger)
+	})
+}